@@ -45,6 +45,8 @@ type SymlinkInterface interface {
 // GitInterface is the interface to implement Git related capabilities.
 type GitInterface interface {
 	GitCanPushOptions() bool
+	GitCanMaintenance() bool
+	GitCanSparseCheckout() bool
 }
 
 // Instance of interface, which can be overridden for test by mocking.
@@ -105,6 +107,17 @@ func (v defaultCapGitImpl) GitCanPushOptions() bool {
 	return version.CompareVersion(version.GitVersion, "2.10.0") >= 0
 }
 
+// GitCanMaintenance indicates git has maintenance command with tasks.
+func (v defaultCapGitImpl) GitCanMaintenance() bool {
+	return version.CompareVersion(version.GitVersion, "2.29.0") >= 0
+}
+
+// GitCanSparseCheckout indicates git has sparse-checkout command with cone
+// mode.
+func (v defaultCapGitImpl) GitCanSparseCheckout() bool {
+	return version.CompareVersion(version.GitVersion, "2.25.0") >= 0
+}
+
 // IsWindows indicates whether current OS is windows.
 func IsWindows() bool {
 	return CapWindows.IsWindows()
@@ -125,6 +138,17 @@ func GitCanPushOptions() bool {
 	return CapGit.GitCanPushOptions()
 }
 
+// GitCanMaintenance indicates whether git can run maintenance tasks.
+func GitCanMaintenance() bool {
+	return CapGit.GitCanMaintenance()
+}
+
+// GitCanSparseCheckout indicates whether git can run sparse-checkout in
+// cone mode.
+func GitCanSparseCheckout() bool {
+	return CapGit.GitCanSparseCheckout()
+}
+
 func init() {
 	CapWindows = &defaultWindowsImpl{}
 	CapTTY = &defaultTTYImpl{}
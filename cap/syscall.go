@@ -12,3 +12,19 @@ func GetRlimitNoFile() (uint64, error) {
 	syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit)
 	return rlimit.Cur, nil
 }
+
+// ProcessExists checks whether process of pid is running.
+func ProcessExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// DiskFree returns free space in bytes of the filesystem of dir, which is
+// available to unprivileged user.
+func DiskFree(dir string) (uint64, error) {
+	stat := syscall.Statfs_t{}
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
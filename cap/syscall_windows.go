@@ -4,9 +4,39 @@ package cap
 
 import (
 	"errors"
+	"os"
+	"syscall"
+	"unsafe"
 )
 
 // GetRlimitNoFile() implements nothing, but returns error on Windows.
 func GetRlimitNoFile() (uint64, error) {
 	return 0, errors.New("getrlimit not implement in Windows")
 }
+
+// ProcessExists checks whether process of pid is running.
+func ProcessExists(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
+
+// DiskFree returns free space in bytes of the filesystem of dir, which is
+// available to current user.
+func DiskFree(dir string) (uint64, error) {
+	var free uint64
+
+	name, err := syscall.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+	proc := syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+	r, _, err := proc.Call(uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&free)), 0, 0)
+	if r == 0 {
+		return 0, err
+	}
+	return free, nil
+}
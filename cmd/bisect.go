@@ -0,0 +1,320 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// bisectDir is where manifests of commits are extracted, in ".repo".
+	bisectDir = "bisect"
+	// bisectSkipCode is exit code of test command to skip a candidate,
+	// the same as "git bisect run".
+	bisectSkipCode = 125
+)
+
+// bisectResult is result of testing a candidate.
+type bisectResult int
+
+// Results of testing a candidate.
+const (
+	bisectGood bisectResult = iota
+	bisectBad
+	bisectSkip
+)
+
+// bisectCandidate is a snapshot manifest, or a commit of manifests
+// project, to sync the workspace to.
+type bisectCandidate struct {
+	// Name is shown to user.
+	Name string
+	// Commit of manifests project, empty for manifest file.
+	Commit string
+	// Manifest is path of manifest file, relative to manifests project.
+	Manifest string
+}
+
+// bisectSearch finds the first bad candidate of n candidates, where the
+// first one is good and the last one is bad. It returns index of the last
+// good and the first bad candidate, and candidates between them are
+// skipped.
+func bisectSearch(n int, test func(i int) (bisectResult, error)) (int, int, error) {
+	good, bad := 0, n-1
+	skipped := make(map[int]bool)
+	for {
+		// Pick an untested candidate nearest to the middle.
+		next := -1
+		mid := (good + bad) / 2
+		for d := 0; mid-d > good || mid+d+1 < bad; d++ {
+			if i := mid - d; i > good && !skipped[i] {
+				next = i
+				break
+			}
+			if i := mid + d + 1; i < bad && !skipped[i] {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return good, bad, nil
+		}
+
+		result, err := test(next)
+		if err != nil {
+			return good, bad, err
+		}
+		switch result {
+		case bisectGood:
+			good = next
+		case bisectBad:
+			bad = next
+		default:
+			skipped[next] = true
+		}
+	}
+}
+
+// bisectResultOf returns result by error of test command: exit code 0 is
+// good, 125 is skip, and other codes below 128 are bad. Test command is
+// aborted by other errors, such as killed by a signal.
+func bisectResultOf(err error) (bisectResult, error) {
+	if err == nil {
+		return bisectGood, nil
+	}
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return bisectBad, err
+	}
+	code := exitErr.ExitCode()
+	switch {
+	case code == bisectSkipCode:
+		return bisectSkip, nil
+	case code > 0 && code < 128:
+		return bisectBad, nil
+	}
+	return bisectBad, fmt.Errorf("test command is aborted: %s", err)
+}
+
+type bisectCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		Good string
+		Bad  string
+		Run  string
+	}
+}
+
+func (v *bisectCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "bisect --run <command> (--good <rev> --bad <rev> | <manifest>...)",
+		Short: "Find which manifest change breaks the workspace",
+		Long: `Bisect over commits of the manifests project between --good and --bad
+(following first parents), or over snapshot manifest files in order, where
+the first one is good and the last one is bad. For each candidate, the
+workspace is synced to the manifest in detached HEAD, and the test command
+is run in the top directory of the workspace.
+
+Exit code 0 of the test command means good, 125 means the candidate cannot
+be tested and is skipped, and other codes below 128 mean bad. Run "git repo
+sync" to go back to the latest revisions after bisecting.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().StringVar(&v.O.Good,
+		"good",
+		"",
+		"a good revision of the manifests project")
+	v.cmd.Flags().StringVar(&v.O.Bad,
+		"bad",
+		"",
+		"a bad revision of the manifests project")
+	v.cmd.Flags().StringVar(&v.O.Run,
+		"run",
+		"",
+		"command to test each candidate")
+
+	return v.cmd
+}
+
+// commitCandidates returns commits of manifests project from good to bad.
+func (v bisectCommand) commitCandidates() ([]bisectCandidate, error) {
+	mp := v.RepoWorkSpace().ManifestProject
+	good, err := mp.ResolveCommit(v.O.Good)
+	if err != nil {
+		return nil, newUserError(err.Error())
+	}
+	bad, err := mp.ResolveCommit(v.O.Bad)
+	if err != nil {
+		return nil, newUserError(err.Error())
+	}
+	commits, err := mp.Revlist("--first-parent", "--reverse", good+".."+bad)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, newUserErrorF("'%s' is not an ancestor of '%s'", v.O.Good, v.O.Bad)
+	}
+
+	candidates := []bisectCandidate{}
+	for _, commit := range append([]string{good}, commits...) {
+		commit = strings.TrimSpace(commit)
+		name := commit
+		result := mp.ExecuteCommand(config.GIT, "log", "-1", "--format=%h %s", commit)
+		if result.Success() {
+			name = strings.TrimSpace(result.Stdout())
+		}
+		candidates = append(candidates, bisectCandidate{
+			Name:     name,
+			Commit:   commit,
+			Manifest: filepath.Join("..", bisectDir, commit, mp.ManifestName()),
+		})
+	}
+	return candidates, nil
+}
+
+// fileCandidates returns candidates of snapshot manifest files.
+func (v bisectCommand) fileCandidates(files []string) ([]bisectCandidate, error) {
+	manifestsDir := v.RepoWorkSpace().ManifestProject.WorkDir
+	candidates := []bisectCandidate{}
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = os.Stat(abs); err != nil {
+			return nil, newUserErrorF("cannot find manifest '%s'", file)
+		}
+		rel, err := filepath.Rel(manifestsDir, abs)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, bisectCandidate{
+			Name:     file,
+			Manifest: rel,
+		})
+	}
+	return candidates, nil
+}
+
+// test syncs workspace to candidate, and runs test command.
+func (v bisectCommand) test(c bisectCandidate) (bisectResult, error) {
+	rws := v.RepoWorkSpace()
+
+	log.Notef("bisect: testing %s", c.Name)
+	if c.Commit != "" {
+		dir := filepath.Join(rws.AdminDir(), bisectDir, c.Commit)
+		if err := rws.ManifestProject.ExtractAt(c.Commit, dir); err != nil {
+			return bisectSkip, fmt.Errorf("fail to extract manifests of %s: %s", c.Commit, err)
+		}
+	}
+
+	s := syncCmd
+	s.O.ManifestName = c.Manifest
+	s.O.DetachHead = true
+	if err := s.Execute(nil); err != nil {
+		log.Warnf("bisect: skip %s, fail to sync: %s", c.Name, err)
+		return bisectSkip, nil
+	}
+
+	_, err := helper.RunCommand(&helper.Command{
+		Args:   []string{"sh", "-c", v.O.Run},
+		Dir:    rws.RootDir,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+	result, err := bisectResultOf(err)
+	if err == nil {
+		log.Notef("bisect: %s is %s", c.Name, map[bisectResult]string{
+			bisectGood: "good",
+			bisectBad:  "bad",
+			bisectSkip: "skipped",
+		}[result])
+	}
+	return result, err
+}
+
+func (v bisectCommand) Execute(args []string) error {
+	var (
+		candidates []bisectCandidate
+		err        error
+	)
+
+	if v.O.Run == "" {
+		return newUserError("no test command, use --run to set one")
+	}
+	if v.O.Good != "" || v.O.Bad != "" {
+		if v.O.Good == "" || v.O.Bad == "" || len(args) > 0 {
+			return newUserError("both --good and --bad must be given, without manifest files")
+		}
+		candidates, err = v.commitCandidates()
+	} else {
+		if len(args) < 2 {
+			return newUserError("at least two manifest files, a good one and a bad one, must be given")
+		}
+		candidates, err = v.fileCandidates(args)
+	}
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(filepath.Join(v.RepoWorkSpace().AdminDir(), bisectDir))
+
+	good, bad, err := bisectSearch(len(candidates), func(i int) (bisectResult, error) {
+		return v.test(candidates[i])
+	})
+	if err != nil {
+		return err
+	}
+
+	if bad-good > 1 {
+		fmt.Printf("There are only skipped candidates left to test.\n" +
+			"The first bad one could be any of:\n")
+		for _, c := range candidates[good+1 : bad+1] {
+			fmt.Printf("  %s\n", c.Name)
+		}
+	} else {
+		fmt.Printf("Last good: %s\n", candidates[good].Name)
+		fmt.Printf("First bad: %s\n", candidates[bad].Name)
+	}
+	log.Note("run 'git repo sync' to go back to the latest revisions")
+	return nil
+}
+
+var bisectCmd = bisectCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bisectCmd.Command())
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockBisectTest(results []bisectResult, tested *[]int) func(int) (bisectResult, error) {
+	return func(i int) (bisectResult, error) {
+		*tested = append(*tested, i)
+		return results[i], nil
+	}
+}
+
+func TestBisectSearch(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		tested []int
+	)
+
+	// Candidate 5 is the first bad one.
+	results := []bisectResult{
+		bisectGood, bisectGood, bisectGood, bisectGood, bisectGood,
+		bisectBad, bisectBad, bisectBad, bisectBad, bisectBad,
+	}
+	good, bad, err := bisectSearch(len(results), mockBisectTest(results, &tested))
+	assert.Nil(err)
+	assert.Equal(4, good)
+	assert.Equal(5, bad)
+	assert.Equal([]int{4, 6, 5}, tested)
+
+	// Nothing to test between good and bad.
+	tested = nil
+	good, bad, err = bisectSearch(2, mockBisectTest(results[4:6], &tested))
+	assert.Nil(err)
+	assert.Equal(0, good)
+	assert.Equal(1, bad)
+	assert.Empty(tested)
+}
+
+func TestBisectSearchSkip(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		tested []int
+	)
+
+	results := []bisectResult{
+		bisectGood, bisectGood, bisectSkip, bisectSkip, bisectBad, bisectBad,
+	}
+	good, bad, err := bisectSearch(len(results), mockBisectTest(results, &tested))
+	assert.Nil(err)
+	assert.Equal(1, good)
+	assert.Equal(4, bad)
+	assert.Equal([]int{2, 3, 1, 4}, tested)
+
+	tested = nil
+	good, bad, err = bisectSearch(len(results), func(i int) (bisectResult, error) {
+		tested = append(tested, i)
+		return bisectBad, errors.New("aborted")
+	})
+	assert.NotNil(err)
+	assert.Equal(0, good)
+	assert.Equal(5, bad)
+	assert.Equal([]int{2}, tested)
+}
+
+func TestBisectResultOf(t *testing.T) {
+	assert := assert.New(t)
+
+	result, err := bisectResultOf(nil)
+	assert.Nil(err)
+	assert.Equal(bisectGood, result)
+
+	result, err = bisectResultOf(exec.Command("sh", "-c", "exit 1").Run())
+	assert.Nil(err)
+	assert.Equal(bisectBad, result)
+
+	result, err = bisectResultOf(exec.Command("sh", "-c", "exit 125").Run())
+	assert.Nil(err)
+	assert.Equal(bisectSkip, result)
+
+	_, err = bisectResultOf(exec.Command("sh", "-c", "exit 129").Run())
+	assert.NotNil(err)
+
+	_, err = bisectResultOf(errors.New("not found"))
+	assert.NotNil(err)
+}
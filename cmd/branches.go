@@ -0,0 +1,135 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/spf13/cobra"
+)
+
+type branchesCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+}
+
+func (v *branchesCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:     "branches [<project>...]",
+		Aliases: []string{"branch"},
+		Short:   "View current topic branches",
+		Long: `Summarizes the currently available topic branches.
+
+The first column shows "*" if the branch is checked out in any project.
+The second column shows "P" if the branch is published in all projects
+where it exists, or "p" if it is only published in some of them.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+
+	return v.cmd
+}
+
+func (v branchesCommand) Execute(args []string) error {
+	ws := v.WorkSpace()
+	projects, err := ws.GetProjects(nil, args...)
+	if err != nil {
+		return err
+	}
+
+	branches := project.CollectBranches(projects)
+	if len(branches) == 0 {
+		log.Note("(no branches)")
+		return nil
+	}
+
+	width := 25
+	for _, b := range branches {
+		if len(b.ShortName()) > width {
+			width = len(b.ShortName())
+		}
+	}
+
+	for _, b := range branches {
+		fmt.Printf("%s%s %-*s | %s\n",
+			branchCurrentMark(b),
+			branchPublishedMark(b),
+			width,
+			b.ShortName(),
+			branchProjectsSummary(b, projects))
+	}
+	return nil
+}
+
+func branchCurrentMark(b *project.BranchInfo) string {
+	if b.IsCurrent() {
+		return "*"
+	}
+	return " "
+}
+
+func branchPublishedMark(b *project.BranchInfo) string {
+	if b.IsPublished() {
+		return "P"
+	} else if b.IsPartlyPublished() {
+		return "p"
+	}
+	return " "
+}
+
+func branchProjectsSummary(b *project.BranchInfo, projects []*project.Project) string {
+	if len(b.Projects) == len(projects) {
+		return "in all projects"
+	}
+
+	in := make(map[string]bool)
+	for _, p := range b.Projects {
+		in[p.Path] = true
+	}
+
+	paths := []string{}
+	if len(b.Projects)*2 <= len(projects) {
+		for _, p := range b.Projects {
+			paths = append(paths, p.Path)
+		}
+		return "in " + strings.Join(paths, ", ")
+	}
+	for _, p := range projects {
+		if !in[p.Path] {
+			paths = append(paths, p.Path)
+		}
+	}
+	return "not in " + strings.Join(paths, ", ")
+}
+
+var branchesCmd = branchesCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: true,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(branchesCmd.Command())
+}
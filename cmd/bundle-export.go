@@ -0,0 +1,364 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alibaba/git-repo-go/file"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/alibaba/git-repo-go/workspace"
+	"github.com/spf13/cobra"
+)
+
+// Files of offline update.
+const (
+	// bundleStateFile is state of refs of projects in archive.
+	bundleStateFile = "bundle-state.json"
+	// bundleManifestName is bundle of manifest project in archive.
+	bundleManifestName = "manifests.bundle"
+	// bundleExportState records refs exported last time, in ".repo".
+	bundleExportState = "bundle-export.json"
+	// bundleImportState records refs imported last time, in ".repo".
+	bundleImportState = "bundle-import.json"
+)
+
+// bundleProjectState is refs of remote tracking branches and tags of a
+// project, with their object IDs.
+type bundleProjectState struct {
+	Name      string            `json:"name"`
+	Path      string            `json:"path"`
+	Refs      map[string]string `json:"refs"`
+	HasBundle bool              `json:"has_bundle,omitempty"`
+}
+
+// Commits returns object IDs of refs.
+func (v bundleProjectState) Commits() []string {
+	commits := []string{}
+	for _, commit := range v.Refs {
+		commits = append(commits, commit)
+	}
+	return commits
+}
+
+// bundleState is state of refs of workspace, saved in archive of "git repo
+// bundle-export", and recorded by export and import to update workspace
+// with changes since then.
+type bundleState struct {
+	Time     time.Time             `json:"time"`
+	Manifest *bundleProjectState   `json:"manifest,omitempty"`
+	Projects []*bundleProjectState `json:"projects"`
+}
+
+// bundleProjectName returns name of bundle of project in archive.
+func bundleProjectName(path string) string {
+	return snapshotBundlesDir + "/" + path + ".bundle"
+}
+
+// loadBundleState reads state from file.
+func loadBundleState(file string) (*bundleState, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	state := bundleState{}
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("bad state of bundles in '%s': %s", file, err)
+	}
+	return &state, nil
+}
+
+// Project returns state of project with path.
+func (v *bundleState) Project(path string) *bundleProjectState {
+	if v == nil {
+		return nil
+	}
+	for _, s := range v.Projects {
+		if s.Path == path {
+			return s
+		}
+	}
+	return nil
+}
+
+// Merge updates state with refs of projects in other.
+func (v *bundleState) Merge(other *bundleState) {
+	v.Time = other.Time
+	if other.Manifest != nil {
+		s := *other.Manifest
+		s.HasBundle = false
+		v.Manifest = &s
+	}
+	for _, o := range other.Projects {
+		s := *o
+		s.HasBundle = false
+		if old := v.Project(s.Path); old != nil {
+			*old = s
+		} else {
+			v.Projects = append(v.Projects, &s)
+		}
+	}
+}
+
+// recordBundleState merges state into the one recorded in file.
+func recordBundleState(name string, state *bundleState) error {
+	recorded, err := loadBundleState(name)
+	if err != nil {
+		recorded = &bundleState{Projects: []*bundleProjectState{}}
+	}
+	recorded.Merge(state)
+	data, err := json.MarshalIndent(recorded, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := file.New(name).OpenCreateRewrite()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// addTarFile adds file on disk as a regular file named name to tw.
+func addTarFile(tw *tar.Writer, name, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     fi.Size(),
+		ModTime:  fi.ModTime(),
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// exportBundleProject saves changes of project since state of last time in
+// bundle, and returns state of refs of project.
+func exportBundleProject(p *project.Project, since *bundleProjectState, bundle string) (*bundleProjectState, error) {
+	refs, err := p.BundleRefs()
+	if err != nil {
+		return nil, err
+	}
+	s := bundleProjectState{
+		Name: p.Name,
+		Path: p.Path,
+		Refs: refs,
+	}
+	commits := []string{}
+	if since != nil {
+		commits = since.Commits()
+	}
+	if s.HasBundle, err = p.CreateUpdateBundle(bundle, refs, commits); err != nil {
+		return nil, fmt.Errorf("%sfail to create bundle: %s", p.Prompt(), err)
+	}
+	return &s, nil
+}
+
+type bundleExportCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		Output string
+		Since  string
+		Full   bool
+	}
+}
+
+func (v *bundleExportCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "bundle-export -o <file> [<project>...]",
+		Short: "Export changes of projects in bundles to update workspace offline",
+		Long: `Save remote tracking branches and tags of manifest project and projects
+in git bundles, which are imported by "git repo bundle-import" to update a
+workspace on a disconnected network.
+
+Only commits since the last export (recorded in ".repo/bundle-export.json")
+are saved, unless --full is given. Use --since with the state recorded by
+"git repo bundle-import" (".repo/bundle-import.json") in the disconnected
+workspace to export exactly what it lacks. The archive is compressed if
+name of output file ends with ".tar.gz" or ".tgz".`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().StringVarP(&v.O.Output,
+		"output",
+		"o",
+		"",
+		"file to save bundles")
+	v.cmd.Flags().StringVar(&v.O.Since,
+		"since",
+		"",
+		"export changes since state in this file (default: state of last export)")
+	v.cmd.Flags().BoolVar(&v.O.Full,
+		"full",
+		false,
+		"export full history of projects")
+
+	return v.cmd
+}
+
+// writeBundles saves state and bundles in archive.
+func (v bundleExportCommand) writeBundles(state *bundleState, bundles map[string]string) error {
+	w, closeFile, err := createExportFile(v.O.Output)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(w)
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err == nil {
+		err = writeTarFile(tw, bundleStateFile, append(data, '\n'))
+	}
+	if err == nil && state.Manifest != nil && state.Manifest.HasBundle {
+		err = addTarFile(tw, bundleManifestName, bundles[bundleManifestName])
+	}
+	for _, s := range state.Projects {
+		if err != nil {
+			break
+		}
+		if s.HasBundle {
+			err = addTarFile(tw, bundleProjectName(s.Path), bundles[s.Path])
+		}
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if e := closeFile(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(v.O.Output)
+	}
+	return err
+}
+
+func (v bundleExportCommand) Execute(args []string) error {
+	var (
+		since *bundleState
+		err   error
+	)
+
+	if v.O.Output == "" {
+		return newUserError("no output file, use -o to set one")
+	}
+	if v.O.Full && v.O.Since != "" {
+		return newUserError("cannot combine --full and --since")
+	}
+
+	rws := v.RepoWorkSpace()
+	exportState := filepath.Join(rws.AdminDir(), bundleExportState)
+	if v.O.Since != "" {
+		if since, err = loadBundleState(v.O.Since); err != nil {
+			return err
+		}
+	} else if !v.O.Full {
+		if since, err = loadBundleState(exportState); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	projects, err := rws.GetProjects(&workspace.GetProjectsOptions{
+		Groups: rws.Settings().Groups,
+	}, args...)
+	if err != nil {
+		return err
+	}
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-bundle-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpdir)
+
+	state := bundleState{
+		Time:     time.Now(),
+		Projects: []*bundleProjectState{},
+	}
+	bundles := make(map[string]string)
+	bundles[bundleManifestName] = filepath.Join(tmpdir, bundleManifestName)
+	var sinceManifest *bundleProjectState
+	if since != nil {
+		sinceManifest = since.Manifest
+	}
+	state.Manifest, err = exportBundleProject(&rws.ManifestProject.Project,
+		sinceManifest, bundles[bundleManifestName])
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for i, p := range projects {
+		if !p.Exists() {
+			log.Warnf("%snot fetched yet, skipped", p.Prompt())
+			continue
+		}
+		bundles[p.Path] = filepath.Join(tmpdir, fmt.Sprintf("%d.bundle", i))
+		s, err := exportBundleProject(p, since.Project(p.Path), bundles[p.Path])
+		if err != nil {
+			return err
+		}
+		if s.HasBundle {
+			count++
+			log.Infof("%sexported", p.Prompt())
+		}
+		state.Projects = append(state.Projects, s)
+	}
+
+	if err = v.writeBundles(&state, bundles); err != nil {
+		return err
+	}
+	if err = recordBundleState(exportState, &state); err != nil {
+		log.Warnf("fail to record state of export: %s", err)
+	}
+	log.Notef("exported changes of %d of %d projects to %s", count, len(state.Projects), v.O.Output)
+	return nil
+}
+
+var bundleExportCmd = bundleExportCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bundleExportCmd.Command())
+}
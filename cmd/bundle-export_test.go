@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordBundleState(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-bundle-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	file := filepath.Join(tmpdir, bundleExportState)
+	state := bundleState{
+		Time: time.Unix(1577923200, 0),
+		Manifest: &bundleProjectState{
+			Name:      "manifests",
+			Path:      "manifests",
+			Refs:      map[string]string{"refs/remotes/origin/master": "1111111111111111111111111111111111111111"},
+			HasBundle: true,
+		},
+		Projects: []*bundleProjectState{
+			{
+				Name:      "platform/app",
+				Path:      "app",
+				Refs:      map[string]string{"refs/remotes/origin/master": "2222222222222222222222222222222222222222"},
+				HasBundle: true,
+			},
+			{
+				Name: "platform/lib",
+				Path: "lib",
+				Refs: map[string]string{"refs/tags/v1.0": "3333333333333333333333333333333333333333"},
+			},
+		},
+	}
+	assert.Nil(recordBundleState(file, &state))
+	// State to write in archive is not changed.
+	assert.True(state.Projects[0].HasBundle)
+
+	update := bundleState{
+		Time: time.Unix(1577923260, 0),
+		Projects: []*bundleProjectState{
+			{
+				Name:      "platform/app",
+				Path:      "app",
+				Refs:      map[string]string{"refs/remotes/origin/master": "4444444444444444444444444444444444444444"},
+				HasBundle: true,
+			},
+		},
+	}
+	assert.Nil(recordBundleState(file, &update))
+
+	recorded, err := loadBundleState(file)
+	assert.Nil(err)
+	assert.Equal(int64(1577923260), recorded.Time.Unix())
+	assert.Equal("manifests", recorded.Manifest.Name)
+	assert.False(recorded.Manifest.HasBundle)
+	assert.Equal(2, len(recorded.Projects))
+	assert.Equal([]string{"4444444444444444444444444444444444444444"}, recorded.Project("app").Commits())
+	assert.False(recorded.Project("app").HasBundle)
+	assert.Equal([]string{"3333333333333333333333333333333333333333"}, recorded.Project("lib").Commits())
+	assert.Nil(recorded.Project("missing"))
+
+	var empty *bundleState
+	assert.Nil(empty.Project("app"))
+}
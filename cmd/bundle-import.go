@@ -0,0 +1,160 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/path"
+	"github.com/spf13/cobra"
+)
+
+// bundleImportDir is where archive of bundles is extracted, in ".repo".
+const bundleImportDir = "bundle-import"
+
+type bundleImportCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		NoSync bool
+	}
+}
+
+func (v *bundleImportCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "bundle-import <file>",
+		Short: "Update workspace offline with bundles saved by bundle-export",
+		Long: `Fetch remote tracking branches and tags of manifest project and projects
+from bundles saved by "git repo bundle-export", and check out projects by
+"git repo sync -l" (unless --no-sync is given), to update a workspace on a
+disconnected network. Repositories of new projects are created.
+
+Imported refs are recorded in ".repo/bundle-import.json", which can be
+given to "git repo bundle-export --since" to export changes for the next
+update.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().BoolVar(&v.O.NoSync,
+		"no-sync",
+		false,
+		"only fetch from bundles, do not check out projects")
+
+	return v.cmd
+}
+
+func (v bundleImportCommand) Execute(args []string) error {
+	rws := v.RepoWorkSpace()
+	dir := filepath.Join(rws.AdminDir(), bundleImportDir)
+	os.RemoveAll(dir)
+	defer os.RemoveAll(dir)
+
+	if err := extractSnapshot(args[0], dir); err != nil {
+		return err
+	}
+	state, err := loadBundleState(filepath.Join(dir, bundleStateFile))
+	if err != nil {
+		return newUserErrorF("'%s' is not saved by bundle-export", args[0])
+	}
+	log.Notef("import bundles exported at %s",
+		state.Time.Local().Format("2006-01-02 15:04:05"))
+
+	imported := bundleState{
+		Time:     state.Time,
+		Projects: []*bundleProjectState{},
+	}
+	if state.Manifest != nil {
+		if state.Manifest.HasBundle {
+			err = rws.ManifestProject.ImportBundle(filepath.Join(dir, bundleManifestName))
+			if err != nil {
+				return err
+			}
+		}
+		imported.Manifest = state.Manifest
+
+		// Check out manifest project to find new projects.
+		s := syncCmd
+		s.O.LocalOnly = true
+		if err = s.updateManifestProject(); err != nil {
+			return err
+		}
+		rws = v.ReloadRepoWorkSpace()
+	}
+
+	count, failed := 0, 0
+	for _, ps := range state.Projects {
+		if !ps.HasBundle {
+			imported.Projects = append(imported.Projects, ps)
+			continue
+		}
+		p := rws.GetProjectWithPath(ps.Path)
+		if p == nil {
+			log.Warnf("project '%s' is not in manifest, skipped", ps.Path)
+			continue
+		}
+		bundle := filepath.Join(dir, filepath.FromSlash(bundleProjectName(ps.Path)))
+		if !path.IsFile(bundle) {
+			log.Errorf("%sbundle is missing in archive", p.Prompt())
+			failed++
+			continue
+		}
+		if err = p.ImportBundle(bundle); err != nil {
+			log.Error(err)
+			failed++
+			continue
+		}
+		log.Infof("%simported", p.Prompt())
+		imported.Projects = append(imported.Projects, ps)
+		count++
+	}
+
+	if err = recordBundleState(filepath.Join(rws.AdminDir(), bundleImportState), &imported); err != nil {
+		log.Warnf("fail to record state of import: %s", err)
+	}
+	if failed > 0 {
+		return fmt.Errorf("fail to import %d of %d bundles", failed, count+failed)
+	}
+	log.Notef("imported bundles of %d projects", count)
+
+	if !v.O.NoSync {
+		s := syncCmd
+		s.O.LocalOnly = true
+		if err = s.Execute(nil); err != nil {
+			return fmt.Errorf("fail to check out projects: %s", err)
+		}
+	}
+	return nil
+}
+
+var bundleImportCmd = bundleImportCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bundleImportCmd.Command())
+}
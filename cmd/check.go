@@ -0,0 +1,200 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/alibaba/git-repo-go/color"
+	"github.com/alibaba/git-repo-go/file"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/spf13/cobra"
+)
+
+// checkProjectReport is result of checks of a project.
+type checkProjectReport struct {
+	Name     string   `json:"name"`
+	Path     string   `json:"path"`
+	Revision string   `json:"revision"`
+	Pinned   string   `json:"pinned,omitempty"`
+	Head     string   `json:"head,omitempty"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// checkReport is compliance report of "git repo check".
+type checkReport struct {
+	Manifest string                `json:"manifest"`
+	Time     time.Time             `json:"time"`
+	Fsck     bool                  `json:"fsck"`
+	Passed   bool                  `json:"passed"`
+	Failed   int                   `json:"failed"`
+	Projects []*checkProjectReport `json:"projects"`
+}
+
+// newCheckProjectReport checks pinned commit, copyfile and linkfile, and
+// objects if fsck is set, of project.
+func newCheckProjectReport(p *project.Project, fsck bool) *checkProjectReport {
+	r := checkProjectReport{
+		Name:     p.Name,
+		Path:     p.Path,
+		Revision: p.Revision,
+	}
+	r.Pinned, r.Head, r.Problems = p.CheckPinned()
+	if !p.Exists() {
+		return &r
+	}
+	r.Problems = append(r.Problems, p.CheckCopyAndLinkFiles()...)
+	if fsck {
+		if err := p.Fsck(); err != nil {
+			r.Problems = append(r.Problems, err.Error())
+		}
+	}
+	return &r
+}
+
+// writeCheckReport writes report in text or JSON format.
+func writeCheckReport(w io.Writer, report *checkReport, format string) error {
+	if format == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	}
+
+	for _, r := range report.Projects {
+		if len(r.Problems) == 0 {
+			fmt.Fprintf(w, "%s[ OK ]%s %s\n",
+				color.Color("green", "", ""), color.Reset(), r.Path)
+			continue
+		}
+		for _, problem := range r.Problems {
+			fmt.Fprintf(w, "%s[FAIL]%s %s: %s\n",
+				color.Color("red", "", ""), color.Reset(), r.Path, problem)
+		}
+	}
+	return nil
+}
+
+type checkCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		Fsck       bool
+		Format     string
+		OutputFile string
+	}
+}
+
+func (v *checkCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "check [<project>...]",
+		Short: "Check projects match the manifest",
+		Long: `Check that commit of revision of each project in manifest exists and is
+reachable from remote branches or tags, and is checked out, that files of
+copyfile and linkfile match the manifest, and objects are connected if
+--fsck is given. Write a report in text or JSON format for compliance
+review, and exit with 1 if any check fails.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().BoolVar(&v.O.Fsck,
+		"fsck",
+		false,
+		"also check connectivity of objects by 'git fsck --connectivity-only'")
+	v.cmd.Flags().StringVar(&v.O.Format,
+		"format",
+		"text",
+		"format of report: text or json")
+	v.cmd.Flags().StringVarP(&v.O.OutputFile,
+		"output-file",
+		"o",
+		"-",
+		"file to save the report to")
+
+	return v.cmd
+}
+
+func (v checkCommand) Execute(args []string) error {
+	if v.O.Format != "text" && v.O.Format != "json" {
+		return newUserErrorF("unknown format of report: %s", v.O.Format)
+	}
+
+	ws := v.RepoWorkSpace()
+	projects, err := ws.GetProjects(nil, args...)
+	if err != nil {
+		return err
+	}
+
+	report := checkReport{
+		Manifest: ws.ManifestProject.ManifestName(),
+		Time:     time.Now(),
+		Fsck:     v.O.Fsck,
+		Projects: []*checkProjectReport{},
+	}
+	for _, p := range projects {
+		r := newCheckProjectReport(p, v.O.Fsck)
+		if len(r.Problems) > 0 {
+			report.Failed++
+		}
+		report.Projects = append(report.Projects, r)
+	}
+	report.Passed = report.Failed == 0
+
+	var writer io.Writer = os.Stdout
+	if v.O.OutputFile != "-" {
+		f, err := file.New(v.O.OutputFile).OpenCreateRewrite()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		writer = f
+	}
+	if err = writeCheckReport(writer, &report, v.O.Format); err != nil {
+		return err
+	}
+	if v.O.OutputFile != "-" {
+		log.Notef("saved check report of %d projects (%d failed) to %s",
+			len(report.Projects), report.Failed, v.O.OutputFile)
+	}
+	if report.Failed > 0 {
+		return newExitError(1, fmt.Errorf("%d of %d projects failed to check",
+			report.Failed, len(report.Projects)))
+	}
+	return nil
+}
+
+var checkCmd = checkCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd.Command())
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteCheckReport(t *testing.T) {
+	assert := assert.New(t)
+
+	report := checkReport{
+		Manifest: "default.xml",
+		Failed:   1,
+		Projects: []*checkProjectReport{
+			{Name: "platform/app", Path: "app", Revision: "master"},
+			{
+				Name:     "platform/lib",
+				Path:     "lib",
+				Revision: "refs/tags/v1.0",
+				Problems: []string{"copyfile Makefile is missing"},
+			},
+		},
+	}
+
+	buf := bytes.Buffer{}
+	assert.Nil(writeCheckReport(&buf, &report, "text"))
+	assert.Contains(buf.String(), "[ OK ]")
+	assert.Contains(buf.String(), " app\n")
+	assert.Contains(buf.String(), " lib: copyfile Makefile is missing\n")
+
+	buf.Reset()
+	assert.Nil(writeCheckReport(&buf, &report, "json"))
+	loaded := checkReport{}
+	assert.Nil(json.Unmarshal(buf.Bytes(), &loaded))
+	assert.Equal(report.Projects, loaded.Projects)
+	assert.False(loaded.Passed)
+}
@@ -0,0 +1,105 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/spf13/cobra"
+)
+
+type checkoutCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+}
+
+func (v *checkoutCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "checkout <branch> [<project>...]",
+		Short: "Checkout a branch for development",
+		Long: `Checkout an existing branch that was previously created by
+"git repo start". Projects which do not have the branch are skipped.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+
+	return v.cmd
+}
+
+func (v checkoutCommand) Execute(args []string) error {
+	var (
+		failed    = []string{}
+		found     = []*project.Project{}
+		execError error
+	)
+
+	rws := v.RepoWorkSpace()
+
+	if len(args) == 0 {
+		return newUserError("no args")
+	}
+
+	branch := args[0]
+	allProjects, err := rws.GetProjects(nil, args[1:]...)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range allProjects {
+		if p.RevisionIsValid(config.RefsHeads + branch) {
+			found = append(found, p)
+		}
+	}
+
+	if len(found) == 0 {
+		return fmt.Errorf("no project has branch '%s'", branch)
+	}
+
+	for _, p := range found {
+		err := p.CheckoutBranch(branch)
+		if err != nil {
+			failed = append(failed, p.Path)
+			execError = err
+		}
+	}
+
+	if execError != nil {
+		for _, p := range failed {
+			log.Errorf("cannot checkout branch '%s' for '%s'", branch, p)
+		}
+		return execError
+	}
+	return nil
+}
+
+var checkoutCmd = checkoutCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkoutCmd.Command())
+}
@@ -0,0 +1,159 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/alibaba/git-repo-go/workspace"
+	"github.com/spf13/cobra"
+)
+
+type cherryPickCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		NoCache bool
+		Remote  string
+	}
+}
+
+func (v *cherryPickCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "cherry-pick [<project>] <sha1>|<change>[/<patchset>]",
+		Short: "Cherry-pick a change",
+		Long: `Cherry-pick a commit, or a change from the review system, into the
+current branch of the project.
+
+If no project is given, the project of current directory is used. If
+the current directory is not in a project, the commit is looked up in
+all projects. A number is taken as a change of the review system, use a
+longer SHA to pick a commit whose abbreviation is all digits.
+
+The Change-Id of the original commit is replaced with a new one, so the
+result can be uploaded as a new change.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().BoolVar(&v.O.NoCache,
+		"no-cache",
+		false,
+		"Ignore ssh-info cache, and recheck ssh-info API")
+	v.cmd.Flags().StringVar(&v.O.Remote,
+		"remote",
+		"",
+		"use specific remote to download change (use with --single)")
+
+	return v.cmd
+}
+
+func (v *cherryPickCommand) Execute(args []string) error {
+	p, commit, err := resolveChange(v.WorkSpace(), v.O.Remote, v.O.NoCache, args...)
+	if err != nil {
+		return err
+	}
+	return p.CherryPickChange(commit)
+}
+
+// resolveChange finds the project and commit from args in the form of
+// "[<project>] <sha1>|<change>[/<patchset>]". Change of the review system
+// is downloaded from remote.
+func resolveChange(ws workspace.WorkSpace, remote string, noCache bool, args ...string) (*project.Project, string, error) {
+	var (
+		projects []*project.Project
+		change   string
+		err      error
+	)
+
+	switch len(args) {
+	case 0:
+		return nil, "", newUserError("no args")
+	case 1:
+		change = args[0]
+		projects, err = ws.GetProjects(nil, ".")
+		if (err != nil || len(projects) == 0) && reChange.FindStringSubmatch(change) == nil {
+			projects, err = findProjectsWithCommit(ws, change)
+		}
+	case 2:
+		change = args[1]
+		projects, err = ws.GetProjects(nil, args[0])
+	default:
+		return nil, "", newUserError("too many args")
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if len(projects) == 0 {
+		return nil, "", fmt.Errorf("cannot find project for '%s'", change)
+	}
+	if len(projects) > 1 {
+		return nil, "", fmt.Errorf("'%s' matches more than one project, please specify the project", change)
+	}
+	p := projects[0]
+
+	matches := reChange.FindStringSubmatch(change)
+	if matches == nil {
+		commit, err := p.ResolveCommit(change)
+		return p, commit, err
+	}
+
+	if remote != "" && !ws.IsSingle() {
+		return nil, "", fmt.Errorf("--remote can be only used with --single")
+	}
+	if err = ws.LoadRemotes(noCache); err != nil {
+		return nil, "", err
+	}
+	reviewID, _ := strconv.Atoi(matches[1])
+	patchID, _ := strconv.Atoi(matches[2])
+	dl, err := p.DownloadPatchSet(remote, reviewID, patchID)
+	if err != nil {
+		return nil, "", err
+	}
+	return p, dl.Commit, nil
+}
+
+// findProjectsWithCommit returns projects which have the commit.
+func findProjectsWithCommit(ws workspace.WorkSpace, commit string) ([]*project.Project, error) {
+	allProjects, err := ws.GetProjects(nil)
+	if err != nil {
+		return nil, err
+	}
+	projects := []*project.Project{}
+	for _, p := range allProjects {
+		if _, err := p.ResolveCommit(commit); err == nil {
+			projects = append(projects, p)
+		}
+	}
+	return projects, nil
+}
+
+var cherryPickCmd = cherryPickCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: true,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cherryPickCmd.Command())
+}
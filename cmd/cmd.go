@@ -22,8 +22,9 @@ import (
 	"strings"
 
 	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/workspace"
-	log "github.com/jiangxin/multi-log"
+	"github.com/spf13/cobra"
 )
 
 // WorkSpaceCommand implements load of workspace
@@ -82,10 +83,20 @@ func (v *WorkSpaceCommand) ReloadRepoWorkSpace() *workspace.RepoWorkSpace {
 	return v.RepoWorkSpace()
 }
 
+// jobsOption returns value of --jobs option, or 0 if it is not given,
+// so that jobs of workspace config or manifest are used.
+func jobsOption(cmd *cobra.Command, jobs int) int {
+	if cmd != nil && cmd.Flags().Changed("jobs") {
+		return jobs
+	}
+	return 0
+}
+
 // commandError is an error used to signal different error situations in command handling.
 type commandError struct {
 	s         string
 	userError bool
+	exitCode  int
 }
 
 func (c commandError) Error() string {
@@ -112,6 +123,11 @@ func newSystemErrorF(format string, a ...interface{}) commandError {
 	return commandError{s: fmt.Sprintf(format, a...), userError: false}
 }
 
+// newExitError wraps err with specific exit code.
+func newExitError(code int, err error) commandError {
+	return commandError{s: err.Error(), exitCode: code}
+}
+
 // Catch some of the obvious user errors from Cobra.
 // We don't want to show the usage message for every error.
 // The below may be to generic. Time will show.
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,3 +16,12 @@ func TestMin(t *testing.T) {
 	m = min(200)
 	assert.Equal(200, int(m))
 }
+
+func TestResponseExitCode(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(0, Response{}.ExitCode())
+	assert.Equal(-1, Response{Err: errors.New("error")}.ExitCode())
+	assert.Equal(-1, Response{Err: newUserError("bad args")}.ExitCode())
+	assert.Equal(2, Response{Err: newExitError(2, errors.New("error"))}.ExitCode())
+}
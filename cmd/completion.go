@@ -0,0 +1,342 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/workspace"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// completeCommandName is name of the hidden command which is called
+	// by completion scripts to list candidates.
+	completeCommandName = "__complete"
+
+	completeKindBranch  = "branch"
+	completeKindProject = "project"
+)
+
+const bashCompletionScript = `# bash completion for git-repo
+__git_repo_complete_words () {
+	local IFS=$'\n'
+	COMPREPLY=($(git-repo __complete "$@" 2>/dev/null))
+}
+
+_git_repo_complete () {
+	__git_repo_complete_words "${COMP_WORDS[@]:1:COMP_CWORD}"
+}
+
+# Called by completion of git for "git repo".
+_git_repo () {
+	__git_repo_complete_words "${words[@]:2:cword-1}"
+}
+
+complete -o default -F _git_repo_complete git-repo
+`
+
+const zshCompletionScript = `#compdef git-repo
+# zsh completion for git-repo, also called by completion of git for
+# "git repo".
+_git-repo () {
+	local -a candidates
+	candidates=("${(@f)$(git-repo __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	compadd -a candidates
+}
+
+compdef _git-repo git-repo
+`
+
+const fishCompletionScript = `# fish completion for git-repo
+function __git_repo_complete
+    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    if test "$tokens[1]" = repo
+        set -e tokens[1]
+    end
+    git-repo __complete $tokens (commandline -ct) 2>/dev/null
+end
+
+complete -c git-repo -f -a '(__git_repo_complete)'
+complete -c git -n '__fish_seen_subcommand_from repo' -f -a '(__git_repo_complete)'
+`
+
+var completionScripts = map[string]string{
+	"bash": bashCompletionScript,
+	"zsh":  zshCompletionScript,
+	"fish": fishCompletionScript,
+}
+
+var (
+	// reUsageArg matches arguments in usage of command, such as
+	// "<branch>" and "[<project>...]".
+	reUsageArg = regexp.MustCompile(`<([a-z0-9-]+)>\S*`)
+	// reUsageOption matches options in usage, such as "[-g <groups>]".
+	reUsageOption = regexp.MustCompile(`\[-[^\]]*\]`)
+)
+
+type completionCommand struct {
+	cmd *cobra.Command
+}
+
+func (v *completionCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "completion bash|zsh|fish",
+		Short: "Generate completion script for shell",
+		Long: `Generate completion script for shell, which completes commands,
+options, and names of projects and branches of current workspace.
+
+E.g.:
+
+    source <(git repo completion bash)
+    git repo completion fish > ~/.config/fish/completions/git-repo.fish`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	return v.cmd
+}
+
+func (v completionCommand) Execute(args []string) error {
+	if len(args) != 1 {
+		return newUserError("shell is not given, should be one of: bash, zsh, fish")
+	}
+	script, ok := completionScripts[args[0]]
+	if !ok {
+		return newUserErrorF("unknown shell '%s', should be one of: bash, zsh, fish", args[0])
+	}
+	fmt.Print(script)
+	return nil
+}
+
+// completeCommand lists candidates for completion scripts, one per line.
+// Arguments are words of command line after "git-repo", and the last one
+// is the word to complete.
+type completeCommand struct {
+	cmd *cobra.Command
+}
+
+func (v *completeCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:                completeCommandName,
+		Short:              "List candidates for completion scripts",
+		Hidden:             true,
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	return v.cmd
+}
+
+func (v completeCommand) Execute(args []string) error {
+	for _, candidate := range complete(rootCmd.Command(), args, listCompletion) {
+		fmt.Println(candidate)
+	}
+	return nil
+}
+
+// complete returns candidates for the last word of args. Names of
+// projects and branches are listed by list.
+func complete(root *cobra.Command, args []string, list func(kind string) []string) []string {
+	var (
+		cmd        = root
+		positional = 0
+		current    string
+		candidates []string
+	)
+
+	if len(args) > 0 {
+		current = args[len(args)-1]
+		args = args[:len(args)-1]
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if strings.HasPrefix(arg, "-") {
+			// Skip value of option, such as "-j 4".
+			if flagTakesValue(cmd, arg) {
+				i++
+			}
+			continue
+		}
+		if positional == 0 {
+			if sub := findSubCommand(cmd, arg); sub != nil {
+				cmd = sub
+				continue
+			}
+		}
+		positional++
+	}
+
+	switch {
+	case strings.HasPrefix(current, "-"):
+		candidates = completeFlags(cmd)
+	case positional == 0 && cmd.HasAvailableSubCommands():
+		for _, sub := range cmd.Commands() {
+			if sub.IsAvailableCommand() {
+				candidates = append(candidates, sub.Name())
+			}
+		}
+	default:
+		if kind := usageArgKind(cmd.Use, positional); kind != "" {
+			candidates = list(kind)
+		}
+	}
+
+	result := []string{}
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			result = append(result, candidate)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func findSubCommand(cmd *cobra.Command, name string) *cobra.Command {
+	for _, sub := range cmd.Commands() {
+		if sub.Name() == name || sub.HasAlias(name) {
+			return sub
+		}
+	}
+	return nil
+}
+
+// flagTakesValue checks whether arg is an option which takes value from
+// the next word.
+func flagTakesValue(cmd *cobra.Command, arg string) bool {
+	if strings.Contains(arg, "=") {
+		return false
+	}
+	for _, flags := range []*pflag.FlagSet{
+		cmd.Flags(),
+		cmd.PersistentFlags(),
+		cmd.InheritedFlags(),
+	} {
+		var flag *pflag.Flag
+		if strings.HasPrefix(arg, "--") {
+			flag = flags.Lookup(arg[2:])
+		} else if len(arg) == 2 {
+			flag = flags.ShorthandLookup(arg[1:])
+		}
+		if flag != nil {
+			return flag.NoOptDefVal == ""
+		}
+	}
+	return false
+}
+
+func completeFlags(cmd *cobra.Command) []string {
+	flags := []string{}
+	add := func(flag *pflag.Flag) {
+		if flag.Hidden {
+			return
+		}
+		flags = append(flags, "--"+flag.Name)
+		if flag.Shorthand != "" {
+			flags = append(flags, "-"+flag.Shorthand)
+		}
+	}
+	cmd.NonInheritedFlags().VisitAll(add)
+	cmd.InheritedFlags().VisitAll(add)
+	return flags
+}
+
+// usageArgKind returns kind of the nth argument, which is defined in
+// usage of command, such as "checkout <branch> [<project>...]". The last
+// argument with "..." is repeated.
+func usageArgKind(use string, n int) string {
+	kinds := []string{}
+	repeated := false
+	use = reUsageOption.ReplaceAllString(use, "")
+	for _, m := range reUsageArg.FindAllStringSubmatch(use, -1) {
+		kinds = append(kinds, m[1])
+		repeated = strings.Contains(m[0], "...")
+	}
+	if len(kinds) == 0 {
+		return ""
+	}
+	if n >= len(kinds) {
+		if !repeated {
+			return ""
+		}
+		n = len(kinds) - 1
+	}
+	switch kinds[n] {
+	case completeKindBranch, completeKindProject:
+		return kinds[n]
+	}
+	return ""
+}
+
+// listCompletion lists names of projects or branches of workspace. Errors
+// are ignored, for there is nothing to complete.
+func listCompletion(kind string) []string {
+	ws, err := workspace.NewRepoWorkSpace("")
+	if err != nil {
+		return nil
+	}
+	projects, err := ws.GetProjects(nil)
+	if err != nil {
+		return nil
+	}
+
+	items := []string{}
+	found := make(map[string]bool)
+	add := func(item string) {
+		if item != "" && !found[item] {
+			found[item] = true
+			items = append(items, item)
+		}
+	}
+	for _, p := range projects {
+		switch kind {
+		case completeKindProject:
+			add(p.Path)
+			add(p.Name)
+		case completeKindBranch:
+			if !p.Exists() {
+				continue
+			}
+			for _, head := range p.Heads() {
+				add(head.ShortName())
+			}
+		}
+	}
+	return items
+}
+
+var (
+	completionCmd = completionCommand{}
+	completeCmd   = completeCommand{}
+)
+
+func init() {
+	rootCmd.AddCommand(completionCmd.Command())
+	rootCmd.AddCommand(completeCmd.Command())
+}
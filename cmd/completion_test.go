@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageArgKind(t *testing.T) {
+	assert := assert.New(t)
+
+	use := "checkout <branch> [<project>...]"
+	assert.Equal("branch", usageArgKind(use, 0))
+	assert.Equal("project", usageArgKind(use, 1))
+	assert.Equal("project", usageArgKind(use, 5))
+
+	use = "cherry-pick [<project>] <sha1>|<change>[/<patchset>]"
+	assert.Equal("project", usageArgKind(use, 0))
+	assert.Equal("", usageArgKind(use, 1))
+	assert.Equal("", usageArgKind(use, 2))
+
+	use = "freeze [-g <groups>] [<project>...]"
+	assert.Equal("project", usageArgKind(use, 0))
+	assert.Equal("project", usageArgKind(use, 1))
+
+	assert.Equal("", usageArgKind("sync", 0))
+}
+
+func TestComplete(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		root   = &cobra.Command{Use: "git-repo"}
+		list   = func(kind string) []string {
+			switch kind {
+			case "branch":
+				return []string{"master", "topic"}
+			case "project":
+				return []string{"apps/app", "main", "platform/app"}
+			}
+			return nil
+		}
+	)
+
+	root.PersistentFlags().BoolP("verbose", "v", false, "")
+	root.PersistentFlags().String("logfile", "", "")
+	checkout := &cobra.Command{Use: "checkout <branch> [<project>...]", Run: func(*cobra.Command, []string) {}}
+	checkout.Flags().IntP("jobs", "j", 0, "")
+	checkout.Flags().Bool("all", false, "")
+	sync := &cobra.Command{Use: "sync [<project>...]", Run: func(*cobra.Command, []string) {}}
+	hidden := &cobra.Command{Use: "hidden", Hidden: true, Run: func(*cobra.Command, []string) {}}
+	root.AddCommand(checkout, sync, hidden)
+
+	assert.Equal([]string{"checkout", "sync"}, complete(root, []string{""}, list))
+	assert.Equal([]string{"sync"}, complete(root, []string{"s"}, list))
+	assert.Equal([]string{"master", "topic"}, complete(root, []string{"checkout", ""}, list))
+	assert.Equal([]string{"main"}, complete(root, []string{"checkout", "topic", "m"}, list))
+	assert.Equal([]string{"apps/app", "main", "platform/app"},
+		complete(root, []string{"checkout", "-j", "4", "--all", "topic", ""}, list))
+	assert.Equal([]string{"topic"}, complete(root, []string{"-v", "checkout", "-j", "4", "t"}, list))
+	assert.Equal([]string{"sync"}, complete(root, []string{"--logfile", "sync.log", "s"}, list))
+	assert.Equal([]string{"--all", "--jobs", "--logfile", "--verbose"}, complete(root, []string{"checkout", "--"}, list))
+	assert.Equal([]string{"apps/app", "main", "platform/app"}, complete(root, []string{"sync", ""}, list))
+}
@@ -0,0 +1,159 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/path"
+	"github.com/alibaba/git-repo-go/version"
+)
+
+const (
+	// crashDir is where crash reports are saved, in ".repo" of workspace,
+	// or in config dir of git-repo if not in a workspace.
+	crashDir = "crash"
+	// crashExitCode is EX_SOFTWARE of sysexits.h, which differs from exit
+	// codes of sync with --detailed-exit-code.
+	crashExitCode = 70
+	// crashStackSize is max size of goroutine dump.
+	crashStackSize = 1024 * 1024
+
+	crashRedacted = "<redacted>"
+)
+
+var (
+	// reSecretURL matches userinfo in URL, such as "https://user:pass@"
+	// or "https://token@".
+	reSecretURL = regexp.MustCompile(`(://)[^/@\s]+@`)
+	// reSecretName matches name of option or variable which has secret.
+	reSecretName = regexp.MustCompile(`(?i)(password|passwd|username|token|secret|credential|private-key)`)
+	// secretShortOptions are short options which have secrets, such as
+	// "-u <username>" and "-p <password>" of sync.
+	secretShortOptions = []string{"-u", "-p"}
+)
+
+// secretShortOption returns the short option which has secret if arg is
+// that option, with or without value.
+func secretShortOption(arg string) string {
+	for _, short := range secretShortOptions {
+		if strings.HasPrefix(arg, short) {
+			return short
+		}
+	}
+	return ""
+}
+
+// redactArgs removes secrets from command line args, such as password in
+// URLs and values of options like "--token".
+func redactArgs(args []string) []string {
+	result := []string{}
+	redactNext := false
+	for _, arg := range args {
+		if redactNext {
+			result = append(result, crashRedacted)
+			redactNext = false
+			continue
+		}
+		arg = reSecretURL.ReplaceAllString(arg, "${1}"+crashRedacted+"@")
+		if short := secretShortOption(arg); short != "" {
+			if arg == short {
+				redactNext = true
+			} else {
+				// Value is given in the same arg, such as "-pabc".
+				arg = short + crashRedacted
+			}
+		} else if kv := strings.SplitN(arg, "=", 2); len(kv) == 2 && reSecretName.MatchString(kv[0]) {
+			arg = kv[0] + "=" + crashRedacted
+		} else if strings.HasPrefix(arg, "-") && reSecretName.MatchString(arg) {
+			redactNext = true
+		}
+		result = append(result, arg)
+	}
+	return result
+}
+
+// crashReport returns content of crash report.
+func crashReport(r interface{}, args []string, stack []byte) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString(fmt.Sprintf("panic: %v\n\n", r))
+	buf.WriteString(fmt.Sprintf("time: %s\n", time.Now().Format(time.RFC3339)))
+	buf.WriteString(fmt.Sprintf("command: %s\n", strings.Join(redactArgs(args), " ")))
+	buf.WriteString(fmt.Sprintf("git-repo version: %s\n", version.GetVersion()))
+	buf.WriteString(fmt.Sprintf("git version: %s\n", version.GetGitVersion()))
+	buf.WriteString(fmt.Sprintf("go version: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH))
+	buf.WriteString("\ngoroutines:\n\n")
+	buf.Write(stack)
+	return buf.Bytes()
+}
+
+// saveCrashReport saves crash report in dir, and returns its filename.
+func saveCrashReport(dir string, report []byte) (string, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+	filename := filepath.Join(dir,
+		fmt.Sprintf("crash-%s-%d.txt", time.Now().Format("20060102-150405"), os.Getpid()))
+	// Report may have paths and names of projects, which is only
+	// readable by the owner.
+	if err = ioutil.WriteFile(filename, report, 0600); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+// crashReportDir returns dir to save crash reports.
+func crashReportDir() string {
+	if topDir, err := path.FindTopDir(""); err == nil {
+		return filepath.Join(topDir, config.DotRepo, crashDir)
+	}
+	if configDir, err := config.GetConfigDir(); err == nil {
+		return filepath.Join(configDir, crashDir)
+	}
+	return filepath.Join(os.TempDir(), "git-repo-"+crashDir)
+}
+
+// recoverCrash recovers from panic of command, saves a crash report and
+// exits. Panic is raised again if fail to save the report. It is deferred
+// in every goroutine of workers, for panic cannot be recovered in other
+// goroutines.
+func recoverCrash() {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	stack := make([]byte, crashStackSize)
+	stack = stack[:runtime.Stack(stack, true)]
+	filename, err := saveCrashReport(crashReportDir(), crashReport(r, os.Args, stack))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fail to save crash report: %s\n", err)
+		panic(r)
+	}
+	fmt.Fprintf(os.Stderr, "FATAL: git-repo crashed: %v\n", r)
+	fmt.Fprintf(os.Stderr, "Crash report is saved in '%s', please attach it when filing a bug.\n", filename)
+	os.Exit(crashExitCode)
+}
@@ -0,0 +1,300 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alibaba/git-repo-go/cap"
+	"github.com/alibaba/git-repo-go/color"
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/path"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/alibaba/git-repo-go/version"
+	"github.com/alibaba/git-repo-go/workspace"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// doctorMinDiskFree is free space of disk below which check fails.
+	doctorMinDiskFree = 1024 * 1024 * 1024
+	// doctorRemoteTimeout limits time to connect to a remote.
+	doctorRemoteTimeout = 30 * time.Second
+)
+
+// doctorCheck is result of a check of "git repo doctor".
+type doctorCheck struct {
+	Name string
+	// Err is nil if check passes.
+	Err error
+	// Warning indicates failure of check is not fatal.
+	Warning bool
+	// Fix is suggestion to fix the failure.
+	Fix string
+}
+
+type doctorCommand struct {
+	cmd *cobra.Command
+	O   struct {
+		NoNetwork bool
+	}
+}
+
+func (v *doctorCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose environment and workspace",
+		Long: `Check version of git, disk space, connectivity and credentials
+of remotes, consistency of ".repo" and validity of manifest, and show a
+checklist with suggestions to fix failures.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().BoolVar(&v.O.NoNetwork,
+		"no-network",
+		false,
+		"do not check connectivity of remotes")
+
+	return v.cmd
+}
+
+func (v doctorCommand) Execute(args []string) error {
+	checks := []doctorCheck{checkDoctorGitVersion()}
+
+	topDir, err := path.FindTopDir("")
+	if err != nil {
+		checks = append(checks, doctorCheck{
+			Name:    "repo workspace",
+			Err:     errors.New("not in a repo workspace"),
+			Warning: true,
+			Fix:     "run 'git repo init' to create a workspace, or run in a workspace",
+		})
+	} else {
+		checks = append(checks, checkDoctorDiskFree(topDir))
+		ws, err := workspace.NewRepoWorkSpace(topDir)
+		if err != nil {
+			checks = append(checks, doctorCheck{
+				Name: "manifest",
+				Err:  err,
+				Fix:  "fix the manifest, or run 'git repo init' to switch to another one",
+			})
+		} else {
+			checks = append(checks, doctorCheck{Name: "manifest"})
+			checks = append(checks, checkDoctorWorkSpace(ws)...)
+			if !v.O.NoNetwork {
+				checks = append(checks, checkDoctorRemotes(ws)...)
+			}
+		}
+	}
+
+	failed := showDoctorChecks(os.Stdout, checks)
+	if failed > 0 {
+		return newExitError(1, fmt.Errorf("%d checks failed", failed))
+	}
+	return nil
+}
+
+// showDoctorChecks shows checklist, and returns number of failed checks.
+func showDoctorChecks(w io.Writer, checks []doctorCheck) int {
+	failed := 0
+	for _, check := range checks {
+		switch {
+		case check.Err == nil:
+			fmt.Fprintf(w, "%s[ OK ]%s %s\n",
+				color.Color("green", "", ""), color.Reset(), check.Name)
+			continue
+		case check.Warning:
+			fmt.Fprintf(w, "%s[WARN]%s %s: %s\n",
+				color.Color("yellow", "", ""), color.Reset(), check.Name, check.Err)
+		default:
+			failed++
+			fmt.Fprintf(w, "%s[FAIL]%s %s: %s\n",
+				color.Color("red", "", ""), color.Reset(), check.Name, check.Err)
+		}
+		if check.Fix != "" {
+			fmt.Fprintf(w, "       fix: %s\n", check.Fix)
+		}
+	}
+	return failed
+}
+
+func checkDoctorGitVersion() doctorCheck {
+	check := doctorCheck{Name: "git version " + version.GitVersion}
+	if version.GitVersion == "" {
+		check.Name = "git version"
+		check.Err = errors.New("git is not found")
+		check.Fix = "install git"
+		return check
+	}
+	lcVersion, hcVersion, messages := version.CheckGitVersion(version.GitVersion)
+	if lcVersion != "" {
+		check.Err = errors.New(strings.Join(messages, "; "))
+		check.Fix = fmt.Sprintf("upgrade git to version %s or above", lcVersion)
+	} else if hcVersion != "" {
+		check.Err = errors.New(strings.Join(messages, "; "))
+		check.Warning = true
+		check.Fix = fmt.Sprintf("upgrade git to version %s or above", hcVersion)
+	}
+	return check
+}
+
+func checkDoctorDiskFree(dir string) doctorCheck {
+	check := doctorCheck{Name: "disk space"}
+	free, err := cap.DiskFree(dir)
+	if err != nil {
+		check.Err = err
+		check.Warning = true
+		return check
+	}
+	check.Name = fmt.Sprintf("disk space (%d MiB free)", free>>20)
+	if free < doctorMinDiskFree {
+		check.Err = fmt.Errorf("less than %d MiB free", doctorMinDiskFree>>20)
+		check.Fix = "free up disk space, or run 'git repo gc' to remove unused objects"
+	}
+	return check
+}
+
+// checkDoctorWorkSpace checks lock files of ".repo", and links of
+// gitdirs of projects.
+func checkDoctorWorkSpace(ws *workspace.RepoWorkSpace) []doctorCheck {
+	checks := []doctorCheck{}
+
+	lockDir := filepath.Join(ws.AdminDir(), config.Locks)
+	files, _ := filepath.Glob(filepath.Join(lockDir, "*.lock"))
+	for _, file := range files {
+		lock := helper.NewLockFile(file)
+		check := doctorCheck{Name: "lock " + file}
+		if lock.IsStale() {
+			check.Err = errors.New("stale lock")
+			check.Fix = fmt.Sprintf("remove '%s'", file)
+		} else if info, err := lock.Owner(); err == nil {
+			check.Err = fmt.Errorf("held by %s", info)
+			check.Warning = true
+		} else {
+			continue
+		}
+		checks = append(checks, check)
+	}
+
+	projects, err := ws.GetProjects(&workspace.GetProjectsOptions{MissingOK: true})
+	if err != nil {
+		return append(checks, doctorCheck{Name: "projects", Err: err})
+	}
+	broken := 0
+	for _, p := range projects {
+		if err := p.CheckGitDir(); err != nil {
+			broken++
+			checks = append(checks, doctorCheck{
+				Name: "gitdir of " + p.Path,
+				Err:  err,
+				Fix:  fmt.Sprintf("remove '%s' and run 'git repo sync %s'", p.Path, p.Path),
+			})
+		}
+		for _, file := range p.GitLockFiles() {
+			checks = append(checks, doctorCheck{
+				Name: "git lock of " + p.Path,
+				Err:  fmt.Errorf("found '%s'", file),
+				Fix:  fmt.Sprintf("remove '%s' if no git command is running", file),
+			})
+		}
+	}
+	if broken == 0 {
+		checks = append(checks, doctorCheck{
+			Name: fmt.Sprintf("gitdir of %d projects", len(projects)),
+		})
+	}
+	return checks
+}
+
+// checkDoctorRemotes connects to each remote of manifest by "git ls-remote"
+// on one of its projects, which also checks credentials.
+func checkDoctorRemotes(ws *workspace.RepoWorkSpace) []doctorCheck {
+	checks := []doctorCheck{}
+	projects, err := ws.GetProjects(&workspace.GetProjectsOptions{MissingOK: true})
+	if err != nil {
+		return checks
+	}
+
+	found := make(map[string]bool)
+	for _, p := range projects {
+		if found[p.RemoteName] {
+			continue
+		}
+		found[p.RemoteName] = true
+		checks = append(checks, checkDoctorRemote(p))
+	}
+	return checks
+}
+
+func checkDoctorRemote(p *project.Project) doctorCheck {
+	check := doctorCheck{Name: fmt.Sprintf("remote '%s'", p.RemoteName)}
+	u, err := p.GetRemoteURL()
+	if err != nil {
+		check.Err = err
+		return check
+	}
+	check.Name = fmt.Sprintf("remote '%s' (%s)", p.RemoteName, u)
+
+	env := []string{"GIT_TERMINAL_PROMPT=0"}
+	if os.Getenv("GIT_SSH_COMMAND") == "" && os.Getenv("GIT_SSH") == "" {
+		env = append(env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+	}
+	stderr := bytes.Buffer{}
+	_, err = helper.RunCommand(&helper.Command{
+		Args:    []string{config.GIT, "ls-remote", u, "HEAD"},
+		Env:     env,
+		Stderr:  &stderr,
+		Timeout: doctorRemoteTimeout,
+	})
+	if err == nil {
+		return check
+	}
+
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		lines := strings.Split(msg, "\n")
+		err = errors.New(lines[len(lines)-1])
+	}
+	check.Err = fmt.Errorf("fail to connect: %s", err)
+	gitURL := config.ParseGitURL(u)
+	if gitURL != nil && gitURL.IsSSH() {
+		target := gitURL.UserHost()
+		if gitURL.Port > 0 {
+			target = fmt.Sprintf("-p %d %s", gitURL.Port, target)
+		}
+		check.Fix = fmt.Sprintf("check ssh keys by 'ssh -T %s'", target)
+	} else {
+		check.Fix = "check network and proxy, and save credentials by git config 'credential.helper'"
+	}
+	return check
+}
+
+var doctorCmd = doctorCommand{}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd.Command())
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShowDoctorChecks(t *testing.T) {
+	assert := assert.New(t)
+
+	checks := []doctorCheck{
+		doctorCheck{Name: "manifest"},
+		doctorCheck{
+			Name:    "git version 2.9.1",
+			Err:     errors.New("old git"),
+			Warning: true,
+			Fix:     "upgrade git",
+		},
+		doctorCheck{
+			Name: "remote 'origin'",
+			Err:  errors.New("fail to connect"),
+		},
+	}
+
+	buf := bytes.Buffer{}
+	assert.Equal(1, showDoctorChecks(&buf, checks))
+	assert.Contains(buf.String(), "[ OK ]")
+	assert.Contains(buf.String(), " manifest\n")
+	assert.Contains(buf.String(), " git version 2.9.1: old git\n       fix: upgrade git\n")
+	assert.Contains(buf.String(), " remote 'origin': fail to connect\n")
+}
@@ -21,8 +21,8 @@ import (
 
 	"github.com/alibaba/git-repo-go/config"
 	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/project"
-	log "github.com/jiangxin/multi-log"
 	"github.com/spf13/cobra"
 )
 
@@ -56,8 +56,11 @@ func (v *downloadCommand) Command() *cobra.Command {
 	}
 
 	v.cmd = &cobra.Command{
-		Use:   "download",
+		Use:   "download [<project>] <change>[/<patchset>]...",
 		Short: "Download and checkout a code review",
+		Long: `Download a change from the review system and make it available
+in your project's local working directory. If no patchset is specified
+for gerrit, the latest patch set of the change is downloaded.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return v.Execute(args)
 		},
@@ -222,6 +225,20 @@ func (v *downloadCommand) Execute(args []string) error {
 		if err != nil {
 			return err
 		}
+
+		vars := map[string]string{
+			"change": strconv.Itoa(c.ReviewID),
+		}
+		if c.PatchID != 0 {
+			vars["patchset"] = strconv.Itoa(c.PatchID)
+		}
+		remote := c.Project.GetDefaultRemote(true)
+		if v.O.Remote != "" {
+			remote = c.Project.Remotes.Get(v.O.Remote)
+		}
+		if u := c.Project.ReviewURL(remote, vars); u != "" {
+			log.Notef("[%s] change %s: %s", c.Project.Name, changeID, u)
+		}
 	}
 
 	return nil
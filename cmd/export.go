@@ -0,0 +1,278 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/alibaba/git-repo-go/workspace"
+	"github.com/spf13/cobra"
+)
+
+// exportManifestName is name of manifest snapshot in exported archives.
+const exportManifestName = "manifest.xml"
+
+// isGzipFile indicates whether file should be compressed by its name.
+func isGzipFile(name string) bool {
+	return strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")
+}
+
+// exportArchiveName returns filename of archive of project, for
+// "--per-project" mode.
+func exportArchiveName(p *project.Project) string {
+	return strings.Replace(strings.Trim(p.Path, "/"), "/", "_", -1) + ".tar.gz"
+}
+
+// createExportFile creates file, which is compressed if name ends with
+// ".tar.gz" or ".tgz". Call the returned function to close file.
+func createExportFile(name string) (io.Writer, func() error, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isGzipFile(name) {
+		return f, f.Close, nil
+	}
+	gz := gzip.NewWriter(f)
+	return gz, func() error {
+		err := gz.Close()
+		if e := f.Close(); err == nil {
+			err = e
+		}
+		return err
+	}, nil
+}
+
+// copyTar copies entries of tar stream r to tw, except pax global header,
+// which git archive uses to save commit ID.
+func copyTar(tw *tar.Writer, r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		if err = tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err = io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// writeTarFile writes a regular file to tw.
+func writeTarFile(tw *tar.Writer, name string, data []byte) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(data)
+	return err
+}
+
+type exportCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		Output     string
+		PerProject bool
+	}
+}
+
+func (v *exportCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "export -o <file> [<project>...]",
+		Short: "Export source of projects without git metadata",
+		Long: `Export files of projects at their current HEAD as a tarball, in which
+each project is in its path of workspace, and ".git" directories are not
+included. A snapshot of manifest with projects pinned to the exported
+commits is saved as "` + exportManifestName + `" at the top of the tarball.
+
+The tarball is compressed if name of output file ends with ".tar.gz" or
+".tgz". With --per-project, output is a directory, where each project is
+saved as a separate compressed archive, with the manifest snapshot beside.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().StringVarP(&v.O.Output,
+		"output",
+		"o",
+		"",
+		"file (or directory for --per-project) to save exported source")
+	v.cmd.Flags().BoolVar(&v.O.PerProject,
+		"per-project",
+		false,
+		"save an archive for each project")
+
+	return v.cmd
+}
+
+// manifestSnapshot returns manifest with revisions of projects pinned to
+// the current HEAD.
+func (v exportCommand) manifestSnapshot() ([]byte, error) {
+	rws := v.RepoWorkSpace()
+	if err := rws.FreezeManifest(true, true); err != nil {
+		return nil, err
+	}
+	return rws.Manifest.Marshal()
+}
+
+// exportTarball saves projects and manifest snapshot in one tarball.
+func (v exportCommand) exportTarball(projects []*project.Project, commits []string, snapshot []byte) error {
+	w, closeFile, err := createExportFile(v.O.Output)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(w)
+
+	err = writeTarFile(tw, exportManifestName, snapshot)
+	for i, p := range projects {
+		if err != nil {
+			break
+		}
+		log.Infof("%sexport %s", p.Prompt(), commits[i])
+		r, pw := io.Pipe()
+		go func(p *project.Project, commit string) {
+			defer recoverCrash()
+			pw.CloseWithError(p.Archive(pw, commit, p.Path+"/"))
+		}(p, commits[i])
+		err = copyTar(tw, r)
+		if err == nil {
+			// Drain padding of tar stream, and get error of git archive.
+			_, err = io.Copy(ioutil.Discard, r)
+		}
+		r.CloseWithError(err)
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if e := closeFile(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(v.O.Output)
+	}
+	return err
+}
+
+// exportPerProject saves each project in an archive in output directory,
+// beside the manifest snapshot.
+func (v exportCommand) exportPerProject(projects []*project.Project, commits []string, snapshot []byte) error {
+	err := os.MkdirAll(v.O.Output, 0755)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(v.O.Output, exportManifestName), snapshot, 0644)
+	if err != nil {
+		return err
+	}
+
+	for i, p := range projects {
+		name := filepath.Join(v.O.Output, exportArchiveName(p))
+		log.Infof("%sexport %s to %s", p.Prompt(), commits[i], name)
+		w, closeFile, err := createExportFile(name)
+		if err != nil {
+			return err
+		}
+		err = p.Archive(w, commits[i], p.Path+"/")
+		if e := closeFile(); err == nil {
+			err = e
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v exportCommand) Execute(args []string) error {
+	if v.O.Output == "" {
+		return newUserError("no output file, use -o to set one")
+	}
+
+	rws := v.RepoWorkSpace()
+	projects, err := rws.GetProjects(&workspace.GetProjectsOptions{
+		Groups: rws.Settings().Groups,
+	}, args...)
+	if err != nil {
+		return err
+	}
+
+	// Pin commits before creating the manifest snapshot, so that they
+	// are consistent.
+	commits := []string{}
+	for _, p := range projects {
+		commit, err := p.ResolveRevision("HEAD")
+		if err != nil {
+			return fmt.Errorf("%sfail to resolve HEAD: %s", p.Prompt(), err)
+		}
+		commits = append(commits, commit)
+	}
+	snapshot, err := v.manifestSnapshot()
+	if err != nil {
+		return err
+	}
+
+	if v.O.PerProject {
+		err = v.exportPerProject(projects, commits, snapshot)
+	} else {
+		err = v.exportTarball(projects, commits, snapshot)
+	}
+	if err != nil {
+		return err
+	}
+	log.Notef("exported %d projects to %s", len(projects), v.O.Output)
+	return nil
+}
+
+var exportCmd = exportCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd.Command())
+}
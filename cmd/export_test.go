@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportArchiveName(t *testing.T) {
+	assert := assert.New(t)
+
+	p := project.Project{Repository: project.Repository{Project: manifest.Project{Path: "platform/build/"}}}
+	assert.Equal("platform_build.tar.gz", exportArchiveName(&p))
+	assert.True(isGzipFile("src.tar.gz"))
+	assert.True(isGzipFile("src.tgz"))
+	assert.False(isGzipFile("src.tar"))
+}
+
+func TestCopyTar(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		src    bytes.Buffer
+		dst    bytes.Buffer
+	)
+
+	tw := tar.NewWriter(&src)
+	err := tw.WriteHeader(&tar.Header{
+		Typeflag:   tar.TypeXGlobalHeader,
+		Name:       "pax_global_header",
+		PAXRecords: map[string]string{"comment": "0123456789abcdef"},
+	})
+	assert.Nil(err)
+	assert.Nil(writeTarFile(tw, "a/README.md", []byte("hello\n")))
+	assert.Nil(tw.Close())
+
+	tw = tar.NewWriter(&dst)
+	assert.Nil(writeTarFile(tw, exportManifestName, []byte("<manifest/>\n")))
+	assert.Nil(copyTar(tw, &src))
+	assert.Nil(tw.Close())
+
+	names := []string{}
+	tr := tar.NewReader(&dst)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(err)
+		names = append(names, hdr.Name)
+		if hdr.Name == "a/README.md" {
+			data, err := ioutil.ReadAll(tr)
+			assert.Nil(err)
+			assert.Equal("hello\n", string(data))
+		}
+	}
+	assert.Equal([]string{exportManifestName, "a/README.md"}, names)
+}
@@ -12,9 +12,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/path"
 	"github.com/jiangxin/goconfig"
-	"github.com/jiangxin/multi-log"
 )
 
 type keywordSubstFilterDriver struct {
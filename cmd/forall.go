@@ -16,18 +16,24 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"regexp"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/alibaba/git-repo-go/color"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/path"
 	"github.com/alibaba/git-repo-go/project"
 	"github.com/alibaba/git-repo-go/workspace"
-	log "github.com/jiangxin/multi-log"
 	"github.com/spf13/cobra"
 )
 
+const (
+	// forallDefaultJobs is the default value of --jobs
+	forallDefaultJobs = 1
+)
+
 type forallCommand struct {
 	WorkSpaceCommand
 
@@ -39,6 +45,7 @@ type forallCommand struct {
 		ProjectHeader bool
 		Command       string
 		Groups        string
+		Platform      string
 		Jobs          int
 	}
 }
@@ -71,6 +78,10 @@ func (v *forallCommand) Command() *cobra.Command {
 		"g",
 		"",
 		"Execute the command only on projects matching the specified groups")
+	v.cmd.Flags().StringVar(&v.O.Platform,
+		"platform",
+		"",
+		"Select projects in platform groups [auto|all|none|linux|darwin|windows] (default: platform of workspace)")
 	v.cmd.Flags().StringVarP(&v.O.Command,
 		"command",
 		"c",
@@ -80,7 +91,7 @@ func (v *forallCommand) Command() *cobra.Command {
 		"abort-on-errors",
 		"e",
 		false,
-		"Abort if a command exits unsuccessfully")
+		"Abort if a command exits unsuccessfully, otherwise continue and report failed projects")
 	v.cmd.Flags().BoolVarP(&v.O.ProjectHeader,
 		"project-header",
 		"p",
@@ -89,20 +100,19 @@ func (v *forallCommand) Command() *cobra.Command {
 	v.cmd.Flags().IntVarP(&v.O.Jobs,
 		"jobs",
 		"j",
-		1,
-		"number of commands to execute simultaneously")
+		0,
+		fmt.Sprintf("number of commands to execute simultaneously "+
+			"(default: repo.jobs of workspace, sync-j of manifest, or %d)",
+			forallDefaultJobs))
 
 	return v.cmd
 }
 
 func (v forallCommand) Execute(args []string) error {
 	var (
-		cmds        []string
-		allProjects []*project.Project
-		projects    []*project.Project
-		err         error
-		inverseMode bool
-		patterns    []*regexp.Regexp
+		cmds     []string
+		projects []*project.Project
+		err      error
 	)
 
 	ws := v.RepoWorkSpace()
@@ -116,61 +126,18 @@ func (v forallCommand) Execute(args []string) error {
 	if len(cmds) == 0 {
 		return fmt.Errorf("no command provided")
 	}
-	if v.O.Jobs < 1 {
-		v.O.Jobs = 1
-	}
-
-	inverseMode = len(v.O.InverseRegex) > 0
-	if inverseMode && len(v.O.Regex) > 0 {
-		return fmt.Errorf("--regex and --inverse-regex cannot be used together")
-	}
+	v.O.Jobs = ws.Jobs(jobsOption(v.cmd, v.O.Jobs), forallDefaultJobs)
 
-	allProjects, err = ws.GetProjects(&workspace.GetProjectsOptions{
-		Groups: v.O.Groups,
+	projects, err = ws.GetProjects(&workspace.GetProjectsOptions{
+		Groups:       v.O.Groups,
+		Platform:     v.O.Platform,
+		Regex:        v.O.Regex,
+		InverseRegex: v.O.InverseRegex,
 	})
 	if err != nil {
 		return err
 	}
 
-	for _, r := range v.O.Regex {
-		re, err := regexp.Compile(r)
-		if err != nil {
-			log.Warnf("cannot compile regex pattern %s: %s", r, err)
-			continue
-		}
-
-		patterns = append(patterns, re)
-	}
-	for _, r := range v.O.InverseRegex {
-		re, err := regexp.Compile(r)
-		if err != nil {
-			log.Warnf("cannot compile regex pattern %s: %s", r, err)
-			continue
-		}
-
-		patterns = append(patterns, re)
-	}
-
-	if len(patterns) == 0 {
-		projects = allProjects
-	} else {
-		for _, p := range allProjects {
-			matched := false
-			for _, re := range patterns {
-				if re.MatchString(p.Name) || re.MatchString(p.Path) {
-					matched = true
-					if !inverseMode {
-						projects = append(projects, p)
-					}
-					break
-				}
-			}
-			if inverseMode && !matched {
-				projects = append(projects, p)
-			}
-		}
-	}
-
 	if len(projects) == 0 {
 		log.Infof("no projects")
 		return nil
@@ -180,14 +147,21 @@ func (v forallCommand) Execute(args []string) error {
 }
 
 func (v forallCommand) RunCommand(projects []*project.Project, cmds []string) error {
+	type indexedResult struct {
+		idx    int
+		result *project.CmdExecResult
+	}
+
 	var (
 		jobs       = v.O.Jobs
 		jobTasks   = make(chan int, jobs)
-		jobResults = make(chan *project.CmdExecResult, jobs)
+		jobResults = make(chan indexedResult, jobs)
+		abort      = make(chan struct{})
+		abortOnce  sync.Once
+		wg         sync.WaitGroup
+		failed     []string
 	)
 
-	os.Setenv("REPO_COUNT", strconv.Itoa(len(projects)))
-
 	if !regexp.MustCompile(`^[a-z0-9A-Z_/\.-]+$`).MatchString(cmds[0]) {
 		shellCmd := []string{
 			"sh",
@@ -199,32 +173,88 @@ func (v forallCommand) RunCommand(projects []*project.Project, cmds []string) er
 	}
 
 	worker := func(i int) {
+		defer recoverCrash()
+		defer wg.Done()
 		log.Debugf("start command worker #%d", i)
 		for idx := range jobTasks {
-			jobResults <- v.executeCommand(projects[idx], cmds)
+			select {
+			case <-abort:
+				continue
+			default:
+			}
+			result := v.executeCommand(projects[idx], len(projects), cmds)
+			if result != nil && !result.Success() && v.O.AbortOnErrors {
+				abortOnce.Do(func() { close(abort) })
+			}
+			jobResults <- indexedResult{idx, result}
 		}
 	}
 
+	wg.Add(jobs)
 	for i := 0; i < jobs; i++ {
 		go worker(i)
 	}
 
 	go func() {
+		defer close(jobTasks)
 		for i := 0; i < len(projects); i++ {
-			jobTasks <- i
+			select {
+			case jobTasks <- i:
+			case <-abort:
+				return
+			}
 		}
-		close(jobTasks)
 	}()
 
-	count := len(projects)
-	for i := 0; i < count; i++ {
-		result := <-jobResults
-		if result == nil {
-			continue
+	go func() {
+		wg.Wait()
+		close(jobResults)
+	}()
+
+	// Show results in the order of projects, not in the order of
+	// finished jobs, so that output is stable.
+	var (
+		count    = len(projects)
+		results  = make([]*project.CmdExecResult, len(projects))
+		finished = make([]bool, len(projects))
+		next     = 0
+		shown    = 0
+	)
+	// Projects not finished are skipped if all is true, such as tasks
+	// are not started for abort.
+	flush := func(all bool) {
+		for ; next < len(projects); next++ {
+			if !finished[next] && !all {
+				break
+			}
+			result := results[next]
+			if result == nil {
+				count--
+				continue
+			}
+			v.showResult(result, shown, count)
+			shown++
+			if !result.Success() {
+				failed = append(failed, result.Project.Path)
+			}
 		}
-		v.showResult(result, i, count)
 	}
-	return nil
+	for r := range jobResults {
+		results[r.idx] = r.result
+		finished[r.idx] = true
+		flush(false)
+	}
+	flush(true)
+
+	if len(failed) == 0 {
+		return nil
+	}
+	if v.O.AbortOnErrors {
+		return fmt.Errorf("aborted: command failed in %s", failed[0])
+	}
+	return fmt.Errorf("command failed in %d project(s): %s",
+		len(failed),
+		strings.Join(failed, ", "))
 }
 
 func (v forallCommand) showResult(result *project.CmdExecResult, i, count int) {
@@ -270,7 +300,25 @@ func (v forallCommand) showResult(result *project.CmdExecResult, i, count int) {
 	}
 }
 
-func (v forallCommand) executeCommand(p *project.Project, cmds []string) *project.CmdExecResult {
+// projectEnv returns environments exported to command running in project.
+func projectEnv(p *project.Project, count int) []string {
+	lrev, err := p.ResolveRemoteTracking(p.Revision)
+	if err != nil {
+		log.Debugf("%sfail to resolve revision: %s", p.Prompt(), err)
+		lrev = ""
+	}
+	env := []string{
+		"REPO_COUNT=" + strconv.Itoa(count),
+		"REPO_PROJECT=" + p.Name,
+		"REPO_PATH=" + p.Path,
+		"REPO_REMOTE=" + p.RemoteName,
+		"REPO_LREV=" + lrev,
+		"REPO_RREV=" + p.Revision,
+	}
+	return append(env, p.AnnotationEnv()...)
+}
+
+func (v forallCommand) executeCommand(p *project.Project, count int, cmds []string) *project.CmdExecResult {
 	workdir := p.WorkDir
 	if p.IsMirror() {
 		workdir = p.GitDir
@@ -280,11 +328,7 @@ func (v forallCommand) executeCommand(p *project.Project, cmds []string) *projec
 		return nil
 	}
 
-	os.Setenv("REPO_PROJECT", p.Name)
-	os.Setenv("REPO_PATH", p.Path)
-	os.Setenv("REPO_REMOTE", p.RemoteName)
-
-	return p.ExecuteCommand(cmds...)
+	return p.ExecuteCommandWithEnv(projectEnv(p, count), cmds...)
 }
 
 var forallCmd = forallCommand{
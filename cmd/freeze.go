@@ -0,0 +1,236 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/alibaba/git-repo-go/workspace"
+	"github.com/spf13/cobra"
+)
+
+// Files in archive of workspace snapshot.
+const (
+	snapshotStateFile  = "snapshot.json"
+	snapshotBundlesDir = "bundles"
+	snapshotDiffsDir   = "diffs"
+)
+
+// workspaceSnapshot is saved in archive of "git repo freeze", with
+// manifest, bundles of local commits and patches of uncommitted changes.
+type workspaceSnapshot struct {
+	Time     time.Time           `json:"time"`
+	Projects []*project.Snapshot `json:"projects"`
+}
+
+// snapshotBundleName returns name of bundle of project in archive.
+func snapshotBundleName(path string) string {
+	return snapshotBundlesDir + "/" + path + ".bundle"
+}
+
+// snapshotDiffName returns name of patch of project in archive.
+func snapshotDiffName(path string) string {
+	return snapshotDiffsDir + "/" + path + ".patch"
+}
+
+// snapshotBaseProject pins projects of manifest to base commits of
+// snapshot, which can be fetched from remote.
+type snapshotBaseProject struct {
+	bases map[string]string
+}
+
+func (v snapshotBaseProject) Process(mp *manifest.Project, parentDir string) error {
+	if parentDir == "" {
+		parentDir = mp.Path
+	} else {
+		parentDir = filepath.Join(parentDir, mp.Path)
+	}
+	if base, ok := v.bases[parentDir]; ok {
+		mp.Revision = base
+	}
+	return nil
+}
+
+type freezeCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		Output string
+	}
+}
+
+func (v *freezeCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "freeze -o <file> [<project>...]",
+		Short: "Save state of workspace to restore on another machine",
+		Long: `Save state of workspace in an archive, which is restored by "git repo
+thaw" in another workspace, for hand-offs between developers. The archive
+has a manifest with projects pinned to commits in remote, local branches
+and HEAD of projects, bundles of local commits which are not in remote,
+patches of uncommitted changes of tracked files, and list of stashes.
+
+Untracked files and stashes are not restored. The archive is compressed
+if name of output file ends with ".tar.gz" or ".tgz".`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().StringVarP(&v.O.Output,
+		"output",
+		"o",
+		"",
+		"file to save state of workspace")
+
+	return v.cmd
+}
+
+// pinnedManifest returns manifest with projects pinned to base commits.
+func (v freezeCommand) pinnedManifest(bases map[string]string) ([]byte, error) {
+	rws := v.RepoWorkSpace()
+	if err := rws.FreezeManifest(true, true); err != nil {
+		return nil, err
+	}
+	if err := rws.Manifest.ProjectHandle(snapshotBaseProject{bases: bases}); err != nil {
+		return nil, err
+	}
+	return rws.Manifest.Marshal()
+}
+
+// writeSnapshot saves snapshot of projects and other files in archive.
+func (v freezeCommand) writeSnapshot(state *workspaceSnapshot, pinned []byte, bundles, diffs map[string]string) error {
+	w, closeFile, err := createExportFile(v.O.Output)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(w)
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err == nil {
+		err = writeTarFile(tw, exportManifestName, pinned)
+	}
+	if err == nil {
+		err = writeTarFile(tw, snapshotStateFile, append(data, '\n'))
+	}
+	for _, s := range state.Projects {
+		if err != nil {
+			break
+		}
+		if s.HasBundle {
+			var bundle []byte
+			bundle, err = ioutil.ReadFile(bundles[s.Path])
+			if err == nil {
+				err = writeTarFile(tw, snapshotBundleName(s.Path), bundle)
+			}
+		}
+		if err == nil && s.HasDiff {
+			err = writeTarFile(tw, snapshotDiffName(s.Path), []byte(diffs[s.Path]))
+		}
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if e := closeFile(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(v.O.Output)
+	}
+	return err
+}
+
+func (v freezeCommand) Execute(args []string) error {
+	if v.O.Output == "" {
+		return newUserError("no output file, use -o to set one")
+	}
+
+	rws := v.RepoWorkSpace()
+	projects, err := rws.GetProjects(&workspace.GetProjectsOptions{
+		Groups: rws.Settings().Groups,
+	}, args...)
+	if err != nil {
+		return err
+	}
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-freeze-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpdir)
+
+	state := workspaceSnapshot{
+		Time:     time.Now(),
+		Projects: []*project.Snapshot{},
+	}
+	bases := make(map[string]string)
+	bundles := make(map[string]string)
+	diffs := make(map[string]string)
+	for i, p := range projects {
+		if !p.Exists() {
+			log.Warnf("%snot checked out, skipped", p.Prompt())
+			continue
+		}
+		s, err := p.TakeSnapshot()
+		if err != nil {
+			return err
+		}
+		if bases[p.Path], err = p.SnapshotBase(); err != nil {
+			return fmt.Errorf("%sfail to find commit in remote: %s", p.Prompt(), err)
+		}
+		bundles[p.Path] = filepath.Join(tmpdir, fmt.Sprintf("%d.bundle", i))
+		if s.HasBundle, err = p.CreateSnapshotBundle(bundles[p.Path]); err != nil {
+			return err
+		}
+		if diffs[p.Path], err = p.WorktreeDiff(); err != nil {
+			return err
+		}
+		s.HasDiff = diffs[p.Path] != ""
+		state.Projects = append(state.Projects, s)
+	}
+
+	pinned, err := v.pinnedManifest(bases)
+	if err != nil {
+		return err
+	}
+	if err = v.writeSnapshot(&state, pinned, bundles, diffs); err != nil {
+		return err
+	}
+	log.Notef("saved state of %d projects to %s", len(state.Projects), v.O.Output)
+	return nil
+}
+
+var freezeCmd = freezeCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(freezeCmd.Command())
+}
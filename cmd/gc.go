@@ -0,0 +1,195 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// gcDefaultJobs is the default value of --jobs
+	gcDefaultJobs = 2
+
+	// Values of config "repo.gc", which is the maintenance policy
+	// after sync.
+	gcPolicyAuto  = "auto"
+	gcPolicyNever = "never"
+)
+
+type gcCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		Aggressive bool
+		Auto       bool
+		Jobs       int
+	}
+}
+
+func (v *gcCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "gc [<project>...]",
+		Short: "Cleanup unnecessary files and optimize object stores of projects",
+		Long: `Run "git gc" on object stores of projects. Object stores shared by
+several projects are repacked with unreachable objects kept, and are never
+pruned. Large object stores are repacked
+incrementally by "git maintenance", unless --aggressive is given.
+
+"git repo sync" runs "git repo gc --auto" after fetching, unless config
+"repo.gc" of the workspace is set to "never".`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().BoolVar(&v.O.Aggressive,
+		"aggressive",
+		false,
+		"optimize object stores more aggressively, at the expense of taking much more time")
+	v.cmd.Flags().BoolVar(&v.O.Auto,
+		"auto",
+		false,
+		"only run gc on object stores with too many loose objects or packs")
+	v.cmd.Flags().IntVarP(&v.O.Jobs,
+		"jobs",
+		"j",
+		0,
+		fmt.Sprintf("number of object stores to gc simultaneously "+
+			"(default: repo.jobs of workspace, sync-j of manifest, or %d)",
+			gcDefaultJobs))
+
+	return v.cmd
+}
+
+func (v gcCommand) Execute(args []string) error {
+	rws := v.RepoWorkSpace()
+	jobs := rws.Jobs(jobsOption(v.cmd, v.O.Jobs), gcDefaultJobs)
+
+	projects, err := rws.GetProjects(nil, args...)
+	if err != nil {
+		return err
+	}
+
+	count, err := gcProjects(projects, rws.Projects, jobs, v.O.Auto, v.O.Aggressive)
+	if count == 0 && err == nil {
+		log.Note("nothing to gc")
+	}
+	return err
+}
+
+// objectStoreKey returns object store of project, which may be shared by
+// other projects.
+func objectStoreKey(p *project.Project) string {
+	if p.ObjectsGitDir != "" {
+		return p.ObjectsGitDir
+	}
+	return p.RepoDir()
+}
+
+// gcProjects runs gc on object stores of projects simultaneously, and
+// returns number of object stores which gc is run on. Object stores
+// shared with any of allProjects are never pruned.
+func gcProjects(projects, allProjects []*project.Project, jobs int, auto, aggressive bool) (int, error) {
+	var (
+		count     int
+		errs      []string
+		mutex     sync.Mutex
+		wg        sync.WaitGroup
+		stores    = make(map[string][]*project.Project)
+		storeKeys []string
+		users     = make(map[string]int)
+	)
+
+	for _, p := range allProjects {
+		users[objectStoreKey(p)]++
+	}
+
+	// Projects may share the same object store, run gc only once for
+	// each object store.
+	for _, p := range projects {
+		if !p.Exists() {
+			continue
+		}
+		key := objectStoreKey(p)
+		if _, ok := stores[key]; !ok {
+			storeKeys = append(storeKeys, key)
+		}
+		stores[key] = append(stores[key], p)
+	}
+
+	if jobs < 1 {
+		jobs = 1
+	}
+	jobTasks := make(chan string, jobs)
+	worker := func(i int) {
+		defer recoverCrash()
+		log.Debugf("start gc worker #%d", i)
+		for key := range jobTasks {
+			p := stores[key][0]
+			ran, err := p.GC(&project.GCOptions{
+				Auto:       auto,
+				Aggressive: aggressive,
+				Shared:     users[key] > 1,
+			})
+			mutex.Lock()
+			if ran {
+				count++
+				log.Infof("%sgc done", p.Prompt())
+			}
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("fail to gc %s: %s", p.Name, err))
+			}
+			mutex.Unlock()
+			wg.Done()
+		}
+	}
+
+	wg.Add(len(storeKeys))
+	for i := 0; i < jobs; i++ {
+		go worker(i)
+	}
+	for _, key := range storeKeys {
+		jobTasks <- key
+	}
+	close(jobTasks)
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return count, errors.New(strings.Join(errs, "\n"))
+	}
+	return count, nil
+}
+
+var gcCmd = gcCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: true,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gcCmd.Command())
+}
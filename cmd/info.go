@@ -0,0 +1,151 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/common"
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/spf13/cobra"
+)
+
+const infoSeparator = "----------------------------"
+
+type infoCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		Tags   bool
+		NoTags bool
+	}
+}
+
+func (v *infoCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "info [<project>...]",
+		Short: "Get info on the manifest branch, current branch or unmerged branches",
+		Long: `Show information of the manifest, and of each project, such as the
+revisions and local branches of the project, and whether tags are
+fetched for the project by "git repo sync".
+
+Tags of a project are fetched unless sync-tags of the project is false
+or the workspace is a shallow clone. Pass --tags or --no-tags to show
+the policy used by "git repo sync" with the same option.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().BoolVar(&v.O.Tags,
+		"tags",
+		false,
+		"show tags policy of sync with --tags")
+	v.cmd.Flags().BoolVar(&v.O.NoTags,
+		"no-tags",
+		false,
+		"show tags policy of sync with --no-tags")
+
+	return v.cmd
+}
+
+func (v infoCommand) Execute(args []string) error {
+	if v.O.Tags && v.O.NoTags {
+		return newUserError("cannot combine --tags and --no-tags")
+	}
+
+	rws := v.RepoWorkSpace()
+	s := rws.Settings()
+	groups := s.Groups
+	if groups == "" {
+		groups = manifest.DefaultGroups()
+	}
+
+	fmt.Printf("Manifest branch: %s\n", rws.ManifestProject.TrackBranch(""))
+	fmt.Printf("Manifest groups: %s\n", groups)
+	fmt.Println(infoSeparator)
+
+	projects, err := rws.GetProjects(nil, args...)
+	if err != nil {
+		return err
+	}
+	if len(projects) == 0 {
+		log.Notef("no projects")
+		return nil
+	}
+
+	fetchOptions := project.FetchOptions{
+		RepoSettings: *s,
+
+		Tags:   v.O.Tags,
+		NoTags: v.O.NoTags,
+	}
+
+	for _, p := range projects {
+		v.showProject(p, &fetchOptions)
+		fmt.Println(infoSeparator)
+	}
+	return nil
+}
+
+func (v infoCommand) showProject(p *project.Project, o *project.FetchOptions) {
+	fmt.Printf("Project: %s\n", p.Name)
+	fmt.Printf("Mount path: %s\n", p.WorkDir)
+
+	head := p.GetHead()
+	if headid, err := p.ResolveRevision(head); err == nil && headid != "" {
+		fmt.Printf("Current revision: %s\n", headid)
+	}
+	if common.IsHead(head) {
+		fmt.Printf("Current branch: %s\n", strings.TrimPrefix(head, config.RefsHeads))
+	}
+	fmt.Printf("Manifest revision: %s\n", p.Revision)
+
+	branches := []string{}
+	for _, b := range p.Heads() {
+		branches = append(branches, b.ShortName())
+	}
+	if len(branches) > 0 {
+		fmt.Printf("Local Branches: %d [%s]\n", len(branches), strings.Join(branches, ", "))
+	} else {
+		fmt.Println("Local Branches: 0")
+	}
+
+	fetchTags, reason := p.TagsPolicy(o)
+	if fetchTags {
+		fmt.Printf("Fetch tags: yes (%s)\n", reason)
+	} else {
+		fmt.Printf("Fetch tags: no (%s)\n", reason)
+	}
+}
+
+var infoCmd = infoCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: true,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd.Command())
+}
@@ -18,7 +18,6 @@ import (
 	"bufio"
 	"fmt"
 	"os"
-	"runtime"
 	"strings"
 
 	"github.com/alibaba/git-repo-go/cap"
@@ -26,11 +25,12 @@ import (
 	"github.com/alibaba/git-repo-go/common"
 	"github.com/alibaba/git-repo-go/config"
 	"github.com/alibaba/git-repo-go/errors"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/manifest"
 	"github.com/alibaba/git-repo-go/path"
 	"github.com/alibaba/git-repo-go/project"
 	"github.com/alibaba/git-repo-go/workspace"
 	"github.com/jiangxin/goconfig"
-	log "github.com/jiangxin/multi-log"
 	"github.com/spf13/cobra"
 )
 
@@ -46,8 +46,10 @@ type initCommand struct {
 		ConfigName        bool
 		CurrentBranchOnly bool
 		Depth             int
+		CloneFilter       string
 		DetachHead        bool
 		Dissociate        bool
+		Force             bool
 		Groups            string
 		Mirror            bool
 		NoCloneBundle     bool
@@ -55,6 +57,7 @@ type initCommand struct {
 		Platform          string
 		Reference         string
 		Submodules        bool
+		Worktree          bool
 	}
 }
 
@@ -95,6 +98,10 @@ func (v *initCommand) Command() *cobra.Command {
 		"d",
 		false,
 		"remove default branch to make manifest project detached")
+	v.cmd.Flags().BoolVar(&v.O.Force,
+		"force",
+		false,
+		"switch manifest branch even if local work would be stranded")
 	v.cmd.Flags().BoolVar(&v.O.Mirror,
 		"mirror",
 		false,
@@ -111,10 +118,18 @@ func (v *initCommand) Command() *cobra.Command {
 		"depth",
 		0,
 		"create a shallow clone with given depth; see git clone")
+	v.cmd.Flags().StringVar(&v.O.CloneFilter,
+		"partial-clone-filter",
+		"",
+		"create a partial clone with given filter, such as blob:none; see git clone --filter")
 	v.cmd.Flags().BoolVar(&v.O.Archive,
 		"archive",
 		false,
 		"checkout an archive instead of a git repository for each project. See git archive.")
+	v.cmd.Flags().BoolVar(&v.O.Worktree,
+		"worktree",
+		false,
+		"use git worktree for checkouts of projects, existing checkouts are migrated by sync")
 	v.cmd.Flags().BoolVar(&v.O.Submodules,
 		"submodules",
 		false,
@@ -146,7 +161,6 @@ func (v *initCommand) Command() *cobra.Command {
 }
 
 func (v initCommand) getGroups() string {
-	allPlatforms := []string{"linux", "darwin", "windows"}
 	groups := []string{}
 	for _, g := range strings.Split(v.O.Groups, ",") {
 		g = strings.TrimSpace(g)
@@ -154,28 +168,18 @@ func (v initCommand) getGroups() string {
 			groups = append(groups, g)
 		}
 	}
-	platformize := func(x string) string { return "platform-" + x }
-	if v.O.Platform == "auto" {
-		isMirror := v.ws.ManifestProject.Config().GetBool("repo.mirror", false)
-		if !v.O.Mirror && !isMirror {
-			groups = append(groups, platformize(runtime.GOOS))
-		}
-	} else if v.O.Platform != "" {
-		found := false
-		for _, sys := range allPlatforms {
-			if v.O.Platform == "all" || v.O.Platform == sys {
-				groups = append(groups, platformize(sys))
-				found = true
-			}
-		}
-		if !found {
+	isMirror := v.ws.ManifestProject.MirrorEnabled()
+	if v.O.Platform != "" &&
+		(v.O.Platform != manifest.PlatformAuto || (!v.O.Mirror && !isMirror)) {
+		platformGroups, err := manifest.PlatformGroups(v.O.Platform)
+		if err != nil {
 			log.Fatalf("invalid platform flag: %s", v.O.Platform)
 		}
+		groups = append(groups, platformGroups...)
 	}
 
 	groupStr := strings.Join(groups, ",")
-	if v.O.Platform == "auto" &&
-		groupStr == "default,"+platformize(runtime.GOOS) {
+	if v.O.Platform == manifest.PlatformAuto && groupStr == manifest.DefaultGroups() {
 		groupStr = ""
 	}
 
@@ -193,6 +197,10 @@ func (v initCommand) Execute(args []string) error {
 		log.Fatal("--mirror and --archive cannot be used together")
 	}
 
+	if v.O.Worktree && (v.O.Mirror || v.O.Archive) {
+		log.Fatal("--worktree cannot be used with --mirror or --archive")
+	}
+
 	if config.IsSingleMode() {
 		log.Fatal("cannot run in single mode")
 	}
@@ -243,6 +251,10 @@ func (v initCommand) Execute(args []string) error {
 		}
 	}
 
+	oldRevision := ""
+	if !isNew {
+		oldRevision = v.ws.ManifestProject.Revision
+	}
 	if v.O.ManifestBranch != "" {
 		log.Debugf("set manifest project revision to %s", v.O.ManifestBranch)
 		v.ws.ManifestProject.SetRevision(v.O.ManifestBranch)
@@ -267,6 +279,7 @@ func (v initCommand) Execute(args []string) error {
 	}
 
 	// v.O.Groups has default value, and use it if setting is empty
+	oldGroups := s.Groups
 	groupStr := v.getGroups()
 	if ((v.cmd.Flags().Changed("groups") || v.cmd.Flags().Changed("platform")) &&
 		s.Groups != groupStr) ||
@@ -275,6 +288,11 @@ func (v initCommand) Execute(args []string) error {
 		s.Groups = groupStr
 	}
 
+	if v.cmd.Flags().Changed("platform") && s.Platform != v.O.Platform {
+		changed = true
+		s.Platform = v.O.Platform
+	}
+
 	if v.cmd.Flags().Changed("reference") {
 		if v.O.Reference != "" {
 			v.O.Reference, err = path.Abs(v.O.Reference)
@@ -293,6 +311,11 @@ func (v initCommand) Execute(args []string) error {
 		s.Depth = v.O.Depth
 	}
 
+	if v.cmd.Flags().Changed("partial-clone-filter") && s.PartialCloneFilter != v.O.CloneFilter {
+		changed = true
+		s.PartialCloneFilter = v.O.CloneFilter
+	}
+
 	if v.cmd.Flags().Changed("archive") && s.Archive != v.O.Archive {
 		changed = true
 		if !isNew {
@@ -317,12 +340,24 @@ Either delete the .repo folder in this workspace, or initialize in another locat
 
 	}
 
+	if v.cmd.Flags().Changed("worktree") && s.Worktree != v.O.Worktree {
+		if !v.O.Worktree {
+			log.Fatal(`--worktree cannot be turned off once enabled.
+Either delete the .repo folder in this workspace, or initialize in another location.`)
+		}
+		if s.Mirror {
+			log.Fatal("--worktree is not supported in a mirror")
+		}
+		changed = true
+		s.Worktree = v.O.Worktree
+	}
+
 	if v.cmd.Flags().Changed("submodules") && s.Submodules != v.O.Submodules {
 		changed = true
 		s.Submodules = v.O.Submodules
 	}
 
-	if changed {
+	if changed || !path.Exist(s.SettingsFile()) {
 		err = v.ws.ManifestProject.SaveSettings(s)
 		if err != nil {
 			return err
@@ -356,6 +391,22 @@ Either delete the .repo folder in this workspace, or initialize in another locat
 			return err
 		}
 		log.Error(err)
+	} else if !isNew && !s.Mirror && oldRevision != "" && v.O.ManifestBranch != "" &&
+		strings.TrimPrefix(oldRevision, config.RefsHeads) !=
+			strings.TrimPrefix(v.O.ManifestBranch, config.RefsHeads) {
+		err = v.checkManifestSwitch(oldRevision, v.O.ManifestBranch)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Verify signature of manifests before checkout.
+	revid, err := v.ws.ManifestProject.ResolveRemoteTracking(v.ws.ManifestProject.Revision)
+	if err == nil && revid != "" {
+		err = v.ws.ManifestProject.VerifyRevision(revid)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Checkout
@@ -388,6 +439,10 @@ Either delete the .repo folder in this workspace, or initialize in another locat
 		return err
 	}
 
+	if !isNew && !s.Mirror && oldGroups != s.Groups {
+		v.showGroupsChange(oldGroups, s.Groups)
+	}
+
 	if cap.Isatty() {
 		if v.O.ConfigName || v.shouldConfigUser() {
 			v.configureUser()
@@ -404,6 +459,81 @@ Either delete the .repo folder in this workspace, or initialize in another locat
 	return nil
 }
 
+// checkManifestSwitch shows projects which will be changed by switching
+// manifest branch, and fails if local work would be stranded unless
+// --force is given.
+func (v initCommand) checkManifestSwitch(oldRevision, newRevision string) error {
+	m, err := v.ws.ManifestProject.LoadManifestAt(newRevision)
+	if err != nil {
+		return err
+	}
+	result := v.ws.CompareManifest(m)
+
+	// Changes are shown in details only if local work would be stranded.
+	show := log.Infof
+	if len(result.Stranded) > 0 {
+		show = log.Notef
+	}
+	show("switch manifest branch from %s to %s",
+		strings.TrimPrefix(oldRevision, config.RefsHeads),
+		strings.TrimPrefix(newRevision, config.RefsHeads))
+	for _, c := range result.Changed {
+		show("  %s: revision %s -> %s", c.Path, c.OldRevision, c.NewRevision)
+	}
+	for _, p := range result.Added {
+		show("  %s: will be added", p)
+	}
+	for _, p := range result.Removed {
+		show("  %s: will be removed", p)
+	}
+	if !result.HasChanges() {
+		show("  no project will be changed")
+	}
+	for _, w := range result.Stranded {
+		log.Warnf("%s", w)
+	}
+	if len(result.Stranded) > 0 && !v.O.Force {
+		return fmt.Errorf("local work would be stranded, use --force to switch manifest branch")
+	}
+	return nil
+}
+
+// showGroupsChange shows projects which will be added to or removed
+// from disk by the next sync after groups of workspace are changed.
+func (v initCommand) showGroupsChange(oldGroups, newGroups string) {
+	// Reload workspace for manifest may be updated.
+	ws, err := workspace.NewRepoWorkSpace(v.ws.RootDir)
+	if err != nil {
+		log.Warnf("fail to load workspace: %s", err)
+		return
+	}
+	result, err := ws.CompareGroups(oldGroups, newGroups)
+	if err != nil {
+		log.Warn(err)
+		return
+	}
+
+	if oldGroups == "" {
+		oldGroups = manifest.DefaultGroups()
+	}
+	if newGroups == "" {
+		newGroups = manifest.DefaultGroups()
+	}
+	log.Notef("groups of workspace are changed from '%s' to '%s'", oldGroups, newGroups)
+	for _, p := range result.Added {
+		log.Notef("  %s: will be added by next sync", p)
+	}
+	for _, p := range result.Removed {
+		log.Notef("  %s: will be removed by next sync", p)
+	}
+	if !result.HasChanges() {
+		log.Note("  no project will be added or removed")
+	}
+	for _, w := range result.Stranded {
+		log.Warnf("%s", w)
+	}
+}
+
 func (v initCommand) shouldConfigUser() bool {
 	var (
 		userName  = "user.name"
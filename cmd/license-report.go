@@ -0,0 +1,216 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"html/template"
+	"io"
+	"os"
+
+	"github.com/alibaba/git-repo-go/file"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/spf13/cobra"
+)
+
+// licenseProjectReport is license files and metadata of a project.
+type licenseProjectReport struct {
+	Name     string                `json:"name"`
+	Path     string                `json:"path"`
+	Revision string                `json:"revision,omitempty"`
+	Licenses []project.LicenseFile `json:"licenses"`
+	Metadata map[string]string     `json:"metadata,omitempty"`
+	Error    string                `json:"error,omitempty"`
+}
+
+// licenseHTMLTemplate is template of license report in HTML format.
+var licenseHTMLTemplate = template.Must(template.New("license").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>License report</title>
+<style>
+body { font-family: sans-serif; }
+pre { background: #f6f8fa; padding: 8px; white-space: pre-wrap; }
+.missing { color: #c00; }
+</style>
+</head>
+<body>
+<h1>License report</h1>
+<ul>
+{{- range .}}
+<li><a href="#{{.Path}}">{{.Path}}</a>{{if not .Licenses}} <span class="missing">(no license)</span>{{end}}</li>
+{{- end}}
+</ul>
+{{- range .}}
+<h2 id="{{.Path}}">{{.Path}}</h2>
+<p>Project: {{.Name}}{{if .Revision}}, revision: {{.Revision}}{{end}}</p>
+{{- if .Error}}
+<p class="missing">{{.Error}}</p>
+{{- end}}
+{{- if .Metadata}}
+<table>
+{{- range $name, $value := .Metadata}}
+<tr><th align="left">{{$name}}</th><td>{{$value}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- range .Licenses}}
+<h3>{{.Name}}</h3>
+<pre>{{.Content}}</pre>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// newLicenseProjectReport collects license files at HEAD and annotations
+// of project.
+func newLicenseProjectReport(p *project.Project) *licenseProjectReport {
+	r := licenseProjectReport{
+		Name:     p.Name,
+		Path:     p.Path,
+		Licenses: []project.LicenseFile{},
+	}
+	if len(p.Annotations) > 0 {
+		r.Metadata = make(map[string]string)
+		for _, a := range p.Annotations {
+			r.Metadata[a.Name] = a.Value
+		}
+	}
+
+	if !p.Exists() {
+		r.Error = "project is not checked out"
+		return &r
+	}
+	revision, err := p.ResolveRevision("HEAD")
+	if err != nil {
+		r.Error = "fail to resolve HEAD"
+		return &r
+	}
+	r.Revision = revision
+	files, err := p.LicenseFiles(revision)
+	if err != nil {
+		r.Error = err.Error()
+		return &r
+	}
+	r.Licenses = files
+	return &r
+}
+
+// writeLicenseReport writes report in JSON or HTML format.
+func writeLicenseReport(w io.Writer, reports []*licenseProjectReport, format string) error {
+	if format == "html" {
+		return licenseHTMLTemplate.Execute(w, reports)
+	}
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+type licenseReportCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		Format     string
+		OutputFile string
+	}
+}
+
+func (v *licenseReportCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "license-report [<project>...]",
+		Short: "Collect license files and metadata of projects",
+		Long: `Collect license and notice files (such as LICENSE, COPYING, NOTICE and
+MODULE_LICENSE_*) in the top directory of each project at HEAD, and
+annotations of projects in manifest as metadata, into a report in JSON or
+HTML format for compliance review.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().StringVar(&v.O.Format,
+		"format",
+		"json",
+		"format of report: json or html")
+	v.cmd.Flags().StringVarP(&v.O.OutputFile,
+		"output-file",
+		"o",
+		"-",
+		"file to save the report to")
+
+	return v.cmd
+}
+
+func (v licenseReportCommand) Execute(args []string) error {
+	if v.O.Format != "json" && v.O.Format != "html" {
+		return newUserErrorF("unknown format of report: %s", v.O.Format)
+	}
+
+	ws := v.RepoWorkSpace()
+	projects, err := ws.GetProjects(nil, args...)
+	if err != nil {
+		return err
+	}
+
+	reports := []*licenseProjectReport{}
+	missing := 0
+	for _, p := range projects {
+		r := newLicenseProjectReport(p)
+		if len(r.Licenses) == 0 {
+			log.Warnf("%sno license files found", p.Prompt())
+			missing++
+		}
+		reports = append(reports, r)
+	}
+
+	var writer io.Writer = os.Stdout
+	if v.O.OutputFile != "-" {
+		f, err := file.New(v.O.OutputFile).OpenCreateRewrite()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		writer = f
+	}
+	if err = writeLicenseReport(writer, reports, v.O.Format); err != nil {
+		return err
+	}
+	if v.O.OutputFile != "-" {
+		log.Notef("saved license report of %d projects (%d without license) to %s",
+			len(reports), missing, v.O.OutputFile)
+	}
+	return nil
+}
+
+var licenseReportCmd = licenseReportCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(licenseReportCmd.Command())
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteLicenseReport(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		buf    bytes.Buffer
+	)
+
+	reports := []*licenseProjectReport{
+		{
+			Name:     "platform/app",
+			Path:     "app",
+			Revision: "8a2c8e5f1f2a0e4c1c7b3a9b9b1e0e6c1a2b3c4d",
+			Licenses: []project.LicenseFile{{Name: "LICENSE", Content: "MIT <license>\n"}},
+			Metadata: map[string]string{"license": "MIT"},
+		},
+		{
+			Name:     "platform/lib",
+			Path:     "lib",
+			Licenses: []project.LicenseFile{},
+			Error:    "project is not checked out",
+		},
+	}
+
+	assert.Nil(writeLicenseReport(&buf, reports, "json"))
+	loaded := []*licenseProjectReport{}
+	assert.Nil(json.Unmarshal(buf.Bytes(), &loaded))
+	assert.Equal(reports, loaded)
+
+	buf.Reset()
+	assert.Nil(writeLicenseReport(&buf, reports, "html"))
+	html := buf.String()
+	assert.Contains(html, `<h2 id="app">app</h2>`)
+	assert.Contains(html, "<pre>MIT &lt;license&gt;\n</pre>")
+	assert.Contains(html, "<tr><th align=\"left\">license</th><td>MIT</td></tr>")
+	assert.Contains(html, `<li><a href="#lib">lib</a> <span class="missing">(no license)</span></li>`)
+	assert.Contains(html, `<p class="missing">project is not checked out</p>`)
+}
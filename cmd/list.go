@@ -16,12 +16,13 @@ package cmd
 
 import (
 	"fmt"
-	"regexp"
+	"path/filepath"
 	"sort"
 
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/path"
 	"github.com/alibaba/git-repo-go/project"
 	"github.com/alibaba/git-repo-go/workspace"
-	log "github.com/jiangxin/multi-log"
 	"github.com/spf13/cobra"
 )
 
@@ -30,12 +31,16 @@ type listCommand struct {
 
 	cmd *cobra.Command
 	O   struct {
-		Regex    []string
-		Groups   string
-		FullPath bool
-		NameOnly bool
-		PathOnly bool
+		Regex      []string
+		Groups     string
+		Platform   string
+		FullPath   bool
+		NameOnly   bool
+		PathOnly   bool
+		RelativeTo string
 	}
+
+	relativeTo string
 }
 
 func (v *listCommand) Command() *cobra.Command {
@@ -44,11 +49,10 @@ func (v *listCommand) Command() *cobra.Command {
 	}
 
 	v.cmd = &cobra.Command{
-		Use:   "list",
+		Use:   "list [<project>...]",
 		Short: "List projects and their associated directories",
 		Long: `List all projects; pass '.' to list the project for the cwd.
-		This is similar to running: git-repo forall -c 'echo "$REPO_PATH :
-		$REPO_PROJECT"'.`,
+This is similar to running: git-repo forall -c 'echo "$REPO_PATH : $REPO_PROJECT"'.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return v.Execute(args)
 		},
@@ -63,6 +67,10 @@ func (v *listCommand) Command() *cobra.Command {
 		"g",
 		"",
 		"Filter the project list based on the groups the project is in")
+	v.cmd.Flags().StringVar(&v.O.Platform,
+		"platform",
+		"",
+		"Select projects in platform groups [auto|all|none|linux|darwin|windows] (default: platform of workspace)")
 	v.cmd.Flags().BoolVarP(&v.O.FullPath,
 		"fullpath",
 		"f",
@@ -78,16 +86,18 @@ func (v *listCommand) Command() *cobra.Command {
 		"p",
 		false,
 		"Display only the path of the repository")
+	v.cmd.Flags().StringVar(&v.O.RelativeTo,
+		"relative-to",
+		"",
+		"Display paths relative to this path, such as \".\" for current dir")
 
 	return v.cmd
 }
 
 func (v listCommand) Execute(args []string) error {
 	var (
-		projects    []*project.Project
-		allProjects []*project.Project
-		patterns    []*regexp.Regexp
-		err         error
+		projects []*project.Project
+		err      error
 	)
 
 	ws := v.RepoWorkSpace()
@@ -100,34 +110,28 @@ func (v listCommand) Execute(args []string) error {
 		log.Fatal("cannot combine -f and -n")
 	}
 
-	allProjects, err = ws.GetProjects(&workspace.GetProjectsOptions{
-		Groups: v.O.Groups,
-	})
-
-	if err != nil {
-		return err
+	if v.O.FullPath && v.O.RelativeTo != "" {
+		log.Fatal("cannot combine -f and --relative-to")
 	}
 
-	for _, r := range v.O.Regex {
-		re, err := regexp.Compile(r)
+	if v.O.RelativeTo != "" {
+		v.relativeTo, err = path.Abs(v.O.RelativeTo)
 		if err != nil {
-			log.Warnf("cannot compile regex pattern %s: %s", r, err)
-			continue
+			return err
+		}
+		if p, err := filepath.EvalSymlinks(v.relativeTo); err == nil {
+			v.relativeTo = p
 		}
-		patterns = append(patterns, re)
 	}
 
-	if len(patterns) == 0 {
-		projects = allProjects
-	} else {
-		for _, p := range allProjects {
-			for _, re := range patterns {
-				if re.MatchString(p.Name) || re.MatchString(p.Path) {
-					projects = append(projects, p)
-					break
-				}
-			}
-		}
+	projects, err = ws.GetProjects(&workspace.GetProjectsOptions{
+		Groups:   v.O.Groups,
+		Platform: v.O.Platform,
+		Regex:    v.O.Regex,
+	}, args...)
+
+	if err != nil {
+		return err
 	}
 
 	if len(projects) == 0 {
@@ -158,6 +162,11 @@ func (v listCommand) getPath(project *project.Project) string {
 	if v.O.FullPath {
 		return project.WorkDir
 	}
+	if v.relativeTo != "" {
+		if rel, err := filepath.Rel(v.relativeTo, project.WorkDir); err == nil {
+			return rel
+		}
+	}
 	return project.Path
 }
 
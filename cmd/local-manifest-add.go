@@ -0,0 +1,198 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/spf13/cobra"
+)
+
+type localManifestAddCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		File     string
+		Revision string
+		Groups   string
+		Path     string
+		Remote   string
+		Drop     bool
+	}
+}
+
+func (v *localManifestAddCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "add <project>",
+		Short: "Add a project override to local manifest",
+		Long: `Add an override for an existing project (matched by name or path) to
+local manifest, such as pinning it to a revision or adding groups to it.
+
+Use --path to add a new project which is not in the manifest, and use
+--drop to remove a project from the manifest.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().StringVarP(&v.O.File,
+		"file",
+		"f",
+		defaultLocalManifestFile,
+		"name of local manifest file")
+	v.cmd.Flags().StringVarP(&v.O.Revision,
+		"revision",
+		"r",
+		"",
+		"pin project to revision")
+	v.cmd.Flags().StringVarP(&v.O.Groups,
+		"groups",
+		"g",
+		"",
+		"add groups to project")
+	v.cmd.Flags().StringVar(&v.O.Path,
+		"path",
+		"",
+		"path of the new project")
+	v.cmd.Flags().StringVar(&v.O.Remote,
+		"remote",
+		"",
+		"remote of the new project")
+	v.cmd.Flags().BoolVar(&v.O.Drop,
+		"drop",
+		false,
+		"remove project from manifest")
+
+	return v.cmd
+}
+
+// findManifestProject finds toplevel project of manifest by name or path.
+func findManifestProject(m *manifest.Manifest, nameOrPath string) (*manifest.Project, error) {
+	var found []*manifest.Project
+
+	for i := range m.Projects {
+		if m.Projects[i].Path == nameOrPath {
+			return &m.Projects[i], nil
+		}
+		if m.Projects[i].Name == nameOrPath {
+			found = append(found, &m.Projects[i])
+		}
+	}
+	if len(found) > 1 {
+		return nil, fmt.Errorf("more than one project named '%s', please use path instead", nameOrPath)
+	}
+	if len(found) == 1 {
+		return found[0], nil
+	}
+	return nil, nil
+}
+
+func (v localManifestAddCommand) Execute(args []string) error {
+	ws := v.RepoWorkSpace()
+	if ws.Manifest == nil {
+		return fmt.Errorf("manifest is not loaded, run 'git repo init' first")
+	}
+
+	p, err := findManifestProject(ws.Manifest, args[0])
+	if err != nil {
+		return err
+	}
+
+	m, err := manifest.LoadLocalManifest(localManifestPath(ws, v.O.File))
+	if err != nil {
+		return err
+	}
+
+	if v.O.Drop {
+		if v.O.Revision != "" || v.O.Groups != "" || v.O.Path != "" || v.O.Remote != "" {
+			return newUserError("cannot combine --drop with other options")
+		}
+		if p == nil {
+			return fmt.Errorf("cannot find project '%s' in manifest", args[0])
+		}
+		m.AddRemoveProject(p.Name)
+	} else if p != nil {
+		if v.O.Path != "" || v.O.Remote != "" {
+			return fmt.Errorf("project '%s' already exists in manifest", args[0])
+		}
+		if v.O.Revision == "" && v.O.Groups == "" {
+			return newUserError("nothing to change, use --revision or --groups")
+		}
+		m.SetExtendProject(manifest.ExtendProject{
+			Name:     p.Name,
+			Path:     p.Path,
+			Revision: v.O.Revision,
+			Groups:   v.O.Groups,
+		})
+	} else {
+		if v.O.Path == "" {
+			return fmt.Errorf("cannot find project '%s' in manifest, use --path to add a new project",
+				args[0])
+		}
+		if ws.GetProjectWithPath(v.O.Path) != nil {
+			return fmt.Errorf("path '%s' is already used by another project", v.O.Path)
+		}
+		remote := v.O.Remote
+		if remote == "" && ws.Manifest.Default != nil {
+			remote = ws.Manifest.Default.RemoteName
+		}
+		found := false
+		for _, r := range ws.Manifest.Remotes {
+			if r.Name == remote {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("cannot find remote '%s' in manifest", remote)
+		}
+		err = m.AddProject(manifest.Project{
+			Name:       args[0],
+			Path:       v.O.Path,
+			RemoteName: v.O.Remote,
+			Revision:   v.O.Revision,
+			Groups:     v.O.Groups,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = saveLocalManifest(ws, m); err != nil {
+		return err
+	}
+	log.Notef("local manifest %s is updated, run 'git repo sync' to apply",
+		filepath.Base(m.SourceFile))
+	return nil
+}
+
+var localManifestAddCmd = localManifestAddCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: true,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	localManifestCmd.Command().AddCommand(localManifestAddCmd.Command())
+}
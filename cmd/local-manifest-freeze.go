@@ -0,0 +1,133 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/alibaba/git-repo-go/common"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/alibaba/git-repo-go/workspace"
+	"github.com/spf13/cobra"
+)
+
+const defaultFreezeManifestFile = "frozen.xml"
+
+type localManifestFreezeCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		File   string
+		Groups string
+	}
+}
+
+func (v *localManifestFreezeCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "freeze [-g <groups>] [<project>...]",
+		Short: "Pin projects to their current revisions in local manifest",
+		Long: `Pin projects which match the given groups (or the given projects) to
+the commits they are checked out, by writing extend-project elements
+into a local manifest. Other projects keep following their branches.
+
+The original branch of a pinned project is saved as upstream. Run
+'git repo local-manifest remove ` + defaultFreezeManifestFile + `' to unfreeze.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().StringVarP(&v.O.File,
+		"file",
+		"f",
+		defaultFreezeManifestFile,
+		"name of local manifest file")
+	v.cmd.Flags().StringVarP(&v.O.Groups,
+		"groups",
+		"g",
+		"",
+		"pin projects which match groups")
+
+	return v.cmd
+}
+
+func (v localManifestFreezeCommand) Execute(args []string) error {
+	ws := v.RepoWorkSpace()
+	if ws.Manifest == nil {
+		return newUserError("manifest is not loaded, run 'git repo init' first")
+	}
+	if v.O.Groups == "" && len(args) == 0 {
+		return newUserError("nothing to freeze, use --groups or give projects")
+	}
+
+	projects, err := ws.GetProjects(&workspace.GetProjectsOptions{
+		Groups: v.O.Groups,
+	}, args...)
+	if err != nil {
+		return err
+	}
+
+	m, err := manifest.LoadLocalManifest(localManifestPath(ws, v.O.File))
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, p := range projects {
+		rev, err := ws.CurrentRevision(p)
+		if err != nil {
+			log.Warnf("skip project '%s': %s", p.Path, err)
+			continue
+		}
+		upstream := p.Upstream
+		if upstream == "" && !common.IsSha(p.Revision) {
+			upstream = p.Revision
+		}
+		m.SetExtendProject(manifest.ExtendProject{
+			Name:     p.Name,
+			Path:     p.Path,
+			Revision: rev,
+			Upstream: upstream,
+		})
+		count++
+	}
+	if count == 0 {
+		log.Note("no projects to freeze")
+		return nil
+	}
+
+	if err = saveLocalManifest(ws, m); err != nil {
+		return err
+	}
+	log.Notef("%d project(s) are pinned in local manifest %s",
+		count, filepath.Base(m.SourceFile))
+	return nil
+}
+
+var localManifestFreezeCmd = localManifestFreezeCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: true,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	localManifestCmd.Command().AddCommand(localManifestFreezeCmd.Command())
+}
@@ -0,0 +1,113 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/spf13/cobra"
+)
+
+type localManifestListCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+}
+
+func (v *localManifestListCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "list",
+		Short: "List entries of local manifests",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+
+	return v.cmd
+}
+
+// formatAttrs formats non-empty attributes as "key=value" pairs.
+func formatAttrs(attrs ...string) string {
+	items := []string{}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if attrs[i+1] != "" {
+			items = append(items, attrs[i]+"="+attrs[i+1])
+		}
+	}
+	return strings.Join(items, " ")
+}
+
+func (v localManifestListCommand) Execute(args []string) error {
+	ws := v.RepoWorkSpace()
+	files, err := manifest.LocalManifestFiles(filepath.Join(ws.RootDir, config.DotRepo))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		log.Note("no local manifests")
+		return nil
+	}
+
+	for _, filename := range files {
+		m, err := manifest.LoadLocalManifest(filename)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s:\n", filepath.Base(filename))
+		for _, r := range m.Remotes {
+			fmt.Printf("  remote %s %s\n", r.Name,
+				formatAttrs("fetch", r.Fetch, "revision", r.Revision))
+		}
+		for _, p := range m.Projects {
+			fmt.Printf("  project %s %s\n", p.Name,
+				formatAttrs("path", p.Path,
+					"remote", p.RemoteName,
+					"revision", p.Revision,
+					"groups", p.Groups))
+		}
+		for _, p := range m.ExtendProjects {
+			fmt.Printf("  extend-project %s %s\n", p.Name,
+				formatAttrs("path", p.Path,
+					"revision", p.Revision,
+					"upstream", p.Upstream,
+					"groups", p.Groups))
+		}
+		for _, p := range m.RemoveProjects {
+			fmt.Printf("  remove-project %s\n", p.Name)
+		}
+	}
+	return nil
+}
+
+var localManifestListCmd = localManifestListCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: true,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	localManifestCmd.Command().AddCommand(localManifestListCmd.Command())
+}
@@ -0,0 +1,126 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/spf13/cobra"
+)
+
+type localManifestRemoveCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		File string
+	}
+}
+
+func (v *localManifestRemoveCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "remove <project>|<file>.xml...",
+		Short: "Remove entries or files from local manifests",
+		Long: `Remove entries of the given projects (matched by name or path) from
+local manifests, and local manifest files which become empty are
+deleted. If a file name with ".xml" suffix is given, the whole local
+manifest file is removed.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().StringVarP(&v.O.File,
+		"file",
+		"f",
+		"",
+		"only remove entries from this local manifest file")
+
+	return v.cmd
+}
+
+func (v localManifestRemoveCommand) Execute(args []string) error {
+	var (
+		files []string
+		err   error
+	)
+
+	ws := v.RepoWorkSpace()
+	if v.O.File != "" {
+		files = []string{localManifestPath(ws, v.O.File)}
+	} else {
+		files, err = manifest.LocalManifestFiles(filepath.Join(ws.RootDir, config.DotRepo))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, arg := range args {
+		if strings.HasSuffix(arg, ".xml") {
+			filename := localManifestPath(ws, arg)
+			if _, err = os.Stat(filename); err != nil {
+				return fmt.Errorf("cannot find local manifest '%s'", arg)
+			}
+			m := manifest.Manifest{SourceFile: filename}
+			if err = saveLocalManifest(ws, &m); err != nil {
+				return err
+			}
+			log.Notef("local manifest %s is removed", filepath.Base(filename))
+			continue
+		}
+
+		count := 0
+		for _, filename := range files {
+			m, err := manifest.LoadLocalManifest(filename)
+			if err != nil {
+				return err
+			}
+			n := m.RemoveEntries(arg)
+			if n == 0 {
+				continue
+			}
+			if err = saveLocalManifest(ws, m); err != nil {
+				return err
+			}
+			count += n
+			log.Notef("remove %d entry(s) of '%s' from %s", n, arg, filepath.Base(filename))
+		}
+		if count == 0 {
+			return fmt.Errorf("cannot find '%s' in local manifests", arg)
+		}
+	}
+	return nil
+}
+
+var localManifestRemoveCmd = localManifestRemoveCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: true,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	localManifestCmd.Command().AddCommand(localManifestRemoveCmd.Command())
+}
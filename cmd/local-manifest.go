@@ -0,0 +1,94 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/alibaba/git-repo-go/workspace"
+	"github.com/spf13/cobra"
+)
+
+const defaultLocalManifestFile = "local.xml"
+
+type localManifestCommand struct {
+	cmd *cobra.Command
+}
+
+func (v *localManifestCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+	v.cmd = &cobra.Command{
+		Use:   "local-manifest <subcommand>",
+		Short: "Manage local manifests of the workspace",
+		Long: `Manage files in '.repo/local_manifests', such as pinning a project to
+a revision, adding a new project or removing a project, instead of
+editing XML by hands.
+
+Local manifests are validated against the merged manifest after change,
+and the change is rolled back if the merged manifest is broken.`,
+	}
+	return v.cmd
+}
+
+// localManifestPath returns full path of a local manifest file.
+func localManifestPath(ws *workspace.RepoWorkSpace, name string) string {
+	if !strings.HasSuffix(name, ".xml") {
+		name += ".xml"
+	}
+	return filepath.Join(ws.RootDir, config.DotRepo, config.LocalManifests, filepath.Base(name))
+}
+
+// saveLocalManifest saves local manifest (removes the file if manifest is
+// empty), and validates it by loading the merged manifest. The original
+// file is restored if validation fails.
+func saveLocalManifest(ws *workspace.RepoWorkSpace, m *manifest.Manifest) error {
+	var err error
+
+	filename := m.SourceFile
+	orig, readErr := ioutil.ReadFile(filename)
+
+	if m.IsEmpty() {
+		err = os.Remove(filename)
+	} else {
+		err = m.Save(filename)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err = manifest.Load(filepath.Join(ws.RootDir, config.DotRepo)); err != nil {
+		if readErr == nil {
+			ioutil.WriteFile(filename, orig, 0644)
+		} else {
+			os.Remove(filename)
+		}
+		return fmt.Errorf("invalid local manifest, changes are discarded: %s", err)
+	}
+	return nil
+}
+
+var localManifestCmd = localManifestCommand{}
+
+func init() {
+	rootCmd.AddCommand(localManifestCmd.Command())
+}
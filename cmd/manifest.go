@@ -15,12 +15,13 @@
 package cmd
 
 import (
+	"fmt"
 	"io"
 	"os"
 
 	"github.com/alibaba/git-repo-go/file"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/manifest"
-	log "github.com/jiangxin/multi-log"
 	"github.com/spf13/cobra"
 )
 
@@ -31,7 +32,11 @@ type manifestCommand struct {
 	O   struct {
 		PegRev           bool
 		PegRevNoUpstream bool
+		PegRevNoDest     bool
 		OutputFile       string
+		Validate         bool
+		IncludeGraph     string
+		ShowSources      bool
 	}
 }
 
@@ -59,11 +64,30 @@ func (v *manifestCommand) Command() *cobra.Command {
 		"If in -r mode, do not write the upstream field.  "+
 			"Only of use if the branch names for a sha1 "+
 			"manifest are sensitive.")
+	v.cmd.Flags().BoolVar(&v.O.PegRevNoDest,
+		"suppress-dest-branch",
+		false,
+		"If in -r mode, do not write the dest-branch field.  "+
+			"Only of use if the branch names for a sha1 "+
+			"manifest are sensitive.")
 	v.cmd.Flags().StringVarP(&v.O.OutputFile,
 		"output-file",
 		"o",
 		"-",
 		"File to save the manifest to")
+	v.cmd.Flags().BoolVar(&v.O.Validate,
+		"validate",
+		false,
+		"Check unknown elements and attributes in manifest files")
+	v.cmd.Flags().StringVar(&v.O.IncludeGraph,
+		"include-graph",
+		"",
+		"Show which manifest file includes which, in format dot (default) or json")
+	v.cmd.Flags().Lookup("include-graph").NoOptDefVal = "dot"
+	v.cmd.Flags().BoolVar(&v.O.ShowSources,
+		"show-sources",
+		false,
+		"Add comments to show which manifest files set default and projects")
 
 	return v.cmd
 }
@@ -72,13 +96,21 @@ func (v manifestCommand) WriteManifest(writer io.Writer) error {
 	ws := v.RepoWorkSpace()
 
 	if v.O.PegRev {
-		err := ws.FreezeManifest(!v.O.PegRevNoUpstream)
+		err := ws.FreezeManifest(!v.O.PegRevNoUpstream, !v.O.PegRevNoDest)
 		if err != nil {
 			return err
 		}
 	}
 
-	data, err := manifest.Marshal(ws.Manifest)
+	var (
+		data []byte
+		err  error
+	)
+	if v.O.ShowSources {
+		data, err = ws.Manifest.MarshalWithSources()
+	} else {
+		data, err = ws.Manifest.Marshal()
+	}
 	if err != nil {
 		return err
 	}
@@ -96,11 +128,63 @@ func (v manifestCommand) WriteManifest(writer io.Writer) error {
 	return nil
 }
 
+// Validate reports unknown elements and attributes in manifest files.
+func (v manifestCommand) Validate() error {
+	ws := v.RepoWorkSpace()
+
+	findings, err := manifest.Validate(ws.AdminDir())
+	if err != nil {
+		return err
+	}
+	for _, finding := range findings {
+		fmt.Println(finding)
+	}
+	if len(findings) > 0 {
+		return fmt.Errorf("%d problem(s) found in manifest files", len(findings))
+	}
+	log.Note("no problems found in manifest files")
+	return nil
+}
+
+// WriteIncludeGraph writes include graph of manifest files.
+func (v manifestCommand) WriteIncludeGraph(writer io.Writer) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	ws := v.RepoWorkSpace()
+	graph, err := manifest.LoadIncludeGraph(ws.AdminDir())
+	if err != nil {
+		return err
+	}
+	switch v.O.IncludeGraph {
+	case "dot":
+		data = graph.DOT()
+	case "json":
+		data, err = graph.JSON()
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	}
+	_, err = writer.Write(data)
+	return err
+}
+
 func (v manifestCommand) Execute(args []string) error {
 	var (
 		writer io.ReadWriteCloser
 	)
 
+	if v.O.Validate {
+		return v.Validate()
+	}
+
+	if v.O.IncludeGraph != "" && v.O.IncludeGraph != "dot" && v.O.IncludeGraph != "json" {
+		return newUserErrorF("unknown format of include graph: %s", v.O.IncludeGraph)
+	}
+
 	if v.O.OutputFile == "" {
 		log.Fatal("no output file, no operation to perform")
 	} else if v.O.OutputFile == "-" {
@@ -114,6 +198,9 @@ func (v manifestCommand) Execute(args []string) error {
 		defer file.Close()
 	}
 
+	if v.O.IncludeGraph != "" {
+		return v.WriteIncludeGraph(writer)
+	}
 	return v.WriteManifest(writer)
 }
 
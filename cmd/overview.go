@@ -0,0 +1,139 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/spf13/cobra"
+)
+
+type overviewCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		CurrentBranch bool
+	}
+}
+
+func (v *overviewCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "overview [<project>...]",
+		Short: "Display overview of unpublished commits",
+		Long: `Lists, for each local branch of the projects, the commits which are
+not in the tracking branch and not published for review yet, which
+helps to find out what still needs to be uploaded.
+
+The "*" mark indicates the branch is the current branch of the project.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().BoolVarP(&v.O.CurrentBranch,
+		"current-branch",
+		"c",
+		false,
+		"consider only checked out branches")
+
+	return v.cmd
+}
+
+type overviewBranch struct {
+	Branch  project.Branch
+	Current bool
+	Commits []string
+}
+
+func (v overviewCommand) Execute(args []string) error {
+	ws := v.WorkSpace()
+	projects, err := ws.GetProjects(nil, args...)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, p := range projects {
+		head := p.GetHead()
+		branches := []overviewBranch{}
+		width := 0
+		for _, b := range p.Heads() {
+			if v.O.CurrentBranch && b.Name != head {
+				continue
+			}
+			commits, err := p.UnpublishedCommits(b.Name)
+			if err != nil {
+				log.Debug(err)
+				continue
+			}
+			if len(commits) == 0 {
+				continue
+			}
+			branches = append(branches, overviewBranch{
+				Branch:  b,
+				Current: b.Name == head,
+				Commits: commits,
+			})
+			if len(b.ShortName()) > width {
+				width = len(b.ShortName())
+			}
+		}
+		if len(branches) == 0 {
+			continue
+		}
+
+		if !found {
+			fmt.Println("Projects Overview")
+			found = true
+		}
+		fmt.Printf("project %s/\n", p.Path)
+		for _, b := range branches {
+			mark := " "
+			if b.Current {
+				mark = "*"
+			}
+			fmt.Printf("  %s %-*s | %s\n",
+				mark,
+				width,
+				b.Branch.ShortName(),
+				p.LastModified(b.Branch.Name))
+			for _, commit := range b.Commits {
+				fmt.Printf("      - %s\n", commit)
+			}
+		}
+	}
+
+	if !found {
+		log.Note("no unpublished commits")
+	}
+	return nil
+}
+
+var overviewCmd = overviewCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: true,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(overviewCmd.Command())
+}
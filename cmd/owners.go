@@ -0,0 +1,102 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/path"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/spf13/cobra"
+)
+
+type ownersCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+}
+
+func (v *ownersCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "owners [<path>...]",
+		Short: "Show owners of projects",
+		Long: `Show owners of projects which contain the given paths (files or
+directories in the workspace, or names of projects), or of all projects.
+
+Owners of a project are set by annotation "owners" of the project in
+manifest, or by file "OWNERS" in the manifests project, in which each line
+has a pattern of project path or name followed by owners, and the last
+matching line wins. Owners are added as reviewers by "git repo upload".`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+
+	return v.cmd
+}
+
+func (v ownersCommand) Execute(args []string) error {
+	var projects []*project.Project
+
+	rws := v.RepoWorkSpace()
+	if len(args) == 0 {
+		ps, err := rws.GetProjects(nil)
+		if err != nil {
+			return err
+		}
+		projects = ps
+	}
+	for _, arg := range args {
+		if path.Exist(arg) {
+			p := rws.FindProject(arg)
+			if p == nil {
+				return newUserErrorF("'%s' does not belong to any project", arg)
+			}
+			projects = append(projects, p)
+			continue
+		}
+		ps, err := rws.GetProjects(nil, arg)
+		if err != nil {
+			return err
+		}
+		projects = append(projects, ps...)
+	}
+
+	for _, p := range projects {
+		owners := rws.ProjectOwners(p)
+		if len(owners) == 0 {
+			fmt.Printf("%s: (no owners)\n", p.Path)
+		} else {
+			fmt.Printf("%s: %s\n", p.Path, strings.Join(owners, ", "))
+		}
+	}
+	return nil
+}
+
+var ownersCmd = ownersCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(ownersCmd.Command())
+}
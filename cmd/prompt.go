@@ -0,0 +1,239 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/path"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// promptDirtyTimeout limits time to count dirty files, so that
+	// shell prompt is not blocked by a huge project.
+	promptDirtyTimeout = 500 * time.Millisecond
+)
+
+// promptInfo is summary of workspace shown in shell prompt.
+type promptInfo struct {
+	Project string
+	Branch  string
+	Dirty   int
+	// SyncAge is time since last sync, and is zero if unknown.
+	SyncAge time.Duration
+}
+
+// String formats info as "<project>@<branch>*<dirty> <sync-age>".
+func (v promptInfo) String() string {
+	items := []string{}
+	if v.Project != "" {
+		s := v.Project
+		if v.Branch != "" {
+			s += "@" + v.Branch
+		}
+		if v.Dirty > 0 {
+			s += fmt.Sprintf("*%d", v.Dirty)
+		}
+		items = append(items, s)
+	}
+	if v.SyncAge > 0 {
+		items = append(items, formatAge(v.SyncAge))
+	}
+	return strings.Join(items, " ")
+}
+
+// formatAge formats duration in the largest unit, such as "5m", "3h"
+// or "2d".
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	}
+	return fmt.Sprintf("%ds", int(d/time.Second))
+}
+
+type promptCommand struct {
+	cmd *cobra.Command
+	O   struct {
+		Dirty bool
+	}
+}
+
+func (v *promptCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "prompt",
+		Short: "Show a compact summary of workspace for shell prompt",
+		Long: `Show a compact summary of workspace for shell prompt, such as PS1.
+
+The summary is in the form of "<project>@<branch>*<dirty> <sync-age>",
+and parts which are unknown are omitted.  The manifest is not loaded,
+projects are found in the project list saved by last sync, and nothing
+is shown outside of a repo workspace.  Dirty files are only counted with
+option --dirty, for "git status" is run each time the prompt is shown.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().BoolVar(&v.O.Dirty,
+		"dirty",
+		false,
+		"count dirty files, which runs git status and may be slow")
+
+	return v.cmd
+}
+
+func (v promptCommand) Execute(args []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	cwd, err = filepath.EvalSymlinks(cwd)
+	if err != nil {
+		return nil
+	}
+	topDir, err := path.FindTopDir(cwd)
+	if err != nil {
+		return nil
+	}
+
+	info := promptInfo{}
+	projectList := filepath.Join(topDir, config.DotRepo, "project.list")
+	if fi, err := os.Stat(projectList); err == nil {
+		info.SyncAge = time.Since(fi.ModTime())
+	}
+
+	rel, err := filepath.Rel(topDir, cwd)
+	if err == nil {
+		info.Project = findProjectPath(projectList, filepath.ToSlash(rel))
+	}
+	if info.Project != "" {
+		workDir := filepath.Join(topDir, info.Project)
+		info.Branch = readBranch(workDir)
+		if v.O.Dirty {
+			info.Dirty = countDirty(workDir)
+		}
+	}
+
+	if s := info.String(); s != "" {
+		fmt.Println(s)
+	}
+	return nil
+}
+
+// findProjectPath returns path of the project which contains dir, by
+// reading the project list file.
+func findProjectPath(projectList, dir string) string {
+	f, err := os.Open(projectList)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	found := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		p := strings.TrimSpace(scanner.Text())
+		if p == "" {
+			continue
+		}
+		if dir == p || strings.HasPrefix(dir, p+"/") {
+			// Nested projects, the innermost one wins.
+			if len(p) > len(found) {
+				found = p
+			}
+		}
+	}
+	return found
+}
+
+// readBranch reads HEAD of project in workDir, and returns branch name,
+// or an abbreviated commit ID if HEAD is detached.
+func readBranch(workDir string) string {
+	gitDir := filepath.Join(workDir, ".git")
+	// Worktree of project has a ".git" file which points to its gitdir.
+	if data, err := ioutil.ReadFile(gitDir); err == nil {
+		line := strings.TrimSpace(string(data))
+		if !strings.HasPrefix(line, "gitdir: ") {
+			return ""
+		}
+		gitDir = strings.TrimPrefix(line, "gitdir: ")
+		if !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(workDir, gitDir)
+		}
+	}
+	data, err := ioutil.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	head := strings.TrimSpace(string(data))
+	if strings.HasPrefix(head, "ref: ") {
+		return strings.TrimPrefix(strings.TrimPrefix(head, "ref: "), config.RefsHeads)
+	}
+	if len(head) > 7 {
+		head = head[:7]
+	}
+	return head
+}
+
+// countDirty returns number of changed tracked files. Untracked files are
+// not counted, and index is not updated, so that it is fast and safe.
+func countDirty(workDir string) int {
+	out, err := helper.RunCommand(&helper.Command{
+		Args: []string{
+			config.GIT,
+			"--no-optional-locks",
+			"status",
+			"--porcelain",
+			"--untracked-files=no",
+			"--ignore-submodules",
+		},
+		Dir:     workDir,
+		Timeout: promptDirtyTimeout,
+	})
+	if err != nil {
+		return 0
+	}
+	count := 0
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		if len(line) > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+var promptCmd = promptCommand{}
+
+func init() {
+	rootCmd.AddCommand(promptCmd.Command())
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromptInfoString(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("", promptInfo{}.String())
+	assert.Equal("3h", promptInfo{SyncAge: 3*time.Hour + 20*time.Minute}.String())
+	assert.Equal("apps/app@topic*2 5m", promptInfo{
+		Project: "apps/app",
+		Branch:  "topic",
+		Dirty:   2,
+		SyncAge: 5 * time.Minute,
+	}.String())
+	assert.Equal("apps/app 2d", promptInfo{
+		Project: "apps/app",
+		SyncAge: 50 * time.Hour,
+	}.String())
+	assert.Equal("30s", formatAge(30*time.Second))
+}
+
+func TestPromptFindProject(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-prompt-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	projectList := filepath.Join(tmpdir, "project.list")
+	assert.Nil(ioutil.WriteFile(projectList,
+		[]byte("apps/app\napps/app/plugins\nmain\n"), 0644))
+	assert.Equal("apps/app", findProjectPath(projectList, "apps/app"))
+	assert.Equal("apps/app", findProjectPath(projectList, "apps/app/src"))
+	assert.Equal("apps/app/plugins", findProjectPath(projectList, "apps/app/plugins/a"))
+	assert.Equal("", findProjectPath(projectList, "apps"))
+	assert.Equal("", findProjectPath(projectList, "mainline"))
+	assert.Equal("", findProjectPath(filepath.Join(tmpdir, "missing"), "main"))
+
+	workDir := filepath.Join(tmpdir, "app")
+	gitDir := filepath.Join(tmpdir, "app.git")
+	assert.Nil(os.MkdirAll(workDir, 0755))
+	assert.Nil(os.MkdirAll(gitDir, 0755))
+	assert.Nil(ioutil.WriteFile(filepath.Join(workDir, ".git"),
+		[]byte("gitdir: ../app.git\n"), 0644))
+	assert.Nil(ioutil.WriteFile(filepath.Join(gitDir, "HEAD"),
+		[]byte("ref: refs/heads/topic\n"), 0644))
+	assert.Equal("topic", readBranch(workDir))
+
+	assert.Nil(ioutil.WriteFile(filepath.Join(gitDir, "HEAD"),
+		[]byte("0123456789abcdef0123456789abcdef01234567\n"), 0644))
+	assert.Equal("0123456", readBranch(workDir))
+	assert.Equal("", readBranch(tmpdir))
+}
@@ -21,8 +21,8 @@ import (
 
 	"github.com/alibaba/git-repo-go/color"
 	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/project"
-	log "github.com/jiangxin/multi-log"
 	"github.com/spf13/cobra"
 )
 
@@ -167,7 +167,9 @@ func (v pruneCommand) Execute(args []string) error {
 					}
 				}
 			}
-			if shouldPruneCurrentBranch {
+			if shouldPruneCurrentBranch && config.IsDryRun() {
+				branches = append(branches, cb)
+			} else if shouldPruneCurrentBranch {
 				err := p.DetachHead()
 				if err != nil {
 					shouldPruneCurrentBranch = false
@@ -188,6 +190,19 @@ func (v pruneCommand) Execute(args []string) error {
 		needToClean := false
 		if len(branches) == 0 {
 			log.Debugf("no branch to prune for project %s", p.Name)
+		} else if config.IsDryRun() {
+			for _, b := range branches {
+				pb := projectBranch{
+					Branch:    b,
+					Project:   p,
+					IsCurrent: b.Name == cb.Name,
+				}
+				if v.O.Force || p.TrackBranch(b.Name) == "" || isMergedBranch(p, b) {
+					success = append(success, pb)
+				} else {
+					failure = append(failure, pb)
+				}
+			}
 		} else {
 			// run branch -d ...
 			forceDropCmdArgs = forceDropCmdArgs[:forceDropCmdArgsInitSize]
@@ -258,7 +273,9 @@ func (v pruneCommand) Execute(args []string) error {
 
 	// Show deleted branch
 	if len(success) > 0 {
-		if v.O.Force {
+		if config.IsDryRun() {
+			color.Hilightln("Branches to be pruned (dryrun)")
+		} else if v.O.Force {
 			color.Hilightln("Abandoned branches")
 		} else {
 			color.Hilightln("Pruned branches (already merged)")
@@ -339,6 +356,21 @@ func (v pruneCommand) Execute(args []string) error {
 	return nil
 }
 
+// isMergedBranch checks whether branch is merged into its tracking branch,
+// like what "git branch -d" does.
+func isMergedBranch(p *project.Project, b project.Branch) bool {
+	tb := p.LocalTrackBranch(b.Name)
+	if tb == "" {
+		return false
+	}
+	list, err := p.Revlist(b.Name, "--not", tb)
+	if err != nil {
+		log.Debugf("project %s> revlist failed for %s: %s", p.Path, b.Name, err)
+		return false
+	}
+	return len(list) == 0
+}
+
 var pruneCmd = pruneCommand{
 	WorkSpaceCommand: WorkSpaceCommand{
 		MirrorOK: false,
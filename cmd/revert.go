@@ -0,0 +1,78 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+type revertCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		NoCache bool
+		Remote  string
+	}
+}
+
+func (v *revertCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "revert [<project>] <sha1>|<change>[/<patchset>]",
+		Short: "Revert a change",
+		Long: `Create a commit in the current branch of the project to revert a
+commit, or a change from the review system.
+
+The project and the change are resolved in the same way as the
+cherry-pick command. A new Change-Id is added if the reverted commit has
+one, so the result can be uploaded as a new change.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().BoolVar(&v.O.NoCache,
+		"no-cache",
+		false,
+		"Ignore ssh-info cache, and recheck ssh-info API")
+	v.cmd.Flags().StringVar(&v.O.Remote,
+		"remote",
+		"",
+		"use specific remote to download change (use with --single)")
+
+	return v.cmd
+}
+
+func (v *revertCommand) Execute(args []string) error {
+	p, commit, err := resolveChange(v.WorkSpace(), v.O.Remote, v.O.NoCache, args...)
+	if err != nil {
+		return err
+	}
+	return p.RevertChange(commit)
+}
+
+var revertCmd = revertCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: true,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(revertCmd.Command())
+}
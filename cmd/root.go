@@ -28,10 +28,12 @@ import (
 	"path/filepath"
 
 	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/alibaba/git-repo-go/path"
 	"github.com/alibaba/git-repo-go/version"
 
-	log "github.com/jiangxin/multi-log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -59,6 +61,17 @@ func (r Response) IsUserError() bool {
 	return r.Err != nil && isUserError(r.Err)
 }
 
+// ExitCode returns exit code for the error of subcommand.
+func (r Response) ExitCode() int {
+	if r.Err == nil {
+		return 0
+	}
+	if cErr, ok := r.Err.(commandError); ok && cErr.exitCode != 0 {
+		return cErr.exitCode
+	}
+	return -1
+}
+
 type rootCommand struct {
 	cmd *cobra.Command
 
@@ -113,6 +126,9 @@ this command with special options.`,
 	v.cmd.PersistentFlags().Bool("dryrun",
 		false,
 		"dryrun mode")
+	v.cmd.PersistentFlags().Bool("wait",
+		false,
+		"wait for locks held by other git-repo processes, instead of failing")
 	v.cmd.PersistentFlags().BoolP("quiet",
 		"q",
 		false,
@@ -123,6 +139,9 @@ this command with special options.`,
 	v.cmd.PersistentFlags().CountP("verbose",
 		"v",
 		"verbose mode")
+	v.cmd.PersistentFlags().String("log-modules",
+		"",
+		"override log level of modules, such as 'project/network-half=debug,helper=warn'")
 	v.cmd.PersistentFlags().Bool("mock-no-symlink",
 		false,
 		"mock no symlink cap")
@@ -152,6 +171,9 @@ this command with special options.`,
 	viper.BindPFlag(
 		"dryrun",
 		v.cmd.PersistentFlags().Lookup("dryrun"))
+	viper.BindPFlag(
+		"wait",
+		v.cmd.PersistentFlags().Lookup("wait"))
 	viper.BindPFlag(
 		"quiet",
 		v.cmd.PersistentFlags().Lookup("quiet"))
@@ -161,6 +183,9 @@ this command with special options.`,
 	viper.BindPFlag(
 		"verbose",
 		v.cmd.PersistentFlags().Lookup("verbose"))
+	viper.BindPFlag(
+		"logmodules",
+		v.cmd.PersistentFlags().Lookup("log-modules"))
 	viper.BindPFlag(
 		"mock-no-symlink",
 		v.cmd.PersistentFlags().Lookup("mock-no-symlink"))
@@ -238,13 +263,24 @@ func (v rootCommand) initConfig() {
 }
 
 func (v rootCommand) initLog() {
-	log.Init(log.Options{
+	options := log.Options{
 		Verbose:       config.GetVerbose(),
 		Quiet:         config.GetQuiet(),
 		LogFile:       config.GetLogFile(),
 		LogLevel:      config.GetLogLevel(),
 		LogRotateSize: config.GetLogRotateSize(),
-	})
+		Modules:       config.GetLogModules(),
+	}
+	// Save log of commands run in workspace, such as a failed sync.
+	if config.GetWorkspaceLog() {
+		if topDir, err := path.FindTopDir(""); err == nil {
+			options.JSONFile = filepath.Join(topDir, config.DotRepo, config.WorkspaceLogFile)
+			options.JSONLevel = config.GetWorkspaceLogLevel()
+			options.JSONRotateSize = config.GetLogRotateSize()
+			options.JSONKeep = config.GetWorkspaceLogKeep()
+		}
+	}
+	log.Init(options)
 }
 
 func (v *rootCommand) AddCommand(cmds ...*cobra.Command) {
@@ -258,9 +294,12 @@ func Execute() Response {
 		resp Response
 	)
 
+	defer recoverCrash()
+	helper.Trace2Start(os.Args)
 	c, err := rootCmd.Command().ExecuteC()
 	resp.Err = err
 	resp.Cmd = c
+	helper.Trace2Exit(resp.ExitCode() & 0xff)
 	return resp
 }
 
@@ -0,0 +1,404 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/alibaba/git-repo-go/workspace"
+	"github.com/spf13/cobra"
+)
+
+// serveProject is a project in project list of "git repo serve".
+type serveProject struct {
+	Name       string   `json:"name"`
+	Path       string   `json:"path"`
+	Remote     string   `json:"remote"`
+	Revision   string   `json:"revision"`
+	Upstream   string   `json:"upstream,omitempty"`
+	DestBranch string   `json:"dest_branch,omitempty"`
+	Groups     []string `json:"groups,omitempty"`
+}
+
+// serveRevision is the resolved revision of a project.
+type serveRevision struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Revision string `json:"revision"`
+	Commit   string `json:"commit,omitempty"`
+	Head     string `json:"head,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// serveStatus is status of the loaded manifest.
+type serveStatus struct {
+	Commit   string    `json:"commit"`
+	Loaded   time.Time `json:"loaded"`
+	Projects int       `json:"projects"`
+}
+
+// serveState is manifest and projects of workspace served by "git repo
+// serve", which is reloaded when manifest is changed.
+type serveState struct {
+	// Commit is HEAD of manifest project.
+	Commit   string
+	Loaded   time.Time
+	Manifest []byte
+	// Pinned is manifest with projects pinned to commits checked out.
+	Pinned   []byte
+	Projects []*project.Project
+
+	adminDir string
+	mp       *project.ManifestProject
+	stamp    string
+	heads    string
+}
+
+// Timeouts of HTTP server, so that slow or idle clients do not hold
+// connections forever.
+const (
+	serveReadTimeout  = 30 * time.Second
+	serveWriteTimeout = 2 * time.Minute
+	serveIdleTimeout  = 2 * time.Minute
+)
+
+// manifestStamp returns stamp of manifest files in admin dir and HEAD of
+// manifest project, which changes if manifest is changed.
+func manifestStamp(adminDir string, mp *project.ManifestProject) string {
+	stamp := []string{}
+	if mp != nil {
+		head, _ := mp.ResolveRevision("HEAD")
+		stamp = append(stamp, head)
+	}
+	files := []string{
+		filepath.Join(adminDir, config.ManifestXML),
+		filepath.Join(adminDir, config.LocalManifestXML),
+	}
+	matches, _ := filepath.Glob(filepath.Join(adminDir, config.LocalManifests, "*.xml"))
+	files = append(files, matches...)
+	for _, file := range files {
+		if fi, err := os.Stat(file); err == nil {
+			stamp = append(stamp, fmt.Sprintf("%s:%d:%d",
+				filepath.Base(file), fi.Size(), fi.ModTime().UnixNano()))
+		}
+	}
+	return strings.Join(stamp, ";")
+}
+
+// projectsStamp returns commits which projects are checked out, or
+// revisions of projects in a mirror, which changes the pinned manifest.
+func projectsStamp(projects []*project.Project) string {
+	stamp := []string{}
+	for _, p := range projects {
+		rev := "HEAD"
+		if p.IsMirror() {
+			rev = p.Revision
+		}
+		commit, _ := p.ResolveRevision(rev)
+		stamp = append(stamp, p.Path+":"+commit)
+	}
+	return strings.Join(stamp, ";")
+}
+
+// loadServeState loads workspace, and saves merged and pinned manifests.
+func loadServeState() (*serveState, error) {
+	rws, err := workspace.NewRepoWorkSpace("")
+	if err != nil {
+		return nil, err
+	}
+	state := serveState{
+		Loaded:   time.Now(),
+		Projects: rws.Projects,
+		adminDir: rws.AdminDir(),
+		mp:       rws.ManifestProject,
+	}
+	state.stamp = manifestStamp(state.adminDir, state.mp)
+	state.heads = projectsStamp(state.Projects)
+	state.Commit, _ = state.mp.ResolveRevision("HEAD")
+	if state.Manifest, err = rws.Manifest.Marshal(); err != nil {
+		return nil, err
+	}
+	// FreezeManifest changes manifest in place, marshal it at last.
+	if err = rws.FreezeManifest(true, true); err != nil {
+		return nil, err
+	}
+	if state.Pinned, err = rws.Manifest.Marshal(); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// manifestServer serves manifest, project list and revisions of projects
+// over HTTP.
+type manifestServer struct {
+	state *serveState
+	mutex sync.RWMutex
+	// resolveMutex serializes access to repositories of projects.
+	resolveMutex sync.Mutex
+}
+
+// Update replaces served state.
+func (v *manifestServer) Update(state *serveState) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.state = state
+}
+
+// State returns served state.
+func (v *manifestServer) State() *serveState {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	return v.state
+}
+
+// Handler returns handler of all endpoints.
+func (v *manifestServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/manifest", v.serveManifest)
+	mux.HandleFunc("/projects", v.serveProjects)
+	mux.HandleFunc("/revisions", v.serveRevisions)
+	mux.HandleFunc("/status", v.serveStatus)
+	return mux
+}
+
+// writeJSON writes data in JSON format.
+func writeJSON(w http.ResponseWriter, data interface{}) {
+	out, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(out, '\n'))
+}
+
+// serveManifest serves merged manifest, or pinned manifest with
+// "?pinned=1".
+func (v *manifestServer) serveManifest(w http.ResponseWriter, r *http.Request) {
+	state := v.State()
+	data := state.Manifest
+	if pinned := r.URL.Query().Get("pinned"); pinned != "" && pinned != "0" && pinned != "false" {
+		data = state.Pinned
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write(data)
+}
+
+// serveProjects serves project list, filtered by "?groups=<groups>".
+func (v *manifestServer) serveProjects(w http.ResponseWriter, r *http.Request) {
+	groups := r.URL.Query().Get("groups")
+	projects := []*serveProject{}
+	for _, p := range v.State().Projects {
+		if groups != "" && !p.MatchGroups(groups) {
+			continue
+		}
+		projects = append(projects, &serveProject{
+			Name:       p.Name,
+			Path:       p.Path,
+			Remote:     p.RemoteName,
+			Revision:   p.Revision,
+			Upstream:   p.Upstream,
+			DestBranch: p.DestBranch,
+			Groups: strings.FieldsFunc(p.Groups, func(c rune) bool {
+				return c == ',' || c == ' '
+			}),
+		})
+	}
+	writeJSON(w, projects)
+}
+
+// serveRevisions resolves revisions of projects, which are given by name
+// or path in "?project=<project>", to commits.
+func (v *manifestServer) serveRevisions(w http.ResponseWriter, r *http.Request) {
+	args := r.URL.Query()["project"]
+	if len(args) == 0 {
+		http.Error(w, "no project to resolve", http.StatusBadRequest)
+		return
+	}
+
+	v.resolveMutex.Lock()
+	defer v.resolveMutex.Unlock()
+
+	state := v.State()
+	revisions := []*serveRevision{}
+	for _, arg := range args {
+		found := false
+		for _, p := range state.Projects {
+			if p.Name != arg && p.Path != strings.Trim(arg, "/") {
+				continue
+			}
+			found = true
+			rev := serveRevision{
+				Name:     p.Name,
+				Path:     p.Path,
+				Revision: p.Revision,
+			}
+			if commit, err := p.ResolveRemoteTracking(p.Revision); err != nil {
+				rev.Error = err.Error()
+			} else {
+				rev.Commit = commit
+			}
+			if !p.IsMirror() && p.Exists() {
+				rev.Head, _ = p.ResolveRevision("HEAD")
+			}
+			revisions = append(revisions, &rev)
+		}
+		if !found {
+			http.Error(w, fmt.Sprintf("project '%s' not found", arg), http.StatusNotFound)
+			return
+		}
+	}
+	writeJSON(w, revisions)
+}
+
+// serveStatus serves status of the loaded manifest.
+func (v *manifestServer) serveStatus(w http.ResponseWriter, r *http.Request) {
+	state := v.State()
+	writeJSON(w, &serveStatus{
+		Commit:   state.Commit,
+		Loaded:   state.Loaded,
+		Projects: len(state.Projects),
+	})
+}
+
+type serveCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		Listen   string
+		Interval time.Duration
+		Fetch    bool
+	}
+}
+
+func (v *serveCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Serve manifest and projects over HTTP",
+		Long: `Run a HTTP service, which serves the merged manifest (GET /manifest),
+the manifest with projects pinned to commits checked out (GET
+/manifest?pinned=1), list of projects in JSON (GET /projects, filtered by
+?groups=<groups>), commits of revisions of projects (GET
+/revisions?project=<name or path>) and status (GET /status).
+
+Manifest files and commits which projects are checked out are checked at
+the given interval, and are reloaded if changed. With --fetch, the manifest project is also updated from remote
+as sync does.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().StringVar(&v.O.Listen,
+		"listen",
+		"127.0.0.1:8080",
+		"serve on this address")
+	v.cmd.Flags().DurationVar(&v.O.Interval,
+		"interval",
+		30*time.Second,
+		"check changes of manifest at this interval")
+	v.cmd.Flags().BoolVar(&v.O.Fetch,
+		"fetch",
+		false,
+		"update manifest project from remote at each interval")
+
+	return v.cmd
+}
+
+// watch reloads state of server if manifest is changed, or projects are
+// checked out at other commits, such as by sync.
+func (v serveCommand) watch(server *manifestServer) {
+	defer recoverCrash()
+	for {
+		time.Sleep(v.O.Interval)
+		if v.O.Fetch {
+			s := syncCmd
+			if err := s.updateManifestProject(); err != nil {
+				log.Errorf("fail to update manifest project: %s", err)
+			}
+		}
+		state := server.State()
+		if manifestStamp(state.adminDir, state.mp) == state.stamp &&
+			projectsStamp(state.Projects) == state.heads {
+			continue
+		}
+		newState, err := loadServeState()
+		if err != nil {
+			log.Errorf("fail to reload manifest: %s", err)
+			continue
+		}
+		server.Update(newState)
+		log.Notef("reloaded manifest (%s)", newState.Commit)
+	}
+}
+
+func (v serveCommand) Execute(args []string) error {
+	if len(args) > 0 {
+		return newUserErrorF("unknown args: %s", strings.Join(args, " "))
+	}
+	if v.O.Interval <= 0 {
+		return newUserError("--interval must be positive")
+	}
+	v.RepoWorkSpace()
+
+	state, err := loadServeState()
+	if err != nil {
+		return err
+	}
+	server := manifestServer{}
+	server.Update(state)
+
+	listener, err := net.Listen("tcp", v.O.Listen)
+	if err != nil {
+		return fmt.Errorf("fail to listen on '%s': %s", v.O.Listen, err)
+	}
+	defer listener.Close()
+
+	go v.watch(&server)
+	log.Notef("serve manifest on http://%s/", listener.Addr())
+	httpServer := http.Server{
+		Handler:      server.Handler(),
+		ReadTimeout:  serveReadTimeout,
+		WriteTimeout: serveWriteTimeout,
+		IdleTimeout:  serveIdleTimeout,
+	}
+	return httpServer.Serve(listener)
+}
+
+var serveCmd = serveCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: true,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd.Command())
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestStamp(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-serve-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	assert.Nil(ioutil.WriteFile(filepath.Join(tmpdir, config.ManifestXML), []byte("<manifest/>\n"), 0644))
+	stamp := manifestStamp(tmpdir, nil)
+	assert.Equal(stamp, manifestStamp(tmpdir, nil))
+
+	assert.Nil(os.MkdirAll(filepath.Join(tmpdir, config.LocalManifests), 0755))
+	assert.Nil(ioutil.WriteFile(filepath.Join(tmpdir, config.LocalManifests, "local.xml"), []byte("<manifest/>\n"), 0644))
+	assert.NotEqual(stamp, manifestStamp(tmpdir, nil))
+}
+
+func TestProjectsStamp(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-serve-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	workDir := filepath.Join(tmpdir, "app")
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", workDir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=A", "GIT_AUTHOR_EMAIL=a@example.com",
+			"GIT_COMMITTER_NAME=A", "GIT_COMMITTER_EMAIL=a@example.com")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			panic(string(out))
+		}
+	}
+	assert.Nil(os.MkdirAll(workDir, 0755))
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "initial")
+
+	p := project.Project{}
+	p.Name = "platform/app"
+	p.Path = "app"
+	p.Settings = &project.RepoSettings{TopDir: tmpdir}
+	p.GitDir = filepath.Join(workDir, ".git")
+	p.WorkDir = workDir
+	projects := []*project.Project{&p}
+
+	stamp := projectsStamp(projects)
+	assert.Equal(len("app:")+40, len(stamp))
+	assert.Equal(stamp, projectsStamp(projects))
+	git("commit", "-q", "--allow-empty", "-m", "second")
+	assert.NotEqual(stamp, projectsStamp(projects))
+}
+
+func TestManifestServer(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-serve-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	newProject := func(name, path, groups string) *project.Project {
+		p := project.Project{}
+		p.Name = name
+		p.Path = path
+		p.RemoteName = "origin"
+		p.Revision = "master"
+		p.Groups = groups
+		p.Settings = &project.RepoSettings{TopDir: tmpdir}
+		p.GitDir = filepath.Join(tmpdir, "projects", path+".git")
+		return &p
+	}
+	server := manifestServer{}
+	server.Update(&serveState{
+		Commit:   "1111111111111111111111111111111111111111",
+		Loaded:   time.Unix(1577923200, 0),
+		Manifest: []byte("<manifest/>\n"),
+		Pinned:   []byte("<manifest pinned/>\n"),
+		Projects: []*project.Project{
+			newProject("platform/app", "app", "apps"),
+			newProject("platform/lib", "lib", "libs,tools"),
+		},
+	})
+	handler := server.Handler()
+
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w
+	}
+
+	w := get("/manifest")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("<manifest/>\n", w.Body.String())
+	w = get("/manifest?pinned=1")
+	assert.Equal("<manifest pinned/>\n", w.Body.String())
+
+	projects := []serveProject{}
+	w = get("/projects")
+	assert.Equal("application/json", w.Header().Get("Content-Type"))
+	assert.Nil(json.Unmarshal(w.Body.Bytes(), &projects))
+	assert.Equal(2, len(projects))
+	assert.Equal([]string{"libs", "tools"}, projects[1].Groups)
+	w = get("/projects?groups=tools")
+	assert.Nil(json.Unmarshal(w.Body.Bytes(), &projects))
+	assert.Equal(1, len(projects))
+	assert.Equal("lib", projects[0].Path)
+
+	w = get("/revisions")
+	assert.Equal(http.StatusBadRequest, w.Code)
+	w = get("/revisions?project=missing")
+	assert.Equal(http.StatusNotFound, w.Code)
+	revisions := []serveRevision{}
+	w = get("/revisions?project=platform/app&project=lib/")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Nil(json.Unmarshal(w.Body.Bytes(), &revisions))
+	assert.Equal(2, len(revisions))
+	assert.Equal("app", revisions[0].Path)
+	assert.Equal("", revisions[0].Commit)
+	assert.Equal("repository for platform/app is missing, fail to parse master", revisions[0].Error)
+	assert.Equal("lib", revisions[1].Path)
+
+	status := serveStatus{}
+	w = get("/status")
+	assert.Nil(json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal("1111111111111111111111111111111111111111", status.Commit)
+	assert.Equal(2, status.Projects)
+}
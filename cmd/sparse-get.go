@@ -0,0 +1,81 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type sparseGetCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+}
+
+func (v *sparseGetCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "get [<project>...]",
+		Short: "Show directories of sparse checkout of projects",
+		Long: `Show directories of sparse checkout of projects, and whether they are
+set in manifest or in this workspace. Projects in full checkout are not
+shown unless given in command line.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+
+	return v.cmd
+}
+
+func (v sparseGetCommand) Execute(args []string) error {
+	projects, err := v.RepoWorkSpace().GetProjects(nil, args...)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range projects {
+		paths, override := p.SparseCheckoutPaths()
+		if len(paths) == 0 {
+			if len(args) > 0 {
+				fmt.Printf("%s: (full checkout)\n", p.Path)
+			}
+			continue
+		}
+		source := "manifest"
+		if override {
+			source = "workspace"
+		}
+		fmt.Printf("%s: %s (%s)\n", p.Path, strings.Join(paths, " "), source)
+	}
+	return nil
+}
+
+var sparseGetCmd = sparseGetCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	sparseCmd.Command().AddCommand(sparseGetCmd.Command())
+}
@@ -0,0 +1,120 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/spf13/cobra"
+)
+
+type sparseSetCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		Reset bool
+	}
+}
+
+func (v *sparseSetCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "set <project> (<dir>... | --reset)",
+		Short: "Set directories of sparse checkout of a project",
+		Long: `Set directories of a project to check out in this workspace, which
+override sparse-checkout elements of the project in manifest, and update
+the worktree of the project. With --reset, directories of manifest are
+used again.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().BoolVar(&v.O.Reset,
+		"reset",
+		false,
+		"remove directories set in workspace, and use those of manifest")
+
+	return v.cmd
+}
+
+// cleanSparsePaths returns directories relative to top of project.
+func cleanSparsePaths(dirs []string) ([]string, error) {
+	paths := []string{}
+	for _, dir := range dirs {
+		p := strings.Trim(filepath.ToSlash(filepath.Clean(dir)), "/")
+		if p == "" || p == "." || p == ".." || strings.HasPrefix(p, "../") ||
+			filepath.IsAbs(dir) || strings.Contains(p, ",") {
+			return nil, newUserErrorF("bad directory '%s' of sparse checkout", dir)
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+func (v sparseSetCommand) Execute(args []string) error {
+	if v.O.Reset && len(args) > 1 {
+		return newUserError("cannot set directories with --reset")
+	}
+	if !v.O.Reset && len(args) < 2 {
+		return newUserError("no directories to check out, use --reset to remove them")
+	}
+	paths, err := cleanSparsePaths(args[1:])
+	if err != nil {
+		return err
+	}
+
+	projects, err := v.RepoWorkSpace().GetProjects(nil, args[0])
+	if err != nil {
+		return err
+	}
+	if len(projects) != 1 {
+		return newUserErrorF("'%s' matches %d projects, only one is allowed",
+			args[0], len(projects))
+	}
+	p := projects[0]
+
+	unlock, err := p.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err = p.SetSparseCheckoutPaths(paths); err != nil {
+		return err
+	}
+	if !p.Exists() {
+		log.Notef("%ssparse checkout will be applied by the next sync", p.Prompt())
+		return nil
+	}
+	return p.ApplySparseCheckout()
+}
+
+var sparseSetCmd = sparseSetCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	sparseCmd.Command().AddCommand(sparseSetCmd.Command())
+}
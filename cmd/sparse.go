@@ -0,0 +1,44 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+type sparseCommand struct {
+	cmd *cobra.Command
+}
+
+func (v *sparseCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+	v.cmd = &cobra.Command{
+		Use:   "sparse <subcommand>",
+		Short: "Manage sparse checkout of projects",
+		Long: `Show or change directories of projects to check out in sparse checkout
+(cone mode of "git sparse-checkout"). Directories are set by
+sparse-checkout elements of projects in manifest, and can be overridden
+in this workspace by "git repo sparse set".`,
+	}
+	return v.cmd
+}
+
+var sparseCmd = sparseCommand{}
+
+func init() {
+	rootCmd.AddCommand(sparseCmd.Command())
+}
@@ -0,0 +1,210 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/color"
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/path"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/spf13/cobra"
+)
+
+type stageCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		Interactive bool
+	}
+}
+
+func (v *stageCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "stage -i [<project>...]",
+		Short: "Stage file(s) for commit",
+		Long: `List projects which have unstaged changes, and run
+"git add -A" in the projects selected by the user.
+
+Select projects by their numbers, paths or names separated by spaces,
+use "*" to select all of them, or "q" to quit.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().BoolVarP(&v.O.Interactive,
+		"interactive",
+		"i",
+		false,
+		"use interactive staging")
+
+	return v.cmd
+}
+
+func (v stageCommand) Execute(args []string) error {
+	if !v.O.Interactive {
+		return newUserError("only interactive mode is supported, use -i")
+	}
+
+	ws := v.RepoWorkSpace()
+	allProjects, err := ws.GetProjects(nil, args...)
+	if err != nil {
+		return err
+	}
+
+	projects := []*project.Project{}
+	for _, p := range allProjects {
+		if !path.Exist(p.WorkDir) {
+			continue
+		}
+		cmdArgs := []string{project.GIT, "status", "--porcelain", "--", "."}
+		cmdArgs = append(cmdArgs, nestedProjectsExcludes(ws.Projects, p)...)
+		if hasUnstagedChanges(p.ExecuteCommand(cmdArgs...).Stdout()) {
+			projects = append(projects, p)
+		}
+	}
+
+	if len(projects) == 0 {
+		log.Note("no projects have unstaged changes")
+		return nil
+	}
+
+	color.Hilightln("Projects with unstaged changes")
+	for i, p := range projects {
+		fmt.Printf("%3d: %s/\n", i+1, p.Path)
+	}
+
+	answer := userInput("Stage which projects (\"*\" for all, \"q\" to quit)? ", "q")
+	selected, err := selectProjects(projects, answer)
+	if err != nil {
+		return err
+	}
+	if len(selected) == 0 {
+		log.Note("nothing staged")
+		return nil
+	}
+
+	var execError error
+	for _, p := range selected {
+		if config.IsDryRun() {
+			log.Notef("%swill stage all changes", p.Prompt())
+			continue
+		}
+		cmdArgs := []string{project.GIT, "add", "-A", "--", "."}
+		cmdArgs = append(cmdArgs, nestedProjectsExcludes(ws.Projects, p)...)
+		result := p.ExecuteCommand(cmdArgs...)
+		if result.Error != nil {
+			log.Errorf("%sfail to stage: %s", p.Prompt(), result.Stderr())
+			execError = result.Error
+			continue
+		}
+		log.Notef("staged changes in %s", p.Path)
+	}
+	return execError
+}
+
+// hasUnstagedChanges checks output of "git status --porcelain" for changes
+// in worktree, including untracked files.
+func hasUnstagedChanges(status string) bool {
+	for _, line := range strings.Split(status, "\n") {
+		if len(line) > 2 && line[1] != ' ' {
+			return true
+		}
+	}
+	return false
+}
+
+// nestedProjectsExcludes returns pathspecs to exclude nested projects of p,
+// so they won't be staged as gitlinks. Nested projects which are already
+// ignored (excluded by sync) are skipped, for git-add fails on pathspecs
+// of ignored paths.
+func nestedProjectsExcludes(projects []*project.Project, p *project.Project) []string {
+	excludes := []string{}
+	for _, nested := range projects {
+		if strings.HasPrefix(nested.Path, p.Path+"/") {
+			rel := strings.TrimPrefix(nested.Path, p.Path+"/")
+			if p.ExecuteCommand(project.GIT, "check-ignore", "-q", rel).Success() {
+				continue
+			}
+			excludes = append(excludes, ":(exclude)"+rel)
+		}
+	}
+	return excludes
+}
+
+// selectProjects parses user's answer of project numbers, paths or names.
+func selectProjects(projects []*project.Project, answer string) ([]*project.Project, error) {
+	var (
+		selected []*project.Project
+		seen     = make(map[int]bool)
+	)
+
+	answer = strings.TrimSpace(answer)
+	switch strings.ToLower(answer) {
+	case "", "q", "quit", "n", "no":
+		return nil, nil
+	case "*", "a", "all", "y", "yes":
+		return projects, nil
+	}
+
+	for _, item := range strings.FieldsFunc(answer, func(c rune) bool {
+		return c == ' ' || c == ',' || c == '\t'
+	}) {
+		idx := -1
+		if n, err := strconv.Atoi(item); err == nil {
+			if n < 1 || n > len(projects) {
+				return nil, fmt.Errorf("bad project number: %d", n)
+			}
+			idx = n - 1
+		} else {
+			name := strings.TrimSuffix(item, "/")
+			for i, p := range projects {
+				if p.Path == name || p.Name == name {
+					idx = i
+					break
+				}
+			}
+			if idx < 0 {
+				return nil, errors.New("unknown project: " + item)
+			}
+		}
+		if !seen[idx] {
+			seen[idx] = true
+			selected = append(selected, projects[idx])
+		}
+	}
+	return selected, nil
+}
+
+var stageCmd = stageCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(stageCmd.Command())
+}
@@ -15,7 +15,7 @@
 package cmd
 
 import (
-	log "github.com/jiangxin/multi-log"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/spf13/cobra"
 )
 
@@ -34,7 +34,7 @@ func (v *startCommand) Command() *cobra.Command {
 	}
 
 	v.cmd = &cobra.Command{
-		Use:   "start",
+		Use:   "start <branch> [<project>...]",
 		Short: "Start a new branch for development",
 		Long:  `Begin a new branch of development, starting from the revision specified in the manifest.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
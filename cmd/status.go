@@ -15,25 +15,91 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/alibaba/git-repo-go/color"
 	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/gerrit"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/path"
 	"github.com/alibaba/git-repo-go/project"
-	log "github.com/jiangxin/multi-log"
 	"github.com/spf13/cobra"
 )
 
+const (
+	// statusDefaultJobs is the default value of --jobs
+	statusDefaultJobs = 2
+	// statusMaxReviewCommits is max number of commits to query reviews
+	statusMaxReviewCommits = 20
+)
+
+// statusResult wraps output of git status and the divergence of
+// current branch from its tracking branch.
+type statusResult struct {
+	*project.CmdExecResult
+
+	Ahead  int
+	Behind int
+
+	// Open changes on Gerrit of current branch, for --reviews.
+	Reviews    []gerrit.ChangeInfo
+	ReviewsErr error
+
+	// Number of changed files, for --porcelain and --json.
+	Counts *project.StatusCounts
+}
+
+// statusRecord is status of a project for --porcelain and --json. Fields
+// are stable for scripts and editor plugins.
+type statusRecord struct {
+	Project   string `json:"project"`
+	Path      string `json:"path"`
+	Branch    string `json:"branch"`
+	Ahead     int    `json:"ahead"`
+	Behind    int    `json:"behind"`
+	Staged    int    `json:"staged"`
+	Unstaged  int    `json:"unstaged"`
+	Untracked int    `json:"untracked"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Porcelain returns fields of record separated by tabs, in the order of
+// project, path, branch, ahead, behind, staged, unstaged and untracked.
+func (v statusRecord) Porcelain() string {
+	return fmt.Sprintf("%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d",
+		v.Project,
+		v.Path,
+		v.Branch,
+		v.Ahead,
+		v.Behind,
+		v.Staged,
+		v.Unstaged,
+		v.Untracked)
+}
+
+// Diverged indicates current branch is ahead of or behind its upstream.
+func (v statusResult) Diverged() bool {
+	return v.Ahead > 0 || v.Behind > 0
+}
+
 type statusCommand struct {
 	WorkSpaceCommand
 
 	cmd *cobra.Command
 	O   struct {
-		Jobs    int
-		Orphans bool
+		Jobs      int
+		Orphans   bool
+		Reviews   bool
+		Porcelain bool
+		JSON      bool
 	}
 }
 
@@ -43,7 +109,7 @@ func (v *statusCommand) Command() *cobra.Command {
 	}
 
 	v.cmd = &cobra.Command{
-		Use:   "status",
+		Use:   "status [<project>...]",
 		Short: "Show the working tree status",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return v.Execute(args)
@@ -54,11 +120,26 @@ func (v *statusCommand) Command() *cobra.Command {
 		"o",
 		false,
 		"include objects in working directory outside of repo projects")
+	v.cmd.Flags().BoolVar(&v.O.Reviews,
+		"reviews",
+		false,
+		"show open code reviews on Gerrit of current branch")
+	v.cmd.Flags().BoolVar(&v.O.Porcelain,
+		"porcelain",
+		false,
+		"show status of each project in one line with tab separated fields: "+
+			"project, path, branch, ahead, behind, staged, unstaged and untracked")
+	v.cmd.Flags().BoolVar(&v.O.JSON,
+		"json",
+		false,
+		"show status of projects in JSON format")
 	v.cmd.Flags().IntVarP(&v.O.Jobs,
 		"jobs",
 		"j",
-		2,
-		"number of projects to check simultaneously")
+		0,
+		fmt.Sprintf("number of projects to check simultaneously "+
+			"(default: repo.jobs of workspace, sync-j of manifest, or %d)",
+			statusDefaultJobs))
 
 	return v.cmd
 }
@@ -69,11 +150,16 @@ func (v statusCommand) Execute(args []string) error {
 		err      error
 	)
 
+	if v.O.Porcelain && v.O.JSON {
+		return newUserError("cannot combine --porcelain and --json")
+	}
+	if v.machineOutput() && (v.O.Orphans || v.O.Reviews) {
+		return newUserError("cannot combine --orphans or --reviews with --porcelain or --json")
+	}
+
 	ws := v.RepoWorkSpace()
 
-	if v.O.Jobs < 1 {
-		v.O.Jobs = 1
-	}
+	v.O.Jobs = ws.Jobs(jobsOption(v.cmd, v.O.Jobs), statusDefaultJobs)
 
 	projects, err = ws.GetProjects(nil, args...)
 	if err != nil {
@@ -81,24 +167,48 @@ func (v statusCommand) Execute(args []string) error {
 	}
 
 	if len(projects) == 0 {
-		log.Infof("no projects")
+		if v.O.JSON {
+			fmt.Println("[]")
+		} else if !v.O.Porcelain {
+			log.Infof("no projects")
+		}
 		return nil
 	}
 
-	return v.RunCommand(projects)
+	if v.O.Reviews {
+		if err = ws.LoadRemotes(false); err != nil {
+			log.Warnf("fail to load remotes: %s", err)
+		}
+	}
+
+	err = v.RunCommand(projects)
+	if err != nil {
+		return err
+	}
+
+	if v.O.Orphans {
+		v.showOrphans(ws.RootDir, projects)
+	}
+	return nil
 }
 
 func (v statusCommand) RunCommand(projects []*project.Project) error {
+	type indexedResult struct {
+		idx    int
+		result *statusResult
+	}
+
 	var (
 		jobs       = v.O.Jobs
 		jobTasks   = make(chan int, jobs)
-		jobResults = make(chan *project.CmdExecResult, jobs)
+		jobResults = make(chan indexedResult, jobs)
 	)
 
 	worker := func(i int) {
+		defer recoverCrash()
 		log.Debugf("start command worker #%d", i)
 		for idx := range jobTasks {
-			jobResults <- v.executeCommand(projects[idx])
+			jobResults <- indexedResult{idx, v.executeCommand(projects[idx])}
 		}
 	}
 
@@ -113,32 +223,91 @@ func (v statusCommand) RunCommand(projects []*project.Project) error {
 		close(jobTasks)
 	}()
 
+	// Show results in the order of projects, not in the order of
+	// finished jobs, so that output is stable.
 	isClean := true
 	count := len(projects)
+	records := []statusRecord{}
+	results := make([]*statusResult, count)
+	finished := make([]bool, count)
+	next := 0
 	for i := 0; i < count; i++ {
-		result := <-jobResults
-		if result == nil {
-			continue
+		r := <-jobResults
+		results[r.idx] = r.result
+		finished[r.idx] = true
+		for ; next < count && finished[next]; next++ {
+			result := results[next]
+			if result == nil {
+				continue
+			}
+			if v.machineOutput() {
+				record := v.newRecord(result)
+				if v.O.Porcelain {
+					fmt.Println(record.Porcelain())
+				} else {
+					records = append(records, record)
+				}
+				continue
+			}
+			if !result.Empty() {
+				isClean = false
+			}
+			v.showResult(result, next, count)
 		}
-		if !result.Empty() {
-			isClean = false
+	}
+
+	if v.O.JSON {
+		out, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return err
 		}
-		v.showResult(result, i, count)
+		fmt.Println(string(out))
+		return nil
 	}
 
-	if isClean {
+	if isClean && !v.O.Porcelain {
 		log.Note("nothing to commit (working directory clean)")
 	}
 
-	// TODO: handle --orphans option
-
 	return nil
 }
 
-func (v statusCommand) showResult(result *project.CmdExecResult, i, count int) {
+// machineOutput indicates status is shown by --porcelain or --json.
+func (v statusCommand) machineOutput() bool {
+	return v.O.Porcelain || v.O.JSON
+}
+
+// newRecord converts result to record for --porcelain and --json.
+// Error of project is also written to stderr for --porcelain.
+func (v statusCommand) newRecord(result *statusResult) statusRecord {
+	record := statusRecord{
+		Ahead:  result.Ahead,
+		Behind: result.Behind,
+	}
+	if p := result.Project; p != nil {
+		record.Project = p.Name
+		record.Path = p.Path
+		record.Branch = strings.TrimPrefix(p.GetHead(), config.RefsHeads)
+	}
+	if result.Counts != nil {
+		record.Staged = result.Counts.Staged
+		record.Unstaged = result.Counts.Unstaged
+		record.Untracked = result.Counts.Untracked
+	}
+	if result.Error != nil {
+		record.Error = result.Error.Error()
+		if v.O.Porcelain {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", record.Path, record.Error)
+		}
+	}
+	return record
+}
+
+func (v statusCommand) showResult(result *statusResult, i, count int) {
 	stdout := result.Stdout()
 	stderr := result.Stderr()
-	if stdout == "" && stderr == "" {
+	if stdout == "" && stderr == "" && !result.Diverged() &&
+		len(result.Reviews) == 0 && result.ReviewsErr == nil {
 		return
 	}
 
@@ -169,10 +338,19 @@ func (v statusCommand) showResult(result *project.CmdExecResult, i, count int) {
 			branchName,
 			color.Reset(),
 		)
+		if result.Diverged() {
+			fmt.Printf(" %s%s%s",
+				color.Color("yellow", "", "normal"),
+				formatAheadBehind(result.Ahead, result.Behind),
+				color.Reset(),
+			)
+		}
 	}
 
 	fmt.Print("\n")
 
+	v.showReviews(result)
+
 	if stdout != "" {
 		if stdout[len(stdout)-1] != '\n' {
 			fmt.Println(stdout)
@@ -194,15 +372,168 @@ func (v statusCommand) showResult(result *project.CmdExecResult, i, count int) {
 	}
 }
 
-func (v statusCommand) executeCommand(p *project.Project) *project.CmdExecResult {
+func (v statusCommand) executeCommand(p *project.Project) *statusResult {
 	if !path.Exist(p.WorkDir) {
-		result := project.CmdExecResult{}
-		result.Project = p
+		result := project.NewCmdExecResult(p)
 		result.Error = errors.New(`missing (run "git repo sync")`)
-		return &result
+		return &statusResult{CmdExecResult: result}
+	}
+
+	result := statusResult{}
+	if v.machineOutput() {
+		result.CmdExecResult = project.NewCmdExecResult(p)
+		result.Counts, result.Error = p.CountStatus()
+	} else {
+		result.CmdExecResult = p.Status()
+	}
+	if p.GetHead() != "" {
+		// Branch without tracking branch has no ahead/behind info.
+		ahead, behind, err := p.AheadBehind("")
+		if err == nil {
+			result.Ahead = ahead
+			result.Behind = behind
+		} else {
+			log.Debugf("%s%s", p.Prompt(), err)
+		}
+		if v.O.Reviews {
+			result.Reviews, result.ReviewsErr = v.queryReviews(p)
+		}
+	}
+	return &result
+}
+
+// queryReviews returns open changes on Gerrit for commits of current
+// branch which are not merged into its tracking branch.
+func (v statusCommand) queryReviews(p *project.Project) ([]gerrit.ChangeInfo, error) {
+	branch := p.GetHead()
+	remote := p.GetBranchRemote(branch, true)
+	if remote == nil || remote.GetType() != helper.ProtoTypeGerrit {
+		return nil, nil
+	}
+	commits, err := p.Revlist("HEAD", "--not", "@{upstream}")
+	if err != nil || len(commits) == 0 {
+		return nil, nil
+	}
+	// Keep query short, only the latest commits are queried.
+	if len(commits) > statusMaxReviewCommits {
+		commits = commits[:statusMaxReviewCommits]
+	}
+
+	client, err := gerrit.GetClient(remote.Review, config.NoCertChecks())
+	if err != nil {
+		return nil, err
+	}
+	return client.QueryChanges(gerrit.OpenChangesQuery(p.Name, commits))
+}
+
+// showReviews shows open changes of current branch on Gerrit.
+func (v statusCommand) showReviews(result *statusResult) {
+	if result.ReviewsErr != nil {
+		fmt.Printf(" %s!!\tfail to query reviews: %s%s\n",
+			color.Color("red", "", ""),
+			result.ReviewsErr,
+			color.Reset())
+		return
+	}
+	for _, c := range result.Reviews {
+		fmt.Printf(" %sR\t#%d %s%s", color.Color("cyan", "", ""), c.Number, c.Subject, color.Reset())
+		if labels := c.LabelSummary(); labels != "" {
+			fmt.Printf(" (%s)", labels)
+		}
+		fmt.Print("\n")
+	}
+}
+
+func formatAheadBehind(ahead, behind int) string {
+	switch {
+	case ahead > 0 && behind > 0:
+		return fmt.Sprintf("[ahead %d, behind %d]", ahead, behind)
+	case ahead > 0:
+		return fmt.Sprintf("[ahead %d]", ahead)
+	case behind > 0:
+		return fmt.Sprintf("[behind %d]", behind)
+	}
+	return ""
+}
+
+func (v statusCommand) showOrphans(topDir string, projects []*project.Project) {
+	projectPaths := []string{}
+	for _, p := range projects {
+		projectPaths = append(projectPaths, p.Path)
+		// Files created by copyfile and linkfile are not orphans.
+		for _, f := range p.CopyFiles {
+			projectPaths = append(projectPaths, f.Dest)
+		}
+		for _, f := range p.LinkFiles {
+			projectPaths = append(projectPaths, f.Dest)
+		}
+	}
+
+	orphans := findOrphans(topDir, projectPaths)
+	if len(orphans) == 0 {
+		log.Note("no orphan paths found")
+		return
+	}
+
+	fmt.Printf("%sObjects not within a project (orphans)%s\n",
+		color.Color("normal", "", "bold"),
+		color.Reset())
+	for _, orphan := range orphans {
+		fmt.Printf("%s --\t%s%s\n",
+			color.Color("red", "", ""),
+			orphan,
+			color.Reset())
+	}
+}
+
+// findOrphans returns paths under topDir which do not belong to any project.
+// Directories which contain projects are scanned recursively.
+func findOrphans(topDir string, projectPaths []string) []string {
+	var (
+		orphans  []string
+		owned    = make(map[string]bool)
+		contains = make(map[string]bool)
+	)
+
+	for _, p := range projectPaths {
+		p = filepath.Clean(p)
+		owned[p] = true
+		for dir := filepath.Dir(p); dir != "." && dir != "/"; dir = filepath.Dir(dir) {
+			contains[dir] = true
+		}
+	}
+
+	var walk func(dir string)
+	walk = func(dir string) {
+		entries, err := ioutil.ReadDir(filepath.Join(topDir, dir))
+		if err != nil {
+			log.Warnf("fail to read dir '%s': %s", dir, err)
+			return
+		}
+		for _, entry := range entries {
+			name := filepath.Join(dir, entry.Name())
+			if dir == "." && entry.Name() == config.DotRepo {
+				continue
+			}
+			if owned[name] {
+				continue
+			}
+			if contains[name] && entry.IsDir() {
+				walk(name)
+				continue
+			}
+			if entry.IsDir() {
+				name += "/"
+			}
+			orphans = append(orphans, name)
+		}
 	}
 
-	return p.Status()
+	if !owned["."] {
+		walk(".")
+	}
+	sort.Strings(orphans)
+	return orphans
 }
 
 var statusCmd = statusCommand{
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindOrphans(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo")
+	if err != nil {
+		panic(err)
+	}
+	defer func(dir string) {
+		os.RemoveAll(dir)
+	}(tmpdir)
+
+	for _, dir := range []string{
+		".repo/manifests",
+		"drivers/driver1",
+		"drivers/driver2",
+		"drivers/misc",
+		"main",
+		"tmp",
+	} {
+		assert.Nil(os.MkdirAll(filepath.Join(tmpdir, dir), 0755))
+	}
+	for _, file := range []string{
+		"Makefile",
+		"drivers/README",
+		"main/README",
+	} {
+		assert.Nil(ioutil.WriteFile(filepath.Join(tmpdir, file), []byte("x"), 0644))
+	}
+
+	orphans := findOrphans(tmpdir, []string{
+		"main",
+		"drivers/driver1",
+		"drivers/driver2/",
+	})
+	assert.Equal([]string{
+		"Makefile",
+		"drivers/README",
+		"drivers/misc/",
+		"tmp/",
+	}, orphans)
+
+	orphans = findOrphans(tmpdir, []string{"."})
+	assert.Nil(orphans)
+}
+
+func TestStatusRecord(t *testing.T) {
+	assert := assert.New(t)
+
+	record := statusRecord{
+		Project:   "platform/app",
+		Path:      "apps/app",
+		Branch:    "topic",
+		Ahead:     2,
+		Behind:    1,
+		Staged:    3,
+		Unstaged:  4,
+		Untracked: 5,
+	}
+	assert.Equal("platform/app\tapps/app\ttopic\t2\t1\t3\t4\t5", record.Porcelain())
+
+	out, err := json.Marshal(record)
+	assert.Nil(err)
+	assert.Equal(`{"project":"platform/app","path":"apps/app","branch":"topic",`+
+		`"ahead":2,"behind":1,"staged":3,"unstaged":4,"untracked":5}`, string(out))
+
+	record = statusRecord{Project: "main", Path: "main", Error: "missing"}
+	assert.Equal("main\tmain\t\t0\t0\t0\t0\t0", record.Porcelain())
+	out, err = json.Marshal(record)
+	assert.Nil(err)
+	assert.Equal(`{"project":"main","path":"main","branch":"",`+
+		`"ahead":0,"behind":0,"staged":0,"unstaged":0,"untracked":0,"error":"missing"}`, string(out))
+}
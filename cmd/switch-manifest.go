@@ -0,0 +1,69 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+type switchManifestCommand struct {
+	cmd *cobra.Command
+	O   struct {
+		Force bool
+	}
+}
+
+func (v *switchManifestCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "switch-manifest <branch>",
+		Short: "Switch manifest branch of the workspace",
+		Long: `Switch manifest project to another branch, just like running
+'git repo init -b <branch>'.
+
+Before switching, projects which will change revision, be added or be
+removed are listed. If local work would be stranded, such as unpublished
+commits or uncommitted changes in projects to be removed, the switch is
+refused unless --force is given.
+
+Run 'git repo sync' after switching to update projects.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().BoolVarP(&v.O.Force,
+		"force",
+		"f",
+		false,
+		"switch even if local work would be stranded")
+
+	return v.cmd
+}
+
+func (v switchManifestCommand) Execute(args []string) error {
+	initCmd.O.ManifestBranch = args[0]
+	initCmd.O.Force = v.O.Force
+	return initCmd.Execute(nil)
+}
+
+var switchManifestCmd = switchManifestCommand{}
+
+func init() {
+	rootCmd.AddCommand(switchManifestCmd.Command())
+}
@@ -0,0 +1,147 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/project"
+)
+
+// syncChange is changes of a project since last sync.
+type syncChange struct {
+	Name    string   `json:"name"`
+	Path    string   `json:"path"`
+	OldHead string   `json:"old_head,omitempty"`
+	NewHead string   `json:"new_head"`
+	New     bool     `json:"new,omitempty"`
+	Commits []string `json:"commits"`
+}
+
+// syncChanges is written in file specified by "sync --report-changes".
+type syncChanges struct {
+	Projects []*syncChange `json:"projects"`
+	Commits  int           `json:"commits"`
+}
+
+// changeLog returns commits of project in range, in the form of
+// "<abbrev> <subject>".
+func changeLog(p *project.Project, oldHead, newHead string) []string {
+	commits := []string{}
+	result := p.ExecuteCommand(config.GIT, "log", "--oneline", "--no-decorate", oldHead+".."+newHead, "--")
+	if !result.Success() {
+		return commits
+	}
+	for _, line := range strings.Split(result.Stdout(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			commits = append(commits, line)
+		}
+	}
+	return commits
+}
+
+// Changes returns projects whose HEAD is changed by sync.
+func (v *syncReport) Changes() *syncChanges {
+	changes := syncChanges{Projects: []*syncChange{}}
+	if v == nil {
+		return &changes
+	}
+
+	for _, r := range v.Projects {
+		if r.NewHead == "" || r.NewHead == r.OldHead {
+			continue
+		}
+		c := syncChange{
+			Name:    r.Name,
+			Path:    r.Path,
+			OldHead: r.OldHead,
+			NewHead: r.NewHead,
+			Commits: []string{},
+		}
+		if r.OldHead == "" {
+			c.New = true
+		} else if r.project != nil {
+			c.Commits = changeLog(r.project, r.OldHead, r.NewHead)
+		}
+		changes.Projects = append(changes.Projects, &c)
+		changes.Commits += len(c.Commits)
+	}
+	return &changes
+}
+
+// Show writes summary of changes.
+func (v syncChanges) Show(w io.Writer) {
+	if len(v.Projects) == 0 {
+		fmt.Fprintln(w, "No changes since last sync.")
+		return
+	}
+	fmt.Fprintln(w, "Changes since last sync:")
+	for _, c := range v.Projects {
+		if c.New {
+			fmt.Fprintf(w, "  %s (new project)\n", c.Path)
+			continue
+		}
+		fmt.Fprintf(w, "  %s (%d commits)\n", c.Path, len(c.Commits))
+		for _, commit := range c.Commits {
+			fmt.Fprintf(w, "    %s\n", commit)
+		}
+	}
+	fmt.Fprintf(w, "Total: %d projects changed, %d commits\n", len(v.Projects), v.Commits)
+}
+
+// Markdown returns changes in Markdown format for release notes.
+func (v syncChanges) Markdown() string {
+	var buf bytes.Buffer
+
+	buf.WriteString("# Changes since last sync\n")
+	for _, c := range v.Projects {
+		buf.WriteString(fmt.Sprintf("\n## %s (`%s`)\n\n", c.Path, c.Name))
+		if c.New {
+			buf.WriteString("New project.\n")
+			continue
+		}
+		for _, commit := range c.Commits {
+			buf.WriteString("* " + commit + "\n")
+		}
+	}
+	buf.WriteString(fmt.Sprintf("\nTotal: %d projects changed, %d commits.\n",
+		len(v.Projects), v.Commits))
+	return buf.String()
+}
+
+// Save writes changes to file, in Markdown format if file has suffix
+// ".md", or in JSON format.
+func (v syncChanges) Save(file string) error {
+	var data []byte
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".md", ".markdown":
+		data = []byte(v.Markdown())
+	default:
+		out, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(out, '\n')
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncChanges(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		buf    bytes.Buffer
+	)
+
+	report := &syncReport{
+		Projects: []*syncProjectReport{
+			{Name: "main", Path: "main", OldHead: "1111111", NewHead: "1111111"},
+			{Name: "project1", Path: "projects/app1", OldHead: "1111111", NewHead: "2222222"},
+			{Name: "project2", Path: "projects/app2", NewHead: "3333333"},
+			{Name: "project3", Path: "projects/app3", OldHead: "4444444"},
+		},
+	}
+	changes := report.Changes()
+	assert.Equal(2, len(changes.Projects))
+	assert.Equal("project1", changes.Projects[0].Name)
+	assert.False(changes.Projects[0].New)
+	assert.Equal("project2", changes.Projects[1].Name)
+	assert.True(changes.Projects[1].New)
+
+	changes.Projects[0].Commits = []string{"2222222 Add feature", "1234567 Fix typo"}
+	changes.Commits = 2
+	changes.Show(&buf)
+	assert.Equal(`Changes since last sync:
+  projects/app1 (2 commits)
+    2222222 Add feature
+    1234567 Fix typo
+  projects/app2 (new project)
+Total: 2 projects changed, 2 commits
+`, buf.String())
+
+	assert.Equal("# Changes since last sync\n"+
+		"\n## projects/app1 (`project1`)\n\n"+
+		"* 2222222 Add feature\n"+
+		"* 1234567 Fix typo\n"+
+		"\n## projects/app2 (`project2`)\n\n"+
+		"New project.\n"+
+		"\nTotal: 2 projects changed, 2 commits.\n",
+		changes.Markdown())
+
+	buf.Reset()
+	(&syncReport{}).Changes().Show(&buf)
+	assert.Equal("No changes since last sync.\n", buf.String())
+
+	dir, err := ioutil.TempDir("", "git-repo-sync-changes-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "changes.json")
+	assert.Nil(changes.Save(file))
+	data, err := ioutil.ReadFile(file)
+	assert.Nil(err)
+	saved := syncChanges{}
+	assert.Nil(json.Unmarshal(data, &saved))
+	assert.Equal(*changes, saved)
+
+	file = filepath.Join(dir, "changes.md")
+	assert.Nil(changes.Save(file))
+	data, err = ioutil.ReadFile(file)
+	assert.Nil(err)
+	assert.Equal(changes.Markdown(), string(data))
+}
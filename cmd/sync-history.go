@@ -0,0 +1,191 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/project"
+)
+
+const (
+	// syncHistoryFile records successful syncs, in ".repo".
+	syncHistoryFile = "history"
+	// syncHistoryMax is max number of syncs kept in history.
+	syncHistoryMax = 100
+	// syncRollbackDir is where manifests of a recorded sync are
+	// extracted for rollback, in ".repo".
+	syncRollbackDir = "rollback"
+)
+
+// syncHistoryEntry is a successful sync, which is saved as a line of JSON
+// in history file.
+type syncHistoryEntry struct {
+	// Commit of manifests project.
+	Commit string `json:"commit"`
+	// Manifest is path of manifest file, relative to manifests project.
+	Manifest string    `json:"manifest"`
+	Time     time.Time `json:"time"`
+	// Projects freezes the sync: path of each project to its checked
+	// out commit.
+	Projects map[string]string `json:"projects,omitempty"`
+}
+
+// loadSyncHistory reads entries of history file, from old to new. No
+// error if file does not exist.
+func loadSyncHistory(file string) ([]syncHistoryEntry, error) {
+	entries := []syncHistoryEntry{}
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return entries, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		entry := syncHistoryEntry{}
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		if err = json.Unmarshal(s.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("bad sync history '%s': %s", file, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, s.Err()
+}
+
+// saveSyncHistory appends entry to history file, and removes the oldest
+// entries if there are more than syncHistoryMax.
+func saveSyncHistory(file string, entry syncHistoryEntry) error {
+	entries, err := loadSyncHistory(file)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, entry)
+	if len(entries) > syncHistoryMax {
+		entries = entries[len(entries)-syncHistoryMax:]
+	}
+
+	f, err := os.Create(file + ".tmp")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, entry := range entries {
+		data, _ := json.Marshal(entry)
+		w.Write(append(data, '\n'))
+	}
+	err = w.Flush()
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(file + ".tmp")
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
+
+// syncHistoryEntryAt returns the entry n syncs before the last one.
+func syncHistoryEntryAt(entries []syncHistoryEntry, n int) (*syncHistoryEntry, error) {
+	if n >= len(entries) {
+		return nil, fmt.Errorf("cannot roll back %d syncs, only %d syncs are recorded",
+			n, len(entries))
+	}
+	return &entries[len(entries)-1-n], nil
+}
+
+// recordSyncHistory saves commit of manifests project of a successful sync,
+// and commits which projects are checked out.
+func (v syncCommand) recordSyncHistory(allProjects []*project.Project) {
+	rws := v.RepoWorkSpace()
+	mp := rws.ManifestProject
+	commit, err := mp.ResolveRevision("HEAD")
+	if err != nil {
+		log.Debugf("fail to record sync history: %s", err)
+		return
+	}
+	name := v.O.ManifestName
+	if name == "" {
+		name = mp.ManifestName()
+	}
+	projects := make(map[string]string)
+	for _, p := range allProjects {
+		if rev, err := rws.CurrentRevision(p); err == nil {
+			projects[p.Path] = rev
+		}
+	}
+	err = saveSyncHistory(filepath.Join(rws.AdminDir(), syncHistoryFile), syncHistoryEntry{
+		Commit:   commit,
+		Manifest: name,
+		Time:     time.Now(),
+		Projects: projects,
+	})
+	if err != nil {
+		log.Warnf("fail to record sync history: %s", err)
+	}
+}
+
+// prepareRollback extracts manifests of the sync n syncs before the last
+// one, and returns name of the manifest to sync with (relative to manifests
+// project) and the recorded entry.
+func (v syncCommand) prepareRollback(n int) (string, *syncHistoryEntry, error) {
+	rws := v.RepoWorkSpace()
+	entries, err := loadSyncHistory(filepath.Join(rws.AdminDir(), syncHistoryFile))
+	if err != nil {
+		return "", nil, err
+	}
+	entry, err := syncHistoryEntryAt(entries, n)
+	if err != nil {
+		return "", nil, newUserError(err)
+	}
+
+	dir := filepath.Join(rws.AdminDir(), syncRollbackDir)
+	os.RemoveAll(dir)
+	if err = rws.ManifestProject.ExtractAt(entry.Commit, dir); err != nil {
+		return "", nil, fmt.Errorf("fail to extract manifests of %s: %s", entry.Commit, err)
+	}
+	log.Notef("roll back to sync at %s, with manifests of %s",
+		entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Commit)
+	return filepath.Join("..", syncRollbackDir, entry.Manifest), entry, nil
+}
+
+// pinRollback checks out projects at commits recorded in entry. Entries
+// recorded by old versions have no commits of projects, and projects are
+// pinned to the last commit before time of the sync.
+func pinRollback(projects []*project.Project, entry *syncHistoryEntry) {
+	if entry.Projects == nil {
+		pinProjects(projects, entry.Time, "")
+		return
+	}
+	for _, p := range projects {
+		commit, ok := entry.Projects[p.Path]
+		if !ok {
+			log.Warnf("%snot found in sync history, keep revision '%s'", p.Prompt(), p.Revision)
+			continue
+		}
+		log.Debugf("%spin '%s' to %s", p.Prompt(), p.Revision, commit)
+		pinRevision(p, commit)
+	}
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-history-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	file := filepath.Join(tmpdir, syncHistoryFile)
+
+	entries, err := loadSyncHistory(file)
+	assert.Nil(err)
+	assert.Empty(entries)
+	_, err = syncHistoryEntryAt(entries, 0)
+	assert.NotNil(err)
+
+	now := time.Unix(1577948645, 0)
+	for i := 0; i < syncHistoryMax+2; i++ {
+		err = saveSyncHistory(file, syncHistoryEntry{
+			Commit:   fmt.Sprintf("%040d", i),
+			Manifest: "default.xml",
+			Time:     now.Add(time.Duration(i) * time.Hour),
+			Projects: map[string]string{"app1": fmt.Sprintf("%040d", i)},
+		})
+		assert.Nil(err)
+	}
+
+	entries, err = loadSyncHistory(file)
+	assert.Nil(err)
+	assert.Equal(syncHistoryMax, len(entries))
+	assert.Equal(fmt.Sprintf("%040d", 2), entries[0].Commit)
+
+	entry, err := syncHistoryEntryAt(entries, 0)
+	assert.Nil(err)
+	assert.Equal(fmt.Sprintf("%040d", syncHistoryMax+1), entry.Commit)
+	entry, err = syncHistoryEntryAt(entries, 1)
+	assert.Nil(err)
+	assert.Equal(fmt.Sprintf("%040d", syncHistoryMax), entry.Commit)
+	assert.Equal("default.xml", entry.Manifest)
+	assert.Equal(map[string]string{"app1": fmt.Sprintf("%040d", syncHistoryMax)}, entry.Projects)
+	assert.Equal(now.Add(time.Duration(syncHistoryMax)*time.Hour).Unix(), entry.Time.Unix())
+	_, err = syncHistoryEntryAt(entries, syncHistoryMax)
+	assert.NotNil(err)
+}
+
+func TestPinRollback(t *testing.T) {
+	assert := assert.New(t)
+
+	newProject := func(path, revision string) *project.Project {
+		p := project.Project{}
+		p.Project = manifest.Project{Path: path, Revision: revision}
+		return &p
+	}
+	app1 := newProject("app1", "master")
+	app2 := newProject("app2", "refs/tags/v1.0")
+	app3 := newProject("app3", "master")
+	pinRollback([]*project.Project{app1, app2, app3}, &syncHistoryEntry{
+		Projects: map[string]string{
+			"app1": fmt.Sprintf("%040d", 1),
+			"app2": fmt.Sprintf("%040d", 2),
+		},
+	})
+	assert.Equal(fmt.Sprintf("%040d", 1), app1.Revision)
+	assert.Equal("master", app1.Upstream)
+	assert.Equal(fmt.Sprintf("%040d", 2), app2.Revision)
+	assert.Equal("refs/tags/v1.0", app2.Upstream)
+	assert.Equal("master", app3.Revision)
+	assert.Equal("", app3.Upstream)
+}
@@ -0,0 +1,150 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/cap"
+	"github.com/alibaba/git-repo-go/color"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/project"
+)
+
+// syncFailure is a project which fails to checkout.
+type syncFailure struct {
+	tree *project.Tree
+	err  error
+}
+
+// syncResolveAction is chosen by user to resolve a project which fails
+// to checkout in "sync --interactive".
+type syncResolveAction int
+
+// Actions to resolve a project.
+const (
+	syncResolveUnknown syncResolveAction = iota
+	syncResolveRebase
+	syncResolveDetach
+	syncResolveSkip
+	syncResolveShell
+	syncResolveQuit
+)
+
+const syncResolveMenu = `  r - stash local changes, and rebase onto the new revision
+  d - detach HEAD at the new revision, and keep the local branch
+  s - skip this project
+  e - open a shell in the project, and try again after it exits
+  q - skip this and all the remaining projects
+`
+
+// parseSyncResolveAction returns action by answer of user, which is the
+// first letter or the whole word of the action.
+func parseSyncResolveAction(answer string) syncResolveAction {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	for _, a := range []struct {
+		action syncResolveAction
+		words  []string
+	}{
+		{syncResolveRebase, []string{"r", "rebase"}},
+		{syncResolveDetach, []string{"d", "detach"}},
+		{syncResolveSkip, []string{"s", "skip"}},
+		{syncResolveShell, []string{"e", "shell"}},
+		{syncResolveQuit, []string{"q", "quit"}},
+	} {
+		for _, word := range a.words {
+			if answer == word {
+				return a.action
+			}
+		}
+	}
+	return syncResolveUnknown
+}
+
+// runShell opens an interactive shell in worktree of project.
+func runShell(p *project.Project) error {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "sh"
+		if cap.IsWindows() {
+			shell = "cmd"
+		}
+	}
+	log.Notef("%sopen %s in %s, exit the shell to try again", p.Prompt(), shell, p.WorkDir)
+	_, err := helper.RunCommand(&helper.Command{
+		Args:   []string{shell},
+		Dir:    p.WorkDir,
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+	return err
+}
+
+// resolveFailures walks through projects which fail to checkout one by one,
+// and asks user how to resolve each of them. Returns projects which are
+// still failed.
+func (v syncCommand) resolveFailures(failures []syncFailure, o project.CheckoutOptions, commitTemplate string) []syncFailure {
+	remains := []syncFailure{}
+	quit := false
+
+	log.Notef("%d project(s) fail to checkout, resolve them one by one", len(failures))
+	for i, f := range failures {
+		p := f.tree.Project
+		err := f.err
+		for err != nil && !quit {
+			color.Hilightf("\n[%d/%d] %s: fail to checkout\n", i+1, len(failures), p.Path)
+			fmt.Printf("%s\n\n%s", strings.TrimSpace(err.Error()), syncResolveMenu)
+
+			opts := o
+			switch parseSyncResolveAction(userInput("What now [r,d,s,e,q]? ", "s")) {
+			case syncResolveRebase:
+				opts.DetachHead = false
+				opts.AutoStash = true
+			case syncResolveDetach:
+				opts.DetachHead = true
+				opts.AutoStash = false
+			case syncResolveShell:
+				if e := runShell(p); e != nil {
+					log.Warnf("%sshell exits with error: %s", p.Prompt(), e)
+				}
+			case syncResolveSkip:
+				log.Notef("%sskipped", p.Prompt())
+				remains = append(remains, syncFailure{tree: f.tree, err: err})
+				err = nil
+				continue
+			case syncResolveQuit:
+				quit = true
+				continue
+			default:
+				fmt.Println("unknown answer, please try again")
+				continue
+			}
+
+			err = v.checkoutProject(f.tree, &opts, commitTemplate)
+			v.report.Checkout(p, err)
+			if err == nil {
+				log.Notef("%scheckout is resolved", p.Prompt())
+			}
+		}
+		if err != nil {
+			remains = append(remains, syncFailure{tree: f.tree, err: err})
+		}
+	}
+	return remains
+}
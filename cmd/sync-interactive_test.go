@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSyncResolveAction(t *testing.T) {
+	assert := assert.New(t)
+
+	for answer, action := range map[string]syncResolveAction{
+		"r":       syncResolveRebase,
+		"Rebase":  syncResolveRebase,
+		"d":       syncResolveDetach,
+		" s\n":    syncResolveSkip,
+		"skip":    syncResolveSkip,
+		"e":       syncResolveShell,
+		"shell":   syncResolveShell,
+		"q":       syncResolveQuit,
+		"yes":     syncResolveUnknown,
+		"":        syncResolveUnknown,
+		"rebase!": syncResolveUnknown,
+	} {
+		assert.Equal(action, parseSyncResolveAction(answer), answer)
+	}
+}
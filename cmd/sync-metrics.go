@@ -0,0 +1,148 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// syncProjectMetrics holds metrics of a project.
+type syncProjectMetrics struct {
+	synced       int64
+	failures     int64
+	fetchedBytes int64
+	lastSuccess  time.Time
+}
+
+// syncMetrics collects results of syncs in daemon mode, and exposes
+// them in Prometheus text format.
+type syncMetrics struct {
+	runs         int64
+	failedRuns   int64
+	lastDuration float64
+	projects     map[string]*syncProjectMetrics
+	mutex        sync.Mutex
+}
+
+func newSyncMetrics() *syncMetrics {
+	return &syncMetrics{
+		projects: make(map[string]*syncProjectMetrics),
+	}
+}
+
+// Update adds result of a sync to metrics.
+func (v *syncMetrics) Update(report *syncReport) {
+	if v == nil || report == nil {
+		return
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.runs++
+	if report.Status != syncStatusOK {
+		v.failedRuns++
+	}
+	v.lastDuration = report.Duration
+	for _, r := range report.Projects {
+		m, ok := v.projects[r.Name]
+		if !ok {
+			m = &syncProjectMetrics{}
+			v.projects[r.Name] = m
+		}
+		if r.Error != "" {
+			m.failures++
+			continue
+		}
+		m.synced++
+		m.fetchedBytes += r.FetchedBytes
+		m.lastSuccess = report.StartTime
+	}
+}
+
+// escapeLabel escapes label value in Prometheus text format.
+func escapeLabel(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `"`, `\"`, -1)
+	return strings.Replace(value, "\n", `\n`, -1)
+}
+
+// Export writes metrics in Prometheus text format.
+func (v *syncMetrics) Export(w io.Writer, now time.Time) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	names := []string{}
+	for name := range v.projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+	}
+	perProject := func(name, kind, help string, value func(*syncProjectMetrics) (float64, bool)) {
+		metric(name, kind, help)
+		for _, project := range names {
+			if n, ok := value(v.projects[project]); ok {
+				fmt.Fprintf(w, "%s{project=\"%s\"} %v\n", name, escapeLabel(project), n)
+			}
+		}
+	}
+
+	metric("git_repo_sync_runs_total", "counter", "Total number of syncs.")
+	fmt.Fprintf(w, "git_repo_sync_runs_total %d\n", v.runs)
+	metric("git_repo_sync_failed_runs_total", "counter", "Total number of syncs with errors.")
+	fmt.Fprintf(w, "git_repo_sync_failed_runs_total %d\n", v.failedRuns)
+	metric("git_repo_sync_last_duration_seconds", "gauge", "Duration of the last sync.")
+	fmt.Fprintf(w, "git_repo_sync_last_duration_seconds %v\n", v.lastDuration)
+
+	perProject("git_repo_sync_project_synced_total", "counter",
+		"Total number of successful syncs of project.",
+		func(m *syncProjectMetrics) (float64, bool) {
+			return float64(m.synced), true
+		})
+	perProject("git_repo_sync_project_failures_total", "counter",
+		"Total number of failed syncs of project.",
+		func(m *syncProjectMetrics) (float64, bool) {
+			return float64(m.failures), true
+		})
+	perProject("git_repo_sync_project_fetched_bytes_total", "counter",
+		"Total bytes of objects fetched for project.",
+		func(m *syncProjectMetrics) (float64, bool) {
+			return float64(m.fetchedBytes), true
+		})
+	perProject("git_repo_sync_project_lag_seconds", "gauge",
+		"Seconds since the last successful sync of project.",
+		func(m *syncProjectMetrics) (float64, bool) {
+			if m.lastSuccess.IsZero() {
+				return 0, false
+			}
+			return now.Sub(m.lastSuccess).Seconds(), true
+		})
+}
+
+// ServeHTTP implements http.Handler.
+func (v *syncMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	v.Export(w, time.Now())
+}
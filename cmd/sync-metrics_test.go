@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncMetrics(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		start  = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		buf    bytes.Buffer
+	)
+
+	metrics := newSyncMetrics()
+	metrics.Update(nil)
+	metrics.Update(&syncReport{
+		Status:    syncStatusOK,
+		StartTime: start,
+		Duration:  2.5,
+		Projects: []*syncProjectReport{
+			{Name: "main", FetchedBytes: 100},
+			{Name: "project1", FetchedBytes: 20},
+		},
+	})
+	metrics.Update(&syncReport{
+		Status:    syncStatusPartial,
+		StartTime: start.Add(time.Minute),
+		Duration:  1,
+		Projects: []*syncProjectReport{
+			{Name: "main", FetchedBytes: 10},
+			{Name: "project1", Error: "fail to fetch"},
+			{Name: "project\"2", Error: "fail to fetch"},
+		},
+	})
+
+	metrics.Export(&buf, start.Add(2*time.Minute))
+	lines := []string{}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	assert.Equal([]string{
+		`git_repo_sync_runs_total 2`,
+		`git_repo_sync_failed_runs_total 1`,
+		`git_repo_sync_last_duration_seconds 1`,
+		`git_repo_sync_project_synced_total{project="main"} 2`,
+		`git_repo_sync_project_synced_total{project="project\"2"} 0`,
+		`git_repo_sync_project_synced_total{project="project1"} 1`,
+		`git_repo_sync_project_failures_total{project="main"} 0`,
+		`git_repo_sync_project_failures_total{project="project\"2"} 1`,
+		`git_repo_sync_project_failures_total{project="project1"} 1`,
+		`git_repo_sync_project_fetched_bytes_total{project="main"} 110`,
+		`git_repo_sync_project_fetched_bytes_total{project="project\"2"} 0`,
+		`git_repo_sync_project_fetched_bytes_total{project="project1"} 20`,
+		`git_repo_sync_project_lag_seconds{project="main"} 60`,
+		`git_repo_sync_project_lag_seconds{project="project1"} 120`,
+	}, lines)
+
+	w := httptest.NewRecorder()
+	metrics.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(200, w.Code)
+	assert.Contains(w.Body.String(), "# TYPE git_repo_sync_runs_total counter\n")
+}
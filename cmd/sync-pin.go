@@ -0,0 +1,97 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/project"
+)
+
+// syncDateLayouts are formats of option --to-date, in local time zone
+// unless zone is given.
+var syncDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseSyncDate parses timestamp of option --to-date, such as
+// "2020-01-02", "2020-01-02 15:04:05", RFC3339 format, or seconds since
+// epoch with prefix "@".
+func parseSyncDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "@") {
+		seconds, err := strconv.ParseInt(value[1:], 10, 64)
+		if err == nil {
+			return time.Unix(seconds, 0), nil
+		}
+	}
+	for _, layout := range syncDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("bad timestamp '%s', should be like '2006-01-02 15:04:05' or '@<seconds>'",
+		value)
+}
+
+// pinProjects changes revision of projects which track branches to the
+// last commit before date, or to tag, so that the whole workspace is
+// checked out as it was at that time or build. Projects pinned to a tag
+// or a commit in manifest are not changed.
+func pinProjects(projects []*project.Project, date time.Time, tag string) {
+	for _, p := range projects {
+		if p.NewRevisionSpec(p.Revision).Kind != project.RevisionBranch {
+			continue
+		}
+		if tag != "" {
+			ref := tag
+			if !strings.HasPrefix(ref, config.RefsTags) {
+				ref = config.RefsTags + ref
+			}
+			if !p.RevisionIsValid(ref) {
+				log.Warnf("%stag '%s' is not found, keep revision '%s'", p.Prompt(), tag, p.Revision)
+				continue
+			}
+			log.Debugf("%spin to tag '%s'", p.Prompt(), ref)
+			pinRevision(p, ref)
+			continue
+		}
+		commit, err := p.CommitBefore(p.Revision, date)
+		if err != nil {
+			log.Warnf("%s, keep revision '%s'", err, p.Revision)
+			continue
+		}
+		log.Debugf("%spin '%s' to %s", p.Prompt(), p.Revision, commit)
+		pinRevision(p, commit)
+	}
+}
+
+// pinRevision changes revision of project, and keeps the branch as
+// upstream, so that tracking branch of local branches is not changed.
+func pinRevision(p *project.Project, revision string) {
+	if p.Upstream == "" {
+		p.Upstream = p.Revision
+	}
+	p.Revision = revision
+}
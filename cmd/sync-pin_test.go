@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSyncDate(t *testing.T) {
+	assert := assert.New(t)
+
+	date, err := parseSyncDate("2020-01-02")
+	assert.Nil(err)
+	assert.Equal(time.Date(2020, 1, 2, 0, 0, 0, 0, time.Local), date)
+
+	date, err = parseSyncDate("2020-01-02 15:04:05")
+	assert.Nil(err)
+	assert.Equal(time.Date(2020, 1, 2, 15, 4, 5, 0, time.Local), date)
+
+	date, err = parseSyncDate("2020-01-02T15:04:05+08:00")
+	assert.Nil(err)
+	assert.Equal(int64(1577948645), date.Unix())
+
+	date, err = parseSyncDate("@1577948645")
+	assert.Nil(err)
+	assert.Equal(int64(1577948645), date.Unix())
+
+	_, err = parseSyncDate("yesterday")
+	assert.Equal("bad timestamp 'yesterday', should be like '2006-01-02 15:04:05' or '@<seconds>'",
+		err.Error())
+}
+
+func TestPinRevision(t *testing.T) {
+	assert := assert.New(t)
+
+	p := project.Project{}
+	p.Project = manifest.Project{Revision: "master"}
+	pinRevision(&p, "refs/tags/v1.0")
+	assert.Equal("refs/tags/v1.0", p.Revision)
+	assert.Equal("master", p.Upstream)
+	assert.Equal("master", p.DefaultTrackingBranch())
+
+	p.Project = manifest.Project{Revision: "master", Upstream: "refs/heads/main"}
+	pinRevision(&p, "8a2c8e5f1f2a0e4c1c7b3a9b9b1e0e6c1a2b3c4d")
+	assert.Equal("refs/heads/main", p.Upstream)
+}
@@ -0,0 +1,213 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/alibaba/git-repo-go/project"
+)
+
+// Status of sync in report file.
+const (
+	syncStatusOK      = "ok"
+	syncStatusPartial = "partial"
+	syncStatusFatal   = "fatal"
+)
+
+// Exit codes for sync with --detailed-exit-code.
+const (
+	syncExitFatal   = 1
+	syncExitPartial = 2
+)
+
+// syncProjectReport is sync result of a project.
+type syncProjectReport struct {
+	Name         string  `json:"name"`
+	Path         string  `json:"path"`
+	OldHead      string  `json:"old_head,omitempty"`
+	NewHead      string  `json:"new_head,omitempty"`
+	FetchedBytes int64   `json:"fetched_bytes"`
+	LFSBytes     int64   `json:"lfs_bytes,omitempty"`
+	Duration     float64 `json:"duration"`
+	Error        string  `json:"error,omitempty"`
+
+	project *project.Project
+}
+
+// syncReport is saved in file specified by "sync --report-file" for CI.
+type syncReport struct {
+	Status    string               `json:"status"`
+	StartTime time.Time            `json:"start_time"`
+	Duration  float64              `json:"duration"`
+	Error     string               `json:"error,omitempty"`
+	Projects  []*syncProjectReport `json:"projects"`
+
+	file     string
+	projects map[string]*syncProjectReport
+	mutex    sync.Mutex
+}
+
+func newSyncReport(file string) *syncReport {
+	return &syncReport{
+		StartTime: time.Now(),
+		Projects:  []*syncProjectReport{},
+		file:      file,
+		projects:  make(map[string]*syncProjectReport),
+	}
+}
+
+// resolveHead returns revision id of HEAD, or empty string if unborn.
+func resolveHead(p *project.Project) string {
+	if !p.Exists() {
+		return ""
+	}
+	revid, err := p.ResolveRevision("HEAD")
+	if err != nil {
+		return ""
+	}
+	return revid
+}
+
+// objectsSize returns disk usage of objects of project.
+func objectsSize(p *project.Project) int64 {
+	var size int64
+
+	dir := p.ObjectsGitDir
+	if dir == "" {
+		dir = p.RepoDir()
+	}
+	filepath.Walk(filepath.Join(dir, "objects"), func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// AddProjects records current HEAD of projects before sync.
+func (v *syncReport) AddProjects(projects []*project.Project) {
+	if v == nil {
+		return
+	}
+	for _, p := range projects {
+		r := &syncProjectReport{
+			Name:    p.Name,
+			Path:    p.Path,
+			OldHead: resolveHead(p),
+
+			project: p,
+		}
+		v.Projects = append(v.Projects, r)
+		v.projects[p.Path] = r
+	}
+}
+
+// StartFetch is called before fetching project, and returns a function
+// to record result of fetch.
+func (v *syncReport) StartFetch(p *project.Project) func(error) {
+	if v == nil {
+		return func(error) {}
+	}
+
+	start := time.Now()
+	size := objectsSize(p)
+	return func(err error) {
+		fetched := objectsSize(p) - size
+		v.mutex.Lock()
+		defer v.mutex.Unlock()
+		r, ok := v.projects[p.Path]
+		if !ok {
+			return
+		}
+		if fetched > 0 {
+			r.FetchedBytes = fetched
+		}
+		r.Duration = time.Since(start).Seconds()
+		if err != nil {
+			r.Error = err.Error()
+		} else if p.Revision != "" {
+			// Revision to checkout, and will be updated after checkout.
+			if revid, err := p.ResolveRemoteTracking(p.Revision); err == nil {
+				r.NewHead = revid
+			}
+		}
+	}
+}
+
+// Checkout records result of checkout for project.
+func (v *syncReport) Checkout(p *project.Project, err error) {
+	if v == nil {
+		return
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	r, ok := v.projects[p.Path]
+	if !ok {
+		return
+	}
+	r.NewHead = resolveHead(p)
+	if err != nil && r.Error == "" {
+		r.Error = err.Error()
+	}
+}
+
+// LFS records size of LFS objects downloaded for project.
+func (v *syncReport) LFS(p *project.Project, size int64) {
+	if v == nil {
+		return
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if r, ok := v.projects[p.Path]; ok {
+		r.LFSBytes += size
+	}
+}
+
+// Save sets status of sync and writes report file.
+func (v *syncReport) Save(status string, err error) error {
+	if v == nil {
+		return nil
+	}
+
+	v.Status = status
+	v.Duration = time.Since(v.StartTime).Seconds()
+	if err != nil {
+		v.Error = err.Error()
+	}
+	if v.file == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(v.file, append(data, '\n'), 0644)
+}
+
+// syncProjectsError indicates some projects fail to sync.
+type syncProjectsError string
+
+func (v syncProjectsError) Error() string {
+	return string(v)
+}
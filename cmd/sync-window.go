@@ -0,0 +1,86 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alibaba/git-repo-go/project"
+)
+
+// fetchWindow is a daily time window, such as "22:00-06:00", in which
+// large fetches, such as cloning new projects, are allowed.
+type fetchWindow struct {
+	start time.Duration
+	end   time.Duration
+}
+
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("bad time '%s', should be HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// parseFetchWindow parses window in the form of "HH:MM-HH:MM". Returns
+// nil if value is empty.
+func parseFetchWindow(value string) (*fetchWindow, error) {
+	if value == "" {
+		return nil, nil
+	}
+	items := strings.Split(value, "-")
+	if len(items) != 2 {
+		return nil, fmt.Errorf("bad fetch window '%s', should be HH:MM-HH:MM", value)
+	}
+	start, err := parseClock(items[0])
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(items[1])
+	if err != nil {
+		return nil, err
+	}
+	return &fetchWindow{start: start, end: end}, nil
+}
+
+// Contains checks whether t is in the window. Window may cross midnight.
+func (v *fetchWindow) Contains(t time.Time) bool {
+	if v == nil {
+		return true
+	}
+	clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if v.start <= v.end {
+		return clock >= v.start && clock < v.end
+	}
+	return clock >= v.start || clock < v.end
+}
+
+// deferNewProjects splits projects which are not cloned yet, so that
+// they are fetched in the fetch window.
+func deferNewProjects(projects []*project.Project) ([]*project.Project, []*project.Project) {
+	kept := []*project.Project{}
+	deferred := []*project.Project{}
+	for _, p := range projects {
+		if p.Repository.Exists() {
+			kept = append(kept, p)
+		} else {
+			deferred = append(deferred, p)
+		}
+	}
+	return kept, deferred
+}
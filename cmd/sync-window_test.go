@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchWindow(t *testing.T) {
+	assert := assert.New(t)
+
+	at := func(clock string) time.Time {
+		value, _ := time.Parse("15:04", clock)
+		return value
+	}
+
+	window, err := parseFetchWindow("")
+	assert.Nil(err)
+	assert.Nil(window)
+	assert.True(window.Contains(at("12:00")))
+
+	window, err = parseFetchWindow("09:00-18:30")
+	assert.Nil(err)
+	assert.True(window.Contains(at("09:00")))
+	assert.True(window.Contains(at("18:29")))
+	assert.False(window.Contains(at("18:30")))
+	assert.False(window.Contains(at("08:59")))
+
+	// Window crosses midnight.
+	window, err = parseFetchWindow("22:00-06:00")
+	assert.Nil(err)
+	assert.True(window.Contains(at("23:00")))
+	assert.True(window.Contains(at("00:30")))
+	assert.False(window.Contains(at("06:00")))
+	assert.False(window.Contains(at("12:00")))
+
+	for _, value := range []string{"22:00", "22:00-", "25:00-06:00", "night"} {
+		_, err = parseFetchWindow(value)
+		assert.NotNil(err, "value: %s", value)
+	}
+}
@@ -16,20 +16,24 @@ package cmd
 
 import (
 	"bufio"
-	"errors"
 	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/alibaba/git-repo-go/cap"
 	"github.com/alibaba/git-repo-go/config"
 	"github.com/alibaba/git-repo-go/file"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/project"
 	"github.com/alibaba/git-repo-go/workspace"
-	log "github.com/jiangxin/multi-log"
 	"github.com/spf13/cobra"
 )
 
@@ -43,26 +47,50 @@ type syncCommand struct {
 
 	cmd          *cobra.Command
 	FetchOptions project.FetchOptions
+	sshMaster    *helper.SSHMaster
+	report       *syncReport
+	removed      *removedPaths
+	toDate       time.Time
+	rollback     *syncHistoryEntry
+	lfsAvailable func() bool
 
 	O struct {
+		FailFast               bool
 		ForceBroken            bool
 		ForceSync              bool
 		LocalOnly              bool
 		NetworkOnly            bool
 		DetachHead             bool
+		AutoStash              bool
+		FetchWindow            string
 		CurrentBranchOnly      bool
 		Jobs                   int
 		ManifestName           string
 		NoCache                bool
 		NoCloneBundle          bool
+		NoLFS                  bool
+		NoSSHMaster            bool
 		ManifestServerUsername string
 		ManifestServerPassword string
 		FetchSubmodules        bool
+		Tags                   bool
 		NoTags                 bool
 		OptimizedFetch         bool
+		RetryFetches           int
 		Prune                  bool
+		RunHooks               bool
 		SmartSync              bool
 		SmartTag               string
+		ReportFile             string
+		ReportChanges          string
+		ShowChanges            bool
+		DetailedExitCode       bool
+		Interval               time.Duration
+		MetricsListen          string
+		ToDate                 string
+		ToTag                  string
+		Rollback               int
+		Interactive            bool
 	}
 }
 
@@ -72,7 +100,7 @@ func (v *syncCommand) Command() *cobra.Command {
 	}
 
 	v.cmd = &cobra.Command{
-		Use:   "sync",
+		Use:   "sync [<project>...]",
 		Short: "Update working tree to the latest revision",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return v.Execute(args)
@@ -83,12 +111,16 @@ func (v *syncCommand) Command() *cobra.Command {
 		"f",
 		false,
 		"continue sync even if a project fails to sync")
+	v.cmd.Flags().BoolVar(&v.O.FailFast,
+		"fail-fast",
+		false,
+		"stop syncing other projects on the first error")
 	v.cmd.Flags().BoolVar(&v.O.ForceSync,
 		"force-sync",
 		false,
 		"overwrite an existing git directory if it needs to "+
-			"point to a different object directory. WARNING: this "+
-			"may cause loss of data")
+			"point to a different object directory, or a worktree "+
+			"of another repository. WARNING: this may cause loss of data")
 	v.cmd.Flags().BoolVarP(&v.O.LocalOnly,
 		"local-only",
 		"l",
@@ -104,6 +136,10 @@ func (v *syncCommand) Command() *cobra.Command {
 		"d",
 		false,
 		"detach projects back to manifest revision")
+	v.cmd.Flags().BoolVar(&v.O.AutoStash,
+		"autostash",
+		false,
+		"stash local changes before updating a topic branch, and restore them afterwards")
 	v.cmd.Flags().BoolVarP(&v.O.CurrentBranchOnly,
 		"current-branch",
 		"c",
@@ -112,8 +148,10 @@ func (v *syncCommand) Command() *cobra.Command {
 	v.cmd.Flags().IntVarP(&v.O.Jobs,
 		"jobs",
 		"j",
-		v.manifestsDefaultJobs(),
-		fmt.Sprintf("projects to fetch simultaneously"))
+		0,
+		fmt.Sprintf("projects to fetch simultaneously "+
+			"(default: repo.jobs of workspace, sync-j of manifest, or %d)",
+			syncDefaultJobs))
 	v.cmd.Flags().StringVarP(&v.O.ManifestName,
 		"manifest-name",
 		"m",
@@ -127,6 +165,14 @@ func (v *syncCommand) Command() *cobra.Command {
 		"no-clone-bundle",
 		false,
 		"disable use of /clone.bundle on HTTP/HTTPS")
+	v.cmd.Flags().BoolVar(&v.O.NoLFS,
+		"no-lfs",
+		false,
+		"do not pull Git LFS objects of projects")
+	v.cmd.Flags().BoolVar(&v.O.NoSSHMaster,
+		"no-ssh-master",
+		false,
+		"do not reuse SSH connections among projects")
 	v.cmd.Flags().StringVarP(&v.O.ManifestServerUsername,
 		"manifest-server-username",
 		"u",
@@ -137,10 +183,19 @@ func (v *syncCommand) Command() *cobra.Command {
 		"p",
 		"",
 		"password to authenticate with the manifest server")
+	v.cmd.Flags().StringVar(&v.O.FetchWindow,
+		"fetch-window",
+		"",
+		"only clone new projects in this daily time window, such as '22:00-06:00' "+
+			"(default: repo.fetchWindow of workspace)")
 	v.cmd.Flags().BoolVar(&v.O.FetchSubmodules,
 		"fetch-submodules",
 		false,
-		"fetch submodules from server")
+		"fetch and update submodules of all projects, not only projects with sync-s")
+	v.cmd.Flags().BoolVar(&v.O.Tags,
+		"tags",
+		false,
+		"fetch tags, even if sync-tags of project is false")
 	v.cmd.Flags().BoolVar(&v.O.NoTags,
 		"no-tags",
 		false,
@@ -149,10 +204,18 @@ func (v *syncCommand) Command() *cobra.Command {
 		"optimized-fetch",
 		false,
 		"only fetch projects fixed to sha1 if revision does not exist locally")
+	v.cmd.Flags().IntVar(&v.O.RetryFetches,
+		"retry-fetches",
+		0,
+		"number of times to retry fetches on transient errors (default: git config repo.retry.maxAttempts)")
 	v.cmd.Flags().BoolVar(&v.O.Prune,
 		"prune",
 		false,
 		"delete refs that no longer exist on the remote")
+	v.cmd.Flags().BoolVar(&v.O.RunHooks,
+		"run-hooks",
+		false,
+		"run post-sync command defined in annotation of projects after checkout")
 	v.cmd.Flags().BoolVar(&v.O.SmartSync,
 		"smart-sync",
 		false,
@@ -162,30 +225,55 @@ func (v *syncCommand) Command() *cobra.Command {
 		"t",
 		"",
 		"smart sync using manifest from a known tag")
+	v.cmd.Flags().StringVar(&v.O.ReportFile,
+		"report-file",
+		"",
+		"write sync result of projects to file in JSON format")
+	v.cmd.Flags().BoolVar(&v.O.ShowChanges,
+		"show-changes",
+		false,
+		"show commits of projects changed since last sync")
+	v.cmd.Flags().StringVar(&v.O.ReportChanges,
+		"report-changes",
+		"",
+		"write commits of projects changed since last sync to file, "+
+			"in Markdown format if file name ends with '.md', or in JSON format")
+	v.cmd.Flags().BoolVar(&v.O.DetailedExitCode,
+		"detailed-exit-code",
+		false,
+		fmt.Sprintf("exit with %d if some projects fail to sync, %d if git-repo crashes, and %d for other errors",
+			syncExitPartial, crashExitCode, syncExitFatal))
+	v.cmd.Flags().DurationVar(&v.O.Interval,
+		"interval",
+		0,
+		"keep running and sync mirror repeatedly at this interval")
+	v.cmd.Flags().StringVar(&v.O.MetricsListen,
+		"metrics-listen",
+		"",
+		"serve metrics on this address (such as ':9090') when running with --interval")
+	v.cmd.Flags().StringVar(&v.O.ToDate,
+		"to-date",
+		"",
+		"check out projects which track branches to the last commit before this time, "+
+			"such as '2006-01-02 15:04:05' or '@<seconds>'")
+	v.cmd.Flags().StringVar(&v.O.ToTag,
+		"to-tag",
+		"",
+		"check out projects which track branches to this tag, if the tag exists")
+	v.cmd.Flags().BoolVarP(&v.O.Interactive,
+		"interactive",
+		"i",
+		false,
+		"resolve projects which fail to checkout one by one")
+	v.cmd.Flags().IntVar(&v.O.Rollback,
+		"rollback",
+		0,
+		"go back to the state of N syncs before the last one recorded in .repo/history")
+	v.cmd.Flags().Lookup("rollback").NoOptDefVal = "1"
 
 	return v.cmd
 }
 
-// Value of manifestsDefaultJobs() is used as command arg's default value.
-// Do not fail if run git-repo command out of a workspace.
-func (v *syncCommand) manifestsDefaultJobs() int {
-	var nJobs int
-
-	rws, _ := workspace.NewRepoWorkSpace("")
-	if rws != nil &&
-		rws.Manifest != nil &&
-		rws.Manifest.Default != nil &&
-		rws.Manifest.Default.SyncJ > 0 {
-		nJobs = rws.Manifest.Default.SyncJ
-	}
-
-	if nJobs <= 0 {
-		nJobs = syncDefaultJobs
-	}
-
-	return nJobs
-}
-
 func (v *syncCommand) maxSyncJobs() int {
 	var (
 		nJobs int = config.MaxJobs
@@ -203,9 +291,49 @@ func (v *syncCommand) maxSyncJobs() int {
 	return nJobs
 }
 
-func (v syncCommand) CallManifestServerRPC() {
-	// TODO: implement `_SmartSyncSetup`
-	log.Panic("not implement CallManifestServerRPC")
+// CallManifestServerRPC gets manifest from manifest server for smart
+// sync, and saves it in file.
+func (v syncCommand) CallManifestServerRPC(file string) error {
+	var (
+		data string
+		err  error
+	)
+
+	rws := v.RepoWorkSpace()
+	if rws.Manifest == nil || rws.Manifest.Server == nil || rws.Manifest.Server.URL == "" {
+		return newUserError("cannot smart sync: no manifest server defined in manifest")
+	}
+	server, err := helper.NewManifestServer(rws.Manifest.Server.URL,
+		v.O.ManifestServerUsername,
+		v.O.ManifestServerPassword)
+	if err != nil {
+		return err
+	}
+
+	if v.O.SmartSync {
+		branch := rws.ManifestProject.TrackBranch("")
+		if branch == "" {
+			return fmt.Errorf("cannot smart sync: manifest project has no tracking branch")
+		}
+		target := ""
+		product := os.Getenv("TARGET_PRODUCT")
+		variant := os.Getenv("TARGET_BUILD_VARIANT")
+		if product != "" && variant != "" {
+			target = product + "-" + variant
+		}
+		data, err = server.GetApprovedManifest(branch, target)
+	} else {
+		data, err = server.GetManifest(v.O.SmartTag)
+	}
+	if err != nil {
+		return fmt.Errorf("fail to get manifest for smart sync: %s", err)
+	}
+
+	err = ioutil.WriteFile(file, []byte(data), 0644)
+	if err != nil {
+		return fmt.Errorf("fail to save smart sync manifest: %s", err)
+	}
+	return nil
 }
 
 func (v *syncCommand) updateManifestProject() error {
@@ -218,7 +346,7 @@ func (v *syncCommand) updateManifestProject() error {
 
 	if track == "" {
 		log.Notef("manifest project is not updated, for there is no tracking branch")
-		return nil
+		return mp.VerifyRevision("HEAD")
 	}
 
 	if !v.O.LocalOnly {
@@ -227,6 +355,7 @@ func (v *syncCommand) updateManifestProject() error {
 			RepoSettings: *s,
 
 			CurrentBranchOnly: v.O.CurrentBranchOnly,
+			Tags:              v.O.Tags,
 			NoTags:            v.O.NoTags,
 			OptimizedFetch:    v.O.OptimizedFetch,
 			Quiet:             config.GetQuiet(),
@@ -242,9 +371,11 @@ func (v *syncCommand) updateManifestProject() error {
 	oldrev, _ := mp.ResolveRevision("HEAD")
 
 	// No update found in manifest project
+	// Manifests in use are verified even if not updated, for they may be
+	// checked out by init or changed behind our back.
 	newrev, _ := mp.ResolveRemoteTracking(track)
 	if oldrev == newrev {
-		return nil
+		return mp.VerifyRevision("HEAD")
 	}
 
 	// Has commit not yet checkout?
@@ -253,7 +384,13 @@ func (v *syncCommand) updateManifestProject() error {
 		return err
 	}
 	if len(revlist) == 0 {
-		return nil
+		return mp.VerifyRevision("HEAD")
+	}
+
+	// Verify signature of manifests before checkout.
+	err = mp.VerifyRevision(newrev)
+	if err != nil {
+		return err
 	}
 
 	// Checkout
@@ -278,6 +415,55 @@ func (v *syncCommand) updateManifestProject() error {
 	return nil
 }
 
+// verifyManifestFile verifies detached signature of manifest given by -m,
+// or generated by thaw, bisect and rollback, if signatures of manifests
+// must be verified in workspace. Generated manifests are not signed, and
+// are refused unless signatures are saved beside them.
+func (v syncCommand) verifyManifestFile(name string) error {
+	rws := v.RepoWorkSpace()
+	trust := rws.ManifestProject.ManifestTrust()
+	if !trust.Verify {
+		return nil
+	}
+	fpr, err := trust.VerifyFile(filepath.Join(rws.RootDir, config.DotRepo, config.Manifests, name))
+	if err != nil {
+		return err
+	}
+	log.Debugf("manifest %s is signed by %s", name, fpr)
+	return nil
+}
+
+// removedPaths records paths removed for --force-sync.
+type removedPaths struct {
+	paths []string
+	mutex sync.Mutex
+}
+
+// Add records removed paths.
+func (v *removedPaths) Add(paths []string) {
+	if v == nil || len(paths) == 0 {
+		return
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.paths = append(v.paths, paths...)
+}
+
+// Show lists removed paths relative to topDir.
+func (v *removedPaths) Show(topDir string) {
+	if v == nil || len(v.paths) == 0 {
+		return
+	}
+	sort.Strings(v.paths)
+	log.Warnf("following paths are removed for --force-sync:")
+	for _, p := range v.paths {
+		if rel, err := filepath.Rel(topDir, p); err == nil {
+			p = rel
+		}
+		log.Warnf("  %s", p)
+	}
+}
+
 func (v syncCommand) NetworkHalf(allProjects []*project.Project) error {
 	var (
 		err  error
@@ -292,64 +478,220 @@ func (v syncCommand) NetworkHalf(allProjects []*project.Project) error {
 	// TODO 1. Record fetch time, save time and project name to JSON
 	// TODO 2. Sort projects by its fetch time (reverse order).
 
-	projectsByName := project.IndexByName(allProjects)
-	jobTasks := make(chan string, jobs)
-	jobResults := make(chan error, jobs)
+	// Projects fixed to SHA which exists locally are not fetched for
+	// option --optimized-fetch.
+	fetchProjects := []*project.Project{}
+	for _, p := range allProjects {
+		if p.IsFetchNeeded(&v.FetchOptions) {
+			fetchProjects = append(fetchProjects, p)
+		} else {
+			log.Debugf("%sskip fetch, revision '%s' exists locally", p.Prompt(), p.Revision)
+		}
+	}
+	projectsByName := project.IndexByName(fetchProjects)
+	nestedByPath := map[string][]string{}
+	nestedPathsByPath(project.ProjectsTree(allProjects), nestedByPath)
+
+	// Start ssh master connections before fetching, so that projects
+	// from the same host share one connection.
+	if v.sshMaster.Enabled() {
+		for _, p := range fetchProjects {
+			v.sshMaster.Start(p.RemoteURL)
+		}
+	}
+	var (
+		jobTasks   = make(chan string, jobs)
+		jobResults = make(chan error, jobs)
+		abort      = make(chan struct{})
+		abortOnce  sync.Once
+		wg         sync.WaitGroup
+	)
 
 	worker := func(i int) {
+		defer recoverCrash()
 		var (
 			err      error
 			name     string
 			projects []*project.Project
 			p        *project.Project
+			unlock   func()
+			removed  []string
 		)
 
+		defer wg.Done()
 		log.Debugf("start NetworkHalf worker #%d", i)
 		for name = range jobTasks {
+			select {
+			case <-abort:
+				continue
+			default:
+			}
 			projects = projectsByName[name]
 			for _, p = range projects {
 				log.Debugf("worker #%d: sync %s", i, p.Name)
-				err = p.SyncNetworkHalf(&v.FetchOptions)
+				done := v.report.StartFetch(p)
+				leave := helper.Trace2Region("fetch", p.Name)
+				unlock, err = p.Lock()
+				if err == nil {
+					removed, err = p.ResolveGitDirConflict(v.O.ForceSync, nestedByPath[p.Path])
+					v.removed.Add(removed)
+					if err == nil {
+						err = p.SyncNetworkHalf(&v.FetchOptions)
+					}
+					if err == nil {
+						err = v.fetchLFS(p)
+					}
+					unlock()
+				}
+				leave()
+				done(err)
+				if err != nil && v.O.FailFast {
+					abortOnce.Do(func() { close(abort) })
+				}
 				jobResults <- err
 			}
 		}
 	}
 
+	wg.Add(jobs)
 	for i := 0; i < jobs; i++ {
 		go worker(i)
 	}
 
 	go func() {
+		defer close(jobTasks)
 		for name := range projectsByName {
-			jobTasks <- name
+			select {
+			case jobTasks <- name:
+			case <-abort:
+				return
+			}
 		}
+	}()
 
-		close(jobTasks)
+	go func() {
+		wg.Wait()
+		close(jobResults)
 	}()
 
-	for i := 0; i < len(projectsByName); i++ {
-		err = <-jobResults
+	for err = range jobResults {
 		if err != nil {
 			errs = append(errs, err)
 		}
 	}
 
+	return v.projectsError(errs, abort)
+}
+
+// projectsError returns syncProjectsError for errors of projects, and
+// tells whether sync is aborted for --fail-fast.
+func (v syncCommand) projectsError(errs []error, abort chan struct{}) error {
 	if len(errs) == 0 {
 		return nil
 	}
 
 	errMsg := ""
-	for _, err = range errs {
+	for _, err := range errs {
 		errMsg += err.Error() + "\n"
 	}
-	return errors.New(errMsg)
+	select {
+	case <-abort:
+		errMsg += "sync is aborted for --fail-fast\n"
+	default:
+	}
+	return syncProjectsError(errMsg)
+}
+
+// commitTemplate returns file of commit template to install in projects,
+// which is set in settings of workspace (git config "repo.committemplate"),
+// or by attribute "commit-template" of default element in manifest.
+// Relative path is based on manifests project.
+func (v syncCommand) commitTemplate() string {
+	rws := v.RepoWorkSpace()
+	name := rws.Settings().CommitTemplate
+	if name == "" && rws.Manifest != nil && rws.Manifest.Default != nil {
+		name = rws.Manifest.Default.CommitTemplate
+	}
+	if name == "" {
+		return ""
+	}
+	file := name
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(rws.ManifestProject.WorkDir, name)
+	}
+	if _, err := os.Stat(file); err != nil {
+		log.Warnf("commit template '%s' is not found", name)
+		return ""
+	}
+	return file
+}
+
+// lfsChecker returns a function to check whether git-lfs is installed,
+// which checks only once, and only if some project uses LFS.
+func lfsChecker() func() bool {
+	var (
+		once sync.Once
+		ok   bool
+	)
+
+	return func() bool {
+		once.Do(func() {
+			ok = project.LFSAvailable()
+			if !ok {
+				log.Warn("git-lfs is not installed, LFS objects of projects are not pulled")
+			}
+		})
+		return ok
+	}
+}
+
+// fetchLFS downloads LFS objects of the revision to checkout in network
+// half, if project uses Git LFS, and records downloaded size in sync
+// report.
+func (v syncCommand) fetchLFS(p *project.Project) error {
+	if v.O.NoLFS || p.IsMirror() || p.Revision == "" {
+		return nil
+	}
+	revision, err := p.ResolveRemoteTracking(p.Revision)
+	if err != nil || !p.UsesLFSAt(revision) || !v.lfsAvailable() {
+		return nil
+	}
+	size := p.LFSObjectsSize()
+	if err = p.LFSFetch(revision, &v.FetchOptions); err != nil {
+		return err
+	}
+	if size = p.LFSObjectsSize() - size; size > 0 {
+		log.Notef("%sfetched %d KiB of LFS objects", p.Prompt(), size>>10)
+		v.report.LFS(p, size)
+	}
+	return nil
+}
+
+// pullLFS checks out LFS objects of project after checkout, if project
+// uses Git LFS. LFS objects are fetched in network half, and missing
+// ones are downloaded unless --local-only is given.
+func (v syncCommand) pullLFS(p *project.Project) error {
+	if v.O.NoLFS || !p.UsesLFS() || !v.lfsAvailable() {
+		return nil
+	}
+	size := p.LFSObjectsSize()
+	if err := p.LFSPull(v.O.LocalOnly); err != nil {
+		return err
+	}
+	if size = p.LFSObjectsSize() - size; size > 0 {
+		log.Notef("%spulled %d KiB of LFS objects", p.Prompt(), size>>10)
+		v.report.LFS(p, size)
+	}
+	return nil
 }
 
 func (v syncCommand) LocalHalf(allProjects []*project.Project) error {
 	var (
-		err  error
-		errs []error
-		wg   sync.WaitGroup
+		failures  []syncFailure
+		errsMutex sync.Mutex
+		wg        sync.WaitGroup
+		abort     = make(chan struct{})
+		abortOnce sync.Once
 	)
 
 	jobs := v.O.Jobs
@@ -360,13 +702,19 @@ func (v syncCommand) LocalHalf(allProjects []*project.Project) error {
 	jobTasks := make(chan *project.Tree, jobs)
 
 	checkoutOptions := project.CheckoutOptions{
+		RepoSettings: v.FetchOptions.RepoSettings,
+
 		Quiet:      config.GetQuiet(),
 		DetachHead: v.O.DetachHead,
+		AutoStash:  v.O.AutoStash,
 	}
+	checkoutOptions.Jobs = jobs
+	commitTemplate := v.commitTemplate()
 
 	wg.Add(len(allProjects))
 
 	worker := func(i int) {
+		defer recoverCrash()
 		var (
 			err  error
 			tree *project.Tree
@@ -376,14 +724,30 @@ func (v syncCommand) LocalHalf(allProjects []*project.Project) error {
 		log.Debugf("start LocalHalf worker #%d", i)
 		for tree = range jobTasks {
 			p = tree.Project
-			if p != nil {
+			aborted := false
+			select {
+			case <-abort:
+				aborted = true
+			default:
+			}
+			if p != nil && !aborted {
 				log.Debugf("worker #%d: checkout %s", i, p.Name)
-				err = p.SyncLocalHalf(&checkoutOptions)
+				leave := helper.Trace2Region("checkout", p.Name)
+				err = v.checkoutProject(tree, &checkoutOptions, commitTemplate)
+				leave()
+				v.report.Checkout(p, err)
 				if err != nil {
-					errs = append(errs, err)
+					errsMutex.Lock()
+					failures = append(failures, syncFailure{tree: tree, err: err})
+					errsMutex.Unlock()
+					if v.O.FailFast {
+						abortOnce.Do(func() { close(abort) })
+					}
 				}
 			}
 
+			// Nested projects are still dispatched after abort, so
+			// that all projects are marked as done.
 			go func(tree project.Tree) {
 				for _, t := range tree.Trees {
 					jobTasks <- t
@@ -408,15 +772,78 @@ func (v syncCommand) LocalHalf(allProjects []*project.Project) error {
 	wg.Wait()
 	close(jobTasks)
 
-	if len(errs) == 0 {
-		return nil
+	aborted := false
+	select {
+	case <-abort:
+		aborted = true
+	default:
+	}
+	if v.O.Interactive && !aborted && len(failures) > 0 {
+		failures = v.resolveFailures(failures, checkoutOptions, commitTemplate)
 	}
 
-	errMsg := ""
-	for _, err = range errs {
-		errMsg += err.Error() + "\n"
+	errs := []error{}
+	for _, f := range failures {
+		errs = append(errs, f.err)
+	}
+	return v.projectsError(errs, abort)
+}
+
+// checkoutProject checks out project of tree, and updates settings of
+// project after checkout.
+func (v syncCommand) checkoutProject(tree *project.Tree, o *project.CheckoutOptions, commitTemplate string) error {
+	p := tree.Project
+	unlock, err := p.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	nested := nestedPaths(tree)
+	removed, err := p.ResolveWorkDirConflict(v.O.ForceSync, nested)
+	v.removed.Add(removed)
+	if err == nil {
+		err = p.SyncLocalHalf(o)
+	}
+	if err == nil {
+		err = p.ApplySparseCheckout()
+	}
+	if err == nil {
+		err = p.ExcludeNestedProjects(nested)
+	}
+	if err == nil {
+		err = p.SetCommitTemplate(commitTemplate)
+	}
+	if err == nil {
+		err = p.ApplyGitConfigs()
+	}
+	if err == nil {
+		err = v.pullLFS(p)
+	}
+	return err
+}
+
+// nestedPaths returns paths of projects nested in project of tree, which
+// are relative to path of the project.
+func nestedPaths(tree *project.Tree) []string {
+	paths := []string{}
+	for _, t := range tree.Trees {
+		if strings.HasPrefix(t.Path, tree.Path+"/") {
+			paths = append(paths, strings.TrimPrefix(t.Path, tree.Path+"/"))
+		}
+	}
+	return paths
+}
+
+// nestedPathsByPath saves nested paths of each project in tree to
+// result, using path of project as index.
+func nestedPathsByPath(tree *project.Tree, result map[string][]string) {
+	for _, t := range tree.Trees {
+		if t.Project != nil {
+			result[t.Path] = nestedPaths(t)
+		}
+		nestedPathsByPath(t, result)
 	}
-	return errors.New(errMsg)
 }
 
 // findObsoletePaths returns obsolete paths.
@@ -550,6 +977,14 @@ Please commit changes, then run sync again`,
 				p)
 		}
 
+		// Remove gitdir of git worktree, which is locked.
+		if dir := project.WorktreeGitDir(workdir); dir != "" {
+			err = os.RemoveAll(dir)
+			if err != nil {
+				return fmt.Errorf("fail to remove '%s': %s", dir, err)
+			}
+		}
+
 		// Remove gitdir first
 		err = os.RemoveAll(gitdir)
 		if err != nil {
@@ -590,8 +1025,7 @@ func (v syncCommand) UpdateProjectList() error {
 	)
 
 	allProjects, err := ws.GetProjects(&workspace.GetProjectsOptions{
-		MissingOK:    true,
-		SubmodulesOK: v.O.FetchSubmodules,
+		MissingOK: true,
 	})
 	if err != nil {
 		return err
@@ -655,17 +1089,76 @@ func (v syncCommand) Execute(args []string) error {
 
 	rws := v.RepoWorkSpace()
 
-	if v.O.Jobs > 0 {
-		v.O.Jobs = min(v.O.Jobs, v.maxSyncJobs())
-	} else {
-		v.O.Jobs = 1
-	}
+	v.O.Jobs = min(rws.Jobs(jobsOption(v.cmd, v.O.Jobs), syncDefaultJobs), v.maxSyncJobs())
 	if v.O.NetworkOnly && v.O.DetachHead {
 		return newUserError("cannot combine -n and -d")
 	}
+	if v.O.NetworkOnly && v.O.AutoStash {
+		return newUserError("cannot combine -n and --autostash")
+	}
+	if v.O.DetachHead && v.O.AutoStash {
+		return newUserError("cannot combine -d and --autostash")
+	}
 	if v.O.NetworkOnly && v.O.LocalOnly {
 		return newUserError("cannot combine -n and -l")
 	}
+	if v.O.NetworkOnly && v.O.RunHooks {
+		return newUserError("cannot combine -n and --run-hooks")
+	}
+	if v.O.Tags && v.O.NoTags {
+		return newUserError("cannot combine --tags and --no-tags")
+	}
+	if v.O.FailFast && v.O.ForceBroken {
+		return newUserError("cannot combine --fail-fast and --force-broken")
+	}
+	if v.O.RetryFetches < 0 {
+		return newUserError("--retry-fetches must not be negative")
+	}
+	if v.O.Rollback < 0 {
+		return newUserError("--rollback must not be negative")
+	}
+	if v.O.Rollback > 0 {
+		if v.O.ManifestName != "" || v.O.SmartSync || v.O.SmartTag != "" ||
+			v.O.ToDate != "" || v.O.ToTag != "" {
+			return newUserError("cannot combine --rollback with -m, -s, -t, --to-date or --to-tag")
+		}
+		if v.O.NetworkOnly || v.O.AutoStash {
+			return newUserError("cannot combine --rollback with -n or --autostash")
+		}
+		if len(args) > 0 {
+			return newUserError("cannot roll back only some projects")
+		}
+		v.O.ManifestName, v.rollback, err = v.prepareRollback(v.O.Rollback)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(filepath.Join(rws.AdminDir(), syncRollbackDir))
+	}
+	if v.O.ToDate != "" && v.O.ToTag != "" {
+		return newUserError("cannot combine --to-date and --to-tag")
+	}
+	if v.O.ToDate != "" || v.O.ToTag != "" {
+		if v.O.NetworkOnly {
+			return newUserError("cannot combine -n with --to-date or --to-tag")
+		}
+		if v.O.AutoStash {
+			return newUserError("cannot combine --autostash with --to-date or --to-tag")
+		}
+	}
+	if v.O.Interactive {
+		if v.O.NetworkOnly || v.O.Interval > 0 {
+			return newUserError("cannot combine --interactive with -n or --interval")
+		}
+		if !cap.Isatty() || config.AssumeYes() || config.AssumeNo() {
+			return newUserError("--interactive needs a terminal to answer questions")
+		}
+	}
+	if v.O.ToDate != "" {
+		v.toDate, err = parseSyncDate(v.O.ToDate)
+		if err != nil {
+			return newUserError(err)
+		}
+	}
 	if v.O.ManifestName != "" && v.O.SmartSync {
 		return newUserError("cannot combine -m and -s")
 	}
@@ -682,8 +1175,108 @@ func (v syncCommand) Execute(args []string) error {
 	}
 
 	if v.O.ManifestName != "" {
+		if err = v.verifyManifestFile(v.O.ManifestName); err != nil {
+			return err
+		}
 		rws.Override(v.O.ManifestName)
 	}
+	if (v.O.SmartSync || v.O.SmartTag != "") && rws.ManifestProject.ManifestTrust().Verify {
+		return newUserError("cannot smart sync: manifest from manifest server is not signed")
+	}
+
+	if v.O.MetricsListen != "" && v.O.Interval <= 0 {
+		return newUserError("--metrics-listen must be used with --interval")
+	}
+	if v.O.Interval > 0 {
+		if !rws.IsMirror() {
+			return newUserError("--interval can only be used in a mirror")
+		}
+		return v.daemon(args)
+	}
+
+	err = v.syncOnce(args, nil)
+	if err != nil && v.O.DetailedExitCode {
+		if _, ok := err.(syncProjectsError); ok {
+			return newExitError(syncExitPartial, err)
+		}
+		return newExitError(syncExitFatal, err)
+	}
+	return err
+}
+
+// syncOnce runs sync, and saves result in report file and metrics.
+func (v syncCommand) syncOnce(args []string, metrics *syncMetrics) error {
+	if v.O.ReportFile != "" || metrics != nil || v.O.ShowChanges || v.O.ReportChanges != "" {
+		v.report = newSyncReport(v.O.ReportFile)
+	}
+
+	// Other git-repo commands working on this workspace must wait until
+	// sync is finished.
+	unlock, err := v.RepoWorkSpace().Lock()
+	if err == nil {
+		err = v.sync(args)
+		unlock()
+	}
+
+	status := syncStatusOK
+	if _, ok := err.(syncProjectsError); ok {
+		status = syncStatusPartial
+	} else if err != nil {
+		status = syncStatusFatal
+	}
+	if e := v.report.Save(status, err); e != nil {
+		log.Errorf("fail to save sync report to '%s': %s", v.O.ReportFile, e)
+	}
+	if v.O.ShowChanges || v.O.ReportChanges != "" {
+		changes := v.report.Changes()
+		if v.O.ShowChanges {
+			changes.Show(os.Stdout)
+		}
+		if v.O.ReportChanges != "" {
+			if e := changes.Save(v.O.ReportChanges); e != nil {
+				log.Errorf("fail to save changes to '%s': %s", v.O.ReportChanges, e)
+			}
+		}
+	}
+	metrics.Update(v.report)
+	return err
+}
+
+// daemon syncs a mirror repeatedly, and serves metrics if required.
+func (v syncCommand) daemon(args []string) error {
+	metrics := newSyncMetrics()
+	if v.O.MetricsListen != "" {
+		listener, err := net.Listen("tcp", v.O.MetricsListen)
+		if err != nil {
+			return fmt.Errorf("fail to listen on '%s': %s", v.O.MetricsListen, err)
+		}
+		defer listener.Close()
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics)
+		go http.Serve(listener, mux)
+		log.Notef("serve metrics on http://%s/metrics", listener.Addr())
+	}
+
+	for {
+		if err := v.syncOnce(args, metrics); err != nil {
+			log.Errorf("fail to sync: %s", err)
+		}
+		log.Infof("next sync will start in %s", v.O.Interval)
+		time.Sleep(v.O.Interval)
+	}
+}
+
+// sync updates manifest project, then fetches and checks out projects.
+func (v syncCommand) sync(args []string) error {
+	var (
+		err error
+	)
+
+	rws := v.RepoWorkSpace()
+
+	v.removed = &removedPaths{}
+	defer v.removed.Show(rws.RootDir)
+	v.lfsAvailable = lfsChecker()
 
 	v.FetchOptions = project.FetchOptions{
 		RepoSettings: *(rws.Settings()),
@@ -692,16 +1285,24 @@ func (v syncCommand) Execute(args []string) error {
 		CloneBundle:       !v.O.NoCloneBundle,
 		CurrentBranchOnly: v.O.CurrentBranchOnly,
 		ForceSync:         v.O.ForceSync,
+		Tags:              v.O.Tags,
 		NoTags:            v.O.NoTags,
 		OptimizedFetch:    v.O.OptimizedFetch,
 		Prune:             v.O.Prune,
+		RetryFetches:      v.O.RetryFetches,
 	}
+	// Submodules of all projects are fetched and updated, if workspace
+	// is initialized with --submodules, or --fetch-submodules is given.
+	v.FetchOptions.Submodules = v.FetchOptions.Submodules || v.O.FetchSubmodules
 
 	smartSyncManifestName := "smart_sync_override.xml"
 	smartSyncManifestPath := filepath.Join(rws.ManifestProject.WorkDir, smartSyncManifestName)
 
 	if v.O.SmartSync || v.O.SmartTag != "" {
-		v.CallManifestServerRPC()
+		err = v.CallManifestServerRPC(smartSyncManifestPath)
+		if err != nil {
+			return err
+		}
 	} else {
 		if _, err = os.Stat(smartSyncManifestPath); err == nil {
 			err = os.Remove(smartSyncManifestPath)
@@ -718,18 +1319,62 @@ func (v syncCommand) Execute(args []string) error {
 
 	// Use reloaded WorkSpace after calling `updateManifestProject()`.
 	rws = v.RepoWorkSpace()
+	if v.O.SmartSync || v.O.SmartTag != "" {
+		err = rws.Override(smartSyncManifestName)
+		if err != nil {
+			return err
+		}
+	}
 
 	allProjects, err := rws.GetProjects(&workspace.GetProjectsOptions{
-		Groups:       rws.Settings().Groups,
-		MissingOK:    true,
-		SubmodulesOK: v.O.FetchSubmodules,
+		Groups:    rws.Settings().Groups,
+		MissingOK: true,
 	}, args...)
 
+	// Defer cloning new projects, which are large fetches, until the
+	// fetch window.
+	windowSpec := v.O.FetchWindow
+	if windowSpec == "" {
+		windowSpec = rws.Settings().FetchWindow
+	}
+	window, err := parseFetchWindow(windowSpec)
+	if err != nil {
+		return newUserError(err)
+	}
+	if !v.O.LocalOnly && !window.Contains(time.Now()) {
+		var deferred []*project.Project
+
+		allProjects, deferred = deferNewProjects(allProjects)
+		for _, p := range deferred {
+			log.Notef("%snot cloned, deferred until fetch window %s", p.Prompt(), windowSpec)
+		}
+	}
+	v.report.AddProjects(allProjects)
+
 	if !v.O.LocalOnly {
+		if !v.O.NoSSHMaster {
+			v.sshMaster = helper.NewSSHMaster()
+			defer v.sshMaster.Close()
+			v.sshMaster.CloseOnSignal()
+			v.sshMaster.Setenv()
+		}
+		// Git commands over HTTP fetch through a local proxy, which
+		// limits bandwidth.
+		if helper.BandwidthLimited() {
+			proxy, err := helper.NewThrottleProxy()
+			if err == nil {
+				defer proxy.Close()
+				err = proxy.Setenv()
+			}
+			if err != nil {
+				log.Warnf("bandwidth limit is not applied to git over HTTP: %s", err)
+			}
+		}
 		err = v.NetworkHalf(allProjects)
 		if err != nil {
 			return err
 		}
+		v.autoGC(allProjects)
 	}
 
 	if v.O.NetworkOnly ||
@@ -750,11 +1395,36 @@ func (v syncCommand) Execute(args []string) error {
 		log.Fatal(err)
 	}
 
+	// Check out projects as they were at the time or build, in detached
+	// HEAD, which is used to bisect breakage of the whole workspace.
+	if v.rollback != nil {
+		pinRollback(allProjects, v.rollback)
+		v.O.DetachHead = true
+	} else if !v.toDate.IsZero() || v.O.ToTag != "" {
+		pinProjects(allProjects, v.toDate, v.O.ToTag)
+		v.O.DetachHead = true
+	}
+
 	err = v.LocalHalf(allProjects)
 	if err != nil {
 		return err
 	}
 
+	if v.O.RunHooks {
+		err = v.runPostSyncHooks(allProjects)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Record a full sync to the latest revisions, which can be rolled
+	// back to by "--rollback". Sync to an extracted manifest, such as
+	// rollback and bisect, is not recorded.
+	if len(args) == 0 && v.toDate.IsZero() && v.O.ToTag == "" &&
+		!strings.HasPrefix(v.O.ManifestName, "..") {
+		v.recordSyncHistory(allProjects)
+	}
+
 	// If there's a notice that's supposed to print at the end of the sync,
 	// print it now...
 	if rws.Manifest != nil && rws.Manifest.Notice != "" {
@@ -764,6 +1434,74 @@ func (v syncCommand) Execute(args []string) error {
 	return nil
 }
 
+// runPostSyncHooks runs post-sync command defined in annotation of projects
+// one by one, and asks user to approve the command if it is new or changed.
+func (v syncCommand) runPostSyncHooks(projects []*project.Project) error {
+	var errs []error
+
+	for _, p := range projects {
+		command := p.PostSyncCommand()
+		if command == "" {
+			continue
+		}
+		if _, err := os.Stat(p.WorkDir); err != nil {
+			continue
+		}
+		if _, err := p.PostSyncHash(); err != nil {
+			errs = append(errs, fmt.Errorf("%s%s", p.Prompt(), err))
+			continue
+		}
+		// Command is not run in dryrun mode, no need to approve.
+		if config.IsDryRun() {
+			log.Notef("%swill run post-sync command: %s", p.Prompt(), command)
+			continue
+		}
+		if !p.IsPostSyncApproved() {
+			fmt.Printf("Post-sync command of project %s is new or changed since last run:\n\n",
+				p.Path)
+			fmt.Printf("\t%s\n\n", command)
+			input := userInput("Do you want to allow this command to run (yes/always/NO)? ", "no")
+			if strings.ToLower(input) == "always" {
+				if err := p.ApprovePostSync(); err != nil {
+					log.Warnf("%sfail to save approval of post-sync command: %s",
+						p.Prompt(), err)
+				}
+			} else if !answerIsTrue(input) {
+				log.Notef("%sskip post-sync command", p.Prompt())
+				continue
+			}
+		}
+		if err := p.RunPostSync(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return v.projectsError(errs, nil)
+}
+
+// autoGC runs gc on object stores of projects after fetch according to
+// maintenance policy in settings of workspace (git config "repo.gc"),
+// errors are only warned.
+func (v syncCommand) autoGC(projects []*project.Project) {
+	rws := v.RepoWorkSpace()
+	policy := rws.Settings().GC
+	switch policy {
+	case "", gcPolicyAuto:
+	case gcPolicyNever:
+		return
+	default:
+		log.Warnf("unknown value '%s' of config %s, use '%s' instead",
+			policy,
+			config.CfgRepoGC,
+			gcPolicyAuto)
+	}
+
+	jobs := rws.Jobs(jobsOption(v.cmd, v.O.Jobs), gcDefaultJobs)
+	_, err := gcProjects(projects, rws.Projects, jobs, true, false)
+	if err != nil {
+		log.Warn(err)
+	}
+}
+
 var syncCmd = syncCommand{
 	WorkSpaceCommand: WorkSpaceCommand{
 		MirrorOK: true,
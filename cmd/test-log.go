@@ -15,7 +15,7 @@
 package cmd
 
 import (
-	log "github.com/jiangxin/multi-log"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/spf13/cobra"
 )
 
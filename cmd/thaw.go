@@ -0,0 +1,205 @@
+// Copyright © 2019 Alibaba Co. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/path"
+	"github.com/spf13/cobra"
+)
+
+// thawDir is where archive of workspace snapshot is extracted, in ".repo".
+const thawDir = "thaw"
+
+// extractSnapshot extracts regular files of archive, which may be
+// compressed, to dir. Files outside dir are refused.
+func extractSnapshot(file, dir string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	// Archive is compressed if it starts with magic number of gzip.
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("bad archive '%s': %s", file, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if filepath.IsAbs(name) || name == ".." ||
+			strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("bad file '%s' in archive '%s'", hdr.Name, file)
+		}
+		target := filepath.Join(dir, name)
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		out, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, tr)
+		if e := out.Close(); err == nil {
+			err = e
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+type thawCommand struct {
+	WorkSpaceCommand
+
+	cmd *cobra.Command
+	O   struct {
+		NoSync bool
+	}
+}
+
+func (v *thawCommand) Command() *cobra.Command {
+	if v.cmd != nil {
+		return v.cmd
+	}
+
+	v.cmd = &cobra.Command{
+		Use:   "thaw <file>",
+		Short: "Restore state of workspace saved by freeze",
+		Long: `Restore state of workspace from an archive saved by "git repo freeze".
+The workspace is synced with the manifest in the archive (unless --no-sync
+is given), then local branches and HEAD of projects are restored, with
+local commits from bundles, and uncommitted changes are applied to the
+worktrees.
+
+Local branches which exist with other commits are not changed, and are
+restored with suffix "-snapshot" instead. Stashes are listed but not
+restored.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Execute(args)
+		},
+	}
+	v.cmd.Flags().BoolVar(&v.O.NoSync,
+		"no-sync",
+		false,
+		"do not sync workspace with manifest in the archive")
+
+	return v.cmd
+}
+
+func (v thawCommand) Execute(args []string) error {
+	rws := v.RepoWorkSpace()
+	dir := filepath.Join(rws.AdminDir(), thawDir)
+	os.RemoveAll(dir)
+	defer os.RemoveAll(dir)
+
+	if err := extractSnapshot(args[0], dir); err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, snapshotStateFile))
+	if err != nil {
+		return newUserErrorF("'%s' is not saved by freeze", args[0])
+	}
+	state := workspaceSnapshot{}
+	if err = json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("bad state of workspace in '%s': %s", args[0], err)
+	}
+	log.Notef("restore state of %d projects saved at %s", len(state.Projects),
+		state.Time.Local().Format("2006-01-02 15:04:05"))
+
+	if !v.O.NoSync {
+		s := syncCmd
+		s.O.ManifestName = filepath.Join("..", thawDir, exportManifestName)
+		s.O.DetachHead = true
+		if err = s.Execute(nil); err != nil {
+			return fmt.Errorf("fail to sync with manifest of snapshot: %s", err)
+		}
+	}
+
+	failed := 0
+	for _, s := range state.Projects {
+		p := rws.GetProjectWithPath(s.Path)
+		if p == nil || !p.Exists() {
+			log.Errorf("project '%s' is not checked out", s.Path)
+			failed++
+			continue
+		}
+		bundle, patch := "", ""
+		if s.HasBundle {
+			bundle = filepath.Join(dir, filepath.FromSlash(snapshotBundleName(s.Path)))
+		}
+		if s.HasDiff {
+			patch = filepath.Join(dir, filepath.FromSlash(snapshotDiffName(s.Path)))
+		}
+		if (bundle != "" && !path.IsFile(bundle)) || (patch != "" && !path.IsFile(patch)) {
+			log.Errorf("%sfiles of project are missing in archive", p.Prompt())
+			failed++
+			continue
+		}
+		if err = p.RestoreSnapshot(s, bundle, patch); err != nil {
+			log.Error(err)
+			failed++
+			continue
+		}
+		log.Infof("%srestored", p.Prompt())
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("fail to restore %d of %d projects", failed, len(state.Projects))
+	}
+	log.Notef("restored state of %d projects", len(state.Projects))
+	return nil
+}
+
+var thawCmd = thawCommand{
+	WorkSpaceCommand: WorkSpaceCommand{
+		MirrorOK: false,
+		SingleOK: false,
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(thawCmd.Command())
+}
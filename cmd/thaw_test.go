@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"archive/tar"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractSnapshot(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-thaw-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	for _, name := range []string{"snapshot.tar", "snapshot.tar.gz"} {
+		file := filepath.Join(tmpdir, name)
+		w, closeFile, err := createExportFile(file)
+		assert.Nil(err)
+		tw := tar.NewWriter(w)
+		assert.Nil(writeTarFile(tw, snapshotStateFile, []byte("{}\n")))
+		assert.Nil(writeTarFile(tw, snapshotDiffName("platform/app"), []byte("diff\n")))
+		assert.Nil(tw.Close())
+		assert.Nil(closeFile())
+
+		dir := filepath.Join(tmpdir, name+".d")
+		assert.Nil(extractSnapshot(file, dir), name)
+		data, err := ioutil.ReadFile(filepath.Join(dir, "diffs", "platform", "app.patch"))
+		assert.Nil(err, name)
+		assert.Equal("diff\n", string(data))
+	}
+
+	// Files outside of dir are refused.
+	file := filepath.Join(tmpdir, "bad.tar")
+	w, closeFile, err := createExportFile(file)
+	assert.Nil(err)
+	tw := tar.NewWriter(w)
+	assert.Nil(writeTarFile(tw, "../outside", []byte("bad\n")))
+	assert.Nil(tw.Close())
+	assert.Nil(closeFile())
+	assert.NotNil(extractSnapshot(file, filepath.Join(tmpdir, "bad")))
+	_, err = os.Stat(filepath.Join(tmpdir, "outside"))
+	assert.True(os.IsNotExist(err))
+}
+
+func TestSnapshotBaseProject(t *testing.T) {
+	assert := assert.New(t)
+
+	m := manifest.Manifest{
+		Projects: []manifest.Project{
+			{
+				Name:     "platform/app",
+				Path:     "app",
+				Revision: "a1",
+				Projects: []manifest.Project{
+					{Name: "platform/lib", Path: "lib", Revision: "b1"},
+				},
+			},
+			{Name: "platform/doc", Path: "doc", Revision: "c1"},
+		},
+	}
+	assert.Nil(m.ProjectHandle(snapshotBaseProject{bases: map[string]string{
+		"app":     "a0",
+		"app/lib": "b0",
+	}}))
+	assert.Equal("a0", m.Projects[0].Revision)
+	assert.Equal("b0", m.Projects[0].Projects[0].Revision)
+	assert.Equal("c1", m.Projects[1].Revision)
+}
@@ -22,13 +22,11 @@ import (
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"hash"
 	"io"
 	"io/ioutil"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -44,8 +42,8 @@ import (
 	"github.com/alibaba/git-repo-go/file"
 	"github.com/alibaba/git-repo-go/format"
 	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/version"
-	log "github.com/jiangxin/multi-log"
 	"github.com/spf13/cobra"
 	"golang.org/x/crypto/openpgp"
 	"gopkg.in/yaml.v2"
@@ -65,6 +63,7 @@ type upgradeCommand struct {
 	O struct {
 		URL          string
 		Test         bool
+		Channel      string
 		Version      string
 		NoCertChecks bool
 	}
@@ -134,8 +133,15 @@ func (v *upgradeCommand) Command() *cobra.Command {
 	}
 
 	v.cmd = &cobra.Command{
-		Use:   "upgrade",
-		Short: "Check and upgrade git-repo",
+		Use:     "upgrade",
+		Aliases: []string{"selfupdate"},
+		Short:   "Check and upgrade git-repo",
+		Long: `Check the latest version of git-repo from the release endpoint, and
+replace the running binary with the downloaded package after checksum
+and signature are verified.
+
+Upgrade from the stable channel by default. Set "releasechannel" to
+"test" in ~/.git-repo/config to pin to the test channel.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return v.Execute(args)
 		},
@@ -155,6 +161,10 @@ func (v *upgradeCommand) Command() *cobra.Command {
 		"t",
 		false,
 		"upgrade to test version")
+	v.cmd.Flags().StringVar(&v.O.Channel,
+		"channel",
+		"",
+		"upgrade from release channel: stable or test")
 	v.cmd.Flags().BoolVar(&v.O.NoCertChecks,
 		"no-cert-checks",
 		false,
@@ -164,40 +174,14 @@ func (v *upgradeCommand) Command() *cobra.Command {
 }
 
 func (v *upgradeCommand) HTTPClient() *http.Client {
-	var (
-		timeout = time.Duration(10)
-	)
-
 	if v.httpClient != nil {
 		return v.httpClient
 	}
 
-	tr := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   timeout * time.Second,
-			KeepAlive: timeout * time.Second,
-		}).DialContext,
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: v.O.NoCertChecks || config.NoCertChecks(),
-		},
-		TLSHandshakeTimeout:   timeout * time.Second,
-		ResponseHeaderTimeout: timeout * time.Second,
-		ExpectContinueTimeout: timeout * time.Second,
-		MaxIdleConns:          10,
-		IdleConnTimeout:       timeout * time.Second,
-		DisableCompression:    true,
-		Proxy:                 http.ProxyFromEnvironment,
-	}
-
-	// http.proxy overrides env $HTTP_PROXY, $HTTPS_PROXY and $NO_PROXY (or the lowercase versions thereof).
-	proxyURL, err := helper.GetProxyFromGitConfig()
-	if err != nil {
-		log.Debugf("fail to get proxy from git config: %s", err)
-	} else {
-		tr.Proxy = http.ProxyURL(proxyURL)
-	}
-
-	v.httpClient = &http.Client{Transport: tr}
+	v.httpClient = helper.NewHTTPClient(v.O.URL, &helper.HTTPClientOptions{
+		Timeout:      10 * time.Second,
+		NoCertChecks: v.O.NoCertChecks || config.NoCertChecks(),
+	})
 	return v.httpClient
 }
 
@@ -725,9 +709,25 @@ func (v upgradeCommand) Execute(args []string) error {
 		err         error
 	)
 
+	if v.O.Test && v.O.Channel != "" {
+		return fmt.Errorf("cannot use --test and --channel together")
+	}
 	if v.O.Test && v.O.Version != "" {
 		return fmt.Errorf("cannot use --test and --version together")
 	}
+	if !v.O.Test && v.O.Version == "" {
+		channel := v.O.Channel
+		if channel == "" {
+			channel = config.GetReleaseChannel()
+		}
+		switch channel {
+		case "", "stable", "production":
+		case "test":
+			v.O.Test = true
+		default:
+			return fmt.Errorf("unknown release channel '%s'", channel)
+		}
+	}
 
 	mainProgram, err = os.Executable()
 	if err != nil {
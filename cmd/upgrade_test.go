@@ -274,3 +274,20 @@ func TestExtract(t *testing.T) {
 		}
 	}
 }
+
+func TestUpgradeChannel(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		cmd    upgradeCommand
+	)
+
+	cmd.O.Test = true
+	cmd.O.Channel = "stable"
+	err := cmd.Execute(nil)
+	assert.Equal("cannot use --test and --channel together", err.Error())
+
+	cmd.O.Test = false
+	cmd.O.Channel = "beta"
+	err = cmd.Execute(nil)
+	assert.Equal("unknown release channel 'beta'", err.Error())
+}
@@ -26,10 +26,12 @@ import (
 	"github.com/alibaba/git-repo-go/common"
 	"github.com/alibaba/git-repo-go/config"
 	"github.com/alibaba/git-repo-go/editor"
+	"github.com/alibaba/git-repo-go/gerrit"
 	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/path"
 	"github.com/alibaba/git-repo-go/project"
-	log "github.com/jiangxin/multi-log"
+	"github.com/alibaba/git-repo-go/workspace"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
@@ -58,6 +60,7 @@ type uploadOptions struct {
 	Description    string
 	DestBranch     string
 	Draft          bool
+	Hashtags       []string
 	Issue          string
 	MockGitPush    bool
 	MockEditScript string
@@ -65,11 +68,16 @@ type uploadOptions struct {
 	NoCertChecks   bool
 	NoEdit         bool
 	NoEmails       bool
+	NoOwners       bool
 	Private        bool
 	PushOptions    []string
+	Ready          bool
+	RemovePrivate  bool
 	Reviewers      []string
 	Remote         string
+	Sign           bool
 	Title          string
+	Topic          string
 	WIP            bool
 }
 
@@ -246,8 +254,9 @@ func (v *uploadOptions) Export(published bool) []string {
 type uploadCommand struct {
 	WorkSpaceCommand
 
-	cmd *cobra.Command
-	O   uploadOptions
+	cmd  *cobra.Command
+	O    uploadOptions
+	hook *workspace.RepoHook
 }
 
 func (v *uploadCommand) Command() *cobra.Command {
@@ -256,7 +265,7 @@ func (v *uploadCommand) Command() *cobra.Command {
 	}
 
 	v.cmd = &cobra.Command{
-		Use:   "upload",
+		Use:   "upload [<project>...]",
 		Short: "Upload changes for code review",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return v.Execute(args)
@@ -273,6 +282,8 @@ func (v *uploadCommand) Command() *cobra.Command {
 			name = "cbr"
 		case "destination":
 			name = "dest"
+		case "ht":
+			name = "hashtag"
 		}
 		return pflag.NormalizedName(name)
 	}
@@ -313,6 +324,10 @@ func (v *uploadCommand) Command() *cobra.Command {
 		"no-emails",
 		false,
 		"If specified, do not send emails on upload")
+	v.cmd.Flags().BoolVar(&v.O.NoOwners,
+		"no-owners",
+		false,
+		"If specified, do not add owners of projects as reviewers")
 	v.cmd.Flags().BoolVarP(&v.O.Private,
 		"private",
 		"p",
@@ -330,11 +345,27 @@ func (v *uploadCommand) Command() *cobra.Command {
 		"issue",
 		"",
 		"Related issues for review")
+	v.cmd.Flags().StringVar(&v.O.Topic,
+		"topic",
+		"",
+		"Topic for review, shared by branches of all projects")
+	v.cmd.Flags().StringArrayVar(&v.O.Hashtags,
+		"hashtag",
+		nil,
+		"Add hashtags (comma delimited) to the review")
 	v.cmd.Flags().BoolVarP(&v.O.WIP,
 		"wip",
 		"w",
 		false,
 		"If specified, upload as a work-in-progress change")
+	v.cmd.Flags().BoolVar(&v.O.Ready,
+		"ready",
+		false,
+		"If specified, mark a work-in-progress change as ready for review")
+	v.cmd.Flags().BoolVar(&v.O.RemovePrivate,
+		"remove-private",
+		false,
+		"If specified, remove the private flag of the change")
 	v.cmd.Flags().StringArrayVarP(&v.O.PushOptions,
 		"push-options",
 		"o",
@@ -356,7 +387,11 @@ func (v *uploadCommand) Command() *cobra.Command {
 	v.cmd.Flags().BoolVar(&v.O.BypassHooks,
 		"no-verify",
 		false,
-		"Do not run the upload hook")
+		"Do not run the upload hook and commit validations")
+	v.cmd.Flags().BoolVar(&v.O.Sign,
+		"sign",
+		false,
+		"Sign commits which are not signed by GPG or SSH key before upload")
 	v.cmd.Flags().BoolVar(&v.O.AllowAllHooks,
 		"verify",
 		false,
@@ -402,6 +437,9 @@ func (v *uploadCommand) getDestBranch(branch *project.ReviewableBranch) (string,
 		destBranch = v.O.DestBranch
 	} else if p.DestBranch != "" {
 		destBranch = p.DestBranch
+	} else if common.IsSha(p.Revision) && p.Upstream != "" {
+		// Project is pinned by a frozen manifest.
+		destBranch = p.Upstream
 	} else if p.Revision != "" {
 		destBranch = p.Revision
 	}
@@ -813,9 +851,28 @@ func (v uploadCommand) fmtUploadOptionsScript(optionsFile string, published bool
 	return script
 }
 
+// appendOwners appends owners of project to reviewers, except those
+// already in reviewers.
+func appendOwners(reviewers, owners []string) []string {
+	for _, owner := range owners {
+		found := false
+		for _, reviewer := range reviewers {
+			if reviewer == owner {
+				found = true
+				break
+			}
+		}
+		if !found {
+			reviewers = append(reviewers, owner)
+		}
+	}
+	return reviewers
+}
+
 func (v *uploadCommand) UploadAndReport(branches []project.ReviewableBranch) error {
 	var (
 		origPeople = [][]string{{}, {}}
+		hashtags   = []string{}
 		oldOid     = ""
 		err        error
 		destBranch string
@@ -842,6 +899,13 @@ func (v *uploadCommand) UploadAndReport(branches []project.ReviewableBranch) err
 		}
 	}
 
+	for _, hashtag := range strings.Split(strings.Join(v.O.Hashtags, ","), ",") {
+		hashtag = strings.TrimSpace(hashtag)
+		if hashtag != "" {
+			hashtags = append(hashtags, hashtag)
+		}
+	}
+
 	haveErrors := false
 	for i := range branches {
 		// Will update branch.Error in this loop.
@@ -855,10 +919,42 @@ func (v *uploadCommand) UploadAndReport(branches []project.ReviewableBranch) err
 			)
 			continue
 		}
+		branch.Sign = v.O.Sign
+		if v.O.BypassHooks && v.O.Sign {
+			if err = branch.SignCommits(); err != nil {
+				branch.Error = err
+				haveErrors = true
+				continue
+			}
+		} else if !v.O.BypassHooks {
+			problems, err := branch.Validate()
+			if err == nil && len(problems) > 0 {
+				for _, problem := range problems {
+					log.Errorf("%s%s", theProject.Prompt(), problem)
+				}
+				err = fmt.Errorf("%d problem(s) found in commits", len(problems))
+			}
+			if err != nil {
+				branch.Error = err
+				haveErrors = true
+				continue
+			}
+		}
+		if v.hook != nil {
+			err = v.runHook(theProject, branch.Commits())
+			if err != nil {
+				branch.Error = err
+				haveErrors = true
+				continue
+			}
+		}
 		people := [][]string{{}, {}}
 		people[0] = append(people[0], origPeople[0]...)
 		people[1] = append(people[1], origPeople[1]...)
 		branch.AppendReviewers(people)
+		if rws, ok := v.WorkSpace().(*workspace.RepoWorkSpace); ok && !v.O.NoOwners {
+			people[0] = appendOwners(people[0], rws.ProjectOwners(theProject))
+		}
 		cfg := theProject.ConfigWithDefault()
 		if !theProject.IsClean() {
 			key := fmt.Sprintf("review.%s.autoupload", remote.Review)
@@ -917,36 +1013,104 @@ func (v *uploadCommand) UploadAndReport(branches []project.ReviewableBranch) err
 			}
 		}
 
+		// Reviewers in push options of Gerrit reject the push if
+		// any of them is unknown, so set them by REST API instead.
+		apiPeople := [][]string{}
+		key := fmt.Sprintf("review.%s.setreviewersbyapi", remote.Review)
+		if remote.GetType() == helper.ProtoTypeGerrit && cfg.GetBool(key, false) {
+			apiPeople = people
+			people = [][]string{{}, {}}
+		}
+
 		o := config.UploadOptions{
-			AutoTopic:    v.O.AutoTopic,
-			CodeReview:   v.O.CodeReview,
-			Description:  v.O.Description,
-			DestBranch:   destBranch,
-			Draft:        v.O.Draft,
-			Issue:        v.O.Issue,
-			LocalBranch:  branch.Branch.Name,
-			MockGitPush:  v.O.MockGitPush,
-			NoCertChecks: v.O.NoCertChecks || config.NoCertChecks(),
-			NoEmails:     v.O.NoEmails,
-			OldOid:       oldOid,
-			People:       people,
-			Private:      v.O.Private,
-			PushOptions:  v.O.PushOptions,
-			Title:        v.O.Title,
-			WIP:          v.O.WIP,
+			AutoTopic:     v.O.AutoTopic,
+			CodeReview:    v.O.CodeReview,
+			Description:   v.O.Description,
+			DestBranch:    destBranch,
+			Draft:         v.O.Draft,
+			Hashtags:      hashtags,
+			Issue:         v.O.Issue,
+			LocalBranch:   branch.Branch.Name,
+			MockGitPush:   v.O.MockGitPush,
+			NoCertChecks:  v.O.NoCertChecks || config.NoCertChecks(),
+			NoEmails:      v.O.NoEmails,
+			OldOid:        oldOid,
+			People:        people,
+			Private:       v.O.Private,
+			PushOptions:   v.O.PushOptions,
+			Ready:         v.O.Ready,
+			RemovePrivate: v.O.RemovePrivate,
+			Title:         v.O.Title,
+			Topic:         v.O.Topic,
+			WIP:           v.O.WIP,
 		}
 
-		err = branch.UploadForReview(&o)
+		// Options such as --wip are rejected before push, if server
+		// advertises capabilities without them.
+		if sshInfo := remote.GetSSHInfo(); sshInfo != nil {
+			if err = sshInfo.CheckUploadOptions(&o); err != nil {
+				branch.Uploaded = false
+				branch.Error = err
+				haveErrors = true
+				continue
+			}
+		}
+
+		var unlock func()
+		unlock, err = theProject.Lock()
+		if err == nil {
+			err = branch.UploadForReview(&o)
+			unlock()
+		}
 		if err != nil {
 			branch.Uploaded = false
 			branch.Error = err
 			haveErrors = true
+		} else {
+			branch.Uploaded = true
+			if len(apiPeople) > 0 {
+				v.setReviewersByAPI(branch, apiPeople, o.NoCertChecks)
+			}
+		}
+
+		// Server of non-Gerrit backend may not send back URL of
+		// code review, so use review URL template of remote. Number
+		// of the change is read from push messages, or is the one
+		// to update.
+		if branch.Uploaded && len(branch.ReviewURLs) == 0 {
+			change := branch.Change
+			if change == "" {
+				change = v.O.CodeReview.ID
+			}
+			u := theProject.ReviewURL(remote, map[string]string{
+				"change":       change,
+				"branch":       strings.TrimPrefix(destBranch, config.RefsHeads),
+				"local-branch": branch.Branch.ShortName(),
+				"topic":        v.O.Topic,
+			})
+			if u != "" {
+				branch.ReviewURLs = append(branch.ReviewURLs, u)
+			}
 		}
-		branch.Uploaded = true
 	}
 
 	fmt.Fprintln(os.Stderr, "")
 	fmt.Fprintln(os.Stderr, "----------------------------------------------------------------------")
+	for _, branch := range branches {
+		if !branch.Uploaded || len(branch.ReviewURLs) == 0 {
+			continue
+		}
+		fmt.Fprintf(os.Stderr,
+			"[OK    ] %-15s %-15s\n",
+			branch.Project.Path+"/",
+			branch.Branch.ShortName())
+		for _, u := range branch.ReviewURLs {
+			fmt.Fprintf(os.Stderr, "         %s\n", u)
+		}
+	}
+	if v.O.Topic != "" {
+		v.showTopicSummary(branches)
+	}
 	if haveErrors {
 		for _, branch := range branches {
 			if !branch.Uploaded && branch.Error != nil {
@@ -969,8 +1133,117 @@ func (v *uploadCommand) UploadAndReport(branches []project.ReviewableBranch) err
 	return nil
 }
 
+// preUploadHook returns pre-upload hook defined in repo-hooks of manifest,
+// and asks user to approve the hook script if it is new or changed.
+func (v *uploadCommand) preUploadHook() (*workspace.RepoHook, error) {
+	if v.O.BypassHooks {
+		return nil, nil
+	}
+	rws, ok := v.WorkSpace().(*workspace.RepoWorkSpace)
+	if !ok {
+		return nil, nil
+	}
+	hook, err := rws.GetRepoHook("pre-upload")
+	if err != nil || hook == nil {
+		// Only show it if repo-hooks is defined in manifest.
+		if err == nil && config.IsDryRun() &&
+			rws.Manifest != nil && rws.Manifest.RepoHooks != nil {
+			log.Note("pre-upload hook is not enabled")
+		}
+		return nil, err
+	}
+	// Hook is not run in dryrun mode, no need to approve.
+	if v.O.AllowAllHooks || config.IsDryRun() || hook.IsApproved() {
+		return hook, nil
+	}
+
+	fmt.Printf("Repository hook script %s is new or changed since last run.\n",
+		hook.Script)
+	input := userInput("Do you want to allow this script to run (yes/always/NO)? ", "no")
+	if strings.ToLower(input) == "always" {
+		if err = hook.Approve(); err != nil {
+			log.Warnf("fail to save approval of %s hook: %s", hook.Name, err)
+		}
+		return hook, nil
+	} else if answerIsTrue(input) {
+		return hook, nil
+	}
+	return nil, fmt.Errorf("%s hook is not approved, use --no-verify to skip it", hook.Name)
+}
+
+// runHook runs pre-upload hook for project, unless it is disabled for the
+// project. In dryrun mode, it only shows whether the hook will run.
+func (v uploadCommand) runHook(p *project.Project, commits []string) error {
+	if !v.hook.IsEnabledFor(p) {
+		if config.IsDryRun() {
+			log.Notef("%swill not run %s hook, which is disabled for this project",
+				p.Prompt(), v.hook.Name)
+		}
+		return nil
+	}
+	if config.IsDryRun() {
+		log.Notef("%swill run %s hook: %s", p.Prompt(), v.hook.Name, v.hook.Script)
+		return nil
+	}
+	return v.hook.Run(p, commits)
+}
+
+// setReviewersByAPI adds reviewers and CCs to changes of uploaded branch
+// by Gerrit REST API. Errors are only warned, for the push is done.
+func (v uploadCommand) setReviewersByAPI(branch *project.ReviewableBranch, people [][]string, noCertChecks bool) {
+	client, err := gerrit.GetClient(branch.Remote.Review, noCertChecks)
+	if err != nil {
+		log.Warnf("fail to set reviewers: %s", err)
+		return
+	}
+	for _, u := range branch.ReviewURLs {
+		change := gerrit.ParseChangeNumber(u)
+		if change == "" {
+			continue
+		}
+		for i, users := range people {
+			for _, user := range users {
+				if err = client.AddReviewer(change, user, i > 0); err != nil {
+					log.Warnf("fail to add '%s' to change %s: %s", user, change, err)
+				}
+			}
+		}
+	}
+}
+
+// showTopicSummary shows how many branches of how many projects are
+// uploaded under the same topic.
+func (v uploadCommand) showTopicSummary(branches []project.ReviewableBranch) {
+	var (
+		count    = 0
+		projects = make(map[string]bool)
+	)
+
+	for _, branch := range branches {
+		if !branch.Uploaded {
+			continue
+		}
+		count++
+		projects[branch.Project.Path] = true
+	}
+	fmt.Fprintf(os.Stderr,
+		"Topic %s: %d branch(es) of %d project(s) uploaded, %d failed\n",
+		v.O.Topic,
+		count,
+		len(projects),
+		len(branches)-count)
+}
+
 func (v uploadCommand) Execute(args []string) error {
 	ws := v.WorkSpace()
+
+	// Do not upload while sync is running.
+	if rws, ok := ws.(*workspace.RepoWorkSpace); ok {
+		if err := rws.WaitUnlocked(); err != nil {
+			return err
+		}
+	}
+
 	err := ws.LoadRemotes(v.O.NoCache)
 	if err != nil {
 		return err
@@ -979,6 +1252,12 @@ func (v uploadCommand) Execute(args []string) error {
 	if v.O.Remote != "" && !config.IsSingleMode() {
 		return fmt.Errorf("--remote can be only used with --single")
 	}
+	if v.O.WIP && v.O.Ready {
+		return newUserError("cannot combine --wip and --ready")
+	}
+	if v.O.Private && v.O.RemovePrivate {
+		return newUserError("cannot combine --private and --remove-private")
+	}
 
 	allProjects, err := ws.GetProjects(nil, args...)
 	if err != nil {
@@ -1128,6 +1407,11 @@ func (v uploadCommand) Execute(args []string) error {
 		return nil
 	}
 
+	v.hook, err = v.preUploadHook()
+	if err != nil {
+		return err
+	}
+
 	if v.O.NoEdit || editor.Editor() == "" {
 		err = v.UploadForReviewWithConfirm(tasks)
 	} else {
@@ -4,6 +4,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/alibaba/git-repo-go/project"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -125,3 +127,56 @@ true
 		},
 	)
 }
+
+func TestGetDestBranch(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		cmd    = uploadCommand{}
+	)
+
+	newBranch := func(mp manifest.Project) *project.ReviewableBranch {
+		return &project.ReviewableBranch{
+			Project: &project.Project{
+				Repository: project.Repository{Project: mp},
+			},
+		}
+	}
+
+	dest, err := cmd.getDestBranch(newBranch(manifest.Project{
+		Revision: "master",
+	}))
+	assert.Nil(err)
+	assert.Equal("master", dest)
+
+	// Pinned project of frozen manifest.
+	dest, err = cmd.getDestBranch(newBranch(manifest.Project{
+		Revision:   "2fdfd9b9ff3bb556a74363bd0dacec0d29a0cc2a",
+		Upstream:   "master",
+		DestBranch: "Maint",
+	}))
+	assert.Nil(err)
+	assert.Equal("Maint", dest)
+
+	dest, err = cmd.getDestBranch(newBranch(manifest.Project{
+		Revision: "2fdfd9b9ff3bb556a74363bd0dacec0d29a0cc2a",
+		Upstream: "master",
+	}))
+	assert.Nil(err)
+	assert.Equal("master", dest)
+
+	cmd.O.DestBranch = "dev"
+	dest, err = cmd.getDestBranch(newBranch(manifest.Project{
+		Revision: "2fdfd9b9ff3bb556a74363bd0dacec0d29a0cc2a",
+		Upstream: "master",
+	}))
+	assert.Nil(err)
+	assert.Equal("dev", dest)
+}
+
+func TestAppendOwners(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{"alice", "bob", "team"},
+		appendOwners([]string{"alice", "bob"}, []string{"bob", "team"}))
+	assert.Equal([]string{"team"}, appendOwners([]string{}, []string{"team"}))
+}
@@ -18,6 +18,8 @@ import (
 	"fmt"
 
 	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/path"
+	"github.com/alibaba/git-repo-go/project"
 	"github.com/alibaba/git-repo-go/version"
 	"github.com/spf13/cobra"
 )
@@ -35,6 +37,27 @@ var versionCmd = &cobra.Command{
 func showVersion() {
 	fmt.Printf("git-repo version %s\n", version.GetVersion())
 	fmt.Printf("git version %s\n", version.GetGitVersion())
+	showManifestVersion()
+}
+
+// showManifestVersion shows url and revision of the manifest repository,
+// if running inside a repo workspace.
+func showManifestVersion() {
+	topDir, err := path.FindTopDir("")
+	if err != nil {
+		return
+	}
+	mp := project.NewManifestProject(topDir, "")
+	if mp.Settings.ManifestURL != "" {
+		fmt.Printf("manifest url %s\n", mp.Settings.ManifestURL)
+	}
+	if commit, err := mp.ResolveRevision("HEAD"); err == nil {
+		revision := mp.Revision
+		if revision == "" {
+			revision = "HEAD"
+		}
+		fmt.Printf("manifest revision %s (%s)\n", revision, commit[:7])
+	}
 }
 
 func init() {
@@ -15,6 +15,16 @@ func IsSha(revision string) bool {
 	return false
 }
 
+// IsShortSha indecates revision is an abbreviated commit id, which has
+// at least one digit, so that a branch name like "deadbeef" is not taken
+// as a commit id.
+func IsShortSha(revision string) bool {
+	if IsSha(revision) || !config.ShortCommitIDPattern.MatchString(revision) {
+		return false
+	}
+	return strings.ContainsAny(revision, "0123456789")
+}
+
 // IsTag indecates revision is a tag.
 func IsTag(revision string) bool {
 	if strings.HasPrefix(revision, config.RefsTags) {
@@ -41,7 +51,7 @@ func IsRef(revision string) bool {
 
 // IsImmutable indecates revision is a tag or sha or change.
 func IsImmutable(revision string) bool {
-	if IsSha(revision) || IsTag(revision) {
+	if IsSha(revision) || IsShortSha(revision) || IsTag(revision) {
 		return true
 	}
 	if IsHead(revision) {
@@ -22,10 +22,11 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/path"
 	"github.com/jiangxin/goconfig"
-	log "github.com/jiangxin/multi-log"
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/viper"
 )
@@ -37,6 +38,9 @@ var (
 	// CommitIDPattern indicates raw commit ID
 	CommitIDPattern = regexp.MustCompile(`^[0-9a-f]{40}([0-9a-f]{24})?$`)
 
+	// ShortCommitIDPattern indicates abbreviated commit ID
+	ShortCommitIDPattern = regexp.MustCompile(`^[0-9a-f]{7,63}$`)
+
 	// GitDefaultConfig is git global and system config.
 	GitDefaultConfig goconfig.GitConfig
 )
@@ -49,19 +53,51 @@ const (
 	DefaultLogRotate = 20 * 1024 * 1024
 	DefaultLogLevel  = "warn"
 
-	CfgRepoArchive           = "repo.archive"
-	CfgRepoDepth             = "repo.depth"
-	CfgRepoDissociate        = "repo.dissociate"
-	CfgRepoMirror            = "repo.mirror"
-	CfgRepoReference         = "repo.reference"
-	CfgRepoSubmodules        = "repo.submodules"
-	CfgManifestGroups        = "manifest.groups"
-	CfgManifestName          = "manifest.name"
-	CfgRemoteOriginURL       = "remote.origin.url"
-	CfgBranchDefaultMerge    = "branch.default.merge"
-	CfgManifestRemoteSSHInfo = "manifest.remote.%s.sshinfo"
-	CfgManifestRemoteExpire  = "manifest.remote.%s.expire"
-	CfgAppGitRepoDisabled    = "app.git.repo.disabled"
+	// WorkspaceLogFile is JSON log file in ".repo" for post-mortem.
+	WorkspaceLogFile         = "logs/git-repo.log"
+	DefaultWorkspaceLogLevel = "warn"
+	DefaultWorkspaceLogKeep  = 5
+
+	CfgRepoArchive            = "repo.archive"
+	CfgRepoDepth              = "repo.depth"
+	CfgRepoPartialCloneFilter = "repo.partialclonefilter"
+	CfgRepoDissociate         = "repo.dissociate"
+	CfgRepoMirror             = "repo.mirror"
+	CfgRepoReference          = "repo.reference"
+	CfgRepoSubmodules         = "repo.submodules"
+	CfgRepoWorktree           = "repo.worktree"
+	CfgManifestGroups         = "manifest.groups"
+	CfgManifestName           = "manifest.name"
+	CfgRemoteOriginURL        = "remote.origin.url"
+	CfgBranchDefaultMerge     = "branch.default.merge"
+	CfgManifestRemoteSSHInfo  = "manifest.remote.%s.sshinfo"
+	CfgManifestRemoteExpire   = "manifest.remote.%s.expire"
+	CfgAppGitRepoDisabled     = "app.git.repo.disabled"
+	CfgRepoAuthMethod         = "repo.auth.%s.method"
+	CfgRepoAuthToken          = "repo.auth.%s.token"
+	CfgRepoBundleURI          = "repo.bundleuri"
+	CfgRepoBandwidthLimit     = "repo.bandwidthlimit"
+	CfgRepoHostBandwidthLimit = "repo.bandwidth.%s.limit"
+	CfgRepoFetchWindow        = "repo.fetchwindow"
+	CfgRepoURLFetchProto      = "repo.url.%s.fetchproto"
+	CfgRepoURLPushProto       = "repo.url.%s.pushproto"
+	CfgRepoURLSSHUser         = "repo.url.%s.sshuser"
+	CfgRepoURLSSHPort         = "repo.url.%s.sshport"
+	CfgRepoRetryMaxAttempts   = "repo.retry.maxattempts"
+	CfgRepoRetryDelay         = "repo.retry.delay"
+	CfgRepoRetryMaxDelay      = "repo.retry.maxdelay"
+	CfgRepoMaxConnections     = "repo.maxconnectionsperhost"
+	CfgRepoNativeRead         = "repo.nativeread"
+	CfgRepoManifestCache      = "repo.manifestcache"
+	CfgRepoManifestStrict     = "repo.manifeststrict"
+	CfgRepoMaxIncludeDepth    = "repo.maxincludedepth"
+	CfgRepoLocalDuplicate     = "repo.localmanifestduplicate"
+	CfgRepoJobs               = "repo.jobs"
+	CfgRepoGC                 = "repo.gc"
+	CfgRepoCommitTemplate     = "repo.committemplate"
+	CfgRepoManifestVerify     = "repo.manifestverify"
+	CfgRepoManifestKeys       = "repo.manifestkeys"
+	CfgRepoManifestSigners    = "repo.manifestsigners"
 
 	ManifestsDotGit  = "manifests.git"
 	Manifests        = "manifests"
@@ -69,7 +105,11 @@ const (
 	ManifestXML      = "manifest.xml"
 	LocalManifestXML = "local_manifest.xml"
 	LocalManifests   = "local_manifests"
+	ManifestCache    = "manifest.cache"
+	RepoLock         = "repo.lock"
+	Locks            = "locks"
 	ProjectObjects   = "project-objects"
+	SettingsFile     = "config.json"
 	Projects         = "projects"
 
 	RefsHeads   = "refs/heads/"
@@ -137,6 +177,27 @@ func GetLogLevel() string {
 	return viper.GetString("loglevel")
 }
 
+// GetLogModules gets --log-modules option, which overrides log level of
+// modules, such as "project/network-half=debug".
+func GetLogModules() string {
+	return viper.GetString("logmodules")
+}
+
+// GetWorkspaceLog checks whether to save JSON log in workspace.
+func GetWorkspaceLog() bool {
+	return viper.GetBool("workspacelog")
+}
+
+// GetWorkspaceLogLevel gets log level of JSON log in workspace.
+func GetWorkspaceLogLevel() string {
+	return viper.GetString("workspaceloglevel")
+}
+
+// GetWorkspaceLogKeep gets number of rotated JSON logs to keep.
+func GetWorkspaceLogKeep() int {
+	return viper.GetInt("workspacelogkeep")
+}
+
 // GetLogRotateSize gets logrotate size from config.
 func GetLogRotateSize() int64 {
 	logrotate := strings.ToLower(viper.GetString("logrotate"))
@@ -170,6 +231,30 @@ func GetLogRotateSize() int64 {
 	return size
 }
 
+// GetSSHInfoCacheExpire gets lifetime of cached ssh_info from config,
+// such as "30m" or "3600" (in seconds). Returns 0 if not set or invalid.
+func GetSSHInfoCacheExpire() time.Duration {
+	value := strings.TrimSpace(viper.GetString("sshinfoexpire"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	expire, err := time.ParseDuration(value)
+	if err != nil || expire < 0 {
+		log.Warnf("bad sshinfoexpire value: %s", value)
+		return 0
+	}
+	return expire
+}
+
+// GetReleaseChannel gets release channel to upgrade from, such as
+// "stable" or "test".
+func GetReleaseChannel() string {
+	return strings.ToLower(strings.TrimSpace(viper.GetString("releasechannel")))
+}
+
 // NoCertChecks indicates whether ignore ssl cert.
 func NoCertChecks() bool {
 	return !GitDefaultConfig.GetBool("http.sslverify", true)
@@ -205,9 +290,34 @@ func IsDryRun() bool {
 	return viper.GetBool("dryrun")
 }
 
+// WaitLock gets --wait option.
+func WaitLock() bool {
+	return viper.GetBool("wait")
+}
+
+// EffectiveJobs returns number of jobs to run simultaneously. Settings
+// are given from low to high priority, such as sync-j of manifest,
+// config of workspace and --jobs option, and settings not set (<= 0)
+// are skipped. Returns fallback if none of settings is set.
+func EffectiveJobs(fallback int, settings ...int) int {
+	jobs := fallback
+	for _, n := range settings {
+		if n > 0 {
+			jobs = n
+		}
+	}
+	if jobs < 1 {
+		jobs = 1
+	}
+	return jobs
+}
+
 func init() {
 	viper.SetDefault("logrotate", DefaultLogRotate)
 	viper.SetDefault("loglevel", DefaultLogLevel)
+	viper.SetDefault("workspacelog", true)
+	viper.SetDefault("workspaceloglevel", DefaultWorkspaceLogLevel)
+	viper.SetDefault("workspacelogkeep", DefaultWorkspaceLogKeep)
 
 	viper.SetEnvPrefix(ViperEnvPrefix)
 	viper.AutomaticEnv()
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -31,3 +32,46 @@ func TestViperEnv(t *testing.T) {
 	os.Setenv(key, "false")
 	assert.False(IsSingleMode())
 }
+
+func TestGetSSHInfoCacheExpire(t *testing.T) {
+	assert := assert.New(t)
+
+	key := fmt.Sprintf("%s_%s", ViperEnvPrefix, "SSHINFOEXPIRE")
+	defer os.Unsetenv(key)
+
+	os.Unsetenv(key)
+	assert.Equal(time.Duration(0), GetSSHInfoCacheExpire())
+	os.Setenv(key, "600")
+	assert.Equal(10*time.Minute, GetSSHInfoCacheExpire())
+	os.Setenv(key, "2h")
+	assert.Equal(2*time.Hour, GetSSHInfoCacheExpire())
+	os.Setenv(key, "bad")
+	assert.Equal(time.Duration(0), GetSSHInfoCacheExpire())
+}
+
+func TestGetReleaseChannel(t *testing.T) {
+	assert := assert.New(t)
+
+	key := fmt.Sprintf("%s_%s", ViperEnvPrefix, "RELEASECHANNEL")
+	defer os.Unsetenv(key)
+
+	os.Unsetenv(key)
+	assert.Equal("", GetReleaseChannel())
+	os.Setenv(key, " Test ")
+	assert.Equal("test", GetReleaseChannel())
+}
+
+func TestEffectiveJobs(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(4, EffectiveJobs(4))
+	assert.Equal(4, EffectiveJobs(4, 0, 0, 0))
+	assert.Equal(1, EffectiveJobs(0, 0, -1))
+	// sync-j of manifest
+	assert.Equal(8, EffectiveJobs(4, 8, 0, 0))
+	// config of workspace overrides manifest
+	assert.Equal(2, EffectiveJobs(4, 8, 2, 0))
+	// --jobs option overrides all
+	assert.Equal(16, EffectiveJobs(4, 8, 2, 16))
+	assert.Equal(16, EffectiveJobs(4, 0, 0, 16))
+}
@@ -7,9 +7,9 @@ import (
 	"path/filepath"
 
 	"github.com/alibaba/git-repo-go/file"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/path"
 	"github.com/jiangxin/goconfig"
-	log "github.com/jiangxin/multi-log"
 )
 
 const (
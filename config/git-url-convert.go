@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const persistentPrefix = "persistent-"
+
+// ConfigGetter reads value of git config.
+type ConfigGetter interface {
+	Get(key string) string
+}
+
+// ConvertProto returns a copy of GitURL which uses protocol proto, such as
+// "ssh", "https" or "persistent-https". User and port are only used for
+// SSH protocol. Returns nil if proto is not supported.
+func (v GitURL) ConvertProto(proto, user string, port int) *GitURL {
+	u := v
+	switch proto {
+	case "ssh":
+		if user == "" {
+			user = "git"
+		}
+		u.User = user
+		u.Port = port
+	case "http", "https", "persistent-http", "persistent-https":
+		u.User = ""
+		u.Port = 0
+	default:
+		return nil
+	}
+	u.Proto = proto
+	return &u
+}
+
+// ConvertGitURL converts protocol of address by git config, so that users
+// behind firewall can fetch over HTTPS but push over SSH. Key is
+// CfgRepoURLFetchProto or CfgRepoURLPushProto, and host of address is
+// used as subsection, such as "repo.url.example.com.pushProto". User and
+// port of SSH URL are read from "repo.url.<host>.sshUser" and
+// "repo.url.<host>.sshPort". Settings are read from cfg first, then
+// from git global config.
+func ConvertGitURL(cfg ConfigGetter, address, key string) string {
+	get := func(key string) string {
+		if cfg != nil {
+			if value := cfg.Get(key); value != "" {
+				return value
+			}
+		}
+		if GitDefaultConfig != nil {
+			return GitDefaultConfig.Get(key)
+		}
+		return ""
+	}
+
+	persistent := strings.HasPrefix(address, persistentPrefix)
+	u := ParseGitURL(strings.TrimPrefix(address, persistentPrefix))
+	if u == nil || u.Host == "" || !(u.IsSSH() || u.IsHTTP()) {
+		return address
+	}
+	if persistent {
+		u.Proto = persistentPrefix + u.Proto
+	}
+
+	proto := strings.ToLower(get(fmt.Sprintf(key, u.Host)))
+	if proto == "" || proto == u.Proto {
+		return address
+	}
+	port, _ := strconv.Atoi(get(fmt.Sprintf(CfgRepoURLSSHPort, u.Host)))
+	newURL := u.ConvertProto(proto, get(fmt.Sprintf(CfgRepoURLSSHUser, u.Host)), port)
+	if newURL == nil {
+		return address
+	}
+	result := newURL.String()
+	if strings.HasSuffix(strings.TrimSuffix(address, "/"), ".git") {
+		result += ".git"
+	}
+	return result
+}
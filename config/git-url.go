@@ -79,7 +79,7 @@ func (v GitURL) String() string {
 	var buf = bytes.NewBuffer([]byte{})
 
 	switch v.Proto {
-	case "http", "https", "ssh", "git":
+	case "http", "https", "ssh", "git", "persistent-http", "persistent-https":
 		buf.WriteString(v.Proto + "://")
 		if v.User != "" {
 			buf.WriteString(v.User + "@")
@@ -3,6 +3,7 @@ package config
 import (
 	"testing"
 
+	"github.com/jiangxin/goconfig"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -207,3 +208,37 @@ func TestGitURLWithoutRepo(t *testing.T) {
 	u = ParseGitURL("user@example.com")
 	assert.Nil(u)
 }
+
+func TestConvertGitURL(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := goconfig.NewGitConfig()
+	cfg.Set("repo.url.example.com.pushProto", "ssh")
+	cfg.Set("repo.url.example.com.sshPort", "29418")
+	cfg.Set("repo.url.example.com.fetchProto", "persistent-https")
+	cfg.Set("repo.url.ssh.example.com.fetchProto", "https")
+	cfg.Set("repo.url.bad.example.com.fetchProto", "ftp")
+
+	assert.Equal("ssh://git@example.com:29418/my/repo.git",
+		ConvertGitURL(cfg, "https://example.com/my/repo.git", CfgRepoURLPushProto))
+	assert.Equal("ssh://git@example.com:29418/my/repo.git",
+		ConvertGitURL(cfg, "persistent-https://example.com/my/repo.git", CfgRepoURLPushProto))
+	assert.Equal("persistent-https://example.com/my/repo.git",
+		ConvertGitURL(cfg, "https://example.com/my/repo.git", CfgRepoURLFetchProto))
+	assert.Equal("persistent-https://example.com/my/repo.git",
+		ConvertGitURL(cfg, "persistent-https://example.com/my/repo.git", CfgRepoURLFetchProto))
+	assert.Equal("https://ssh.example.com/my/repo",
+		ConvertGitURL(cfg, "ssh://jiangxin@ssh.example.com:2222/my/repo", CfgRepoURLFetchProto))
+	assert.Equal("https://ssh.example.com/my/repo.git",
+		ConvertGitURL(cfg, "jiangxin@ssh.example.com:my/repo.git", CfgRepoURLFetchProto))
+
+	// Not converted.
+	assert.Equal("https://bad.example.com/my/repo.git",
+		ConvertGitURL(cfg, "https://bad.example.com/my/repo.git", CfgRepoURLFetchProto))
+	assert.Equal("https://other.example.com/my/repo.git",
+		ConvertGitURL(cfg, "https://other.example.com/my/repo.git", CfgRepoURLPushProto))
+	assert.Equal("/path/of/my/repo.git",
+		ConvertGitURL(cfg, "/path/of/my/repo.git", CfgRepoURLPushProto))
+	assert.Equal("https://example.com/my/repo.git",
+		ConvertGitURL(nil, "https://example.com/my/repo.git", "repo.url.%s.unknown"))
+}
@@ -13,7 +13,7 @@ import (
 const (
 	// gitHooksVersion is version of hooks, and should be promoted if
 	// anything changed in hooks
-	gitHooksVersion = "1"
+	gitHooksVersion = "2"
 
 	// gerritCommitMsgHook is content of commit-msg hook for Gerrit
 	gerritCommitMsgHook = `#!/bin/sh
@@ -83,7 +83,52 @@ fi
 `
 )
 
+const (
+	// preAutoGcHook is content of pre-auto-gc hook, which prevents
+	// "git gc --auto" from running when on battery power.
+	preAutoGcHook = `#!/bin/sh
+#
+# An example hook script to verify if you are on battery, in case you
+# are running Linux or OS X. Called by git-gc --auto with no arguments.
+# The hook should exit with non-zero status after issuing an appropriate
+# message if it wants to stop the auto repacking.
+
+if test -x /sbin/on_ac_power && (/sbin/on_ac_power;test $? -ne 1)
+then
+	exit 0
+elif cat /sys/class/power_supply/*/online 2>/dev/null | grep -q 1
+then
+	exit 0
+elif grep -q 'on-line' /proc/acpi/ac_adapter/AC/state 2>/dev/null
+then
+	exit 0
+elif grep -q '0x01$' /proc/apm 2>/dev/null
+then
+	exit 0
+elif test -x /usr/bin/pmset && /usr/bin/pmset -g batt |
+	grep -q "drawing from 'AC Power'"
+then
+	exit 0
+elif test -d /sys/class/power_supply && ! ls /sys/class/power_supply/ 2>/dev/null | grep -q .
+then
+	# No power supply at all, such as a desktop or a server.
+	exit 0
+elif test ! -d /sys/class/power_supply && test ! -x /usr/bin/pmset
+then
+	exit 0
+fi
+
+echo "Auto packing deferred; not on AC"
+exit 1
+`
+)
+
 var (
+	// DefaultHooks defines map of hooks installed for all projects.
+	DefaultHooks = map[string]string{
+		"pre-auto-gc": preAutoGcHook,
+	}
+
 	// GerritHooks defines map of Gerrit hooks.
 	GerritHooks = map[string]string{
 		"commit-msg": gerritCommitMsgHook,
@@ -134,13 +179,16 @@ func InstallRepoHooks() error {
 			return fmt.Errorf("fail to install hooks: %s", err)
 		}
 	}
-	for name, data := range GerritHooks {
-		file := filepath.Join(hooksDir, name)
-		finfo, err := os.Stat(file)
-		if err != nil || int(finfo.Size()) != len(data) {
-			err = ioutil.WriteFile(file, []byte(data), 0755)
-			if err != nil {
-				return fmt.Errorf("fail to write hooks: %s", err)
+	for _, hooks := range []map[string]string{DefaultHooks, GerritHooks} {
+		for name, data := range hooks {
+			file := filepath.Join(hooksDir, name)
+			// Update hook if template is changed.
+			old, err := ioutil.ReadFile(file)
+			if err != nil || string(old) != data {
+				err = ioutil.WriteFile(file, []byte(data), 0755)
+				if err != nil {
+					return fmt.Errorf("fail to write hooks: %s", err)
+				}
 			}
 		}
 	}
@@ -5,7 +5,7 @@ import (
 	"path/filepath"
 
 	"github.com/alibaba/git-repo-go/file"
-	log "github.com/jiangxin/multi-log"
+	"github.com/alibaba/git-repo-go/log"
 )
 
 const (
@@ -24,9 +24,9 @@ import (
 	"strings"
 
 	"github.com/alibaba/git-repo-go/cap"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/path"
 	"github.com/jiangxin/goconfig"
-	log "github.com/jiangxin/multi-log"
 	"github.com/mattn/go-shellwords"
 )
 
@@ -39,3 +39,14 @@ func ProjectNoExistError(name string) error {
 func ProjectNotBelongToGroupsError(name, groups string) error {
 	return fmt.Errorf("project '%s' not belong to groups '%s'", name, groups)
 }
+
+// AmbiguousProjectError indicates name and path of arg match different projects.
+func AmbiguousProjectError(arg, byName, byPath string) error {
+	return fmt.Errorf("'%s' is ambiguous, which matches name of project '%s' and path of project '%s'",
+		arg, byName, byPath)
+}
+
+// NoMatchingProjectError indicates no project matches the pattern.
+func NoMatchingProjectError(pattern string) error {
+	return fmt.Errorf("no project matches pattern '%s'", pattern)
+}
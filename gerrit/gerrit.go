@@ -0,0 +1,206 @@
+// Package gerrit implements a client of Gerrit REST API, which is used to
+// set reviewers of changes and query status of changes.
+package gerrit
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
+)
+
+var (
+	reChangeNumber = regexp.MustCompile(`/(?:c/(?:.+/\+/)?|#/c/)?([0-9]+)/?$`)
+
+	// clients saves client of each review URL, so that credential and
+	// connections are shared by all projects of the same Gerrit server.
+	clients     = make(map[string]*Client)
+	clientMutex sync.Mutex
+)
+
+// AccountInfo is account of a Gerrit user.
+type AccountInfo struct {
+	AccountID int    `json:"_account_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Username  string `json:"username,omitempty"`
+}
+
+// LabelInfo is status of a label of change, such as "Code-Review".
+type LabelInfo struct {
+	Approved    *AccountInfo `json:"approved,omitempty"`
+	Rejected    *AccountInfo `json:"rejected,omitempty"`
+	Recommended *AccountInfo `json:"recommended,omitempty"`
+	Disliked    *AccountInfo `json:"disliked,omitempty"`
+	Blocking    bool         `json:"blocking,omitempty"`
+}
+
+// Status returns status of the label, or empty string if nobody voted.
+func (v LabelInfo) Status() string {
+	switch {
+	case v.Rejected != nil:
+		return "rejected"
+	case v.Approved != nil:
+		return "approved"
+	case v.Disliked != nil:
+		return "disliked"
+	case v.Recommended != nil:
+		return "recommended"
+	}
+	return ""
+}
+
+// ChangeInfo is a change returned by Gerrit.
+type ChangeInfo struct {
+	ID        string               `json:"id"`
+	Project   string               `json:"project"`
+	Branch    string               `json:"branch"`
+	Topic     string               `json:"topic,omitempty"`
+	ChangeID  string               `json:"change_id"`
+	Subject   string               `json:"subject"`
+	Status    string               `json:"status"`
+	Number    int                  `json:"_number"`
+	Owner     AccountInfo          `json:"owner"`
+	Mergeable bool                 `json:"mergeable,omitempty"`
+	WIP       bool                 `json:"work_in_progress,omitempty"`
+	Labels    map[string]LabelInfo `json:"labels,omitempty"`
+}
+
+// LabelSummary returns labels which are voted, such as
+// "Code-Review=approved Verified=rejected".
+func (v ChangeInfo) LabelSummary() string {
+	names := []string{}
+	for name := range v.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	items := []string{}
+	for _, name := range names {
+		if status := v.Labels[name].Status(); status != "" {
+			items = append(items, name+"="+status)
+		}
+	}
+	return strings.Join(items, " ")
+}
+
+// ReviewerInput is used to add a reviewer or CC to a change.
+type ReviewerInput struct {
+	Reviewer string `json:"reviewer"`
+	State    string `json:"state,omitempty"`
+}
+
+// Client accesses REST API of a Gerrit server.
+type Client struct {
+	URL string
+
+	httpClient *http.Client
+	auth       *helper.HTTPAuth
+}
+
+// GetClient returns client for Gerrit server of review URL, which is
+// created only once for each server. Credential of the server is read in
+// the same way as HTTP remotes, and authenticated API is used if
+// credential is available.
+func GetClient(reviewURL string, noCertChecks bool) (*Client, error) {
+	key := fmt.Sprintf("%s %v", strings.TrimSuffix(reviewURL, "/"), noCertChecks)
+	clientMutex.Lock()
+	defer clientMutex.Unlock()
+	if client, ok := clients[key]; ok {
+		return client, nil
+	}
+	client, err := newClient(reviewURL, noCertChecks)
+	if err != nil {
+		return nil, err
+	}
+	clients[key] = client
+	return client, nil
+}
+
+// newClient creates client for Gerrit server of review URL.
+func newClient(reviewURL string, noCertChecks bool) (*Client, error) {
+	u := config.ParseGitURL(reviewURL)
+	if u == nil || !u.IsHTTP() {
+		return nil, fmt.Errorf("review URL '%s' is not a HTTP URL", reviewURL)
+	}
+	auth, err := helper.GetHTTPAuth(reviewURL)
+	if err != nil {
+		log.Warnf("fail to get credential for '%s': %s", reviewURL, err)
+	}
+	return &Client{
+		URL: strings.TrimSuffix(reviewURL, "/"),
+
+		httpClient: helper.NewHTTPClient(reviewURL, &helper.HTTPClientOptions{
+			NoCertChecks: noCertChecks,
+		}),
+		auth: auth,
+	}, nil
+}
+
+// apiURL returns URL of API endpoint.
+func (v Client) apiURL(endpoint string) string {
+	if v.auth != nil {
+		return v.URL + "/a" + endpoint
+	}
+	return v.URL + endpoint
+}
+
+// call sends request to endpoint, and decodes JSON response into result.
+func (v Client) call(method, endpoint string, input, result interface{}) error {
+	return helper.CallJSONAPI(v.httpClient, v.auth, method, v.apiURL(endpoint), input, result)
+}
+
+// AddReviewer adds reviewer, or CC if cc is true, to change.
+func (v Client) AddReviewer(change, reviewer string, cc bool) error {
+	input := ReviewerInput{Reviewer: reviewer}
+	if cc {
+		input.State = "CC"
+	}
+	return v.call(http.MethodPost,
+		"/changes/"+url.PathEscape(change)+"/reviewers",
+		&input,
+		nil)
+}
+
+// QueryChanges returns changes matched with query, with labels.
+func (v Client) QueryChanges(query string) ([]ChangeInfo, error) {
+	var changes []ChangeInfo
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Add("o", "LABELS")
+	err := v.call(http.MethodGet, "/changes/?"+params.Encode(), nil, &changes)
+	return changes, err
+}
+
+// OpenChangesQuery returns query of open changes of project, which have
+// patch sets of commits.
+func OpenChangesQuery(project string, commits []string) string {
+	query := fmt.Sprintf("status:open project:%s", project)
+	items := []string{}
+	for _, commit := range commits {
+		items = append(items, "commit:"+commit)
+	}
+	if len(items) > 0 {
+		query += " (" + strings.Join(items, " OR ") + ")"
+	}
+	return query
+}
+
+// ParseChangeNumber returns number of change from URL of code review,
+// such as "https://example.com/c/project/+/123". Returns empty string if
+// not found.
+func ParseChangeNumber(reviewURL string) string {
+	m := reChangeNumber.FindStringSubmatch(reviewURL)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
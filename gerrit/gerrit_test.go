@@ -0,0 +1,92 @@
+package gerrit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseChangeNumber(t *testing.T) {
+	var (
+		assert = assert.New(t)
+	)
+
+	assert.Equal("123", ParseChangeNumber("https://example.com/c/project/app/+/123"))
+	assert.Equal("123", ParseChangeNumber("https://example.com/c/123/"))
+	assert.Equal("45", ParseChangeNumber("https://example.com/#/c/45"))
+	assert.Equal("", ParseChangeNumber("https://example.com/c/project/app/+/abc"))
+}
+
+func TestOpenChangesQuery(t *testing.T) {
+	var (
+		assert = assert.New(t)
+	)
+
+	assert.Equal("status:open project:app",
+		OpenChangesQuery("app", nil))
+	assert.Equal("status:open project:app (commit:1234 OR commit:5678)",
+		OpenChangesQuery("app", []string{"1234", "5678"}))
+}
+
+func TestClient(t *testing.T) {
+	var (
+		assert   = assert.New(t)
+		reviewer ReviewerInput
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/changes/123/reviewers":
+			data, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(data, &reviewer)
+			w.Write([]byte(")]}'\n{}"))
+		case r.Method == http.MethodGet && r.URL.Path == "/changes/":
+			assert.Equal("status:open project:app (commit:1234)", r.URL.Query().Get("q"))
+			w.Write([]byte(`)]}'
+[{"id": "app~master~I01", "project": "app", "branch": "master",
+  "change_id": "I01", "subject": "hack", "status": "NEW", "_number": 123,
+  "labels": {"Verified": {"rejected": {"_account_id": 1}},
+             "Code-Review": {"approved": {"_account_id": 2}}}}]`))
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{URL: server.URL, httpClient: server.Client()}
+
+	assert.Nil(client.AddReviewer("123", "jiangxin", true))
+	assert.Equal(ReviewerInput{Reviewer: "jiangxin", State: "CC"}, reviewer)
+
+	changes, err := client.QueryChanges(OpenChangesQuery("app", []string{"1234"}))
+	assert.Nil(err)
+	if assert.Equal(1, len(changes)) {
+		assert.Equal(123, changes[0].Number)
+		assert.Equal("NEW", changes[0].Status)
+		assert.Equal("Code-Review=approved Verified=rejected", changes[0].LabelSummary())
+	}
+}
+
+func TestGetClient(t *testing.T) {
+	var (
+		assert = assert.New(t)
+	)
+
+	client1, err := GetClient("https://example.com/", true)
+	assert.Nil(err)
+	client2, err := GetClient("https://example.com", true)
+	assert.Nil(err)
+	assert.True(client1 == client2)
+	assert.Equal("https://example.com", client1.URL)
+
+	client3, err := GetClient("https://example.org", true)
+	assert.Nil(err)
+	assert.False(client1 == client3)
+
+	_, err = GetClient("ssh://example.com", true)
+	assert.NotNil(err)
+}
@@ -0,0 +1,187 @@
+package helper
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+)
+
+const (
+	// bandwidthChunk is max size of a read, so that waits are smooth.
+	bandwidthChunk = 16 * 1024
+)
+
+var (
+	bandwidthOnce    sync.Once
+	bandwidthGlobal  *RateLimiter
+	bandwidthHosts   = make(map[string]*RateLimiter)
+	bandwidthMutex   sync.Mutex
+	bandwidthLimited bool
+)
+
+// ParseBandwidth parses bandwidth in bytes per second, such as "512k",
+// "10M" or "1g". Zero means no limit.
+func ParseBandwidth(value string) (int64, error) {
+	var unit int64 = 1
+
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return 0, nil
+	}
+	switch value[len(value)-1] {
+	case 'k':
+		unit = 1024
+	case 'm':
+		unit = 1024 * 1024
+	case 'g':
+		unit = 1024 * 1024 * 1024
+	}
+	if unit > 1 {
+		value = value[:len(value)-1]
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("bad bandwidth '%s'", value)
+	}
+	return n * unit, nil
+}
+
+// RateLimiter limits bandwidth shared by readers by token bucket, which
+// allows a burst of one second.
+type RateLimiter struct {
+	rate   int64
+	tokens float64
+	last   time.Time
+	mutex  sync.Mutex
+}
+
+// NewRateLimiter creates RateLimiter of rate bytes per second, and
+// returns nil if rate <= 0, which means no limit.
+func NewRateLimiter(rate int64) *RateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &RateLimiter{
+		rate:   rate,
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until n bytes are allowed to transfer.
+func (v *RateLimiter) Wait(n int) {
+	if v == nil || n <= 0 {
+		return
+	}
+
+	v.mutex.Lock()
+	now := time.Now()
+	v.tokens += now.Sub(v.last).Seconds() * float64(v.rate)
+	if v.tokens > float64(v.rate) {
+		v.tokens = float64(v.rate)
+	}
+	v.last = now
+	// Tokens may be negative, and later readers wait for the debt.
+	v.tokens -= float64(n)
+	wait := time.Duration(0)
+	if v.tokens < 0 {
+		wait = time.Duration(-v.tokens / float64(v.rate) * float64(time.Second))
+	}
+	v.mutex.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+type rateLimitedReader struct {
+	r        io.Reader
+	limiters []*RateLimiter
+}
+
+func (v rateLimitedReader) Read(p []byte) (int, error) {
+	if len(p) > bandwidthChunk {
+		p = p[:bandwidthChunk]
+	}
+	n, err := v.r.Read(p)
+	for _, limiter := range v.limiters {
+		limiter.Wait(n)
+	}
+	return n, err
+}
+
+// NewRateLimitedReader returns reader which is limited by limiters. Nil
+// limiters are ignored.
+func NewRateLimitedReader(r io.Reader, limiters ...*RateLimiter) io.Reader {
+	active := []*RateLimiter{}
+	for _, limiter := range limiters {
+		if limiter != nil {
+			active = append(active, limiter)
+		}
+	}
+	if len(active) == 0 {
+		return r
+	}
+	return rateLimitedReader{r: r, limiters: active}
+}
+
+func loadBandwidthLimits() {
+	bandwidthOnce.Do(func() {
+		cfg := config.GitDefaultConfig
+		rate, err := ParseBandwidth(cfg.Get(config.CfgRepoBandwidthLimit))
+		if err != nil {
+			log.Warnf("ignore %s: %s", config.CfgRepoBandwidthLimit, err)
+		}
+		bandwidthGlobal = NewRateLimiter(rate)
+		bandwidthLimited = bandwidthGlobal != nil
+		for _, section := range cfg.Sections() {
+			if strings.HasPrefix(section, "repo.bandwidth.") {
+				bandwidthLimited = true
+			}
+		}
+	})
+}
+
+// BandwidthLimited indicates bandwidth limit is set by git config
+// "repo.bandwidthLimit" or "repo.bandwidth.<host>.limit".
+func BandwidthLimited() bool {
+	loadBandwidthLimits()
+	return bandwidthLimited
+}
+
+// BandwidthLimiters returns limiters for address, which are the global
+// one and the one of the host. Limiters are shared by all transfers.
+func BandwidthLimiters(address string) []*RateLimiter {
+	loadBandwidthLimits()
+	limiters := []*RateLimiter{}
+	if bandwidthGlobal != nil {
+		limiters = append(limiters, bandwidthGlobal)
+	}
+
+	u := config.ParseGitURL(address)
+	if u == nil || u.Host == "" {
+		return limiters
+	}
+	bandwidthMutex.Lock()
+	defer bandwidthMutex.Unlock()
+	limiter, ok := bandwidthHosts[u.Host]
+	if !ok {
+		key := fmt.Sprintf(config.CfgRepoHostBandwidthLimit, u.Host)
+		rate, err := ParseBandwidth(config.GitDefaultConfig.Get(key))
+		if err != nil {
+			log.Warnf("ignore %s: %s", key, err)
+		}
+		limiter = NewRateLimiter(rate)
+		bandwidthHosts[u.Host] = limiter
+	}
+	if limiter != nil {
+		limiters = append(limiters, limiter)
+	}
+	return limiters
+}
@@ -0,0 +1,85 @@
+package helper
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBandwidth(t *testing.T) {
+	assert := assert.New(t)
+
+	for value, expect := range map[string]int64{
+		"":     0,
+		"0":    0,
+		"100":  100,
+		"512k": 512 * 1024,
+		"10M":  10 * 1024 * 1024,
+		" 1g ": 1024 * 1024 * 1024,
+	} {
+		n, err := ParseBandwidth(value)
+		assert.Nil(err, "value: %s", value)
+		assert.Equal(expect, n, "value: %s", value)
+	}
+	for _, value := range []string{"k", "-1", "10x", "1.5m"} {
+		_, err := ParseBandwidth(value)
+		assert.NotNil(err, "value: %s", value)
+	}
+}
+
+func TestRateLimitedReader(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(NewRateLimiter(0))
+
+	data := bytes.Repeat([]byte("x"), 3000)
+	r := NewRateLimitedReader(bytes.NewReader(data), nil)
+	_, ok := r.(*bytes.Reader)
+	assert.True(ok)
+
+	// Burst of the first second is free, and the rest takes 0.5 second.
+	limiter := NewRateLimiter(2000)
+	start := time.Now()
+	buf, err := ioutil.ReadAll(NewRateLimitedReader(bytes.NewReader(data), limiter))
+	assert.Nil(err)
+	assert.Equal(data, buf)
+	elapsed := time.Since(start)
+	assert.True(elapsed >= 400*time.Millisecond, "elapsed: %s", elapsed)
+	assert.True(elapsed < 2*time.Second, "elapsed: %s", elapsed)
+}
+
+func TestThrottleProxy(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "yes")
+		w.Write([]byte("hello " + r.URL.Path))
+	}))
+	defer server.Close()
+
+	proxy, err := NewThrottleProxy()
+	if !assert.Nil(err) {
+		return
+	}
+	defer proxy.Close()
+	assert.True(strings.HasPrefix(proxy.URL(), "http://127.0.0.1:"))
+
+	proxyURL, _ := url.Parse(proxy.URL())
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	resp, err := client.Get(server.URL + "/info/refs")
+	if !assert.Nil(err) {
+		return
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(err)
+	assert.Equal("hello /info/refs", string(data))
+	assert.Equal("yes", resp.Header.Get("X-Test"))
+}
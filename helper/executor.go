@@ -0,0 +1,218 @@
+package helper
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+)
+
+var (
+	executor      Executor = ExecExecutor{}
+	executorMutex sync.RWMutex
+
+	// Git commands which do not change repository, and are executed
+	// even in dryrun mode.
+	readOnlyGitCommands = map[string]bool{
+		"archive":       true,
+		"blame":         true,
+		"cat-file":      true,
+		"check-ignore":  true,
+		"describe":      true,
+		"diff":          true,
+		"diff-files":    true,
+		"diff-index":    true,
+		"diff-tree":     true,
+		"for-each-ref":  true,
+		"fsck":          true,
+		"log":           true,
+		"ls-files":      true,
+		"ls-remote":     true,
+		"ls-tree":       true,
+		"merge-base":    true,
+		"rev-list":      true,
+		"rev-parse":     true,
+		"show":          true,
+		"show-ref":      true,
+		"status":        true,
+		"var":           true,
+		"verify-commit": true,
+		"version":       true,
+	}
+)
+
+// Command defines a command to run by Executor.
+type Command struct {
+	Args []string
+	Dir  string
+	// Env is extra environments in the form "key=value".
+	Env []string
+	// If ClearEnv is true, environments of git-repo are not inherited,
+	// and the command only sees Env.
+	ClearEnv bool
+	Stdin    io.Reader
+	// If Stdout is nil, output is captured and returned by Executor.
+	// If Stderr is also nil, error output is saved in *exec.ExitError.
+	Stdout io.Writer
+	Stderr io.Writer
+	// Command is killed if it runs longer than Timeout.
+	Timeout time.Duration
+}
+
+// String returns command line.
+func (v Command) String() string {
+	return strings.Join(v.Args, " ")
+}
+
+// Executor runs commands, and returns captured output.
+type Executor interface {
+	Run(c *Command) ([]byte, error)
+}
+
+// ExecExecutor runs commands in child processes.
+type ExecExecutor struct{}
+
+// Run implements Executor interface.
+func (v ExecExecutor) Run(c *Command) ([]byte, error) {
+	var (
+		ctx    = context.Background()
+		cancel context.CancelFunc
+		out    []byte
+		err    error
+	)
+
+	if c.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, c.Args[0], c.Args[1:]...)
+	cmd.Dir = c.Dir
+	if c.ClearEnv {
+		cmd.Env = append([]string{}, c.Env...)
+	} else if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	cmd.Stdin = c.Stdin
+	cmd.Stderr = c.Stderr
+
+	log.Tracef("execute command: %s", c)
+	done := Trace2Child(cmd)
+	if c.Stdout == nil {
+		out, err = cmd.Output()
+	} else {
+		cmd.Stdout = c.Stdout
+		err = cmd.Run()
+	}
+	done(err)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		log.Warnf("command timeout after %s: %s", c.Timeout, c)
+	}
+	return out, err
+}
+
+// DryRunExecutor only runs read-only git commands, and prints others.
+type DryRunExecutor struct {
+	Executor Executor
+}
+
+// IsReadOnlyCommand indicates whether command does not change anything.
+func IsReadOnlyCommand(args []string) bool {
+	if len(args) < 2 || args[0] != config.GIT {
+		return false
+	}
+	for i := 1; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "-c", "-C", "--git-dir", "--work-tree":
+			i++
+		default:
+			if strings.HasPrefix(arg, "-") {
+				continue
+			}
+			// "git credential fill" only reads credential.
+			if arg == "credential" {
+				return i+1 < len(args) && args[i+1] == "fill"
+			}
+			return readOnlyGitCommands[arg]
+		}
+	}
+	return false
+}
+
+// Run implements Executor interface.
+func (v DryRunExecutor) Run(c *Command) ([]byte, error) {
+	if IsReadOnlyCommand(c.Args) {
+		return v.Executor.Run(c)
+	}
+	log.Notef("will execute command: %s", c)
+	return nil, nil
+}
+
+// MockExecutor records commands without running them, and Handler can
+// be used to return mocked output and error.
+type MockExecutor struct {
+	Handler  func(c *Command) ([]byte, error)
+	Commands []*Command
+	mutex    sync.Mutex
+}
+
+// Run implements Executor interface.
+func (v *MockExecutor) Run(c *Command) ([]byte, error) {
+	v.mutex.Lock()
+	v.Commands = append(v.Commands, c)
+	v.mutex.Unlock()
+	if v.Handler != nil {
+		return v.Handler(c)
+	}
+	return nil, nil
+}
+
+// CommandLines returns command lines of recorded commands.
+func (v *MockExecutor) CommandLines() []string {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	lines := []string{}
+	for _, c := range v.Commands {
+		lines = append(lines, c.String())
+	}
+	return lines
+}
+
+// SetExecutor replaces default executor, and returns a function to
+// restore it.
+func SetExecutor(e Executor) func() {
+	executorMutex.Lock()
+	defer executorMutex.Unlock()
+
+	old := executor
+	executor = e
+	return func() {
+		executorMutex.Lock()
+		executor = old
+		executorMutex.Unlock()
+	}
+}
+
+// GetExecutor returns default executor, which is wrapped by
+// DryRunExecutor in dryrun mode.
+func GetExecutor() Executor {
+	executorMutex.RLock()
+	e := executor
+	executorMutex.RUnlock()
+
+	if config.IsDryRun() {
+		return DryRunExecutor{Executor: e}
+	}
+	return e
+}
+
+// RunCommand runs command using default executor.
+func RunCommand(c *Command) ([]byte, error) {
+	return GetExecutor().Run(c)
+}
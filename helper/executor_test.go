@@ -0,0 +1,109 @@
+package helper
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsReadOnlyCommand(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(IsReadOnlyCommand([]string{"git", "rev-parse", "HEAD"}))
+	assert.True(IsReadOnlyCommand([]string{"git", "-c", "core.quotepath=false", "status"}))
+	assert.True(IsReadOnlyCommand([]string{"git", "-C", "path", "--no-pager", "log"}))
+	assert.True(IsReadOnlyCommand([]string{"git", "archive", "--format=tar", "HEAD"}))
+	assert.True(IsReadOnlyCommand([]string{"git", "credential", "fill"}))
+	assert.False(IsReadOnlyCommand([]string{"git", "credential", "approve"}))
+	assert.False(IsReadOnlyCommand([]string{"git", "fetch", "origin"}))
+	assert.False(IsReadOnlyCommand([]string{"git", "-c", "status"}))
+	assert.False(IsReadOnlyCommand([]string{"git"}))
+	assert.False(IsReadOnlyCommand([]string{"ls", "-l"}))
+}
+
+func TestExecExecutor(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		e      = ExecExecutor{}
+		buf    bytes.Buffer
+	)
+
+	out, err := e.Run(&Command{Args: []string{"sh", "-c", "echo $FOO"}, Env: []string{"FOO=bar"}})
+	assert.Nil(err)
+	assert.Equal("bar\n", string(out))
+
+	os.Setenv("GIT_REPO_TEST_SECRET", "secret")
+	defer os.Unsetenv("GIT_REPO_TEST_SECRET")
+	out, err = e.Run(&Command{Args: []string{"sh", "-c", "echo $GIT_REPO_TEST_SECRET"}})
+	assert.Nil(err)
+	assert.Equal("secret\n", string(out))
+	out, err = e.Run(&Command{
+		Args:     []string{"/bin/sh", "-c", "echo $GIT_REPO_TEST_SECRET$FOO"},
+		Env:      []string{"FOO=bar"},
+		ClearEnv: true,
+	})
+	assert.Nil(err)
+	assert.Equal("bar\n", string(out))
+
+	out, err = e.Run(&Command{Args: []string{"sh", "-c", "echo hello"}, Stdout: &buf})
+	assert.Nil(err)
+	assert.Nil(out)
+	assert.Equal("hello\n", buf.String())
+
+	// Error output is saved in ExitError.
+	_, err = e.Run(&Command{Args: []string{"sh", "-c", "echo oops >&2; exit 3"}})
+	exitError, ok := err.(*exec.ExitError)
+	assert.True(ok)
+	assert.Equal("oops\n", string(exitError.Stderr))
+
+	start := time.Now()
+	_, err = e.Run(&Command{Args: []string{"sleep", "5"}, Timeout: 100 * time.Millisecond})
+	assert.NotNil(err)
+	assert.True(time.Since(start) < 5*time.Second)
+}
+
+func TestDryRunExecutor(t *testing.T) {
+	assert := assert.New(t)
+
+	mock := MockExecutor{
+		Handler: func(c *Command) ([]byte, error) {
+			if c.Args[1] == "rev-parse" {
+				return []byte("refs/heads/master\n"), nil
+			}
+			return nil, errors.New("unexpected")
+		},
+	}
+	restore := SetExecutor(&mock)
+	defer restore()
+
+	viper.Set("dryrun", true)
+	defer viper.Set("dryrun", false)
+
+	out, err := RunCommand(&Command{Args: []string{"git", "rev-parse", "--symbolic-full-name", "HEAD"}})
+	assert.Nil(err)
+	assert.Equal("refs/heads/master\n", string(out))
+	out, err = RunCommand(&Command{Args: []string{"git", "checkout", "master"}})
+	assert.Nil(err)
+	assert.Nil(out)
+	assert.Equal([]string{
+		"git rev-parse --symbolic-full-name HEAD",
+	}, mock.CommandLines())
+
+	viper.Set("dryrun", false)
+	_, err = RunCommand(&Command{Args: []string{"git", "checkout", "master"}})
+	assert.Equal("unexpected", err.Error())
+	assert.Equal([]string{
+		"git rev-parse --symbolic-full-name HEAD",
+		"git checkout master",
+	}, mock.CommandLines())
+
+	restore()
+	_, ok := GetExecutor().(ExecExecutor)
+	assert.True(ok)
+}
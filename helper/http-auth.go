@@ -0,0 +1,273 @@
+package helper
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// Methods of HTTP authentication, which can be set for each host by git
+// config "repo.auth.<host>.method".
+const (
+	HTTPAuthNone       = "none"
+	HTTPAuthNetrc      = "netrc"
+	HTTPAuthCredential = "credential"
+	HTTPAuthToken      = "token"
+)
+
+var (
+	// credentialCache saves results of "git credential fill" for each
+	// host, so that credential helper is called (and may prompt) only
+	// once for all projects.
+	credentialCache = make(map[string]*credentialResult)
+	credentialMutex sync.Mutex
+)
+
+// credentialResult is result of "git credential fill".
+type credentialResult struct {
+	username string
+	password string
+	err      error
+}
+
+// HTTPAuth is credential for HTTP remotes.
+type HTTPAuth struct {
+	Method   string
+	Username string
+	Password string
+	Token    string
+}
+
+// authHost returns host (with non-default port) of HTTP URL, which is
+// used as key of auth settings.
+func authHost(u *config.GitURL) string {
+	if u.Port > 0 && u.Port != 80 && u.Port != 443 {
+		return u.Host + ":" + strconv.Itoa(u.Port)
+	}
+	return u.Host
+}
+
+// GetHTTPAuth returns credential for HTTP URL. Method of authentication
+// is read from git config "repo.auth.<host>.method":
+//
+//   - token: use bearer token in "repo.auth.<host>.token"
+//   - netrc: use username and password in ~/.netrc
+//   - credential: call "git credential fill"
+//   - none: no authentication
+//
+// If method is not set, token is used if defined, or try ~/.netrc.
+// Returns nil if no credential is available.
+func GetHTTPAuth(address string) (*HTTPAuth, error) {
+	u := config.ParseGitURL(address)
+	if u == nil || !u.IsHTTP() {
+		return nil, nil
+	}
+	host := authHost(u)
+	method := config.GitDefaultConfig.Get(fmt.Sprintf(config.CfgRepoAuthMethod, host))
+	token := config.GitDefaultConfig.Get(fmt.Sprintf(config.CfgRepoAuthToken, host))
+	if method == "" {
+		if token != "" {
+			method = HTTPAuthToken
+		} else {
+			method = HTTPAuthNetrc
+		}
+	}
+
+	switch method {
+	case HTTPAuthNone:
+		return nil, nil
+	case HTTPAuthToken:
+		if token == "" {
+			return nil, fmt.Errorf("token is not set for host '%s'", host)
+		}
+		return &HTTPAuth{Method: method, Token: token}, nil
+	case HTTPAuthNetrc:
+		username, password, err := netrcCredential(u.Host)
+		if err != nil || username == "" {
+			return nil, err
+		}
+		return &HTTPAuth{Method: method, Username: username, Password: password}, nil
+	case HTTPAuthCredential:
+		username, password, err := gitCredential(u.Proto, host)
+		if err != nil {
+			return nil, err
+		}
+		return &HTTPAuth{Method: method, Username: username, Password: password}, nil
+	}
+	return nil, fmt.Errorf("unknown auth method '%s' for host '%s'", method, host)
+}
+
+// SetRequestAuth sets authorization header of request.
+func (v *HTTPAuth) SetRequestAuth(req *http.Request) {
+	if v == nil {
+		return
+	}
+	if v.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+v.Token)
+	} else if v.Username != "" {
+		req.SetBasicAuth(v.Username, v.Password)
+	}
+}
+
+// GitEnv returns environments for git commands to access address. Git
+// reads ~/.netrc and calls credential helpers by itself, so only bearer
+// token is passed as an extra header, and config in environments will
+// not be seen in process list. Configs already given by GIT_CONFIG_COUNT
+// in environments are kept.
+func (v *HTTPAuth) GitEnv(address string) []string {
+	if v == nil || v.Token == "" {
+		return nil
+	}
+	u := config.ParseGitURL(address)
+	if u == nil {
+		return nil
+	}
+	n, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	if n < 0 {
+		n = 0
+	}
+	return []string{
+		fmt.Sprintf("GIT_CONFIG_COUNT=%d", n+1),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=http.%s://%s/.extraHeader", n, u.Proto, authHost(u)),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=Authorization: Bearer %s", n, v.Token),
+	}
+}
+
+// netrcFile returns path of netrc file, which can be overridden by $NETRC.
+func netrcFile() string {
+	if file := os.Getenv("NETRC"); file != "" {
+		return file
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+// netrcCredential finds login and password for host in netrc file.
+func netrcCredential(host string) (string, string, error) {
+	file := netrcFile()
+	if file == "" {
+		return "", "", nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+	return parseNetrc(string(data), host)
+}
+
+// parseNetrc parses netrc data, and returns login and password of the
+// machine, or of the default entry.
+func parseNetrc(data, host string) (string, string, error) {
+	var (
+		tokens                = strings.Fields(data)
+		matched, isDefault    bool
+		login, password       string
+		defLogin, defPassword string
+	)
+
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			if matched {
+				return login, password, nil
+			}
+			if i+1 < len(tokens) {
+				i++
+				matched = tokens[i] == host
+			}
+			isDefault = false
+		case "default":
+			if matched {
+				return login, password, nil
+			}
+			isDefault = true
+		case "login":
+			if i+1 < len(tokens) {
+				i++
+				if matched {
+					login = tokens[i]
+				} else if isDefault {
+					defLogin = tokens[i]
+				}
+			}
+		case "password":
+			if i+1 < len(tokens) {
+				i++
+				if matched {
+					password = tokens[i]
+				} else if isDefault {
+					defPassword = tokens[i]
+				}
+			}
+		case "account":
+			i++
+		case "macdef":
+			// Macro definitions are always at the end of file.
+			i = len(tokens)
+		}
+	}
+	if matched {
+		return login, password, nil
+	}
+	return defLogin, defPassword, nil
+}
+
+// gitCredential returns username and password from "git credential fill",
+// which is called once for each host.
+func gitCredential(proto, host string) (string, string, error) {
+	key := proto + "://" + host
+	credentialMutex.Lock()
+	defer credentialMutex.Unlock()
+	if r, ok := credentialCache[key]; ok {
+		return r.username, r.password, r.err
+	}
+	username, password, err := fillGitCredential(proto, host)
+	credentialCache[key] = &credentialResult{
+		username: username,
+		password: password,
+		err:      err,
+	}
+	return username, password, err
+}
+
+// fillGitCredential calls "git credential fill" to get username and
+// password.
+func fillGitCredential(proto, host string) (string, string, error) {
+	var username, password string
+
+	out, err := RunCommand(&Command{
+		Args:  []string{config.GIT, "credential", "fill"},
+		Env:   []string{"GIT_TERMINAL_PROMPT=0"},
+		Stdin: strings.NewReader(fmt.Sprintf("protocol=%s\nhost=%s\n\n", proto, host)),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("fail to get credential of '%s': %s", host, err)
+	}
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "username=") {
+			username = strings.TrimPrefix(line, "username=")
+		} else if strings.HasPrefix(line, "password=") {
+			password = strings.TrimPrefix(line, "password=")
+		}
+	}
+	log.Debugf("got credential of '%s' from git credential helper", host)
+	return username, password, nil
+}
@@ -0,0 +1,174 @@
+package helper
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNetrc(t *testing.T) {
+	assert := assert.New(t)
+
+	data := `
+machine example.com
+  login user1
+  password pass1
+machine gerrit.example.com login user2 password pass2 account acct
+default login anonymous password guest
+`
+	login, password, err := parseNetrc(data, "example.com")
+	assert.Nil(err)
+	assert.Equal("user1", login)
+	assert.Equal("pass1", password)
+
+	login, password, err = parseNetrc(data, "gerrit.example.com")
+	assert.Nil(err)
+	assert.Equal("user2", login)
+	assert.Equal("pass2", password)
+
+	login, password, err = parseNetrc(data, "other.example.com")
+	assert.Nil(err)
+	assert.Equal("anonymous", login)
+	assert.Equal("guest", password)
+
+	login, _, err = parseNetrc("machine example.com login user1", "other.example.com")
+	assert.Nil(err)
+	assert.Equal("", login)
+}
+
+func TestGetHTTPAuth(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	netrc := filepath.Join(tmpdir, "netrc")
+	err = ioutil.WriteFile(netrc, []byte("machine example.com login user1 password pass1\n"), 0600)
+	assert.Nil(err)
+	os.Setenv("NETRC", netrc)
+	defer os.Unsetenv("NETRC")
+
+	// Not HTTP protocol.
+	auth, err := GetHTTPAuth("ssh://git@example.com/project.git")
+	assert.Nil(err)
+	assert.Nil(auth)
+
+	auth, err = GetHTTPAuth("https://example.com/project.git")
+	assert.Nil(err)
+	assert.Equal(&HTTPAuth{Method: HTTPAuthNetrc, Username: "user1", Password: "pass1"}, auth)
+	assert.Nil(auth.GitEnv("https://example.com/project.git"))
+	req, _ := http.NewRequest("GET", "https://example.com/ssh_info", nil)
+	auth.SetRequestAuth(req)
+	username, password, ok := req.BasicAuth()
+	assert.True(ok)
+	assert.Equal("user1", username)
+	assert.Equal("pass1", password)
+
+	// No entry in netrc.
+	auth, err = GetHTTPAuth("https://other.example.com/project.git")
+	assert.Nil(err)
+	assert.Nil(auth)
+
+	// Token for host with port.
+	config.GitDefaultConfig.Set("repo.auth.gerrit.example.com:8080.token", "secret")
+	defer config.GitDefaultConfig.Unset("repo.auth.gerrit.example.com:8080.token")
+	auth, err = GetHTTPAuth("http://gerrit.example.com:8080/project.git")
+	assert.Nil(err)
+	assert.Equal(&HTTPAuth{Method: HTTPAuthToken, Token: "secret"}, auth)
+	assert.Equal([]string{
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.http://gerrit.example.com:8080/.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Bearer secret",
+	}, auth.GitEnv("http://gerrit.example.com:8080/project.git"))
+	os.Setenv("GIT_CONFIG_COUNT", "2")
+	assert.Equal([]string{
+		"GIT_CONFIG_COUNT=3",
+		"GIT_CONFIG_KEY_2=http.http://gerrit.example.com:8080/.extraHeader",
+		"GIT_CONFIG_VALUE_2=Authorization: Bearer secret",
+	}, auth.GitEnv("http://gerrit.example.com:8080/project.git"))
+	os.Unsetenv("GIT_CONFIG_COUNT")
+	req, _ = http.NewRequest("GET", "http://gerrit.example.com:8080/ssh_info", nil)
+	auth.SetRequestAuth(req)
+	assert.Equal("Bearer secret", req.Header.Get("Authorization"))
+
+	// Disable authentication.
+	config.GitDefaultConfig.Set("repo.auth.example.com.method", "none")
+	defer config.GitDefaultConfig.Unset("repo.auth.example.com.method")
+	auth, err = GetHTTPAuth("https://example.com/project.git")
+	assert.Nil(err)
+	assert.Nil(auth)
+
+	config.GitDefaultConfig.Set("repo.auth.example.com.method", "bad")
+	_, err = GetHTTPAuth("https://example.com/project.git")
+	assert.Equal("unknown auth method 'bad' for host 'example.com'", err.Error())
+}
+
+func TestGitCredentialOncePerHost(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// Credential helper counts how many times it is called.
+	counter := filepath.Join(tmpdir, "counter")
+	gitconfig := filepath.Join(tmpdir, "gitconfig")
+	err = ioutil.WriteFile(gitconfig, []byte(fmt.Sprintf(`[credential]
+	helper = "!f() { echo called >>'%s'; echo username=user1; echo password=pass1; }; f"
+`, counter)), 0644)
+	assert.Nil(err)
+	for k, v := range map[string]string{
+		"GIT_CONFIG_GLOBAL":   gitconfig,
+		"GIT_CONFIG_NOSYSTEM": "1",
+	} {
+		if old, ok := os.LookupEnv(k); ok {
+			defer os.Setenv(k, old)
+		} else {
+			defer os.Unsetenv(k)
+		}
+		os.Setenv(k, v)
+	}
+	config.GitDefaultConfig.Set("repo.auth.cred.example.com.method", "credential")
+	defer config.GitDefaultConfig.Unset("repo.auth.cred.example.com.method")
+
+	for i := 0; i < 3; i++ {
+		auth, err := GetHTTPAuth(fmt.Sprintf("https://cred.example.com/project%d.git", i))
+		assert.Nil(err)
+		assert.Equal(&HTTPAuth{Method: HTTPAuthCredential, Username: "user1", Password: "pass1"}, auth)
+	}
+	data, err := ioutil.ReadFile(counter)
+	assert.Nil(err)
+	assert.Equal("called\n", string(data))
+}
+
+func TestFillGitCredentialByExecutor(t *testing.T) {
+	assert := assert.New(t)
+
+	mock := &MockExecutor{
+		Handler: func(c *Command) ([]byte, error) {
+			return []byte("protocol=https\nhost=mock.example.com\nusername=user2\npassword=pass2\n"), nil
+		},
+	}
+	defer SetExecutor(mock)()
+
+	username, password, err := fillGitCredential("https", "mock.example.com")
+	assert.Nil(err)
+	assert.Equal("user2", username)
+	assert.Equal("pass2", password)
+	assert.Equal([]string{"git credential fill"}, mock.CommandLines())
+	assert.Equal([]string{"GIT_TERMINAL_PROMPT=0"}, mock.Commands[0].Env)
+	input, err := ioutil.ReadAll(mock.Commands[0].Stdin)
+	assert.Nil(err)
+	assert.Equal("protocol=https\nhost=mock.example.com\n\n", string(input))
+}
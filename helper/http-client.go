@@ -0,0 +1,232 @@
+package helper
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/jiangxin/goconfig"
+)
+
+// gerritMagicPrefix is prepended to JSON responses of Gerrit to prevent
+// XSSI.
+const gerritMagicPrefix = ")]}'"
+
+// HTTPClientOptions defines options for NewHTTPClient.
+type HTTPClientOptions struct {
+	// Timeout for connection, TLS handshake and response header.
+	Timeout time.Duration
+	// NoCertChecks disables verifying ssl certs, besides git config
+	// "http.sslVerify" and env $GIT_SSL_NO_VERIFY.
+	NoCertChecks bool
+}
+
+// NewHTTPClient creates HTTP client to access address, which is shared
+// by manifest server RPC, ssh_info API, review API and upgrade command.
+// Settings are read from git config the same way as git does:
+//
+//   - http.<url>.proxy, http.proxy: proxy which overrides env
+//     $HTTP_PROXY, $HTTPS_PROXY and $NO_PROXY. Proxy is selected for
+//     each request, so a proxy can be set for a remote by URL.
+//   - http.<url>.sslCAInfo, http.sslCAInfo: file of custom CA bundle,
+//     overridden by env $GIT_SSL_CAINFO.
+//   - http.<url>.sslVerify, http.sslVerify: verify ssl certs or not,
+//     overridden by env $GIT_SSL_NO_VERIFY.
+func NewHTTPClient(address string, o *HTTPClientOptions) *http.Client {
+	if o == nil {
+		o = &HTTPClientOptions{}
+	}
+	timeout := o.Timeout
+	if timeout == 0 {
+		timeout = remoteCallTimeout * time.Second
+	}
+
+	cfg, err := goconfig.LoadAll("")
+	if err != nil {
+		log.Debugf("fail to load git config: %s", err)
+		cfg = config.GitDefaultConfig
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: o.NoCertChecks || noCertChecks(cfg, address),
+	}
+	if caFile := caInfo(cfg, address); caFile != "" && !tlsConfig.InsecureSkipVerify {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			log.Warnf("fail to load CA bundle: %s", err)
+		} else {
+			tlsConfig.RootCAs = pool
+		}
+	}
+
+	tr := &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   timeout,
+			KeepAlive: timeout,
+		}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		ExpectContinueTimeout: 1 * time.Second,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       timeout,
+		DisableCompression:    true,
+		Proxy:                 proxyFunc(cfg),
+	}
+
+	return &http.Client{Transport: tr}
+}
+
+// CallJSONAPI sends request to REST API of Gerrit, GitHub or GitLab, and
+// decodes JSON response into result. Input is sent as JSON body if not
+// nil, and error is returned if status of response is not 2xx.
+func CallJSONAPI(client *http.Client, auth *HTTPAuth, method, address string, input, result interface{}) error {
+	var body []byte
+
+	if input != nil {
+		data, err := json.Marshal(input)
+		if err != nil {
+			return err
+		}
+		body = data
+	}
+	req, err := http.NewRequest(method, address, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if input != nil {
+		req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	}
+	auth.SetRequestAuth(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s: %s",
+			method,
+			address,
+			resp.Status,
+			strings.TrimSpace(string(data)))
+	}
+	if result == nil {
+		return nil
+	}
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte(gerritMagicPrefix))
+	return json.Unmarshal(data, result)
+}
+
+// httpConfigURLs returns URLs used as subsection of "http.<url>.*" to
+// match address, from the most specific one.
+func httpConfigURLs(address string) []string {
+	u, err := url.Parse(address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil
+	}
+
+	urls := []string{}
+	base := u.Scheme + "://" + u.Host
+	p := strings.TrimSuffix(u.Path, "/")
+	for p != "" {
+		urls = append(urls, base+p+"/", base+p)
+		i := strings.LastIndex(p, "/")
+		if i < 0 {
+			break
+		}
+		p = p[:i]
+	}
+	return append(urls, base+"/", base)
+}
+
+// httpConfig returns value of "http.<url>.<key>" matched with address,
+// or value of "http.<key>".
+func httpConfig(cfg goconfig.GitConfig, address, key string) (string, bool) {
+	for _, u := range httpConfigURLs(address) {
+		name := fmt.Sprintf("http.%s.%s", u, key)
+		if value := cfg.Get(name); value != "" {
+			return value, true
+		}
+	}
+	value := cfg.Get("http." + key)
+	return value, value != ""
+}
+
+// noCertChecks checks env $GIT_SSL_NO_VERIFY and git config sslVerify.
+func noCertChecks(cfg goconfig.GitConfig, address string) bool {
+	if os.Getenv("GIT_SSL_NO_VERIFY") != "" {
+		return true
+	}
+	value, ok := httpConfig(cfg, address, "sslverify")
+	if !ok {
+		return false
+	}
+	switch strings.ToLower(value) {
+	case "false", "no", "off", "0":
+		return true
+	}
+	return false
+}
+
+// caInfo returns file of CA bundle from env $GIT_SSL_CAINFO or git
+// config sslCAInfo.
+func caInfo(cfg goconfig.GitConfig, address string) string {
+	if file := os.Getenv("GIT_SSL_CAINFO"); file != "" {
+		return file
+	}
+	file, _ := httpConfig(cfg, address, "sslcainfo")
+	return file
+}
+
+// loadCertPool loads CA bundle in PEM format, appended to system pool.
+func loadCertPool(file string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificate found in '%s'", file)
+	}
+	return pool, nil
+}
+
+// parseProxy parses proxy URL, and scheme can be omitted like git does.
+func parseProxy(proxy string) (*url.URL, error) {
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	return url.Parse(proxy)
+}
+
+// proxyFunc returns proxy function for each request. Proxy set in git
+// config overrides env $HTTP_PROXY, $HTTPS_PROXY and $NO_PROXY (or the
+// lowercase versions thereof).
+func proxyFunc(cfg goconfig.GitConfig) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if proxy, ok := httpConfig(cfg, req.URL.String(), "proxy"); ok {
+			return parseProxy(proxy)
+		}
+		return http.ProxyFromEnvironment(req)
+	}
+}
@@ -0,0 +1,83 @@
+package helper
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/jiangxin/goconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPConfigURLs(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(httpConfigURLs("ssh://git@example.com/project.git"))
+	assert.Nil(httpConfigURLs("git@example.com:project.git"))
+	assert.Equal([]string{
+		"https://example.com/",
+		"https://example.com",
+	}, httpConfigURLs("https://example.com"))
+	assert.Equal([]string{
+		"https://example.com:8443/group/project.git/",
+		"https://example.com:8443/group/project.git",
+		"https://example.com:8443/group/",
+		"https://example.com:8443/group",
+		"https://example.com:8443/",
+		"https://example.com:8443",
+	}, httpConfigURLs("https://example.com:8443/group/project.git"))
+}
+
+func TestHTTPConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	defer os.Setenv("GIT_SSL_CAINFO", os.Getenv("GIT_SSL_CAINFO"))
+	defer os.Setenv("GIT_SSL_NO_VERIFY", os.Getenv("GIT_SSL_NO_VERIFY"))
+	os.Unsetenv("GIT_SSL_CAINFO")
+	os.Unsetenv("GIT_SSL_NO_VERIFY")
+
+	cfg := goconfig.NewGitConfig()
+	cfg.Set("http.sslVerify", "true")
+	cfg.Set("http.sslCAInfo", "/etc/ca.pem")
+	cfg.Set("http.https://example.com/.sslVerify", "false")
+	cfg.Set("http.https://example.com/internal.sslCAInfo", "/etc/internal-ca.pem")
+
+	assert.False(noCertChecks(cfg, "https://other.example.com/project.git"))
+	assert.True(noCertChecks(cfg, "https://example.com/project.git"))
+	assert.Equal("/etc/ca.pem", caInfo(cfg, "https://example.com/project.git"))
+	assert.Equal("/etc/internal-ca.pem", caInfo(cfg, "https://example.com/internal/project.git"))
+
+	os.Setenv("GIT_SSL_CAINFO", "/tmp/ca.pem")
+	assert.Equal("/tmp/ca.pem", caInfo(cfg, "https://example.com/internal/project.git"))
+
+	os.Setenv("GIT_SSL_NO_VERIFY", "1")
+	assert.True(noCertChecks(cfg, "https://other.example.com/project.git"))
+}
+
+func TestProxyFunc(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := goconfig.NewGitConfig()
+	cfg.Set("http.https://example.com/.proxy", "example-proxy:8080")
+	proxy := proxyFunc(cfg)
+
+	req, _ := http.NewRequest("GET", "https://example.com/ssh_info", nil)
+	u, err := proxy(req)
+	assert.Nil(err)
+	assert.Equal("http://example-proxy:8080", u.String())
+
+	cfg.Set("http.proxy", "socks5://global-proxy:1080")
+	req, _ = http.NewRequest("GET", "https://other.example.com/ssh_info", nil)
+	u, err = proxy(req)
+	assert.Nil(err)
+	assert.Equal("socks5://global-proxy:1080", u.String())
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	assert := assert.New(t)
+
+	client := NewHTTPClient("https://example.com", &HTTPClientOptions{NoCertChecks: true})
+	tr, ok := client.Transport.(*http.Transport)
+	assert.True(ok)
+	assert.True(tr.TLSClientConfig.InsecureSkipVerify)
+}
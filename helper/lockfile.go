@@ -0,0 +1,213 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alibaba/git-repo-go/cap"
+	"github.com/alibaba/git-repo-go/log"
+)
+
+const (
+	// lockPollInterval is interval to check lock when waiting for it.
+	lockPollInterval = 200 * time.Millisecond
+	// lockIncompleteTimeout is time to treat a lock file without valid
+	// content as stale, for its owner may crash before writing it.
+	lockIncompleteTimeout = time.Minute
+)
+
+// LockInfo is saved in lock file to tell who holds the lock.
+type LockInfo struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Command string    `json:"command"`
+	Time    time.Time `json:"time"`
+}
+
+func (v LockInfo) String() string {
+	return fmt.Sprintf("pid %d on %s since %s, command: %s",
+		v.PID, v.Host, v.Time.Format(time.RFC3339), v.Command)
+}
+
+// LockedError indicates lock is held by another process.
+type LockedError struct {
+	File string
+	Info *LockInfo
+}
+
+func (v LockedError) Error() string {
+	if v.Info == nil {
+		return fmt.Sprintf("lock '%s' is held by another process", v.File)
+	}
+	return fmt.Sprintf("lock '%s' is held by another process (%s)", v.File, v.Info)
+}
+
+// IsLockedError checks whether err is LockedError.
+func IsLockedError(err error) bool {
+	_, ok := err.(*LockedError)
+	return ok
+}
+
+// LockFile is an exclusive lock between processes, which is a file
+// created exclusively and removed on unlock. A lock is stale if the
+// process which holds it is gone, and is removed automatically.
+type LockFile struct {
+	File string
+	info LockInfo
+}
+
+// NewLockFile creates LockFile instance.
+func NewLockFile(file string) *LockFile {
+	hostname, _ := os.Hostname()
+	return &LockFile{
+		File: file,
+		info: LockInfo{
+			PID:     os.Getpid(),
+			Host:    hostname,
+			Command: strings.Join(os.Args, " "),
+		},
+	}
+}
+
+// Owner reads info of the process which holds the lock.
+func (v LockFile) Owner() (*LockInfo, error) {
+	data, err := ioutil.ReadFile(v.File)
+	if err != nil {
+		return nil, err
+	}
+	info := LockInfo{}
+	if err = json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// IsStale checks whether lock is held by a process which is gone. Lock
+// held by process of other host is never stale.
+func (v LockFile) IsStale() bool {
+	info, err := v.Owner()
+	if err != nil {
+		fi, err := os.Stat(v.File)
+		return err == nil && time.Since(fi.ModTime()) > lockIncompleteTimeout
+	}
+	if info.Host != v.info.Host {
+		return false
+	}
+	return !cap.ProcessExists(info.PID)
+}
+
+// create creates file exclusively, and saves info of lock in it.
+func (v *LockFile) create(file string) error {
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	v.info.Time = time.Now()
+	data, _ := json.Marshal(v.info)
+	_, err = f.Write(data)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(file)
+		return err
+	}
+	return nil
+}
+
+// takeOver replaces a stale lock with ours, and returns false if lock is
+// being taken over or is already taken over by another process. Processes
+// taking over the lock are serialized by a guard file, and the stale lock
+// is replaced by rename, so that the lock file is never missing and is
+// never removed after another process takes it over.
+func (v *LockFile) takeOver() (bool, error) {
+	guard := v.File + ".takeover"
+	if err := v.create(guard); err != nil {
+		if !os.IsExist(err) {
+			return false, err
+		}
+		// Owner of guard may crash, remove it after a while.
+		if fi, err := os.Stat(guard); err == nil && time.Since(fi.ModTime()) > lockIncompleteTimeout {
+			os.Remove(guard)
+		}
+		return false, nil
+	}
+	defer os.Remove(guard)
+
+	// Check again, for lock may be taken over by another process before
+	// we hold the guard.
+	if !v.IsStale() {
+		return false, nil
+	}
+	info, _ := v.Owner()
+	tmpFile := fmt.Sprintf("%s.%d.tmp", v.File, os.Getpid())
+	if err := v.create(tmpFile); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmpFile, v.File); err != nil {
+		os.Remove(tmpFile)
+		return false, err
+	}
+	if info != nil {
+		log.Warnf("take over stale lock '%s' (%s)", v.File, info)
+	} else {
+		log.Warnf("take over stale lock '%s'", v.File)
+	}
+	return true, nil
+}
+
+// TryLock acquires lock without waiting, and returns LockedError if lock
+// is held by another process.
+func (v *LockFile) TryLock() error {
+	err := v.create(v.File)
+	if os.IsNotExist(err) {
+		if err = os.MkdirAll(filepath.Dir(v.File), 0755); err != nil {
+			return err
+		}
+		err = v.create(v.File)
+	}
+	if err == nil || !os.IsExist(err) {
+		return err
+	}
+	if v.IsStale() {
+		ok, err := v.takeOver()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+	info, _ := v.Owner()
+	return &LockedError{File: v.File, Info: info}
+}
+
+// Lock acquires lock, and waits until lock is released if wait is true.
+func (v *LockFile) Lock(wait bool) error {
+	notified := false
+	for {
+		err := v.TryLock()
+		if err == nil || !wait || !IsLockedError(err) {
+			return err
+		}
+		if !notified {
+			log.Notef("%s, waiting for it to be released", err)
+			notified = true
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// Unlock releases lock.
+func (v *LockFile) Unlock() error {
+	info, err := v.Owner()
+	if err == nil && (info.PID != v.info.PID || info.Host != v.info.Host) {
+		return fmt.Errorf("lock '%s' is not owned by us", v.File)
+	}
+	return os.Remove(v.File)
+}
@@ -0,0 +1,97 @@
+package helper
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockFile(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	// Parent dir is created.
+	file := filepath.Join(tmpdir, "locks", "test.lock")
+	lock1 := NewLockFile(file)
+	assert.Nil(lock1.TryLock())
+	info, err := lock1.Owner()
+	assert.Nil(err)
+	assert.Equal(os.Getpid(), info.PID)
+
+	// Held by a running process.
+	lock2 := NewLockFile(file)
+	err = lock2.TryLock()
+	assert.True(IsLockedError(err))
+	assert.Contains(err.Error(), "is held by another process (pid ")
+
+	// Wait until lock is released.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		lock1.Unlock()
+	}()
+	assert.Nil(lock2.Lock(true))
+	assert.Nil(lock2.Unlock())
+	_, err = os.Stat(file)
+	assert.True(os.IsNotExist(err))
+}
+
+func TestStaleLockFile(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	file := filepath.Join(tmpdir, "test.lock")
+	lock := NewLockFile(file)
+
+	// Process is gone.
+	info := lock.info
+	info.PID = 1 << 30
+	data, _ := json.Marshal(info)
+	assert.Nil(ioutil.WriteFile(file, data, 0644))
+	assert.Nil(lock.TryLock())
+	assert.Nil(lock.Unlock())
+
+	// Lock of another host is not stale.
+	info.Host = "other-host"
+	data, _ = json.Marshal(info)
+	assert.Nil(ioutil.WriteFile(file, data, 0644))
+	assert.True(IsLockedError(lock.TryLock()))
+	assert.NotNil(lock.Unlock())
+
+	// Incomplete lock file is stale after a while.
+	assert.Nil(ioutil.WriteFile(file, nil, 0644))
+	assert.True(IsLockedError(lock.TryLock()))
+	past := time.Now().Add(-2 * lockIncompleteTimeout)
+	os.Chtimes(file, past, past)
+	assert.Nil(lock.TryLock())
+	assert.Nil(lock.Unlock())
+
+	// Lock is being taken over by another process.
+	info.Host = lock.info.Host
+	data, _ = json.Marshal(info)
+	assert.Nil(ioutil.WriteFile(file, data, 0644))
+	assert.Nil(ioutil.WriteFile(file+".takeover", nil, 0644))
+	assert.True(IsLockedError(lock.TryLock()))
+	assert.Nil(os.Remove(file + ".takeover"))
+	assert.Nil(lock.TryLock())
+	_, err = os.Stat(file + ".takeover")
+	assert.True(os.IsNotExist(err))
+	owner, err := lock.Owner()
+	assert.Nil(err)
+	assert.Equal(os.Getpid(), owner.PID)
+	assert.Nil(lock.Unlock())
+}
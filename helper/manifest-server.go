@@ -0,0 +1,195 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/log"
+)
+
+// ManifestServer is client of manifest server, which is an XML-RPC
+// service to get manifest for smart sync.
+type ManifestServer struct {
+	// address has no credential, and is safe to show.
+	address    string
+	auth       *HTTPAuth
+	httpClient *http.Client
+}
+
+// NewManifestServer creates client of manifest server. Credential is
+// selected in order:
+//
+//   - username and password given by options of command line
+//   - username and password embedded in URL of manifest server
+//   - credential of the shared auth settings, such as token in git config
+//     "repo.auth.<host>.token", or username and password in ~/.netrc
+func NewManifestServer(address, username, password string) (*ManifestServer, error) {
+	u, err := url.Parse(address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		// Do not show address, which may have password.
+		return nil, errors.New("bad URL of manifest server, only HTTP and HTTPS are supported")
+	}
+	if u.User != nil {
+		if username == "" {
+			username = u.User.Username()
+			password, _ = u.User.Password()
+		}
+		u.User = nil
+	}
+
+	v := ManifestServer{address: u.String()}
+	if username != "" {
+		v.auth = &HTTPAuth{Username: username, Password: password}
+	} else {
+		v.auth, err = GetHTTPAuth(v.address)
+		if err != nil {
+			log.Debugf("fail to get credential for '%s': %s", v.address, err)
+		}
+	}
+	v.httpClient = NewHTTPClient(v.address, nil)
+	return &v, nil
+}
+
+// GetApprovedManifest returns manifest in which each project is pegged
+// to a known good revision for branch and target. Target is omitted if
+// it is empty.
+func (v ManifestServer) GetApprovedManifest(branch, target string) (string, error) {
+	if target == "" {
+		return v.getManifest("GetApprovedManifest", branch)
+	}
+	return v.getManifest("GetApprovedManifest", branch, target)
+}
+
+// GetManifest returns manifest in which each project is pegged to the
+// revision at tag.
+func (v ManifestServer) GetManifest(tag string) (string, error) {
+	return v.getManifest("GetManifest", tag)
+}
+
+// getManifest calls method which returns an array of success and
+// manifest (or error message).
+func (v ManifestServer) getManifest(method string, params ...string) (string, error) {
+	result, err := v.call(method, params...)
+	if err != nil {
+		return "", err
+	}
+	if result.Array == nil || len(result.Array.Data) != 2 {
+		return "", fmt.Errorf("bad response of %s from manifest server", method)
+	}
+	success := result.Array.Data[0].text()
+	msg := result.Array.Data[1].text()
+	if success != "1" && strings.ToLower(success) != "true" {
+		return "", fmt.Errorf("manifest server: %s", msg)
+	}
+	return msg, nil
+}
+
+// xmlrpcValue is value of XML-RPC. Value without type is a string.
+type xmlrpcValue struct {
+	Text    string  `xml:",chardata"`
+	String  *string `xml:"string"`
+	Boolean *string `xml:"boolean"`
+	Int     *string `xml:"int"`
+	I4      *string `xml:"i4"`
+	Array   *struct {
+		Data []xmlrpcValue `xml:"data>value"`
+	} `xml:"array"`
+	Members []struct {
+		Name  string      `xml:"name"`
+		Value xmlrpcValue `xml:"value"`
+	} `xml:"struct>member"`
+}
+
+func (v xmlrpcValue) text() string {
+	for _, s := range []*string{v.String, v.Boolean, v.Int, v.I4} {
+		if s != nil {
+			return *s
+		}
+	}
+	return v.Text
+}
+
+func (v xmlrpcValue) member(name string) string {
+	for _, m := range v.Members {
+		if m.Name == name {
+			return m.Value.text()
+		}
+	}
+	return ""
+}
+
+type xmlrpcResponse struct {
+	Params []xmlrpcValue `xml:"params>param>value"`
+	Fault  *xmlrpcValue  `xml:"fault>value"`
+}
+
+func xmlrpcRequest(method string, params ...string) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString(`<?xml version="1.0"?>`)
+	buf.WriteString("\n<methodCall>\n<methodName>")
+	xml.EscapeText(&buf, []byte(method))
+	buf.WriteString("</methodName>\n<params>\n")
+	for _, param := range params {
+		buf.WriteString("<param><value><string>")
+		xml.EscapeText(&buf, []byte(param))
+		buf.WriteString("</string></value></param>\n")
+	}
+	buf.WriteString("</params>\n</methodCall>\n")
+	return buf.Bytes()
+}
+
+// call calls method of XML-RPC, and returns the result.
+func (v ManifestServer) call(method string, params ...string) (*xmlrpcValue, error) {
+	log.Debugf("call %s of manifest server '%s'", method, v.address)
+	req, err := http.NewRequest("POST", v.address, bytes.NewReader(xmlrpcRequest(method, params...)))
+	if err != nil {
+		return nil, fmt.Errorf("bad request to manifest server '%s': %s", v.address, err)
+	}
+	req.Header.Set("Content-Type", "text/xml")
+	v.auth.SetRequestAuth(req)
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		// Error of client has URL of request, which has no credential.
+		return nil, fmt.Errorf("fail to call manifest server: %s", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, fmt.Errorf("%d: fail to authenticate with manifest server '%s', "+
+			"check credential in ~/.netrc or by options '-u' and '-p'",
+			resp.StatusCode,
+			v.address)
+	default:
+		return nil, fmt.Errorf("%d: bad response of manifest server '%s'",
+			resp.StatusCode,
+			v.address)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read response of manifest server: %s", err)
+	}
+	result := xmlrpcResponse{}
+	if err = xml.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("bad response of manifest server: %s", err)
+	}
+	if result.Fault != nil {
+		return nil, fmt.Errorf("manifest server fault %s: %s",
+			result.Fault.member("faultCode"),
+			result.Fault.member("faultString"))
+	}
+	if len(result.Params) == 0 {
+		return nil, fmt.Errorf("no result of %s from manifest server", method)
+	}
+	return &result.Params[0], nil
+}
@@ -23,8 +23,8 @@ import (
 	"github.com/alibaba/git-repo-go/cap"
 	"github.com/alibaba/git-repo-go/config"
 	"github.com/alibaba/git-repo-go/encode"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/version"
-	log "github.com/jiangxin/multi-log"
 )
 
 // AGitProtoHelper implements helper for AGit server.
@@ -138,6 +138,9 @@ func (v AGitProtoHelper) GetGitPushCommand(o *config.UploadOptions) (*GitPushCom
 		if o.Issue != "" {
 			cmds = append(cmds, "-o", "issue="+encode.B64Encode(o.Issue))
 		}
+		if o.Topic != "" {
+			cmds = append(cmds, "-o", "topic="+encode.B64Encode(o.Topic))
+		}
 		if o.People != nil && len(o.People) > 0 && len(o.People[0]) > 0 {
 			reviewers := strings.Join(o.People[0], ",")
 			cmds = append(cmds, "-o", "reviewers="+encode.B64Encode(reviewers))
@@ -155,12 +158,20 @@ func (v AGitProtoHelper) GetGitPushCommand(o *config.UploadOptions) (*GitPushCom
 		}
 		if o.WIP {
 			cmds = append(cmds, "-o", "wip=yes")
+		} else if o.Ready {
+			cmds = append(cmds, "-o", "wip=no")
+		}
+		if o.RemovePrivate {
+			cmds = append(cmds, "-o", "private=no")
 		}
 		if o.OldOid != "" {
 			cmds = append(cmds, "-o", "oldoid="+o.OldOid)
 		}
 	} else {
 		opts := []string{}
+		if o.Topic != "" {
+			opts = append(opts, "topic="+o.Topic)
+		}
 		if o.People != nil && len(o.People) > 0 {
 			for _, u := range o.People[0] {
 				opts = append(opts, "r="+u)
@@ -180,6 +191,12 @@ func (v AGitProtoHelper) GetGitPushCommand(o *config.UploadOptions) (*GitPushCom
 		if o.WIP {
 			opts = append(opts, "wip")
 		}
+		if o.Ready {
+			opts = append(opts, "ready")
+		}
+		if o.RemovePrivate {
+			opts = append(opts, "remove-private")
+		}
 		if o.OldOid != "" {
 			opts = append(opts, "oldoid="+o.OldOid)
 		}
@@ -21,7 +21,7 @@ import (
 	"strings"
 
 	"github.com/alibaba/git-repo-go/config"
-	log "github.com/jiangxin/multi-log"
+	"github.com/alibaba/git-repo-go/log"
 )
 
 // GerritProtoHelper wraps helper for gerrit server.
@@ -106,11 +106,15 @@ func (v GerritProtoHelper) GetGitPushCommand(o *config.UploadOptions) (*GitPushC
 		uploadType,
 		destBranch)
 
-	if o.AutoTopic && localBranch != "" {
+	opts := []string{}
+	if o.Topic != "" {
+		opts = append(opts, "topic="+o.Topic)
+	} else if o.AutoTopic && localBranch != "" {
 		refSpec = refSpec + "/" + localBranch
 	}
-
-	opts := []string{}
+	for _, hashtag := range o.Hashtags {
+		opts = append(opts, "t="+hashtag)
+	}
 	if o.People != nil && len(o.People) > 0 {
 		for _, u := range o.People[0] {
 			opts = append(opts, "r="+u)
@@ -130,6 +134,12 @@ func (v GerritProtoHelper) GetGitPushCommand(o *config.UploadOptions) (*GitPushC
 	if o.WIP {
 		opts = append(opts, "wip")
 	}
+	if o.Ready {
+		opts = append(opts, "ready")
+	}
+	if o.RemovePrivate {
+		opts = append(opts, "remove-private")
+	}
 	if len(opts) > 0 {
 		refSpec = refSpec + "%" + strings.Join(opts, ",")
 	}
@@ -0,0 +1,268 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+)
+
+// ReviewCreator is implemented by proto helper which creates code review
+// by REST API after the topic branch is pushed, such as pull request of
+// GitHub and merge request of GitLab.
+type ReviewCreator interface {
+	CreateReview(o *config.UploadOptions) (string, error)
+}
+
+// ProtoTypeFromURL guesses proto type from host of address, returns empty
+// string if unknown.
+func ProtoTypeFromURL(address string) string {
+	u := config.ParseGitURL(address)
+	if u == nil {
+		return ""
+	}
+	host := strings.ToLower(u.Host)
+	switch {
+	case host == "github.com" || strings.HasPrefix(host, "github."):
+		return ProtoTypeGitHub
+	case host == "gitlab.com" || strings.HasPrefix(host, "gitlab."):
+		return ProtoTypeGitLab
+	}
+	return ""
+}
+
+// PullRequestProtoHelper implements helper for GitHub and GitLab, which
+// pushes topic branch and opens pull request (merge request) by REST API.
+type PullRequestProtoHelper struct {
+	sshInfo   *SSHInfo
+	protoType string
+
+	// apiURL overrides root URL of REST API, only for test.
+	apiURL string
+}
+
+// NewPullRequestProtoHelper returns PullRequestProtoHelper object.
+func NewPullRequestProtoHelper(sshInfo *SSHInfo, protoType string) *PullRequestProtoHelper {
+	return &PullRequestProtoHelper{
+		sshInfo:   sshInfo,
+		protoType: protoType,
+	}
+}
+
+// GetType returns remote server type.
+func (v PullRequestProtoHelper) GetType() string {
+	return v.protoType
+}
+
+// GetSSHInfo returns SSHInfo object.
+func (v PullRequestProtoHelper) GetSSHInfo() *SSHInfo {
+	return v.sshInfo
+}
+
+// reviewBranch returns name of remote branch for review, which is the
+// topic, or name of the local branch.
+func reviewBranch(o *config.UploadOptions) string {
+	if o.Topic != "" {
+		return o.Topic
+	}
+	return strings.TrimPrefix(o.LocalBranch, config.RefsHeads)
+}
+
+// GetGitPushCommand reads upload options and returns git push command.
+func (v PullRequestProtoHelper) GetGitPushCommand(o *config.UploadOptions) (*GitPushCommand, error) {
+	if !o.CodeReview.Empty() {
+		return nil, fmt.Errorf("Change code review by ID is not allowed in %s", v.protoType)
+	}
+	if o.RemoteURL == "" {
+		return nil, errors.New("empty review url for helper")
+	}
+	if strings.TrimPrefix(o.DestBranch, config.RefsHeads) == "" {
+		return nil, errors.New("empty dest branch for helper")
+	}
+	branch := reviewBranch(o)
+	if branch == "" {
+		return nil, errors.New("empty local branch for helper")
+	}
+
+	cmds := []string{"git", "push"}
+	for _, pushOption := range o.PushOptions {
+		cmds = append(cmds, "-o", pushOption)
+	}
+	if o.RemoteName != "" {
+		cmds = append(cmds, o.RemoteName)
+	} else {
+		cmds = append(cmds, o.RemoteURL)
+	}
+	// Review branch is rewritten when commits are amended.
+	cmds = append(cmds, fmt.Sprintf("+%s%s:%s%s",
+		config.RefsHeads,
+		strings.TrimPrefix(o.LocalBranch, config.RefsHeads),
+		config.RefsHeads,
+		branch))
+
+	cmd := GitPushCommand{}
+	cmd.Cmd = cmds[0]
+	cmd.Args = cmds[1:]
+	return &cmd, nil
+}
+
+// GetDownloadRef returns reference name of the specific code review.
+func (v PullRequestProtoHelper) GetDownloadRef(cr, patch string) (string, error) {
+	if _, err := strconv.Atoi(cr); err != nil {
+		return "", fmt.Errorf("bad review ID %s: %s", cr, err)
+	}
+	if v.protoType == ProtoTypeGitLab {
+		return "refs/merge-requests/" + cr + "/head", nil
+	}
+	return "refs/pull/" + cr + "/head", nil
+}
+
+// apiRootURL returns root URL of REST API for remote.
+func (v PullRequestProtoHelper) apiRootURL(u *config.GitURL) string {
+	if v.apiURL != "" {
+		return v.apiURL
+	}
+	if v.protoType == ProtoTypeGitHub && strings.ToLower(u.Host) == "github.com" {
+		return "https://api.github.com"
+	}
+
+	root := "https://" + u.Host
+	if u.IsHTTP() {
+		root = u.Proto + "://" + u.Host
+		if u.Port > 0 && u.Port != 80 && u.Port != 443 {
+			root += fmt.Sprintf(":%d", u.Port)
+		}
+	}
+	if v.protoType == ProtoTypeGitLab {
+		return root + "/api/v4"
+	}
+	// API of GitHub Enterprise.
+	return root + "/api/v3"
+}
+
+// apiAuth returns credential for REST API. Token in $GITHUB_TOKEN or
+// $GITLAB_TOKEN is used if no credential is set for the API host.
+func (v PullRequestProtoHelper) apiAuth(apiURL string) *HTTPAuth {
+	auth, err := GetHTTPAuth(apiURL)
+	if err == nil && auth != nil {
+		return auth
+	}
+	token := os.Getenv(strings.ToUpper(v.protoType) + "_TOKEN")
+	if token == "" {
+		return nil
+	}
+	return &HTTPAuth{Method: HTTPAuthToken, Token: token}
+}
+
+// CreateReview opens pull request (merge request) for the pushed topic
+// branch, and returns its URL. URL of the existing one is returned if
+// the topic branch is already under review.
+func (v PullRequestProtoHelper) CreateReview(o *config.UploadOptions) (string, error) {
+	u := config.ParseGitURL(o.RemoteURL)
+	if u == nil || u.Repo == "" {
+		return "", fmt.Errorf("bad review url: %s", o.RemoteURL)
+	}
+	repo := strings.TrimSuffix(strings.Trim(u.Repo, "/"), ".git")
+	apiURL := v.apiRootURL(u)
+	client := NewHTTPClient(apiURL, &HTTPClientOptions{NoCertChecks: o.NoCertChecks})
+	auth := v.apiAuth(apiURL)
+	head := reviewBranch(o)
+	base := strings.TrimPrefix(o.DestBranch, config.RefsHeads)
+
+	if v.protoType == ProtoTypeGitLab {
+		return v.createMergeRequest(client, auth, apiURL, repo, head, base, o)
+	}
+	return v.createPullRequest(client, auth, apiURL, repo, head, base, o)
+}
+
+func (v PullRequestProtoHelper) createPullRequest(client *http.Client, auth *HTTPAuth, apiURL, repo, head, base string, o *config.UploadOptions) (string, error) {
+	type pullRequest struct {
+		Number  int    `json:"number,omitempty"`
+		HTMLURL string `json:"html_url,omitempty"`
+		Title   string `json:"title,omitempty"`
+		Body    string `json:"body,omitempty"`
+		Head    string `json:"head,omitempty"`
+		Base    string `json:"base,omitempty"`
+		Draft   bool   `json:"draft,omitempty"`
+	}
+
+	var (
+		pulls    []pullRequest
+		endpoint = apiURL + "/repos/" + repo + "/pulls"
+		owner    = strings.SplitN(repo, "/", 2)[0]
+	)
+
+	params := url.Values{}
+	params.Set("state", "open")
+	params.Set("head", owner+":"+head)
+	params.Set("base", base)
+	err := CallJSONAPI(client, auth, http.MethodGet, endpoint+"?"+params.Encode(), nil, &pulls)
+	if err != nil {
+		return "", err
+	}
+	if len(pulls) > 0 {
+		return pulls[0].HTMLURL, nil
+	}
+
+	pull := pullRequest{}
+	err = CallJSONAPI(client, auth, http.MethodPost, endpoint, &pullRequest{
+		Title: o.Title,
+		Body:  o.Description,
+		Head:  head,
+		Base:  base,
+		Draft: o.Draft || o.WIP,
+	}, &pull)
+	if err != nil {
+		return "", err
+	}
+	return pull.HTMLURL, nil
+}
+
+func (v PullRequestProtoHelper) createMergeRequest(client *http.Client, auth *HTTPAuth, apiURL, repo, head, base string, o *config.UploadOptions) (string, error) {
+	type mergeRequest struct {
+		IID          int    `json:"iid,omitempty"`
+		WebURL       string `json:"web_url,omitempty"`
+		Title        string `json:"title,omitempty"`
+		Description  string `json:"description,omitempty"`
+		SourceBranch string `json:"source_branch,omitempty"`
+		TargetBranch string `json:"target_branch,omitempty"`
+	}
+
+	var (
+		mrs      []mergeRequest
+		endpoint = apiURL + "/projects/" + url.PathEscape(repo) + "/merge_requests"
+	)
+
+	params := url.Values{}
+	params.Set("state", "opened")
+	params.Set("source_branch", head)
+	params.Set("target_branch", base)
+	err := CallJSONAPI(client, auth, http.MethodGet, endpoint+"?"+params.Encode(), nil, &mrs)
+	if err != nil {
+		return "", err
+	}
+	if len(mrs) > 0 {
+		return mrs[0].WebURL, nil
+	}
+
+	title := o.Title
+	if o.Draft || o.WIP {
+		title = "Draft: " + title
+	}
+	mr := mergeRequest{}
+	err = CallJSONAPI(client, auth, http.MethodPost, endpoint, &mergeRequest{
+		Title:        title,
+		Description:  o.Description,
+		SourceBranch: head,
+		TargetBranch: base,
+	}, &mr)
+	if err != nil {
+		return "", err
+	}
+	return mr.WebURL, nil
+}
@@ -0,0 +1,145 @@
+package helper
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProtoTypeFromURL(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(ProtoTypeGitHub, ProtoTypeFromURL("https://github.com/jiangxin/app.git"))
+	assert.Equal(ProtoTypeGitHub, ProtoTypeFromURL("git@github.com:jiangxin/app.git"))
+	assert.Equal(ProtoTypeGitHub, ProtoTypeFromURL("https://github.example.com/jiangxin/app.git"))
+	assert.Equal(ProtoTypeGitLab, ProtoTypeFromURL("ssh://git@gitlab.com/jiangxin/app.git"))
+	assert.Equal(ProtoTypeGitLab, ProtoTypeFromURL("https://gitlab.example.com/"))
+	assert.Equal("", ProtoTypeFromURL("https://example.com/jiangxin/app.git"))
+	assert.Equal("", ProtoTypeFromURL(""))
+}
+
+func TestPullRequestGetGitPushCommand(t *testing.T) {
+	assert := assert.New(t)
+
+	h := NewPullRequestProtoHelper(&SSHInfo{}, ProtoTypeGitHub)
+	assert.Equal(ProtoTypeGitHub, h.GetType())
+
+	o := config.UploadOptions{
+		RemoteName:  "origin",
+		RemoteURL:   "https://github.com/jiangxin/app.git",
+		DestBranch:  "master",
+		LocalBranch: "refs/heads/my/topic",
+	}
+	cmd, err := h.GetGitPushCommand(&o)
+	assert.Nil(err)
+	assert.Equal("git", cmd.Cmd)
+	assert.Equal([]string{"push", "origin", "+refs/heads/my/topic:refs/heads/my/topic"},
+		cmd.Args)
+
+	o.Topic = "feature"
+	o.PushOptions = []string{"ci.skip"}
+	cmd, err = h.GetGitPushCommand(&o)
+	assert.Nil(err)
+	assert.Equal([]string{"push", "-o", "ci.skip", "origin", "+refs/heads/my/topic:refs/heads/feature"},
+		cmd.Args)
+
+	o.DestBranch = ""
+	_, err = h.GetGitPushCommand(&o)
+	assert.NotNil(err)
+}
+
+func TestPullRequestGetDownloadRef(t *testing.T) {
+	assert := assert.New(t)
+
+	ref, err := NewPullRequestProtoHelper(nil, ProtoTypeGitHub).GetDownloadRef("12", "")
+	assert.Nil(err)
+	assert.Equal("refs/pull/12/head", ref)
+
+	ref, err = NewPullRequestProtoHelper(nil, ProtoTypeGitLab).GetDownloadRef("12", "")
+	assert.Nil(err)
+	assert.Equal("refs/merge-requests/12/head", ref)
+
+	_, err = NewPullRequestProtoHelper(nil, ProtoTypeGitLab).GetDownloadRef("abc", "")
+	assert.NotNil(err)
+}
+
+func TestPullRequestCreateReview(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		input   map[string]interface{}
+		existed bool
+	)
+
+	os.Unsetenv("GITHUB_TOKEN")
+	os.Unsetenv("GITLAB_TOKEN")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/jiangxin/app/pulls":
+			assert.Equal("jiangxin:my/topic", r.URL.Query().Get("head"))
+			assert.Equal("master", r.URL.Query().Get("base"))
+			if existed {
+				w.Write([]byte(`[{"number": 1, "html_url": "https://github.com/jiangxin/app/pull/1"}]`))
+			} else {
+				w.Write([]byte(`[]`))
+			}
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/jiangxin/app/pulls":
+			data, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(data, &input)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"number": 2, "html_url": "https://github.com/jiangxin/app/pull/2"}`))
+		case r.Method == http.MethodGet && r.URL.EscapedPath() == "/projects/jiangxin%2Fapp/merge_requests":
+			assert.Equal("my/topic", r.URL.Query().Get("source_branch"))
+			w.Write([]byte(`[]`))
+		case r.Method == http.MethodPost && r.URL.EscapedPath() == "/projects/jiangxin%2Fapp/merge_requests":
+			data, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(data, &input)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"iid": 3, "web_url": "https://gitlab.com/jiangxin/app/-/merge_requests/3"}`))
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	o := config.UploadOptions{
+		RemoteURL:   "https://github.com/jiangxin/app.git",
+		DestBranch:  "refs/heads/master",
+		LocalBranch: "refs/heads/my/topic",
+		Title:       "hack",
+		Description: "details",
+		Draft:       true,
+	}
+
+	h := NewPullRequestProtoHelper(nil, ProtoTypeGitHub)
+	h.apiURL = server.URL
+	reviewURL, err := h.CreateReview(&o)
+	assert.Nil(err)
+	assert.Equal("https://github.com/jiangxin/app/pull/2", reviewURL)
+	assert.Equal("hack", input["title"])
+	assert.Equal("details", input["body"])
+	assert.Equal("my/topic", input["head"])
+	assert.Equal("master", input["base"])
+	assert.Equal(true, input["draft"])
+
+	existed = true
+	reviewURL, err = h.CreateReview(&o)
+	assert.Nil(err)
+	assert.Equal("https://github.com/jiangxin/app/pull/1", reviewURL)
+
+	o.RemoteURL = "https://gitlab.com/jiangxin/app.git"
+	h = NewPullRequestProtoHelper(nil, ProtoTypeGitLab)
+	h.apiURL = server.URL
+	reviewURL, err = h.CreateReview(&o)
+	assert.Nil(err)
+	assert.Equal("https://gitlab.com/jiangxin/app/-/merge_requests/3", reviewURL)
+	assert.Equal("Draft: hack", input["title"])
+	assert.Equal("my/topic", input["source_branch"])
+	assert.Equal("master", input["target_branch"])
+}
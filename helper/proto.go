@@ -17,6 +17,7 @@ package helper
 import (
 	"encoding/json"
 	"os"
+	"regexp"
 	"strings"
 
 	"github.com/alibaba/git-repo-go/config"
@@ -26,6 +27,18 @@ import (
 const (
 	ProtoTypeAGit   = "agit"
 	ProtoTypeGerrit = "gerrit"
+	ProtoTypeGitHub = "github"
+	ProtoTypeGitLab = "gitlab"
+	// ProtoTypeNone disables upload and download of code reviews.
+	ProtoTypeNone = "none"
+)
+
+var (
+	reRemoteURL = regexp.MustCompile(`^remote:\s+(https?://\S+)`)
+	// Number of code review in push messages, such as "remote: Change
+	// 123 is created", "remote: change-number: 123" or "Merge request
+	// #12 is created".
+	reRemoteChange = regexp.MustCompile(`(?i)^remote:.*\b(?:change|change[ -]number|request|review)[ :]+#?([0-9]+)\b`)
 )
 
 // GitPushCommand holds command and args for git command.
@@ -51,12 +64,26 @@ func NewProtoHelper(sshInfo *SSHInfo) ProtoHelper {
 		return NewAGitProtoHelper(sshInfo)
 	case ProtoTypeGerrit:
 		return NewGerritProtoHelper(sshInfo)
-	case "":
+	case ProtoTypeGitHub, ProtoTypeGitLab:
+		return NewPullRequestProtoHelper(sshInfo, strings.ToLower(sshInfo.ProtoType))
+	case "", ProtoTypeNone:
 		return NewDefaultProtoHelper(sshInfo)
 	}
 	return NewExternalProtoHelper(sshInfo)
 }
 
+// ParseReviewChange finds number of code review from messages sent back
+// by the server during git push. Returns empty string if not found.
+func ParseReviewChange(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(strings.TrimRight(line, "\r"))
+		if m := reRemoteChange.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
 // GetGitPushCommandPipe reads JSON from STDIN, pipe it to the helper, and
 // output the result in JSON.
 func GetGitPushCommandPipe(proto ProtoHelper) ([]byte, error) {
@@ -77,3 +104,25 @@ func GetGitPushCommandPipe(proto ProtoHelper) ([]byte, error) {
 	}
 	return json.MarshalIndent(&cmd, "", "\t")
 }
+
+// ParseReviewURLs finds URLs of code reviews from messages sent back by
+// the server during git push, such as:
+//
+//	remote:   https://example.com/c/project/+/123 subject [NEW]
+func ParseReviewURLs(output string) []string {
+	var (
+		urls []string
+		seen = make(map[string]bool)
+	)
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(strings.TrimRight(line, "\r"))
+		m := reRemoteURL.FindStringSubmatch(line)
+		if m == nil || seen[m[1]] {
+			continue
+		}
+		seen[m[1]] = true
+		urls = append(urls, m[1])
+	}
+	return urls
+}
@@ -0,0 +1,76 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAGitPushTopic(t *testing.T) {
+	assert := assert.New(t)
+
+	o := config.UploadOptions{
+		RemoteURL:   "ssh://git@example.com/main.git",
+		LocalBranch: "my/topic",
+		DestBranch:  "master",
+		Topic:       "my-topic",
+	}
+	proto := NewAGitProtoHelper(&SSHInfo{ProtoType: ProtoTypeAGit, ProtoVersion: 2})
+	cmd, err := proto.GetGitPushCommand(&o)
+	assert.Nil(err)
+	assert.Contains(cmd.Args, "topic=my-topic")
+
+	// Topic of non-ASCII is base64 encoded, like title.
+	o.Topic = "主题"
+	cmd, err = proto.GetGitPushCommand(&o)
+	assert.Nil(err)
+	assert.Contains(cmd.Args, "topic={base64}5Li76aKY")
+}
+
+func TestParseReviewURLs(t *testing.T) {
+	assert := assert.New(t)
+
+	output := `Enumerating objects: 5, done.
+Writing objects: 100% (3/3), 270 bytes | 270.00 KiB/s, done.
+remote: Processing changes: refs: 1, new: 1, done
+remote:
+remote: New Changes:
+remote:   https://example.com/c/main/+/123 New topic [NEW]
+remote:   https://example.com/c/main/+/123 New topic [NEW]
+remote:
+To ssh://example.com:29418/main.git
+ * [new reference]   my/topic -> refs/for/master`
+	assert.Equal([]string{"https://example.com/c/main/+/123"},
+		ParseReviewURLs(output))
+
+	output = "remote: ====\r\nremote: Merge request #12 is created, visit:\r\n" +
+		"remote:     http://example.com/jiangxin/main/merge_requests/12\r\n"
+	assert.Equal([]string{"http://example.com/jiangxin/main/merge_requests/12"},
+		ParseReviewURLs(output))
+
+	assert.Nil(ParseReviewURLs("Everything up-to-date"))
+}
+
+func TestParseReviewChange(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("12", ParseReviewChange("remote: ====\r\n"+
+		"remote: Merge request #12 is created, visit:\r\n"))
+	assert.Equal("123", ParseReviewChange("remote: Processing changes: refs: 1, new: 1, done\n"+
+		"remote: Change 123 is created\n"))
+	assert.Equal("45", ParseReviewChange("remote: change-number: 45\n"))
+	assert.Equal("", ParseReviewChange("remote: Processing changes: refs: 1, new: 1, done\n"+
+		"To ssh://example.com:29418/main.git\n"))
+}
+
+func TestNewProtoHelper(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(ProtoTypeGerrit, NewProtoHelper(&SSHInfo{ProtoType: "Gerrit"}).GetType())
+	assert.Equal(ProtoTypeAGit, NewProtoHelper(&SSHInfo{ProtoType: ProtoTypeAGit}).GetType())
+	assert.Equal(ProtoTypeGitLab, NewProtoHelper(&SSHInfo{ProtoType: ProtoTypeGitLab}).GetType())
+	// Type "none" disables upload and download.
+	assert.Equal("", NewProtoHelper(&SSHInfo{ProtoType: ProtoTypeNone}).GetType())
+	assert.Equal("", NewProtoHelper(&SSHInfo{}).GetType())
+}
@@ -0,0 +1,236 @@
+package helper
+
+import (
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+)
+
+// Default settings of retry policy and connections per host.
+const (
+	DefaultRetryMaxAttempts = 3
+	DefaultRetryDelay       = 1 * time.Second
+	DefaultRetryMaxDelay    = 30 * time.Second
+	DefaultRetryJitter      = 0.25
+	DefaultMaxConnections   = 8
+)
+
+var (
+	httpStatusPattern = regexp.MustCompile(`(?i)(?:returned error:|http) ([0-9]{3})\b`)
+
+	// Messages of transient errors from git-remote-http(s) and ssh.
+	transientGitErrors = []string{
+		"early eof",
+		"rpc failed",
+		"the remote end hung up unexpectedly",
+		"connection reset",
+		"connection refused",
+		"connection timed out",
+		"operation timed out",
+		"connection closed by",
+		"could not resolve host",
+		"temporary failure in name resolution",
+		"ssh_exchange_identification",
+		"kex_exchange_identification",
+		"broken pipe",
+	}
+
+	// Messages which indicate retry is useless.
+	permanentGitErrors = []string{
+		"permission denied",
+		"authentication failed",
+		"repository not found",
+		"does not appear to be a git repository",
+	}
+
+	hostLimiter     *HostLimiter
+	hostLimiterOnce sync.Once
+)
+
+// RetryableError wraps an error which may succeed by trying again.
+type RetryableError struct {
+	Err error
+}
+
+// Error implements error interface.
+func (v RetryableError) Error() string {
+	return v.Err.Error()
+}
+
+// NewRetryableError marks err as retryable.
+func NewRetryableError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &RetryableError{Err: err}
+}
+
+// IsRetryableStatus indicates whether HTTP status code is worth retrying,
+// such as 429 (too many requests) and 5xx.
+func IsRetryableStatus(code int) bool {
+	return code == 429 || (code >= 500 && code < 600 && code != 501)
+}
+
+// IsTransientGitError checks error output of git command, and returns
+// true if it failed because of rate limit or network problems.
+func IsTransientGitError(stderr string) bool {
+	msg := strings.ToLower(stderr)
+	for _, s := range permanentGitErrors {
+		if strings.Contains(msg, s) {
+			return false
+		}
+	}
+	for _, m := range httpStatusPattern.FindAllStringSubmatch(msg, -1) {
+		if code, err := strconv.Atoi(m[1]); err == nil && IsRetryableStatus(code) {
+			return true
+		}
+	}
+	for _, s := range transientGitErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryPolicy defines how to retry network operations, using
+// exponential backoff with jitter. Only idempotent operations, such as
+// fetch, are retried, and push is never retried.
+type RetryPolicy struct {
+	MaxAttempts int
+	Delay       time.Duration
+	MaxDelay    time.Duration
+	Jitter      float64
+
+	sleep func(time.Duration)
+}
+
+// configDuration reads duration from git config, such as "500ms", "2s",
+// or a number in seconds.
+func configDuration(key string, defaultValue time.Duration) time.Duration {
+	value := config.GitDefaultConfig.Get(key)
+	if value == "" {
+		return defaultValue
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		return time.Duration(n) * time.Second
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Warnf("bad duration '%s' for config %s", value, key)
+		return defaultValue
+	}
+	return d
+}
+
+// NewRetryPolicy creates retry policy from git config:
+//
+//   - repo.retry.maxAttempts: max attempts, 1 to disable retry
+//   - repo.retry.delay: delay before the first retry
+//   - repo.retry.maxDelay: upper limit of delay
+func NewRetryPolicy() *RetryPolicy {
+	maxAttempts := config.GitDefaultConfig.GetInt(config.CfgRepoRetryMaxAttempts,
+		DefaultRetryMaxAttempts)
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &RetryPolicy{
+		MaxAttempts: maxAttempts,
+		Delay:       configDuration(config.CfgRepoRetryDelay, DefaultRetryDelay),
+		MaxDelay:    configDuration(config.CfgRepoRetryMaxDelay, DefaultRetryMaxDelay),
+		Jitter:      DefaultRetryJitter,
+	}
+}
+
+// Backoff returns delay after the n-th failed attempt (starts from 1).
+func (v *RetryPolicy) Backoff(n int) time.Duration {
+	d := v.Delay
+	for i := 1; i < n && d < v.MaxDelay; i++ {
+		d *= 2
+	}
+	if v.MaxDelay > 0 && d > v.MaxDelay {
+		d = v.MaxDelay
+	}
+	if v.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * v.Jitter * float64(d))
+	}
+	return d
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, or
+// reaches max attempts.
+func (v *RetryPolicy) Do(fn func() error) error {
+	var err error
+
+	sleep := v.sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+	for n := 1; ; n++ {
+		err = fn()
+		retryErr, ok := err.(*RetryableError)
+		if !ok {
+			return err
+		}
+		if n >= v.MaxAttempts {
+			return retryErr.Err
+		}
+		d := v.Backoff(n)
+		log.Warnf("attempt %d of %d failed, retry in %s: %s",
+			n, v.MaxAttempts, d.Round(time.Millisecond), retryErr.Err)
+		sleep(d)
+	}
+}
+
+// HostLimiter caps concurrent connections to the same host.
+type HostLimiter struct {
+	max   int
+	slots map[string]chan struct{}
+	mutex sync.Mutex
+}
+
+// NewHostLimiter creates HostLimiter, and max <= 0 means no limit.
+func NewHostLimiter(max int) *HostLimiter {
+	return &HostLimiter{
+		max:   max,
+		slots: make(map[string]chan struct{}),
+	}
+}
+
+// Acquire waits for a free slot of the host of address, and returns a
+// function to release the slot.
+func (v *HostLimiter) Acquire(address string) func() {
+	u := config.ParseGitURL(address)
+	if v == nil || v.max <= 0 || u == nil || u.IsLocal() || u.Host == "" {
+		return func() {}
+	}
+
+	v.mutex.Lock()
+	slot, ok := v.slots[u.Host]
+	if !ok {
+		slot = make(chan struct{}, v.max)
+		v.slots[u.Host] = slot
+	}
+	v.mutex.Unlock()
+
+	slot <- struct{}{}
+	return func() { <-slot }
+}
+
+// AcquireHost acquires a slot of the host of address from the default
+// HostLimiter, which allows "repo.maxConnectionsPerHost" connections to
+// each host.
+func AcquireHost(address string) func() {
+	hostLimiterOnce.Do(func() {
+		hostLimiter = NewHostLimiter(config.GitDefaultConfig.GetInt(
+			config.CfgRepoMaxConnections,
+			DefaultMaxConnections))
+	})
+	return hostLimiter.Acquire(address)
+}
@@ -0,0 +1,141 @@
+package helper
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTransientGitError(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(IsTransientGitError("error: RPC failed; HTTP 503 curl 22 The requested URL returned error: 503"))
+	assert.True(IsTransientGitError("fatal: unable to access 'https://example.com/a.git/': The requested URL returned error: 429"))
+	assert.True(IsTransientGitError("ssh: connect to host example.com port 29418: Connection timed out\nfatal: Could not read from remote repository."))
+	assert.True(IsTransientGitError("fetch-pack: unexpected disconnect while reading sideband packet\nfatal: early EOF"))
+	assert.False(IsTransientGitError("fatal: The requested URL returned error: 404"))
+	assert.False(IsTransientGitError("fatal: unable to access 'https://example.com/a.git/': The requested URL returned error: 501"))
+	assert.False(IsTransientGitError("git@example.com: Permission denied (publickey).\nfatal: the remote end hung up unexpectedly"))
+	assert.False(IsTransientGitError(" ! [remote rejected] HEAD -> refs/for/master (no new changes)"))
+
+	assert.True(IsRetryableStatus(429))
+	assert.True(IsRetryableStatus(502))
+	assert.False(IsRetryableStatus(501))
+	assert.False(IsRetryableStatus(404))
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	assert := assert.New(t)
+
+	policy := RetryPolicy{
+		MaxAttempts: 5,
+		Delay:       time.Second,
+		MaxDelay:    5 * time.Second,
+	}
+	assert.Equal(1*time.Second, policy.Backoff(1))
+	assert.Equal(2*time.Second, policy.Backoff(2))
+	assert.Equal(4*time.Second, policy.Backoff(3))
+	assert.Equal(5*time.Second, policy.Backoff(4))
+	assert.Equal(5*time.Second, policy.Backoff(10))
+
+	policy.Jitter = 0.25
+	for i := 0; i < 10; i++ {
+		d := policy.Backoff(2)
+		assert.True(d >= 1500*time.Millisecond && d <= 2500*time.Millisecond)
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		delays []time.Duration
+		n      int
+	)
+
+	policy := RetryPolicy{
+		MaxAttempts: 3,
+		Delay:       time.Second,
+		MaxDelay:    time.Minute,
+		sleep:       func(d time.Duration) { delays = append(delays, d) },
+	}
+
+	// Succeed after retry.
+	err := policy.Do(func() error {
+		n++
+		if n < 3 {
+			return NewRetryableError(errors.New("timeout"))
+		}
+		return nil
+	})
+	assert.Nil(err)
+	assert.Equal(3, n)
+	assert.Equal([]time.Duration{time.Second, 2 * time.Second}, delays)
+
+	// Reach max attempts, and returns the wrapped error.
+	n = 0
+	err = policy.Do(func() error {
+		n++
+		return NewRetryableError(errors.New("timeout"))
+	})
+	assert.Equal("timeout", err.Error())
+	assert.Equal(3, n)
+
+	// Not retryable.
+	n = 0
+	err = policy.Do(func() error {
+		n++
+		return errors.New("not found")
+	})
+	assert.Equal("not found", err.Error())
+	assert.Equal(1, n)
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	policy := NewRetryPolicy()
+	assert.Equal(DefaultRetryMaxAttempts, policy.MaxAttempts)
+	assert.Equal(DefaultRetryDelay, policy.Delay)
+
+	config.GitDefaultConfig.Set(config.CfgRepoRetryMaxAttempts, "5")
+	config.GitDefaultConfig.Set(config.CfgRepoRetryDelay, "500ms")
+	config.GitDefaultConfig.Set(config.CfgRepoRetryMaxDelay, "10")
+	defer func() {
+		config.GitDefaultConfig.Unset(config.CfgRepoRetryMaxAttempts)
+		config.GitDefaultConfig.Unset(config.CfgRepoRetryDelay)
+		config.GitDefaultConfig.Unset(config.CfgRepoRetryMaxDelay)
+	}()
+	policy = NewRetryPolicy()
+	assert.Equal(5, policy.MaxAttempts)
+	assert.Equal(500*time.Millisecond, policy.Delay)
+	assert.Equal(10*time.Second, policy.MaxDelay)
+}
+
+func TestHostLimiter(t *testing.T) {
+	assert := assert.New(t)
+
+	limiter := NewHostLimiter(1)
+	release := limiter.Acquire("https://example.com/a.git")
+
+	acquired := make(chan bool)
+	go func() {
+		r := limiter.Acquire("ssh://git@example.com/b.git")
+		acquired <- true
+		r()
+	}()
+
+	// Other host and local path are not blocked.
+	limiter.Acquire("https://other.example.com/a.git")()
+	limiter.Acquire("/path/to/a.git")()
+
+	select {
+	case <-acquired:
+		t.Fatal("should wait for free slot of the same host")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	assert.True(<-acquired)
+}
@@ -2,6 +2,7 @@ package helper
 
 import (
 	"bytes"
+	"os"
 	"regexp"
 	"strings"
 	"unicode"
@@ -130,3 +131,17 @@ func NewShellCmd(cmd string, withArgs bool) *ShellCmd {
 	}
 	return &shellCmd
 }
+
+// setenv sets environment of name to value, and returns a function to
+// restore the previous value.
+func setenv(name, value string) func() {
+	old, ok := os.LookupEnv(name)
+	os.Setenv(name, value)
+	return func() {
+		if ok {
+			os.Setenv(name, old)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}
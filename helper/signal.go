@@ -0,0 +1,65 @@
+package helper
+
+import (
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+
+	"github.com/alibaba/git-repo-go/log"
+)
+
+var (
+	signalOnce     sync.Once
+	signalMutex    sync.Mutex
+	signalCleanups = make(map[int]func())
+	signalNextID   = 0
+)
+
+// OnSignal registers cleanup which runs before exit if interrupted by
+// signal, and returns a function to unregister it. The signal handler is
+// installed only once, and runs cleanups in reverse order of registration.
+func OnSignal(cleanup func()) func() {
+	signalOnce.Do(func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			sig := <-c
+			runSignalCleanups()
+			log.Errorf("interrupted by signal: %s", sig)
+			os.Exit(1)
+		}()
+	})
+
+	signalMutex.Lock()
+	defer signalMutex.Unlock()
+	id := signalNextID
+	signalNextID++
+	signalCleanups[id] = cleanup
+	return func() {
+		signalMutex.Lock()
+		delete(signalCleanups, id)
+		signalMutex.Unlock()
+	}
+}
+
+// runSignalCleanups runs and unregisters all cleanups, newest first.
+func runSignalCleanups() {
+	signalMutex.Lock()
+	ids := []int{}
+	for id := range signalCleanups {
+		ids = append(ids, id)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	cleanups := []func(){}
+	for _, id := range ids {
+		cleanups = append(cleanups, signalCleanups[id])
+	}
+	signalCleanups = make(map[int]func())
+	signalMutex.Unlock()
+
+	for _, cleanup := range cleanups {
+		cleanup()
+	}
+}
@@ -4,12 +4,11 @@ import (
 	"bufio"
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"regexp"
@@ -19,9 +18,9 @@ import (
 	"time"
 
 	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/path"
 	"github.com/jiangxin/goconfig"
-	log "github.com/jiangxin/multi-log"
 	"gopkg.in/h2non/gock.v1"
 )
 
@@ -33,9 +32,19 @@ const (
 )
 
 var (
-	sshInfoPattern = regexp.MustCompile(`^[\S]+ [0-9]+$`)
-	httpClient     *http.Client
-	internalCache  = sync.Map{}
+	sshInfoPattern    = regexp.MustCompile(`^[\S]+ [0-9]+$`)
+	capabilityPattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+	// uploadCapabilities are capabilities advertised by server in
+	// ssh_info for options of upload.
+	uploadCapabilities = map[string]bool{
+		"private":        true,
+		"ready":          true,
+		"remove-private": true,
+		"wip":            true,
+	}
+	httpClients   = sync.Map{}
+	internalCache = sync.Map{}
 )
 
 // SSHInfo stands for Smart Submit Handler information. Hold data returned from ssh_info API
@@ -59,9 +68,70 @@ type SSHInfo struct {
 	// Macro {id}, {patch}, {id:left:N}, {id:right:N} can be used in this pattern.
 	ReviewRefPattern string `json:"review_ref,omitempty"`
 
+	// Capabilities are options of upload supported by server, such as
+	// "wip", "private", "ready" and "remove-private". If server does not
+	// advertise capabilities, all options are sent to server.
+	Capabilities []string `json:"capabilities,omitempty"`
+
 	Expire int64 `json:"-"`
 }
 
+// validateCapabilities checks capabilities advertised by server. Malformed
+// capabilities are dropped, and unknown ones are warned and ignored.
+func (v *SSHInfo) validateCapabilities() {
+	if len(v.Capabilities) == 0 {
+		return
+	}
+	caps := []string{}
+	for _, c := range v.Capabilities {
+		if !capabilityPattern.MatchString(c) {
+			log.Warnf("ssh_info: ignore malformed capability '%s'", c)
+			continue
+		}
+		if !uploadCapabilities[c] {
+			log.Warnf("ssh_info: unknown capability '%s'", c)
+		}
+		caps = append(caps, c)
+	}
+	if len(caps) == 0 {
+		caps = nil
+	}
+	v.Capabilities = caps
+}
+
+// HasCapability checks whether server supports capability. Returns true
+// if server does not advertise capabilities.
+func (v SSHInfo) HasCapability(name string) bool {
+	if v.Capabilities == nil {
+		return true
+	}
+	for _, c := range v.Capabilities {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckUploadOptions returns error if any option of upload is not
+// supported by server.
+func (v SSHInfo) CheckUploadOptions(o *config.UploadOptions) error {
+	for _, item := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"wip", o.WIP},
+		{"private", o.Private},
+		{"ready", o.Ready},
+		{"remove-private", o.RemovePrivate},
+	} {
+		if item.enabled && !v.HasCapability(item.name) {
+			return fmt.Errorf("--%s is not supported by server", item.name)
+		}
+	}
+	return nil
+}
+
 // ToJSON encodes ssh_info to JSON.
 func (v SSHInfo) ToJSON() string {
 	buf, err := json.Marshal(&v)
@@ -154,7 +224,11 @@ func (v SSHInfoQuery) GetSSHInfo(address string, useCache bool) (*SSHInfo, error
 	if v.CacheFile != "" && v.cfg != nil {
 		path.SafeCreateParentDir(v.CacheFile)
 		data := sshInfo.ToJSON()
-		expireTime := time.Now().Add(time.Second * sshInfoCacheDefaultExpire).Format(expireTimeLayout)
+		expire := config.GetSSHInfoCacheExpire()
+		if expire == 0 {
+			expire = time.Second * sshInfoCacheDefaultExpire
+		}
+		expireTime := time.Now().Add(expire).Format(expireTimeLayout)
 		v.cfg.Set(fmt.Sprintf(config.CfgManifestRemoteSSHInfo, key), data)
 		v.cfg.Set(fmt.Sprintf(config.CfgManifestRemoteExpire, key), expireTime)
 		v.cfg.Save(v.CacheFile)
@@ -251,8 +325,13 @@ func sshInfoFromAPI(url *config.GitURL) (*SSHInfo, error) {
 		return nil, fmt.Errorf("bad ssh_info access to '%s': %s", infoURL, err)
 	}
 	req.Header.Set("Accept", "application/json")
+	if auth, err := GetHTTPAuth(infoURL); err != nil {
+		log.Debugf("fail to get credential for '%s': %s", infoURL, err)
+	} else {
+		auth.SetRequestAuth(req)
+	}
 
-	client := getHTTPClient()
+	client := getHTTPClient(infoURL)
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -389,47 +468,30 @@ func sshInfoFromString(data string) (*SSHInfo, error) {
 	if err != nil {
 		return nil, err
 	}
+	sshInfo.validateCapabilities()
 	return &sshInfo, nil
 }
 
-func getHTTPClient() *http.Client {
-	if httpClient != nil {
-		return httpClient
+func getHTTPClient(address string) *http.Client {
+	key := address
+	if u, err := url.Parse(address); err == nil {
+		key = u.Scheme + "://" + u.Host
 	}
-
-	skipSSLVerify := config.NoCertChecks()
-
-	tr := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   remoteCallTimeout * time.Second,
-			KeepAlive: remoteCallTimeout * time.Second,
-		}).DialContext,
-		TLSClientConfig:       &tls.Config{InsecureSkipVerify: skipSSLVerify},
-		TLSHandshakeTimeout:   remoteCallTimeout * time.Second,
-		ResponseHeaderTimeout: remoteCallTimeout * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-		MaxIdleConns:          10,
-		IdleConnTimeout:       remoteCallTimeout * time.Second,
-		DisableCompression:    true,
-		Proxy:                 http.ProxyFromEnvironment,
-	}
-
-	// http.proxy overrides env $HTTP_PROXY, $HTTPS_PROXY and $NO_PROXY (or the lowercase versions thereof).
-	proxyURL, err := GetProxyFromGitConfig()
-	if err != nil {
-		log.Debugf("fail to get proxy from git config: %s", err)
-	} else {
-		tr.Proxy = http.ProxyURL(proxyURL)
+	if client, ok := httpClients.Load(key); ok {
+		return client.(*http.Client)
 	}
 
-	httpClient = &http.Client{Transport: tr}
+	client := NewHTTPClient(address, &HTTPClientOptions{
+		Timeout: remoteCallTimeout * time.Second,
+	})
 
 	// Mock ssh_info API
 	if config.GetMockSSHInfoResponse() != "" || config.GetMockSSHInfoStatus() != 0 {
-		gock.InterceptClient(httpClient)
+		gock.InterceptClient(client)
 	}
 
-	return httpClient
+	httpClients.Store(key, client)
+	return client
 }
 
 func urlToKey(address string) string {
@@ -3,9 +3,33 @@ package helper
 import (
 	"testing"
 
+	"github.com/alibaba/git-repo-go/config"
 	"github.com/stretchr/testify/assert"
 )
 
+func TestSSHInfoCapabilities(t *testing.T) {
+	assert := assert.New(t)
+
+	sshInfo, err := sshInfoFromString(`{"type":"agit"}`)
+	assert.Nil(err)
+	assert.Nil(sshInfo.Capabilities)
+	assert.Nil(sshInfo.CheckUploadOptions(&config.UploadOptions{WIP: true, Private: true}))
+
+	sshInfo, err = sshInfoFromString(`{"type":"agit", "capabilities":["wip", "Bad Cap", "future"]}`)
+	assert.Nil(err)
+	assert.Equal([]string{"wip", "future"}, sshInfo.Capabilities)
+	assert.True(sshInfo.HasCapability("wip"))
+	assert.False(sshInfo.HasCapability("private"))
+	assert.Nil(sshInfo.CheckUploadOptions(&config.UploadOptions{WIP: true}))
+	assert.Equal("--private is not supported by server",
+		sshInfo.CheckUploadOptions(&config.UploadOptions{WIP: true, Private: true}).Error())
+
+	// All capabilities are malformed, as if not advertised.
+	sshInfo, err = sshInfoFromString(`{"type":"agit", "capabilities":["Bad Cap"]}`)
+	assert.Nil(err)
+	assert.Nil(sshInfo.Capabilities)
+}
+
 func TestGetReviewRef(t *testing.T) {
 	var (
 		ref string
@@ -0,0 +1,213 @@
+package helper
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+)
+
+const (
+	sshMasterStartTimeout = 1
+)
+
+// SSHMaster manages ssh master connections (ControlMaster), one for each
+// host, which are reused by ssh sessions of git-fetch, so that sync of
+// hundreds of projects will not do handshake again and again.
+type SSHMaster struct {
+	ssh     *SSHCmd
+	sockDir string
+	masters map[string]*exec.Cmd
+	failed  map[string]bool
+	mutex   sync.Mutex
+
+	restoreEnv func()
+	unregister func()
+}
+
+// NewSSHMaster creates SSHMaster. It is disabled if ssh program is not
+// OpenSSH, or running on Windows.
+func NewSSHMaster() *SSHMaster {
+	v := SSHMaster{
+		ssh:     NewSSHCmd(),
+		masters: make(map[string]*exec.Cmd),
+		failed:  make(map[string]bool),
+	}
+	if runtime.GOOS == "windows" || v.ssh.Variant() != SSHVariantSSH {
+		return &v
+	}
+	dir, err := ioutil.TempDir("", "git-repo-ssh-")
+	if err != nil {
+		log.Debugf("fail to create dir for ssh master sockets: %s", err)
+		return &v
+	}
+	v.sockDir = dir
+	return &v
+}
+
+// Enabled indicates ssh master connections can be used.
+func (v *SSHMaster) Enabled() bool {
+	return v != nil && v.sockDir != ""
+}
+
+func (v *SSHMaster) controlPath() string {
+	return filepath.Join(v.sockDir, "master-%r@%h:%p")
+}
+
+// Command returns ssh command, which is used as GIT_SSH_COMMAND to reuse
+// master connections.
+func (v *SSHMaster) Command() string {
+	cmd := ShellCmd{
+		Cmd:  v.ssh.SSH(),
+		Args: append(append([]string{}, v.ssh.Args()...), "-o", "ControlPath="+v.controlPath()),
+	}
+	return cmd.QuoteCommand()
+}
+
+// Setenv exports GIT_SSH_COMMAND, so that git commands will reuse master
+// connections. The previous value is restored by Close.
+func (v *SSHMaster) Setenv() {
+	if !v.Enabled() || v.restoreEnv != nil {
+		return
+	}
+	v.restoreEnv = setenv("GIT_SSH_COMMAND", v.Command())
+}
+
+func (v *SSHMaster) masterArgs(u *config.GitURL) []string {
+	args := []string{v.ssh.SSH()}
+	args = append(args, v.ssh.Args()...)
+	args = append(args,
+		"-o", "ControlPath="+v.controlPath(),
+		"-M",
+		"-N")
+	if u.Port > 0 && u.Port != 22 {
+		args = append(args, "-p", strconv.Itoa(u.Port))
+	}
+	return append(args, u.UserHost())
+}
+
+func (v *SSHMaster) controlArgs(u *config.GitURL, op string) []string {
+	args := []string{v.ssh.SSH()}
+	args = append(args, v.ssh.Args()...)
+	args = append(args,
+		"-o", "ControlPath="+v.controlPath(),
+		"-O", op)
+	if u.Port > 0 && u.Port != 22 {
+		args = append(args, "-p", strconv.Itoa(u.Port))
+	}
+	return append(args, u.UserHost())
+}
+
+func (v *SSHMaster) isRunning(u *config.GitURL) bool {
+	args := v.controlArgs(u, "check")
+	cmd := exec.Command(args[0], args[1:]...)
+	return cmd.Run() == nil
+}
+
+// Start starts master connection for host of address, if address is a
+// SSH URL and there is no master connection for the host yet. Returns
+// true if master connection is ready.
+func (v *SSHMaster) Start(address string) bool {
+	if !v.Enabled() {
+		return false
+	}
+	u := config.ParseGitURL(address)
+	if u == nil || !u.IsSSH() || u.Host == "" {
+		return false
+	}
+
+	key := fmt.Sprintf("%s:%d", u.UserHost(), u.Port)
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if _, ok := v.masters[key]; ok {
+		return true
+	}
+	if v.failed[key] {
+		return false
+	}
+	// Master connection may be started by ssh_config or others.
+	if v.isRunning(u) {
+		v.masters[key] = nil
+		return true
+	}
+
+	args := v.masterArgs(u)
+	log.Debugf("start ssh master: %v", args)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = nil
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+	if err := cmd.Start(); err != nil {
+		log.Debugf("fail to start ssh master for %s: %s", key, err)
+		v.failed[key] = true
+		return false
+	}
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	deadline := time.Now().Add(sshMasterStartTimeout * time.Second)
+	for time.Now().Before(deadline) {
+		select {
+		case <-exited:
+			log.Debugf("ssh master for %s exited", key)
+			v.failed[key] = true
+			return false
+		case <-time.After(100 * time.Millisecond):
+		}
+		if v.isRunning(u) {
+			v.masters[key] = cmd
+			return true
+		}
+	}
+	// Still connecting (may wait for passphrase), keep it for reuse.
+	v.masters[key] = cmd
+	return true
+}
+
+// Close stops all master connections started by us, removes the dir of
+// sockets, and restores GIT_SSH_COMMAND.
+func (v *SSHMaster) Close() {
+	if !v.Enabled() {
+		return
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.unregister != nil {
+		v.unregister()
+		v.unregister = nil
+	}
+	if v.restoreEnv != nil {
+		v.restoreEnv()
+		v.restoreEnv = nil
+	}
+	for key, cmd := range v.masters {
+		if cmd == nil || cmd.Process == nil {
+			continue
+		}
+		log.Debugf("stop ssh master for %s", key)
+		cmd.Process.Signal(syscall.SIGTERM)
+	}
+	v.masters = make(map[string]*exec.Cmd)
+	os.RemoveAll(v.sockDir)
+	v.sockDir = ""
+}
+
+// CloseOnSignal closes master connections if interrupted.
+func (v *SSHMaster) CloseOnSignal() {
+	if !v.Enabled() || v.unregister != nil {
+		return
+	}
+	v.unregister = OnSignal(v.Close)
+}
@@ -0,0 +1,77 @@
+package helper
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSHMasterCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ssh master is not supported on Windows")
+	}
+
+	assert := assert.New(t)
+	os.Unsetenv("GIT_SSH")
+	os.Unsetenv("GIT_SSH_VARIANT")
+	os.Setenv("GIT_SSH_COMMAND", "ssh -o StrictHostKeyChecking=no")
+	defer os.Unsetenv("GIT_SSH_COMMAND")
+
+	master := NewSSHMaster()
+	assert.True(master.Enabled())
+	sockDir := master.sockDir
+	controlPath := filepath.Join(sockDir, "master-%r@%h:%p")
+
+	assert.Equal("ssh -o StrictHostKeyChecking=no -o ControlPath="+controlPath,
+		master.Command())
+
+	u := config.ParseGitURL("ssh://git@example.com:29418/project.git")
+	assert.Equal([]string{
+		"ssh", "-o", "StrictHostKeyChecking=no",
+		"-o", "ControlPath=" + controlPath,
+		"-M", "-N",
+		"-p", "29418",
+		"git@example.com",
+	}, master.masterArgs(u))
+	assert.Equal([]string{
+		"ssh", "-o", "StrictHostKeyChecking=no",
+		"-o", "ControlPath=" + controlPath,
+		"-O", "exit",
+		"git@example.com",
+	}, master.controlArgs(config.ParseGitURL("git@example.com:project.git"), "exit"))
+
+	// Not SSH protocol.
+	assert.False(master.Start("https://example.com/project.git"))
+	assert.False(master.Start("/path/of/project.git"))
+
+	master.Setenv()
+	assert.Equal(master.Command(), os.Getenv("GIT_SSH_COMMAND"))
+	master.CloseOnSignal()
+
+	master.Close()
+	assert.False(master.Enabled())
+	_, err := os.Stat(sockDir)
+	assert.True(os.IsNotExist(err))
+	// GIT_SSH_COMMAND is restored for next sync.
+	assert.Equal("ssh -o StrictHostKeyChecking=no", os.Getenv("GIT_SSH_COMMAND"))
+	assert.Equal(0, len(signalCleanups))
+}
+
+func TestSSHMasterDisabled(t *testing.T) {
+	assert := assert.New(t)
+	os.Unsetenv("GIT_SSH")
+	os.Setenv("GIT_SSH_COMMAND", "plink")
+	defer os.Unsetenv("GIT_SSH_COMMAND")
+
+	master := NewSSHMaster()
+	assert.False(master.Enabled())
+	assert.False(master.Start("ssh://git@example.com/project.git"))
+	master.Close()
+
+	var none *SSHMaster
+	assert.False(none.Enabled())
+}
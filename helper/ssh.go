@@ -10,7 +10,7 @@ import (
 	"time"
 
 	"github.com/alibaba/git-repo-go/config"
-	log "github.com/jiangxin/multi-log"
+	"github.com/alibaba/git-repo-go/log"
 )
 
 // Define constants for SSH variant types.
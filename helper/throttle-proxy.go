@@ -0,0 +1,137 @@
+package helper
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/alibaba/git-repo-go/log"
+)
+
+// ThrottleProxy is a local HTTP proxy for git commands, which limits
+// bandwidth of downloads over HTTP and HTTPS by rate-limited readers.
+// HTTPS connections are tunneled by CONNECT, and are not decrypted.
+type ThrottleProxy struct {
+	listener   net.Listener
+	server     *http.Server
+	transport  *http.Transport
+	restoreEnv []func()
+}
+
+// NewThrottleProxy starts ThrottleProxy on a random port of localhost.
+func NewThrottleProxy() (*ThrottleProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	v := ThrottleProxy{
+		listener: listener,
+		transport: &http.Transport{
+			Proxy:                 nil,
+			ResponseHeaderTimeout: remoteCallTimeout * time.Second,
+			DisableCompression:    true,
+		},
+	}
+	v.server = &http.Server{Handler: &v}
+	go v.server.Serve(listener)
+	return &v, nil
+}
+
+// URL returns URL of the proxy.
+func (v *ThrottleProxy) URL() string {
+	return "http://" + v.listener.Addr().String()
+}
+
+// Setenv exports http_proxy and https_proxy, so that git commands will
+// fetch through the proxy, and they are restored by Close. Returns error
+// if proxy is already set by user, which is not overridden.
+func (v *ThrottleProxy) Setenv() error {
+	for _, name := range []string{"http_proxy", "https_proxy", "HTTP_PROXY", "HTTPS_PROXY", "all_proxy", "ALL_PROXY"} {
+		if os.Getenv(name) != "" {
+			return errors.New("proxy is set by $" + name)
+		}
+	}
+	if _, err := GetProxyFromGitConfig(); err == nil {
+		return errors.New("proxy is set by http.proxy")
+	}
+	for _, name := range []string{"http_proxy", "https_proxy"} {
+		v.restoreEnv = append(v.restoreEnv, setenv(name, v.URL()))
+	}
+	return nil
+}
+
+// Close stops the proxy, and restores environments changed by Setenv.
+func (v *ThrottleProxy) Close() {
+	for _, restore := range v.restoreEnv {
+		restore()
+	}
+	v.restoreEnv = nil
+	v.server.Close()
+}
+
+// ServeHTTP implements http.Handler.
+func (v *ThrottleProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		v.tunnel(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "not a proxy request", http.StatusBadRequest)
+		return
+	}
+
+	r.RequestURI = ""
+	r.Header.Del("Proxy-Connection")
+	r.Header.Del("Proxy-Authorization")
+	resp, err := v.transport.RoundTrip(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for k, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(k, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, NewRateLimitedReader(resp.Body, BandwidthLimiters(r.URL.String())...))
+}
+
+// tunnel copies data of CONNECT request, and limits the download side.
+func (v *ThrottleProxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	upstream, err := net.DialTimeout("tcp", r.Host, remoteCallTimeout*time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "hijack is not supported", http.StatusInternalServerError)
+		return
+	}
+	client, _, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		log.Debugf("fail to hijack connection for %s: %s", r.Host, err)
+		return
+	}
+	_, err = client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+	if err != nil {
+		client.Close()
+		upstream.Close()
+		return
+	}
+
+	limiters := BandwidthLimiters("https://" + r.Host)
+	go func() {
+		io.Copy(upstream, client)
+		upstream.Close()
+	}()
+	io.Copy(client, NewRateLimitedReader(upstream, limiters...))
+	client.Close()
+}
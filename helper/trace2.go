@@ -0,0 +1,251 @@
+package helper
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/version"
+)
+
+const (
+	// trace2EventVersion is version of trace2 event format.
+	trace2EventVersion = "3"
+	trace2TimeLayout   = "2006-01-02T15:04:05.000000Z"
+	trace2EnvEvent     = "GIT_TRACE2_EVENT"
+	trace2EnvParentSID = "GIT_TRACE2_PARENT_SID"
+	trace2CfgEvent     = "trace2.eventtarget"
+	trace2MainThread   = "main"
+)
+
+var (
+	trace2 *Trace2
+)
+
+// Trace2 writes events in git trace2 event format, so that events of
+// git-repo and child git commands can be analyzed together.
+type Trace2 struct {
+	sid     string
+	start   time.Time
+	w       io.Writer
+	closer  io.Closer
+	childID int
+	mutex   sync.Mutex
+}
+
+// trace2Target returns target of trace2 events, which is defined by env
+// $GIT_TRACE2_EVENT or git config "trace2.eventTarget".
+func trace2Target() string {
+	if target, ok := os.LookupEnv(trace2EnvEvent); ok {
+		return target
+	}
+	return config.GitDefaultConfig.Get(trace2CfgEvent)
+}
+
+// trace2SID returns session ID, which is prefixed by SID of parent
+// process like git does.
+func trace2SID(now time.Time) string {
+	var buf [4]byte
+
+	rand.Read(buf[:])
+	sid := fmt.Sprintf("%s-H%s-P%08x",
+		now.UTC().Format("20060102T150405.000000Z"),
+		hex.EncodeToString(buf[:]),
+		os.Getpid())
+	if parent := os.Getenv(trace2EnvParentSID); parent != "" {
+		sid = parent + "/" + sid
+	}
+	return sid
+}
+
+// newTrace2 opens target to write events. Target can be:
+//
+//   - "1", "2" or "true": write to stderr
+//   - absolute path of a file: append events to the file
+//   - absolute path of a directory: write to a new file for each process
+//
+// Returns nil if trace2 is disabled.
+func newTrace2(target string, now time.Time) *Trace2 {
+	v := Trace2{
+		sid:   trace2SID(now),
+		start: now,
+	}
+
+	switch strings.ToLower(target) {
+	case "", "0", "false":
+		return nil
+	case "1", "2", "true":
+		v.w = os.Stderr
+		return &v
+	}
+
+	if !filepath.IsAbs(target) {
+		log.Warnf("trace2 target '%s' is not supported, must be an absolute path", target)
+		return nil
+	}
+	if fi, err := os.Stat(target); err == nil && fi.IsDir() {
+		name := strings.Replace(v.sid, "/", "_", -1)
+		target = filepath.Join(target, name)
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		log.Warnf("fail to open trace2 target '%s': %s", target, err)
+		return nil
+	}
+	v.w = f
+	v.closer = f
+	return &v
+}
+
+// event writes one event with common fields. Field "thread" is only set
+// by events of the main goroutine, for regions and child commands are
+// in worker goroutines which have no name in Go.
+func (v *Trace2) event(name string, fields map[string]interface{}) {
+	if v == nil {
+		return
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	_, file, line, _ := runtime.Caller(2)
+	fields["event"] = name
+	fields["sid"] = v.sid
+	fields["time"] = time.Now().UTC().Format(trace2TimeLayout)
+	fields["file"] = filepath.Base(file)
+	fields["line"] = line
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return
+	}
+	v.w.Write(append(data, '\n'))
+}
+
+// elapsed returns seconds since start of process.
+func (v *Trace2) elapsed() float64 {
+	return time.Since(v.start).Seconds()
+}
+
+// Trace2Start opens trace2 target, and writes "version" and "start"
+// events.
+func Trace2Start(argv []string) {
+	now := time.Now()
+	trace2 = newTrace2(trace2Target(), now)
+	if trace2 == nil {
+		return
+	}
+	trace2.event("version", map[string]interface{}{
+		"thread": trace2MainThread,
+		"evt":    trace2EventVersion,
+		"exe":    version.Version,
+	})
+	trace2.event("start", map[string]interface{}{
+		"thread": trace2MainThread,
+		"t_abs":  trace2.elapsed(),
+		"argv":   argv,
+	})
+}
+
+// Trace2Exit writes "exit" and "atexit" events, and closes trace2 target.
+func Trace2Exit(code int) {
+	if trace2 == nil {
+		return
+	}
+	trace2.event("exit", map[string]interface{}{
+		"thread": trace2MainThread,
+		"t_abs":  trace2.elapsed(),
+		"code":   code,
+	})
+	trace2.event("atexit", map[string]interface{}{
+		"thread": trace2MainThread,
+		"t_abs":  trace2.elapsed(),
+		"code":   code,
+	})
+	if trace2.closer != nil {
+		trace2.closer.Close()
+	}
+	trace2 = nil
+}
+
+// Trace2Region writes "region_enter" event, and returns a function to
+// write "region_leave" event, such as span of fetching a project. Field
+// "nesting" is omitted, because regions of concurrent workers are not
+// nested in each other.
+func Trace2Region(category, label string) func() {
+	if trace2 == nil {
+		return func() {}
+	}
+
+	start := time.Now()
+	trace2.event("region_enter", map[string]interface{}{
+		"category": category,
+		"label":    label,
+	})
+	return func() {
+		trace2.event("region_leave", map[string]interface{}{
+			"t_rel":    time.Since(start).Seconds(),
+			"category": category,
+			"label":    label,
+		})
+	}
+}
+
+// Trace2Child writes "child_start" event for cmd, and returns a function
+// to write "child_exit" event after cmd is finished. Must be called
+// after setting environments of cmd and before starting it, because SID
+// is passed to child in environment.
+func Trace2Child(cmd *exec.Cmd) func(error) {
+	if trace2 == nil {
+		return func(error) {}
+	}
+
+	trace2.mutex.Lock()
+	id := trace2.childID
+	trace2.childID++
+	trace2.mutex.Unlock()
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, trace2EnvParentSID+"="+trace2.sid)
+
+	class := "?"
+	if len(cmd.Args) > 0 && cmd.Args[0] == config.GIT {
+		class = "git"
+	}
+	start := time.Now()
+	trace2.event("child_start", map[string]interface{}{
+		"child_id":    id,
+		"child_class": class,
+		"use_shell":   false,
+		"argv":        cmd.Args,
+	})
+	return func(err error) {
+		pid, code := 0, 0
+		if cmd.Process != nil {
+			pid = cmd.Process.Pid
+		}
+		if cmd.ProcessState != nil {
+			code = cmd.ProcessState.ExitCode()
+		} else if err != nil {
+			code = -1
+		}
+		trace2.event("child_exit", map[string]interface{}{
+			"child_id": id,
+			"pid":      pid,
+			"code":     code,
+			"t_rel":    time.Since(start).Seconds(),
+		})
+	}
+}
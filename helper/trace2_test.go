@@ -0,0 +1,123 @@
+package helper
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTrace2(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	assert.Nil(newTrace2("", time.Now()))
+	assert.Nil(newTrace2("0", time.Now()))
+	assert.Nil(newTrace2("relative/path", time.Now()))
+	assert.Equal(os.Stderr, newTrace2("1", time.Now()).w)
+
+	// Directory as target, create file for each process.
+	v := newTrace2(tmpdir, time.Now())
+	assert.NotNil(v)
+	v.closer.Close()
+	assert.True(strings.HasPrefix(filepath.Base(v.w.(*os.File).Name()),
+		time.Now().UTC().Format("20060102T")))
+
+	// SID of parent process is prefix of SID.
+	os.Setenv(trace2EnvParentSID, "parent-sid")
+	defer os.Unsetenv(trace2EnvParentSID)
+	assert.True(strings.HasPrefix(trace2SID(time.Now()), "parent-sid/"))
+}
+
+func TestTrace2Events(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	target := filepath.Join(tmpdir, "trace2.json")
+	os.Setenv(trace2EnvEvent, target)
+	defer os.Unsetenv(trace2EnvEvent)
+
+	Trace2Start([]string{"git-repo", "sync"})
+	leave := Trace2Region("fetch", "project1")
+	cmd := exec.Command("git", "version")
+	done := Trace2Child(cmd)
+	assert.Contains(cmd.Env, trace2EnvParentSID+"="+trace2.sid)
+	done(cmd.Run())
+	cmd = exec.Command("git", "bad-command")
+	done = Trace2Child(cmd)
+	done(cmd.Run())
+	leave()
+	Trace2Child(exec.Command("not-exist"))(errors.New("not found"))
+	sid := trace2.sid
+	Trace2Exit(2)
+
+	// Disabled after exit.
+	Trace2Region("fetch", "project2")()
+
+	data, err := ioutil.ReadFile(target)
+	assert.Nil(err)
+	events := []map[string]interface{}{}
+	childEvents := 0
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		event := map[string]interface{}{}
+		assert.Nil(json.Unmarshal([]byte(line), &event))
+		// Events of child git commands are in the same target.
+		if strings.HasPrefix(event["sid"].(string), sid+"/") {
+			childEvents++
+			continue
+		}
+		assert.Equal(sid, event["sid"])
+		events = append(events, event)
+	}
+	assert.True(childEvents > 0)
+	names := []string{}
+	for _, event := range events {
+		names = append(names, event["event"].(string))
+	}
+	assert.Equal([]string{
+		"version",
+		"start",
+		"region_enter",
+		"child_start",
+		"child_exit",
+		"child_start",
+		"child_exit",
+		"region_leave",
+		"child_start",
+		"child_exit",
+		"exit",
+		"atexit",
+	}, names)
+
+	assert.Equal("3", events[0]["evt"])
+	assert.Equal([]interface{}{"git-repo", "sync"}, events[1]["argv"])
+	assert.Equal("main", events[1]["thread"])
+	assert.Equal("project1", events[2]["label"])
+	assert.NotContains(events[2], "thread")
+	assert.NotContains(events[2], "nesting")
+	assert.Equal("git", events[3]["child_class"])
+	assert.Equal(float64(0), events[4]["code"])
+	assert.Equal(float64(1), events[6]["child_id"])
+	assert.NotEqual(float64(0), events[6]["code"])
+	assert.Equal("?", events[8]["child_class"])
+	assert.Equal(float64(-1), events[9]["code"])
+	assert.Equal(float64(2), events[11]["code"])
+	assert.Equal("trace2_test.go", events[2]["file"])
+}
@@ -0,0 +1,151 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// jsonLockTimeout is age of a stale lock file of rotation.
+const jsonLockTimeout = time.Minute
+
+// jsonRecord is a line of JSON log file.
+type jsonRecord struct {
+	Time    string            `json:"time"`
+	Level   string            `json:"level"`
+	Module  string            `json:"module,omitempty"`
+	PID     int               `json:"pid"`
+	Message string            `json:"msg"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// jsonWriter saves messages in JSON lines, and rotates file by size.
+// Rotated files are renamed with suffix ".1", ".2", and so on.
+type jsonWriter struct {
+	name       string
+	level      Level
+	rotateSize int64
+	keep       int
+
+	f     *os.File
+	size  int64
+	mutex sync.Mutex
+}
+
+func newJSONWriter(name string, level Level, rotateSize int64, keep int) (*jsonWriter, error) {
+	if keep < 1 {
+		keep = 1
+	}
+	v := jsonWriter{
+		name:       name,
+		level:      level,
+		rotateSize: rotateSize,
+		keep:       keep,
+	}
+	err := os.MkdirAll(filepath.Dir(name), 0755)
+	if err != nil {
+		return nil, err
+	}
+	if err = v.open(); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (v *jsonWriter) open() error {
+	f, err := os.OpenFile(v.name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	v.f = f
+	v.size = fi.Size()
+	return nil
+}
+
+// lockRotate creates lock file of rotation, for JSON log in workspace is
+// written by several processes. Lock left by a crashed process is removed
+// after jsonLockTimeout.
+func (v *jsonWriter) lockRotate() (func(), bool) {
+	lockFile := v.name + ".lock"
+	for i := 0; i < 2; i++ {
+		f, err := os.OpenFile(lockFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockFile) }, true
+		}
+		fi, err := os.Stat(lockFile)
+		if err != nil || time.Since(fi.ModTime()) < jsonLockTimeout {
+			break
+		}
+		os.Remove(lockFile)
+	}
+	return nil, false
+}
+
+// rotate renames log file with suffix, and opens a new one. If file is
+// being rotated or is already rotated by another process, only reopen it.
+func (v *jsonWriter) rotate() error {
+	fi, err := v.f.Stat()
+	if err != nil {
+		return err
+	}
+	unlock, ok := v.lockRotate()
+	if ok {
+		defer unlock()
+		if current, err := os.Stat(v.name); err == nil && os.SameFile(fi, current) {
+			os.Remove(fmt.Sprintf("%s.%d", v.name, v.keep))
+			for i := v.keep - 1; i > 0; i-- {
+				os.Rename(fmt.Sprintf("%s.%d", v.name, i), fmt.Sprintf("%s.%d", v.name, i+1))
+			}
+			if err = os.Rename(v.name, v.name+".1"); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	v.f.Close()
+	v.f = nil
+	return v.open()
+}
+
+// Write saves message as a line of JSON.
+func (v *jsonWriter) Write(level Level, module string, fields Fields, msg string) {
+	record := jsonRecord{
+		Time:    time.Now().Format(time.RFC3339Nano),
+		Level:   level.String(),
+		Module:  module,
+		PID:     os.Getpid(),
+		Message: msg,
+	}
+	if len(fields) > 0 {
+		record.Fields = make(map[string]string)
+		for k, value := range fields {
+			record.Fields[k] = fmt.Sprint(value)
+		}
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.f == nil {
+		return
+	}
+	if v.rotateSize > 0 && v.size > 0 && v.size+int64(len(line)) > v.rotateSize {
+		if err = v.rotate(); err != nil {
+			return
+		}
+	}
+	n, _ := v.f.Write(line)
+	v.size += int64(n)
+}
@@ -0,0 +1,64 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is level of log, and a message is shown if its level is less than
+// or equal to the level of logger.
+type Level int
+
+// Levels of log, from the most severe to the least one.
+const (
+	PanicLevel Level = iota
+	FatalLevel
+	ErrorLevel
+	WarnLevel
+	NoteLevel
+	InfoLevel
+	DebugLevel
+	TraceLevel
+)
+
+var levelNames = []string{
+	"PANIC",
+	"FATAL",
+	"ERROR",
+	"WARNING",
+	"NOTE",
+	"INFO",
+	"DEBUG",
+	"TRACE",
+}
+
+// String returns name of level, such as "WARNING".
+func (v Level) String() string {
+	if v < PanicLevel || v > TraceLevel {
+		return fmt.Sprintf("LEVEL(%d)", v)
+	}
+	return levelNames[v]
+}
+
+// ParseLevel parses name of level, such as "debug" or "warn".
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "panic":
+		return PanicLevel, nil
+	case "fatal":
+		return FatalLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "note":
+		return NoteLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "debug":
+		return DebugLevel, nil
+	case "trace":
+		return TraceLevel, nil
+	}
+	return PanicLevel, fmt.Errorf("unknown log level '%s'", name)
+}
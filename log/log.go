@@ -0,0 +1,367 @@
+// Package log is the logging facade of git-repo. Messages are shown on
+// console by multi-log as before, and are also saved in a JSON log file
+// of workspace for post-mortem. Level of console can be overridden for
+// modules, which are named by package and file, such as
+// "project/network-half".
+package log
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	mlog "github.com/jiangxin/multi-log"
+)
+
+// Fields are key-value pairs attached to a message.
+type Fields map[string]interface{}
+
+// Options of logger.
+type Options struct {
+	Verbose       int
+	Quiet         bool
+	LogFile       string
+	LogLevel      string
+	LogRotateSize int64
+
+	// Modules overrides level of console for modules, such as
+	// "project/network-half=debug,helper=warn".
+	Modules string
+
+	// JSONFile saves messages in JSON lines if not empty.
+	JSONFile       string
+	JSONLevel      string
+	JSONRotateSize int64
+	JSONKeep       int
+}
+
+var (
+	filters      []moduleFilter
+	jsonLog      *jsonWriter
+	consoleMutex sync.Mutex
+)
+
+// Init initializes logger.
+func Init(o Options) {
+	var err error
+
+	mlog.Init(mlog.Options{
+		Verbose:       o.Verbose,
+		Quiet:         o.Quiet,
+		LogFile:       o.LogFile,
+		LogLevel:      o.LogLevel,
+		LogRotateSize: o.LogRotateSize,
+	})
+
+	filters, err = parseModuleFilters(o.Modules)
+	if err != nil {
+		mlog.Warnf("ignore log modules: %s", err)
+	}
+
+	jsonLog = nil
+	if o.JSONFile != "" {
+		level := DebugLevel
+		if o.JSONLevel != "" {
+			level, err = ParseLevel(o.JSONLevel)
+			if err != nil {
+				mlog.Warnf("ignore level of JSON log: %s", err)
+				level = DebugLevel
+			}
+		}
+		jsonLog, err = newJSONWriter(o.JSONFile, level, o.JSONRotateSize, o.JSONKeep)
+		if err != nil {
+			mlog.Debugf("fail to open JSON log '%s': %s", o.JSONFile, err)
+		}
+	}
+}
+
+// logEntry saves message in JSON log file, and returns true if message
+// should be shown on console by multi-log. Messages of modules whose
+// level is overridden are shown on console here.
+func logEntry(level Level, fields Fields, message func() string) bool {
+	if jsonLog == nil && len(filters) == 0 {
+		return true
+	}
+
+	// Skip logEntry and the exported function.
+	_, file, _, _ := runtime.Caller(2)
+	module := moduleOf(file)
+	msg := ""
+	formatted := false
+
+	if jsonLog != nil && level <= jsonLog.level {
+		msg = message()
+		formatted = true
+		jsonLog.Write(level, module, fields, msg)
+	}
+
+	// Fatal and panic messages are never filtered.
+	if level <= FatalLevel {
+		return true
+	}
+	override, ok := moduleLevel(filters, module)
+	if !ok {
+		return true
+	}
+	if level <= override {
+		if !formatted {
+			msg = message()
+		}
+		writeConsole(level, fields, msg)
+	}
+	return false
+}
+
+// writeConsole writes message in the same format as multi-log.
+func writeConsole(level Level, fields Fields, msg string) {
+	msg = strings.TrimRight(msg, "\n")
+	if len(fields) > 0 {
+		keys := []string{}
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := []string{}
+		for _, k := range keys {
+			items = append(items, fmt.Sprintf("%s=%v", k, fields[k]))
+		}
+		msg = fmt.Sprintf("%-44s (%s)", msg, strings.Join(items, " "))
+	}
+
+	consoleMutex.Lock()
+	defer consoleMutex.Unlock()
+	fmt.Fprintf(os.Stderr, "%s: %s\n", level, msg)
+}
+
+func sprint(args []interface{}) func() string {
+	return func() string { return fmt.Sprint(args...) }
+}
+
+func sprintf(format string, args []interface{}) func() string {
+	return func() string { return fmt.Sprintf(format, args...) }
+}
+
+func sprintln(args []interface{}) func() string {
+	return func() string { return fmt.Sprintln(args...) }
+}
+
+// Tracef logs a message at level trace.
+func Tracef(format string, args ...interface{}) {
+	if logEntry(TraceLevel, nil, sprintf(format, args)) {
+		mlog.Tracef(format, args...)
+	}
+}
+
+// Debug logs a message at level debug.
+func Debug(args ...interface{}) {
+	if logEntry(DebugLevel, nil, sprint(args)) {
+		mlog.Debug(args...)
+	}
+}
+
+// Debugf logs a message at level debug.
+func Debugf(format string, args ...interface{}) {
+	if logEntry(DebugLevel, nil, sprintf(format, args)) {
+		mlog.Debugf(format, args...)
+	}
+}
+
+// Info logs a message at level info.
+func Info(args ...interface{}) {
+	if logEntry(InfoLevel, nil, sprint(args)) {
+		mlog.Info(args...)
+	}
+}
+
+// Infof logs a message at level info.
+func Infof(format string, args ...interface{}) {
+	if logEntry(InfoLevel, nil, sprintf(format, args)) {
+		mlog.Infof(format, args...)
+	}
+}
+
+// Note logs a message at level note.
+func Note(args ...interface{}) {
+	if logEntry(NoteLevel, nil, sprint(args)) {
+		mlog.Note(args...)
+	}
+}
+
+// Notef logs a message at level note.
+func Notef(format string, args ...interface{}) {
+	if logEntry(NoteLevel, nil, sprintf(format, args)) {
+		mlog.Notef(format, args...)
+	}
+}
+
+// Printf logs a message at level note.
+func Printf(format string, args ...interface{}) {
+	if logEntry(NoteLevel, nil, sprintf(format, args)) {
+		mlog.Printf(format, args...)
+	}
+}
+
+// Warn logs a message at level warning.
+func Warn(args ...interface{}) {
+	if logEntry(WarnLevel, nil, sprint(args)) {
+		mlog.Warn(args...)
+	}
+}
+
+// Warnf logs a message at level warning.
+func Warnf(format string, args ...interface{}) {
+	if logEntry(WarnLevel, nil, sprintf(format, args)) {
+		mlog.Warnf(format, args...)
+	}
+}
+
+// Warningf logs a message at level warning.
+func Warningf(format string, args ...interface{}) {
+	if logEntry(WarnLevel, nil, sprintf(format, args)) {
+		mlog.Warningf(format, args...)
+	}
+}
+
+// Error logs a message at level error.
+func Error(args ...interface{}) {
+	if logEntry(ErrorLevel, nil, sprint(args)) {
+		mlog.Error(args...)
+	}
+}
+
+// Errorf logs a message at level error.
+func Errorf(format string, args ...interface{}) {
+	if logEntry(ErrorLevel, nil, sprintf(format, args)) {
+		mlog.Errorf(format, args...)
+	}
+}
+
+// Fatal logs a message at level fatal, and exits.
+func Fatal(args ...interface{}) {
+	logEntry(FatalLevel, nil, sprint(args))
+	mlog.Fatal(args...)
+}
+
+// Fatalf logs a message at level fatal, and exits.
+func Fatalf(format string, args ...interface{}) {
+	logEntry(FatalLevel, nil, sprintf(format, args))
+	mlog.Fatalf(format, args...)
+}
+
+// Panic logs a message at level panic, and panics.
+func Panic(args ...interface{}) {
+	logEntry(PanicLevel, nil, sprint(args))
+	mlog.Panic(args...)
+}
+
+// Panicf logs a message at level panic, and panics.
+func Panicf(format string, args ...interface{}) {
+	logEntry(PanicLevel, nil, sprintf(format, args))
+	mlog.Panicf(format, args...)
+}
+
+// Entry is a message with fields.
+type Entry struct {
+	fields Fields
+}
+
+// WithField returns entry with a field.
+func WithField(key string, value interface{}) *Entry {
+	return &Entry{fields: Fields{key: value}}
+}
+
+// WithFields returns entry with fields.
+func WithFields(fields Fields) *Entry {
+	return &Entry{fields: fields}
+}
+
+func (v *Entry) raw() map[string]interface{} {
+	return map[string]interface{}(v.fields)
+}
+
+// Trace logs a message with fields at level trace.
+func (v *Entry) Trace(args ...interface{}) {
+	if logEntry(TraceLevel, v.fields, sprint(args)) {
+		mlog.WithFields(v.raw()).Trace(args...)
+	}
+}
+
+// Tracef logs a message with fields at level trace.
+func (v *Entry) Tracef(format string, args ...interface{}) {
+	if logEntry(TraceLevel, v.fields, sprintf(format, args)) {
+		mlog.WithFields(v.raw()).Tracef(format, args...)
+	}
+}
+
+// Traceln logs a message with fields at level trace.
+func (v *Entry) Traceln(args ...interface{}) {
+	if logEntry(TraceLevel, v.fields, sprintln(args)) {
+		mlog.WithFields(v.raw()).Traceln(args...)
+	}
+}
+
+// Debug logs a message with fields at level debug.
+func (v *Entry) Debug(args ...interface{}) {
+	if logEntry(DebugLevel, v.fields, sprint(args)) {
+		mlog.WithFields(v.raw()).Debug(args...)
+	}
+}
+
+// Debugf logs a message with fields at level debug.
+func (v *Entry) Debugf(format string, args ...interface{}) {
+	if logEntry(DebugLevel, v.fields, sprintf(format, args)) {
+		mlog.WithFields(v.raw()).Debugf(format, args...)
+	}
+}
+
+// Debugln logs a message with fields at level debug.
+func (v *Entry) Debugln(args ...interface{}) {
+	if logEntry(DebugLevel, v.fields, sprintln(args)) {
+		mlog.WithFields(v.raw()).Debugln(args...)
+	}
+}
+
+// Info logs a message with fields at level info.
+func (v *Entry) Info(args ...interface{}) {
+	if logEntry(InfoLevel, v.fields, sprint(args)) {
+		mlog.WithFields(v.raw()).Info(args...)
+	}
+}
+
+// Infof logs a message with fields at level info.
+func (v *Entry) Infof(format string, args ...interface{}) {
+	if logEntry(InfoLevel, v.fields, sprintf(format, args)) {
+		mlog.WithFields(v.raw()).Infof(format, args...)
+	}
+}
+
+// Warn logs a message with fields at level warning.
+func (v *Entry) Warn(args ...interface{}) {
+	if logEntry(WarnLevel, v.fields, sprint(args)) {
+		mlog.WithFields(v.raw()).Warn(args...)
+	}
+}
+
+// Warnf logs a message with fields at level warning.
+func (v *Entry) Warnf(format string, args ...interface{}) {
+	if logEntry(WarnLevel, v.fields, sprintf(format, args)) {
+		mlog.WithFields(v.raw()).Warnf(format, args...)
+	}
+}
+
+// Error logs a message with fields at level error.
+func (v *Entry) Error(args ...interface{}) {
+	if logEntry(ErrorLevel, v.fields, sprint(args)) {
+		mlog.WithFields(v.raw()).Error(args...)
+	}
+}
+
+// Errorf logs a message with fields at level error.
+func (v *Entry) Errorf(format string, args ...interface{}) {
+	if logEntry(ErrorLevel, v.fields, sprintf(format, args)) {
+		mlog.WithFields(v.raw()).Errorf(format, args...)
+	}
+}
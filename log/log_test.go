@@ -0,0 +1,140 @@
+package log
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	level, err := ParseLevel("Warn")
+	assert.Nil(err)
+	assert.Equal(WarnLevel, level)
+	assert.Equal("WARNING", level.String())
+	level, err = ParseLevel("debug")
+	assert.Nil(err)
+	assert.Equal(DebugLevel, level)
+	_, err = ParseLevel("verbose")
+	assert.Equal("unknown log level 'verbose'", err.Error())
+}
+
+func TestModuleFilters(t *testing.T) {
+	assert := assert.New(t)
+
+	filters, err := parseModuleFilters("project/network-half=debug, project=warn,helper/=trace")
+	assert.Nil(err)
+	assert.Equal([]moduleFilter{
+		{Module: "project/network-half", Level: DebugLevel},
+		{Module: "project", Level: WarnLevel},
+		{Module: "helper", Level: TraceLevel},
+	}, filters)
+
+	level, ok := moduleLevel(filters, "project/network-half")
+	assert.True(ok)
+	assert.Equal(DebugLevel, level)
+	level, ok = moduleLevel(filters, "project/local-half")
+	assert.True(ok)
+	assert.Equal(WarnLevel, level)
+	_, ok = moduleLevel(filters, "cmd/sync")
+	assert.False(ok)
+
+	_, err = parseModuleFilters("project")
+	assert.NotNil(err)
+	_, err = parseModuleFilters("project=loud")
+	assert.NotNil(err)
+
+	assert.Equal("project/network-half", moduleOf("/src/git-repo-go/project/network-half.go"))
+	assert.Equal("", moduleOf(""))
+}
+
+func TestJSONLog(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-log-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	name := filepath.Join(tmpdir, "logs", "git-repo.log")
+	w, err := newJSONWriter(name, InfoLevel, 200, 2)
+	assert.Nil(err)
+	jsonLog = w
+	filters = []moduleFilter{{Module: "log", Level: PanicLevel}}
+	defer func() {
+		jsonLog = nil
+		filters = nil
+	}()
+
+	WithField("project", "main").Debugf("not saved")
+	Infof("fetch %s", "main")
+	data, err := ioutil.ReadFile(name)
+	assert.Nil(err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Equal(1, len(lines))
+	record := jsonRecord{}
+	assert.Nil(json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal("INFO", record.Level)
+	assert.Equal("log/log_test", record.Module)
+	assert.Equal("fetch main", record.Message)
+
+	for i := 0; i < 10; i++ {
+		WithField("n", i).Warnf("warn message")
+	}
+	for _, suffix := range []string{"", ".1", ".2"} {
+		fi, err := os.Stat(name + suffix)
+		assert.Nil(err)
+		assert.True(fi.Size() <= 200)
+	}
+	_, err = os.Stat(name + ".3")
+	assert.True(os.IsNotExist(err))
+}
+
+func TestJSONLogRotateLocked(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-log-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	name := filepath.Join(tmpdir, "git-repo.log")
+	w1, err := newJSONWriter(name, InfoLevel, 100, 2)
+	assert.Nil(err)
+	w2, err := newJSONWriter(name, InfoLevel, 100, 2)
+	assert.Nil(err)
+	w2.Write(InfoLevel, "", nil, "w2")
+
+	// Another process is rotating, do not rotate.
+	assert.Nil(ioutil.WriteFile(name+".lock", nil, 0644))
+	w1.Write(InfoLevel, "", nil, strings.Repeat("x", 60))
+	w1.Write(InfoLevel, "", nil, strings.Repeat("x", 60))
+	_, err = os.Stat(name + ".1")
+	assert.True(os.IsNotExist(err))
+
+	// Stale lock is removed.
+	old := time.Now().Add(-2 * jsonLockTimeout)
+	assert.Nil(os.Chtimes(name+".lock", old, old))
+	w1.Write(InfoLevel, "", nil, strings.Repeat("x", 60))
+	_, err = os.Stat(name + ".1")
+	assert.Nil(err)
+	_, err = os.Stat(name + ".lock")
+	assert.True(os.IsNotExist(err))
+
+	// File is already rotated by w1, and w2 only reopens it.
+	w2.Write(InfoLevel, "", nil, strings.Repeat("y", 60))
+	_, err = os.Stat(name + ".2")
+	assert.True(os.IsNotExist(err))
+	data, err := ioutil.ReadFile(name)
+	assert.Nil(err)
+	assert.Contains(string(data), strings.Repeat("y", 60))
+}
@@ -0,0 +1,71 @@
+package log
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// moduleFilter overrides level of console for a module.
+type moduleFilter struct {
+	Module string
+	Level  Level
+}
+
+// parseModuleFilters parses filters in the form of "<module>=<level>",
+// separated by commas, such as "project/network-half=debug,helper=warn".
+func parseModuleFilters(value string) ([]moduleFilter, error) {
+	filters := []moduleFilter{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("bad log filter '%s', should be <module>=<level>", item)
+		}
+		level, err := ParseLevel(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, moduleFilter{
+			Module: strings.Trim(strings.TrimSpace(kv[0]), "/"),
+			Level:  level,
+		})
+	}
+	return filters, nil
+}
+
+// moduleOf returns module of source file, which is name of package and
+// file, such as "project/network-half" for ".../project/network-half.go".
+func moduleOf(file string) string {
+	if file == "" {
+		return ""
+	}
+	file = filepath.ToSlash(file)
+	name := strings.TrimSuffix(filepath.Base(file), ".go")
+	pkg := filepath.Base(filepath.Dir(file))
+	return pkg + "/" + name
+}
+
+// moduleLevel returns level of module. Filter of file wins over filter
+// of package.
+func moduleLevel(filters []moduleFilter, module string) (Level, bool) {
+	var (
+		level Level
+		found bool
+		pkg   = strings.SplitN(module, "/", 2)[0]
+	)
+
+	for _, f := range filters {
+		if f.Module == module {
+			return f.Level, true
+		}
+		if f.Module == pkg {
+			level = f.Level
+			found = true
+		}
+	}
+	return level, found
+}
@@ -29,7 +29,7 @@ func main() {
 			resp.Cmd.Println("")
 			resp.Cmd.Println(resp.Cmd.UsageString())
 		}
-		os.Exit(-1)
+		os.Exit(resp.ExitCode())
 	}
 }
 
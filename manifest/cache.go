@@ -0,0 +1,214 @@
+package manifest
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/path"
+)
+
+// manifestCacheVersion is changed if format of manifest cache is changed.
+const manifestCacheVersion = 5
+
+var manifestCache = newCache()
+
+// fileStamp is used to check whether a manifest file is changed.
+type fileStamp struct {
+	Path    string
+	Missing bool
+	Size    int64
+	MTime   time.Time
+	Hash    string
+	// Time of creating stamp, used to find racily clean files.
+	Stamped time.Time
+}
+
+// cacheEntry is a merged manifest with stamps of its source files.
+type cacheEntry struct {
+	Version  int
+	File     string
+	Options  loadOptions
+	Sources  []fileStamp
+	Manifest []byte
+}
+
+// cache saves merged manifests in memory, and in file under .repo
+// if "repo.manifestCache" is enabled, so that manifest files are not
+// parsed and merged again if none of them is changed.
+type cache struct {
+	entries map[string]*cacheEntry
+	mutex   sync.Mutex
+}
+
+func newCache() *cache {
+	return &cache{entries: make(map[string]*cacheEntry)}
+}
+
+func fileHash(name string) string {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return ""
+	}
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func newFileStamp(name string) fileStamp {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return fileStamp{Path: name, Missing: true}
+	}
+	return fileStamp{
+		Path:    name,
+		Size:    fi.Size(),
+		MTime:   fi.ModTime(),
+		Hash:    fileHash(name),
+		Stamped: time.Now(),
+	}
+}
+
+// IsValid checks file by size and mtime, and checks content only if
+// mtime is changed, such as file is touched, or file is racily clean
+// for it may be changed again in the same second of creating stamp.
+func (v fileStamp) IsValid() bool {
+	fi, err := os.Stat(v.Path)
+	if err != nil {
+		return v.Missing
+	}
+	if v.Missing || fi.Size() != v.Size {
+		return false
+	}
+	if fi.ModTime().Equal(v.MTime) &&
+		v.MTime.Before(v.Stamped.Truncate(time.Second)) {
+		return true
+	}
+	return fileHash(v.Path) == v.Hash
+}
+
+func (v cacheEntry) isValid(file string, o *loadOptions, sources []string) bool {
+	if v.Version != manifestCacheVersion || v.File != file || v.Options != *o {
+		return false
+	}
+	// Stamps of included files are saved with sources, and a new local
+	// manifest is found if it is not in stamps.
+	required := map[string]bool{}
+	for _, name := range sources {
+		required[name] = true
+	}
+	for _, stamp := range v.Sources {
+		if !stamp.IsValid() {
+			log.Debugf("manifest cache is invalid, '%s' is changed", stamp.Path)
+			return false
+		}
+		delete(required, stamp.Path)
+	}
+	return len(required) == 0
+}
+
+func (v cacheEntry) manifest() (*Manifest, error) {
+	m := Manifest{}
+	err := gob.NewDecoder(bytes.NewReader(v.Manifest)).Decode(&m)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func newCacheEntry(file string, o *loadOptions, m *Manifest, sources []string) (*cacheEntry, error) {
+	var buf bytes.Buffer
+
+	if err := gob.NewEncoder(&buf).Encode(m); err != nil {
+		return nil, err
+	}
+	entry := cacheEntry{
+		Version:  manifestCacheVersion,
+		File:     file,
+		Options:  *o,
+		Manifest: buf.Bytes(),
+	}
+	for _, name := range sources {
+		entry.Sources = append(entry.Sources, newFileStamp(name))
+	}
+	return &entry, nil
+}
+
+func cacheFile(repoDir string) string {
+	return filepath.Join(repoDir, config.ManifestCache)
+}
+
+func useCacheFile() bool {
+	return config.GitDefaultConfig.GetBool(config.CfgRepoManifestCache, false)
+}
+
+// Get returns a copy of cached manifest, or nil if cache is missing or
+// any source file or load options is changed. Sources are manifest file
+// and local manifests to load.
+func (v *cache) Get(repoDir, file string, o *loadOptions, sources []string) *Manifest {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	entry := v.entries[repoDir]
+	if entry == nil && useCacheFile() {
+		entry = &cacheEntry{}
+		f, err := os.Open(cacheFile(repoDir))
+		if err != nil {
+			return nil
+		}
+		err = gob.NewDecoder(f).Decode(entry)
+		f.Close()
+		if err != nil {
+			log.Debugf("fail to load manifest cache: %s", err)
+			return nil
+		}
+		v.entries[repoDir] = entry
+	}
+	if entry == nil || !entry.isValid(file, o, sources) {
+		return nil
+	}
+	m, err := entry.manifest()
+	if err != nil {
+		return nil
+	}
+	return m
+}
+
+// Put saves merged manifest and stamps of source files, including
+// included files.
+func (v *cache) Put(repoDir, file string, o *loadOptions, m *Manifest, sources []string) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	entry, err := newCacheEntry(file, o, m, sources)
+	if err != nil {
+		log.Debugf("fail to cache manifest: %s", err)
+		return
+	}
+	v.entries[repoDir] = entry
+
+	if !useCacheFile() || !path.IsDir(repoDir) {
+		return
+	}
+	var buf bytes.Buffer
+	if err = gob.NewEncoder(&buf).Encode(entry); err != nil {
+		return
+	}
+	// Write to a temporary file and rename, so that other processes
+	// never read a partial cache file.
+	tmpFile := cacheFile(repoDir) + ".tmp"
+	if err = ioutil.WriteFile(tmpFile, buf.Bytes(), 0644); err == nil {
+		err = os.Rename(tmpFile, cacheFile(repoDir))
+	}
+	if err != nil {
+		os.Remove(tmpFile)
+		log.Debugf("fail to save manifest cache: %s", err)
+	}
+}
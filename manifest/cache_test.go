@@ -0,0 +1,130 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func projectNames(m *Manifest) []string {
+	names := []string{}
+	for _, p := range m.Projects {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// writeOldFile writes file with mtime in the past, so that it is not
+// racily clean.
+func writeOldFile(name, content string, age time.Duration) {
+	err := ioutil.WriteFile(name, []byte(content), 0644)
+	if err != nil {
+		panic(err)
+	}
+	past := time.Now().Add(-age)
+	os.Chtimes(name, past, past)
+}
+
+func TestManifestCache(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	oldCache := manifestCache
+	manifestCache = newCache()
+	defer func() { manifestCache = oldCache }()
+
+	repoDir := filepath.Join(tmpdir, ".repo")
+	assert.Nil(os.MkdirAll(filepath.Join(repoDir, "local_manifests"), 0755))
+	manifestFile := filepath.Join(repoDir, "manifest.xml")
+	incFile := filepath.Join(repoDir, "manifest.inc")
+	writeOldFile(manifestFile, `
+<manifest>
+  <remote name="origin" fetch=".." revision="master"></remote>
+  <default remote="origin"></default>
+  <project name="main" path="main"></project>
+  <include name="manifest.inc"></include>
+</manifest>`, time.Hour)
+	writeOldFile(incFile, `
+<manifest>
+  <project name="foo" path="foo"></project>
+</manifest>`, time.Hour)
+
+	m, err := Load(repoDir)
+	assert.Nil(err)
+	assert.Equal([]string{"main", "foo"}, projectNames(m))
+	entry := manifestCache.entries[repoDir]
+	assert.NotNil(entry)
+	assert.Equal(2, len(entry.Sources))
+	assert.Equal(incFile, entry.Sources[1].Path)
+
+	// Loaded from cache, and changes of returned manifest are not saved.
+	m.Projects = nil
+	m, err = Load(repoDir)
+	assert.Nil(err)
+	assert.Equal([]string{"main", "foo"}, projectNames(m))
+	assert.Equal(entry, manifestCache.entries[repoDir])
+
+	// Touched file is checked by content.
+	past := time.Now().Add(-30 * time.Minute)
+	os.Chtimes(incFile, past, past)
+	_, err = Load(repoDir)
+	assert.Nil(err)
+	assert.Equal(entry, manifestCache.entries[repoDir])
+
+	// Included file is changed.
+	writeOldFile(incFile, `
+<manifest>
+  <project name="bar" path="bar"></project>
+</manifest>`, 20*time.Minute)
+	m, err = Load(repoDir)
+	assert.Nil(err)
+	assert.Equal([]string{"main", "bar"}, projectNames(m))
+	assert.NotEqual(entry, manifestCache.entries[repoDir])
+
+	// New local manifest.
+	writeOldFile(filepath.Join(repoDir, "local_manifests", "local.xml"), `
+<manifest>
+  <project name="baz" path="baz"></project>
+</manifest>`, time.Hour)
+	m, err = Load(repoDir)
+	assert.Nil(err)
+	assert.Equal([]string{"main", "bar", "baz"}, projectNames(m))
+
+	// Cache file is not saved by default.
+	assert.False(manifestCache.entries[repoDir] == nil)
+	_, err = os.Stat(filepath.Join(repoDir, config.ManifestCache))
+	assert.True(os.IsNotExist(err))
+
+	config.GitDefaultConfig.Set(config.CfgRepoManifestCache, "true")
+	defer config.GitDefaultConfig.Unset(config.CfgRepoManifestCache)
+	manifestCache = newCache()
+	_, err = LoadFile(repoDir, manifestFile)
+	assert.Nil(err)
+	_, err = os.Stat(filepath.Join(repoDir, config.ManifestCache))
+	assert.Nil(err)
+
+	// Load cache file in a new process.
+	manifestCache = newCache()
+	m = manifestCache.Get(repoDir, manifestFile, newLoadOptions(repoDir), []string{
+		manifestFile,
+		filepath.Join(repoDir, "local_manifests", "local.xml"),
+	})
+	assert.NotNil(m)
+	assert.Equal([]string{"main", "bar", "baz"}, projectNames(m))
+
+	// Local manifest is removed.
+	os.Remove(filepath.Join(repoDir, "local_manifests", "local.xml"))
+	m, err = Load(repoDir)
+	assert.Nil(err)
+	assert.Equal([]string{"main", "bar"}, projectNames(m))
+}
@@ -0,0 +1,118 @@
+package manifest
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+const (
+	groupDefaultConst    = "default"
+	groupAllConst        = "all"
+	groupNotDefaultConst = "notdefault"
+	groupPlatformPrefix  = "platform-"
+
+	// PlatformAuto selects platform group of the current system.
+	PlatformAuto = "auto"
+	// PlatformAll selects platform groups of all systems.
+	PlatformAll = "all"
+	// PlatformNone selects no platform group.
+	PlatformNone = "none"
+)
+
+// allPlatforms are systems which have platform groups.
+var allPlatforms = []string{"linux", "darwin", "windows"}
+
+// DefaultGroups returns groups to use if manifest.groups is not set.
+func DefaultGroups() string {
+	return groupDefaultConst + ",platform-" + runtime.GOOS
+}
+
+// PlatformGroups returns platform groups, such as "platform-linux", for
+// platform, which is "auto", "all", "none" or name of a system. Empty
+// platform is the same as "auto".
+func PlatformGroups(platform string) ([]string, error) {
+	switch platform {
+	case "", PlatformAuto:
+		return []string{groupPlatformPrefix + runtime.GOOS}, nil
+	case PlatformNone:
+		return nil, nil
+	case PlatformAll:
+		groups := []string{}
+		for _, sys := range allPlatforms {
+			groups = append(groups, groupPlatformPrefix+sys)
+		}
+		return groups, nil
+	}
+	for _, sys := range allPlatforms {
+		if platform == sys {
+			return []string{groupPlatformPrefix + sys}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid platform: %s", platform)
+}
+
+// EffectiveGroups adds platform groups to groups, so projects which are
+// only for the platform are matched. Groups are not changed if there
+// is a platform group already.
+func EffectiveGroups(groups, platform string) (string, error) {
+	for _, g := range strings.Split(groups, ",") {
+		g = strings.TrimPrefix(strings.TrimSpace(g), "-")
+		if strings.HasPrefix(g, groupPlatformPrefix) {
+			return groups, nil
+		}
+	}
+	platformGroups, err := PlatformGroups(platform)
+	if err != nil {
+		return "", err
+	}
+	if len(platformGroups) == 0 {
+		return groups, nil
+	}
+	if groups == "" {
+		groups = groupDefaultConst
+	}
+	return groups + "," + strings.Join(platformGroups, ","), nil
+}
+
+// MatchGroups checks if project has matched groups.
+func MatchGroups(match, groups string) bool {
+	matchGroups := []string{}
+	for _, g := range strings.Split(match, ",") {
+		matchGroups = append(matchGroups, strings.TrimSpace(g))
+	}
+	if len(matchGroups) == 0 {
+		matchGroups = append(matchGroups, groupDefaultConst)
+	}
+
+	projectGroups := []string{groupAllConst}
+	hasNotDefault := false
+	for _, g := range strings.Split(groups, ",") {
+		g = strings.TrimSpace(g)
+		projectGroups = append(projectGroups, g)
+		if g == groupNotDefaultConst {
+			hasNotDefault = true
+		}
+	}
+	if !hasNotDefault {
+		projectGroups = append(projectGroups, groupDefaultConst)
+	}
+
+	matched := false
+	for _, g := range matchGroups {
+		inverse := false
+		if strings.HasPrefix(g, "-") {
+			inverse = true
+			g = g[1:]
+		}
+		for _, pg := range projectGroups {
+			if pg == g {
+				matched = !inverse
+				break
+			}
+		}
+	}
+
+	return matched
+
+}
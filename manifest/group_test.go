@@ -0,0 +1,108 @@
+package manifest
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchGroups(t *testing.T) {
+	var (
+		match, groups string
+	)
+
+	assert := assert.New(t)
+
+	match = ""
+	groups = ""
+	assert.True(MatchGroups(match, groups))
+
+	match = "default"
+	groups = ""
+	assert.True(MatchGroups(match, groups))
+
+	match = "all"
+	groups = ""
+	assert.True(MatchGroups(match, groups))
+
+	match = "g3"
+	groups = "g1,g2"
+	assert.False(MatchGroups(match, groups))
+
+	match = "-g1,g2"
+	groups = "g1,g2"
+	assert.True(MatchGroups(match, groups))
+
+	match = "g1,-g2"
+	groups = "g1,g2"
+	assert.False(MatchGroups(match, groups))
+
+	match = ""
+	groups = "g1,notdefault"
+	assert.False(MatchGroups(match, groups))
+
+	match = "default"
+	groups = "g1,notdefault"
+	assert.False(MatchGroups(match, groups))
+
+	match = "all"
+	groups = "g1,notdefault"
+	assert.True(MatchGroups(match, groups))
+
+	match = "g1"
+	groups = "g1,notdefault"
+	assert.True(MatchGroups(match, groups))
+
+	match = "g2"
+	groups = "g1,notdefault"
+	assert.False(MatchGroups(match, groups))
+}
+
+func TestPlatformGroups(t *testing.T) {
+	assert := assert.New(t)
+
+	groups, err := PlatformGroups("")
+	assert.Nil(err)
+	assert.Equal([]string{"platform-" + runtime.GOOS}, groups)
+
+	groups, err = PlatformGroups("none")
+	assert.Nil(err)
+	assert.Nil(groups)
+
+	groups, err = PlatformGroups("all")
+	assert.Nil(err)
+	assert.Equal([]string{"platform-linux", "platform-darwin", "platform-windows"}, groups)
+
+	groups, err = PlatformGroups("darwin")
+	assert.Nil(err)
+	assert.Equal([]string{"platform-darwin"}, groups)
+
+	_, err = PlatformGroups("beos")
+	assert.Equal("invalid platform: beos", err.Error())
+}
+
+func TestEffectiveGroups(t *testing.T) {
+	assert := assert.New(t)
+
+	groups, err := EffectiveGroups("", "windows")
+	assert.Nil(err)
+	assert.Equal("default,platform-windows", groups)
+
+	groups, err = EffectiveGroups("g1,g2", "linux")
+	assert.Nil(err)
+	assert.Equal("g1,g2,platform-linux", groups)
+	assert.True(MatchGroups(groups, "notdefault,platform-linux"))
+	assert.False(MatchGroups(groups, "notdefault,platform-darwin"))
+
+	groups, err = EffectiveGroups("default,-platform-linux", "linux")
+	assert.Nil(err)
+	assert.Equal("default,-platform-linux", groups)
+
+	groups, err = EffectiveGroups("g1", "none")
+	assert.Nil(err)
+	assert.Equal("g1", groups)
+
+	_, err = EffectiveGroups("g1", "beos")
+	assert.NotNil(err)
+}
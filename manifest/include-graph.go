@@ -0,0 +1,119 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IncludeNode is a manifest file in include graph.
+type IncludeNode struct {
+	File  string `json:"file"`
+	Depth int    `json:"depth"`
+	// Missing is true for an optional include which does not exist.
+	Missing bool `json:"missing,omitempty"`
+}
+
+// IncludeEdge is an include from one manifest file to another.
+type IncludeEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Depth int    `json:"depth"`
+}
+
+// IncludeGraph shows which manifest file includes which. Top-level
+// manifest files, such as the manifest and local manifests, have depth
+// 0. Files are relative to repoDir if they are in it.
+type IncludeGraph struct {
+	Nodes []IncludeNode `json:"nodes"`
+	Edges []IncludeEdge `json:"edges"`
+}
+
+// relativeFile returns file relative to repoDir if file is in it.
+func relativeFile(repoDir, file string) string {
+	if file == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(repoDir, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return file
+	}
+	return filepath.ToSlash(rel)
+}
+
+// newIncludeGraph creates include graph from parsed manifest files.
+func newIncludeGraph(repoDir string, ms []*Manifest) *IncludeGraph {
+	graph := IncludeGraph{
+		Nodes: []IncludeNode{},
+		Edges: []IncludeEdge{},
+	}
+	found := make(map[string]bool)
+	for _, m := range ms {
+		file := relativeFile(repoDir, m.SourceFile)
+		if !found[file] {
+			found[file] = true
+			node := IncludeNode{File: file, Depth: m.IncludeDepth}
+			if _, err := os.Stat(m.SourceFile); os.IsNotExist(err) {
+				node.Missing = true
+			}
+			graph.Nodes = append(graph.Nodes, node)
+		}
+		if m.IncludedBy != "" {
+			graph.Edges = append(graph.Edges, IncludeEdge{
+				From:  relativeFile(repoDir, m.IncludedBy),
+				To:    file,
+				Depth: m.IncludeDepth,
+			})
+		}
+	}
+	return &graph
+}
+
+// LoadIncludeGraph parses manifest files of repoDir, and returns the
+// include graph.
+func LoadIncludeGraph(repoDir string) (*IncludeGraph, error) {
+	file, err := manifestFile(repoDir)
+	if err != nil {
+		return nil, err
+	}
+
+	o := newLoadOptions(repoDir)
+	manifests := []*Manifest{}
+	for _, f := range append([]string{file}, localManifestFiles(repoDir)...) {
+		ms, err := parseXML(f, "", nil, o)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, ms...)
+	}
+	return newIncludeGraph(repoDir, manifests), nil
+}
+
+// JSON returns include graph in JSON.
+func (v IncludeGraph) JSON() ([]byte, error) {
+	return json.MarshalIndent(v, "", "  ")
+}
+
+// DOT returns include graph in DOT language of graphviz.
+func (v IncludeGraph) DOT() []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString("digraph includes {\n")
+	buf.WriteString("  rankdir=LR;\n")
+	buf.WriteString("  node [shape=box];\n")
+	for _, node := range v.Nodes {
+		attrs := []string{fmt.Sprintf("label=%q", fmt.Sprintf("%s (%d)", node.File, node.Depth))}
+		if node.Missing {
+			attrs = append(attrs, "style=dashed")
+		}
+		buf.WriteString(fmt.Sprintf("  %q [%s];\n", node.File, strings.Join(attrs, ", ")))
+	}
+	for _, edge := range v.Edges {
+		buf.WriteString(fmt.Sprintf("  %q -> %q;\n", edge.From, edge.To))
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,78 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncludeGraph(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	repoDir := filepath.Join(tmpdir, ".repo")
+	manifestsDir := filepath.Join(repoDir, "manifests")
+	err = os.MkdirAll(manifestsDir, 0755)
+	assert.Nil(err)
+
+	err = ioutil.WriteFile(filepath.Join(repoDir, "manifest.xml"), []byte(`
+<manifest>
+  <include name="manifests/base.xml"/>
+  <include name="manifests/site.xml" optional="true"/>
+</manifest>`), 0644)
+	assert.Nil(err)
+	err = ioutil.WriteFile(filepath.Join(manifestsDir, "base.xml"), []byte(`
+<manifest>
+  <include name="remotes.xml"/>
+</manifest>`), 0644)
+	assert.Nil(err)
+	err = ioutil.WriteFile(filepath.Join(manifestsDir, "remotes.xml"), []byte(`
+<manifest>
+  <remote name="origin" fetch="https://example.com"></remote>
+</manifest>`), 0644)
+	assert.Nil(err)
+
+	graph, err := LoadIncludeGraph(repoDir)
+	assert.Nil(err)
+	assert.Equal([]IncludeNode{
+		IncludeNode{File: "manifest.xml", Depth: 0},
+		IncludeNode{File: "manifests/base.xml", Depth: 1},
+		IncludeNode{File: "manifests/remotes.xml", Depth: 2},
+		IncludeNode{File: "manifests/site.xml", Depth: 1, Missing: true},
+	}, graph.Nodes)
+	assert.Equal([]IncludeEdge{
+		IncludeEdge{From: "manifest.xml", To: "manifests/base.xml", Depth: 1},
+		IncludeEdge{From: "manifests/base.xml", To: "manifests/remotes.xml", Depth: 2},
+		IncludeEdge{From: "manifest.xml", To: "manifests/site.xml", Depth: 1},
+	}, graph.Edges)
+
+	assert.Equal(`digraph includes {
+  rankdir=LR;
+  node [shape=box];
+  "manifest.xml" [label="manifest.xml (0)"];
+  "manifests/base.xml" [label="manifests/base.xml (1)"];
+  "manifests/remotes.xml" [label="manifests/remotes.xml (2)"];
+  "manifests/site.xml" [label="manifests/site.xml (1)", style=dashed];
+  "manifest.xml" -> "manifests/base.xml";
+  "manifests/base.xml" -> "manifests/remotes.xml";
+  "manifest.xml" -> "manifests/site.xml";
+}
+`, string(graph.DOT()))
+
+	// Limit depth of includes.
+	o := loadOptions{MaxIncludeDepth: 1}
+	_, err = parseXML(filepath.Join(repoDir, "manifest.xml"), "", nil, &o)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "exceeded maximum include depth (1)")
+	o.MaxIncludeDepth = 2
+	_, err = parseXML(filepath.Join(repoDir, "manifest.xml"), "", nil, &o)
+	assert.Nil(err)
+}
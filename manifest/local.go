@@ -0,0 +1,165 @@
+package manifest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/file"
+)
+
+// LocalManifestFiles returns xml files in local_manifests dir of repoDir,
+// sorted by name.
+func LocalManifestFiles(repoDir string) ([]string, error) {
+	dir := filepath.Join(repoDir, config.LocalManifests)
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	files := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".xml") {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// LoadLocalManifest loads a local manifest file without includes. Returns
+// an empty manifest if file does not exist.
+func LoadLocalManifest(file string) (*Manifest, error) {
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return &Manifest{SourceFile: file}, nil
+	}
+	m, err := unmarshalFile(file, false)
+	if err != nil {
+		return nil, err
+	}
+	m.SourceFile = file
+	return m, nil
+}
+
+// IsEmpty indicates manifest has no elements.
+func (v Manifest) IsEmpty() bool {
+	return v.Notice == "" &&
+		len(v.Remotes) == 0 &&
+		v.Default == nil &&
+		v.Server == nil &&
+		len(v.Projects) == 0 &&
+		len(v.RemoveProjects) == 0 &&
+		len(v.ExtendProjects) == 0 &&
+		len(v.OverrideProjects) == 0 &&
+		v.RepoHooks == nil &&
+		len(v.Includes) == 0
+}
+
+// Save writes manifest to file.
+func (v Manifest) Save(filename string) error {
+	data, err := Marshal(&v)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	f, err := file.New(filename).OpenCreateRewrite()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(xml.Header + string(data) + "\n")
+	return err
+}
+
+// SetExtendProject adds extend-project element, or updates the one with
+// the same name and path.
+func (v *Manifest) SetExtendProject(p ExtendProject) {
+	for i := range v.ExtendProjects {
+		if v.ExtendProjects[i].Name == p.Name && v.ExtendProjects[i].Path == p.Path {
+			v.ExtendProjects[i] = p
+			return
+		}
+	}
+	v.ExtendProjects = append(v.ExtendProjects, p)
+}
+
+// AddProject adds project element, fails if path is already used.
+func (v *Manifest) AddProject(p Project) error {
+	for _, project := range v.Projects {
+		if project.Path == p.Path {
+			return fmt.Errorf("project of path '%s' already exists in '%s'",
+				p.Path, v.SourceFile)
+		}
+	}
+	v.Projects = append(v.Projects, p)
+	return nil
+}
+
+// AddRemoveProject adds remove-project element for project of name.
+func (v *Manifest) AddRemoveProject(name string) {
+	for _, r := range v.RemoveProjects {
+		if r.Name == name {
+			return
+		}
+	}
+	v.RemoveProjects = append(v.RemoveProjects, RemoveProject{Name: name})
+}
+
+// RemoveEntries removes project, extend-project, override-project and
+// remove-project elements which match name or path, and returns number
+// of removed elements.
+func (v *Manifest) RemoveEntries(nameOrPath string) int {
+	count := 0
+
+	projects := []Project{}
+	for _, p := range v.Projects {
+		if p.Name == nameOrPath || p.Path == nameOrPath {
+			count++
+			continue
+		}
+		projects = append(projects, p)
+	}
+	v.Projects = projects
+
+	extends := []ExtendProject{}
+	for _, p := range v.ExtendProjects {
+		if p.Name == nameOrPath || p.Path == nameOrPath {
+			count++
+			continue
+		}
+		extends = append(extends, p)
+	}
+	v.ExtendProjects = extends
+
+	overrides := []OverrideProject{}
+	for _, p := range v.OverrideProjects {
+		if p.Name == nameOrPath || p.Path == nameOrPath {
+			count++
+			continue
+		}
+		overrides = append(overrides, p)
+	}
+	v.OverrideProjects = overrides
+
+	removes := []RemoveProject{}
+	for _, p := range v.RemoveProjects {
+		if p.Name == nameOrPath {
+			count++
+			continue
+		}
+		removes = append(removes, p)
+	}
+	v.RemoveProjects = removes
+
+	return count
+}
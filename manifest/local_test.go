@@ -0,0 +1,66 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalManifestEntries(t *testing.T) {
+	assert := assert.New(t)
+
+	m := Manifest{}
+	assert.True(m.IsEmpty())
+
+	m.SetExtendProject(ExtendProject{Name: "main", Path: "main", Revision: "v1"})
+	m.SetExtendProject(ExtendProject{Name: "main", Path: "main", Revision: "v2"})
+	assert.Equal(1, len(m.ExtendProjects))
+	assert.Equal("v2", m.ExtendProjects[0].Revision)
+
+	assert.Nil(m.AddProject(Project{Name: "tools", Path: "tools"}))
+	assert.NotNil(m.AddProject(Project{Name: "tools2", Path: "tools"}))
+	m.AddRemoveProject("drivers/driver-1")
+	m.AddRemoveProject("drivers/driver-1")
+	assert.Equal(1, len(m.RemoveProjects))
+	assert.False(m.IsEmpty())
+
+	assert.Equal(0, m.RemoveEntries("unknown"))
+	assert.Equal(1, m.RemoveEntries("tools"))
+	assert.Equal(1, m.RemoveEntries("main"))
+	assert.Equal(1, m.RemoveEntries("drivers/driver-1"))
+	assert.True(m.IsEmpty())
+}
+
+func TestLocalManifestSaveAndLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	files, err := LocalManifestFiles(tmpdir)
+	assert.Nil(err)
+	assert.Equal(0, len(files))
+
+	filename := filepath.Join(tmpdir, "local_manifests", "local.xml")
+	m, err := LoadLocalManifest(filename)
+	assert.Nil(err)
+	assert.True(m.IsEmpty())
+
+	m.SetExtendProject(ExtendProject{Name: "main", Path: "main", Revision: "v1"})
+	assert.Nil(m.Save(filename))
+	files, err = LocalManifestFiles(tmpdir)
+	assert.Nil(err)
+	assert.Equal([]string{filename}, files)
+
+	m, err = LoadLocalManifest(filename)
+	assert.Nil(err)
+	assert.Equal(filename, m.SourceFile)
+	assert.Equal(1, len(m.ExtendProjects))
+	assert.Equal("v1", m.ExtendProjects[0].Revision)
+}
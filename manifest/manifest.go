@@ -22,12 +22,13 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/path"
 	"github.com/jiangxin/goconfig"
-	log "github.com/jiangxin/multi-log"
 )
 
 // Macros for manifest
@@ -35,19 +36,34 @@ const (
 	maxRecursiveDepth = 10
 )
 
+// remoteTypes are known types of remote, which select the protocol to
+// upload and download code reviews.
+var remoteTypes = []string{"agit", "gerrit", "github", "gitlab", "none"}
+
 // Manifest is for toplevel XML structure.
 type Manifest struct {
-	XMLName        xml.Name        `xml:"manifest"`
-	Notice         string          `xml:"notice,omitempty"`
-	Remotes        []Remote        `xml:"remote,omitempty"`
-	Default        *Default        `xml:"default,omitempty"`
-	Server         *Server         `xml:"manifest-server,omitempty"`
-	Projects       []Project       `xml:"project,omitempty"`
-	RemoveProjects []RemoveProject `xml:"remove-project,omitempty"`
-	ExtendProjects []ExtendProject `xml:"extend-project,omitempty"`
-	RepoHooks      *RepoHooks      `xml:"repo-hooks,omitempty"`
-	Includes       []Include       `xml:"include,omitempty"`
-	SourceFile     string          `xml:"-"`
+	XMLName          xml.Name          `xml:"manifest"`
+	Notice           string            `xml:"notice,omitempty"`
+	Remotes          []Remote          `xml:"remote,omitempty"`
+	Default          *Default          `xml:"default,omitempty"`
+	Server           *Server           `xml:"manifest-server,omitempty"`
+	Projects         []Project         `xml:"project,omitempty"`
+	RemoveProjects   []RemoveProject   `xml:"remove-project,omitempty"`
+	ExtendProjects   []ExtendProject   `xml:"extend-project,omitempty"`
+	OverrideProjects []OverrideProject `xml:"override-project,omitempty"`
+	RepoHooks        *RepoHooks        `xml:"repo-hooks,omitempty"`
+	Includes         []Include         `xml:"include,omitempty"`
+	SourceFile       string            `xml:"-"`
+	// IncludedBy is the file which includes SourceFile, and IncludeDepth
+	// is the depth of the include, 0 for a top-level manifest file.
+	IncludedBy   string `xml:"-"`
+	IncludeDepth int    `xml:"-"`
+	// DefaultSources records which manifest file sets each attribute
+	// of the merged default element, for debugging.
+	DefaultSources map[string]string `xml:"-"`
+	// ProjectSources records which manifest files add and change each
+	// project of the merged manifest by path, for debugging.
+	ProjectSources map[string][]string `xml:"-"`
 }
 
 // Remote is for remote XML element.
@@ -60,6 +76,26 @@ type Remote struct {
 	Review   string `xml:"review,attr,omitempty"`
 	Revision string `xml:"revision,attr,omitempty"`
 	Type     string `xml:"type,attr,omitempty"`
+
+	ReviewURLTemplate string `xml:"review-url-template,attr,omitempty"`
+}
+
+// CheckType checks whether type of remote is known. Empty type is valid,
+// and type of remote is probed from its review server. Unknown types may
+// be handled by external proto-helpers, and are only warned by Merge.
+func (v Remote) CheckType() error {
+	if v.Type == "" {
+		return nil
+	}
+	for _, t := range remoteTypes {
+		if strings.ToLower(v.Type) == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown type '%s' of remote '%s', must be one of: %s",
+		v.Type,
+		v.Name,
+		strings.Join(remoteTypes, ", "))
 }
 
 // Default is for default XML element.
@@ -73,6 +109,96 @@ type Default struct {
 	SyncC      string `xml:"sync-c,attr,omitempty"`
 	SyncS      string `xml:"sync-s,attr,omitempty"`
 	SyncTags   string `xml:"sync-tags,attr,omitempty"`
+	// CommitTemplate is file in manifests project, which is set as
+	// "commit.template" of projects by sync.
+	CommitTemplate string `xml:"commit-template,attr,omitempty"`
+
+	// GitConfigs are inherited by all projects.
+	GitConfigs []GitConfig `xml:"config,omitempty"`
+}
+
+// stringAttrs returns string attributes of default by name.
+func (v *Default) stringAttrs() []struct {
+	name  string
+	value *string
+} {
+	return []struct {
+		name  string
+		value *string
+	}{
+		{"remote", &v.RemoteName},
+		{"revision", &v.Revision},
+		{"dest-branch", &v.DestBranch},
+		{"upstream", &v.Upstream},
+		{"sync-c", &v.SyncC},
+		{"sync-s", &v.SyncS},
+		{"sync-tags", &v.SyncTags},
+		{"commit-template", &v.CommitTemplate},
+	}
+}
+
+// merge merges attributes of d from source into v, and sources records
+// file of each attribute. Attributes not set yet are added, and it fails
+// if an attribute is set to another value, unless d has override set,
+// which replaces the whole default.
+func (v *Default) merge(d *Default, source string, sources map[string]string) error {
+	if d.Override {
+		*v = *d
+		for k := range sources {
+			delete(sources, k)
+		}
+	}
+
+	attrs := v.stringAttrs()
+	for i, attr := range d.stringAttrs() {
+		if *attr.value == "" {
+			continue
+		}
+		if value := attrs[i].value; *value == "" || *value == *attr.value {
+			*value = *attr.value
+			if sources[attr.name] == "" {
+				sources[attr.name] = source
+			}
+		} else {
+			return fmt.Errorf("duplicate default in %s, attribute '%s' is set in %s. "+
+				"If you want to override, set atrribute 'override' true",
+				source, attr.name, sources[attr.name])
+		}
+	}
+	for _, c := range d.GitConfigs {
+		key := "config " + c.Name
+		found := false
+		for _, vc := range v.GitConfigs {
+			if vc.Name != c.Name {
+				continue
+			}
+			if vc.Value != c.Value {
+				return fmt.Errorf("duplicate default in %s, config '%s' is set in %s. "+
+					"If you want to override, set atrribute 'override' true",
+					source, c.Name, sources[key])
+			}
+			found = true
+		}
+		if !found {
+			v.GitConfigs = append(v.GitConfigs, c)
+		}
+		if sources[key] == "" {
+			sources[key] = source
+		}
+	}
+	if d.SyncJ != 0 {
+		if v.SyncJ == 0 || v.SyncJ == d.SyncJ {
+			v.SyncJ = d.SyncJ
+			if sources["sync-j"] == "" {
+				sources["sync-j"] = source
+			}
+		} else {
+			return fmt.Errorf("duplicate default in %s, attribute '%s' is set in %s. "+
+				"If you want to override, set atrribute 'override' true",
+				source, "sync-j", sources["sync-j"])
+		}
+	}
+	return nil
 }
 
 // Server is for manifest-server XML element.
@@ -87,6 +213,9 @@ type Project struct {
 	Projects    []Project    `xml:"project,omitempty"`
 	CopyFiles   []CopyFile   `xml:"copyfile,omitempty"`
 	LinkFiles   []LinkFile   `xml:"linkfile,omitempty"`
+	GitConfigs  []GitConfig  `xml:"config,omitempty"`
+	// SparseCheckouts are directories to check out in sparse checkout.
+	SparseCheckouts []SparseCheckout `xml:"sparse-checkout,omitempty"`
 
 	Name       string `xml:"name,attr,omitempty"`
 	Path       string `xml:"path,attr,omitempty"`
@@ -113,6 +242,19 @@ type Annotation struct {
 	Keep  string `xml:"keep,attr,omitempty"`
 }
 
+// GitConfig is for config XML element, which sets git config of project
+// by sync.
+type GitConfig struct {
+	Name  string `xml:"name,attr,omitempty"`
+	Value string `xml:"value,attr,omitempty"`
+}
+
+// SparseCheckout is for sparse-checkout XML element, which is a directory
+// of project to check out in sparse checkout.
+type SparseCheckout struct {
+	Path string `xml:"path,attr,omitempty"`
+}
+
 // CopyFile is for copyfile XML element.
 type CopyFile struct {
 	Src  string `xml:"src,attr,omitempty"`
@@ -131,6 +273,47 @@ type ExtendProject struct {
 	Path     string `xml:"path,attr,omitempty"`
 	Groups   string `xml:"groups,attr,omitempty"`
 	Revision string `xml:"revision,attr,omitempty"`
+	Upstream string `xml:"upstream,attr,omitempty"`
+}
+
+// OverrideProject is for override-project XML element, which replaces
+// attributes of an existing project.
+type OverrideProject struct {
+	Name       string `xml:"name,attr,omitempty"`
+	Path       string `xml:"path,attr,omitempty"`
+	RemoteName string `xml:"remote,attr,omitempty"`
+	Revision   string `xml:"revision,attr,omitempty"`
+	DestBranch string `xml:"dest-branch,attr,omitempty"`
+	Groups     string `xml:"groups,attr,omitempty"`
+	Rebase     string `xml:"rebase,attr,omitempty"`
+	SyncC      string `xml:"sync-c,attr,omitempty"`
+	SyncS      string `xml:"sync-s,attr,omitempty"`
+	SyncTags   string `xml:"sync-tags,attr,omitempty"`
+	Upstream   string `xml:"upstream,attr,omitempty"`
+	CloneDepth string `xml:"clone-depth,attr,omitempty"`
+}
+
+// apply replaces attributes of project p which are set in override.
+func (v OverrideProject) apply(p *Project) {
+	for _, attr := range []struct {
+		value string
+		field *string
+	}{
+		{v.RemoteName, &p.RemoteName},
+		{v.Revision, &p.Revision},
+		{v.DestBranch, &p.DestBranch},
+		{v.Groups, &p.Groups},
+		{v.Rebase, &p.Rebase},
+		{v.SyncC, &p.SyncC},
+		{v.SyncS, &p.SyncS},
+		{v.SyncTags, &p.SyncTags},
+		{v.Upstream, &p.Upstream},
+		{v.CloneDepth, &p.CloneDepth},
+	} {
+		if attr.value != "" {
+			*attr.field = attr.value
+		}
+	}
 }
 
 // RemoveProject is for remove-project XML element.
@@ -144,9 +327,33 @@ type RepoHooks struct {
 	EnabledList string `xml:"enabled-list,attr,omitempty"`
 }
 
+// EnabledHooks returns names of hooks in enabled-list, which are separated
+// by spaces or commas.
+func (v RepoHooks) EnabledHooks() []string {
+	return strings.FieldsFunc(v.EnabledList, func(c rune) bool {
+		return c == ',' || c == ' ' || c == '\t' || c == '\n'
+	})
+}
+
 // Include is for include XML element.
 type Include struct {
-	Name string `xml:"name,attr,omitempty"`
+	Name     string `xml:"name,attr,omitempty"`
+	Optional string `xml:"optional,attr,omitempty"`
+	Groups   string `xml:"groups,attr,omitempty"`
+}
+
+// IsOptional indicates it is OK if included file does not exist.
+func (v Include) IsOptional() bool {
+	return isTrue(v.Optional, false)
+}
+
+// MatchGroups checks whether file should be included for groups of
+// workspace. Include without groups always matches.
+func (v Include) MatchGroups(groups string) bool {
+	if v.Groups == "" {
+		return true
+	}
+	return MatchGroups(groups, v.Groups)
 }
 
 // AllProjects returns all projects (include current project and all sub-projects)
@@ -202,20 +409,43 @@ func (v *Project) AllProjects(parent *Project) []Project {
 	return projects
 }
 
+// parseBool parses a boolean attribute with values repo accepts, and
+// reports whether value is a valid boolean.
+func parseBool(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "1", "t", "y", "on":
+		return true, true
+	case "false", "no", "0", "f", "n", "off":
+		return false, true
+	}
+	return false, false
+}
+
 func isTrue(value string, def bool) bool {
 	if value == "" {
 		return def
 	}
-	value = strings.ToLower(value)
-	if value == "true" ||
-		value == "yes" ||
-		value == "1" ||
-		value == "t" ||
-		value == "y" ||
-		value == "on" {
-		return true
+	result, ok := parseBool(value)
+	if !ok {
+		log.Warnf("invalid boolean value '%s', use default: %v", value, def)
+		return def
 	}
-	return false
+	return result
+}
+
+// SyncCBool returns sync-c attribute of default element as bool.
+func (v Default) SyncCBool() bool {
+	return isTrue(v.SyncC, false)
+}
+
+// SyncSBool returns sync-s attribute of default element as bool.
+func (v Default) SyncSBool() bool {
+	return isTrue(v.SyncS, false)
+}
+
+// SyncTagsBool returns sync-tags attribute of default element as bool.
+func (v Default) SyncTagsBool() bool {
+	return isTrue(v.SyncTags, true)
 }
 
 // IsRebase indicates a project should use rebase instead of reset for syncing.
@@ -223,19 +453,96 @@ func (v Project) IsRebase() bool {
 	return isTrue(v.Rebase, true)
 }
 
-// IsSyncS indicates a project should sync submodules.
-func (v Project) IsSyncS() bool {
+// SyncCBool indicates a project should sync current branch only. Projects
+// returned by AllProjects inherit the attribute from default element.
+func (v Project) SyncCBool() bool {
+	return isTrue(v.SyncC, false)
+}
+
+// SyncSBool indicates a project should sync submodules. Projects returned
+// by AllProjects inherit the attribute from default element.
+func (v Project) SyncSBool() bool {
 	return isTrue(v.SyncS, false)
 }
 
-// IsSyncC indicates a project should sync current branch.
+// SyncTagsBool indicates a project should sync tags. Projects returned by
+// AllProjects inherit the attribute from default element.
+func (v Project) SyncTagsBool() bool {
+	return isTrue(v.SyncTags, true)
+}
+
+// ForcePathBool returns force-path attribute of project as bool.
+func (v Project) ForcePathBool() bool {
+	return isTrue(v.ForcePath, false)
+}
+
+// CloneDepthInt returns clone-depth attribute of project, 0 means full
+// clone.
+func (v Project) CloneDepthInt() int {
+	if v.CloneDepth == "" {
+		return 0
+	}
+	depth, err := strconv.Atoi(v.CloneDepth)
+	if err != nil || depth < 0 {
+		log.Warnf("invalid clone-depth '%s' for project '%s'",
+			v.CloneDepth, v.Name)
+		return 0
+	}
+	return depth
+}
+
+// IsSyncS is an alias of SyncSBool.
+func (v Project) IsSyncS() bool {
+	return v.SyncSBool()
+}
+
+// IsSyncC is an alias of SyncCBool.
 func (v Project) IsSyncC() bool {
-	return isTrue(v.SyncC, false)
+	return v.SyncCBool()
 }
 
-// IsSyncTags indicates a project should sync tags.
+// IsSyncTags is an alias of SyncTagsBool.
 func (v Project) IsSyncTags() bool {
-	return isTrue(v.SyncTags, true)
+	return v.SyncTagsBool()
+}
+
+// IsKeep indicates the annotation should be kept, and is exported to
+// environment of forall and hooks. Default is true.
+func (v Annotation) IsKeep() bool {
+	return isTrue(v.Keep, true)
+}
+
+// GetAnnotation returns value of annotation of name, and whether it is
+// defined.
+func (v Project) GetAnnotation(name string) (string, bool) {
+	for _, a := range v.Annotations {
+		if a.Name == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// AnnotationBool returns value of annotation of name as bool, or def if
+// it is not defined.
+func (v Project) AnnotationBool(name string, def bool) bool {
+	value, _ := v.GetAnnotation(name)
+	return isTrue(value, def)
+}
+
+// SparseCheckoutPaths returns directories of sparse-checkout elements of
+// project. Paths outside the project are ignored.
+func (v Project) SparseCheckoutPaths() []string {
+	paths := []string{}
+	for _, s := range v.SparseCheckouts {
+		p := strings.Trim(filepath.ToSlash(filepath.Clean(s.Path)), "/")
+		if p == "" || p == "." || p == ".." || strings.HasPrefix(p, "../") {
+			log.Warnf("bad path '%s' of sparse-checkout in project '%s'", s.Path, v.Name)
+			continue
+		}
+		paths = append(paths, p)
+	}
+	return paths
 }
 
 // IsMetaProject indicates current project is a ManifestProject or not.
@@ -254,60 +561,183 @@ func (v *Manifest) allProjects() []Project {
 // AllProjects returns all projects and fill missing fields
 func (v *Manifest) AllProjects() []Project {
 	projects := v.allProjects()
-	remotes := make(map[string]*Remote)
+	for i := range projects {
+		resolved, err := v.ResolveProject(projects[i])
+		if err != nil {
+			log.Fatal(err)
+		}
+		projects[i] = resolved.Project
+	}
+	return projects
+}
+
+// ResolvedProject is a project whose missing attributes are filled from
+// its remote and the default element.
+type ResolvedProject struct {
+	Project
+
+	// GitRemoteName is name of the remote in git config of project,
+	// which is alias of the manifest remote if set.
+	GitRemoteName string
+	// RevisionFrom is where revision comes from, one of "project",
+	// "remote" and "default".
+	RevisionFrom string
+}
+
+// ResolveProject resolves effective attributes of project p in order:
+//
+//   - remote: remote of project, or remote of default element
+//   - revision: revision of project, revision of remote, or revision of
+//     default element
+//   - name of git remote: alias of remote, or name of remote
+//   - other attributes: attributes of project, or attributes of default
+//     element
+//   - configs: configs of project, and configs of default element which
+//     are not set by project
+func (v *Manifest) ResolveProject(p Project) (*ResolvedProject, error) {
+	r := ResolvedProject{Project: p}
+
+	if r.RemoteName == "" {
+		if v.Default == nil || v.Default.RemoteName == "" {
+			return nil, fmt.Errorf("no remote defined for for project '%s'", p.Name)
+		}
+		r.RemoteName = v.Default.RemoteName
+	}
+	r.ManifestRemote = nil
 	for i := range v.Remotes {
-		remotes[v.Remotes[i].Name] = &v.Remotes[i]
+		if v.Remotes[i].Name == r.RemoteName {
+			r.ManifestRemote = &v.Remotes[i]
+			break
+		}
+	}
+	if r.ManifestRemote == nil {
+		return nil, fmt.Errorf("cannot find remote '%s' for project '%s'",
+			r.RemoteName,
+			p.Name)
+	}
+	r.GitRemoteName = r.ManifestRemote.Alias
+	if r.GitRemoteName == "" {
+		r.GitRemoteName = r.ManifestRemote.Name
 	}
 
-	for i := range projects {
-		if projects[i].RemoteName == "" {
-			if v.Default == nil || v.Default.RemoteName == "" {
-				log.Fatalf("no remote defined for for project '%s'",
-					projects[i].Name)
-			}
-			projects[i].RemoteName = v.Default.RemoteName
+	if r.Revision != "" {
+		r.RevisionFrom = "project"
+	} else if r.ManifestRemote.Revision != "" {
+		r.Revision = r.ManifestRemote.Revision
+		r.RevisionFrom = "remote"
+	}
+
+	if v.Default != nil {
+		if r.Revision == "" && v.Default.Revision != "" {
+			r.Revision = v.Default.Revision
+			r.RevisionFrom = "default"
 		}
-		projects[i].ManifestRemote = remotes[projects[i].RemoteName]
-		if projects[i].ManifestRemote == nil {
-			log.Fatalf("cannot find remote '%s' for project '%s'",
-				projects[i].RemoteName,
-				projects[i].Name)
+		for _, attr := range []struct {
+			value string
+			field *string
+		}{
+			{v.Default.DestBranch, &r.DestBranch},
+			{v.Default.Upstream, &r.Upstream},
+			{v.Default.SyncC, &r.SyncC},
+			{v.Default.SyncS, &r.SyncS},
+			{v.Default.SyncTags, &r.SyncTags},
+		} {
+			if *attr.field == "" {
+				*attr.field = attr.value
+			}
 		}
+		r.GitConfigs = inheritGitConfigs(r.GitConfigs, v.Default.GitConfigs)
+	}
+
+	if r.Revision == "" {
+		return nil, fmt.Errorf("no revision for project '%s'", p.Name)
+	}
+	return &r, nil
+}
 
-		if projects[i].Revision == "" {
-			projects[i].Revision = projects[i].ManifestRemote.Revision
+// inheritGitConfigs returns configs of project, with configs of default
+// element which are not set by project.
+func inheritGitConfigs(configs, inherited []GitConfig) []GitConfig {
+	if len(inherited) == 0 {
+		return configs
+	}
+	result := append([]GitConfig{}, configs...)
+	for _, c := range inherited {
+		if findGitConfig(configs, c.Name) == nil {
+			result = append(result, c)
 		}
+	}
+	return result
+}
 
-		if v.Default != nil {
-			if projects[i].Revision == "" {
-				projects[i].Revision = v.Default.Revision
-			}
-			if projects[i].DestBranch == "" {
-				projects[i].DestBranch = v.Default.DestBranch
-			}
-			if projects[i].Upstream == "" {
-				projects[i].Upstream = v.Default.Upstream
-			}
-			if projects[i].SyncC == "" {
-				projects[i].SyncC = v.Default.SyncC
-			}
-			if projects[i].SyncS == "" {
-				projects[i].SyncS = v.Default.SyncS
-			}
-			if projects[i].SyncTags == "" {
-				projects[i].SyncTags = v.Default.SyncTags
-			}
+// findGitConfig returns config of name, or nil if not found.
+func findGitConfig(configs []GitConfig, name string) *GitConfig {
+	for i := range configs {
+		if configs[i].Name == name {
+			return &configs[i]
 		}
+	}
+	return nil
+}
+
+// DuplicatePolicy decides what to do if a project of the same path is
+// found while merging manifests.
+type DuplicatePolicy string
 
-		if projects[i].Revision == "" {
-			log.Fatalf("no revision for project '%s'", projects[i].Name)
+// Policies for projects of duplicate paths.
+const (
+	// DuplicateError fails to merge.
+	DuplicateError DuplicatePolicy = "error"
+	// DuplicateLastWins replaces the project by the one merged later.
+	DuplicateLastWins DuplicatePolicy = "last-wins"
+	// DuplicateFirstWins ignores the project merged later.
+	DuplicateFirstWins DuplicatePolicy = "first-wins"
+)
+
+// ParseDuplicatePolicy parses policy for duplicate paths, and empty
+// policy is "error".
+func ParseDuplicatePolicy(policy string) (DuplicatePolicy, error) {
+	switch DuplicatePolicy(strings.ToLower(policy)) {
+	case "", DuplicateError:
+		return DuplicateError, nil
+	case DuplicateLastWins:
+		return DuplicateLastWins, nil
+	case DuplicateFirstWins:
+		return DuplicateFirstWins, nil
+	}
+	return DuplicateError, fmt.Errorf("unknown policy for duplicate path: %s", policy)
+}
+
+// changeSource returns provenance of a change of project by element in
+// file, with attributes which are set in pairs of name and value.
+func changeSource(element, file string, attrs ...string) string {
+	changes := []string{}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if attrs[i+1] != "" {
+			changes = append(changes, attrs[i]+"="+attrs[i+1])
 		}
 	}
-	return projects
+	if len(changes) == 0 {
+		return element + " in " + file
+	}
+	return fmt.Sprintf("%s in %s: %s", element, file, strings.Join(changes, " "))
 }
 
 // Merge implements merging another manifest to self.
 func (v *Manifest) Merge(m *Manifest) error {
+	return v.MergeWithPolicy(m, DuplicateError)
+}
+
+// MergeWithPolicy merges another manifest to self, and projects of
+// duplicate paths in m are handled by policy.
+//
+// Elements of m take effect in order: remote, default, manifest-server,
+// remove-project, project, extend-project, and override-project. So
+// remove-project only removes projects of earlier layers (manifest files
+// merged before m), and m can add a project again at the freed path.
+// Extend-project and override-project can change projects defined in m
+// itself, and override-project wins if both change the same attribute.
+func (v *Manifest) MergeWithPolicy(m *Manifest, policy DuplicatePolicy) error {
 	if m.Notice != "" {
 		if v.Notice == "" {
 			v.Notice = m.Notice
@@ -317,6 +747,9 @@ func (v *Manifest) Merge(m *Manifest) error {
 	}
 
 	for _, r1 := range m.Remotes {
+		if err := r1.CheckType(); err != nil {
+			log.Warnf("%s in '%s', and is left to external proto-helper", err, m.SourceFile)
+		}
 		found := false
 		for idx, r2 := range v.Remotes {
 			if r1.Name == r2.Name {
@@ -336,11 +769,22 @@ func (v *Manifest) Merge(m *Manifest) error {
 	}
 
 	if m.Default != nil {
-		if m.Default.Override || v.Default == nil {
-			v.Default = m.Default
-		} else if !reflect.DeepEqual(v.Default, m.Default) {
-			return fmt.Errorf("duplicate default in %s. If you want to override, set atrribute 'override' true", m.SourceFile)
+		// Merge into copies, which are not changed if fail.
+		d := Default{}
+		if v.Default != nil {
+			d = *v.Default
 		}
+		sources := make(map[string]string)
+		for k, file := range v.DefaultSources {
+			sources[k] = file
+		}
+		if err := d.merge(m.Default, m.SourceFile, sources); err != nil {
+			return err
+		}
+		v.Default = &d
+		v.DefaultSources = sources
+		log.Debugf("merge default from '%s', sources of attributes: %v",
+			m.SourceFile, v.DefaultSources)
 	}
 
 	if m.Server != nil {
@@ -352,41 +796,70 @@ func (v *Manifest) Merge(m *Manifest) error {
 		}
 	}
 
+	// Remove projects of earlier layers before adding projects of m, so
+	// that m can re-add a project at the path freed by remove-project.
+	rmName := make(map[string]bool)
+	for _, r := range m.RemoveProjects {
+		rmName[cleanPath(r.Name)] = true
+	}
 	realPath := make(map[string]bool)
+	oldProjects := []Project{}
 	for _, p := range v.allProjects() {
+		if rmName[p.Name] {
+			continue
+		}
 		if realPath[p.Path] {
 			return fmt.Errorf("duplicate path for project '%s' in '%s'",
 				p.Path,
 				v.SourceFile)
 		}
 		realPath[p.Path] = true
+		oldProjects = append(oldProjects, p)
+	}
+	v.Projects = oldProjects
+	if v.ProjectSources == nil {
+		v.ProjectSources = make(map[string][]string)
+	}
+	for projectPath := range v.ProjectSources {
+		if !realPath[projectPath] {
+			delete(v.ProjectSources, projectPath)
+		}
 	}
 	for _, p := range m.allProjects() {
 		p.Name = cleanPath(p.Name)
 		p.Path = cleanPath(p.Path)
 		if realPath[p.Path] {
+			switch policy {
+			case DuplicateFirstWins:
+				log.Debugf("ignore project of duplicate path '%s' in '%s'",
+					p.Path,
+					m.SourceFile)
+				continue
+			case DuplicateLastWins:
+				for i := range v.Projects {
+					if v.Projects[i].Path == p.Path {
+						v.Projects[i] = p
+					}
+				}
+				v.ProjectSources[p.Path] = []string{"project in " + m.SourceFile}
+				log.Debugf("override project of path '%s' by '%s'",
+					p.Path,
+					m.SourceFile)
+				continue
+			}
 			return fmt.Errorf("duplicate path for project '%s' in '%s'",
 				p.Path,
 				m.SourceFile)
 		}
+		if err := checkNestedPath(&p, oldProjects); err != nil {
+			return fmt.Errorf("%s in '%s'", err, m.SourceFile)
+		}
 		v.Projects = append(v.Projects, p)
 		realPath[p.Path] = true
+		v.ProjectSources[p.Path] = []string{"project in " + m.SourceFile}
 	}
 
-	rmPath := make(map[string]bool)
-	for _, r := range m.RemoveProjects {
-		r.Name = cleanPath(r.Name)
-		rmPath[r.Name] = true
-	}
-	ps := []Project{}
-	for _, p := range v.allProjects() {
-		if rmPath[p.Name] {
-			realPath[p.Path] = false
-		} else {
-			ps = append(ps, p)
-		}
-	}
-	v.Projects = ps
+	v.Projects = v.allProjects()
 
 	extPath := make(map[string]ExtendProject)
 	for _, p := range m.ExtendProjects {
@@ -407,15 +880,79 @@ func (v *Manifest) Merge(m *Manifest) error {
 				if p2.Revision != "" {
 					v.Projects[i].Revision = p2.Revision
 				}
+				if p2.Upstream != "" {
+					v.Projects[i].Upstream = p2.Upstream
+				}
+				v.ProjectSources[p.Path] = append(v.ProjectSources[p.Path],
+					changeSource("extend-project", m.SourceFile,
+						"groups", p2.Groups,
+						"revision", p2.Revision,
+						"upstream", p2.Upstream))
+			}
+		}
+	}
+
+	for _, o := range m.OverrideProjects {
+		o.Name = cleanPath(o.Name)
+		if o.Path != "" {
+			o.Path = cleanPath(o.Path)
+		}
+		found := false
+		for i, p := range v.Projects {
+			if p.Name == o.Name && (o.Path == "" || o.Path == p.Path) {
+				o.apply(&v.Projects[i])
+				found = true
+				v.ProjectSources[p.Path] = append(v.ProjectSources[p.Path],
+					changeSource("override-project", m.SourceFile,
+						"remote", o.RemoteName,
+						"revision", o.Revision,
+						"dest-branch", o.DestBranch,
+						"groups", o.Groups,
+						"rebase", o.Rebase,
+						"sync-c", o.SyncC,
+						"sync-s", o.SyncS,
+						"sync-tags", o.SyncTags,
+						"upstream", o.Upstream,
+						"clone-depth", o.CloneDepth))
 			}
 		}
+		if !found {
+			return fmt.Errorf("no project '%s' to override in '%s'", o.Name, m.SourceFile)
+		}
 	}
 
-	// m.RepoHooks
+	if m.RepoHooks != nil {
+		if v.RepoHooks == nil {
+			v.RepoHooks = m.RepoHooks
+		} else if !reflect.DeepEqual(v.RepoHooks, m.RepoHooks) {
+			return fmt.Errorf("duplicate repo-hooks in %s", m.SourceFile)
+		}
+	}
 
 	return nil
 }
 
+// checkNestedPath fails if project p and one of projects from another
+// manifest file are nested, unless force-path is set for either of them.
+func checkNestedPath(p *Project, projects []Project) error {
+	for _, q := range projects {
+		inner, outer := p, &q
+		if strings.HasPrefix(q.Path, p.Path+"/") {
+			inner, outer = &q, p
+		} else if !strings.HasPrefix(p.Path, q.Path+"/") {
+			continue
+		}
+		if p.ForcePathBool() || q.ForcePathBool() {
+			continue
+		}
+		return fmt.Errorf("path '%s' of project '%s' is nested in project '%s', set force-path to allow it",
+			inner.Path,
+			inner.Name,
+			outer.Path)
+	}
+	return nil
+}
+
 // ProjectHandler is an interface to manipulate projects of manifest
 type ProjectHandler interface {
 	// The 1st parameter is pointer of a project, and the 2nd parameter
@@ -463,7 +1000,37 @@ func cleanPath(name string) string {
 	return filepath.Clean(strings.Replace(strings.TrimSuffix(name, ".git"), "\\", "/", -1))
 }
 
-func unmarshalFile(file string) (*Manifest, error) {
+// loadOptions are options for parsing manifest files.
+type loadOptions struct {
+	// Groups of workspace to filter includes.
+	Groups string
+	// Strict mode fails on unknown elements and attributes.
+	Strict bool
+	// MaxIncludeDepth limits depth of includes, 0 for no limit.
+	MaxIncludeDepth int
+	// LocalDuplicate is policy for projects of duplicate paths in local
+	// manifests.
+	LocalDuplicate DuplicatePolicy
+}
+
+// newLoadOptions returns options for workspace of repoDir.
+func newLoadOptions(repoDir string) *loadOptions {
+	policy, err := ParseDuplicatePolicy(config.GitDefaultConfig.Get(config.CfgRepoLocalDuplicate))
+	if err != nil {
+		log.Warnf("ignore git config '%s': %s", config.CfgRepoLocalDuplicate, err)
+	}
+	return &loadOptions{
+		Groups:          manifestGroups(repoDir),
+		Strict:          config.GitDefaultConfig.GetBool(config.CfgRepoManifestStrict, false),
+		MaxIncludeDepth: config.GitDefaultConfig.GetInt(config.CfgRepoMaxIncludeDepth, 0),
+		LocalDuplicate:  policy,
+	}
+}
+
+// unmarshalFile parses manifest file, and checks unknown elements and
+// attributes. They are reported as error in strict mode, otherwise are
+// ignored.
+func unmarshalFile(file string, strict bool) (*Manifest, error) {
 	if _, err := os.Stat(file); err != nil {
 		return nil, err
 	}
@@ -478,13 +1045,46 @@ func unmarshalFile(file string) (*Manifest, error) {
 		return nil, fmt.Errorf("fail to parse manifest file '%s': %s", file, err)
 	}
 
+	findings, _ := CheckFields(buf)
+	if len(findings) > 0 {
+		msgs := []string{}
+		for _, finding := range findings {
+			finding.File = file
+			msgs = append(msgs, finding.String())
+		}
+		if strict {
+			return nil, fmt.Errorf("unknown fields in manifest file '%s':\n\t%s",
+				file, strings.Join(msgs, "\n\t"))
+		}
+		for _, msg := range msgs {
+			log.Infof("ignore %s", msg)
+		}
+	}
+
 	return ms, nil
 }
 
-func parseXML(file string, depth int) ([]*Manifest, error) {
+// canonicalPath returns absolute path of file with symlinks resolved,
+// which identifies a manifest file in an include chain.
+func canonicalPath(file string) string {
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(file); err == nil {
+		file = resolved
+	}
+	return file
+}
+
+// parseXML parses file and files included recursively. Includes which
+// do not match groups are ignored. Missing optional file is returned as
+// an empty manifest, so that it is also watched by manifest cache.
+// Chain has canonical paths of files which include file, and is used to
+// find circular includes and depth of includes.
+func parseXML(file, includedBy string, chain []string, o *loadOptions) ([]*Manifest, error) {
 	ms := []*Manifest{}
 
-	m, err := unmarshalFile(file)
+	m, err := unmarshalFile(file, o.Strict)
 	if err != nil {
 		return ms, err
 	}
@@ -492,7 +1092,10 @@ func parseXML(file string, depth int) ([]*Manifest, error) {
 		return ms, nil
 	}
 	m.SourceFile = file
+	m.IncludedBy = includedBy
+	m.IncludeDepth = len(chain)
 	ms = append(ms, m)
+	chain = append(chain[:len(chain):len(chain)], canonicalPath(file))
 
 	for _, i := range m.Includes {
 		f, err := path.AbsJoin(filepath.Dir(file), i.Name)
@@ -500,18 +1103,44 @@ func parseXML(file string, depth int) ([]*Manifest, error) {
 			return ms, err
 		}
 
-		if depth > maxRecursiveDepth {
+		if !i.MatchGroups(o.Groups) {
+			log.Debugf("ignore include '%s', not in groups '%s'", i.Name, o.Groups)
+			continue
+		}
+		if i.IsOptional() {
+			if _, err := os.Stat(f); os.IsNotExist(err) {
+				log.Debugf("ignore missing optional include '%s'", i.Name)
+				ms = append(ms, &Manifest{
+					SourceFile:   f,
+					IncludedBy:   file,
+					IncludeDepth: len(chain),
+				})
+				continue
+			}
+		}
+
+		canonical := canonicalPath(f)
+		for idx, included := range chain {
+			if included == canonical {
+				return nil, fmt.Errorf("circular include of manifest files:\n\t%s\n\t-> %s",
+					strings.Join(chain[idx:], "\n\t-> "),
+					canonical)
+			}
+		}
+
+		if o.MaxIncludeDepth > 0 && len(chain) > o.MaxIncludeDepth {
 			return nil, fmt.Errorf("exceeded maximum include depth (%d) while including\n"+
 				"\t%s\n"+
-				"from"+
+				"from\n"+
 				"\t%s\n"+
-				"This might be due to circular includes",
-				maxRecursiveDepth,
+				"Limit is set by git config '%s'",
+				o.MaxIncludeDepth,
 				f,
-				file)
+				file,
+				config.CfgRepoMaxIncludeDepth)
 		}
 
-		subMs, err := parseXML(f, depth+1)
+		subMs, err := parseXML(f, file, chain, o)
 		if err != nil {
 			return ms, err
 		}
@@ -521,10 +1150,16 @@ func parseXML(file string, depth int) ([]*Manifest, error) {
 	return ms, nil
 }
 
-func mergeManifests(ms []*Manifest) (*Manifest, error) {
+// mergeManifests merges manifests, and projects of duplicate paths in
+// manifests since localStart (local manifests) are handled by policy.
+func mergeManifests(ms []*Manifest, localStart int, policy DuplicatePolicy) (*Manifest, error) {
 	manifest := &Manifest{}
-	for _, m := range ms {
-		err := manifest.Merge(m)
+	for i, m := range ms {
+		p := DuplicateError
+		if i >= localStart {
+			p = policy
+		}
+		err := manifest.MergeWithPolicy(m, p)
 		if err != nil {
 			return nil, err
 		}
@@ -532,69 +1167,68 @@ func mergeManifests(ms []*Manifest) (*Manifest, error) {
 	return manifest, nil
 }
 
-// Load implements load and parse manifest XML file in repoDir.
-func Load(repoDir string) (*Manifest, error) {
-	var (
-		file string
-		err  error
-	)
+// manifestFile returns manifest.xml in repoDir, or default manifest in
+// manifests project if manifest.xml does not exist.
+func manifestFile(repoDir string) (string, error) {
+	file := filepath.Join(repoDir, config.ManifestXML)
+	if _, err := os.Stat(file); err == nil {
+		return file, nil
+	}
 
-	file = filepath.Join(repoDir, config.ManifestXML)
+	defaultXML := ""
+	manifestsDir := filepath.Join(repoDir, config.Manifests)
+	cfg, err := goconfig.Load(manifestsDir)
+	if err != nil && err != goconfig.ErrNotExist {
+		return "", fmt.Errorf("fail to read config from %s: %s", manifestsDir, err)
+	}
+	if cfg != nil {
+		defaultXML = cfg.Get(config.CfgManifestName)
+	}
+	if defaultXML == "" {
+		defaultXML = config.DefaultXML
+	}
+	file = filepath.Join(manifestsDir, defaultXML)
 	if _, err = os.Stat(file); err != nil {
-		defaultXML := ""
-		manifestsDir := filepath.Join(repoDir, config.Manifests)
-		cfg, err := goconfig.Load(manifestsDir)
-		if err != nil && err != goconfig.ErrNotExist {
-			return nil, fmt.Errorf("fail to read config from %s: %s", manifestsDir, err)
-		}
-		if cfg != nil {
-			defaultXML = cfg.Get(config.CfgManifestName)
-		}
-		if defaultXML == "" {
-			defaultXML = config.DefaultXML
-		}
-		file = filepath.Join(manifestsDir, defaultXML)
-		if _, err = os.Stat(file); err != nil {
-			return nil, err
-		}
+		return "", err
 	}
-	return LoadFile(repoDir, file)
+	return file, nil
 }
 
-// LoadFile implements load specific manifest file inside repoDir.
-func LoadFile(repoDir, file string) (*Manifest, error) {
-	var (
-		dir       string
-		err       error
-		manifests = []*Manifest{}
-	)
-
-	if !filepath.IsAbs(file) {
-		file = filepath.Join(repoDir, config.Manifests, file)
+// Load implements load and parse manifest XML file in repoDir.
+func Load(repoDir string) (*Manifest, error) {
+	file, err := manifestFile(repoDir)
+	if err != nil {
+		return nil, err
 	}
+	return LoadFile(repoDir, file)
+}
 
-	// Ignore uninitialized repo
-	if _, err := os.Stat(file); err != nil {
-		return nil, nil
+// manifestGroups returns groups of workspace to filter includes.
+func manifestGroups(repoDir string) string {
+	groups := ""
+	cfg, err := goconfig.Load(filepath.Join(repoDir, config.Manifests))
+	if err == nil && cfg != nil {
+		groups = cfg.Get(config.CfgManifestGroups)
 	}
-
-	ms, err := parseXML(file, 1)
-	if err != nil {
-		return nil, err
+	if groups == "" {
+		groups = DefaultGroups()
 	}
-	manifests = append(manifests, ms...)
+	return groups
+}
 
-	// load local_manifest.xml (obsolete)
+// localManifestFiles returns obsolete local_manifest.xml and xml files in
+// local_manifests dir.
+func localManifestFiles(repoDir string) []string {
 	files := []string{}
-	file = filepath.Join(repoDir, config.LocalManifestXML)
-	dir = filepath.Join(repoDir, config.LocalManifests)
-	if _, err = os.Stat(file); err == nil {
+	file := filepath.Join(repoDir, config.LocalManifestXML)
+	dir := filepath.Join(repoDir, config.LocalManifests)
+	if _, err := os.Stat(file); err == nil {
 		log.Warnf("%s is deprecated; put local manifests in `%s` instead", file, dir)
 		files = append(files, file)
 	}
 
 	// load xml files in local_manifests
-	if _, err = os.Stat(dir); err == nil {
+	if _, err := os.Stat(dir); err == nil {
 		filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
@@ -611,16 +1245,53 @@ func LoadFile(repoDir, file string) (*Manifest, error) {
 			return nil
 		})
 	}
+	return files
+}
+
+// LoadFile implements load specific manifest file inside repoDir.
+// Merged manifest is cached, and is reused until any source file is
+// changed.
+func LoadFile(repoDir, file string) (*Manifest, error) {
+	manifests := []*Manifest{}
+
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(repoDir, config.Manifests, file)
+	}
 
-	for _, file = range files {
-		ms, err := parseXML(file, 1)
+	// Ignore uninitialized repo
+	if _, err := os.Stat(file); err != nil {
+		return nil, nil
+	}
+
+	o := newLoadOptions(repoDir)
+	files := append([]string{file}, localManifestFiles(repoDir)...)
+	if m := manifestCache.Get(repoDir, file, o, files); m != nil {
+		log.Debugf("load manifest from cache for '%s'", file)
+		return m, nil
+	}
+
+	localStart := 0
+	for i, f := range files {
+		ms, err := parseXML(f, "", nil, o)
 		if err != nil {
 			return nil, err
 		}
 		manifests = append(manifests, ms...)
+		if i == 0 {
+			localStart = len(manifests)
+		}
 	}
 
-	return mergeManifests(manifests)
+	m, err := mergeManifests(manifests, localStart, o.LocalDuplicate)
+	if err != nil {
+		return nil, err
+	}
+	sources := []string{}
+	for _, ms := range manifests {
+		sources = append(sources, ms.SourceFile)
+	}
+	manifestCache.Put(repoDir, file, o, m, sources)
+	return m, nil
 }
 
 // Unmarshal implements decoding XML (in buf) to manifest.
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"testing"
 
-	log "github.com/jiangxin/multi-log"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -556,10 +558,65 @@ func TestCircularInclude(t *testing.T) {
 	assert.Equal(nil, err)
 
 	m, err := Load(repoDir)
-	assert.Contains(err.Error(), "exceeded maximum include depth (10)")
+	inc1 := canonicalPath(filepath.Join(workDir, "manifest.inc"))
+	inc2 := canonicalPath(filepath.Join(workDir, "manifest2.inc"))
+	assert.Equal("circular include of manifest files:\n"+
+		"\t"+inc1+"\n"+
+		"\t-> "+inc2+"\n"+
+		"\t-> "+inc1,
+		err.Error())
 	assert.Equal(true, nil == m)
 }
 
+func TestLoadDeepIncludes(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	repoDir := filepath.Join(tmpdir, ".repo")
+	err = os.MkdirAll(filepath.Join(repoDir, "manifests"), 0755)
+	assert.Nil(err)
+
+	err = ioutil.WriteFile(filepath.Join(repoDir, "manifest.xml"), []byte(`
+<manifest>
+  <remote name="origin" fetch="https://example.com"></remote>
+  <default remote="origin" revision="master"></default>
+  <include name="inc-1.xml"/>
+</manifest>`), 0644)
+	assert.Nil(err)
+
+	// An acyclic chain of 20 includes, and each one also includes a
+	// shared file, which is not a circular include.
+	levels := 20
+	for i := 1; i <= levels; i++ {
+		next := ""
+		if i < levels {
+			next = fmt.Sprintf(`<include name="inc-%d.xml"/>`, i+1)
+		}
+		err = ioutil.WriteFile(filepath.Join(repoDir, fmt.Sprintf("inc-%d.xml", i)), []byte(fmt.Sprintf(`
+<manifest>
+  <project name="app%d" path="app%d"/>
+  <include name="shared.xml"/>
+  %s
+</manifest>`, i, i, next)), 0644)
+		assert.Nil(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(repoDir, "shared.xml"), []byte(`
+<manifest>
+</manifest>`), 0644)
+	assert.Nil(err)
+
+	m, err := Load(repoDir)
+	assert.Nil(err)
+	if assert.NotNil(m) {
+		assert.Equal(levels, len(m.Projects))
+	}
+}
+
 func TestManifestRevision1(t *testing.T) {
 	assert := assert.New(t)
 
@@ -708,6 +765,56 @@ func TestManifestRevision3(t *testing.T) {
 	assert.True(p.IsSyncTags())
 }
 
+func TestResolveProject(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := []byte(`
+<manifest>
+  <remote name="aone" alias="origin"
+    fetch="https://example.com"
+    revision="aone-master" />
+  <remote name="gerrit"
+    fetch="https://gerrit.example.com" />
+  <default remote="aone"
+    revision="default-master"
+    dest-branch="default-dest"
+    sync-c="true" />
+  <project name="platform/app1" path="app1" />
+  <project name="platform/app2" path="app2" remote="gerrit" />
+  <project name="platform/app3" path="app3" remote="gerrit" revision="dev" sync-c="false" />
+  <project name="platform/app4" path="app4" remote="unknown" />
+</manifest>`)
+
+	m, err := Unmarshal(buf)
+	assert.Nil(err)
+
+	r, err := m.ResolveProject(m.Projects[0])
+	assert.Nil(err)
+	assert.Equal("aone", r.RemoteName)
+	assert.Equal("origin", r.GitRemoteName)
+	assert.Equal("aone-master", r.Revision)
+	assert.Equal("remote", r.RevisionFrom)
+	assert.Equal("default-dest", r.DestBranch)
+	assert.True(r.IsSyncC())
+	// Project of manifest is not changed.
+	assert.Equal("", m.Projects[0].Revision)
+
+	r, err = m.ResolveProject(m.Projects[1])
+	assert.Nil(err)
+	assert.Equal("gerrit", r.GitRemoteName)
+	assert.Equal("default-master", r.Revision)
+	assert.Equal("default", r.RevisionFrom)
+
+	r, err = m.ResolveProject(m.Projects[2])
+	assert.Nil(err)
+	assert.Equal("dev", r.Revision)
+	assert.Equal("project", r.RevisionFrom)
+	assert.False(r.IsSyncC())
+
+	_, err = m.ResolveProject(m.Projects[3])
+	assert.Equal("cannot find remote 'unknown' for project 'platform/app4'", err.Error())
+}
+
 func ExampleMarshal() {
 	m := Manifest{
 		Remotes: []Remote{
@@ -803,3 +910,487 @@ func ExampleUnmarshal() {
 	// project #2> name: platform/drivers/platform/nic, path: platform-drivers/nic
 	// project #3> name: platform/manifest, path: platform-manifest
 }
+
+func TestTypedAccessors(t *testing.T) {
+	assert := assert.New(t)
+
+	d := Default{}
+	assert.False(d.SyncCBool())
+	assert.False(d.SyncSBool())
+	assert.True(d.SyncTagsBool())
+
+	d = Default{SyncC: "Yes", SyncS: "1", SyncTags: "no"}
+	assert.True(d.SyncCBool())
+	assert.True(d.SyncSBool())
+	assert.False(d.SyncTagsBool())
+
+	buf := []byte(`
+<manifest>
+  <remote name="aone" fetch="https://example.com" revision="master" />
+  <default remote="aone" sync-c="true" sync-tags="false" />
+  <project name="app1" path="app1" />
+  <project name="app2" path="app2" sync-c="0" sync-tags="on" clone-depth="1" />
+  <project name="app3" path="app3" sync-s="bad" clone-depth="-1" />
+</manifest>`)
+
+	m, err := Unmarshal(buf)
+	assert.Nil(err)
+	projects := m.AllProjects()
+	assert.Equal(3, len(projects))
+
+	assert.True(projects[0].SyncCBool())
+	assert.False(projects[0].SyncSBool())
+	assert.False(projects[0].SyncTagsBool())
+	assert.Equal(0, projects[0].CloneDepthInt())
+
+	assert.False(projects[1].SyncCBool())
+	assert.True(projects[1].SyncTagsBool())
+	assert.Equal(1, projects[1].CloneDepthInt())
+
+	// Invalid values fall back to defaults.
+	assert.False(projects[2].SyncSBool())
+	assert.Equal(0, projects[2].CloneDepthInt())
+}
+
+func TestGetAnnotation(t *testing.T) {
+	assert := assert.New(t)
+
+	p := Project{
+		Name: "app1",
+		Annotations: []Annotation{
+			{Name: "BUILD_TYPE", Value: "release"},
+			{Name: "SECRET", Value: "hidden", Keep: "false"},
+		},
+	}
+	value, ok := p.GetAnnotation("BUILD_TYPE")
+	assert.True(ok)
+	assert.Equal("release", value)
+	_, ok = p.GetAnnotation("unknown")
+	assert.False(ok)
+	assert.True(p.Annotations[0].IsKeep())
+	assert.False(p.Annotations[1].IsKeep())
+}
+
+func TestMergeNestedProjects(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &Manifest{
+		Projects: []Project{
+			{Name: "platform/app", Path: "app"},
+		},
+	}
+	m2 := &Manifest{
+		SourceFile: "local.xml",
+		Projects: []Project{
+			{Name: "platform/lib", Path: "app/lib"},
+		},
+	}
+	err := m.Merge(m2)
+	assert.Equal("path 'app/lib' of project 'platform/lib' is nested in project 'app', set force-path to allow it in 'local.xml'",
+		err.Error())
+
+	// Outer project defined in another manifest.
+	m = &Manifest{
+		Projects: []Project{
+			{Name: "platform/lib", Path: "app/lib"},
+		},
+	}
+	m2.Projects = []Project{
+		{Name: "platform/app", Path: "app"},
+	}
+	assert.NotNil(m.Merge(m2))
+
+	m2.Projects[0].ForcePath = "true"
+	assert.Nil(m.Merge(m2))
+	assert.Equal(2, len(m.Projects))
+
+	// Nested project elements in the same manifest are allowed.
+	m = &Manifest{}
+	m2.Projects = []Project{
+		{
+			Name: "platform/app",
+			Path: "app",
+			Projects: []Project{
+				{Name: "lib", Path: "lib"},
+			},
+		},
+	}
+	assert.Nil(m.Merge(m2))
+}
+
+func TestMergeDuplicatePolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	newManifest := func() *Manifest {
+		return &Manifest{
+			Projects: []Project{
+				{Name: "platform/app", Path: "app", Revision: "master"},
+				{Name: "platform/lib", Path: "lib"},
+			},
+		}
+	}
+	m2 := &Manifest{
+		SourceFile: "local.xml",
+		Projects: []Project{
+			{Name: "mirror/app", Path: "app", Revision: "dev", RemoteName: "mirror"},
+		},
+	}
+
+	m := newManifest()
+	err := m.MergeWithPolicy(m2, DuplicateError)
+	assert.Equal("duplicate path for project 'app' in 'local.xml'", err.Error())
+
+	m = newManifest()
+	assert.Nil(m.MergeWithPolicy(m2, DuplicateFirstWins))
+	assert.Equal(2, len(m.Projects))
+	assert.Equal("platform/app", m.Projects[0].Name)
+	assert.Equal("master", m.Projects[0].Revision)
+
+	m = newManifest()
+	assert.Nil(m.MergeWithPolicy(m2, DuplicateLastWins))
+	assert.Equal(2, len(m.Projects))
+	assert.Equal("mirror/app", m.Projects[0].Name)
+	assert.Equal("dev", m.Projects[0].Revision)
+	assert.Equal("mirror", m.Projects[0].RemoteName)
+	assert.Equal("lib", m.Projects[1].Path)
+
+	policy, err := ParseDuplicatePolicy("")
+	assert.Nil(err)
+	assert.Equal(DuplicateError, policy)
+	policy, err = ParseDuplicatePolicy("Last-Wins")
+	assert.Nil(err)
+	assert.Equal(DuplicateLastWins, policy)
+	_, err = ParseDuplicatePolicy("merge")
+	assert.Equal("unknown policy for duplicate path: merge", err.Error())
+}
+
+func TestMergeOverrideProject(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &Manifest{
+		Projects: []Project{
+			{Name: "platform/app", Path: "app", Revision: "master", Groups: "g1"},
+			{Name: "platform/app", Path: "app2", Revision: "master"},
+		},
+	}
+	m2 := &Manifest{
+		SourceFile: "local.xml",
+		ExtendProjects: []ExtendProject{
+			{Name: "platform/app", Path: "app2", Revision: "release"},
+		},
+		OverrideProjects: []OverrideProject{
+			{Name: "platform/app", Path: "app", RemoteName: "mirror", Revision: "dev", Groups: "g2"},
+		},
+	}
+	assert.Nil(m.Merge(m2))
+	assert.Equal("mirror", m.Projects[0].RemoteName)
+	assert.Equal("dev", m.Projects[0].Revision)
+	assert.Equal("g2", m.Projects[0].Groups)
+	assert.Equal("", m.Projects[1].RemoteName)
+	assert.Equal("release", m.Projects[1].Revision)
+
+	m2 = &Manifest{
+		SourceFile: "local.xml",
+		OverrideProjects: []OverrideProject{
+			{Name: "platform/missing", Revision: "dev"},
+		},
+	}
+	assert.Equal("no project 'platform/missing' to override in 'local.xml'",
+		m.Merge(m2).Error())
+
+	m = &Manifest{
+		OverrideProjects: []OverrideProject{
+			{Name: "platform/app", Path: "app", Revision: "dev"},
+		},
+	}
+	data, err := m.Marshal()
+	assert.Nil(err)
+	assert.Contains(string(data), `<override-project name="platform/app" path="app" revision="dev"/>`)
+}
+
+func TestMergeRemoveAndReAddProject(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &Manifest{}
+	assert.Nil(m.Merge(&Manifest{
+		SourceFile: "default.xml",
+		Projects: []Project{
+			{Name: "platform/app", Path: "app", Revision: "master"},
+			{Name: "platform/lib", Path: "lib"},
+		},
+	}))
+
+	// Re-add at the same path in the same layer.
+	assert.Nil(m.Merge(&Manifest{
+		SourceFile: "local1.xml",
+		RemoveProjects: []RemoveProject{
+			{Name: "platform/app"},
+		},
+		Projects: []Project{
+			{Name: "platform/app", Path: "app", Revision: "dev", RemoteName: "mirror"},
+		},
+	}))
+	assert.Equal(2, len(m.Projects))
+	assert.Equal("lib", m.Projects[0].Path)
+	assert.Equal("app", m.Projects[1].Path)
+	assert.Equal("dev", m.Projects[1].Revision)
+	assert.Equal("mirror", m.Projects[1].RemoteName)
+
+	// Remove in one layer, and re-add in a later layer.
+	assert.Nil(m.Merge(&Manifest{
+		SourceFile: "local2.xml",
+		RemoveProjects: []RemoveProject{
+			{Name: "platform/lib"},
+		},
+	}))
+	assert.Nil(m.Merge(&Manifest{
+		SourceFile: "local3.xml",
+		Projects: []Project{
+			{Name: "vendor/lib", Path: "lib"},
+		},
+	}))
+	assert.Equal(2, len(m.Projects))
+	assert.Equal("vendor/lib", m.Projects[1].Name)
+
+	// Path is not freed without remove-project.
+	err := m.Merge(&Manifest{
+		SourceFile: "local4.xml",
+		Projects: []Project{
+			{Name: "other/lib", Path: "lib"},
+		},
+	})
+	assert.Equal("duplicate path for project 'lib' in 'local4.xml'", err.Error())
+}
+
+func TestMergeDefaultFields(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &Manifest{}
+	assert.Nil(m.Merge(&Manifest{
+		SourceFile: "default.xml",
+		Default: &Default{
+			RemoteName: "origin",
+			Revision:   "master",
+		},
+	}))
+
+	// Set other attributes, or the same value.
+	child := &Default{
+		Revision: "master",
+		SyncJ:    8,
+	}
+	assert.Nil(m.Merge(&Manifest{
+		SourceFile: "jobs.xml",
+		Default:    child,
+	}))
+	assert.Equal(&Default{
+		RemoteName: "origin",
+		Revision:   "master",
+		SyncJ:      8,
+	}, m.Default)
+	assert.Equal(map[string]string{
+		"remote":   "default.xml",
+		"revision": "default.xml",
+		"sync-j":   "jobs.xml",
+	}, m.DefaultSources)
+	assert.Equal(&Default{Revision: "master", SyncJ: 8}, child)
+
+	// Conflict of attribute.
+	err := m.Merge(&Manifest{
+		SourceFile: "local.xml",
+		Default: &Default{
+			SyncJ: 4,
+		},
+	})
+	assert.Equal("duplicate default in local.xml, attribute 'sync-j' is set in jobs.xml. "+
+		"If you want to override, set atrribute 'override' true", err.Error())
+	assert.Equal(8, m.Default.SyncJ)
+
+	// Replace the whole default.
+	assert.Nil(m.Merge(&Manifest{
+		SourceFile: "override.xml",
+		Default: &Default{
+			Revision: "main",
+			Override: true,
+		},
+	}))
+	assert.Equal("", m.Default.RemoteName)
+	assert.Equal("main", m.Default.Revision)
+	assert.Equal(0, m.Default.SyncJ)
+	assert.Equal(map[string]string{
+		"revision": "override.xml",
+	}, m.DefaultSources)
+}
+
+func TestConditionalInclude(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(dir string) {
+		os.RemoveAll(dir)
+	}(tmpdir)
+
+	repoDir := filepath.Join(tmpdir, ".repo")
+	manifestsDir := filepath.Join(repoDir, "manifests")
+	err = exec.Command("git", "init", "-q", manifestsDir).Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	manifestFile := filepath.Join(repoDir, "manifest.xml")
+	err = ioutil.WriteFile(manifestFile, []byte(`
+<manifest>
+  <remote name="origin" fetch=".." revision="master"></remote>
+  <default remote="origin"></default>
+  <project name="main" path="main"></project>
+  <include name="site.xml" optional="true"></include>
+  <include name="platform.xml" groups="notdefault,platform-`+runtime.GOOS+`"></include>
+  <include name="extra.xml" groups="notdefault,extra"></include>
+</manifest>`), 0644)
+	assert.Nil(err)
+	for _, name := range []string{"platform", "extra"} {
+		err = ioutil.WriteFile(filepath.Join(repoDir, name+".xml"), []byte(`
+<manifest>
+  <project name="`+name+`" path="`+name+`"></project>
+</manifest>`), 0644)
+		assert.Nil(err)
+	}
+
+	// Optional include is missing, and only includes in default groups
+	// are loaded.
+	oldCache := manifestCache
+	manifestCache = newCache()
+	defer func() { manifestCache = oldCache }()
+	m, err := LoadFile(repoDir, manifestFile)
+	assert.Nil(err)
+	assert.Equal([]string{"main", "platform"}, projectNames(m))
+
+	// Optional include is created.
+	err = ioutil.WriteFile(filepath.Join(repoDir, "site.xml"), []byte(`
+<manifest>
+  <project name="site" path="site"></project>
+</manifest>`), 0644)
+	assert.Nil(err)
+	m, err = LoadFile(repoDir, manifestFile)
+	assert.Nil(err)
+	assert.Equal([]string{"main", "site", "platform"}, projectNames(m))
+
+	// Groups of workspace are changed.
+	err = exec.Command("git", "-C", manifestsDir, "config", "manifest.groups", "default,extra").Run()
+	assert.Nil(err)
+	m, err = LoadFile(repoDir, manifestFile)
+	assert.Nil(err)
+	assert.Equal([]string{"main", "site", "extra"}, projectNames(m))
+
+	// Missing include which is not optional.
+	os.Remove(filepath.Join(repoDir, "extra.xml"))
+	_, err = LoadFile(repoDir, manifestFile)
+	assert.NotNil(err)
+}
+
+func TestRemoteCheckType(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, typ := range []string{"", "agit", "gerrit", "GitHub", "gitlab", "none"} {
+		assert.Nil(Remote{Name: "origin", Type: typ}.CheckType(), "type: %s", typ)
+	}
+	err := Remote{Name: "origin", Type: "svn"}.CheckType()
+	assert.Equal("unknown type 'svn' of remote 'origin', must be one of: agit, gerrit, github, gitlab, none",
+		err.Error())
+
+	m := &Manifest{}
+	m2 := &Manifest{
+		SourceFile: "local.xml",
+		Remotes: []Remote{
+			{Name: "origin", Fetch: "..", Type: "svn"},
+		},
+	}
+	// Unknown type may be handled by external proto-helper.
+	assert.Nil(m.Merge(m2))
+	assert.Equal("svn", m.Remotes[0].Type)
+}
+
+func TestProjectGitConfigs(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := []byte(`
+<manifest>
+  <remote name="origin" fetch="https://example.com" />
+  <default remote="origin" revision="master">
+    <config name="core.autocrlf" value="input" />
+    <config name="lfs.fetchinclude" value="assets" />
+  </default>
+  <project name="app1" path="app1" />
+  <project name="app2" path="app2">
+    <config name="core.autocrlf" value="false" />
+    <config name="core.fileMode" value="false" />
+  </project>
+</manifest>`)
+
+	m, err := Unmarshal(buf)
+	assert.Nil(err)
+	projects := m.AllProjects()
+	assert.Equal([]GitConfig{
+		{Name: "core.autocrlf", Value: "input"},
+		{Name: "lfs.fetchinclude", Value: "assets"},
+	}, projects[0].GitConfigs)
+	assert.Equal([]GitConfig{
+		{Name: "core.autocrlf", Value: "false"},
+		{Name: "core.fileMode", Value: "false"},
+		{Name: "lfs.fetchinclude", Value: "assets"},
+	}, projects[1].GitConfigs)
+
+	// Configs of default are merged by name.
+	merged := &Manifest{}
+	assert.Nil(merged.Merge(m))
+	assert.Nil(merged.Merge(&Manifest{
+		SourceFile: "local.xml",
+		Default: &Default{
+			GitConfigs: []GitConfig{{Name: "core.fileMode", Value: "false"}},
+		},
+	}))
+	assert.Equal(3, len(merged.Default.GitConfigs))
+	err = merged.Merge(&Manifest{
+		SourceFile: "conflict.xml",
+		Default: &Default{
+			GitConfigs: []GitConfig{{Name: "core.autocrlf", Value: "true"}},
+		},
+	})
+	assert.NotNil(err)
+}
+
+func TestProjectSparseCheckout(t *testing.T) {
+	assert := assert.New(t)
+
+	buf := []byte(`
+<manifest>
+  <remote name="origin" fetch="https://example.com" />
+  <default remote="origin" revision="master" />
+  <project name="app">
+    <sparse-checkout path="src/" />
+    <sparse-checkout path="docs" />
+    <sparse-checkout path="../outside" />
+  </project>
+</manifest>`)
+
+	m, err := Unmarshal(buf)
+	assert.Nil(err)
+	p := m.AllProjects()[0]
+	assert.Equal([]string{"src", "docs"}, p.SparseCheckoutPaths())
+
+	data, err := m.Marshal()
+	assert.Nil(err)
+	assert.Contains(string(data), `<sparse-checkout path="src/"/>`)
+	assert.Contains(string(data), `<sparse-checkout path="docs"/>`)
+}
+
+func TestEnabledHooks(t *testing.T) {
+	assert := assert.New(t)
+
+	hooks := RepoHooks{EnabledList: "pre-upload, commit-msg\tpost-sync"}
+	assert.Equal([]string{"pre-upload", "commit-msg", "post-sync"}, hooks.EnabledHooks())
+	assert.Empty(RepoHooks{}.EnabledHooks())
+}
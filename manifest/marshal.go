@@ -0,0 +1,438 @@
+package manifest
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// xmlNode is an element or a text node, and is written in the same
+// format as python's xml.dom.minidom, which is used by repo.
+type xmlNode struct {
+	name     string
+	attrs    [][2]string
+	children []*xmlNode
+	text     string
+	comment  bool
+}
+
+func newElement(name string) *xmlNode {
+	return &xmlNode{name: name}
+}
+
+// commentNode is a comment, and "--" which is not allowed in comment is
+// replaced.
+func commentNode(text string) *xmlNode {
+	return &xmlNode{text: strings.Replace(text, "--", "- -", -1), comment: true}
+}
+
+// blankNode is an empty text node, which is written as a blank line.
+func blankNode() *xmlNode {
+	return &xmlNode{}
+}
+
+func (v *xmlNode) setAttr(name, value string) {
+	v.attrs = append(v.attrs, [2]string{name, value})
+}
+
+func (v *xmlNode) appendChild(node *xmlNode) *xmlNode {
+	v.children = append(v.children, node)
+	return node
+}
+
+func escapeXML(s string) string {
+	return strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		"\"", "&quot;",
+		">", "&gt;",
+	).Replace(s)
+}
+
+func (v *xmlNode) write(buf *bytes.Buffer, indent string) {
+	const addIndent = "  "
+
+	if v.comment {
+		buf.WriteString(indent + "<!-- " + v.text + " -->\n")
+		return
+	}
+	if v.name == "" {
+		buf.WriteString(indent + escapeXML(v.text) + "\n")
+		return
+	}
+	buf.WriteString(indent + "<" + v.name)
+	for _, attr := range v.attrs {
+		buf.WriteString(" " + attr[0] + "=\"" + escapeXML(attr[1]) + "\"")
+	}
+	if len(v.children) == 0 {
+		buf.WriteString("/>\n")
+		return
+	}
+	buf.WriteString(">")
+	if len(v.children) == 1 && v.children[0].name == "" {
+		buf.WriteString(escapeXML(v.children[0].text))
+	} else {
+		buf.WriteString("\n")
+		for _, child := range v.children {
+			child.write(buf, indent+addIndent)
+		}
+		buf.WriteString(indent)
+	}
+	buf.WriteString("</" + v.name + ">\n")
+}
+
+// formatNotice removes common indentation and blank lines around notice
+// like repo does, and indents it for output.
+func formatNotice(notice string) string {
+	lines := strings.Split(notice, "\n")
+	minIndent := -1
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if minIndent < 0 || n < minIndent {
+			minIndent = n
+		}
+	}
+	start, end := 0, len(lines)-1
+	for start <= end && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	for end >= start && strings.TrimSpace(lines[end]) == "" {
+		end--
+	}
+	result := []string{}
+	for i := start; i <= end; i++ {
+		line := lines[i]
+		if i > 0 && minIndent > 0 && len(line) >= minIndent {
+			line = line[minIndent:]
+		}
+		if i > start {
+			line = "    " + line
+		}
+		result = append(result, line)
+	}
+	return strings.Join(result, "\n")
+}
+
+// extraGroups returns groups of project without groups which every
+// project belongs to.
+func extraGroups(p *Project) string {
+	groups := []string{}
+	for _, g := range strings.FieldsFunc(p.Groups, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	}) {
+		if g == "all" || g == "name:"+p.Name || g == "path:"+p.Path {
+			continue
+		}
+		groups = append(groups, g)
+	}
+	return strings.Join(groups, ",")
+}
+
+func (v *Manifest) remoteRevision(name string) string {
+	for _, r := range v.Remotes {
+		if r.Name == name {
+			return r.Revision
+		}
+	}
+	return ""
+}
+
+func (v *Manifest) projectNode(p *Project) *xmlNode {
+	d := v.Default
+	if d == nil {
+		d = &Default{}
+	}
+
+	e := newElement("project")
+	e.setAttr("name", p.Name)
+	if p.Path != "" && p.Path != "." && p.Path != p.Name {
+		e.setAttr("path", p.Path)
+	}
+	if p.RemoteName != "" && p.RemoteName != d.RemoteName {
+		e.setAttr("remote", p.RemoteName)
+	}
+	remoteName := p.RemoteName
+	if remoteName == "" {
+		remoteName = d.RemoteName
+	}
+	inheritRevision := v.remoteRevision(remoteName)
+	if inheritRevision == "" {
+		inheritRevision = d.Revision
+	}
+	if p.Revision != "" && p.Revision != inheritRevision {
+		e.setAttr("revision", p.Revision)
+	}
+	revision := p.Revision
+	if revision == "" {
+		revision = inheritRevision
+	}
+	if p.Upstream != "" && (p.Upstream != revision || p.Upstream != d.Upstream) {
+		e.setAttr("upstream", p.Upstream)
+	}
+	if p.DestBranch != "" && p.DestBranch != d.DestBranch {
+		e.setAttr("dest-branch", p.DestBranch)
+	}
+	for _, c := range p.CopyFiles {
+		ce := e.appendChild(newElement("copyfile"))
+		ce.setAttr("src", c.Src)
+		ce.setAttr("dest", c.Dest)
+	}
+	for _, l := range p.LinkFiles {
+		le := e.appendChild(newElement("linkfile"))
+		le.setAttr("src", l.Src)
+		le.setAttr("dest", l.Dest)
+	}
+	if groups := extraGroups(p); groups != "" {
+		e.setAttr("groups", groups)
+	}
+	for _, sc := range p.SparseCheckouts {
+		se := e.appendChild(newElement("sparse-checkout"))
+		se.setAttr("path", sc.Path)
+	}
+	for _, c := range p.GitConfigs {
+		// Omit configs inherited from default element.
+		if dc := findGitConfig(d.GitConfigs, c.Name); dc != nil && dc.Value == c.Value {
+			continue
+		}
+		ce := e.appendChild(newElement("config"))
+		ce.setAttr("name", c.Name)
+		ce.setAttr("value", c.Value)
+	}
+	for _, a := range p.Annotations {
+		if a.IsKeep() {
+			ae := e.appendChild(newElement("annotation"))
+			ae.setAttr("name", a.Name)
+			ae.setAttr("value", a.Value)
+		}
+	}
+	if p.SyncCBool() {
+		e.setAttr("sync-c", "true")
+	}
+	if isTrue(p.SyncS, d.SyncSBool()) {
+		e.setAttr("sync-s", "true")
+	}
+	if !isTrue(p.SyncTags, d.SyncTagsBool()) {
+		e.setAttr("sync-tags", "false")
+	}
+	if p.CloneDepthInt() > 0 {
+		e.setAttr("clone-depth", strconv.Itoa(p.CloneDepthInt()))
+	}
+	// Attributes not supported by repo.
+	if p.Rebase != "" {
+		e.setAttr("rebase", p.Rebase)
+	}
+	if p.ForcePathBool() {
+		e.setAttr("force-path", "true")
+	}
+
+	subProjects := append([]Project{}, p.Projects...)
+	sort.SliceStable(subProjects, func(i, j int) bool {
+		return subProjects[i].Name < subProjects[j].Name
+	})
+	for i := range subProjects {
+		e.appendChild(v.projectNode(&subProjects[i]))
+	}
+	return e
+}
+
+// Marshal encodes manifest to XML in the same format as "repo manifest",
+// such as order of elements and attributes, indentation and blank lines,
+// and omits attributes which are the same as inherited values. So that
+// output of this tool and repo can be compared with diff.
+func (v *Manifest) Marshal() ([]byte, error) {
+	return v.marshal(false)
+}
+
+// MarshalWithSources is the same as Marshal, and adds comments before
+// default element and projects to show which manifest files set them.
+func (v *Manifest) MarshalWithSources() ([]byte, error) {
+	return v.marshal(true)
+}
+
+// defaultSources returns provenance of attributes of default element.
+func (v *Manifest) defaultSources() string {
+	attrs := []string{}
+	for attr := range v.DefaultSources {
+		attrs = append(attrs, attr)
+	}
+	sort.Strings(attrs)
+	sources := []string{}
+	for _, attr := range attrs {
+		sources = append(sources, attr+" from "+v.DefaultSources[attr])
+	}
+	return strings.Join(sources, "; ")
+}
+
+func (v *Manifest) marshal(withSources bool) ([]byte, error) {
+	var buf bytes.Buffer
+
+	root := newElement("manifest")
+	if v.Notice != "" {
+		e := root.appendChild(newElement("notice"))
+		e.appendChild(&xmlNode{text: formatNotice(v.Notice)})
+	}
+
+	remotes := append([]Remote{}, v.Remotes...)
+	sort.SliceStable(remotes, func(i, j int) bool {
+		return remotes[i].Name < remotes[j].Name
+	})
+	for _, r := range remotes {
+		e := root.appendChild(newElement("remote"))
+		e.setAttr("name", r.Name)
+		e.setAttr("fetch", r.Fetch)
+		if r.PushURL != "" {
+			e.setAttr("pushurl", r.PushURL)
+		}
+		if r.Alias != "" {
+			e.setAttr("alias", r.Alias)
+		}
+		if r.Review != "" {
+			e.setAttr("review", r.Review)
+		}
+		if r.Revision != "" {
+			e.setAttr("revision", r.Revision)
+		}
+		if r.Type != "" {
+			e.setAttr("type", r.Type)
+		}
+		if r.ReviewURLTemplate != "" {
+			e.setAttr("review-url-template", r.ReviewURLTemplate)
+		}
+	}
+	if len(remotes) > 0 {
+		root.appendChild(blankNode())
+	}
+
+	if d := v.Default; d != nil {
+		e := newElement("default")
+		if d.RemoteName != "" {
+			e.setAttr("remote", d.RemoteName)
+		}
+		if d.Revision != "" {
+			e.setAttr("revision", d.Revision)
+		}
+		if d.DestBranch != "" {
+			e.setAttr("dest-branch", d.DestBranch)
+		}
+		if d.Upstream != "" {
+			e.setAttr("upstream", d.Upstream)
+		}
+		if d.SyncJ > 1 {
+			e.setAttr("sync-j", strconv.Itoa(d.SyncJ))
+		}
+		if d.SyncCBool() {
+			e.setAttr("sync-c", "true")
+		}
+		if d.SyncSBool() {
+			e.setAttr("sync-s", "true")
+		}
+		if !d.SyncTagsBool() {
+			e.setAttr("sync-tags", "false")
+		}
+		if d.CommitTemplate != "" {
+			e.setAttr("commit-template", d.CommitTemplate)
+		}
+		for _, c := range d.GitConfigs {
+			ce := e.appendChild(newElement("config"))
+			ce.setAttr("name", c.Name)
+			ce.setAttr("value", c.Value)
+		}
+		if len(e.attrs) > 0 || len(e.children) > 0 {
+			if withSources && len(v.DefaultSources) > 0 {
+				root.appendChild(commentNode(v.defaultSources()))
+			}
+			root.appendChild(e)
+			root.appendChild(blankNode())
+		}
+	}
+
+	if v.Server != nil && v.Server.URL != "" {
+		e := root.appendChild(newElement("manifest-server"))
+		e.setAttr("url", v.Server.URL)
+		root.appendChild(blankNode())
+	}
+
+	projects := append([]Project{}, v.Projects...)
+	sort.SliceStable(projects, func(i, j int) bool {
+		return projects[i].Name < projects[j].Name
+	})
+	for i := range projects {
+		if withSources {
+			if sources := v.ProjectSources[projects[i].Path]; len(sources) > 0 {
+				root.appendChild(commentNode(strings.Join(sources, "; ")))
+			}
+		}
+		root.appendChild(v.projectNode(&projects[i]))
+	}
+
+	// Elements only in manifest files which are not merged.
+	for _, p := range v.ExtendProjects {
+		e := root.appendChild(newElement("extend-project"))
+		e.setAttr("name", p.Name)
+		if p.Path != "" {
+			e.setAttr("path", p.Path)
+		}
+		if p.Groups != "" {
+			e.setAttr("groups", p.Groups)
+		}
+		if p.Revision != "" {
+			e.setAttr("revision", p.Revision)
+		}
+		if p.Upstream != "" {
+			e.setAttr("upstream", p.Upstream)
+		}
+	}
+	for _, p := range v.OverrideProjects {
+		e := root.appendChild(newElement("override-project"))
+		e.setAttr("name", p.Name)
+		for _, attr := range []struct {
+			name, value string
+		}{
+			{"path", p.Path},
+			{"remote", p.RemoteName},
+			{"revision", p.Revision},
+			{"dest-branch", p.DestBranch},
+			{"groups", p.Groups},
+			{"rebase", p.Rebase},
+			{"sync-c", p.SyncC},
+			{"sync-s", p.SyncS},
+			{"sync-tags", p.SyncTags},
+			{"upstream", p.Upstream},
+			{"clone-depth", p.CloneDepth},
+		} {
+			if attr.value != "" {
+				e.setAttr(attr.name, attr.value)
+			}
+		}
+	}
+	for _, p := range v.RemoveProjects {
+		e := root.appendChild(newElement("remove-project"))
+		e.setAttr("name", p.Name)
+	}
+
+	if v.RepoHooks != nil && v.RepoHooks.InProject != "" {
+		root.appendChild(blankNode())
+		e := root.appendChild(newElement("repo-hooks"))
+		e.setAttr("in-project", v.RepoHooks.InProject)
+		e.setAttr("enabled-list", strings.Join(strings.Fields(v.RepoHooks.EnabledList), " "))
+	}
+
+	for _, i := range v.Includes {
+		e := root.appendChild(newElement("include"))
+		e.setAttr("name", i.Name)
+		if i.Optional != "" {
+			e.setAttr("optional", i.Optional)
+		}
+		if i.Groups != "" {
+			e.setAttr("groups", i.Groups)
+		}
+	}
+
+	buf.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	root.write(&buf, "")
+	return buf.Bytes(), nil
+}
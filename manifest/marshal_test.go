@@ -0,0 +1,104 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestMarshal(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := Unmarshal([]byte(`
+<manifest>
+  <notice>
+    Notice of manifest:
+      * read "README" &amp; "NOTES"
+  </notice>
+  <remote name="origin" fetch=".." review="https://example.com" revision="master"></remote>
+  <remote name="aone" fetch="." alias="origin"></remote>
+  <default remote="origin" revision="master" sync-j="4" sync-tags="false"></default>
+  <manifest-server url="https://example.com/manifest"></manifest-server>
+  <project name="foo" path="foo" revision="master" groups="app,name:foo">
+    <annotation name="kept" value="1"></annotation>
+    <annotation name="dropped" value="2" keep="false"></annotation>
+    <linkfile src="Makefile" dest="Makefile"></linkfile>
+    <copyfile src="VERSION" dest="VERSION"></copyfile>
+  </project>
+  <project name="bar" path="src/bar" remote="aone" revision="refs/tags/v1" sync-c="true" sync-tags="true"></project>
+  <repo-hooks in-project="foo" enabled-list="pre-upload   commit-msg"></repo-hooks>
+</manifest>`))
+	assert.Nil(err)
+
+	data, err := m.Marshal()
+	assert.Nil(err)
+	assert.Equal(`<?xml version="1.0" encoding="UTF-8"?>
+<manifest>
+  <notice>Notice of manifest:
+      * read &quot;README&quot; &amp; &quot;NOTES&quot;</notice>
+  <remote name="aone" fetch="." alias="origin"/>
+  <remote name="origin" fetch=".." review="https://example.com" revision="master"/>
+  
+  <default remote="origin" revision="master" sync-j="4" sync-tags="false"/>
+  
+  <manifest-server url="https://example.com/manifest"/>
+  
+  <project name="bar" path="src/bar" remote="aone" revision="refs/tags/v1" sync-c="true"/>
+  <project name="foo" groups="app" sync-tags="false">
+    <copyfile src="VERSION" dest="VERSION"/>
+    <linkfile src="Makefile" dest="Makefile"/>
+    <annotation name="kept" value="1"/>
+  </project>
+  
+  <repo-hooks in-project="foo" enabled-list="pre-upload commit-msg"/>
+</manifest>
+`, string(data))
+}
+
+func TestManifestMarshalWithSources(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &Manifest{}
+	assert.Nil(m.Merge(&Manifest{
+		SourceFile: "default.xml",
+		Remotes:    []Remote{{Name: "origin", Fetch: ".."}},
+		Default:    &Default{RemoteName: "origin", Revision: "master"},
+		Projects: []Project{
+			{Name: "foo", Path: "foo"},
+			{Name: "bar", Path: "bar"},
+		},
+	}))
+	assert.Nil(m.Merge(&Manifest{
+		SourceFile: "jobs.xml",
+		Default:    &Default{SyncJ: 4},
+	}))
+	assert.Nil(m.Merge(&Manifest{
+		SourceFile:     "local.xml",
+		RemoveProjects: []RemoveProject{{Name: "bar"}},
+		Projects:       []Project{{Name: "baz", Path: "bar"}},
+		ExtendProjects: []ExtendProject{{Name: "foo", Path: "foo", Groups: "app"}},
+		OverrideProjects: []OverrideProject{
+			{Name: "foo", Revision: "dev"},
+		},
+	}))
+
+	data, err := m.MarshalWithSources()
+	assert.Nil(err)
+	assert.Equal(`<?xml version="1.0" encoding="UTF-8"?>
+<manifest>
+  <remote name="origin" fetch=".."/>
+  
+  <!-- remote from default.xml; revision from default.xml; sync-j from jobs.xml -->
+  <default remote="origin" revision="master" sync-j="4"/>
+  
+  <!-- project in local.xml -->
+  <project name="baz" path="bar"/>
+  <!-- project in default.xml; extend-project in local.xml: groups=app; override-project in local.xml: revision=dev -->
+  <project name="foo" revision="dev" groups="app"/>
+</manifest>
+`, string(data))
+
+	data, err = m.Marshal()
+	assert.Nil(err)
+	assert.NotContains(string(data), "<!--")
+}
@@ -0,0 +1,192 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	manifestSchema     *elementSchema
+	manifestSchemaOnce sync.Once
+)
+
+// Finding is a problem found in manifest file, such as unknown element
+// or attribute which is ignored by parser.
+type Finding struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (v Finding) String() string {
+	if v.File == "" {
+		return fmt.Sprintf("line %d: %s", v.Line, v.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", v.File, v.Line, v.Message)
+}
+
+// elementSchema defines known attributes and child elements of an
+// element, which are generated from xml tags of structs.
+type elementSchema struct {
+	attrs    map[string]bool
+	children map[string]*elementSchema
+}
+
+func newSchema(t reflect.Type, cache map[reflect.Type]*elementSchema) *elementSchema {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if s, ok := cache[t]; ok {
+		return s
+	}
+
+	s := &elementSchema{
+		attrs:    make(map[string]bool),
+		children: make(map[string]*elementSchema),
+	}
+	if t.Kind() != reflect.Struct {
+		return s
+	}
+	cache[t] = s
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("xml")
+		if tag == "" || tag == "-" || field.Name == "XMLName" {
+			continue
+		}
+		items := strings.Split(tag, ",")
+		name, opts := items[0], items[1:]
+		isAttr := false
+		for _, opt := range opts {
+			if opt == "attr" {
+				isAttr = true
+			}
+		}
+		if isAttr {
+			s.attrs[name] = true
+		} else if name != "" {
+			s.children[name] = newSchema(field.Type, cache)
+		}
+	}
+	return s
+}
+
+func getManifestSchema() *elementSchema {
+	manifestSchemaOnce.Do(func() {
+		manifestSchema = newSchema(reflect.TypeOf(Manifest{}),
+			make(map[reflect.Type]*elementSchema))
+	})
+	return manifestSchema
+}
+
+// CheckFields finds unknown elements and attributes in manifest XML,
+// which are silently dropped by parser, such as typo "revison".
+func CheckFields(buf []byte) ([]Finding, error) {
+	var (
+		findings = []Finding{}
+		stack    = []*elementSchema{}
+		decoder  = xml.NewDecoder(bytes.NewReader(buf))
+	)
+
+	lineAt := func(offset int64) int {
+		return bytes.Count(buf[:offset], []byte("\n")) + 1
+	}
+
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return findings, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			var schema *elementSchema
+			if len(stack) == 0 {
+				if t.Name.Local == "manifest" {
+					schema = getManifestSchema()
+				}
+			} else if parent := stack[len(stack)-1]; parent != nil {
+				schema = parent.children[t.Name.Local]
+				if schema == nil {
+					findings = append(findings, Finding{
+						Line:    lineAt(offset),
+						Message: fmt.Sprintf("unknown element <%s>", t.Name.Local),
+					})
+				}
+			}
+			// Children of unknown element are not checked.
+			if schema != nil {
+				for _, attr := range t.Attr {
+					if attr.Name.Space != "" || schema.attrs[attr.Name.Local] {
+						continue
+					}
+					findings = append(findings, Finding{
+						Line: lineAt(offset),
+						Message: fmt.Sprintf("unknown attribute '%s' in element <%s>",
+							attr.Name.Local, t.Name.Local),
+					})
+				}
+			}
+			stack = append(stack, schema)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return findings, nil
+}
+
+// CheckFile finds unknown elements and attributes in manifest file.
+func CheckFile(file string) ([]Finding, error) {
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	findings, err := CheckFields(buf)
+	for i := range findings {
+		findings[i].File = file
+	}
+	return findings, err
+}
+
+// Validate checks manifest file of repoDir, and files it includes, and
+// local manifests. Returns findings for unknown elements and attributes.
+func Validate(repoDir string) ([]Finding, error) {
+	file, err := manifestFile(repoDir)
+	if err != nil {
+		return nil, err
+	}
+
+	o := newLoadOptions(repoDir)
+	// Do not fail on unknown fields, but report them.
+	o.Strict = false
+	findings := []Finding{}
+	for _, f := range append([]string{file}, localManifestFiles(repoDir)...) {
+		ms, err := parseXML(f, "", nil, o)
+		if err != nil {
+			return findings, err
+		}
+		for _, m := range ms {
+			// Optional include which does not exist.
+			if _, err := os.Stat(m.SourceFile); os.IsNotExist(err) {
+				continue
+			}
+			result, err := CheckFile(m.SourceFile)
+			if err != nil {
+				return findings, err
+			}
+			findings = append(findings, result...)
+		}
+	}
+	return findings, nil
+}
@@ -0,0 +1,96 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckFields(t *testing.T) {
+	assert := assert.New(t)
+
+	findings, err := CheckFields([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<manifest>
+  <remote name="origin" fetch=".." revison="master"/>
+  <default remote="origin" revision="master" sync-j="4"/>
+  <project name="main" path="main" groups="app">
+    <annotation name="a" value="b"/>
+    <project name="lib" pth="lib"/>
+    <copyfile src="a" dest="b"/>
+  </project>
+  <superproject name="super" remote="origin">
+    <project unknown="true"/>
+  </superproject>
+  <include name="site.xml" optional="true" groups="site"/>
+</manifest>`))
+	assert.Nil(err)
+	msgs := []string{}
+	for _, finding := range findings {
+		msgs = append(msgs, finding.String())
+	}
+	assert.Equal([]string{
+		"line 3: unknown attribute 'revison' in element <remote>",
+		"line 7: unknown attribute 'pth' in element <project>",
+		"line 10: unknown element <superproject>",
+	}, msgs)
+
+	_, err = CheckFields([]byte(`<manifest><project></manifest>`))
+	assert.NotNil(err)
+}
+
+func TestStrictParsing(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	oldCache := manifestCache
+	manifestCache = newCache()
+	defer func() { manifestCache = oldCache }()
+
+	repoDir := filepath.Join(tmpdir, ".repo")
+	assert.Nil(os.MkdirAll(filepath.Join(repoDir, "local_manifests"), 0755))
+	manifestFile := filepath.Join(repoDir, "manifest.xml")
+	assert.Nil(ioutil.WriteFile(manifestFile, []byte(`
+<manifest>
+  <remote name="origin" fetch=".." revision="master"/>
+  <default remote="origin"/>
+  <project name="main" path="main"/>
+  <include name="inc.xml"/>
+</manifest>`), 0644))
+	assert.Nil(ioutil.WriteFile(filepath.Join(repoDir, "inc.xml"), []byte(`
+<manifest>
+  <project name="foo" path="foo" revison="v1"/>
+</manifest>`), 0644))
+	assert.Nil(ioutil.WriteFile(filepath.Join(repoDir, "local_manifests", "local.xml"), []byte(`
+<manifest>
+  <remove-project name="foo" path="foo"/>
+</manifest>`), 0644))
+
+	// Unknown fields are ignored by default.
+	m, err := Load(repoDir)
+	assert.Nil(err)
+	assert.Equal([]string{"main"}, projectNames(m))
+
+	findings, err := Validate(repoDir)
+	assert.Nil(err)
+	assert.Equal(2, len(findings))
+	assert.Equal(filepath.Join(repoDir, "inc.xml")+":3: unknown attribute 'revison' in element <project>",
+		findings[0].String())
+	assert.Equal(filepath.Join(repoDir, "local_manifests", "local.xml")+":3: unknown attribute 'path' in element <remove-project>",
+		findings[1].String())
+
+	config.GitDefaultConfig.Set(config.CfgRepoManifestStrict, "true")
+	defer config.GitDefaultConfig.Unset(config.CfgRepoManifestStrict)
+	_, err = Load(repoDir)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "unknown fields in manifest file '"+filepath.Join(repoDir, "inc.xml")+"':")
+	assert.Contains(err.Error(), "inc.xml:3: unknown attribute 'revison' in element <project>")
+}
@@ -6,7 +6,7 @@ import (
 
 	"github.com/alibaba/git-repo-go/common"
 	"github.com/alibaba/git-repo-go/config"
-	log "github.com/jiangxin/multi-log"
+	"github.com/alibaba/git-repo-go/log"
 	"gopkg.in/src-d/go-git.v4/plumbing"
 )
 
@@ -30,7 +30,11 @@ type Reference struct {
 // Heads returns branches of repository.
 func (v Repository) Heads() []Branch {
 	var heads []Branch
-	branches, err := v.Raw().Branches()
+	raw := v.Raw()
+	if raw == nil {
+		return nil
+	}
+	branches, err := raw.Branches()
 	if err != nil {
 		return nil
 	}
@@ -198,17 +202,12 @@ func (v Project) ResolveRemoteTracking(rev string) (string, error) {
 	if rev == "" {
 		log.Errorf("empty Revision for project '%s'", v.Name)
 	}
-	if !common.IsSha(rev) {
-		if common.IsHead(rev) {
-			rev = strings.TrimPrefix(rev, config.RefsHeads)
-		}
-		if !strings.HasPrefix(rev, config.Refs) {
-			rev = fmt.Sprintf("%s%s/%s",
-				config.RefsRemotes,
-				v.RemoteName,
-				rev)
-		}
+	spec := v.NewRevisionSpec(rev)
+	if spec.Kind != RevisionBranch {
+		// Tags may be annotated, and abbreviated SHAs are resolved by git.
+		return v.peelCommit(spec.CheckoutTarget())
 	}
+	rev = spec.CheckoutTarget()
 	revid, err := raw.ResolveRevision(plumbing.Revision(rev))
 	if err != nil {
 		return "", fmt.Errorf("revision %s in %s not found", rev, v.Name)
@@ -298,3 +297,101 @@ func (v Project) UpdateBranchTracking(branch, remote, track string) {
 
 	v.SaveConfig(cfg)
 }
+
+// AheadBehind counts commits of branch which are ahead of and behind
+// its local tracking branch.
+func (v Repository) AheadBehind(branch string) (int, int, error) {
+	if branch == "" {
+		branch = v.GetHead()
+	}
+	if branch == "" {
+		return 0, 0, fmt.Errorf("%sdetached HEAD has no tracking branch", v.Prompt())
+	}
+	if !common.IsHead(branch) {
+		branch = config.RefsHeads + branch
+	}
+	track := v.LocalTrackBranch(branch)
+	if track == "" {
+		return 0, 0, fmt.Errorf("%sno tracking branch for %s", v.Prompt(), branch)
+	}
+	raw := v.Raw()
+	if raw == nil {
+		return 0, 0, fmt.Errorf("%srepository is missing", v.Prompt())
+	}
+	trackID, err := raw.ResolveRevision(plumbing.Revision(track))
+	if err != nil {
+		return 0, 0, fmt.Errorf("%stracking branch %s is not fetched", v.Prompt(), track)
+	}
+	// Same commit, no need to run rev-list.
+	if branchID, err := raw.ResolveRevision(plumbing.Revision(branch)); err == nil && *branchID == *trackID {
+		return 0, 0, nil
+	}
+
+	ahead, err := v.Revlist(branch, "--not", track)
+	if err != nil {
+		return 0, 0, err
+	}
+	behind, err := v.Revlist(track, "--not", branch)
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(ahead), len(behind), nil
+}
+
+// UnpublishedCommits returns commits of branch which are neither in its
+// tracking branch nor published for review, in "<abbrev> <subject>"
+// format, newest first.
+func (v Project) UnpublishedCommits(branch string) ([]string, error) {
+	if !common.IsHead(branch) {
+		branch = config.RefsHeads + branch
+	}
+	track := v.LocalTrackBranch(branch)
+	if track == "" {
+		return nil, fmt.Errorf("%sno tracking branch for %s", v.Prompt(), branch)
+	}
+	if !v.RevisionIsValid(track) {
+		return nil, fmt.Errorf("%stracking branch %s is not fetched", v.Prompt(), track)
+	}
+
+	args := []string{config.GIT, "log", "--format=%h %s", branch, "--not", track}
+	if pub := v.PublishedReference(strings.TrimPrefix(branch, config.RefsHeads)); pub != "" {
+		args = append(args, pub)
+	}
+	result := v.ExecuteCommand(args...)
+	if !result.Success() {
+		return nil, fmt.Errorf("%sfail to list commits of %s: %s",
+			v.Prompt(),
+			branch,
+			strings.TrimSpace(result.Stderr()))
+	}
+	commits := []string{}
+	for _, line := range strings.Split(result.Stdout(), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			commits = append(commits, line)
+		}
+	}
+	return commits, nil
+}
+
+// CheckoutBranch switches worktree to an existing local branch.
+func (v Project) CheckoutBranch(branch string) error {
+	if common.IsHead(branch) {
+		branch = strings.TrimPrefix(branch, config.RefsHeads)
+	}
+
+	if v.GetHead() == config.RefsHeads+branch {
+		return nil
+	}
+	if !v.RevisionIsValid(config.RefsHeads + branch) {
+		return fmt.Errorf("%sno branch '%s'", v.Prompt(), branch)
+	}
+
+	cmdArgs := []string{
+		GIT,
+		"checkout",
+		branch,
+		"--",
+	}
+	return executeCommandIn(v.WorkDir, cmdArgs)
+}
@@ -0,0 +1,67 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeadsAndAheadBehind(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	gitIn(tmpdir, "init", "-q")
+	gitIn(tmpdir, "checkout", "-q", "-b", "master")
+	gitIn(tmpdir, "commit", "-q", "--allow-empty", "-m", "initial")
+	master := gitIn(tmpdir, "rev-parse", "HEAD")
+	gitIn(tmpdir, "update-ref", "refs/remotes/origin/master", master)
+	gitIn(tmpdir, "checkout", "-q", "-b", "topic")
+	gitIn(tmpdir, "config", "branch.topic.remote", "origin")
+	gitIn(tmpdir, "config", "branch.topic.merge", "refs/heads/master")
+	// Branches in packed-refs and loose refs are both listed.
+	gitIn(tmpdir, "pack-refs", "--all")
+	gitIn(tmpdir, "commit", "-q", "--allow-empty", "-m", "topic")
+	topic := gitIn(tmpdir, "rev-parse", "HEAD")
+
+	p := Project{}
+	p.WorkDir = tmpdir
+	p.DotGit = filepath.Join(tmpdir, ".git")
+	p.Settings = &RepoSettings{}
+
+	heads := map[string]string{}
+	for _, b := range p.Heads() {
+		heads[b.Name] = b.Hash
+	}
+	assert.Equal(map[string]string{
+		"refs/heads/master": master,
+		"refs/heads/topic":  topic,
+	}, heads)
+
+	ahead, behind, err := p.AheadBehind("topic")
+	assert.Nil(err)
+	assert.Equal(1, ahead)
+	assert.Equal(0, behind)
+
+	// Same commit as tracking branch.
+	gitIn(tmpdir, "update-ref", "refs/remotes/origin/master", topic)
+	ahead, behind, err = p.AheadBehind("topic")
+	assert.Nil(err)
+	assert.Equal(0, ahead)
+	assert.Equal(0, behind)
+
+	_, _, err = p.AheadBehind("master")
+	assert.NotNil(err)
+
+	// Repository is missing.
+	p.DotGit = filepath.Join(tmpdir, "missing")
+	p.GitDir = filepath.Join(tmpdir, "missing.git")
+	assert.Nil(p.Heads())
+}
@@ -0,0 +1,65 @@
+package project
+
+import (
+	"sort"
+)
+
+// BranchInfo is the state of a topic branch across projects.
+type BranchInfo struct {
+	Name      string
+	Projects  []*Project
+	Current   []*Project
+	Published []*Project
+}
+
+// ShortName removes prefix "refs/heads/".
+func (v BranchInfo) ShortName() string {
+	return Branch{Name: v.Name}.ShortName()
+}
+
+// IsCurrent indicates branch is checked out in any project.
+func (v BranchInfo) IsCurrent() bool {
+	return len(v.Current) > 0
+}
+
+// IsPublished indicates branch is published in all its projects.
+func (v BranchInfo) IsPublished() bool {
+	return len(v.Published) > 0 && len(v.Published) == len(v.Projects)
+}
+
+// IsPartlyPublished indicates branch is published in some of its projects.
+func (v BranchInfo) IsPartlyPublished() bool {
+	return len(v.Published) > 0 && len(v.Published) < len(v.Projects)
+}
+
+// CollectBranches aggregates local branches of projects, sorted by name.
+func CollectBranches(projects []*Project) []*BranchInfo {
+	var (
+		result    []*BranchInfo
+		infoByRef = make(map[string]*BranchInfo)
+	)
+
+	for _, p := range projects {
+		head := p.GetHead()
+		for _, b := range p.Heads() {
+			info, ok := infoByRef[b.Name]
+			if !ok {
+				info = &BranchInfo{Name: b.Name}
+				infoByRef[b.Name] = info
+				result = append(result, info)
+			}
+			info.Projects = append(info.Projects, p)
+			if b.Name == head {
+				info.Current = append(info.Current, p)
+			}
+			if p.PublishedRevision(b.Name) == b.Hash {
+				info.Published = append(info.Published, p)
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
@@ -0,0 +1,91 @@
+package project
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+)
+
+// BundleRefs returns remote tracking branches and tags of project, which
+// are saved in bundle to update workspace offline, with their object IDs.
+func (v Project) BundleRefs() (map[string]string, error) {
+	out, err := v.gitOutput("for-each-ref", "--format=%(objectname) %(refname)",
+		config.RefsRemotes+v.RemoteName+"/", config.RefsTags)
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		items := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(items) != 2 || strings.HasSuffix(items[1], "/HEAD") {
+			continue
+		}
+		refs[items[1]] = items[0]
+	}
+	return refs, nil
+}
+
+// CreateUpdateBundle saves refs with commits, which are not reachable from
+// commits of since, in bundle file. Commits of since which do not exist in
+// project are ignored. Returns false if there are no new commits.
+func (v Project) CreateUpdateBundle(file string, refs map[string]string, since []string) (bool, error) {
+	names := []string{}
+	for name := range refs {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return false, nil
+	}
+	sort.Strings(names)
+
+	exclude := []string{}
+	for _, commit := range since {
+		if v.RevisionIsValid(commit) {
+			exclude = append(exclude, commit)
+		}
+	}
+	commits, err := v.Revlist(append(append(names, "--not"), exclude...)...)
+	if err != nil {
+		return false, err
+	}
+	if len(commits) == 0 {
+		return false, nil
+	}
+
+	args := append([]string{"bundle", "create", file}, names...)
+	for _, commit := range exclude {
+		args = append(args, "^"+commit)
+	}
+	if _, err = v.gitOutput(args...); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ImportBundle fetches remote tracking branches and tags from bundle file
+// created by CreateUpdateBundle. Repository of project is initialized if
+// it does not exist.
+func (v *Project) ImportBundle(file string) error {
+	if !v.Repository.Exists() {
+		if err := v.GitInit(); err != nil {
+			return err
+		}
+	}
+	// Worktree of new project is not checked out yet, run in gitdir.
+	dir := v.RepoDir()
+	if err := executeCommandIn(dir, []string{config.GIT, "bundle", "verify", "-q", file}); err != nil {
+		return fmt.Errorf("%sbad bundle '%s': %s", v.Prompt(), file, err)
+	}
+	remoteRefs := config.RefsRemotes + v.RemoteName + "/"
+	err := executeCommandIn(dir, []string{config.GIT, "fetch", "-q", file,
+		"+" + remoteRefs + "*:" + remoteRefs + "*",
+		"+" + config.RefsTags + "*:" + config.RefsTags + "*"})
+	if err != nil {
+		return fmt.Errorf("%sfail to fetch from bundle: %s", v.Prompt(), err)
+	}
+	log.Debugf("%simported bundle %s", v.Prompt(), file)
+	return nil
+}
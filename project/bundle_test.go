@@ -0,0 +1,35 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBundleRefs(t *testing.T) {
+	assert := assert.New(t)
+
+	mock := helper.MockExecutor{
+		Handler: func(c *helper.Command) ([]byte, error) {
+			return []byte("1111111111111111111111111111111111111111 refs/remotes/origin/HEAD\n" +
+				"1111111111111111111111111111111111111111 refs/remotes/origin/master\n" +
+				"2222222222222222222222222222222222222222 refs/tags/v1.0\n"), nil
+		},
+	}
+	defer helper.SetExecutor(&mock)()
+
+	p := Project{}
+	p.Path = "app"
+	p.RemoteName = "origin"
+	p.Settings = &RepoSettings{}
+	refs, err := p.BundleRefs()
+	assert.Nil(err)
+	assert.Equal(map[string]string{
+		"refs/remotes/origin/master": "1111111111111111111111111111111111111111",
+		"refs/tags/v1.0":             "2222222222222222222222222222222222222222",
+	}, refs)
+	assert.Equal([]string{
+		"git for-each-ref --format=%(objectname) %(refname) refs/remotes/origin/ refs/tags/",
+	}, mock.CommandLines())
+}
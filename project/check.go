@@ -0,0 +1,187 @@
+package project
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/path"
+)
+
+// gitLockFiles are lock files which git leaves in gitdir if crashed.
+var gitLockFiles = []string{
+	"index.lock",
+	"HEAD.lock",
+	"config.lock",
+	"packed-refs.lock",
+	"shallow.lock",
+}
+
+// CheckGitDir checks links between worktree, gitdir and objects of
+// project, and returns error for a dangling one.
+func (v Project) CheckGitDir() error {
+	if !v.IsBare && v.WorkDir != "" && path.IsDir(v.WorkDir) {
+		fi, err := os.Lstat(v.DotGit)
+		if err != nil {
+			return fmt.Errorf("missing '%s'", v.DotGit)
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if !path.Exist(v.DotGit) {
+				return fmt.Errorf("dangling symlink '%s'", v.DotGit)
+			}
+		} else if fi.Mode().IsRegular() {
+			dir := readGitDirFile(v.DotGit)
+			if dir == "" {
+				return fmt.Errorf("bad gitdir file '%s'", v.DotGit)
+			}
+			if !path.IsDir(dir) {
+				return fmt.Errorf("gitdir file '%s' points to missing '%s'", v.DotGit, dir)
+			}
+		}
+	}
+
+	if v.ObjectsGitDir != "" && path.IsDir(v.GitDir) && !path.IsDir(v.ObjectsGitDir) {
+		return fmt.Errorf("missing objects repository '%s'", v.ObjectsGitDir)
+	}
+
+	alternates := filepath.Join(v.CommonDir(), "objects", "info", "alternates")
+	f, err := os.Open(alternates)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		dir := strings.TrimSpace(s.Text())
+		if dir == "" || strings.HasPrefix(dir, "#") {
+			continue
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(alternates), "..", dir)
+		}
+		if !path.IsDir(dir) {
+			return fmt.Errorf("alternate '%s' in '%s' is missing", dir, alternates)
+		}
+	}
+	return nil
+}
+
+// GitLockFiles returns lock files left in gitdir of project.
+func (v Project) GitLockFiles() []string {
+	files := []string{}
+	dir := v.RepoDir()
+	for _, name := range gitLockFiles {
+		file := filepath.Join(dir, name)
+		if path.Exist(file) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// CheckPinned checks that revision of project in manifest is resolved to a
+// commit (pinned), which is reachable from remote branches or tags (or
+// from upstream branch if set), and is checked out. Returns the pinned
+// commit, commit of HEAD and problems found.
+func (v Project) CheckPinned() (string, string, []string) {
+	var problems []string
+
+	if !v.Exists() {
+		return "", "", []string{"repository is missing"}
+	}
+	pinned, err := v.ResolveRemoteTracking(v.Revision)
+	if err != nil {
+		problems = append(problems,
+			fmt.Sprintf("revision %s is not found", v.Revision))
+	} else if v.NewRevisionSpec(v.Revision).IsImmutable() {
+		refs := []string{config.RefsRemotes, config.RefsTags}
+		if v.IsBare {
+			refs = []string{config.RefsHeads, config.RefsTags}
+		}
+		if v.Upstream != "" {
+			refs = []string{v.NewRevisionSpec(v.Upstream).CheckoutTarget()}
+		}
+		out, err := v.gitOutput(append([]string{"for-each-ref", "--count=1",
+			"--format=%(refname)", "--contains", pinned}, refs...)...)
+		if err != nil || strings.TrimSpace(out) == "" {
+			problems = append(problems,
+				fmt.Sprintf("commit %s is not reachable from %s", pinned, strings.Join(refs, " ")))
+		}
+	}
+
+	head := ""
+	if !v.IsBare {
+		head, err = v.ResolveRevision("HEAD")
+		if err != nil || head == "" {
+			problems = append(problems, "HEAD is not valid")
+		} else if pinned != "" && head != pinned {
+			problems = append(problems,
+				fmt.Sprintf("HEAD %s does not match pinned commit %s", head, pinned))
+		}
+	}
+	return pinned, head, problems
+}
+
+// Fsck checks connectivity of objects of project by "git fsck
+// --connectivity-only".
+func (v Project) Fsck() error {
+	_, err := v.gitOutput("fsck", "--connectivity-only", "--no-dangling", "--no-progress")
+	return err
+}
+
+// CheckCopyAndLinkFiles checks files copied and linked by copyfile and
+// linkfile elements of project, and returns problems found.
+func (v Project) CheckCopyAndLinkFiles() []string {
+	var problems []string
+
+	for _, f := range v.CopyFiles {
+		src := filepath.Join(v.WorkDir, f.Src)
+		dest := filepath.Join(v.TopDir(), f.Dest)
+		srcData, err := ioutil.ReadFile(src)
+		if err != nil {
+			problems = append(problems,
+				fmt.Sprintf("source %s of copyfile %s is missing", f.Src, f.Dest))
+			continue
+		}
+		destData, err := ioutil.ReadFile(dest)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("copyfile %s is missing", f.Dest))
+		} else if !bytes.Equal(srcData, destData) {
+			problems = append(problems,
+				fmt.Sprintf("copyfile %s differs from %s", f.Dest, f.Src))
+		}
+	}
+
+	for _, f := range v.LinkFiles {
+		src := filepath.Clean(filepath.Join(v.WorkDir, f.Src))
+		dest := filepath.Join(v.TopDir(), f.Dest)
+		fi, err := os.Lstat(dest)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("linkfile %s is missing", f.Dest))
+			continue
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(dest)
+			if err == nil && !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(dest), target)
+			}
+			if err != nil || filepath.Clean(target) != src {
+				problems = append(problems,
+					fmt.Sprintf("linkfile %s does not point to %s", f.Dest, f.Src))
+			}
+			continue
+		}
+		// Hard link is used if symlink is not available.
+		srcInfo, err := os.Stat(src)
+		if err != nil || !os.SameFile(srcInfo, fi) {
+			problems = append(problems,
+				fmt.Sprintf("linkfile %s is not a link to %s", f.Dest, f.Src))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,79 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckGitDir(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-check-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	workDir := filepath.Join(tmpdir, "app")
+	gitDir := filepath.Join(tmpdir, ".repo", "projects", "app.git")
+	assert.Nil(os.MkdirAll(workDir, 0755))
+
+	p := Project{WorkDir: workDir}
+	p.DotGit = filepath.Join(workDir, ".git")
+	p.GitDir = gitDir
+	assert.Equal("missing '"+p.DotGit+"'", p.CheckGitDir().Error())
+
+	assert.Nil(ioutil.WriteFile(p.DotGit, []byte("gitdir: ../.repo/projects/app.git\n"), 0644))
+	assert.Equal("gitdir file '"+p.DotGit+"' points to missing '"+gitDir+"'", p.CheckGitDir().Error())
+
+	assert.Nil(os.MkdirAll(filepath.Join(gitDir, "objects", "info"), 0755))
+	assert.Nil(p.CheckGitDir())
+	assert.Equal([]string{}, p.GitLockFiles())
+
+	alternates := filepath.Join(gitDir, "objects", "info", "alternates")
+	assert.Nil(ioutil.WriteFile(alternates, []byte("../../missing.git/objects\n"), 0644))
+	assert.NotNil(p.CheckGitDir())
+	assert.Nil(os.MkdirAll(filepath.Join(tmpdir, ".repo", "projects", "missing.git", "objects"), 0755))
+	assert.Nil(p.CheckGitDir())
+
+	assert.Nil(ioutil.WriteFile(filepath.Join(gitDir, "index.lock"), nil, 0644))
+	assert.Equal([]string{filepath.Join(gitDir, "index.lock")}, p.GitLockFiles())
+}
+
+func TestCheckCopyAndLinkFiles(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-check-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	p := Project{WorkDir: filepath.Join(tmpdir, "build")}
+	p.Settings = &RepoSettings{TopDir: tmpdir}
+	p.CopyFiles = []manifest.CopyFile{{Src: "Makefile", Dest: "Makefile"}}
+	p.LinkFiles = []manifest.LinkFile{{Src: "envsetup.sh", Dest: "envsetup.sh"}}
+	assert.Nil(os.MkdirAll(p.WorkDir, 0755))
+	assert.Nil(ioutil.WriteFile(filepath.Join(p.WorkDir, "Makefile"), []byte("all:\n"), 0644))
+	assert.Nil(ioutil.WriteFile(filepath.Join(p.WorkDir, "envsetup.sh"), []byte("#!/bin/sh\n"), 0755))
+	assert.Equal([]string{
+		"copyfile Makefile is missing",
+		"linkfile envsetup.sh is missing",
+	}, p.CheckCopyAndLinkFiles())
+
+	assert.Nil(p.CopyAndLinkFiles())
+	assert.Nil(p.CheckCopyAndLinkFiles())
+
+	assert.Nil(ioutil.WriteFile(filepath.Join(tmpdir, "Makefile"), []byte("changed\n"), 0644))
+	assert.Nil(os.Remove(filepath.Join(tmpdir, "envsetup.sh")))
+	assert.Nil(os.Symlink("Makefile", filepath.Join(tmpdir, "envsetup.sh")))
+	assert.Equal([]string{
+		"copyfile Makefile differs from Makefile",
+		"linkfile envsetup.sh does not point to envsetup.sh",
+	}, p.CheckCopyAndLinkFiles())
+}
@@ -0,0 +1,94 @@
+package project
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/helper"
+)
+
+var reChangeIDLine = regexp.MustCompile(`(?m)^Change-Id: I[0-9a-f]{40}\s*$\n?`)
+
+// stripChangeID removes Change-Id lines from commit message.
+func stripChangeID(msg string) string {
+	return reChangeIDLine.ReplaceAllString(msg, "")
+}
+
+// ResolveCommit resolves revision, such as an abbreviated SHA, to the
+// full commit ID.
+func (v Project) ResolveCommit(rev string) (string, error) {
+	result := v.ExecuteCommand(config.GIT, "rev-parse", "--verify", "-q", rev+"^{commit}")
+	if !result.Success() {
+		return "", fmt.Errorf("%scannot find commit '%s'", v.Prompt(), rev)
+	}
+	return strings.TrimSpace(result.Stdout()), nil
+}
+
+// CherryPickChange cherry-picks commit into current branch. Change-Id of
+// the original commit is replaced with a new one, so that the result can
+// be uploaded as a new change.
+func (v Project) CherryPickChange(commit string) error {
+	if err := v.CherryPick(commit); err != nil {
+		return fmt.Errorf("%sfail to cherry-pick %s, resolve the conflicts, and run 'git cherry-pick --continue'",
+			v.Prompt(),
+			commit)
+	}
+	return v.rewriteChangeID(commit, fmt.Sprintf("(cherry picked from commit %s)", commit))
+}
+
+// RevertChange creates a commit in current branch to revert commit. A new
+// Change-Id is added if the reverted commit has one.
+func (v Project) RevertChange(commit string) error {
+	if err := v.Revert(commit, "--no-edit"); err != nil {
+		return fmt.Errorf("%sfail to revert %s, resolve the conflicts, and run 'git revert --continue'",
+			v.Prompt(),
+			commit)
+	}
+	return v.rewriteChangeID(commit, "")
+}
+
+// rewriteChangeID amends the HEAD commit, which was created from the
+// original commit, to drop the Change-Id copied from the original commit,
+// append note, and add a new Change-Id if the original commit has one.
+func (v Project) rewriteChangeID(original, note string) error {
+	orig, err := v.catCommit(original)
+	if err != nil {
+		return err
+	}
+	head, err := v.catCommit("HEAD")
+	if err != nil {
+		return err
+	}
+
+	msg := strings.TrimRight(stripChangeID(head.Message), "\n") + "\n"
+	if note != "" {
+		msg += "\n" + note + "\n"
+	}
+	if reChangeID.MatchString(orig.Message) {
+		// Derive Change-Id from content of the new commit.
+		changeID := fmt.Sprintf("I%x", sha1.Sum(head.Bytes()))
+		msg = addChangeID(msg, changeID)
+	}
+	if msg == head.Message {
+		return nil
+	}
+
+	var out bytes.Buffer
+	_, err = helper.RunCommand(&helper.Command{
+		Args:   []string{config.GIT, "commit", "--amend", "--no-verify", "--cleanup=whitespace", "-q", "-F", "-"},
+		Dir:    v.WorkDir,
+		Stdin:  strings.NewReader(msg),
+		Stdout: &out,
+		Stderr: &out,
+	})
+	if err != nil {
+		return fmt.Errorf("%sfail to rewrite message of HEAD: %s",
+			v.Prompt(),
+			strings.TrimSpace(out.String()))
+	}
+	return nil
+}
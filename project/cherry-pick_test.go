@@ -0,0 +1,17 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripChangeID(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("subject\n\nSigned-off-by: A U Thor <author@example.com>\n",
+		stripChangeID("subject\n\nChange-Id: I0123456789012345678901234567890123456789\n"+
+			"Signed-off-by: A U Thor <author@example.com>\n"))
+	assert.Equal("subject\n\nChange-Id: Iabc\n",
+		stripChangeID("subject\n\nChange-Id: Iabc\n"))
+}
@@ -0,0 +1,207 @@
+package project
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/path"
+)
+
+// errNoCloneBundle indicates no clone bundle is provided by server.
+var errNoCloneBundle = errors.New("no clone bundle")
+
+// cloneBundleURL returns URL of bundle file for the first fetch. Bundles
+// are downloaded from "<bundleuri>/<project>.bundle" if git config
+// "repo.bundleuri" is set, such as a CDN, or from "<url>/clone.bundle"
+// of HTTP remote.
+func (v Repository) cloneBundleURL() string {
+	var bundleURI string
+
+	if v.Settings != nil && v.Settings.Config != nil {
+		bundleURI = v.Settings.Config.Get(config.CfgRepoBundleURI)
+	}
+	if bundleURI == "" && config.GitDefaultConfig != nil {
+		bundleURI = config.GitDefaultConfig.Get(config.CfgRepoBundleURI)
+	}
+	if bundleURI != "" {
+		return strings.TrimSuffix(bundleURI, "/") + "/" + v.Name + ".bundle"
+	}
+
+	remoteURL := strings.TrimPrefix(v.RemoteURL, "persistent-")
+	u := config.ParseGitURL(remoteURL)
+	if u == nil || !u.IsHTTP() {
+		return ""
+	}
+	return strings.TrimSuffix(remoteURL, "/") + "/clone.bundle"
+}
+
+// getCloneBundle sends GET request to address by client, with credential
+// of the host the same as HTTP remotes, and requests content from offset
+// if it is not zero.
+func getCloneBundle(client *http.Client, address string, offset int64) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	auth, err := helper.GetHTTPAuth(address)
+	if err != nil {
+		log.Warnf("fail to get credential for '%s': %s", address, err)
+	}
+	auth.SetRequestAuth(req)
+	return client.Do(req)
+}
+
+// downloadCloneBundle downloads bundle from address to file. A partial
+// file left by an interrupted download is resumed. Returns
+// errNoCloneBundle if server has no bundle.
+func downloadCloneBundle(client *http.Client, address, file string) error {
+	var offset int64
+
+	if fi, err := os.Stat(file); err == nil {
+		offset = fi.Size()
+	}
+
+	release := helper.AcquireHost(address)
+	defer release()
+	resp, err := getCloneBundle(client, address, offset)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flag := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusOK:
+		flag |= os.O_TRUNC
+	case http.StatusPartialContent:
+		flag |= os.O_APPEND
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Download is already completed.
+		if offset > 0 {
+			return nil
+		}
+		return errNoCloneBundle
+	case http.StatusNotFound, http.StatusForbidden, http.StatusUnauthorized:
+		return errNoCloneBundle
+	default:
+		return fmt.Errorf("fail to download '%s': %s", address, resp.Status)
+	}
+
+	f, err := os.OpenFile(file, flag, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, helper.NewRateLimitedReader(resp.Body, helper.BandwidthLimiters(address)...))
+	return err
+}
+
+// verifyCloneBundle checks sha256 checksum of bundle against file
+// "<bundle-url>.sha256" on server, if there is one.
+func verifyCloneBundle(client *http.Client, address, file string) error {
+	resp, err := getCloneBundle(client, address+".sha256", 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Debugf("no checksum for clone bundle '%s': %s", address, resp.Status)
+		return nil
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return fmt.Errorf("bad checksum file for clone bundle '%s'", address)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return err
+	}
+	if actual := fmt.Sprintf("%x", h.Sum(nil)); !strings.EqualFold(actual, fields[0]) {
+		return fmt.Errorf("checksum mismatch for clone bundle '%s': %s != %s",
+			address, actual, fields[0])
+	}
+	return nil
+}
+
+// applyCloneBundle downloads bundle and fetches from it before the first
+// fetch of repository, so that most objects are downloaded from a static
+// file, which is much faster than fetching from git server, especially
+// through CDN.
+func (v Repository) applyCloneBundle(remote string) error {
+	address := v.cloneBundleURL()
+	if address == "" {
+		return nil
+	}
+
+	bundleFile := filepath.Join(v.RepoDir(), "clone.bundle")
+	partialFile := bundleFile + ".part"
+	if !path.Exist(bundleFile) {
+		log.Debugf("%sdownloading clone bundle from %s", v.Prompt(), address)
+		client := helper.NewHTTPClient(address, nil)
+		err := downloadCloneBundle(client, address, partialFile)
+		if err == errNoCloneBundle {
+			log.Debugf("%sno clone bundle in %s", v.Prompt(), address)
+			return nil
+		} else if err != nil {
+			// Partial file is kept to resume download next time.
+			return err
+		}
+		if err = verifyCloneBundle(client, address, partialFile); err != nil {
+			os.Remove(partialFile)
+			return err
+		}
+		if err = os.Rename(partialFile, bundleFile); err != nil {
+			return err
+		}
+	}
+	defer os.Remove(bundleFile)
+
+	_, err := helper.RunCommand(&helper.Command{
+		Args: []string{GIT, "bundle", "verify", "-q", bundleFile},
+		Dir:  v.RepoDir(),
+	})
+	if err != nil {
+		return fmt.Errorf("bad clone bundle: %s", err)
+	}
+
+	refspec := "+refs/heads/*:refs/remotes/" + remote + "/*"
+	if v.IsBare {
+		refspec = "+refs/heads/*:refs/heads/*"
+	}
+	cmdArgs := []string{
+		GIT,
+		"fetch",
+		"--quiet",
+		bundleFile,
+		refspec,
+		"+refs/tags/*:refs/tags/*",
+	}
+	log.Debugf("%sfetching clone bundle using command: %s", v.Prompt(), strings.Join(cmdArgs, " "))
+	if err = executeCommandIn(v.RepoDir(), cmdArgs); err != nil {
+		return fmt.Errorf("fail to fetch from clone bundle: %s", err)
+	}
+	return nil
+}
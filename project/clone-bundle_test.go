@@ -0,0 +1,94 @@
+package project
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloneBundleURL(t *testing.T) {
+	assert := assert.New(t)
+
+	repo := Repository{RemoteURL: "https://example.com/platform/app.git"}
+	repo.Name = "platform/app"
+	assert.Equal("https://example.com/platform/app.git/clone.bundle", repo.cloneBundleURL())
+
+	repo.RemoteURL = "persistent-https://example.com/platform/app.git"
+	assert.Equal("https://example.com/platform/app.git/clone.bundle", repo.cloneBundleURL())
+
+	repo.RemoteURL = "ssh://git@example.com/platform/app.git"
+	assert.Equal("", repo.cloneBundleURL())
+}
+
+func TestDownloadCloneBundle(t *testing.T) {
+	var (
+		assert  = assert.New(t)
+		content = "# v2 git bundle\n" + strings.Repeat("x", 100)
+		sum     = fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+		ranges  = []string{}
+		sumAuth string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app.git/clone.bundle":
+			ranges = append(ranges, r.Header.Get("Range"))
+			http.ServeContent(w, r, "clone.bundle", time.Time{}, strings.NewReader(content))
+		case "/app.git/clone.bundle.sha256":
+			sumAuth = r.Header.Get("Authorization")
+			w.Write([]byte(sum + "  clone.bundle\n"))
+		case "/bad.git/clone.bundle":
+			w.Write([]byte(content))
+		case "/bad.git/clone.bundle.sha256":
+			w.Write([]byte("0123456789abcdef\n"))
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := server.Client()
+
+	// Checksum is downloaded with credential of the host.
+	host := strings.TrimPrefix(server.URL, "http://")
+	config.GitDefaultConfig.Set(fmt.Sprintf(config.CfgRepoAuthToken, host), "secret")
+	defer config.GitDefaultConfig.Unset(fmt.Sprintf(config.CfgRepoAuthToken, host))
+
+	dir, err := ioutil.TempDir("", "git-repo-clone-bundle-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Resume partial download.
+	file := filepath.Join(dir, "clone.bundle.part")
+	assert.Nil(ioutil.WriteFile(file, []byte(content[:10]), 0644))
+	assert.Nil(downloadCloneBundle(client, server.URL+"/app.git/clone.bundle", file))
+	data, err := ioutil.ReadFile(file)
+	assert.Nil(err)
+	assert.Equal(content, string(data))
+	assert.Equal([]string{"bytes=10-"}, ranges)
+	assert.Nil(verifyCloneBundle(client, server.URL+"/app.git/clone.bundle", file))
+	assert.Equal("Bearer secret", sumAuth)
+
+	// Download is already completed.
+	assert.Nil(downloadCloneBundle(client, server.URL+"/app.git/clone.bundle", file))
+
+	file = filepath.Join(dir, "bad.bundle.part")
+	assert.Nil(downloadCloneBundle(client, server.URL+"/bad.git/clone.bundle", file))
+	assert.NotNil(verifyCloneBundle(client, server.URL+"/bad.git/clone.bundle", file))
+
+	file = filepath.Join(dir, "missing.bundle.part")
+	assert.Equal(errNoCloneBundle, downloadCloneBundle(client, server.URL+"/missing.git/clone.bundle", file))
+	// No checksum file, skip verify.
+	assert.Nil(verifyCloneBundle(client, server.URL+"/missing.git/clone.bundle", file))
+}
@@ -0,0 +1,32 @@
+package project
+
+import (
+	"github.com/alibaba/git-repo-go/config"
+)
+
+const cfgCommitTemplate = "commit.template"
+
+// SetCommitTemplate sets "commit.template" of project to file, which is
+// the commit template of workspace, or removes it if file is empty. The
+// installed file is saved in "repo.committemplate" of project, so that
+// commit template set by user is not changed.
+func (v Project) SetCommitTemplate(file string) error {
+	cfg := v.Config()
+	current := cfg.Get(cfgCommitTemplate)
+	installed := cfg.Get(config.CfgRepoCommitTemplate)
+	if current != "" && current != installed {
+		return nil
+	}
+	if current == file && installed == file {
+		return nil
+	}
+
+	if file == "" {
+		cfg.Unset(cfgCommitTemplate)
+		cfg.Unset(config.CfgRepoCommitTemplate)
+	} else {
+		cfg.Set(cfgCommitTemplate, file)
+		cfg.Set(config.CfgRepoCommitTemplate, file)
+	}
+	return v.SaveConfig(cfg)
+}
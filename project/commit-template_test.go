@@ -0,0 +1,45 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetCommitTemplate(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-template-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	p := Project{}
+	p.GitDir = filepath.Join(tmpdir, "app.git")
+	assert.Nil(os.MkdirAll(p.GitDir, 0755))
+
+	assert.Nil(p.SetCommitTemplate("/ws/.repo/manifests/commit.txt"))
+	assert.Equal("/ws/.repo/manifests/commit.txt", p.Config().Get("commit.template"))
+	assert.Equal("/ws/.repo/manifests/commit.txt", p.Config().Get(config.CfgRepoCommitTemplate))
+
+	assert.Nil(p.SetCommitTemplate("/ws/.repo/manifests/new.txt"))
+	assert.Equal("/ws/.repo/manifests/new.txt", p.Config().Get("commit.template"))
+
+	assert.Nil(p.SetCommitTemplate(""))
+	assert.Equal("", p.Config().Get("commit.template"))
+	assert.Equal("", p.Config().Get(config.CfgRepoCommitTemplate))
+
+	// Commit template set by user is not changed.
+	cfg := p.Config()
+	cfg.Set("commit.template", "~/.gitmessage")
+	assert.Nil(p.SaveConfig(cfg))
+	assert.Nil(p.SetCommitTemplate("/ws/.repo/manifests/commit.txt"))
+	assert.Equal("~/.gitmessage", p.Config().Get("commit.template"))
+	assert.Nil(p.SetCommitTemplate(""))
+	assert.Equal("~/.gitmessage", p.Config().Get("commit.template"))
+}
@@ -0,0 +1,190 @@
+package project
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/path"
+)
+
+// ConflictError indicates gitdir or worktree of project is used by
+// something else, which can be overwritten by sync with --force-sync.
+type ConflictError struct {
+	Project string
+	Path    string
+	Reason  string
+}
+
+func (v ConflictError) Error() string {
+	return fmt.Sprintf("cannot sync project '%s': '%s' %s, use --force-sync to overwrite",
+		v.Project, v.Path, v.Reason)
+}
+
+// IsConflictError checks whether err is ConflictError.
+func IsConflictError(err error) bool {
+	_, ok := err.(*ConflictError)
+	return ok
+}
+
+// samePath compares two paths, with symlinks resolved.
+func samePath(a, b string) bool {
+	if realA, err := filepath.EvalSymlinks(a); err == nil {
+		a = realA
+	}
+	if realB, err := filepath.EvalSymlinks(b); err == nil {
+		b = realB
+	}
+	return filepath.Clean(a) == filepath.Clean(b)
+}
+
+// gitDirConflict returns reason if gitdir of project links to objects
+// of another repository, such as project of the same path is renamed.
+func (v Project) gitDirConflict() string {
+	if v.ObjectsGitDir == "" || !path.IsDir(v.GitDir) {
+		return ""
+	}
+	objects := filepath.Join(v.GitDir, "objects")
+	target, err := os.Readlink(objects)
+	if err != nil {
+		// Not a symlink, unknown layout.
+		return ""
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(v.GitDir, target)
+	}
+	if samePath(target, filepath.Join(v.ObjectsGitDir, "objects")) {
+		return ""
+	}
+	return fmt.Sprintf("points to a different object directory '%s'", target)
+}
+
+// workDirConflict returns reason if ".git" of worktree points to
+// another gitdir.
+func (v Project) workDirConflict() string {
+	if v.IsMirror() || v.WorkDir == "" {
+		return ""
+	}
+	dotgit := filepath.Join(v.WorkDir, ".git")
+	fi, err := os.Lstat(dotgit)
+	if err != nil || fi.IsDir() {
+		return ""
+	}
+
+	var target string
+	if fi.Mode()&os.ModeSymlink != 0 {
+		target, err = os.Readlink(dotgit)
+	} else {
+		var data []byte
+		data, err = ioutil.ReadFile(dotgit)
+		line := strings.TrimSpace(string(data))
+		if !strings.HasPrefix(line, "gitdir:") {
+			return ""
+		}
+		target = strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))
+	}
+	if err != nil {
+		return ""
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(v.WorkDir, target)
+	}
+	if samePath(target, v.GitDir) {
+		return ""
+	}
+	// Worktree added by git worktree, or of legacy layout to migrate.
+	if v.IsWorktree() {
+		commonDir := Repository{GitDir: target}.CommonDir()
+		if samePath(commonDir, v.GitDir) || samePath(target, v.legacyGitDir()) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("is a worktree of another repository '%s'", target)
+}
+
+// removeAllExcept removes dir and all its children, except paths in
+// keeps, which are relative to dir, such as worktrees of nested projects.
+func removeAllExcept(dir string, keeps []string) error {
+	if len(keeps) == 0 {
+		return os.RemoveAll(dir)
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		kept := false
+		subKeeps := []string{}
+		for _, keep := range keeps {
+			if keep == name {
+				kept = true
+				break
+			}
+			if strings.HasPrefix(keep, name+"/") {
+				subKeeps = append(subKeeps, strings.TrimPrefix(keep, name+"/"))
+			}
+		}
+		if kept {
+			continue
+		}
+		if len(subKeeps) > 0 && entry.IsDir() {
+			err = removeAllExcept(filepath.Join(dir, name), subKeeps)
+		} else {
+			err = os.RemoveAll(filepath.Join(dir, name))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v Project) resolveConflict(dirs []string, nested []string, reason string, force bool) ([]string, error) {
+	if reason == "" {
+		return nil, nil
+	}
+	if !force {
+		return nil, &ConflictError{Project: v.Name, Path: dirs[0], Reason: reason}
+	}
+	log.Warnf("%sremove '%s' which %s", v.Prompt(), dirs[0], reason)
+	removed := []string{}
+	for _, dir := range dirs {
+		if !path.Exist(dir) {
+			continue
+		}
+		keeps := []string{}
+		if dir == v.WorkDir {
+			keeps = nested
+		}
+		if err := removeAllExcept(dir, keeps); err != nil {
+			return removed, fmt.Errorf("fail to remove '%s': %s", dir, err)
+		}
+		removed = append(removed, dir)
+	}
+	return removed, nil
+}
+
+// ResolveGitDirConflict checks whether gitdir of project links to
+// objects of another repository. Returns ConflictError if force is
+// false, or removes the gitdir and its worktree, and returns removed
+// paths. Worktrees of nested projects, which are relative paths in
+// nested, are kept.
+func (v Project) ResolveGitDirConflict(force bool, nested []string) ([]string, error) {
+	dirs := []string{v.GitDir}
+	if !v.IsMirror() && v.WorkDir != "" {
+		dirs = append(dirs, v.WorkDir)
+	}
+	return v.resolveConflict(dirs, nested, v.gitDirConflict(), force)
+}
+
+// ResolveWorkDirConflict checks whether worktree of project belongs to
+// another repository. Returns ConflictError if force is false, or
+// removes the worktree, except worktrees of nested projects in nested,
+// and returns removed paths.
+func (v Project) ResolveWorkDirConflict(force bool, nested []string) ([]string, error) {
+	return v.resolveConflict([]string{v.WorkDir}, nested, v.workDirConflict(), force)
+}
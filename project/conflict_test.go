@@ -0,0 +1,59 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/path"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoveAllExcept(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-conflict-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	workDir := filepath.Join(tmpdir, "main")
+	for _, dir := range []string{
+		"src",
+		"drivers/driver1/src",
+		"drivers/driver2",
+		"lib/nested",
+	} {
+		assert.Nil(os.MkdirAll(filepath.Join(workDir, dir), 0755))
+	}
+	for _, file := range []string{
+		"README.md",
+		"src/main.c",
+		"drivers/Makefile",
+		"drivers/driver1/src/driver.c",
+		"lib/nested/lib.c",
+	} {
+		assert.Nil(ioutil.WriteFile(filepath.Join(workDir, file), []byte("x\n"), 0644))
+	}
+
+	assert.Nil(removeAllExcept(workDir, []string{"drivers/driver1", "lib/nested"}))
+	for _, p := range []string{
+		"drivers/driver1/src/driver.c",
+		"lib/nested/lib.c",
+	} {
+		assert.True(path.Exist(filepath.Join(workDir, p)), p)
+	}
+	for _, p := range []string{
+		"README.md",
+		"src",
+		"drivers/Makefile",
+		"drivers/driver2",
+	} {
+		assert.False(path.Exist(filepath.Join(workDir, p)), p)
+	}
+
+	assert.Nil(removeAllExcept(workDir, nil))
+	assert.False(path.Exist(workDir))
+}
@@ -5,7 +5,8 @@ import (
 	"strconv"
 	"strings"
 
-	log "github.com/jiangxin/multi-log"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
 )
 
 // PatchSet contains code review reference and commits.
@@ -30,6 +31,9 @@ func (v Project) DownloadPatchSet(remoteName string, reviewID, patchID int) (*Pa
 		log.Fatalf("%snot remote tracking defined, and do not know where to download",
 			v.Prompt())
 	}
+	if patchID == 0 && remote.GetType() == helper.ProtoTypeGerrit {
+		patchID = v.latestPatchID(remote, reviewID)
+	}
 	reviewRef, err := remote.GetDownloadRef(strconv.Itoa(reviewID), strconv.Itoa(patchID))
 	if err != nil {
 		return nil, err
@@ -74,10 +78,48 @@ func (v Project) DownloadPatchSet(remoteName string, reviewID, patchID int) (*Pa
 	return &dl, nil
 }
 
+// latestPatchID finds the last patch set of a gerrit change by listing
+// review references on remote. Returns 0 if nothing found.
+func (v Project) latestPatchID(remote *Remote, reviewID int) int {
+	ref, err := remote.GetDownloadRef(strconv.Itoa(reviewID), "1")
+	if err != nil || !strings.HasSuffix(ref, "/1") {
+		return 0
+	}
+	prefix := strings.TrimSuffix(ref, "1")
+
+	result := v.ExecuteCommand(GIT, "ls-remote", remote.Name, prefix+"*")
+	if result.Error != nil {
+		log.Debugf("%sfail to list patch sets of %d: %s",
+			v.Prompt(),
+			reviewID,
+			result.Stderr())
+		return 0
+	}
+
+	latest := 0
+	for _, line := range strings.Split(result.Stdout(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !strings.HasPrefix(fields[1], prefix) {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(fields[1], prefix))
+		if err == nil && id > latest {
+			latest = id
+		}
+	}
+	if latest > 0 {
+		log.Debugf("%sfound latest patch set %d/%d", v.Prompt(), reviewID, latest)
+	}
+	return latest
+}
+
 // CherryPick runs cherry-pick on commits.
 func (v Project) CherryPick(commits ...string) error {
 	for i := len(commits) - 1; i >= 0; i-- {
 		c := commits[i]
+		if err := v.EnsureParents(c); err != nil {
+			return err
+		}
 		cmdArgs := []string{
 			GIT,
 			"cherry-pick",
@@ -93,14 +135,14 @@ func (v Project) CherryPick(commits ...string) error {
 	return nil
 }
 
-// Revert runs revert on commit.
-func (v Project) Revert(commit string) error {
-	cmdArgs := []string{
-		GIT,
-		"revert",
-		commit,
-		"--",
+// Revert runs revert on commit, with extra options of git revert, such
+// as "--no-edit".
+func (v Project) Revert(commit string, options ...string) error {
+	if err := v.EnsureParents(commit); err != nil {
+		return err
 	}
+	cmdArgs := append([]string{GIT, "revert"}, options...)
+	cmdArgs = append(cmdArgs, commit, "--")
 	log.Debugf("%swill execute: %s", v.Prompt(), strings.Join(cmdArgs, " "))
 	return executeCommandIn(v.WorkDir, cmdArgs)
 }
@@ -1,11 +1,14 @@
 package project
 
 import (
+	"bytes"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
 
-	log "github.com/jiangxin/multi-log"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
 )
 
 // CmdExecResult holds command output, and error.
@@ -61,17 +64,25 @@ func (v CmdExecResult) Success() bool {
 
 // ExecuteCommand runs command.
 func (v Project) ExecuteCommand(args ...string) *CmdExecResult {
+	return v.ExecuteCommandWithEnv(nil, args...)
+}
+
+// ExecuteCommandWithEnv runs command with extra environments, which are
+// in the form "key=value".
+func (v Project) ExecuteCommandWithEnv(env []string, args ...string) *CmdExecResult {
 	result := CmdExecResult{
 		Project: &v,
 	}
-	cmd := exec.Command(args[0], args[1:]...)
+	c := helper.Command{
+		Args: args,
+		Env:  env,
+	}
 	if v.IsMirror() {
-		cmd.Dir = v.GitDir
+		c.Dir = v.GitDir
 	} else {
-		cmd.Dir = v.WorkDir
+		c.Dir = v.WorkDir
 	}
-	cmd.Stdin = nil
-	result.Out, result.Error = cmd.Output()
+	result.Out, result.Error = helper.RunCommand(&c)
 	return &result
 }
 
@@ -80,17 +91,48 @@ func executeCommand(args ...string) error {
 }
 
 func executeCommandIn(cwd string, args []string) error {
-	cmd := exec.Command(args[0], args[1:]...)
+	return executeCommandWithEnvIn(cwd, nil, args)
+}
+
+// executeCommandWithEnvIn runs command in cwd with extra environments.
+func executeCommandWithEnvIn(cwd string, env []string, args []string) error {
 	if cwd != "" {
 		if _, err := os.Stat(cwd); err != nil {
 			log.Errorf("cannot enter '%s' to run %s",
 				cwd,
 				strings.Join(args, " "))
 		}
-		cmd.Dir = cwd
 	}
-	cmd.Stdin = nil
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	_, err := helper.RunCommand(&helper.Command{
+		Args:   args,
+		Dir:    cwd,
+		Env:    env,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+	return err
+}
+
+// executeNetworkCommandIn runs git command which accesses remote address.
+// Connections to the same host are limited, and command is retried by
+// policy if it fails because of network problems or rate limit.
+func executeNetworkCommandIn(cwd, address string, env []string, args []string, policy *helper.RetryPolicy) error {
+	return policy.Do(func() error {
+		var stderr bytes.Buffer
+
+		release := helper.AcquireHost(address)
+		defer release()
+
+		_, err := helper.RunCommand(&helper.Command{
+			Args:   args,
+			Dir:    cwd,
+			Env:    env,
+			Stdout: os.Stdout,
+			Stderr: io.MultiWriter(os.Stderr, &stderr),
+		})
+		if err != nil && helper.IsTransientGitError(stderr.String()) {
+			return helper.NewRetryableError(err)
+		}
+		return err
+	})
 }
@@ -0,0 +1,34 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecuteCommandWithMockExecutor(t *testing.T) {
+	assert := assert.New(t)
+
+	mock := helper.MockExecutor{
+		Handler: func(c *helper.Command) ([]byte, error) {
+			return []byte("ok"), nil
+		},
+	}
+	defer helper.SetExecutor(&mock)()
+
+	p := Project{}
+	p.Name = "project1"
+	p.Settings = &RepoSettings{}
+	p.WorkDir = "/path/of/workdir"
+	result := p.ExecuteCommandWithEnv([]string{"FOO=bar"}, "git", "status")
+	assert.True(result.Success())
+	assert.Equal("ok", result.Stdout())
+
+	err := executeCommandWithEnvIn("", nil, []string{"git", "fetch", "origin"})
+	assert.Nil(err)
+
+	assert.Equal([]string{"git status", "git fetch origin"}, mock.CommandLines())
+	assert.Equal("/path/of/workdir", mock.Commands[0].Dir)
+	assert.Equal([]string{"FOO=bar"}, mock.Commands[0].Env)
+}
@@ -0,0 +1,142 @@
+package project
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/cap"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
+)
+
+const (
+	// gcAutoLooseObjects is the number of loose objects to trigger gc,
+	// same as default gc.auto of git.
+	gcAutoLooseObjects = 6700
+	// gcAutoPackLimit is the number of packs to trigger gc, same as
+	// default gc.autoPackLimit of git.
+	gcAutoPackLimit = 50
+	// gcIncrementalSize is the size (in KiB) of object store, above
+	// which objects are repacked incrementally by git maintenance,
+	// because full repack of gc is too expensive.
+	gcIncrementalSize = 2 * 1024 * 1024
+)
+
+// GCOptions is options for garbage collection of object store.
+type GCOptions struct {
+	// Auto only runs gc if there are too many loose objects or packs.
+	Auto       bool
+	Aggressive bool
+	// Shared indicates the object store is shared by other projects,
+	// objects unreachable from this repository must not be pruned, or
+	// be made loose to be pruned by gc of other repositories.
+	Shared bool
+}
+
+// ObjectsStat holds output of `git count-objects -v`, sizes are in KiB.
+type ObjectsStat struct {
+	Count    int
+	Size     int64
+	InPack   int
+	Packs    int
+	SizePack int64
+	Garbage  int
+}
+
+// TotalSize returns size (in KiB) of loose objects and packs.
+func (v ObjectsStat) TotalSize() int64 {
+	return v.Size + v.SizePack
+}
+
+// NeedGC indicates there are too many loose objects, packs or garbage.
+func (v ObjectsStat) NeedGC() bool {
+	return v.Count >= gcAutoLooseObjects ||
+		v.Packs >= gcAutoPackLimit ||
+		v.Garbage > 0
+}
+
+func parseCountObjects(out []byte) *ObjectsStat {
+	stat := ObjectsStat{}
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		kv := strings.SplitN(string(line), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value, err := strconv.ParseInt(strings.TrimSpace(kv[1]), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch kv[0] {
+		case "count":
+			stat.Count = int(value)
+		case "size":
+			stat.Size = value
+		case "in-pack":
+			stat.InPack = int(value)
+		case "packs":
+			stat.Packs = int(value)
+		case "size-pack":
+			stat.SizePack = value
+		case "garbage":
+			stat.Garbage = int(value)
+		}
+	}
+	return &stat
+}
+
+// ObjectsStat returns statistics of objects of repository.
+func (v Repository) ObjectsStat() (*ObjectsStat, error) {
+	out, err := helper.RunCommand(&helper.Command{
+		Args: []string{GIT, "count-objects", "-v"},
+		Dir:  v.RepoDir(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fail to count objects of %s: %s", v.Name, err)
+	}
+	return parseCountObjects(out), nil
+}
+
+// GC runs garbage collection on objects of repository, and returns false
+// if it is skipped because of o.Auto. Large object stores are repacked
+// incrementally by git maintenance if git supports it.
+func (v Repository) GC(o *GCOptions) (bool, error) {
+	stat, err := v.ObjectsStat()
+	if err != nil {
+		return false, err
+	}
+	if o.Auto && !stat.NeedGC() {
+		log.Debugf("%sno need to gc (loose: %d, packs: %d)", v.Prompt(), stat.Count, stat.Packs)
+		return false, nil
+	}
+
+	cmdArgs := []string{GIT}
+	if !o.Aggressive && stat.TotalSize() >= gcIncrementalSize && cap.GitCanMaintenance() {
+		cmdArgs = append(cmdArgs,
+			"maintenance",
+			"run",
+			"--quiet",
+			"--task=loose-objects",
+			"--task=incremental-repack")
+	} else if o.Shared {
+		// "git gc --prune=never" still unpacks unreachable objects,
+		// keep them in the new pack instead.
+		cmdArgs = append(cmdArgs,
+			"repack",
+			"-a",
+			"-d",
+			"-q",
+			"--keep-unreachable")
+		if o.Aggressive {
+			cmdArgs = append(cmdArgs, "-f")
+		}
+	} else {
+		cmdArgs = append(cmdArgs, "gc", "--quiet")
+		if o.Aggressive {
+			cmdArgs = append(cmdArgs, "--aggressive")
+		}
+	}
+	log.Debugf("%sgc using command: %s", v.Prompt(), strings.Join(cmdArgs, " "))
+	return true, executeCommandIn(v.RepoDir(), cmdArgs)
+}
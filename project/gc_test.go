@@ -0,0 +1,66 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCountObjects(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		out    = []byte(`count: 7000
+size: 28
+in-pack: 1234
+packs: 3
+size-pack: 4096
+prune-packable: 0
+garbage: 0
+size-garbage: 0
+`)
+	)
+
+	stat := parseCountObjects(out)
+	assert.Equal(7000, stat.Count)
+	assert.Equal(int64(28), stat.Size)
+	assert.Equal(1234, stat.InPack)
+	assert.Equal(3, stat.Packs)
+	assert.Equal(int64(4096), stat.SizePack)
+	assert.Equal(0, stat.Garbage)
+	assert.Equal(int64(4124), stat.TotalSize())
+	assert.True(stat.NeedGC())
+
+	stat = parseCountObjects([]byte("count: 10\npacks: 1\n"))
+	assert.False(stat.NeedGC())
+	stat.Packs = 50
+	assert.True(stat.NeedGC())
+}
+
+func TestGCSharedObjectStore(t *testing.T) {
+	assert := assert.New(t)
+
+	mock := helper.MockExecutor{
+		Handler: func(c *helper.Command) ([]byte, error) {
+			if c.Args[1] == "count-objects" {
+				return []byte("count: 10\npacks: 1\nsize-pack: 100\n"), nil
+			}
+			return nil, nil
+		},
+	}
+	defer helper.SetExecutor(&mock)()
+
+	repo := Repository{GitDir: "/path/of/app.git"}
+	ran, err := repo.GC(&GCOptions{Shared: true})
+	assert.Nil(err)
+	assert.True(ran)
+	ran, err = repo.GC(&GCOptions{})
+	assert.Nil(err)
+	assert.True(ran)
+	assert.Equal([]string{
+		"git count-objects -v",
+		"git repack -a -d -q --keep-unreachable",
+		"git count-objects -v",
+		"git gc --quiet",
+	}, mock.CommandLines())
+}
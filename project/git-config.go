@@ -0,0 +1,121 @@
+package project
+
+import (
+	"strings"
+
+	"github.com/alibaba/git-repo-go/log"
+)
+
+// cfgRepoManifestConfig saves names of git config set by manifest, so
+// that they are removed when removed from manifest.
+const cfgRepoManifestConfig = "repo.manifestconfig"
+
+// safeGitConfigs are git config which neither run commands, load other
+// files nor change where and how objects are transferred, and are the only
+// ones can be set by manifest.
+var safeGitConfigs = map[string]bool{
+	"apply.whitespace":           true,
+	"core.autocrlf":              true,
+	"core.bigfilethreshold":      true,
+	"core.checkstat":             true,
+	"core.compression":           true,
+	"core.eol":                   true,
+	"core.filemode":              true,
+	"core.ignorecase":            true,
+	"core.longpaths":             true,
+	"core.precomposeunicode":     true,
+	"core.preloadindex":          true,
+	"core.quotepath":             true,
+	"core.safecrlf":              true,
+	"core.symlinks":              true,
+	"core.trustctime":            true,
+	"core.untrackedcache":        true,
+	"core.whitespace":            true,
+	"diff.algorithm":             true,
+	"diff.renamelimit":           true,
+	"diff.renames":               true,
+	"fetch.prune":                true,
+	"gc.auto":                    true,
+	"gc.autodetach":              true,
+	"i18n.commitencoding":        true,
+	"i18n.logoutputencoding":     true,
+	"index.version":              true,
+	"lfs.fetchexclude":           true,
+	"lfs.fetchinclude":           true,
+	"lfs.fetchrecentalways":      true,
+	"lfs.fetchrecentcommitsdays": true,
+	"lfs.fetchrecentrefsdays":    true,
+	"lfs.locksverify":            true,
+	"lfs.pruneoffsetdays":        true,
+	"lfs.setlockablereadonly":    true,
+	"merge.conflictstyle":        true,
+	"merge.ff":                   true,
+	"merge.renamelimit":          true,
+	"pull.ff":                    true,
+	"pull.rebase":                true,
+	"rebase.autosquash":          true,
+	"rebase.autostash":           true,
+	"rerere.autoupdate":          true,
+	"rerere.enabled":             true,
+	"status.showuntrackedfiles":  true,
+}
+
+// IsUnsafeGitConfig indicates whether git config of name cannot be set by
+// manifest. Only harmless config, such as "core.autocrlf", are allowed,
+// and others, such as "core.sshCommand", "pager.<cmd>" and
+// "url.<base>.insteadOf", which run commands or redirect traffic, are not.
+func IsUnsafeGitConfig(name string) bool {
+	return !safeGitConfigs[strings.ToLower(name)]
+}
+
+// ApplyGitConfigs sets git config of project defined in manifest, and
+// removes those which were set by manifest before but are removed now.
+func (v Project) ApplyGitConfigs() error {
+	cfg := v.Config()
+	changed := false
+
+	names := []string{}
+	for _, c := range v.GitConfigs {
+		if IsUnsafeGitConfig(c.Name) {
+			log.Warnf("%sgit config '%s' in manifest is not allowed", v.Prompt(), c.Name)
+			continue
+		}
+		if cfg.Get(c.Name) != c.Value {
+			cfg.Set(c.Name, c.Value)
+			changed = true
+		}
+		names = append(names, c.Name)
+	}
+
+	for _, name := range strings.Split(cfg.Get(cfgRepoManifestConfig), ",") {
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, n := range names {
+			if strings.EqualFold(n, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			cfg.Unset(name)
+			changed = true
+		}
+	}
+
+	value := strings.Join(names, ",")
+	if cfg.Get(cfgRepoManifestConfig) != value {
+		if value == "" {
+			cfg.Unset(cfgRepoManifestConfig)
+		} else {
+			cfg.Set(cfgRepoManifestConfig, value)
+		}
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return v.SaveConfig(cfg)
+}
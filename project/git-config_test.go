@@ -0,0 +1,90 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsUnsafeGitConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, name := range []string{"core.autocrlf", "lfs.fetchinclude", "core.fileMode", "pull.rebase"} {
+		assert.False(IsUnsafeGitConfig(name), name)
+	}
+	for _, name := range []string{
+		"core.sshCommand",
+		"core.fsmonitor",
+		"core.hooksPath",
+		"filter.lfs.smudge",
+		"alias.co",
+		"include.path",
+		"remote.origin.uploadpack",
+		"diff.foo.textconv",
+		"autocrlf",
+		"pager.log",
+		"difftool.foo.cmd",
+		"mergetool.foo.cmd",
+		"submodule.lib.update",
+		"protocol.ext.allow",
+		"remote.origin.url",
+		"remote.origin.pushurl",
+		"url.https://evil.example.com/.insteadOf",
+		"http.sslVerify",
+		"http.proxy",
+		"core.worktree",
+		"lfs.customtransfer.foo.path",
+		"lfs.standalonetransferagent",
+		"lfs.url",
+		"lfs.skipdownloaderrors",
+	} {
+		assert.True(IsUnsafeGitConfig(name), name)
+	}
+}
+
+func TestApplyGitConfigs(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-config-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	p := Project{}
+	p.GitDir = filepath.Join(tmpdir, "app.git")
+	assert.Nil(os.MkdirAll(p.GitDir, 0755))
+
+	p.GitConfigs = []manifest.GitConfig{
+		{Name: "core.autocrlf", Value: "input"},
+		{Name: "lfs.fetchinclude", Value: "assets"},
+		{Name: "lfs.url", Value: "https://evil.example.com/lfs"},
+		{Name: "core.sshCommand", Value: "evil"},
+	}
+	assert.Nil(p.ApplyGitConfigs())
+	cfg := p.Config()
+	assert.Equal("input", cfg.Get("core.autocrlf"))
+	assert.Equal("assets", cfg.Get("lfs.fetchinclude"))
+	assert.Equal("", cfg.Get("lfs.url"))
+	assert.Equal("", cfg.Get("core.sshCommand"))
+	assert.Equal("core.autocrlf,lfs.fetchinclude", cfg.Get(cfgRepoManifestConfig))
+
+	p.GitConfigs = []manifest.GitConfig{
+		{Name: "core.autocrlf", Value: "false"},
+	}
+	assert.Nil(p.ApplyGitConfigs())
+	cfg = p.Config()
+	assert.Equal("false", cfg.Get("core.autocrlf"))
+	assert.Equal("", cfg.Get("lfs.fetchinclude"))
+	assert.Equal("core.autocrlf", cfg.Get(cfgRepoManifestConfig))
+
+	p.GitConfigs = nil
+	assert.Nil(p.ApplyGitConfigs())
+	cfg = p.Config()
+	assert.Equal("", cfg.Get("core.autocrlf"))
+	assert.Equal("", cfg.Get(cfgRepoManifestConfig))
+}
@@ -0,0 +1,253 @@
+package project
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+)
+
+const (
+	indexSignature      = "DIRC"
+	indexEntryExtended  = 0x4000
+	indexSkipWorktree   = 0x4000
+	indexIntentToAdd    = 0x2000
+	indexNameMask       = 0x0fff
+	indexEntryFixedSize = 62
+)
+
+// indexEntry is an entry of git index with cached stat data.
+type indexEntry struct {
+	MTime        time.Time
+	Mode         uint32
+	Size         uint32
+	OID          string
+	Path         string
+	SkipWorktree bool
+	IntentToAdd  bool
+}
+
+// gitIndex holds entries of git index, and tree oid of root in cache
+// tree extension, which is empty if cache tree is invalid.
+type gitIndex struct {
+	Version  uint32
+	MTime    time.Time
+	Entries  []indexEntry
+	RootTree string
+}
+
+// errIndexUnsupported indicates index should be checked by git command.
+var errIndexUnsupported = errors.New("unsupported index")
+
+// readIndex parses index file of version 2, 3 and 4. Split index and
+// sparse index are not supported.
+func readIndex(file string) (*gitIndex, error) {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12+20 || string(data[:4]) != indexSignature {
+		return nil, fmt.Errorf("bad signature of index '%s'", file)
+	}
+
+	index := gitIndex{
+		Version: binary.BigEndian.Uint32(data[4:8]),
+		MTime:   fi.ModTime(),
+	}
+	if index.Version < 2 || index.Version > 4 {
+		return nil, errIndexUnsupported
+	}
+	count := binary.BigEndian.Uint32(data[8:12])
+	// Ignore trailing checksum.
+	data = data[:len(data)-20]
+	pos := 12
+	prevPath := []byte{}
+	for i := uint32(0); i < count; i++ {
+		if pos+indexEntryFixedSize > len(data) {
+			return nil, fmt.Errorf("index '%s' is truncated", file)
+		}
+		b := data[pos:]
+		entry := indexEntry{
+			MTime: time.Unix(int64(binary.BigEndian.Uint32(b[8:12])),
+				int64(binary.BigEndian.Uint32(b[12:16]))),
+			Mode: binary.BigEndian.Uint32(b[24:28]),
+			Size: binary.BigEndian.Uint32(b[36:40]),
+			OID:  hex.EncodeToString(b[40:60]),
+		}
+		flags := binary.BigEndian.Uint16(b[60:62])
+		n := indexEntryFixedSize
+		if flags&indexEntryExtended != 0 {
+			if index.Version < 3 || pos+n+2 > len(data) {
+				return nil, fmt.Errorf("bad extended flags in index '%s'", file)
+			}
+			extended := binary.BigEndian.Uint16(b[n : n+2])
+			entry.SkipWorktree = extended&indexSkipWorktree != 0
+			entry.IntentToAdd = extended&indexIntentToAdd != 0
+			n += 2
+		}
+
+		if index.Version == 4 {
+			// Path is prefix compressed: remove N bytes from previous
+			// path, and append the NUL-terminated suffix.
+			strip, size := binary.Uvarint(b[n:])
+			if size <= 0 || int(strip) > len(prevPath) {
+				return nil, fmt.Errorf("bad path in index '%s'", file)
+			}
+			n += size
+			end := bytes.IndexByte(b[n:], 0)
+			if end < 0 {
+				return nil, fmt.Errorf("bad path in index '%s'", file)
+			}
+			name := append([]byte{}, prevPath[:len(prevPath)-int(strip)]...)
+			name = append(name, b[n:n+end]...)
+			entry.Path = string(name)
+			prevPath = name
+			n += end + 1
+		} else {
+			end := bytes.IndexByte(b[n:], 0)
+			if end < 0 || (int(flags&indexNameMask) != indexNameMask && end != int(flags&indexNameMask)) {
+				return nil, fmt.Errorf("bad path in index '%s'", file)
+			}
+			entry.Path = string(b[n : n+end])
+			// Entry is padded with 1-8 NULs to multiple of 8 bytes.
+			n = (n + end + 8) &^ 7
+		}
+		index.Entries = append(index.Entries, entry)
+		pos += n
+	}
+
+	for pos+8 <= len(data) {
+		sig := string(data[pos : pos+4])
+		size := int(binary.BigEndian.Uint32(data[pos+4 : pos+8]))
+		pos += 8
+		if pos+size > len(data) {
+			return nil, fmt.Errorf("bad extension '%s' in index '%s'", sig, file)
+		}
+		switch sig {
+		case "TREE":
+			index.RootTree = parseRootTree(data[pos : pos+size])
+		case "link", "sdir":
+			return nil, errIndexUnsupported
+		}
+		pos += size
+	}
+	return &index, nil
+}
+
+// parseRootTree returns tree oid of root from data of cache tree
+// extension, or empty string if it is invalid.
+func parseRootTree(data []byte) string {
+	// Root entry: "" NUL entry_count SP subtrees LF oid
+	if len(data) == 0 || data[0] != 0 {
+		return ""
+	}
+	lf := bytes.IndexByte(data, '\n')
+	if lf < 0 {
+		return ""
+	}
+	fields := bytes.Fields(data[1:lf])
+	if len(fields) != 2 {
+		return ""
+	}
+	if n, err := strconv.Atoi(string(fields[0])); err != nil || n < 0 {
+		return ""
+	}
+	if len(data) < lf+1+20 {
+		return ""
+	}
+	return hex.EncodeToString(data[lf+1 : lf+1+20])
+}
+
+// matchStat checks whether file in worktree matches stat data in index.
+func (v indexEntry) matchStat(fi os.FileInfo, checkFileMode bool) bool {
+	switch v.Mode &^ 0777 {
+	case 0100000:
+		if !fi.Mode().IsRegular() {
+			return false
+		}
+		if checkFileMode && (fi.Mode()&0100 != 0) != (v.Mode&0100 != 0) {
+			return false
+		}
+	case 0120000:
+		if fi.Mode()&os.ModeSymlink == 0 {
+			return false
+		}
+	default:
+		// Submodules are checked by git.
+		return false
+	}
+	if uint32(fi.Size()) != v.Size {
+		return false
+	}
+	mtime := fi.ModTime()
+	if v.MTime.Nanosecond() == 0 {
+		mtime = mtime.Truncate(time.Second)
+	}
+	return mtime.Equal(v.MTime)
+}
+
+// isTrackedClean checks tracked files of worktree in process, instead of
+// running "git status -uno". It returns true only if files in worktree
+// match stat data in index, and index matches tree of HEAD using cache
+// tree extension. Returns false if worktree is dirty or cannot be
+// determined, and caller should check it using git command.
+func (v Project) isTrackedClean() bool {
+	if !config.GitDefaultConfig.GetBool(config.CfgRepoNativeRead, true) {
+		return false
+	}
+
+	index, err := readIndex(filepath.Join(v.RepoDir(), "index"))
+	if err != nil {
+		log.Debugf("%sfail to read index: %s", v.Prompt(), err)
+		return false
+	}
+	if index.RootTree == "" {
+		return false
+	}
+
+	raw := v.Raw()
+	if raw == nil {
+		return false
+	}
+	head, err := raw.Head()
+	if err != nil {
+		return false
+	}
+	commit, err := raw.CommitObject(head.Hash())
+	if err != nil || commit.TreeHash.String() != index.RootTree {
+		return false
+	}
+
+	checkFileMode := v.Config().GetBool("core.filemode", true)
+	for _, entry := range index.Entries {
+		if entry.IntentToAdd {
+			return false
+		}
+		if entry.SkipWorktree {
+			continue
+		}
+		fi, err := os.Lstat(filepath.Join(v.WorkDir, filepath.FromSlash(entry.Path)))
+		if err != nil || !entry.matchStat(fi, checkFileMode) {
+			return false
+		}
+		// Racily clean entry, file may be changed after index is
+		// written in the same timestamp.
+		if !entry.MTime.Before(index.MTime) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func gitIn(dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=user", "GIT_AUTHOR_EMAIL=user@example.com",
+		"GIT_COMMITTER_NAME=user", "GIT_COMMITTER_EMAIL=user@example.com")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		panic(string(out))
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// refreshIndex sets mtime of files in the past, and rewrites index, so
+// that entries are not racily clean. Git may compare mtime in seconds,
+// so use different age for each refresh.
+func refreshIndex(dir string, age time.Duration, files ...string) {
+	past := time.Now().Add(-age)
+	for _, name := range files {
+		os.Chtimes(filepath.Join(dir, name), past, past)
+	}
+	gitIn(dir, "update-index", "--refresh")
+	gitIn(dir, "update-index", "--force-write-index")
+}
+
+func TestReadIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	gitIn(tmpdir, "init", "-q")
+	os.MkdirAll(filepath.Join(tmpdir, "dir", "subdir"), 0755)
+	ioutil.WriteFile(filepath.Join(tmpdir, "README.md"), []byte("hello\n"), 0644)
+	ioutil.WriteFile(filepath.Join(tmpdir, "dir", "subdir", "run.sh"), []byte("#!/bin/sh\n"), 0755)
+	ioutil.WriteFile(filepath.Join(tmpdir, "dir", "subdir", "a-file-with-long-name"), []byte("x"), 0644)
+	os.Symlink("README.md", filepath.Join(tmpdir, "link"))
+	gitIn(tmpdir, "add", "-A")
+	gitIn(tmpdir, "commit", "-q", "-m", "initial")
+	expect := gitIn(tmpdir, "ls-files", "-s")
+	tree := gitIn(tmpdir, "rev-parse", "HEAD^{tree}")
+
+	for _, version := range []string{"2", "4"} {
+		gitIn(tmpdir, "update-index", "--index-version", version)
+		index, err := readIndex(filepath.Join(tmpdir, ".git", "index"))
+		assert.Nil(err)
+		assert.Equal(version, string('0'+rune(index.Version)))
+		lines := []string{}
+		for _, entry := range index.Entries {
+			lines = append(lines, strings.Join([]string{
+				strings.TrimLeft(string([]byte{
+					byte('0' + entry.Mode>>15&7),
+					byte('0' + entry.Mode>>12&7),
+					byte('0' + entry.Mode>>9&7),
+					byte('0' + entry.Mode>>6&7),
+					byte('0' + entry.Mode>>3&7),
+					byte('0' + entry.Mode&7),
+				}), "0"),
+				entry.OID,
+				"0\t" + entry.Path,
+			}, " "))
+		}
+		assert.Equal(expect, strings.Join(lines, "\n"))
+		assert.Equal(tree, index.RootTree)
+	}
+
+	_, err = readIndex(filepath.Join(tmpdir, "README.md"))
+	assert.NotNil(err)
+}
+
+func TestIsTrackedClean(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	gitIn(tmpdir, "init", "-q")
+	ioutil.WriteFile(filepath.Join(tmpdir, "README.md"), []byte("hello\n"), 0644)
+	ioutil.WriteFile(filepath.Join(tmpdir, "VERSION"), []byte("1.0\n"), 0644)
+	gitIn(tmpdir, "add", "-A")
+	gitIn(tmpdir, "commit", "-q", "-m", "initial")
+
+	p := Project{}
+	p.WorkDir = tmpdir
+	p.DotGit = filepath.Join(tmpdir, ".git")
+	p.Settings = &RepoSettings{}
+
+	assert.True(p.IsClean())
+
+	refreshIndex(tmpdir, time.Hour, "README.md", "VERSION")
+	assert.True(p.isTrackedClean())
+
+	// Untracked file is ignored.
+	ioutil.WriteFile(filepath.Join(tmpdir, "untracked"), []byte("x"), 0644)
+	assert.True(p.isTrackedClean())
+
+	// File changed in worktree.
+	ioutil.WriteFile(filepath.Join(tmpdir, "README.md"), []byte("hello, world\n"), 0644)
+	assert.False(p.isTrackedClean())
+	assert.False(p.IsClean())
+
+	// Changes in index.
+	gitIn(tmpdir, "add", "README.md")
+	refreshIndex(tmpdir, 2*time.Hour, "README.md", "VERSION")
+	assert.False(p.isTrackedClean())
+	assert.False(p.IsClean())
+
+	gitIn(tmpdir, "commit", "-q", "-m", "update")
+	refreshIndex(tmpdir, 3*time.Hour, "README.md", "VERSION")
+	assert.True(p.isTrackedClean())
+
+	// Mode changed.
+	os.Chmod(filepath.Join(tmpdir, "VERSION"), 0755)
+	assert.False(p.isTrackedClean())
+	assert.False(p.IsClean())
+}
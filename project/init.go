@@ -3,11 +3,11 @@ package project
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/alibaba/git-repo-go/file"
+	"github.com/alibaba/git-repo-go/helper"
 	"github.com/alibaba/git-repo-go/path"
 )
 
@@ -38,7 +38,10 @@ func (v *Project) GitInit() error {
 	}
 
 	objectsRepo := v.ObjectsRepository()
-	if objectsRepo != nil {
+	if objectsRepo != nil && v.IsWorktree() {
+		// Shared object store is the main repository of git worktree.
+		objectsRepo.Init(v.RemoteName, remoteURL, referenceGitDir)
+	} else if objectsRepo != nil {
 		objectsRepo.Init("", "", "")
 		v.Repository.InitByLink(v.RemoteName, remoteURL, objectsRepo)
 	} else {
@@ -114,9 +117,7 @@ func (v *Repository) Init(remoteName, remoteURL, referenceGitDir string) error {
 		v.GitDir,
 	}
 
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
-	cmd.Stdin = nil
-	err = cmd.Run()
+	_, err = helper.RunCommand(&helper.Command{Args: cmdArgs})
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,120 @@
+package project
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
+)
+
+// lfsAnnotation is name of annotation to turn on or off Git LFS of
+// project, which overrides detection by ".gitattributes".
+const lfsAnnotation = "lfs"
+
+// reLFSFilter matches attributes which use the LFS filter.
+var reLFSFilter = regexp.MustCompile(`(?m)^[^#\n]*\sfilter=lfs(\s|$)`)
+
+// LFSAvailable indicates whether git-lfs is installed.
+func LFSAvailable() bool {
+	_, err := helper.RunCommand(&helper.Command{
+		Args: []string{config.GIT, "lfs", "version"},
+	})
+	return err == nil
+}
+
+// UsesLFS indicates whether project uses Git LFS, which is set by
+// annotation "lfs" of project, or detected by "filter=lfs" in
+// ".gitattributes" of worktree.
+func (v Project) UsesLFS() bool {
+	if _, ok := v.GetAnnotation(lfsAnnotation); ok {
+		return v.AnnotationBool(lfsAnnotation, false)
+	}
+	if v.WorkDir == "" {
+		return false
+	}
+	data, err := ioutil.ReadFile(filepath.Join(v.WorkDir, ".gitattributes"))
+	if err != nil {
+		return false
+	}
+	return reLFSFilter.Match(data)
+}
+
+// LFSObjectsSize returns disk usage of LFS objects of project.
+func (v Project) LFSObjectsSize() int64 {
+	var size int64
+
+	filepath.Walk(filepath.Join(v.RepoDir(), "lfs", "objects"), func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// UsesLFSAt indicates whether project uses Git LFS at revision, which is
+// set by annotation "lfs" of project, or detected by "filter=lfs" in
+// ".gitattributes" of revision. Worktree of project may not be checked
+// out yet, so it is used before checkout.
+func (v Project) UsesLFSAt(revision string) bool {
+	if _, ok := v.GetAnnotation(lfsAnnotation); ok {
+		return v.AnnotationBool(lfsAnnotation, false)
+	}
+	out, err := helper.RunCommand(&helper.Command{
+		Args: []string{config.GIT, "cat-file", "blob", revision + ":.gitattributes"},
+		Dir:  v.RepoDir(),
+	})
+	if err != nil {
+		return false
+	}
+	return reLFSFilter.Match(out)
+}
+
+// LFSFetch downloads LFS objects of revision without checkout, so that
+// they can be checked out later without network. Connections to the same
+// host are limited, and fetch is retried by policy of o.
+func (v Project) LFSFetch(revision string, o *FetchOptions) error {
+	cmdArgs := []string{config.GIT, "lfs", "fetch", v.RemoteName, revision}
+	log.Debugf("%sfetching LFS objects using command: %s", v.Prompt(), strings.Join(cmdArgs, " "))
+
+	auth, err := helper.GetHTTPAuth(v.RemoteURL)
+	if err != nil {
+		log.Warnf("%sfail to get credential: %s", v.Prompt(), err)
+	}
+	env := auth.GitEnv(v.RemoteURL)
+
+	err = executeNetworkCommandIn(v.RepoDir(), v.RemoteURL, env, cmdArgs, o.retryPolicy())
+	if err != nil {
+		return fmt.Errorf("fail to fetch LFS objects of project '%s': %s", v.Name, err)
+	}
+	return nil
+}
+
+// LFSPull sets up Git LFS in local config of project, and checks out LFS
+// objects of the current checkout. LFS objects are downloaded if they are
+// missing, unless localOnly is set.
+func (v Project) LFSPull(localOnly bool) error {
+	action := "pull"
+	if localOnly {
+		action = "checkout"
+	}
+	for _, args := range [][]string{
+		{config.GIT, "lfs", "install", "--local"},
+		{config.GIT, "lfs", action},
+	} {
+		result := v.ExecuteCommand(args...)
+		if !result.Success() {
+			return fmt.Errorf("%sfail to run '%s': %s",
+				v.Prompt(),
+				strings.Join(args, " "),
+				strings.TrimSpace(result.Stderr()))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package project
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsesLFS(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-lfs-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	p := Project{}
+	p.WorkDir = tmpdir
+	assert.False(p.UsesLFS())
+
+	attributes := filepath.Join(tmpdir, ".gitattributes")
+	assert.Nil(ioutil.WriteFile(attributes, []byte("*.sh text eol=lf\n# *.bin filter=lfs\n"), 0644))
+	assert.False(p.UsesLFS())
+
+	assert.Nil(ioutil.WriteFile(attributes, []byte("*.sh text eol=lf\n*.bin filter=lfs diff=lfs merge=lfs -text\n"), 0644))
+	assert.True(p.UsesLFS())
+
+	p.Annotations = []manifest.Annotation{{Name: "lfs", Value: "false"}}
+	assert.False(p.UsesLFS())
+
+	p.WorkDir = filepath.Join(tmpdir, "not-exist")
+	p.Annotations = []manifest.Annotation{{Name: "lfs", Value: "true"}}
+	assert.True(p.UsesLFS())
+}
+
+func TestLFSPull(t *testing.T) {
+	assert := assert.New(t)
+
+	failed := false
+	mock := helper.MockExecutor{
+		Handler: func(c *helper.Command) ([]byte, error) {
+			if failed && c.Args[2] == "pull" {
+				return nil, errors.New("exit status 2")
+			}
+			return nil, nil
+		},
+	}
+	defer helper.SetExecutor(&mock)()
+
+	p := Project{}
+	p.Path = "app"
+	p.Settings = &RepoSettings{}
+	assert.Nil(p.LFSPull(false))
+	assert.Nil(p.LFSPull(true))
+	assert.Equal([]string{
+		"git lfs install --local",
+		"git lfs pull",
+		"git lfs install --local",
+		"git lfs checkout",
+	}, mock.CommandLines())
+
+	failed = true
+	assert.NotNil(p.LFSPull(false))
+}
+
+func TestLFSFetch(t *testing.T) {
+	assert := assert.New(t)
+
+	mock := helper.MockExecutor{
+		Handler: func(c *helper.Command) ([]byte, error) {
+			if c.Args[1] == "cat-file" {
+				return []byte("*.bin filter=lfs diff=lfs merge=lfs -text\n"), nil
+			}
+			return nil, nil
+		},
+	}
+	defer helper.SetExecutor(&mock)()
+
+	p := Project{}
+	p.Path = "app"
+	p.RemoteName = "origin"
+	p.RemoteURL = "/path/of/app.git"
+	p.Settings = &RepoSettings{}
+	assert.True(p.UsesLFSAt("1234567"))
+	assert.Nil(p.LFSFetch("1234567", &FetchOptions{}))
+	assert.Equal([]string{
+		"git cat-file blob 1234567:.gitattributes",
+		"git lfs fetch origin 1234567",
+	}, mock.CommandLines())
+}
@@ -0,0 +1,85 @@
+package project
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+)
+
+// reLicenseFile matches names of license and notice files, such as
+// "LICENSE", "COPYING.md", "NOTICE" and "MODULE_LICENSE_APACHE2".
+var reLicenseFile = regexp.MustCompile(`(?i)^(licen[cs]e|copying|notice|module_license_)`)
+
+// LicenseFile is a license or notice file of project.
+type LicenseFile struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// IsLicenseFile indicates whether file is a license or notice file.
+func IsLicenseFile(name string) bool {
+	return reLicenseFile.MatchString(name)
+}
+
+// licenseDir is a directory of license files, such as "LICENSES/MIT.txt".
+const licenseDir = "LICENSES"
+
+// listBlobs returns names of files (not trees or submodules) in path of
+// revision, with path prefixed.
+func (v Project) listBlobs(revision, path string) ([]string, error) {
+	args := []string{config.GIT, "ls-tree", "-z", revision}
+	if path != "" {
+		args = append(args, path+"/")
+	}
+	result := v.ExecuteCommand(args...)
+	if !result.Success() {
+		return nil, fmt.Errorf("%sfail to list files of %s: %s",
+			v.Prompt(),
+			revision,
+			strings.TrimSpace(result.Stderr()))
+	}
+	names := []string{}
+	for _, entry := range strings.Split(result.Stdout(), "\x00") {
+		// Entry is "<mode> SP <type> SP <object> TAB <file>".
+		tab := strings.Index(entry, "\t")
+		if tab < 0 {
+			continue
+		}
+		fields := strings.Fields(entry[:tab])
+		if len(fields) != 3 || fields[1] != "blob" {
+			continue
+		}
+		names = append(names, entry[tab+1:])
+	}
+	return names, nil
+}
+
+// LicenseFiles returns license and notice files in the top directory of
+// project at revision, and files in the "LICENSES" directory.
+func (v Project) LicenseFiles(revision string) ([]LicenseFile, error) {
+	files := []LicenseFile{}
+	names, err := v.listBlobs(revision, "")
+	if err != nil {
+		return nil, err
+	}
+	licenses, err := v.listBlobs(revision, licenseDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range append(names, licenses...) {
+		if !IsLicenseFile(name) && !strings.HasPrefix(name, licenseDir+"/") {
+			continue
+		}
+		result := v.ExecuteCommand(config.GIT, "cat-file", "blob", revision+":"+name)
+		if !result.Success() {
+			return nil, fmt.Errorf("%sfail to read '%s': %s",
+				v.Prompt(),
+				name,
+				strings.TrimSpace(result.Stderr()))
+		}
+		files = append(files, LicenseFile{Name: name, Content: result.Stdout()})
+	}
+	return files, nil
+}
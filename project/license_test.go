@@ -0,0 +1,63 @@
+package project
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsLicenseFile(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, name := range []string{"LICENSE", "License.md", "LICENCE", "COPYING", "NOTICE.txt", "MODULE_LICENSE_APACHE2"} {
+		assert.True(IsLicenseFile(name), name)
+	}
+	for _, name := range []string{"README.md", "main.go", "MY_LICENSE"} {
+		assert.False(IsLicenseFile(name), name)
+	}
+}
+
+func TestLicenseFiles(t *testing.T) {
+	assert := assert.New(t)
+
+	mock := helper.MockExecutor{
+		Handler: func(c *helper.Command) ([]byte, error) {
+			switch c.Args[1] {
+			case "ls-tree":
+				if len(c.Args) > 4 && c.Args[4] == "LICENSES/" {
+					return []byte("100644 blob 1111111111111111111111111111111111111111\tLICENSES/MIT.txt\x00"), nil
+				}
+				return []byte("100644 blob 1111111111111111111111111111111111111111\tLICENSE\x00" +
+					"100644 blob 2222222222222222222222222222222222222222\tNOTICE\x00" +
+					"100644 blob 3333333333333333333333333333333333333333\tREADME.md\x00" +
+					"040000 tree 4444444444444444444444444444444444444444\tLICENSES\x00" +
+					"040000 tree 5555555555555555555555555555555555555555\tnotices\x00" +
+					"160000 commit 6666666666666666666666666666666666666666\tlicense-tools\x00"), nil
+			case "cat-file":
+				switch c.Args[3] {
+				case "HEAD:LICENSE":
+					return []byte("Apache License 2.0\n"), nil
+				case "HEAD:NOTICE":
+					return []byte("Copyright Alibaba\n"), nil
+				case "HEAD:LICENSES/MIT.txt":
+					return []byte("MIT License\n"), nil
+				}
+			}
+			return nil, errors.New("unknown command")
+		},
+	}
+	defer helper.SetExecutor(&mock)()
+
+	p := Project{}
+	p.Name = "platform/app"
+	p.Settings = &RepoSettings{}
+	files, err := p.LicenseFiles("HEAD")
+	assert.Nil(err)
+	assert.Equal([]LicenseFile{
+		{Name: "LICENSE", Content: "Apache License 2.0\n"},
+		{Name: "NOTICE", Content: "Copyright Alibaba\n"},
+		{Name: "LICENSES/MIT.txt", Content: "MIT License\n"},
+	}, files)
+}
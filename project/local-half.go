@@ -1,12 +1,10 @@
 package project
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
@@ -15,8 +13,8 @@ import (
 	"github.com/alibaba/git-repo-go/config"
 	"github.com/alibaba/git-repo-go/file"
 	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/path"
-	log "github.com/jiangxin/multi-log"
 )
 
 // CheckoutOptions is options for git fetch.
@@ -25,7 +23,10 @@ type CheckoutOptions struct {
 
 	Quiet      bool
 	DetachHead bool
+	AutoStash  bool
 	IsManifest bool
+	// Jobs is number of jobs to update submodules.
+	Jobs int
 }
 
 // IsClean indicates git worktree is clean.
@@ -43,32 +44,18 @@ func IsClean(dir string) (bool, error) {
 		"--",
 	}
 
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
-	cmd.Dir = dir
-	cmd.Stdin = nil
-	cmd.Stderr = nil
-	out, err := cmd.StdoutPipe()
+	out, err := helper.RunCommand(&helper.Command{
+		Args: cmdArgs,
+		Dir:  dir,
+	})
 	if err != nil {
 		return false, err
 	}
-	if err = cmd.Start(); err != nil {
-		return false, err
-	}
-
-	r := bufio.NewReader(out)
-	for {
-		line, err := r.ReadString('\n')
+	for _, line := range strings.Split(string(out), "\n") {
 		line = strings.TrimSpace(line)
 		if len(line) > 0 {
 			status = append(status, line)
 		}
-		if err != nil {
-			break
-		}
-	}
-
-	if err = cmd.Wait(); err != nil {
-		return false, err
 	}
 
 	if len(status) == 0 {
@@ -81,6 +68,9 @@ func IsClean(dir string) (bool, error) {
 // IsClean indicates git worktree is clean.
 // TODO: cannot use go-git, because it is incompatible with git new index format.
 func (v Project) IsClean() bool {
+	if v.isTrackedClean() {
+		return true
+	}
 	ok, err := IsClean(v.WorkDir)
 	if err != nil {
 		log.Warnf("%sfail to run IsClean: %s", v.Prompt(), err)
@@ -137,6 +127,17 @@ func (v Project) FastForward(args ...string) error {
 	return executeCommandIn(v.WorkDir, cmdArgs)
 }
 
+// Stash runs git stash to save local changes of worktree.
+func (v Project) Stash(args ...string) error {
+	cmdArgs := []string{
+		GIT,
+		"stash",
+	}
+	cmdArgs = append(cmdArgs, args...)
+	log.Debugf("%sstashing using command: %s", v.Prompt(), strings.Join(cmdArgs, " "))
+	return executeCommandIn(v.WorkDir, cmdArgs)
+}
+
 // SubmoduleUpdate runs git submodule update.
 func (v Project) SubmoduleUpdate(args ...string) error {
 	cmdArgs := []string{
@@ -193,6 +194,13 @@ func (v Project) SyncLocalHalf(o *CheckoutOptions) error {
 		}
 	}
 
+	// Set up sparse checkout before the first checkout.
+	if headid == "" {
+		if err = v.initSparseCheckout(); err != nil {
+			return err
+		}
+	}
+
 	// We have a branch, check whether tracking branch is set properly.
 	if branch != "" {
 		track = v.TrackBranch(branch)
@@ -223,17 +231,24 @@ func (v Project) SyncLocalHalf(o *CheckoutOptions) error {
 			v.UpdateBranchTracking(branch, v.RemoteName, defaultTrack)
 		}
 
-		if update && o.Submodules {
-			err = v.SubmoduleUpdate()
+		if o.Submodules || v.SyncSBool() {
+			err = v.syncSubmodules(update, o)
 			if err != nil {
 				return err
 			}
 		}
 
+		// Hooks are not necessary for checkout, so only warn on errors.
+		if err = v.InstallHooks(); err != nil {
+			log.Warnf("%sfail to install hooks: %s", v.Prompt(), err)
+		}
+
 		// Install gerrit hooks
 		if remote != nil {
 			if remote.GetType() == helper.ProtoTypeGerrit {
-				v.InstallGerritHooks()
+				if err = v.InstallGerritHooks(); err != nil {
+					log.Warnf("%sfail to install gerrit hooks: %s", v.Prompt(), err)
+				}
 			}
 
 			// Disable default push, push command must have specific refspec
@@ -292,6 +307,12 @@ func (v Project) SyncLocalHalf(o *CheckoutOptions) error {
 		return PostUpdate(true)
 	}
 
+	// Local commits and remote changes are counted from merge base, which
+	// may be missing in a shallow clone.
+	if err = v.EnsureMergeBase(headid, revid); err != nil {
+		return err
+	}
+
 	log.Debugf("%schecking rev-list: %s..%s", v.Prompt(), headid, revid)
 	remoteChanges, err := v.Revlist(revid, "--not", headid)
 	if err != nil {
@@ -344,9 +365,18 @@ func (v Project) SyncLocalHalf(o *CheckoutOptions) error {
 		}
 	}
 
-	// Failed if worktree is dirty.
+	// Failed if worktree is dirty, unless local changes can be stashed.
+	stashed := false
 	if !v.IsClean() {
-		return fmt.Errorf("worktree of %s is dirty, checkout failed", v.Name)
+		if !o.AutoStash || o.IsManifest {
+			return fmt.Errorf("worktree of %s is dirty, checkout failed", v.Name)
+		}
+		log.Notef("%sstashing local changes of branch %s", v.Prompt(), branch)
+		err = v.Stash("push", "-q", "-m", "git-repo sync autostash")
+		if err != nil {
+			return fmt.Errorf("fail to stash local changes of %s: %s", v.Name, err)
+		}
+		stashed = true
 	}
 
 	// For ManifestProject, use `reset --hard` to switch branch,
@@ -364,24 +394,58 @@ func (v Project) SyncLocalHalf(o *CheckoutOptions) error {
 		return PostUpdate(false)
 	}
 
-	// Default action if not turn off by rebase attribute of project in manifest file.
-	if v.IsRebase() {
+	// Fast-forward if there are no local commits, otherwise rebase local
+	// commits onto the new revision, unless turned off by rebase attribute
+	// of project in manifest file.
+	localChanges, err := v.Revlist(headid, "--not", revid)
+	if err != nil {
+		log.Warnf("%srev-list failed: %s", v.Prompt(), err)
+	}
+	if len(localChanges) == 0 {
+		err = v.FastForward("--ff-only", revid)
+	} else if v.IsRebase() {
+		log.Notef("%srebasing %d local commit(s) of %s onto %s",
+			v.Prompt(),
+			len(localChanges),
+			branch,
+			v.Revision)
 		err = v.Rebase(revid)
-		if err != nil {
-			return err
-		}
 	} else {
 		err = v.FastForward(revid)
+	}
+	if err != nil {
+		if stashed {
+			log.Warnf("%slocal changes are kept in stash, run `git stash pop` to restore them",
+				v.Prompt())
+		}
+		return err
+	}
+
+	if stashed {
+		err = v.Stash("pop", "-q")
 		if err != nil {
-			return err
+			return fmt.Errorf("fail to restore stashed changes of %s, resolve conflicts "+
+				"and run `git stash drop`: %s", v.Name, err)
 		}
 	}
 
 	return PostUpdate(true)
 }
 
+// InstallHooks installs default hooks, which are not specific for Gerrit.
+func (v Project) InstallHooks() error {
+	return v.linkHooks(config.DefaultHooks)
+}
+
 // InstallGerritHooks installs gerrit hooks if remote of current project is gerrit.
 func (v Project) InstallGerritHooks() error {
+	return v.linkHooks(config.GerritHooks)
+}
+
+// linkHooks creates symlinks in hooks dir of the project, which point to
+// the hook templates in ~/.git-repo/hooks. Hooks are updated in all
+// projects once the templates are updated.
+func (v Project) linkHooks(hooks map[string]string) error {
 	hooksDir, err := config.GetRepoHooksDir()
 	if err != nil {
 		return err
@@ -396,8 +460,8 @@ func (v Project) InstallGerritHooks() error {
 	if p, err := filepath.EvalSymlinks(localHooksDir); err == nil {
 		localHooksDir = p
 	}
-	log.Debugf("installing gerrit hooks for %s", v.Path)
-	for name := range config.GerritHooks {
+	log.Debugf("installing hooks for %s", v.Path)
+	for name := range hooks {
 		src := filepath.Join(hooksDir, name)
 		target := filepath.Join(localHooksDir, name)
 		srcRel, err := filepath.Rel(localHooksDir, src)
@@ -0,0 +1,47 @@
+package project
+
+import (
+	"net/url"
+	"path/filepath"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/path"
+)
+
+// LockFile returns file of project lock in .repo/locks, or empty string
+// if project is not in a repo workspace.
+func (v Project) LockFile() string {
+	if v.Settings == nil || v.TopDir() == "" {
+		return ""
+	}
+	adminDir := filepath.Join(v.TopDir(), config.DotRepo)
+	if !path.IsDir(adminDir) {
+		return ""
+	}
+	name := v.Path
+	if name == "" {
+		name = v.Name
+	}
+	return filepath.Join(adminDir, config.Locks, url.PathEscape(name)+".lock")
+}
+
+// Lock acquires lock of project, so that commands which work on
+// different projects can run simultaneously. Returns a function to
+// release the lock.
+func (v Project) Lock() (func(), error) {
+	file := v.LockFile()
+	if file == "" {
+		return func() {}, nil
+	}
+	lock := helper.NewLockFile(file)
+	if err := lock.Lock(config.WaitLock()); err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := lock.Unlock(); err != nil {
+			log.Warnf("%sfail to unlock: %s", v.Prompt(), err)
+		}
+	}, nil
+}
@@ -1,25 +1,92 @@
 package project
 
 import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/file"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/manifest"
 	"github.com/jiangxin/goconfig"
 )
 
-// RepoSettings holds settings in manifest project.
+// RepoSettings holds settings of the workspace, which are saved in
+// .repo/config.json, and in config of manifest project for compatibility.
 type RepoSettings struct {
-	TopDir       string
-	ManifestURL  string
-	ManifestName string
-	Groups       string
-	Reference    string
-	Revision     string
-	Depth        int
-	Archive      bool
-	Dissociate   bool
-	Mirror       bool
-	Submodules   bool
-	Config       goconfig.GitConfig
+	TopDir             string             `json:"-"`
+	ManifestURL        string             `json:"-"`
+	ManifestName       string             `json:"manifest_name,omitempty"`
+	Groups             string             `json:"groups,omitempty"`
+	Platform           string             `json:"platform,omitempty"`
+	Reference          string             `json:"reference,omitempty"`
+	Revision           string             `json:"-"`
+	Depth              int                `json:"depth,omitempty"`
+	PartialCloneFilter string             `json:"partial_clone_filter,omitempty"`
+	Jobs               int                `json:"jobs,omitempty"`
+	FetchWindow        string             `json:"fetch_window,omitempty"`
+	GC                 string             `json:"gc,omitempty"`
+	CommitTemplate     string             `json:"commit_template,omitempty"`
+	ManifestVerify     bool               `json:"manifest_verify,omitempty"`
+	ManifestKeys       string             `json:"manifest_keys,omitempty"`
+	ManifestSigners    string             `json:"manifest_signers,omitempty"`
+	Archive            bool               `json:"archive,omitempty"`
+	Dissociate         bool               `json:"dissociate,omitempty"`
+	Mirror             bool               `json:"mirror,omitempty"`
+	Submodules         bool               `json:"submodules,omitempty"`
+	Worktree           bool               `json:"worktree,omitempty"`
+	Config             goconfig.GitConfig `json:"-"`
+}
+
+// SettingsFile returns path of the settings file of the workspace.
+func (v RepoSettings) SettingsFile() string {
+	return filepath.Join(v.TopDir, config.DotRepo, config.SettingsFile)
+}
+
+// load reads settings from settings file, and returns false if settings
+// file does not exist.
+func (v *RepoSettings) load() (bool, error) {
+	data, err := ioutil.ReadFile(v.SettingsFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	loaded := RepoSettings{
+		TopDir:      v.TopDir,
+		ManifestURL: v.ManifestURL,
+		Revision:    v.Revision,
+		Config:      v.Config,
+	}
+	if err = json.Unmarshal(data, &loaded); err != nil {
+		return false, fmt.Errorf("bad settings file '%s': %s", v.SettingsFile(), err)
+	}
+	*v = loaded
+	return true, nil
+}
+
+// save writes settings to settings file.
+func (v RepoSettings) save() error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := file.New(v.SettingsFile()).OpenCreateRewrite()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
 }
 
 // ManifestProject is a special type of project.
@@ -27,30 +94,84 @@ type ManifestProject struct {
 	Project
 }
 
-// ReadSettings reads settings from manifest project.
+// ReadSettings reads settings from settings file of the workspace. Fall
+// back to config of manifest project if settings file does not exist.
 func (v *ManifestProject) ReadSettings() *RepoSettings {
 	cfg := v.Config()
 
 	s := v.Settings
 	s.ManifestURL = cfg.Get(config.CfgRemoteOriginURL)
+	s.Config = cfg
+	if ok, err := s.load(); ok {
+		s.readTunables(cfg)
+		return s
+	} else if err != nil {
+		log.Warn(err)
+	}
+
 	s.ManifestName = cfg.Get(config.CfgManifestName)
 	s.Groups = cfg.Get(config.CfgManifestGroups)
 	s.Reference = cfg.Get(config.CfgRepoReference)
 	s.Depth = cfg.GetInt(config.CfgRepoDepth, 0)
+	s.PartialCloneFilter = cfg.Get(config.CfgRepoPartialCloneFilter)
 	s.Archive = cfg.GetBool(config.CfgRepoArchive, false)
 	s.Dissociate = cfg.GetBool(config.CfgRepoDissociate, false)
 	s.Mirror = cfg.GetBool(config.CfgRepoMirror, false)
 	s.Submodules = cfg.GetBool(config.CfgRepoSubmodules, false)
-	s.Config = v.Config()
+	s.Worktree = cfg.GetBool(config.CfgRepoWorktree, false)
+	s.readTunables(cfg)
 
 	return s
 }
 
-// SaveSettings saves settings to manifest project.
+// readTunables reads settings which are tuned by git config of the
+// workspace, such as "git -C .repo/manifests config repo.jobs 8". Git
+// config overrides the settings file if set. Trust of manifests falls
+// back to global git config, so that the manifest project can be
+// verified by the first "repo init".
+func (v *RepoSettings) readTunables(cfg goconfig.GitConfig) {
+	if cfg.HasKey(config.CfgRepoJobs) {
+		v.Jobs = cfg.GetInt(config.CfgRepoJobs, 0)
+	}
+	if cfg.HasKey(config.CfgRepoFetchWindow) {
+		v.FetchWindow = cfg.Get(config.CfgRepoFetchWindow)
+	}
+	if cfg.HasKey(config.CfgRepoGC) {
+		v.GC = cfg.Get(config.CfgRepoGC)
+	}
+	if cfg.HasKey(config.CfgRepoCommitTemplate) {
+		v.CommitTemplate = cfg.Get(config.CfgRepoCommitTemplate)
+	}
+
+	trustConfig := func(key string) (goconfig.GitConfig, bool) {
+		if cfg.HasKey(key) {
+			return cfg, true
+		}
+		if config.GitDefaultConfig != nil && config.GitDefaultConfig.HasKey(key) {
+			return config.GitDefaultConfig, true
+		}
+		return nil, false
+	}
+	if c, ok := trustConfig(config.CfgRepoManifestVerify); ok {
+		v.ManifestVerify = c.GetBool(config.CfgRepoManifestVerify, false)
+	}
+	if c, ok := trustConfig(config.CfgRepoManifestKeys); ok {
+		v.ManifestKeys = c.Get(config.CfgRepoManifestKeys)
+	}
+	if c, ok := trustConfig(config.CfgRepoManifestSigners); ok {
+		v.ManifestSigners = c.Get(config.CfgRepoManifestSigners)
+	}
+}
+
+// SaveSettings saves settings to settings file of the workspace, and to
+// config of manifest project.
 func (v *ManifestProject) SaveSettings(s *RepoSettings) error {
 	cfg := v.Config()
 
 	v.Settings = s
+	if err := s.save(); err != nil {
+		return err
+	}
 
 	if s.ManifestURL != "" {
 		cfg.Set(config.CfgRemoteOriginURL, s.ManifestURL)
@@ -78,6 +199,54 @@ func (v *ManifestProject) SaveSettings(s *RepoSettings) error {
 		cfg.Unset(config.CfgRepoDepth)
 	}
 
+	if s.PartialCloneFilter != "" {
+		cfg.Set(config.CfgRepoPartialCloneFilter, s.PartialCloneFilter)
+	} else {
+		cfg.Unset(config.CfgRepoPartialCloneFilter)
+	}
+
+	if s.Jobs > 0 {
+		cfg.Set(config.CfgRepoJobs, s.Jobs)
+	} else {
+		cfg.Unset(config.CfgRepoJobs)
+	}
+
+	if s.FetchWindow != "" {
+		cfg.Set(config.CfgRepoFetchWindow, s.FetchWindow)
+	} else {
+		cfg.Unset(config.CfgRepoFetchWindow)
+	}
+
+	if s.GC != "" {
+		cfg.Set(config.CfgRepoGC, s.GC)
+	} else {
+		cfg.Unset(config.CfgRepoGC)
+	}
+
+	if s.CommitTemplate != "" {
+		cfg.Set(config.CfgRepoCommitTemplate, s.CommitTemplate)
+	} else {
+		cfg.Unset(config.CfgRepoCommitTemplate)
+	}
+
+	if s.ManifestVerify {
+		cfg.Set(config.CfgRepoManifestVerify, true)
+	} else {
+		cfg.Unset(config.CfgRepoManifestVerify)
+	}
+
+	if s.ManifestKeys != "" {
+		cfg.Set(config.CfgRepoManifestKeys, s.ManifestKeys)
+	} else {
+		cfg.Unset(config.CfgRepoManifestKeys)
+	}
+
+	if s.ManifestSigners != "" {
+		cfg.Set(config.CfgRepoManifestSigners, s.ManifestSigners)
+	} else {
+		cfg.Unset(config.CfgRepoManifestSigners)
+	}
+
 	// Only initialized for the first time, cannot unset
 	if s.Archive {
 		cfg.Set(config.CfgRepoArchive, true)
@@ -100,27 +269,37 @@ func (v *ManifestProject) SaveSettings(s *RepoSettings) error {
 		cfg.Unset(config.CfgRepoSubmodules)
 	}
 
+	// Cannot switch back to legacy layout once enabled
+	if s.Worktree {
+		cfg.Set(config.CfgRepoWorktree, true)
+	}
+
 	return v.SaveConfig(cfg)
 }
 
-// MirrorEnabled checks if config variable repo.mirror is true.
+// MirrorEnabled checks if mirror is enabled in settings.
 func (v ManifestProject) MirrorEnabled() bool {
-	return v.Config().GetBool(config.CfgRepoMirror, false)
+	return v.Settings.Mirror
 }
 
-// SubmoduleEnabled checks if config variable repo.submodules is true.
+// SubmoduleEnabled checks if submodules is enabled in settings.
 func (v ManifestProject) SubmoduleEnabled() bool {
-	return v.Config().GetBool(config.CfgRepoSubmodules, false)
+	return v.Settings.Submodules
+}
+
+// WorktreeEnabled checks if projects are checked out by git worktree.
+func (v ManifestProject) WorktreeEnabled() bool {
+	return v.Settings.Worktree
 }
 
-// ArchiveEnabled checks if config variable repo.archive is true.
+// ArchiveEnabled checks if archive is enabled in settings.
 func (v ManifestProject) ArchiveEnabled() bool {
-	return v.Config().GetBool(config.CfgRepoArchive, false)
+	return v.Settings.Archive
 }
 
-// DissociateEnabled checks if config variable repo.dissociate is true.
+// DissociateEnabled checks if dissociate is enabled in settings.
 func (v ManifestProject) DissociateEnabled() bool {
-	return v.Config().GetBool(config.CfgRepoDissociate, false)
+	return v.Settings.Dissociate
 }
 
 // SetRevision changes project default branch.
@@ -148,3 +327,86 @@ func NewManifestProject(topDir, mURL string) *ManifestProject {
 	}
 	return &p
 }
+
+// LoadManifestAt loads manifest from the tracking branch of revision,
+// without checking out the revision. Local manifests of the workspace
+// are also merged.
+func (v ManifestProject) LoadManifestAt(revision string) (*manifest.Manifest, error) {
+	commit, err := v.ResolveRemoteTracking(revision)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := ioutil.TempDir("", "git-repo-manifests-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err = v.ExtractAt(commit, tmpDir); err != nil {
+		return nil, fmt.Errorf("fail to archive manifests of %s: %s", revision, err)
+	}
+
+	name := v.ManifestName()
+	m, err := manifest.LoadFile(filepath.Join(v.TopDir(), config.DotRepo),
+		filepath.Join(tmpDir, name))
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, fmt.Errorf("cannot find %s in manifests of %s", name, revision)
+	}
+	return m, nil
+}
+
+// ManifestName returns name of manifest file in manifests project.
+func (v ManifestProject) ManifestName() string {
+	if v.Settings.ManifestName != "" {
+		return v.Settings.ManifestName
+	}
+	return config.DefaultXML
+}
+
+// ExtractAt extracts files of manifests project at commit into dir,
+// without checking out the commit.
+func (v ManifestProject) ExtractAt(commit, dir string) error {
+	out, err := helper.RunCommand(&helper.Command{
+		Args: []string{config.GIT, "archive", "--format=tar", commit},
+		Dir:  v.RepoDir(),
+	})
+	if err != nil {
+		return err
+	}
+	return extractTar(bytes.NewReader(out), dir)
+}
+
+// extractTar extracts regular files from tar stream into dir.
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg ||
+			strings.HasPrefix(filepath.Clean(hdr.Name), "..") {
+			continue
+		}
+		name := filepath.Join(dir, hdr.Name)
+		if err = os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return err
+		}
+		f, err := file.New(name).OpenCreateRewrite()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, tr)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/jiangxin/goconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepoSettingsSaveLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	s := RepoSettings{TopDir: tmpdir}
+	ok, err := s.load()
+	assert.Nil(err)
+	assert.False(ok)
+
+	s = RepoSettings{
+		TopDir:             tmpdir,
+		ManifestURL:        "https://example.com/manifests.git",
+		ManifestName:       "default.xml",
+		Groups:             "default,platform-linux",
+		Platform:           "linux",
+		Depth:              1,
+		PartialCloneFilter: "blob:none",
+		Jobs:               8,
+		FetchWindow:        "22:00-06:00",
+		ManifestVerify:     true,
+		Mirror:             true,
+	}
+	assert.Nil(os.MkdirAll(filepath.Join(tmpdir, ".repo"), 0755))
+	assert.Nil(s.save())
+
+	loaded := RepoSettings{
+		TopDir:      tmpdir,
+		ManifestURL: "https://example.com/manifests.git",
+		Groups:      "stale",
+		Dissociate:  true,
+	}
+	ok, err = loaded.load()
+	assert.Nil(err)
+	assert.True(ok)
+	assert.Equal(s, loaded)
+}
+
+func TestRepoSettingsReadTunables(t *testing.T) {
+	assert := assert.New(t)
+
+	s := RepoSettings{
+		Jobs:           8,
+		GC:             "never",
+		CommitTemplate: "commit.txt",
+	}
+	cfg := goconfig.NewGitConfig()
+	cfg.Set(config.CfgRepoJobs, 2)
+	cfg.Set(config.CfgRepoFetchWindow, "22:00-06:00")
+	cfg.Set(config.CfgRepoManifestKeys, "0x5a3d5f2e1b1c0d9e")
+	s.readTunables(cfg)
+	assert.Equal(2, s.Jobs)
+	assert.Equal("22:00-06:00", s.FetchWindow)
+	assert.Equal("never", s.GC)
+	assert.Equal("commit.txt", s.CommitTemplate)
+	assert.Equal("0x5a3d5f2e1b1c0d9e", s.ManifestKeys)
+	assert.False(s.ManifestVerify)
+
+	// Trust of manifests falls back to global git config.
+	config.GitDefaultConfig.Set(config.CfgRepoManifestVerify, "true")
+	defer config.GitDefaultConfig.Unset(config.CfgRepoManifestVerify)
+	s.readTunables(cfg)
+	assert.True(s.ManifestVerify)
+}
@@ -0,0 +1,192 @@
+package project
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+)
+
+const (
+	nestedSectionBegin = "# BEGIN nested projects, managed by git-repo"
+	nestedSectionEnd   = "# END nested projects"
+)
+
+// gitPath returns path of file inside git dir of the worktree.
+func (v Project) gitPath(name string) (string, error) {
+	result := v.ExecuteCommand(config.GIT, "rev-parse", "--git-path", name)
+	if !result.Success() {
+		return "", fmt.Errorf("%sfail to find git path %s: %s",
+			v.Prompt(), name, strings.TrimSpace(result.Stderr()))
+	}
+	file := strings.TrimSpace(result.Stdout())
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(v.WorkDir, file)
+	}
+	return file, nil
+}
+
+// updateNestedSection replaces section of nested projects in file with
+// lines. The section is removed if lines is empty, and returns whether
+// file is changed.
+func updateNestedSection(file string, lines []string) (bool, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	kept := []string{}
+	inSection := false
+	if len(data) > 0 {
+		for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+			if line == nestedSectionBegin {
+				inSection = true
+				continue
+			} else if line == nestedSectionEnd {
+				inSection = false
+				continue
+			}
+			if !inSection {
+				kept = append(kept, line)
+			}
+		}
+	}
+	if len(lines) > 0 {
+		kept = append(kept, nestedSectionBegin)
+		kept = append(kept, lines...)
+		kept = append(kept, nestedSectionEnd)
+	}
+
+	content := ""
+	if len(kept) > 0 {
+		content = strings.Join(kept, "\n") + "\n"
+	}
+	if content == string(data) {
+		return false, nil
+	}
+	if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return false, err
+	}
+	return true, ioutil.WriteFile(file, []byte(content), 0644)
+}
+
+// ExcludeNestedProjects adds paths of nested projects, which are relative
+// to the worktree of the project, to "info/exclude", so the outer project
+// will not see files of the inner projects. If files of the outer project
+// are tracked in these paths, sparse checkout is enabled to leave them
+// out, so the two projects won't fight over files.
+func (v Project) ExcludeNestedProjects(paths []string) error {
+	if v.IsMirror() || !v.Exists() {
+		return nil
+	}
+
+	excludes := []string{}
+	for _, p := range paths {
+		excludes = append(excludes, "/"+p+"/")
+	}
+	excludeFile, err := v.gitPath("info/exclude")
+	if err != nil {
+		return err
+	}
+	if _, err = updateNestedSection(excludeFile, excludes); err != nil {
+		return fmt.Errorf("%sfail to update %s: %s", v.Prompt(), excludeFile, err)
+	}
+
+	sparse := []string{}
+	tracked := []string{}
+	for _, p := range paths {
+		result := v.ExecuteCommand(config.GIT, "ls-files", "--", p)
+		if !result.Success() {
+			continue
+		}
+		files := strings.Fields(result.Stdout())
+		if len(files) > 0 {
+			log.Warnf("%sfiles in '%s' are hidden by nested project",
+				v.Prompt(), p)
+			sparse = append(sparse, "!/"+p)
+			tracked = append(tracked, files...)
+		}
+	}
+	if len(sparse) > 0 {
+		sparse = append([]string{"/*"}, sparse...)
+	}
+	sparseFile, err := v.gitPath("info/sparse-checkout")
+	if err != nil {
+		return err
+	}
+	changed, err := updateNestedSection(sparseFile, sparse)
+	if err != nil {
+		return fmt.Errorf("%sfail to update %s: %s", v.Prompt(), sparseFile, err)
+	}
+	if len(sparse) > 0 {
+		return v.enableSparseCheckout(tracked)
+	} else if changed {
+		return v.disableSparseCheckout(sparseFile)
+	}
+	return nil
+}
+
+func (v Project) applySparseCheckout() error {
+	v.ExecuteCommand(config.GIT, "update-index", "-q", "--refresh")
+	result := v.ExecuteCommand(config.GIT, "read-tree", "-mu", "HEAD")
+	if !result.Success() {
+		return fmt.Errorf("%sfail to apply sparse checkout: %s",
+			v.Prompt(), strings.TrimSpace(result.Stderr()))
+	}
+	return nil
+}
+
+// enableSparseCheckout turns on sparse checkout, and marks tracked files
+// as skip-worktree, even if they cannot be removed from worktree, because
+// the nested project is already there. Set sparse.expectFilesOutsideOfPatterns
+// so that git will not clear skip-worktree bits of these paths.
+func (v Project) enableSparseCheckout(tracked []string) error {
+	cfg := v.Config()
+	if !cfg.GetBool("core.sparseCheckout", false) {
+		cfg.Set("core.sparseCheckout", true)
+		cfg.Set("sparse.expectFilesOutsideOfPatterns", true)
+		if err := v.SaveConfig(cfg); err != nil {
+			return err
+		}
+	}
+	if err := v.applySparseCheckout(); err != nil {
+		return err
+	}
+	args := []string{config.GIT, "update-index", "--skip-worktree", "--"}
+	result := v.ExecuteCommand(append(args, tracked...)...)
+	if !result.Success() {
+		return fmt.Errorf("%sfail to set skip-worktree: %s",
+			v.Prompt(), strings.TrimSpace(result.Stderr()))
+	}
+	return nil
+}
+
+// disableSparseCheckout checks out all files, and turns off sparse
+// checkout if no other patterns are left in sparseFile.
+func (v Project) disableSparseCheckout(sparseFile string) error {
+	data, err := ioutil.ReadFile(sparseFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(data) > 0 {
+		return v.applySparseCheckout()
+	}
+
+	if err = ioutil.WriteFile(sparseFile, []byte("/*\n"), 0644); err != nil {
+		return err
+	}
+	if err = v.applySparseCheckout(); err != nil {
+		return err
+	}
+	if err = os.Remove(sparseFile); err != nil {
+		return err
+	}
+	cfg := v.Config()
+	cfg.Unset("core.sparseCheckout")
+	cfg.Unset("sparse.expectFilesOutsideOfPatterns")
+	return v.SaveConfig(cfg)
+}
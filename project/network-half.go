@@ -6,10 +6,11 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/alibaba/git-repo-go/common"
+	"github.com/alibaba/git-repo-go/config"
 	"github.com/alibaba/git-repo-go/file"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/path"
-	log "github.com/jiangxin/multi-log"
 )
 
 // FetchOptions is options for git fetch.
@@ -21,9 +22,92 @@ type FetchOptions struct {
 	CurrentBranchOnly bool
 	CloneBundle       bool
 	ForceSync         bool
+	Tags              bool
 	NoTags            bool
 	OptimizedFetch    bool
 	Prune             bool
+	// RetryFetches is times to retry fetch if it fails because of
+	// network problems, and git config "repo.retry.maxAttempts" is
+	// used if it is 0.
+	RetryFetches int
+}
+
+// retryPolicy returns policy to retry fetch.
+func (o FetchOptions) retryPolicy() *helper.RetryPolicy {
+	policy := helper.NewRetryPolicy()
+	if o.RetryFetches > 0 {
+		policy.MaxAttempts = o.RetryFetches + 1
+	}
+	return policy
+}
+
+// IsFetchNeeded indicates whether repository should be fetched. For
+// option --optimized-fetch, repository fixed to a SHA which exists
+// locally is not fetched.
+func (v Repository) IsFetchNeeded(o *FetchOptions) bool {
+	if !o.OptimizedFetch || v.Revision == "" {
+		return true
+	}
+	kind := v.NewRevisionSpec(v.Revision).Kind
+	if (kind != RevisionSha && kind != RevisionShortSha) || !v.Exists() {
+		return true
+	}
+	_, err := v.peelCommit(v.Revision)
+	return err != nil
+}
+
+// TagsPolicy tells whether tags are fetched for the repository, and why.
+// Option --tags or --no-tags overrides the sync-tags attribute of project
+// in manifest, and tags are not fetched for a shallow clone unless --tags
+// is given.
+func (v Repository) TagsPolicy(o *FetchOptions) (bool, string) {
+	switch {
+	case o.NoTags:
+		return false, "--no-tags"
+	case o.Tags:
+		return true, "--tags"
+	case o.Depth > 0 && !o.Mirror:
+		return false, "shallow clone"
+	}
+	return v.SyncTagsBool(), "sync-tags"
+}
+
+// IsFetchTags indicates tags should be fetched for the repository.
+func (v Repository) IsFetchTags(o *FetchOptions) bool {
+	fetchTags, _ := v.TagsPolicy(o)
+	return fetchTags
+}
+
+// checkRemoteRef checks whether revision which looks like an abbreviated
+// SHA is a branch or a tag of remote, such as "20201012", and changes kind
+// of spec if it is.
+func (v Repository) checkRemoteRef(spec *RevisionSpec) {
+	branch := config.RefsHeads + spec.Revision
+	tag := config.RefsTags + spec.Revision
+
+	auth, err := helper.GetHTTPAuth(v.RemoteURL)
+	if err != nil {
+		log.Warnf("%sfail to get credential: %s", v.Prompt(), err)
+	}
+	release := helper.AcquireHost(v.RemoteURL)
+	defer release()
+	out, err := helper.RunCommand(&helper.Command{
+		Args: []string{GIT, "ls-remote", v.RemoteURL, branch, tag},
+		Dir:  v.RepoDir(),
+		Env:  auth.GitEnv(v.RemoteURL),
+	})
+	if err != nil {
+		log.Debugf("%sfail to list refs of remote: %s", v.Prompt(), err)
+		return
+	}
+	refs := make(map[string]bool)
+	for _, line := range strings.Split(string(out), "\n") {
+		items := strings.Fields(line)
+		if len(items) == 2 {
+			refs[items[1]] = true
+		}
+	}
+	spec.setRefKind(refs[branch], refs[tag])
 }
 
 // Fetch runs git-fetch on repository.
@@ -59,13 +143,18 @@ func (v *Repository) Fetch(remote string, o *FetchOptions) error {
 		hasAlternates = true
 	}
 
-	if o.CloneBundle && !hasAlternates {
-		v.applyCloneBundle()
-	}
-
 	if v.RemoteURL == "" {
 		return fmt.Errorf("don't know where to fetch repo %s from remote %s", v.Name, remote)
 	}
+	if err = CheckRevision(v.Revision); err != nil {
+		return fmt.Errorf("project '%s': %s", v.Name, err)
+	}
+
+	if o.CloneBundle && !hasAlternates && o.Depth == 0 && o.PartialCloneFilter == "" && v.isUnborn() {
+		if err = v.applyCloneBundle(remote); err != nil {
+			log.Warnf("%sfail to apply clone bundle, fetch from remote: %s", v.Prompt(), err)
+		}
+	}
 
 	if revision == "" {
 		revision = v.TrackBranch("")
@@ -75,20 +164,28 @@ func (v *Repository) Fetch(remote string, o *FetchOptions) error {
 		}
 	}
 
-	isSha := common.IsSha(revision)
-	isTag := common.IsTag(revision)
+	spec := v.NewRevisionSpec(revision)
+	if spec.Kind == RevisionShortSha {
+		v.checkRemoteRef(spec)
+	}
+	isSha := spec.Kind == RevisionSha
+	isTag := spec.Kind == RevisionTag
 
-	if o.OptimizedFetch && isSha && v.RevisionIsValid(revision) {
+	if !v.IsFetchNeeded(o) {
 		return nil
 	}
 
 	if o.Mirror && o.Depth > 0 {
 		o.Depth = 0
 	}
+	currentBranchOnly := o.CurrentBranchOnly
 	if o.Depth > 0 {
-		o.CurrentBranchOnly = true
+		currentBranchOnly = true
+	} else if spec.UpstreamBranch() != "" {
+		// Only fetch the upstream branch which contains the SHA.
+		currentBranchOnly = true
 	}
-	if o.CurrentBranchOnly {
+	if currentBranchOnly {
 		if isSha || isTag {
 			if v.RevisionIsValid(revision) {
 				return nil
@@ -107,15 +204,21 @@ func (v *Repository) Fetch(remote string, o *FetchOptions) error {
 		cmdArgs = append(cmdArgs, "--unshallow")
 	}
 
+	// Partial clone, objects not fetched are fetched on demand later.
+	// Manifest project is always fully fetched.
+	if o.PartialCloneFilter != "" && !o.Mirror && !v.IsMetaProject() {
+		cmdArgs = append(cmdArgs, "--filter="+o.PartialCloneFilter)
+	}
+
 	if o.Quiet {
 		cmdArgs = append(cmdArgs, "--quiet")
 
 	}
 
-	if o.NoTags || o.Depth > 0 {
-		cmdArgs = append(cmdArgs, "--no-tags")
-	} else {
+	if v.IsFetchTags(o) {
 		cmdArgs = append(cmdArgs, "--tags")
+	} else {
+		cmdArgs = append(cmdArgs, "--no-tags")
 	}
 
 	if o.Prune {
@@ -128,35 +231,29 @@ func (v *Repository) Fetch(remote string, o *FetchOptions) error {
 	}
 
 	cmdArgs = append(cmdArgs, v.RemoteURL)
-	if o.CurrentBranchOnly {
-		if isSha {
-			cmdArgs = append(cmdArgs, revision)
-		} else if isTag {
-			cmdArgs = append(cmdArgs, fmt.Sprintf("+%s:%s", revision, revision))
-		} else if strings.HasPrefix(revision, "refs/heads/") || !strings.HasPrefix(revision, "refs/") {
-			branch := strings.TrimPrefix(revision, "refs/heads/")
-			if v.IsBare {
-				cmdArgs = append(cmdArgs, fmt.Sprintf("+refs/heads/%s:refs/heads/%s", branch, branch))
-			} else {
-				cmdArgs = append(cmdArgs, fmt.Sprintf("+refs/heads/%s:refs/remotes/%s/%s", branch, v.RemoteName, branch))
-			}
-		} else {
-			cmdArgs = append(cmdArgs, fmt.Sprintf("+%s:%s", revision, revision))
-		}
-	} else {
-		if v.IsBare {
-			cmdArgs = append(cmdArgs, "+refs/heads/*:refs/heads/*")
-		} else {
-			cmdArgs = append(cmdArgs, fmt.Sprintf("+refs/heads/*:refs/remotes/%s/*", v.RemoteName))
-		}
-	}
+	cmdArgs = append(cmdArgs, spec.FetchRefspecs(currentBranchOnly, o.Depth > 0)...)
 	log.Debugf("%sfetching using command: %s", v.Prompt(), strings.Join(cmdArgs, " "))
 
-	err = executeCommandIn(v.RepoDir(), cmdArgs)
+	auth, err := helper.GetHTTPAuth(v.RemoteURL)
+	if err != nil {
+		log.Warnf("%sfail to get credential: %s", v.Prompt(), err)
+	}
+	env := auth.GitEnv(v.RemoteURL)
+
+	err = executeNetworkCommandIn(v.RepoDir(), v.RemoteURL, env, cmdArgs, o.retryPolicy())
 	if err != nil {
 		return fmt.Errorf("fail to fetch project '%s': %s", v.Name, err)
 	}
 
+	// The SHA is not reachable from the upstream branch, fetch it directly.
+	if isSha && currentBranchOnly && !v.RevisionIsValid(revision) {
+		cmdArgs = append(cmdArgs[:len(cmdArgs)-1], revision)
+		log.Debugf("%sfetching using command: %s", v.Prompt(), strings.Join(cmdArgs, " "))
+		if err = executeNetworkCommandIn(v.RepoDir(), v.RemoteURL, env, cmdArgs, o.retryPolicy()); err != nil {
+			return fmt.Errorf("fail to fetch project '%s': %s", v.Name, err)
+		}
+	}
+
 	if hasAlternates && v.Settings.Dissociate {
 		cmdArgs = []string{
 			GIT,
@@ -241,9 +338,21 @@ func (v *Project) SyncNetworkHalf(o *FetchOptions) error {
 		return v.CopyAndLinkFiles()
 	}
 
-	if !v.Repository.Exists() {
-		// Initial repository
+	if !v.IsFetchNeeded(o) {
+		log.Debugf("%sskip fetch, revision '%s' exists locally", v.Prompt(), v.Revision)
+		return nil
+	}
+
+	if !v.Repository.Exists() ||
+		(v.IsWorktree() && v.ObjectsRepository().GitConfigRemoteURL(v.RemoteName) == "") {
+		// Initial repository, or shared object store of legacy layout
+		// becomes the main repository of git worktree.
 		v.GitInit()
+	} else if !o.Mirror {
+		err = v.ShareObjects()
+		if err != nil {
+			log.Warnf("%sfail to share objects: %s", v.Prompt(), err)
+		}
 	}
 	return v.Repository.Fetch(v.RemoteName, o)
 }
@@ -0,0 +1,64 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagsPolicy(t *testing.T) {
+	var (
+		assert    = assert.New(t)
+		fetchTags bool
+		reason    string
+	)
+
+	repo := Repository{}
+	fetchTags, reason = repo.TagsPolicy(&FetchOptions{})
+	assert.True(fetchTags)
+	assert.Equal("sync-tags", reason)
+
+	repo = Repository{Project: manifest.Project{SyncTags: "false"}}
+	fetchTags, reason = repo.TagsPolicy(&FetchOptions{})
+	assert.False(fetchTags)
+	assert.Equal("sync-tags", reason)
+	fetchTags, reason = repo.TagsPolicy(&FetchOptions{Tags: true})
+	assert.True(fetchTags)
+	assert.Equal("--tags", reason)
+
+	repo = Repository{}
+	fetchTags, reason = repo.TagsPolicy(&FetchOptions{NoTags: true})
+	assert.False(fetchTags)
+	assert.Equal("--no-tags", reason)
+	fetchTags, reason = repo.TagsPolicy(&FetchOptions{RepoSettings: RepoSettings{Depth: 1}})
+	assert.False(fetchTags)
+	assert.Equal("shallow clone", reason)
+	fetchTags, _ = repo.TagsPolicy(&FetchOptions{RepoSettings: RepoSettings{Depth: 1, Mirror: true}})
+	assert.True(fetchTags)
+	assert.True(repo.IsFetchTags(&FetchOptions{RepoSettings: RepoSettings{Depth: 1}, Tags: true}))
+}
+
+func TestIsFetchNeeded(t *testing.T) {
+	assert := assert.New(t)
+
+	repo := Repository{Project: manifest.Project{
+		Revision: "0123456789012345678901234567890123456789",
+	}}
+	assert.True(repo.IsFetchNeeded(&FetchOptions{}))
+	// Revision does not exist in repository.
+	assert.True(repo.IsFetchNeeded(&FetchOptions{OptimizedFetch: true}))
+
+	repo = Repository{Project: manifest.Project{Revision: "master"}}
+	assert.True(repo.IsFetchNeeded(&FetchOptions{OptimizedFetch: true}))
+}
+
+func TestFetchRetryPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	o := FetchOptions{}
+	assert.Equal(helper.NewRetryPolicy().MaxAttempts, o.retryPolicy().MaxAttempts)
+	o.RetryFetches = 4
+	assert.Equal(5, o.retryPolicy().MaxAttempts)
+}
@@ -0,0 +1,142 @@
+package project
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/cap"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/path"
+)
+
+// hasObjects checks whether objects dir has any loose objects or packs.
+func hasObjects(objectsDir string) bool {
+	entries, err := ioutil.ReadDir(objectsDir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == "info" {
+			continue
+		}
+		if entry.Name() == "pack" {
+			packs, _ := filepath.Glob(filepath.Join(objectsDir, "pack", "*.pack"))
+			if len(packs) > 0 {
+				return true
+			}
+			continue
+		}
+		if files, err := ioutil.ReadDir(filepath.Join(objectsDir, entry.Name())); err == nil && len(files) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// moveLooseObjects moves loose objects from objects dir to the target
+// objects dir. Objects already in target are removed.
+func moveLooseObjects(objectsDir, target string) error {
+	files, _ := filepath.Glob(filepath.Join(objectsDir, "[0-9a-f][0-9a-f]", "*"))
+	for _, file := range files {
+		dir := filepath.Base(filepath.Dir(file))
+		dest := filepath.Join(target, dir, filepath.Base(file))
+		var err error
+		if path.Exist(dest) {
+			err = os.Remove(file)
+		} else if err = os.MkdirAll(filepath.Join(target, dir), 0755); err == nil {
+			err = os.Rename(file, dest)
+		}
+		if err != nil {
+			return fmt.Errorf("fail to move '%s' to shared object store: %s", file, err)
+		}
+	}
+	return nil
+}
+
+// ShareObjects moves private objects of project (created by a legacy
+// layout, or by a platform without symlink) into the shared object store
+// in ".repo/project-objects/<name>.git", and links objects of gitdir to
+// it, so that projects of the same name at multiple paths store objects
+// only once. Do nothing if objects of project are already shared.
+func (v *Project) ShareObjects() error {
+	if v.ObjectsGitDir == "" || samePath(v.ObjectsGitDir, v.GitDir) || !cap.CanSymlink() {
+		return nil
+	}
+
+	objects := filepath.Join(v.GitDir, "objects")
+	fi, err := os.Lstat(objects)
+	if err != nil || !fi.IsDir() {
+		// Not exist, or already linked to shared object store.
+		return nil
+	}
+
+	objectsRepo := v.ObjectsRepository()
+	if !objectsRepo.Exists() {
+		err = objectsRepo.Init("", "", "")
+		if err != nil {
+			return err
+		}
+	}
+	sharedObjects := filepath.Join(v.ObjectsGitDir, "objects")
+
+	if !hasObjects(sharedObjects) {
+		// Shared object store is empty, take over private objects.
+		log.Notef("%smove objects to shared object store %s", v.Prompt(), v.ObjectsGitDir)
+		err = os.RemoveAll(sharedObjects)
+		if err == nil {
+			err = os.Rename(objects, sharedObjects)
+		}
+		if err != nil {
+			return fmt.Errorf("fail to move objects to '%s': %s", sharedObjects, err)
+		}
+	} else {
+		// Pack all objects of project, including unreachable ones
+		// which may be still in use (e.g. by a running command), and
+		// move the pack into shared object store.
+		log.Notef("%smerge objects into shared object store %s", v.Prompt(), v.ObjectsGitDir)
+		err = executeCommandIn(v.GitDir, []string{GIT, "repack", "-a", "-d", "-q", "--keep-unreachable"})
+		if err != nil {
+			return fmt.Errorf("fail to repack objects: %s", err)
+		}
+		packs, _ := filepath.Glob(filepath.Join(objects, "pack", "pack-*"))
+		// Move index files last, so packs are not used until complete.
+		sort.SliceStable(packs, func(i, j int) bool {
+			return !strings.HasSuffix(packs[i], ".idx") && strings.HasSuffix(packs[j], ".idx")
+		})
+		for _, pack := range packs {
+			target := filepath.Join(sharedObjects, "pack", filepath.Base(pack))
+			if path.Exist(target) {
+				// Name of pack is checksum of its content, so
+				// it is the same pack.
+				err = os.Remove(pack)
+			} else {
+				err = os.Rename(pack, target)
+			}
+			if err != nil {
+				return fmt.Errorf("fail to move '%s' to shared object store: %s", pack, err)
+			}
+		}
+		// Unreachable loose objects may be not packed.
+		if err = moveLooseObjects(objects, sharedObjects); err != nil {
+			return err
+		}
+		// Never remove objects which are not moved.
+		if hasObjects(objects) {
+			return fmt.Errorf("objects are left in '%s' after repack", objects)
+		}
+		err = os.RemoveAll(objects)
+		if err != nil {
+			return err
+		}
+	}
+
+	relpath, err := filepath.Rel(v.GitDir, sharedObjects)
+	if err != nil {
+		relpath = sharedObjects
+	}
+	return os.Symlink(relpath, objects)
+}
@@ -0,0 +1,194 @@
+package project
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/path"
+	"github.com/mattn/go-shellwords"
+)
+
+const (
+	// PostSyncAnnotation is name of annotation, which defines command
+	// to run in worktree of project after checkout.
+	PostSyncAnnotation = "post-sync"
+
+	postSyncApprovedHashKey = "repo.hooks.post-sync.approvedhash"
+)
+
+// PostSyncCommand returns command defined in post-sync annotation.
+func (v Project) PostSyncCommand() string {
+	for _, a := range v.Annotations {
+		if a.Name == PostSyncAnnotation {
+			return strings.TrimSpace(a.Value)
+		}
+	}
+	return ""
+}
+
+// postSyncArgs splits post-sync command into program and its arguments.
+// The command is run without shell, so shell operators are not allowed.
+func postSyncArgs(command string) ([]string, error) {
+	parser := shellwords.NewParser()
+	args, err := parser.Parse(command)
+	if err != nil {
+		return nil, fmt.Errorf("bad post-sync command '%s': %s", command, err)
+	}
+	if parser.Position >= 0 {
+		return nil, fmt.Errorf("post-sync command '%s' is not a single command", command)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty post-sync command")
+	}
+	return args, nil
+}
+
+// fileInWorkDir returns file of name in worktree, or empty string if name
+// is outside of worktree.
+func (v Project) fileInWorkDir(name string) string {
+	if filepath.IsAbs(name) {
+		return ""
+	}
+	file := filepath.Join(v.WorkDir, name)
+	rel, err := filepath.Rel(v.WorkDir, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return ""
+	}
+	return file
+}
+
+// postSyncScript returns script of post-sync command in worktree, or empty
+// string if command is a program in PATH. Script outside worktree is not
+// allowed, so that post-sync command only runs programs provided by
+// project itself.
+func (v Project) postSyncScript(command string) (string, error) {
+	args, err := postSyncArgs(command)
+	if err != nil {
+		return "", err
+	}
+	name := args[0]
+	if !strings.Contains(name, "/") {
+		return "", nil
+	}
+	script := v.fileInWorkDir(name)
+	if script == "" {
+		return "", fmt.Errorf("post-sync command '%s' is outside of project", name)
+	}
+	if !path.IsFile(script) {
+		return "", fmt.Errorf("post-sync command '%s' does not exist", name)
+	}
+	return script, nil
+}
+
+// PostSyncHash returns hash of post-sync command and files of project it
+// runs, such as the script, or a file in arguments of the command (e.g.
+// "sh generate.sh"). It is used to check whether the command is changed
+// since last approval.
+func (v Project) PostSyncHash() (string, error) {
+	command := v.PostSyncCommand()
+	if _, err := v.postSyncScript(command); err != nil {
+		return "", err
+	}
+	args, err := postSyncArgs(command)
+	if err != nil {
+		return "", err
+	}
+	h := sha1.New()
+	h.Write([]byte(command))
+	for _, arg := range args {
+		file := v.fileInWorkDir(arg)
+		if file == "" || !path.IsFile(file) {
+			continue
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte{0})
+		h.Write([]byte(arg))
+		h.Write([]byte{0})
+		h.Write(data)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// IsPostSyncApproved checks whether post-sync command is approved by user,
+// and not changed since then.
+func (v Project) IsPostSyncApproved() bool {
+	hash, err := v.PostSyncHash()
+	if err != nil {
+		return false
+	}
+	return v.Config().Get(postSyncApprovedHashKey) == hash
+}
+
+// ApprovePostSync saves hash of post-sync command, so user will not be
+// asked again until the command or its script is changed.
+func (v Project) ApprovePostSync() error {
+	hash, err := v.PostSyncHash()
+	if err != nil {
+		return err
+	}
+	cfg := v.Config()
+	cfg.Set(postSyncApprovedHashKey, hash)
+	return v.SaveConfig(cfg)
+}
+
+// postSyncEnv returns environments of post-sync command, which are PATH,
+// HOME and environments of the project.
+func (v Project) postSyncEnv() []string {
+	env := []string{}
+	for _, key := range []string{"PATH", "HOME"} {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+	env = append(env,
+		"REPO_PROJECT="+v.Name,
+		"REPO_PATH="+v.Path,
+		"REPO_REMOTE="+v.RemoteName,
+		"REPO_RREV="+v.Revision,
+	)
+	return append(env, v.AnnotationEnv()...)
+}
+
+// RunPostSync runs post-sync command in worktree of project without shell.
+// The command cannot read from stdin, and only sees PATH, HOME and
+// environments of the project.
+func (v Project) RunPostSync() error {
+	command := v.PostSyncCommand()
+	if command == "" {
+		return nil
+	}
+	script, err := v.postSyncScript(command)
+	if err != nil {
+		return err
+	}
+	args, err := postSyncArgs(command)
+	if err != nil {
+		return err
+	}
+	if script != "" {
+		args[0] = script
+	}
+
+	log.Debugf("%srun post-sync command: %s", v.Prompt(), command)
+	_, err = helper.RunCommand(&helper.Command{
+		Args:     args,
+		Dir:      v.WorkDir,
+		Stdout:   os.Stdout,
+		Stderr:   os.Stderr,
+		Env:      v.postSyncEnv(),
+		ClearEnv: true,
+	})
+	if err != nil {
+		return fmt.Errorf("%spost-sync command failed: %s", v.Prompt(), err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostSyncCommand(t *testing.T) {
+	var (
+		assert = assert.New(t)
+	)
+
+	workDir, err := ioutil.TempDir("", "git-repo-post-sync-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(workDir)
+	err = ioutil.WriteFile(filepath.Join(workDir, "generate.sh"), []byte("#!/bin/sh\n"), 0755)
+	assert.Nil(err)
+
+	p := Project{WorkDir: workDir}
+	assert.Equal("", p.PostSyncCommand())
+
+	p.Annotations = []manifest.Annotation{
+		{Name: "owner", Value: "nobody"},
+		{Name: "post-sync", Value: " ./generate.sh --all "},
+	}
+	assert.Equal("./generate.sh --all", p.PostSyncCommand())
+
+	script, err := p.postSyncScript("./generate.sh --all")
+	assert.Nil(err)
+	assert.Equal(filepath.Join(workDir, "generate.sh"), script)
+
+	script, err = p.postSyncScript("make generate")
+	assert.Nil(err)
+	assert.Equal("", script)
+
+	for _, command := range []string{
+		"/bin/rm -rf .",
+		"../generate.sh",
+		"sub/../../generate.sh",
+		"./missing.sh",
+		"./generate.sh; rm -rf .",
+		"./generate.sh | sh",
+		"'./generate.sh",
+		"",
+	} {
+		_, err = p.postSyncScript(command)
+		assert.NotNil(err, "command: %s", command)
+	}
+
+	hash1, err := p.PostSyncHash()
+	assert.Nil(err)
+	err = ioutil.WriteFile(filepath.Join(workDir, "generate.sh"), []byte("#!/bin/sh\necho hacked\n"), 0755)
+	assert.Nil(err)
+	hash2, err := p.PostSyncHash()
+	assert.Nil(err)
+	assert.NotEqual(hash1, hash2)
+
+	// Files in arguments of command are also hashed.
+	p.Annotations = []manifest.Annotation{
+		{Name: "post-sync", Value: "sh generate.sh"},
+	}
+	hash1, err = p.PostSyncHash()
+	assert.Nil(err)
+	err = ioutil.WriteFile(filepath.Join(workDir, "generate.sh"), []byte("#!/bin/sh\necho hacked again\n"), 0755)
+	assert.Nil(err)
+	hash2, err = p.PostSyncHash()
+	assert.Nil(err)
+	assert.NotEqual(hash1, hash2)
+}
+
+func TestPostSyncEnv(t *testing.T) {
+	assert := assert.New(t)
+
+	for k, v := range map[string]string{
+		"PATH":                 "/usr/bin:/bin",
+		"HOME":                 "/home/user",
+		"GIT_REPO_TEST_SECRET": "secret",
+	} {
+		if old, ok := os.LookupEnv(k); ok {
+			defer os.Setenv(k, old)
+		} else {
+			defer os.Unsetenv(k)
+		}
+		os.Setenv(k, v)
+	}
+
+	p := Project{}
+	p.Name = "app"
+	p.Path = "apps/app"
+	p.RemoteName = "origin"
+	p.Revision = "master"
+	p.Annotations = []manifest.Annotation{
+		{Name: "owner", Value: "nobody"},
+	}
+	assert.Equal([]string{
+		"PATH=/usr/bin:/bin",
+		"HOME=/home/user",
+		"REPO_PROJECT=app",
+		"REPO_PATH=apps/app",
+		"REPO_REMOTE=origin",
+		"REPO_RREV=master",
+		"REPO__owner=nobody",
+	}, p.postSyncEnv())
+}
@@ -12,10 +12,10 @@ import (
 
 	"github.com/alibaba/git-repo-go/common"
 	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/manifest"
 	"github.com/alibaba/git-repo-go/path"
 	"github.com/jiangxin/goconfig"
-	log "github.com/jiangxin/multi-log"
 	"gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing"
 )
@@ -160,6 +160,10 @@ func (v *Project) PrepareWorkdir() error {
 		return nil
 	}
 
+	if v.IsWorktree() {
+		return v.prepareWorktree()
+	}
+
 	gitdir := filepath.Join(v.WorkDir, ".git")
 	if _, err = os.Stat(gitdir); err != nil {
 		// Remove index file for fresh checkout
@@ -357,7 +361,7 @@ func (v *Project) GetRemoteURL() (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("fail to remote url for '%s': %s", v.Name, err)
 	}
-	return u, nil
+	return config.ConvertGitURL(v.ConfigWithDefault(), u, config.CfgRepoURLFetchProto), nil
 }
 
 // ConfigWithDefault returns git config file parser.
@@ -370,18 +374,23 @@ func (v *Project) ManifestConfig() goconfig.GitConfig {
 	return v.Settings.Config
 }
 
+// AnnotationEnv returns annotations with keep="true" as environments,
+// in the form of "REPO__<name>=<value>".
+func (v Project) AnnotationEnv() []string {
+	env := []string{}
+	for _, a := range v.Annotations {
+		if a.IsKeep() {
+			env = append(env, "REPO__"+a.Name+"="+a.Value)
+		}
+	}
+	return env
+}
+
 // MatchGroups indecates if project belongs to special groups.
 func (v Project) MatchGroups(expect string) bool {
 	return MatchGroups(expect, v.Groups)
 }
 
-// GetSubmoduleProjects returns submodule projects.
-func (v Project) GetSubmoduleProjects() []*Project {
-	// TODO: return submodule projects
-	log.Fatal("not implement GitSubmodules")
-	return nil
-}
-
 // UserEmail returns user identity.
 func (v Project) UserEmail() string {
 	username := os.Getenv("GIT_COMMITTER_NAME")
@@ -440,6 +449,11 @@ func NewProject(mp *manifest.Project, s *RepoSettings, m *manifest.Manifest) *Pr
 			config.ProjectObjects,
 			mp.Name+".git",
 		)
+		// Worktree of project is added by git worktree, and the shared
+		// object store is the main repository.
+		if s.Worktree {
+			gitDir = objectsGitDir
+		}
 	}
 	dotGit = filepath.Join(workDir, ".git")
 
@@ -456,30 +470,7 @@ func NewProject(mp *manifest.Project, s *RepoSettings, m *manifest.Manifest) *Pr
 		Remotes:   NewRemoteMap(),
 	}
 
-	if m != nil {
-		if repo.RemoteName == "" {
-			repo.RemoteName = m.Default.RemoteName
-		}
-		if repo.Revision == "" {
-			if m.Default.Revision != "" {
-				repo.Revision = m.Default.Revision
-			}
-		}
-		if repo.DestBranch == "" {
-			if m.Default.DestBranch != "" {
-				repo.DestBranch = m.Default.DestBranch
-			}
-		}
-		if repo.Upstream == "" {
-			if m.Default.Upstream != "" {
-				repo.Upstream = m.Default.DestBranch
-			}
-		}
-		if (repo.Revision == "" || common.IsImmutable(repo.Revision)) &&
-			!common.IsImmutable(m.Default.Revision) {
-			repo.ManifestDefaultRevision = m.Default.Revision
-		}
-	}
+	repo.resolveManifest(m)
 
 	p := Project{
 		Repository: repo,
@@ -495,6 +486,26 @@ func NewProject(mp *manifest.Project, s *RepoSettings, m *manifest.Manifest) *Pr
 	return &p
 }
 
+// resolveManifest fills missing attributes of project from its remote and
+// the default element of manifest m, and saves revision of the default
+// element, which is used as tracking branch if revision of project is
+// immutable.
+func (v *Repository) resolveManifest(m *manifest.Manifest) {
+	if m == nil {
+		return
+	}
+	if resolved, err := m.ResolveProject(v.Project); err == nil {
+		v.Project = resolved.Project
+	} else {
+		log.Debugf("fail to resolve project '%s': %s", v.Name, err)
+	}
+	if m.Default != nil &&
+		(v.Revision == "" || common.IsImmutable(v.Revision)) &&
+		!common.IsImmutable(m.Default.Revision) {
+		v.ManifestDefaultRevision = m.Default.Revision
+	}
+}
+
 // NewMirrorProject returns a mirror project.
 func NewMirrorProject(mp *manifest.Project, s *RepoSettings, m *manifest.Manifest) *Project {
 	var (
@@ -522,29 +533,7 @@ func NewMirrorProject(mp *manifest.Project, s *RepoSettings, m *manifest.Manifes
 		Reference: referencePath(mp, s),
 	}
 
-	if m != nil {
-		if repo.RemoteName == "" {
-			repo.RemoteName = m.Default.RemoteName
-		}
-		if repo.Revision == "" {
-			if m.Default.Revision != "" {
-				repo.Revision = m.Default.Revision
-			}
-		}
-		if repo.DestBranch == "" {
-			if m.Default.DestBranch != "" {
-				repo.DestBranch = m.Default.DestBranch
-			}
-		}
-		if repo.Upstream == "" {
-			if m.Default.Upstream != "" {
-				repo.Upstream = m.Default.DestBranch
-			}
-		}
-		if repo.Revision == "" || common.IsImmutable(repo.Revision) {
-			repo.ManifestDefaultRevision = m.Default.Revision
-		}
-	}
+	repo.resolveManifest(m)
 
 	p := Project{
 		Repository: repo,
@@ -572,7 +561,7 @@ func isHashRevision(rev string) bool {
 // Join two group of projects, ignore duplicated projects.
 func Join(group1, group2 []*Project) []*Project {
 	projectMap := make(map[string]bool)
-	result := make([]*Project, len(group1)+len(group2))
+	result := make([]*Project, 0, len(group1)+len(group2))
 
 	for _, p := range group1 {
 		if _, ok := projectMap[p.Path]; !ok {
@@ -207,6 +207,27 @@ name: Group3/Name1, path-1: app/3-1`
 	assert.Equal(expect, strings.Join(actual, "\n"))
 }
 
+func TestJoin(t *testing.T) {
+	assert := assert.New(t)
+	newProject := func(name, path string) *Project {
+		return &Project{
+			Repository: Repository{
+				Project: manifest.Project{
+					Name: name,
+					Path: path,
+				},
+			},
+		}
+	}
+	p1 := newProject("Name1", "app/1")
+	p2 := newProject("Name2", "app/2")
+	p3 := newProject("Name3", "app/3")
+
+	assert.Equal([]*Project{p1, p2, p3},
+		Join([]*Project{p1, p2}, []*Project{p2, p3}))
+	assert.Equal([]*Project{p1}, Join(nil, []*Project{p1}))
+}
+
 func TestProjectsTree(t *testing.T) {
 	assert := assert.New(t)
 	projects := []*Project{
@@ -284,3 +305,36 @@ path: app2/name2, name: Group2/Name2`
 	}
 	assert.Equal(expect, strings.Join(actual, "\n"))
 }
+
+func TestNewProjectResolveManifest(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &manifest.Manifest{
+		Remotes: []manifest.Remote{
+			{Name: "origin", Fetch: "..", Revision: "release"},
+		},
+		Default: &manifest.Default{
+			RemoteName: "origin",
+			Revision:   "master",
+			DestBranch: "main",
+		},
+	}
+	s := &RepoSettings{
+		TopDir:      "/path/of/workspace",
+		ManifestURL: "https://example.com/manifest.git",
+	}
+
+	p := NewProject(&manifest.Project{Name: "my/foo", Path: "foo"}, s, m)
+	assert.Equal("origin", p.RemoteName)
+	assert.Equal("release", p.Revision)
+	assert.Equal("main", p.DestBranch)
+	assert.Equal("", p.ManifestDefaultRevision)
+
+	p = NewProject(&manifest.Project{
+		Name:     "my/bar",
+		Path:     "bar",
+		Revision: "8a2c8e5f1f2a0e4c1c7b3a9b9b1e0e6c1a2b3c4d",
+	}, s, m)
+	assert.Equal("8a2c8e5f1f2a0e4c1c7b3a9b9b1e0e6c1a2b3c4d", p.Revision)
+	assert.Equal("master", p.ManifestDefaultRevision)
+}
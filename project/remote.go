@@ -9,8 +9,8 @@ import (
 
 	"github.com/alibaba/git-repo-go/config"
 	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/manifest"
-	log "github.com/jiangxin/multi-log"
 )
 
 // Remote wraps manifest remote.
@@ -46,6 +46,71 @@ func NewRemote(r *manifest.Remote, h helper.ProtoHelper) *Remote {
 	return &remote
 }
 
+// reviewURLTemplateKey is git config to override review URL template of
+// remote, and the subsection is the review URL of remote.
+func reviewURLTemplateKey(review string) string {
+	return fmt.Sprintf("review.%s.urltemplate", review)
+}
+
+// expandReviewURL replaces placeholders such as "{change}" in template
+// with vars. Returns empty string if any placeholder is not available.
+func expandReviewURL(template string, vars map[string]string) string {
+	var (
+		result strings.Builder
+		rest   = template
+	)
+
+	for {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			result.WriteString(rest)
+			break
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return ""
+		}
+		end += start
+		value, ok := vars[rest[start+1:end]]
+		if !ok || value == "" {
+			return ""
+		}
+		result.WriteString(rest[:start])
+		result.WriteString(value)
+		rest = rest[end+1:]
+	}
+	return result.String()
+}
+
+// ReviewURL returns URL of code review expanded from review URL template
+// of remote, which is defined in git config "review.<review>.urltemplate"
+// or in attribute "review-url-template" of manifest remote. Available
+// placeholders are "{review}", "{project}" and those in vars, such as
+// "{change}", "{patchset}" and "{branch}".
+func (v Project) ReviewURL(remote *Remote, vars map[string]string) string {
+	if remote == nil {
+		return ""
+	}
+	template := remote.ReviewURLTemplate
+	if remote.Review != "" {
+		if t := v.ConfigWithDefault().Get(reviewURLTemplateKey(remote.Review)); t != "" {
+			template = t
+		}
+	}
+	if template == "" {
+		return ""
+	}
+
+	values := map[string]string{
+		"review":  strings.TrimSuffix(remote.Review, "/"),
+		"project": v.Name,
+	}
+	for k, val := range vars {
+		values[k] = val
+	}
+	return expandReviewURL(template, values)
+}
+
 // GetRemotePushNameURL returns remote name and URL for push.
 func (v *Project) GetRemotePushNameURL(remote *Remote) (string, string) {
 	var (
@@ -118,7 +183,7 @@ func (v *Project) GetRemotePushURL(remote *Remote) string {
 	} else {
 		defaultURL = remote.Fetch
 	}
-	return defaultURL
+	return config.ConvertGitURL(v.ConfigWithDefault(), defaultURL, config.CfgRepoURLPushProto)
 }
 
 // GetDefaultRemote gets default remote for project.
@@ -281,6 +346,9 @@ func (v *Project) LoadRemotes(remoteMap *RemoteMap, noCache bool) {
 					if r.Type != "" {
 						mr.Type = r.Type
 					}
+					if r.ReviewURLTemplate != "" {
+						mr.ReviewURLTemplate = r.ReviewURLTemplate
+					}
 					newRemote := NewRemote(&mr, r.ProtoHelper)
 					v.Remotes.Add(newRemote)
 					return
@@ -310,9 +378,13 @@ func (v *Project) AddRemote(mr *manifest.Remote, noCache bool) *Remote {
 		protoHelper helper.ProtoHelper
 	)
 
-	if mr.Review != "" {
-		if mr.Type != "" {
-			sshInfo := &helper.SSHInfo{ProtoType: mr.Type}
+	protoType := strings.ToLower(mr.Type)
+	if protoType == "" {
+		protoType = helper.ProtoTypeFromURL(mr.Fetch)
+	}
+	if mr.Review != "" || protoType != "" {
+		if protoType != "" {
+			sshInfo := &helper.SSHInfo{ProtoType: protoType}
 			protoHelper = helper.NewProtoHelper(sshInfo)
 		} else {
 			query := helper.NewSSHInfoQuery(v.SSHInfoCacheFile())
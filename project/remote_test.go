@@ -0,0 +1,34 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandReviewURL(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		vars   = map[string]string{
+			"review":  "https://gerrit.example.com",
+			"project": "platform/app",
+			"change":  "123",
+		}
+	)
+
+	assert.Equal("https://gerrit.example.com/#/c/123",
+		expandReviewURL("{review}/#/c/{change}", vars))
+	assert.Equal("https://code.example.com/platform/app/reviews/123",
+		expandReviewURL("https://code.example.com/{project}/reviews/{change}", vars))
+	assert.Equal("https://example.com/",
+		expandReviewURL("https://example.com/", vars))
+	assert.Equal("",
+		expandReviewURL("{review}/#/c/{change}/{patchset}", vars))
+	assert.Equal("",
+		expandReviewURL("{review}/#/c/{change", vars))
+	assert.Equal("",
+		expandReviewURL("{review}/{topic}", map[string]string{
+			"review": "https://example.com",
+			"topic":  "",
+		}))
+}
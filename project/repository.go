@@ -2,18 +2,22 @@ package project
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/alibaba/git-repo-go/common"
 	"github.com/alibaba/git-repo-go/config"
 	"github.com/alibaba/git-repo-go/file"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/manifest"
 	"github.com/alibaba/git-repo-go/path"
 	"github.com/jiangxin/goconfig"
-	log "github.com/jiangxin/multi-log"
 	"gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing"
 )
@@ -42,14 +46,39 @@ type Repository struct {
 	raw       *git.Repository
 }
 
-// RepoDir returns git dir of the repository
+// RepoDir returns git dir of the repository. If .git of worktree is a
+// gitdir file, such as a worktree added by git worktree, returns the
+// gitdir it points to.
 func (v Repository) RepoDir() string {
 	if path.IsDir(v.DotGit) {
 		return v.DotGit
 	}
+	if dir := readGitDirFile(v.DotGit); dir != "" && path.IsDir(dir) {
+		return dir
+	}
 	return v.GitDir
 }
 
+// readGitDirFile returns the gitdir which a gitdir file points to.
+func readGitDirFile(dotGit string) string {
+	if dotGit == "" || !path.IsFile(dotGit) {
+		return ""
+	}
+	data, err := ioutil.ReadFile(dotGit)
+	if err != nil {
+		return ""
+	}
+	line := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(line, "gitdir:") {
+		return ""
+	}
+	dir := strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(filepath.Dir(dotGit), dir)
+	}
+	return filepath.Clean(dir)
+}
+
 // CommonDir returns commondir of a repository.
 func (v Repository) CommonDir() string {
 	dir := v.RepoDir()
@@ -169,10 +198,6 @@ func (v Repository) HasAlternates() bool {
 	return true
 }
 
-func (v Repository) applyCloneBundle() {
-	// TODO: download and clone from bundle file
-}
-
 // GetHead returns current branch name
 func (v Repository) GetHead() string {
 	var head string
@@ -237,43 +262,47 @@ func (v Repository) LastModified(revision string) string {
 	return commit.Committer.When.Format("Mon Jan 2 15:04:05 -0700 2006")
 }
 
+// Archive writes files of commit to w in tar format, with prefix added
+// to each filename. Git metadata, such as ".git", is not included.
+func (v Repository) Archive(w io.Writer, commit, prefix string) error {
+	var stderr bytes.Buffer
+
+	_, err := helper.RunCommand(&helper.Command{
+		Args:   []string{GIT, "archive", "--format=tar", "--prefix=" + prefix, commit},
+		Dir:    v.RepoDir(),
+		Stdout: w,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("fail to archive %s of '%s': %s",
+			commit, v.Name, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
 // Revlist works like rev-list.
 // TODO: Hack go-git plumbing/revlist package to replace git exec
 func (v Repository) Revlist(args ...string) ([]string, error) {
 	result := []string{}
 	cmdArgs := []string{
-		"git",
+		GIT,
 		"rev-list",
 	}
 
 	cmdArgs = append(cmdArgs, args...)
 
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
-	cmd.Dir = v.RepoDir()
-	cmd.Stdin = nil
-	cmd.Stderr = nil
-	out, err := cmd.StdoutPipe()
+	out, err := helper.RunCommand(&helper.Command{
+		Args: cmdArgs,
+		Dir:  v.RepoDir(),
+	})
 	if err != nil {
 		return nil, err
 	}
-	if err = cmd.Start(); err != nil {
-		return nil, err
-	}
-
-	r := bufio.NewReader(out)
-	for {
-		line, err := r.ReadString('\n')
+	for _, line := range strings.Split(string(out), "\n") {
 		line = strings.TrimSpace(line)
 		if len(line) > 0 {
 			result = append(result, line)
 		}
-		if err != nil {
-			break
-		}
-	}
-
-	if err = cmd.Wait(); err != nil {
-		return nil, err
 	}
 	return result, nil
 }
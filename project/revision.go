@@ -0,0 +1,286 @@
+package project
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alibaba/git-repo-go/common"
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/helper"
+)
+
+// RevisionKind is the kind of revision defined in manifest.
+type RevisionKind int
+
+// Kinds of revision.
+const (
+	// RevisionBranch is a branch, such as "master" or "refs/heads/master".
+	RevisionBranch RevisionKind = iota
+	// RevisionTag is a tag, such as "refs/tags/v1.0".
+	RevisionTag
+	// RevisionSha is a full commit ID.
+	RevisionSha
+	// RevisionRef is other reference, such as "refs/changes/12/12/1".
+	RevisionRef
+	// RevisionShortSha is an abbreviated commit ID, such as "8a2c8e5".
+	RevisionShortSha
+)
+
+// String returns name of the kind.
+func (v RevisionKind) String() string {
+	switch v {
+	case RevisionBranch:
+		return "branch"
+	case RevisionTag:
+		return "tag"
+	case RevisionSha:
+		return "sha"
+	case RevisionRef:
+		return "ref"
+	case RevisionShortSha:
+		return "short sha"
+	}
+	return "unknown"
+}
+
+// ClassifyRevision returns kind of revision.
+func ClassifyRevision(revision string) RevisionKind {
+	switch {
+	case common.IsSha(revision):
+		return RevisionSha
+	case common.IsShortSha(revision):
+		return RevisionShortSha
+	case common.IsTag(revision):
+		return RevisionTag
+	case common.IsHead(revision):
+		return RevisionBranch
+	case common.IsRef(revision):
+		return RevisionRef
+	}
+	return RevisionBranch
+}
+
+// CheckRevision checks syntax of revision in manifest, which must be a
+// valid branch name, reference or commit ID.
+func CheckRevision(revision string) error {
+	if revision == "" {
+		return nil
+	}
+	if strings.HasPrefix(revision, "-") ||
+		strings.HasPrefix(revision, "/") ||
+		strings.HasSuffix(revision, "/") ||
+		strings.HasSuffix(revision, ".") ||
+		strings.HasSuffix(revision, ".lock") ||
+		strings.Contains(revision, "..") ||
+		strings.Contains(revision, "//") ||
+		strings.Contains(revision, "@{") {
+		return fmt.Errorf("bad revision '%s'", revision)
+	}
+	for _, c := range revision {
+		if c <= ' ' || c == 0x7f || strings.ContainsRune("~^:?*[\\", c) {
+			return fmt.Errorf("bad revision '%s', invalid character %q", revision, c)
+		}
+	}
+	return nil
+}
+
+// RevisionSpec resolves revision of a project to refspecs for fetching,
+// and the target for checking out.
+type RevisionSpec struct {
+	Revision   string
+	Kind       RevisionKind
+	Upstream   string
+	RemoteName string
+	IsBare     bool
+}
+
+// NewRevisionSpec creates RevisionSpec for revision of repository. A
+// revision which looks like an abbreviated SHA, such as "20201012", is a
+// branch or a tag if the reference is fetched to the repository.
+func (v Repository) NewRevisionSpec(revision string) *RevisionSpec {
+	spec := &RevisionSpec{
+		Revision:   revision,
+		Kind:       ClassifyRevision(revision),
+		Upstream:   v.Upstream,
+		RemoteName: v.RemoteName,
+		IsBare:     v.IsBare,
+	}
+	if spec.Kind == RevisionShortSha && v.Exists() {
+		out, err := helper.RunCommand(&helper.Command{
+			Args: []string{
+				GIT,
+				"for-each-ref",
+				"--format=%(refname)",
+				spec.trackingRef(revision),
+				config.RefsTags + revision,
+			},
+			Dir: v.RepoDir(),
+		})
+		if err == nil {
+			refs := make(map[string]bool)
+			for _, ref := range strings.Fields(string(out)) {
+				refs[ref] = true
+			}
+			spec.setRefKind(refs[spec.trackingRef(revision)], refs[config.RefsTags+revision])
+		}
+	}
+	return spec
+}
+
+// trackingRef returns the reference which branch is fetched to.
+func (v RevisionSpec) trackingRef(branch string) string {
+	if v.IsBare {
+		return config.RefsHeads + branch
+	}
+	return config.RefsRemotes + v.RemoteName + "/" + branch
+}
+
+// setRefKind changes kind of an abbreviated SHA to branch or tag, if a
+// branch or a tag of the same name exists.
+func (v *RevisionSpec) setRefKind(isBranch, isTag bool) {
+	if v.Kind != RevisionShortSha {
+		return
+	}
+	switch {
+	case isBranch:
+		v.Kind = RevisionBranch
+	case isTag:
+		v.Kind = RevisionTag
+		v.Revision = config.RefsTags + v.Revision
+	}
+}
+
+// Branch returns short branch name of a branch revision.
+func (v RevisionSpec) Branch() string {
+	if v.Kind != RevisionBranch {
+		return ""
+	}
+	return strings.TrimPrefix(v.Revision, config.RefsHeads)
+}
+
+// UpstreamBranch returns short name of the upstream branch, which contains
+// the commit of a SHA revision.
+func (v RevisionSpec) UpstreamBranch() string {
+	if (v.Kind != RevisionSha && v.Kind != RevisionShortSha) || v.Upstream == "" ||
+		ClassifyRevision(v.Upstream) != RevisionBranch {
+		return ""
+	}
+	return strings.TrimPrefix(v.Upstream, config.RefsHeads)
+}
+
+// IsImmutable indicates revision will not move, such as a tag or a SHA.
+func (v RevisionSpec) IsImmutable() bool {
+	return v.Kind != RevisionBranch
+}
+
+func (v RevisionSpec) branchRefspec(branch string) string {
+	if v.IsBare {
+		return fmt.Sprintf("+%s%s:%s%s",
+			config.RefsHeads, branch, config.RefsHeads, branch)
+	}
+	return fmt.Sprintf("+%s%s:%s%s/%s",
+		config.RefsHeads, branch, config.RefsRemotes, v.RemoteName, branch)
+}
+
+// FetchRefspecs returns refspecs for git-fetch. If currentBranchOnly is
+// false, all branches are fetched. A SHA revision with an upstream branch
+// only fetches the upstream branch, unless shallow is set. An abbreviated
+// SHA cannot be fetched directly, and is found in the upstream branch or
+// in all branches.
+func (v RevisionSpec) FetchRefspecs(currentBranchOnly, shallow bool) []string {
+	if !currentBranchOnly {
+		refspecs := []string{v.branchRefspec("*")}
+		if v.Kind == RevisionRef {
+			refspecs = append(refspecs, fmt.Sprintf("+%s:%s", v.Revision, v.Revision))
+		}
+		return refspecs
+	}
+
+	switch v.Kind {
+	case RevisionSha:
+		if branch := v.UpstreamBranch(); branch != "" && !shallow {
+			return []string{v.branchRefspec(branch)}
+		}
+		return []string{v.Revision}
+	case RevisionShortSha:
+		if branch := v.UpstreamBranch(); branch != "" {
+			return []string{v.branchRefspec(branch)}
+		}
+		return []string{v.branchRefspec("*")}
+	case RevisionBranch:
+		return []string{v.branchRefspec(v.Branch())}
+	}
+	return []string{fmt.Sprintf("+%s:%s", v.Revision, v.Revision)}
+}
+
+// CheckoutTarget returns the reference or commit to check out after
+// fetching.
+func (v RevisionSpec) CheckoutTarget() string {
+	if v.Kind != RevisionBranch {
+		return v.Revision
+	}
+	if v.IsBare {
+		return config.RefsHeads + v.Branch()
+	}
+	return fmt.Sprintf("%s%s/%s", config.RefsRemotes, v.RemoteName, v.Branch())
+}
+
+// peelCommit resolves revision to commit ID by git, which peels annotated
+// tags, and resolves abbreviated SHAs and other references, such as
+// "refs/changes/12/12/1" and "FETCH_HEAD".
+func (v Repository) peelCommit(revision string) (string, error) {
+	var stderr bytes.Buffer
+
+	out, err := helper.RunCommand(&helper.Command{
+		Args:   []string{GIT, "rev-parse", "--verify", revision + "^{commit}"},
+		Dir:    v.RepoDir(),
+		Stderr: &stderr,
+	})
+	if err != nil {
+		if strings.Contains(stderr.String(), "ambiguous") {
+			return "", fmt.Errorf("revision '%s' of project '%s' is ambiguous, use a longer SHA",
+				revision,
+				v.Name)
+		}
+		return "", fmt.Errorf("revision '%s' of project '%s' is not found",
+			revision,
+			v.Name)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CommitBefore returns the last commit of revision (a branch) before
+// time t, following only the first parent, so that merged commits of
+// topic branches are skipped.
+func (v Repository) CommitBefore(revision string, t time.Time) (string, error) {
+	target := v.NewRevisionSpec(revision).CheckoutTarget()
+	out, err := helper.RunCommand(&helper.Command{
+		Args: []string{
+			GIT,
+			"rev-list",
+			"-1",
+			"--first-parent",
+			"--before=" + strconv.FormatInt(t.Unix(), 10),
+			target,
+			"--",
+		},
+		Dir: v.RepoDir(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("fail to find commit of '%s' in project '%s': %s",
+			revision,
+			v.Name,
+			err)
+	}
+	commit := strings.TrimSpace(string(out))
+	if commit == "" {
+		return "", fmt.Errorf("no commit of '%s' in project '%s' before %s",
+			revision,
+			v.Name,
+			t.Format(time.RFC3339))
+	}
+	return commit, nil
+}
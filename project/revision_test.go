@@ -0,0 +1,185 @@
+package project
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyRevision(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(RevisionBranch, ClassifyRevision("master"))
+	assert.Equal(RevisionBranch, ClassifyRevision("refs/heads/master"))
+	assert.Equal(RevisionTag, ClassifyRevision("refs/tags/v1.0"))
+	assert.Equal(RevisionSha, ClassifyRevision("8a2c8e5f1f2a0e4c1c7b3a9b9b1e0e6c1a2b3c4d"))
+	assert.Equal(RevisionRef, ClassifyRevision("refs/changes/12/12/1"))
+	assert.Equal(RevisionShortSha, ClassifyRevision("8a2c8e5"))
+	// Hexadecimal branch name without digits is not a SHA.
+	assert.Equal(RevisionBranch, ClassifyRevision("deadbeef"))
+	assert.Equal(RevisionBranch, ClassifyRevision("8a2c8e"))
+	assert.Equal("sha", RevisionSha.String())
+}
+
+func TestCheckRevision(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, rev := range []string{
+		"",
+		"master",
+		"refs/heads/release/1.0",
+		"refs/tags/v1.0",
+		"refs/changes/12/12/1",
+		"8a2c8e5",
+	} {
+		assert.Nil(CheckRevision(rev), rev)
+	}
+
+	assert.Equal("bad revision 'master..dev'", CheckRevision("master..dev").Error())
+	assert.Equal("bad revision 'refs/heads/'", CheckRevision("refs/heads/").Error())
+	assert.Equal("bad revision 'a.lock'", CheckRevision("a.lock").Error())
+	assert.Equal("bad revision 'master~1', invalid character '~'", CheckRevision("master~1").Error())
+	assert.Equal("bad revision 'my branch', invalid character ' '", CheckRevision("my branch").Error())
+}
+
+func TestRevisionSpec(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		sha    = "8a2c8e5f1f2a0e4c1c7b3a9b9b1e0e6c1a2b3c4d"
+	)
+
+	repo := Repository{}
+	repo.RemoteName = "origin"
+	spec := repo.NewRevisionSpec("refs/heads/master")
+	assert.Equal("master", spec.Branch())
+	assert.False(spec.IsImmutable())
+	assert.Equal([]string{"+refs/heads/*:refs/remotes/origin/*"},
+		spec.FetchRefspecs(false, false))
+	assert.Equal([]string{"+refs/heads/master:refs/remotes/origin/master"},
+		spec.FetchRefspecs(true, false))
+	assert.Equal("refs/remotes/origin/master", spec.CheckoutTarget())
+
+	spec = repo.NewRevisionSpec("refs/tags/v1.0")
+	assert.True(spec.IsImmutable())
+	assert.Equal([]string{"+refs/tags/v1.0:refs/tags/v1.0"},
+		spec.FetchRefspecs(true, false))
+	assert.Equal("refs/tags/v1.0", spec.CheckoutTarget())
+
+	spec = repo.NewRevisionSpec("refs/changes/12/12/1")
+	assert.Equal([]string{
+		"+refs/heads/*:refs/remotes/origin/*",
+		"+refs/changes/12/12/1:refs/changes/12/12/1",
+	}, spec.FetchRefspecs(false, false))
+
+	spec = repo.NewRevisionSpec(sha)
+	assert.Equal("", spec.UpstreamBranch())
+	assert.Equal([]string{sha}, spec.FetchRefspecs(true, false))
+	assert.Equal(sha, spec.CheckoutTarget())
+
+	// Only fetch upstream branch for SHA revision.
+	repo.Upstream = "refs/heads/Maint"
+	spec = repo.NewRevisionSpec(sha)
+	assert.Equal("Maint", spec.UpstreamBranch())
+	assert.Equal([]string{"+refs/heads/Maint:refs/remotes/origin/Maint"},
+		spec.FetchRefspecs(true, false))
+	assert.Equal([]string{sha}, spec.FetchRefspecs(true, true))
+
+	// Upstream of a tag is ignored.
+	repo.Upstream = "refs/tags/v1.0"
+	spec = repo.NewRevisionSpec(sha)
+	assert.Equal("", spec.UpstreamBranch())
+
+	// Abbreviated SHA is found in upstream branch, or all branches.
+	spec = repo.NewRevisionSpec("8a2c8e5")
+	assert.True(spec.IsImmutable())
+	assert.Equal([]string{"+refs/heads/*:refs/remotes/origin/*"},
+		spec.FetchRefspecs(true, false))
+	assert.Equal("8a2c8e5", spec.CheckoutTarget())
+	repo.Upstream = "refs/heads/Maint"
+	spec = repo.NewRevisionSpec("8a2c8e5")
+	assert.Equal([]string{"+refs/heads/Maint:refs/remotes/origin/Maint"},
+		spec.FetchRefspecs(true, false))
+
+	repo = Repository{IsBare: true}
+	repo.RemoteName = "origin"
+	spec = repo.NewRevisionSpec("master")
+	assert.Equal([]string{"+refs/heads/master:refs/heads/master"},
+		spec.FetchRefspecs(true, false))
+	assert.Equal("refs/heads/master", spec.CheckoutTarget())
+}
+
+func TestPeelCommit(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		sha    = "8a2c8e5f1f2a0e4c1c7b3a9b9b1e0e6c1a2b3c4d"
+	)
+
+	mock := helper.MockExecutor{
+		Handler: func(c *helper.Command) ([]byte, error) {
+			switch c.Args[len(c.Args)-1] {
+			case "refs/tags/v1.0^{commit}":
+				return []byte(sha + "\n"), nil
+			case "8a2c^{commit}":
+				c.Stderr.Write([]byte("error: short SHA1 8a2c is ambiguous\n"))
+			}
+			return nil, errors.New("exit status 128")
+		},
+	}
+	defer helper.SetExecutor(&mock)()
+
+	repo := Repository{}
+	repo.Name = "platform/app"
+	commit, err := repo.peelCommit("refs/tags/v1.0")
+	assert.Nil(err)
+	assert.Equal(sha, commit)
+
+	_, err = repo.peelCommit("8a2c")
+	assert.Equal("revision '8a2c' of project 'platform/app' is ambiguous, use a longer SHA", err.Error())
+
+	_, err = repo.peelCommit("refs/changes/12/12/1")
+	assert.Equal("revision 'refs/changes/12/12/1' of project 'platform/app' is not found", err.Error())
+}
+
+func TestRevisionLikeShortSha(t *testing.T) {
+	assert := assert.New(t)
+
+	mock := helper.MockExecutor{
+		Handler: func(c *helper.Command) ([]byte, error) {
+			if c.Args[1] == "ls-remote" {
+				return []byte("8a2c8e5f1f2a0e4c1c7b3a9b9b1e0e6c1a2b3c4d\trefs/heads/20201012\n"), nil
+			}
+			return nil, nil
+		},
+	}
+	defer helper.SetExecutor(&mock)()
+
+	repo := Repository{}
+	repo.RemoteName = "origin"
+	repo.RemoteURL = "https://example.com/app.git"
+
+	// Branch of remote which looks like a SHA.
+	spec := repo.NewRevisionSpec("20201012")
+	assert.Equal(RevisionShortSha, spec.Kind)
+	repo.checkRemoteRef(spec)
+	assert.Equal(RevisionBranch, spec.Kind)
+	assert.Equal([]string{"+refs/heads/20201012:refs/remotes/origin/20201012"},
+		spec.FetchRefspecs(true, false))
+	assert.Equal("refs/remotes/origin/20201012", spec.CheckoutTarget())
+
+	// Not a reference of remote.
+	spec = repo.NewRevisionSpec("8a2c8e5")
+	repo.checkRemoteRef(spec)
+	assert.Equal(RevisionShortSha, spec.Kind)
+
+	assert.Equal([]string{
+		"git ls-remote https://example.com/app.git refs/heads/20201012 refs/tags/20201012",
+		"git ls-remote https://example.com/app.git refs/heads/8a2c8e5 refs/tags/8a2c8e5",
+	}, mock.CommandLines())
+
+	spec = repo.NewRevisionSpec("20201012")
+	spec.setRefKind(false, true)
+	assert.Equal(RevisionTag, spec.Kind)
+	assert.Equal("refs/tags/20201012", spec.Revision)
+}
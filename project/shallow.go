@@ -0,0 +1,119 @@
+package project
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/path"
+)
+
+// deepenSteps are numbers of commits to deepen a shallow clone by, before
+// fetching the whole history.
+var deepenSteps = []int{50, 500}
+
+// shallowFile returns file which lists boundary commits of shallow clone.
+func (v Repository) shallowFile() string {
+	return filepath.Join(v.RepoDir(), "shallow")
+}
+
+// IsShallow indicates whether repository is a shallow clone.
+func (v Repository) IsShallow() bool {
+	return path.Exist(v.shallowFile())
+}
+
+// isShallowCommit checks whether commit is a boundary commit of shallow
+// clone, whose parents are not fetched.
+func (v Repository) isShallowCommit(commit string) bool {
+	data, err := ioutil.ReadFile(v.shallowFile())
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == commit {
+			return true
+		}
+	}
+	return false
+}
+
+// Deepen fetches more history of tracking branch for shallow repository,
+// or fetches the whole history if depth is 0.
+func (v *Repository) Deepen(depth int) error {
+	cmdArgs := []string{
+		GIT,
+		"fetch",
+		"--quiet",
+		"--no-tags",
+	}
+	if depth > 0 {
+		cmdArgs = append(cmdArgs, fmt.Sprintf("--deepen=%d", depth))
+	} else {
+		cmdArgs = append(cmdArgs, "--unshallow")
+	}
+	cmdArgs = append(cmdArgs, v.RemoteURL)
+	cmdArgs = append(cmdArgs, v.NewRevisionSpec(v.Revision).FetchRefspecs(true, true)...)
+	log.Debugf("%sdeepening using command: %s", v.Prompt(), strings.Join(cmdArgs, " "))
+
+	auth, err := helper.GetHTTPAuth(v.RemoteURL)
+	if err != nil {
+		log.Warnf("%sfail to get credential: %s", v.Prompt(), err)
+	}
+	err = executeNetworkCommandIn(v.RepoDir(), v.RemoteURL, auth.GitEnv(v.RemoteURL), cmdArgs,
+		helper.NewRetryPolicy())
+	if err != nil {
+		return fmt.Errorf("fail to deepen project '%s': %s", v.Name, err)
+	}
+	return nil
+}
+
+// ensureHistory deepens shallow clone step by step until found returns
+// true, instead of failing with cryptic errors of git commands.
+func (v *Project) ensureHistory(what string, found func() bool) error {
+	if !v.IsShallow() || found() {
+		return nil
+	}
+	for _, depth := range append(deepenSteps, 0) {
+		if depth > 0 {
+			log.Notef("%sdeepen shallow clone by %d commits to find %s", v.Prompt(), depth, what)
+		} else {
+			log.Notef("%sfetch whole history of shallow clone to find %s", v.Prompt(), what)
+		}
+		if err := v.Deepen(depth); err != nil {
+			return err
+		}
+		if found() {
+			return nil
+		}
+		if !v.IsShallow() {
+			break
+		}
+	}
+	return fmt.Errorf("%scannot find %s in history", v.Prompt(), what)
+}
+
+// EnsureMergeBase makes sure merge base of two revisions is available in
+// shallow clone, which is required to rebase or merge.
+func (v *Project) EnsureMergeBase(rev1, rev2 string) error {
+	return v.ensureHistory(fmt.Sprintf("merge base of %s and %s", rev1, rev2), func() bool {
+		return v.ExecuteCommand(GIT, "merge-base", rev1, rev2).Success()
+	})
+}
+
+// EnsureParents makes sure parents of commit are available in shallow
+// clone, which are required to cherry-pick or revert the commit.
+func (v *Project) EnsureParents(commit string) error {
+	if !v.IsShallow() {
+		return nil
+	}
+	id, err := v.ResolveCommit(commit)
+	if err != nil {
+		return err
+	}
+	return v.ensureHistory("parents of "+commit, func() bool {
+		return !v.isShallowCommit(id)
+	})
+}
@@ -0,0 +1,220 @@
+package project
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+)
+
+const (
+	// snapshotRefs is namespace of refs fetched from bundle of snapshot,
+	// which are removed after branches are restored.
+	snapshotRefs = "refs/snapshot/"
+	// snapshotBranchSuffix is appended to name of branch restored from
+	// snapshot, if the branch exists with other commits.
+	snapshotBranchSuffix = "-snapshot"
+)
+
+// BranchSnapshot is a local branch saved in snapshot of project.
+type BranchSnapshot struct {
+	Name   string `json:"name"`
+	Commit string `json:"commit"`
+	Remote string `json:"remote,omitempty"`
+	Track  string `json:"track,omitempty"`
+}
+
+// Snapshot is state of local work of project, to restore in another
+// workspace.
+type Snapshot struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Head is commit of HEAD, and Branch is current branch, empty for
+	// detached HEAD.
+	Head     string           `json:"head"`
+	Branch   string           `json:"branch,omitempty"`
+	Branches []BranchSnapshot `json:"branches,omitempty"`
+	// Stashes are listed only, and are not restored.
+	Stashes []string `json:"stashes,omitempty"`
+	// HasBundle indicates local commits are saved in a bundle.
+	HasBundle bool `json:"has_bundle,omitempty"`
+	// HasDiff indicates uncommitted changes are saved in a patch.
+	HasDiff bool `json:"has_diff,omitempty"`
+}
+
+// gitOutput runs git command in project, and returns its output.
+func (v Project) gitOutput(args ...string) (string, error) {
+	result := v.ExecuteCommand(append([]string{config.GIT}, args...)...)
+	if !result.Success() {
+		return "", fmt.Errorf("%sfail to run 'git %s': %s",
+			v.Prompt(),
+			strings.Join(args, " "),
+			strings.TrimSpace(result.Stderr()))
+	}
+	return result.Stdout(), nil
+}
+
+// TakeSnapshot saves HEAD, local branches and list of stashes of project.
+func (v Project) TakeSnapshot() (*Snapshot, error) {
+	s := Snapshot{
+		Name: v.Name,
+		Path: v.Path,
+	}
+	head, err := v.ResolveRevision("HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("%sfail to resolve HEAD: %s", v.Prompt(), err)
+	}
+	s.Head = head
+	s.Branch = strings.TrimPrefix(v.GetHead(), config.RefsHeads)
+
+	for _, b := range v.Heads() {
+		s.Branches = append(s.Branches, BranchSnapshot{
+			Name:   b.ShortName(),
+			Commit: b.Hash,
+			Remote: v.TrackRemote(b.Name),
+			Track:  v.TrackBranch(b.Name),
+		})
+	}
+
+	out, err := v.gitOutput("stash", "list", "--format=%gd: %gs")
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			s.Stashes = append(s.Stashes, line)
+		}
+	}
+	return &s, nil
+}
+
+// CreateSnapshotBundle saves commits of HEAD and local branches, which are
+// not in remote branches, in bundle file. Returns false if there are no
+// such commits.
+func (v Project) CreateSnapshotBundle(file string) (bool, error) {
+	commits, err := v.Revlist("HEAD", "--branches", "--not", "--remotes")
+	if err != nil {
+		return false, err
+	}
+	if len(commits) == 0 {
+		return false, nil
+	}
+	_, err = v.gitOutput("bundle", "create", file, "HEAD", "--branches", "--not", "--remotes")
+	return err == nil, err
+}
+
+// SnapshotBase returns the last commit of HEAD which is in remote branches,
+// so that it can be fetched in another workspace. Local commits are saved
+// in bundle by CreateSnapshotBundle.
+func (v Project) SnapshotBase() (string, error) {
+	head, err := v.ResolveRevision("HEAD")
+	if err != nil {
+		return "", err
+	}
+	commits, err := v.Revlist("HEAD", "--not", "--remotes")
+	if err != nil {
+		return "", err
+	}
+	if len(commits) == 0 {
+		return head, nil
+	}
+	revid, err := v.ResolveRemoteTracking(v.Revision)
+	if err != nil {
+		return "", err
+	}
+	out, err := v.gitOutput("merge-base", head, revid)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// WorktreeDiff returns uncommitted changes of tracked files in worktree.
+func (v Project) WorktreeDiff() (string, error) {
+	return v.gitOutput("diff", "--binary", "HEAD")
+}
+
+// RestoreSnapshot restores local branches and HEAD of project from
+// snapshot, with commits in bundle file and uncommitted changes in patch
+// file, which are empty if not saved. Branches which exist with other
+// commits are not changed, and are restored with suffix "-snapshot"
+// instead.
+func (v Project) RestoreSnapshot(s *Snapshot, bundle, patch string) error {
+	var (
+		err     error
+		renames = make(map[string]string)
+	)
+
+	if bundle != "" {
+		args := []string{"fetch", "-q", bundle,
+			"+" + config.RefsHeads + "*:" + snapshotRefs + "heads/*"}
+		// Detached HEAD is in bundle if it has local commits.
+		if s.Branch == "" && !v.RevisionIsValid(s.Head) {
+			args = append(args, "+HEAD:"+snapshotRefs+"HEAD")
+		}
+		if _, err = v.gitOutput(args...); err != nil {
+			return err
+		}
+		defer func() {
+			out, _ := v.gitOutput("for-each-ref", "--format=%(refname)", snapshotRefs)
+			for _, ref := range strings.Split(out, "\n") {
+				if ref = strings.TrimSpace(ref); ref != "" {
+					v.gitOutput("update-ref", "-d", ref)
+				}
+			}
+		}()
+	}
+
+	for _, b := range s.Branches {
+		name := b.Name
+		if commit, err := v.ResolveRevision(config.RefsHeads + name); err == nil && commit != "" {
+			if commit == b.Commit {
+				continue
+			}
+			name = b.Name + snapshotBranchSuffix
+			if commit, err := v.ResolveRevision(config.RefsHeads + name); err == nil && commit != "" {
+				if commit != b.Commit {
+					return fmt.Errorf("%sbranch %s and %s exist with other commits, cannot restore branch %s",
+						v.Prompt(), b.Name, name, b.Name)
+				}
+				renames[b.Name] = name
+				continue
+			}
+			log.Warnf("%sbranch %s exists with other commits, restored as %s",
+				v.Prompt(), b.Name, name)
+			renames[b.Name] = name
+		}
+		if _, err = v.gitOutput("update-ref", config.RefsHeads+name, b.Commit, ""); err != nil {
+			return err
+		}
+		v.UpdateBranchTracking(name, b.Remote, b.Track)
+	}
+
+	if !v.IsClean() {
+		return fmt.Errorf("%sworktree is dirty, cannot restore HEAD", v.Prompt())
+	}
+	if s.Branch != "" {
+		branch := s.Branch
+		if name, ok := renames[branch]; ok {
+			branch = name
+		}
+		_, err = v.gitOutput("checkout", "-q", branch)
+	} else {
+		_, err = v.gitOutput("checkout", "-q", "--detach", s.Head)
+	}
+	if err != nil {
+		return err
+	}
+
+	if patch != "" {
+		if _, err = v.gitOutput("apply", "--whitespace=nowarn", patch); err != nil {
+			return err
+		}
+	}
+	if len(s.Stashes) > 0 {
+		log.Warnf("%s%d stash(es) are not restored: %s",
+			v.Prompt(), len(s.Stashes), strings.Join(s.Stashes, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestoreSnapshotToExistingBranch(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	gitIn(tmpdir, "init", "-q")
+	ioutil.WriteFile(filepath.Join(tmpdir, "README.md"), []byte("hello\n"), 0644)
+	gitIn(tmpdir, "add", "-A")
+	gitIn(tmpdir, "commit", "-q", "-m", "initial")
+	commitA := gitIn(tmpdir, "rev-parse", "HEAD")
+	gitIn(tmpdir, "commit", "-q", "--allow-empty", "-m", "snapshot")
+	commitB := gitIn(tmpdir, "rev-parse", "HEAD")
+	gitIn(tmpdir, "reset", "-q", "--hard", commitA)
+	gitIn(tmpdir, "branch", "topic", commitA)
+
+	p := Project{WorkDir: tmpdir}
+	p.Path = "app"
+	p.GitDir = filepath.Join(tmpdir, ".git")
+	p.DotGit = p.GitDir
+	p.Settings = &RepoSettings{}
+
+	s := Snapshot{
+		Head:   commitB,
+		Branch: "topic",
+		Branches: []BranchSnapshot{
+			{Name: "topic", Commit: commitB},
+		},
+	}
+	assert.Nil(p.RestoreSnapshot(&s, "", ""))
+	assert.Equal(commitA, gitIn(tmpdir, "rev-parse", "refs/heads/topic"))
+	assert.Equal(commitB, gitIn(tmpdir, "rev-parse", "refs/heads/topic-snapshot"))
+	assert.Equal("refs/heads/topic-snapshot", gitIn(tmpdir, "symbolic-ref", "HEAD"))
+
+	// Restore again, and the restored branch is reused.
+	gitIn(tmpdir, "checkout", "-q", "topic")
+	assert.Nil(p.RestoreSnapshot(&s, "", ""))
+	assert.Equal("refs/heads/topic-snapshot", gitIn(tmpdir, "symbolic-ref", "HEAD"))
+
+	// Both branches exist with other commits.
+	gitIn(tmpdir, "checkout", "-q", "topic")
+	gitIn(tmpdir, "branch", "-f", "topic-snapshot", commitA)
+	assert.NotNil(p.RestoreSnapshot(&s, "", ""))
+	assert.Equal("refs/heads/topic", gitIn(tmpdir, "symbolic-ref", "HEAD"))
+}
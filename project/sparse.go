@@ -0,0 +1,225 @@
+package project
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/cap"
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/log"
+)
+
+const (
+	// cfgRepoSparse saves directories of sparse checkout set by "git repo
+	// sparse set", which override sparse-checkout elements of manifest.
+	cfgRepoSparse = "repo.sparse"
+	// cfgRepoSparseApplied saves directories of sparse checkout applied by
+	// sync, so that sparse checkout set up by user is not changed.
+	cfgRepoSparseApplied        = "repo.sparseapplied"
+	cfgCoreSparseCheckout       = "core.sparsecheckout"
+	cfgCoreSparseCheckoutCone   = "core.sparsecheckoutcone"
+	cfgExtensionsWorktreeConfig = "extensions.worktreeconfig"
+)
+
+// splitSparsePaths splits comma separated directories.
+func splitSparsePaths(value string) []string {
+	paths := []string{}
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// coneSparsePatterns returns patterns of sparse-checkout file in cone
+// mode, the same as "git sparse-checkout set" writes: files at top and
+// in parents of paths, and all files in paths.
+func coneSparsePatterns(paths []string) []string {
+	dirs := map[string]bool{}
+	parents := map[string]bool{}
+	for _, p := range paths {
+		dirs[p] = true
+		for dir := filepath.ToSlash(filepath.Dir(p)); dir != "."; dir = filepath.ToSlash(filepath.Dir(dir)) {
+			parents[dir] = true
+		}
+	}
+	names := []string{}
+	for dir := range parents {
+		if !dirs[dir] {
+			names = append(names, dir)
+		}
+	}
+	for dir := range dirs {
+		names = append(names, dir)
+	}
+	sort.Strings(names)
+
+	patterns := []string{"/*", "!/*/"}
+	for _, dir := range names {
+		patterns = append(patterns, "/"+dir+"/")
+		if !dirs[dir] {
+			patterns = append(patterns, "!/"+dir+"/*/")
+		}
+	}
+	return patterns
+}
+
+// getWorktreeConfig reads key from config of worktree. Settings of sparse
+// checkout are saved in config of worktree, because config of repository
+// is shared by worktrees of the same repository.
+func (v Project) getWorktreeConfig(key string) string {
+	result := v.ExecuteCommand(config.GIT, "config", "--worktree", "--get", key)
+	if !result.Success() {
+		return ""
+	}
+	return strings.TrimSpace(result.Stdout())
+}
+
+// setWorktreeConfig sets key in config of worktree, or unsets it if value
+// is empty. Extension worktreeConfig is enabled first like "git
+// sparse-checkout", otherwise "git config --worktree" writes to config of
+// repository.
+func (v Project) setWorktreeConfig(key, value string) error {
+	args := []string{config.GIT, "config", "--worktree"}
+	if value == "" {
+		if v.getWorktreeConfig(key) == "" {
+			return nil
+		}
+		args = append(args, "--unset-all", key)
+	} else {
+		if err := v.enableWorktreeConfig(); err != nil {
+			return err
+		}
+		args = append(args, key, value)
+	}
+	return v.runGitCommands(args)
+}
+
+// enableWorktreeConfig turns on extension worktreeConfig, which needs
+// repository format version 1.
+func (v Project) enableWorktreeConfig() error {
+	result := v.ExecuteCommand(config.GIT, "config", "--bool", "--get", cfgExtensionsWorktreeConfig)
+	if result.Success() && strings.TrimSpace(result.Stdout()) == "true" {
+		return nil
+	}
+	return v.runGitCommands(
+		[]string{config.GIT, "config", "core.repositoryformatversion", "1"},
+		[]string{config.GIT, "config", cfgExtensionsWorktreeConfig, "true"})
+}
+
+// runGitCommands runs commands in worktree, and stops at the first error.
+func (v Project) runGitCommands(cmds ...[]string) error {
+	for _, args := range cmds {
+		result := v.ExecuteCommand(args...)
+		if !result.Success() {
+			return fmt.Errorf("%sfail to run '%s': %s",
+				v.Prompt(),
+				strings.Join(args, " "),
+				strings.TrimSpace(result.Stderr()))
+		}
+	}
+	return nil
+}
+
+// SparseCheckoutPaths returns directories of project to check out in
+// sparse checkout, and whether they are set in workspace by "git repo
+// sparse set" instead of manifest. Empty for a full checkout.
+func (v Project) SparseCheckoutPaths() ([]string, bool) {
+	if value := v.getWorktreeConfig(cfgRepoSparse); value != "" {
+		return splitSparsePaths(value), true
+	}
+	return v.Project.SparseCheckoutPaths(), false
+}
+
+// SetSparseCheckoutPaths saves directories of sparse checkout of project
+// in workspace, or removes them to use those of manifest if paths is
+// empty. Run ApplySparseCheckout to update worktree.
+func (v Project) SetSparseCheckoutPaths(paths []string) error {
+	return v.setWorktreeConfig(cfgRepoSparse, strings.Join(paths, ","))
+}
+
+// userSparseCheckout checks whether sparse checkout is set up by user
+// instead of sync.
+func (v Project) userSparseCheckout(applied string) bool {
+	return applied == "" && v.getWorktreeConfig(cfgCoreSparseCheckout) == "true"
+}
+
+// initSparseCheckout sets up sparse checkout in cone mode before the
+// first checkout of project, so that files out of directories of sparse
+// checkout are never written to worktree. Once worktree is checked out,
+// ApplySparseCheckout updates it.
+func (v Project) initSparseCheckout() error {
+	paths, _ := v.SparseCheckoutPaths()
+	if len(paths) == 0 || v.IsMirror() {
+		return nil
+	}
+	value := strings.Join(paths, ",")
+	applied := v.getWorktreeConfig(cfgRepoSparseApplied)
+	if value == applied || v.userSparseCheckout(applied) {
+		return nil
+	}
+	if !cap.GitCanSparseCheckout() {
+		log.Warnf("%ssparse checkout needs git 2.25.0 or above, skipped", v.Prompt())
+		return nil
+	}
+
+	sparseFile, err := v.gitPath("info/sparse-checkout")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(sparseFile), 0755); err != nil {
+		return err
+	}
+	data := strings.Join(coneSparsePatterns(paths), "\n") + "\n"
+	if err = ioutil.WriteFile(sparseFile, []byte(data), 0644); err != nil {
+		return fmt.Errorf("%sfail to write %s: %s", v.Prompt(), sparseFile, err)
+	}
+	for _, kv := range [][2]string{
+		{cfgCoreSparseCheckout, "true"},
+		{cfgCoreSparseCheckoutCone, "true"},
+		{cfgRepoSparseApplied, value},
+	} {
+		if err = v.setWorktreeConfig(kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplySparseCheckout runs "git sparse-checkout" in cone mode to check out
+// only directories of sparse checkout, or disables sparse checkout if
+// they are removed. Sparse checkout which is set up by user is not changed.
+func (v Project) ApplySparseCheckout() error {
+	paths, _ := v.SparseCheckoutPaths()
+	value := strings.Join(paths, ",")
+	applied := v.getWorktreeConfig(cfgRepoSparseApplied)
+	if value == applied {
+		return nil
+	}
+	if v.userSparseCheckout(applied) {
+		log.Warnf("%ssparse checkout is set up by user, not changed", v.Prompt())
+		return nil
+	}
+	if !cap.GitCanSparseCheckout() {
+		log.Warnf("%ssparse checkout needs git 2.25.0 or above, skipped", v.Prompt())
+		return nil
+	}
+
+	var err error
+	if value == "" {
+		err = v.runGitCommands([]string{config.GIT, "sparse-checkout", "disable"})
+	} else {
+		err = v.runGitCommands(
+			[]string{config.GIT, "sparse-checkout", "init", "--cone"},
+			append([]string{config.GIT, "sparse-checkout", "set"}, paths...))
+	}
+	if err != nil {
+		return err
+	}
+	return v.setWorktreeConfig(cfgRepoSparseApplied, value)
+}
@@ -0,0 +1,174 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/cap"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockCapGit struct {
+	sparseCheckout bool
+}
+
+func (v mockCapGit) GitCanPushOptions() bool {
+	return true
+}
+
+func (v mockCapGit) GitCanMaintenance() bool {
+	return true
+}
+
+func (v mockCapGit) GitCanSparseCheckout() bool {
+	return v.sparseCheckout
+}
+
+func TestConeSparsePatterns(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{
+		"/*",
+		"!/*/",
+		"/docs/",
+		"/lib/",
+		"!/lib/*/",
+		"/lib/a/",
+		"!/lib/a/*/",
+		"/lib/a/b/",
+	}, coneSparsePatterns([]string{"lib/a/b", "docs"}))
+}
+
+// newSparseProject creates a project, which is cloned without checkout.
+func newSparseProject(tmpdir string) *Project {
+	src := filepath.Join(tmpdir, "src")
+	for _, name := range []string{"README", "src/main.c", "docs/index.md"} {
+		file := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			panic(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(name+"\n"), 0644); err != nil {
+			panic(err)
+		}
+	}
+	gitIn(tmpdir, "init", "-q", src)
+	gitIn(src, "add", "-A")
+	gitIn(src, "commit", "-q", "-m", "initial")
+	gitIn(tmpdir, "clone", "-q", "--no-checkout", src, "app")
+
+	p := Project{}
+	p.Path = "app"
+	p.Settings = &RepoSettings{}
+	p.WorkDir = filepath.Join(tmpdir, "app")
+	p.GitDir = filepath.Join(p.WorkDir, ".git")
+	return &p
+}
+
+func TestInitSparseCheckout(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-sparse-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	capGit := cap.CapGit
+	defer func() { cap.CapGit = capGit }()
+	cap.CapGit = mockCapGit{sparseCheckout: true}
+
+	p := newSparseProject(tmpdir)
+	p.SparseCheckouts = []manifest.SparseCheckout{{Path: "src"}}
+	assert.Nil(p.initSparseCheckout())
+	assert.Equal("src", p.getWorktreeConfig(cfgRepoSparseApplied))
+	assert.Equal("true", p.getWorktreeConfig(cfgCoreSparseCheckout))
+	assert.Equal("true", gitIn(p.WorkDir, "config", "extensions.worktreeConfig"))
+	assert.Equal("", gitIn(p.WorkDir, "config", "--local", "--default", "", "core.sparseCheckout"))
+
+	// The first checkout writes only files in directories of sparse checkout.
+	gitIn(p.WorkDir, "checkout", "-q", "master")
+	assert.FileExists(filepath.Join(p.WorkDir, "README"))
+	assert.FileExists(filepath.Join(p.WorkDir, "src", "main.c"))
+	_, err = os.Stat(filepath.Join(p.WorkDir, "docs"))
+	assert.True(os.IsNotExist(err))
+}
+
+func TestApplySparseCheckout(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-sparse-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	capGit := cap.CapGit
+	defer func() { cap.CapGit = capGit }()
+	cap.CapGit = mockCapGit{sparseCheckout: false}
+
+	p := newSparseProject(tmpdir)
+	gitIn(p.WorkDir, "checkout", "-q", "master")
+	p.SparseCheckouts = []manifest.SparseCheckout{
+		{Path: "src/"},
+		{Path: "../outside"},
+	}
+	docs := filepath.Join(p.WorkDir, "docs")
+
+	paths, override := p.SparseCheckoutPaths()
+	assert.Equal([]string{"src"}, paths)
+	assert.False(override)
+
+	// Git is too old.
+	assert.Nil(p.ApplySparseCheckout())
+	assert.DirExists(docs)
+	assert.Equal("", p.getWorktreeConfig(cfgRepoSparseApplied))
+
+	cap.CapGit = mockCapGit{sparseCheckout: true}
+	assert.Nil(p.ApplySparseCheckout())
+	assert.Equal("src", p.getWorktreeConfig(cfgRepoSparseApplied))
+	_, err = os.Stat(docs)
+	assert.True(os.IsNotExist(err))
+
+	// Not changed.
+	mock := helper.MockExecutor{
+		Handler: func(c *helper.Command) ([]byte, error) {
+			return helper.ExecExecutor{}.Run(c)
+		},
+	}
+	restore := helper.SetExecutor(&mock)
+	assert.Nil(p.ApplySparseCheckout())
+	assert.Equal([]string{
+		"git config --worktree --get repo.sparse",
+		"git config --worktree --get repo.sparseapplied",
+	}, mock.CommandLines())
+	restore()
+
+	// Override by workspace.
+	assert.Nil(p.SetSparseCheckoutPaths([]string{"docs", "src"}))
+	paths, override = p.SparseCheckoutPaths()
+	assert.Equal([]string{"docs", "src"}, paths)
+	assert.True(override)
+	assert.Equal("", gitIn(p.WorkDir, "config", "--local", "--default", "", cfgRepoSparse))
+	assert.Nil(p.ApplySparseCheckout())
+	assert.Equal("docs,src", p.getWorktreeConfig(cfgRepoSparseApplied))
+	assert.DirExists(docs)
+
+	// Back to full checkout.
+	assert.Nil(p.SetSparseCheckoutPaths(nil))
+	p.SparseCheckouts = nil
+	assert.Nil(p.ApplySparseCheckout())
+	assert.Equal("", p.getWorktreeConfig(cfgRepoSparseApplied))
+	assert.FileExists(filepath.Join(p.WorkDir, "README"))
+
+	// Sparse checkout of user is not changed.
+	gitIn(p.WorkDir, "sparse-checkout", "set", "src")
+	p.SparseCheckouts = []manifest.SparseCheckout{{Path: "docs"}}
+	assert.Nil(p.ApplySparseCheckout())
+	assert.Equal("", p.getWorktreeConfig(cfgRepoSparseApplied))
+	_, err = os.Stat(docs)
+	assert.True(os.IsNotExist(err))
+}
@@ -8,7 +8,7 @@ import (
 	"strings"
 
 	"github.com/alibaba/git-repo-go/color"
-	log "github.com/jiangxin/multi-log"
+	"github.com/alibaba/git-repo-go/log"
 )
 
 type gitStatus struct {
@@ -151,6 +151,18 @@ func combineGitStatus(sti, stf []*gitStatus, sto []string) string {
 	return result
 }
 
+func formatSubmoduleStatus(submodules []*Submodule) string {
+	result := ""
+	for _, s := range submodules {
+		if s.Status == ' ' {
+			continue
+		}
+		line := fmt.Sprintf(" S%c\t%s (%s)", s.Status, s.Path, s.Description())
+		result += color.Color("red", "", "") + line + color.Reset() + "\n"
+	}
+	return result
+}
+
 // Status shows combined output of git status for project.
 func (v Project) Status() *CmdExecResult {
 	result := NewCmdExecResult(&v)
@@ -161,22 +173,38 @@ func (v Project) Status() *CmdExecResult {
 		return result
 	}
 
-	di := v.ExecuteCommand("git",
-		"diff-index",
-		"-z",
-		"-M",
-		"--cached",
-		"HEAD")
-	df := v.ExecuteCommand("git",
-		"diff-files",
-		"-z")
+	// No need to run diff-index and diff-files, if tracked files are clean.
+	di, df := NewCmdExecResult(&v), NewCmdExecResult(&v)
+	if !v.isTrackedClean() {
+		di = v.ExecuteCommand("git",
+			"diff-index",
+			"-z",
+			"-M",
+			"--cached",
+			"HEAD")
+		df = v.ExecuteCommand("git",
+			"diff-files",
+			"-z")
+	}
 	do := v.ExecuteCommand("git",
 		"ls-files",
 		"-z",
 		"--others",
 		"--exclude-standard")
 
+	// Show submodules which are not in sync, if project syncs submodules.
+	subStatus := ""
+	if v.SyncSBool() {
+		submodules, err := v.Submodules()
+		if err != nil {
+			result.Error = err
+			return result
+		}
+		subStatus = formatSubmoduleStatus(submodules)
+	}
+
 	if di.Empty() && df.Empty() && do.Empty() {
+		result.Out = []byte(subStatus)
 		return result
 	}
 
@@ -190,7 +218,7 @@ func (v Project) Status() *CmdExecResult {
 	}
 
 	output := combineGitStatus(sti, stf, sto)
-	result.Out = []byte(output)
+	result.Out = []byte(output + subStatus)
 
 	if di.Error != nil && df.Error != nil {
 		errMsg := di.Stderr()
@@ -207,3 +235,55 @@ func (v Project) Status() *CmdExecResult {
 
 	return result
 }
+
+// StatusCounts is number of changed files of project.
+type StatusCounts struct {
+	Staged    int
+	Unstaged  int
+	Untracked int
+}
+
+// CountStatus counts staged, unstaged and untracked files of project,
+// which is used for machine-readable output of status.
+func (v Project) CountStatus() (*StatusCounts, error) {
+	counts := StatusCounts{}
+
+	if v.IsRebaseInProgress() {
+		return nil, fmt.Errorf("prior sync failed; rebase still in progress")
+	}
+
+	if !v.isTrackedClean() {
+		di := v.ExecuteCommand("git",
+			"diff-index",
+			"-z",
+			"-M",
+			"--cached",
+			"HEAD")
+		if di.Error != nil {
+			return nil, di.Error
+		}
+		df := v.ExecuteCommand("git",
+			"diff-files",
+			"-z")
+		if df.Error != nil {
+			return nil, df.Error
+		}
+		counts.Staged = len(parseGitStatus(di.Out))
+		counts.Unstaged = len(parseGitStatus(df.Out))
+	}
+
+	do := v.ExecuteCommand("git",
+		"ls-files",
+		"-z",
+		"--others",
+		"--exclude-standard")
+	if do.Error != nil {
+		return nil, do.Error
+	}
+	for _, name := range bytes.Split(do.Out, []byte("\x00")) {
+		if len(name) > 0 {
+			counts.Untracked++
+		}
+	}
+	return &counts, nil
+}
@@ -0,0 +1,114 @@
+package project
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/path"
+)
+
+// Submodule holds status of a submodule reported by `git submodule status`.
+type Submodule struct {
+	Path   string
+	Commit string
+	// Status is one of ' ' (up to date), '-' (not initialized),
+	// '+' (not at the recorded commit) and 'U' (merge conflicts).
+	Status byte
+}
+
+// IsInitialized indicates the submodule is initialized.
+func (v Submodule) IsInitialized() bool {
+	return v.Status != '-'
+}
+
+// Description explains status of the submodule.
+func (v Submodule) Description() string {
+	switch v.Status {
+	case '-':
+		return "not initialized"
+	case '+':
+		return "not at the recorded commit"
+	case 'U':
+		return "merge conflicts"
+	}
+	return ""
+}
+
+func parseSubmoduleStatus(out []byte) []*Submodule {
+	result := []*Submodule{}
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		if len(line) < 2 {
+			continue
+		}
+		fields := strings.Fields(string(line[1:]))
+		if len(fields) < 2 {
+			log.Errorf("wrong submodule status line: %s", string(line))
+			continue
+		}
+		result = append(result, &Submodule{
+			Status: line[0],
+			Commit: fields[0],
+			Path:   fields[1],
+		})
+	}
+	return result
+}
+
+// HasSubmodules indicates project has submodules defined in .gitmodules.
+func (v Project) HasSubmodules() bool {
+	return !v.IsMirror() && path.Exist(filepath.Join(v.WorkDir, ".gitmodules"))
+}
+
+// Submodules returns status of submodules of project recursively.
+func (v Project) Submodules() ([]*Submodule, error) {
+	if !v.HasSubmodules() {
+		return nil, nil
+	}
+	result := v.ExecuteCommand(GIT, "submodule", "status", "--recursive")
+	if result.Error != nil {
+		return nil, fmt.Errorf("fail to get submodule status: %s", result.Stderr())
+	}
+	return parseSubmoduleStatus(result.Out), nil
+}
+
+// syncSubmodules initializes and updates submodules recursively after
+// checkout. Submodules are only updated if HEAD is updated (force is
+// true) or some of them are not initialized yet, so that commits checked
+// out in submodules by the user are left untouched.
+func (v Project) syncSubmodules(force bool, o *CheckoutOptions) error {
+	if !v.HasSubmodules() {
+		return nil
+	}
+
+	if !force {
+		submodules, err := v.Submodules()
+		if err != nil {
+			return err
+		}
+		for _, s := range submodules {
+			if !s.IsInitialized() {
+				force = true
+				break
+			}
+		}
+		if !force {
+			return nil
+		}
+	}
+
+	args := []string{}
+	depth := v.CloneDepthInt()
+	if depth == 0 {
+		depth = o.Depth
+	}
+	if depth > 0 {
+		args = append(args, fmt.Sprintf("--depth=%d", depth))
+	}
+	if o.Jobs > 1 {
+		args = append(args, fmt.Sprintf("--jobs=%d", o.Jobs))
+	}
+	return v.SubmoduleUpdate(args...)
+}
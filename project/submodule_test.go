@@ -0,0 +1,35 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSubmoduleStatus(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		out    = []byte(` 8a2c8e5f1f2a0e4c1c7b3a9b9b1e0e6c1a2b3c4d submodule-1 (heads/master)
++9b3d9f6a2a3b1f5d2d8c4bac0c2f1f7d2b3c4d5e submodule-1/submodule-1-1 (heads/master~1)
+-ac4e0a7b3b4c2a6e3e9d5cbd1d3a2a8e3c4d5e6f submodule-2
+`)
+	)
+
+	submodules := parseSubmoduleStatus(out)
+	if assert.Equal(3, len(submodules)) {
+		assert.Equal("submodule-1", submodules[0].Path)
+		assert.Equal("8a2c8e5f1f2a0e4c1c7b3a9b9b1e0e6c1a2b3c4d", submodules[0].Commit)
+		assert.True(submodules[0].IsInitialized())
+		assert.Equal("", submodules[0].Description())
+
+		assert.Equal("submodule-1/submodule-1-1", submodules[1].Path)
+		assert.True(submodules[1].IsInitialized())
+		assert.Equal("not at the recorded commit", submodules[1].Description())
+
+		assert.Equal("submodule-2", submodules[2].Path)
+		assert.False(submodules[2].IsInitialized())
+		assert.Equal("not initialized", submodules[2].Description())
+	}
+
+	assert.Equal(0, len(parseSubmoduleStatus(nil)))
+}
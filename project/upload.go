@@ -1,15 +1,16 @@
 package project
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"strings"
 
 	"github.com/alibaba/git-repo-go/common"
 	"github.com/alibaba/git-repo-go/config"
 	"github.com/alibaba/git-repo-go/helper"
-	log "github.com/jiangxin/multi-log"
+	"github.com/alibaba/git-repo-go/log"
 )
 
 // RemoteTrack holds info of remote tracking branch
@@ -29,6 +30,9 @@ type ReviewableBranch struct {
 	Error       error
 	CodeReview  config.CodeReview // Push to update specific code review, only available for single repository mode.
 	Remote      *Remote
+	ReviewURLs  []string // URLs of code reviews returned from server.
+	Change      string   // Number of code review returned from server.
+	Sign        bool     // Sign commits by "git commit-tree -S" before upload.
 
 	isPublished int
 }
@@ -123,9 +127,54 @@ func (v ReviewableBranch) Commits() []string {
 	return commits
 }
 
+// reviewMessage returns title and description of code review from commit
+// messages. The subject of the only commit is used as title, or the
+// subjects of commits are listed in description.
+func (v ReviewableBranch) reviewMessage() (string, string) {
+	commits := v.Commits()
+	if len(commits) == 0 {
+		return v.Branch.ShortName(), ""
+	}
+
+	args := []string{GIT, "log", "--reverse", "--no-walk=unsorted", "--format=%s%x00%b%x01"}
+	args = append(args, commits...)
+	result := v.Project.ExecuteCommand(args...)
+	if !result.Success() {
+		return v.Branch.ShortName(), ""
+	}
+
+	subjects := []string{}
+	bodies := []string{}
+	for _, entry := range strings.Split(result.Stdout(), "\x01") {
+		items := strings.SplitN(strings.TrimSpace(entry), "\x00", 2)
+		if items[0] == "" {
+			continue
+		}
+		subjects = append(subjects, items[0])
+		if len(items) == 2 {
+			bodies = append(bodies, strings.TrimSpace(items[1]))
+		}
+	}
+	if len(subjects) == 1 {
+		body := ""
+		if len(bodies) == 1 {
+			body = bodies[0]
+		}
+		return subjects[0], body
+	}
+	description := ""
+	for _, subject := range subjects {
+		description += "* " + subject + "\n"
+	}
+	return subjects[0], description
+}
+
 // UploadForReview sends review for branch.
-func (v ReviewableBranch) UploadForReview(o *config.UploadOptions) error {
-	var err error
+func (v *ReviewableBranch) UploadForReview(o *config.UploadOptions) error {
+	var (
+		err          error
+		pushMessages bytes.Buffer
+	)
 
 	p := v.Project
 	if p == nil {
@@ -185,23 +234,39 @@ func (v ReviewableBranch) UploadForReview(o *config.UploadOptions) error {
 		log.Debugf("%sreview by command: %s",
 			v.Project.Prompt(),
 			strings.Join(cmdArgs, " "))
-		cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
-		cmd.Dir = p.WorkDir
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if len(envs) > 0 {
-			cmd.Env = []string{}
-			cmd.Env = append(cmd.Env, os.Environ()...)
-			cmd.Env = append(cmd.Env, envs...)
-			for _, env := range envs {
-				log.Debugf("%swith extra environment: %s", v.Project.Prompt(), env)
-			}
+		for _, env := range envs {
+			log.Debugf("%swith extra environment: %s", v.Project.Prompt(), env)
 		}
-		err = cmd.Run()
+		// Push is not retried, for it may fail after the server has
+		// accepted it, and retry may create duplicate reviews.
+		release := helper.AcquireHost(remoteURL)
+		_, err = helper.RunCommand(&helper.Command{
+			Args:   cmdArgs,
+			Dir:    p.WorkDir,
+			Env:    envs,
+			Stdin:  os.Stdin,
+			Stdout: os.Stdout,
+			Stderr: io.MultiWriter(os.Stderr, &pushMessages),
+		})
+		release()
 		if err != nil {
 			return fmt.Errorf("upload failed: %s", err)
 		}
+		v.ReviewURLs = helper.ParseReviewURLs(pushMessages.String())
+		v.Change = helper.ParseReviewChange(pushMessages.String())
+
+		// Open pull request for the pushed branch by REST API.
+		if creator, ok := v.Remote.ProtoHelper.(helper.ReviewCreator); ok {
+			ro := *o
+			if ro.Title == "" {
+				ro.Title, ro.Description = v.reviewMessage()
+			}
+			reviewURL, err := creator.CreateReview(&ro)
+			if err != nil {
+				return fmt.Errorf("fail to create code review: %s", err)
+			}
+			v.ReviewURLs = []string{reviewURL}
+		}
 	}
 
 	branchName := v.Branch.Name
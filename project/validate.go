@@ -0,0 +1,531 @@
+package project
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// Git config variables to control validations before upload.
+const (
+	CfgUploadRequireChangeID    = "upload.requireChangeId"
+	CfgUploadInsertChangeID     = "upload.insertChangeId"
+	CfgUploadMaxSubjectLength   = "upload.maxSubjectLength"
+	CfgUploadRequireSignedOffBy = "upload.requireSignedOffBy"
+	CfgUploadForbiddenFiles     = "upload.forbiddenFiles"
+	CfgUploadRequireSigned      = "upload.requireSigned"
+	CfgReviewRequireSigned      = "review.%s.requireSigned"
+)
+
+var (
+	reChangeID    = regexp.MustCompile(`(?m)^Change-Id: I[0-9a-f]{40}\s*$`)
+	reSignedOffBy = regexp.MustCompile(`(?m)^Signed-off-by: \S`)
+	reTrailer     = regexp.MustCompile(`^[A-Za-z0-9-]+: `)
+	reIdent       = regexp.MustCompile(`^(.*) <(.*)> (\d+ [+-]\d{4})$`)
+)
+
+// commitObject holds headers and message of a raw commit object.
+type commitObject struct {
+	ID      string
+	Headers []string
+	Message string
+}
+
+// Bytes returns content of commit object.
+func (v commitObject) Bytes() []byte {
+	var buf bytes.Buffer
+
+	for _, h := range v.Headers {
+		buf.WriteString(h)
+		buf.WriteString("\n")
+	}
+	buf.WriteString("\n")
+	buf.WriteString(v.Message)
+	return buf.Bytes()
+}
+
+// Committer returns committer of commit in the form "Name <email>".
+func (v commitObject) Committer() string {
+	for _, h := range v.Headers {
+		if !strings.HasPrefix(h, "committer ") {
+			continue
+		}
+		if m := reIdent.FindStringSubmatch(strings.TrimPrefix(h, "committer ")); m != nil {
+			return m[1] + " <" + m[2] + ">"
+		}
+	}
+	return ""
+}
+
+// gitConfig reads git config of key by git, which includes global and
+// system config.
+func (v Project) gitConfig(key string) string {
+	result := v.ExecuteCommand(config.GIT, "config", "--get", key)
+	if !result.Success() {
+		return ""
+	}
+	return strings.TrimSpace(result.Stdout())
+}
+
+// hasGoodSignature checks signature of commit by git, and the signature
+// must be good ("G"), or good but made by a key of unknown validity ("U").
+// Missing, bad and unverifiable signatures are not good.
+func (v Project) hasGoodSignature(commit string) bool {
+	result := v.ExecuteCommand(config.GIT, "log", "-1", "--format=%G?", commit)
+	if !result.Success() {
+		return false
+	}
+	switch strings.TrimSpace(result.Stdout()) {
+	case "G", "U":
+		return true
+	}
+	return false
+}
+
+// catCommit reads and parses commit object.
+func (v Project) catCommit(commit string) (*commitObject, error) {
+	result := v.ExecuteCommand(config.GIT, "cat-file", "commit", commit)
+	if !result.Success() {
+		return nil, fmt.Errorf("fail to read commit %s: %s",
+			commit,
+			strings.TrimSpace(result.Stderr()))
+	}
+	data := result.Stdout()
+	items := strings.SplitN(data, "\n\n", 2)
+	obj := commitObject{ID: commit}
+	// Continuation lines of multi-line headers (such as gpgsig) start
+	// with a space, and are kept together with the header.
+	for _, line := range strings.Split(items[0], "\n") {
+		if strings.HasPrefix(line, " ") && len(obj.Headers) > 0 {
+			obj.Headers[len(obj.Headers)-1] += "\n" + line
+			continue
+		}
+		obj.Headers = append(obj.Headers, line)
+	}
+	if len(items) == 2 {
+		obj.Message = items[1]
+	}
+	return &obj, nil
+}
+
+// changedFiles returns files changed by the commit.
+func (v Project) changedFiles(commit string) ([]string, error) {
+	result := v.ExecuteCommand(config.GIT,
+		"diff-tree",
+		"--no-commit-id",
+		"--name-only",
+		"-r",
+		"--root",
+		commit)
+	if !result.Success() {
+		return nil, fmt.Errorf("fail to list files of commit %s: %s",
+			commit,
+			strings.TrimSpace(result.Stderr()))
+	}
+	files := []string{}
+	for _, line := range strings.Split(result.Stdout(), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// matchForbiddenFile checks whether file matches one of the patterns,
+// either by full path or by base name.
+func matchForbiddenFile(file string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, file); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(file)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCommitMessage returns problems found in commit message.
+func checkCommitMessage(msg string, requireChangeID, requireSignedOffBy bool, maxSubjectLength int) []string {
+	var problems []string
+
+	subject := strings.SplitN(strings.TrimSpace(msg), "\n", 2)[0]
+	if maxSubjectLength > 0 && len([]rune(subject)) > maxSubjectLength {
+		problems = append(problems,
+			fmt.Sprintf("subject is longer than %d characters", maxSubjectLength))
+	}
+	if requireChangeID && !reChangeID.MatchString(msg) {
+		problems = append(problems, "missing Change-Id")
+	}
+	if requireSignedOffBy && !reSignedOffBy.MatchString(msg) {
+		problems = append(problems, "missing Signed-off-by")
+	}
+	return problems
+}
+
+// addChangeID appends Change-Id to the trailers of commit message.
+func addChangeID(msg, changeID string) string {
+	msg = strings.TrimRight(msg, "\n")
+	lines := strings.Split(msg, "\n")
+	last := lines[len(lines)-1]
+	if len(lines) > 1 && reTrailer.MatchString(last) {
+		return msg + "\nChange-Id: " + changeID + "\n"
+	}
+	return msg + "\n\nChange-Id: " + changeID + "\n"
+}
+
+// RequireSigned indicates commits must be signed before upload, which is
+// set by git config upload.requireSigned, or review.<review>.requireSigned
+// for the review server of remote.
+func (v ReviewableBranch) RequireSigned() bool {
+	cfg := v.Project.ConfigWithDefault()
+	if cfg.GetBool(CfgUploadRequireSigned, false) {
+		return true
+	}
+	if v.Remote != nil && v.Remote.Review != "" {
+		return cfg.GetBool(fmt.Sprintf(CfgReviewRequireSigned, v.Remote.Review), false)
+	}
+	return false
+}
+
+// Validate checks commits of the branch before upload, and returns
+// problems found. Missing Change-Id will be inserted by rewriting
+// commits if upload.insertChangeId is set, and commits which are not
+// signed will be signed if Sign is set.
+func (v *ReviewableBranch) Validate() ([]string, error) {
+	var (
+		problems []string
+		missing  = 0
+		unsigned = 0
+	)
+
+	p := v.Project
+	cfg := p.ConfigWithDefault()
+	requireChangeID := cfg.GetBool(CfgUploadRequireChangeID, false)
+	insertChangeID := cfg.GetBool(CfgUploadInsertChangeID, false)
+	requireSignedOffBy := cfg.GetBool(CfgUploadRequireSignedOffBy, false)
+	maxSubjectLength, _ := strconv.Atoi(cfg.Get(CfgUploadMaxSubjectLength))
+	forbiddenFiles := []string{}
+	for _, pattern := range strings.Split(cfg.Get(CfgUploadForbiddenFiles), ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern != "" {
+			forbiddenFiles = append(forbiddenFiles, pattern)
+		}
+	}
+
+	requireSigned := v.RequireSigned()
+
+	if !requireChangeID &&
+		!requireSignedOffBy &&
+		!requireSigned &&
+		!v.Sign &&
+		maxSubjectLength <= 0 &&
+		len(forbiddenFiles) == 0 {
+		return nil, nil
+	}
+
+	commits := v.Commits()
+	for i := len(commits) - 1; i >= 0; i-- {
+		commit := commits[i]
+		obj, err := p.catCommit(commit)
+		if err != nil {
+			return nil, err
+		}
+		for _, problem := range checkCommitMessage(obj.Message,
+			requireChangeID && !insertChangeID,
+			requireSignedOffBy,
+			maxSubjectLength) {
+			problems = append(problems,
+				fmt.Sprintf("commit %s: %s", commit[:7], problem))
+		}
+		if requireChangeID && insertChangeID && !reChangeID.MatchString(obj.Message) {
+			missing++
+		}
+		if !p.hasGoodSignature(commit) {
+			unsigned++
+			if requireSigned && !v.Sign {
+				problems = append(problems,
+					fmt.Sprintf("commit %s: not signed (use --sign to sign it)", commit[:7]))
+			}
+		}
+		if len(forbiddenFiles) > 0 {
+			files, err := p.changedFiles(commit)
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range files {
+				if matchForbiddenFile(file, forbiddenFiles) {
+					problems = append(problems,
+						fmt.Sprintf("commit %s: forbidden file %s", commit[:7], file))
+				}
+			}
+		}
+	}
+
+	// Signatures are removed from commits rewritten to insert Change-Id.
+	if len(problems) == 0 && missing > 0 && requireSigned && !v.Sign {
+		problems = append(problems,
+			"commits to insert Change-Id must be signed again (use --sign to sign them)")
+	}
+	if len(problems) == 0 && (missing > 0 || (v.Sign && unsigned > 0)) {
+		if err := v.rewriteCommits(commits, missing > 0); err != nil {
+			return nil, err
+		}
+	}
+	return problems, nil
+}
+
+// SignCommits signs commits of the branch which are not signed, without
+// other validations.
+func (v *ReviewableBranch) SignCommits() error {
+	v.Sign = true
+	return v.rewriteCommits(v.Commits(), false)
+}
+
+// signPayload signs payload of commit object as "git commit -S" does, by
+// program of gpg.format with key of user.signingKey, or key of committer
+// if not set, and returns the signature.
+func (v Project) signPayload(payload []byte, committer string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	format := v.gitConfig("gpg.format")
+	if format == "" {
+		format = "openpgp"
+	}
+	key := v.gitConfig("user.signingkey")
+	program := v.gitConfig("gpg." + format + ".program")
+	switch format {
+	case "openpgp":
+		if program == "" {
+			program = v.gitConfig("gpg.program")
+		}
+		if program == "" {
+			program = "gpg"
+		}
+	case "x509":
+		if program == "" {
+			program = "gpgsm"
+		}
+	case "ssh":
+		if program == "" {
+			program = "ssh-keygen"
+		}
+		return v.signPayloadBySSH(program, key, payload)
+	default:
+		return "", fmt.Errorf("unsupported gpg.format '%s'", format)
+	}
+	if key == "" {
+		key = committer
+	}
+
+	_, err := helper.RunCommand(&helper.Command{
+		Args:   []string{program, "--status-fd=2", "-bsau", key},
+		Dir:    v.WorkDir,
+		Stdin:  bytes.NewReader(payload),
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return "", errors.New(strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// signPayloadBySSH signs payload by "ssh-keygen -Y sign" in namespace
+// "git", and key is a private key file, or a literal public key (with or
+// without prefix "key::") of which private key is in ssh-agent.
+func (v Project) signPayloadBySSH(program, key string, payload []byte) (string, error) {
+	var stderr bytes.Buffer
+
+	if key == "" {
+		return "", errors.New("user.signingKey is not set for SSH signature")
+	}
+	dir, err := ioutil.TempDir("", "git-repo-sign-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	args := []string{program, "-Y", "sign", "-n", "git"}
+	if literal := strings.TrimPrefix(key, "key::"); literal != key || strings.HasPrefix(key, "ssh-") {
+		keyFile := filepath.Join(dir, "key.pub")
+		if err = ioutil.WriteFile(keyFile, []byte(literal+"\n"), 0600); err != nil {
+			return "", err
+		}
+		args = append(args, "-U", "-f", keyFile)
+	} else {
+		if strings.HasPrefix(key, "~/") {
+			if home, err := homedir.Dir(); err == nil {
+				key = filepath.Join(home, key[2:])
+			}
+		}
+		args = append(args, "-f", key)
+	}
+	file := filepath.Join(dir, "payload")
+	if err = ioutil.WriteFile(file, payload, 0600); err != nil {
+		return "", err
+	}
+	args = append(args, file)
+
+	_, err = helper.RunCommand(&helper.Command{
+		Args:   args,
+		Dir:    v.WorkDir,
+		Stdout: ioutil.Discard,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return "", errors.New(strings.TrimSpace(stderr.String()))
+	}
+	sig, err := ioutil.ReadFile(file + ".sig")
+	if err != nil {
+		return "", err
+	}
+	return string(sig), nil
+}
+
+// writeCommit writes rewritten commit object, and signs it if sign is
+// set. All headers, such as "encoding" and "mergetag", are kept. Returns
+// ID of the new commit.
+func (v Project) writeCommit(obj *commitObject, sign bool) (string, error) {
+	var stderr bytes.Buffer
+
+	if sign {
+		sig, err := v.signPayload(obj.Bytes(), obj.Committer())
+		if err != nil {
+			return "", fmt.Errorf("fail to sign commit %s: %s", obj.ID, err)
+		}
+		// Signature is saved in the last header, and continuation lines
+		// start with a space.
+		obj.Headers = append(obj.Headers,
+			"gpgsig "+strings.Replace(strings.TrimRight(sig, "\n"), "\n", "\n ", -1))
+	}
+
+	out, err := helper.RunCommand(&helper.Command{
+		Args:   []string{config.GIT, "hash-object", "-t", "commit", "-w", "--stdin"},
+		Dir:    v.WorkDir,
+		Stdin:  bytes.NewReader(obj.Bytes()),
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return "", fmt.Errorf("fail to rewrite commit %s: %s",
+			obj.ID,
+			strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// rewriteCommits rewrites commits (newest first, as returned by Commits)
+// to add missing Change-Id if insertChangeID is set, and to sign commits
+// if Sign is set, and updates the branch to the rewritten commit. Trees
+// of commits are not changed, so it is safe to rewrite a checked out
+// branch.
+func (v *ReviewableBranch) rewriteCommits(commits []string, insertChangeID bool) error {
+	var (
+		p        = v.Project
+		rewrites = make(map[string]string)
+		head     = ""
+		count    = 0
+		signed   = 0
+		action   = "insert Change-Id into"
+	)
+
+	if !insertChangeID {
+		action = "sign"
+	} else if v.Sign {
+		action = "insert Change-Id into and sign"
+	}
+	if config.IsDryRun() {
+		log.Notef("%swill %s commits of branch %s",
+			p.Prompt(),
+			action,
+			v.Branch.ShortName())
+		return nil
+	}
+
+	for i := len(commits) - 1; i >= 0; i-- {
+		obj, err := p.catCommit(commits[i])
+		if err != nil {
+			return err
+		}
+
+		changed := false
+		headers := []string{}
+		for _, h := range obj.Headers {
+			if strings.HasPrefix(h, "parent ") {
+				parent := strings.TrimPrefix(h, "parent ")
+				if newParent, ok := rewrites[parent]; ok {
+					h = "parent " + newParent
+					changed = true
+				}
+			}
+			headers = append(headers, h)
+		}
+		obj.Headers = headers
+		if insertChangeID && !reChangeID.MatchString(obj.Message) {
+			// Derive Change-Id from content of the commit.
+			changeID := fmt.Sprintf("I%x", sha1.Sum(obj.Bytes()))
+			obj.Message = addChangeID(obj.Message, changeID)
+			changed = true
+			count++
+		}
+		if v.Sign && !p.hasGoodSignature(obj.ID) {
+			changed = true
+		}
+		if !changed {
+			head = obj.ID
+			continue
+		}
+
+		// Signature is broken after rewrite.
+		headers = []string{}
+		for _, h := range obj.Headers {
+			if !strings.HasPrefix(h, "gpgsig ") && !strings.HasPrefix(h, "gpgsig-sha256 ") {
+				headers = append(headers, h)
+			}
+		}
+		obj.Headers = headers
+
+		head, err = p.writeCommit(obj, v.Sign)
+		if err != nil {
+			return err
+		}
+		rewrites[obj.ID] = head
+		if v.Sign {
+			signed++
+		}
+	}
+
+	if count == 0 && signed == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("insert Change-Id into %d commit(s)", count)
+	if count == 0 {
+		msg = fmt.Sprintf("sign %d commit(s)", signed)
+	} else if signed > 0 {
+		msg += fmt.Sprintf(", and sign %d commit(s)", signed)
+	}
+	err := p.UpdateRef(config.RefsHeads+v.Branch.ShortName(), head, msg)
+	if err != nil {
+		return err
+	}
+	log.Notef("%s%s of branch %s",
+		p.Prompt(),
+		msg,
+		v.Branch.ShortName())
+	v.Branch.Hash = head
+	return nil
+}
@@ -0,0 +1,116 @@
+package project
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckCommitMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	msg := "short subject\n\nSigned-off-by: A U Thor <author@example.com>\n" +
+		"Change-Id: I0123456789012345678901234567890123456789\n"
+	assert.Nil(checkCommitMessage(msg, true, true, 20))
+
+	msg = "a very long subject for test\n"
+	assert.Equal([]string{
+		"subject is longer than 20 characters",
+		"missing Change-Id",
+		"missing Signed-off-by",
+	}, checkCommitMessage(msg, true, true, 20))
+	assert.Nil(checkCommitMessage(msg, false, false, 0))
+}
+
+func TestAddChangeID(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("subject\n\nChange-Id: Iabc\n",
+		addChangeID("subject\n", "Iabc"))
+	assert.Equal("subject\n\nbody\n\nChange-Id: Iabc\n",
+		addChangeID("subject\n\nbody\n\n", "Iabc"))
+	assert.Equal("subject\n\nSigned-off-by: A U Thor <author@example.com>\nChange-Id: Iabc\n",
+		addChangeID("subject\n\nSigned-off-by: A U Thor <author@example.com>\n", "Iabc"))
+}
+
+func TestMatchForbiddenFile(t *testing.T) {
+	assert := assert.New(t)
+
+	patterns := []string{"*.jar", "secret/*"}
+	assert.True(matchForbiddenFile("libs/a.jar", patterns))
+	assert.True(matchForbiddenFile("secret/key", patterns))
+	assert.False(matchForbiddenFile("src/main.go", patterns))
+}
+
+func TestCommitObjectCommitter(t *testing.T) {
+	assert := assert.New(t)
+
+	obj := commitObject{
+		Headers: []string{
+			"tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904",
+			"author A U Thor <author@example.com> 1577923200 +0800",
+			"committer C O Mitter <committer@example.com> 1577923260 -0700",
+		},
+		Message: "subject\n",
+	}
+	assert.Equal("C O Mitter <committer@example.com>", obj.Committer())
+}
+
+func TestWriteSignedCommit(t *testing.T) {
+	assert := assert.New(t)
+
+	mock := helper.MockExecutor{
+		Handler: func(c *helper.Command) ([]byte, error) {
+			switch c.Args[1] {
+			case "config":
+				if c.Args[3] == "user.signingkey" {
+					return []byte("5A3D5F2E1B1C0D9E\n"), nil
+				}
+				return nil, errors.New("exit status 1")
+			case "log":
+				return []byte("B\n"), nil
+			case "--status-fd=2":
+				io.WriteString(c.Stdout, "-----BEGIN PGP SIGNATURE-----\n\niQEz\n-----END PGP SIGNATURE-----\n")
+			case "hash-object":
+				return []byte("0123456789012345678901234567890123456789\n"), nil
+			}
+			return nil, nil
+		},
+	}
+	defer helper.SetExecutor(&mock)()
+
+	p := Project{WorkDir: "/path/of/app"}
+	p.Settings = &RepoSettings{}
+	// Bad signature is not good.
+	assert.False(p.hasGoodSignature("HEAD"))
+
+	obj := commitObject{
+		ID: "abcdef0",
+		Headers: []string{
+			"tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904",
+			"author A U Thor <author@example.com> 1577923200 +0800",
+			"committer C O Mitter <committer@example.com> 1577923260 -0700",
+			"encoding ISO-8859-1",
+			"mergetag object 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n type commit",
+		},
+		Message: "subject\n",
+	}
+	commit, err := p.writeCommit(&obj, true)
+	assert.Nil(err)
+	assert.Equal("0123456789012345678901234567890123456789", commit)
+	assert.Equal([]string{
+		"git log -1 --format=%G? HEAD",
+		"git config --get gpg.format",
+		"git config --get user.signingkey",
+		"git config --get gpg.openpgp.program",
+		"git config --get gpg.program",
+		"gpg --status-fd=2 -bsau 5A3D5F2E1B1C0D9E",
+		"git hash-object -t commit -w --stdin",
+	}, mock.CommandLines())
+	assert.Equal("gpgsig -----BEGIN PGP SIGNATURE-----\n \n iQEz\n -----END PGP SIGNATURE-----",
+		obj.Headers[len(obj.Headers)-1])
+	assert.Equal("encoding ISO-8859-1", obj.Headers[3])
+}
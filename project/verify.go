@@ -0,0 +1,201 @@
+package project
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
+)
+
+// manifestSigNamespace is namespace of SSH signatures of manifest files,
+// which are signed by "ssh-keygen -Y sign -n git-repo".
+const manifestSigNamespace = "git-repo"
+
+var (
+	// reGPGValidSig matches fingerprint of key in GPG status output.
+	reGPGValidSig = regexp.MustCompile(`(?m)^\[GNUPG:\] VALIDSIG ([0-9A-Fa-f]+)`)
+	// reSSHGoodSig matches fingerprint of key in output of ssh-keygen.
+	reSSHGoodSig = regexp.MustCompile(`(?m)^Good "[^"]*" signature for .* key (SHA256:\S+)`)
+)
+
+// ManifestTrust is trust configuration of workspace, to verify signatures
+// of manifests before sync.
+type ManifestTrust struct {
+	// Verify indicates signatures of manifests must be verified.
+	Verify bool
+	// Keys are trusted GPG key IDs or fingerprints, and fingerprints of
+	// SSH keys (such as "SHA256:..."). Any valid signature is trusted if
+	// it is empty.
+	Keys []string
+	// AllowedSigners is allowed signers file to verify SSH signatures.
+	AllowedSigners string
+}
+
+// ManifestTrust returns trust configuration in settings of workspace.
+func (v ManifestProject) ManifestTrust() *ManifestTrust {
+	s := v.Settings
+	trust := ManifestTrust{
+		Verify: s.ManifestVerify,
+	}
+	keys := strings.FieldsFunc(s.ManifestKeys, func(c rune) bool {
+		return c == ',' || unicode.IsSpace(c)
+	})
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "SHA256:") {
+			key = strings.ToUpper(strings.TrimPrefix(key, "0x"))
+		}
+		trust.Keys = append(trust.Keys, key)
+	}
+	if signers := s.ManifestSigners; signers != "" {
+		if !filepath.IsAbs(signers) {
+			signers = filepath.Join(v.TopDir(), signers)
+		}
+		trust.AllowedSigners = signers
+	}
+	return &trust
+}
+
+// trusted finds fingerprints of keys of good signatures in output of
+// verification, and returns the one which is trusted.
+func (v ManifestTrust) trusted(output string) (string, error) {
+	fingerprints := []string{}
+	for _, m := range reGPGValidSig.FindAllStringSubmatch(output, -1) {
+		fingerprints = append(fingerprints, strings.ToUpper(m[1]))
+	}
+	for _, m := range reSSHGoodSig.FindAllStringSubmatch(output, -1) {
+		fingerprints = append(fingerprints, m[1])
+	}
+	if len(fingerprints) == 0 {
+		return "", errors.New("no good signature found")
+	}
+	if len(v.Keys) == 0 {
+		return fingerprints[0], nil
+	}
+	for _, fpr := range fingerprints {
+		for _, key := range v.Keys {
+			if fpr == key ||
+				(!strings.HasPrefix(key, "SHA256:") && strings.HasSuffix(fpr, key)) {
+				return fpr, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("signed by untrusted key %s", fingerprints[0])
+}
+
+// runVerify runs command to verify signature, and returns its output on
+// stdout and stderr.
+func runVerify(dir string, stdin io.Reader, args ...string) (string, error) {
+	var out bytes.Buffer
+
+	_, err := helper.RunCommand(&helper.Command{
+		Args:   args,
+		Dir:    dir,
+		Stdin:  stdin,
+		Stdout: &out,
+		Stderr: &out,
+	})
+	return out.String(), err
+}
+
+// VerifyCommit verifies GPG or SSH signature of commit of manifest project
+// by "git verify-commit", and returns fingerprint of the signing key.
+func (v ManifestProject) VerifyCommit(commit string, trust *ManifestTrust) (string, error) {
+	args := []string{config.GIT}
+	if trust.AllowedSigners != "" {
+		args = append(args, "-c", "gpg.ssh.allowedSignersFile="+trust.AllowedSigners)
+	}
+	args = append(args, "verify-commit", "--raw", commit)
+	out, err := runVerify(v.RepoDir(), nil, args...)
+	if err != nil {
+		return "", fmt.Errorf("bad signature of manifest commit %s: %s",
+			commit, strings.TrimSpace(out))
+	}
+	fpr, err := trust.trusted(out)
+	if err != nil {
+		return "", fmt.Errorf("manifest commit %s is %s", commit, err)
+	}
+	return fpr, nil
+}
+
+// VerifyRevision verifies signature of revision of manifest project, such
+// as HEAD or the commit to check out, if signatures of manifests must be
+// verified in workspace.
+func (v ManifestProject) VerifyRevision(revision string) error {
+	trust := v.ManifestTrust()
+	if !trust.Verify {
+		return nil
+	}
+	commit, err := v.ResolveRevision(revision)
+	if err != nil || commit == "" {
+		return fmt.Errorf("cannot verify signature of manifest: bad revision '%s'", revision)
+	}
+	fpr, err := v.VerifyCommit(commit, trust)
+	if err != nil {
+		return err
+	}
+	log.Debugf("manifest commit %s is signed by %s", commit, fpr)
+	return nil
+}
+
+// VerifyFile verifies detached signature of manifest file, which is
+// "<file>.asc" signed by GPG, or "<file>.sig" signed by SSH key in
+// namespace "git-repo", and returns fingerprint of the signing key.
+func (v ManifestTrust) VerifyFile(file string) (string, error) {
+	var (
+		args  []string
+		stdin io.Reader
+	)
+
+	gpgSig := file + ".asc"
+	sshSig := file + ".sig"
+	if _, err := os.Stat(gpgSig); err == nil {
+		args = []string{"gpg", "--status-fd=1", "--verify", gpgSig, file}
+	} else if _, err = os.Stat(sshSig); err == nil {
+		if v.AllowedSigners == "" {
+			return "", fmt.Errorf("cannot verify SSH signature of %s: %s is not set",
+				file, config.CfgRepoManifestSigners)
+		}
+	} else {
+		return "", fmt.Errorf("no signature (%s or %s) of manifest %s",
+			filepath.Base(gpgSig), filepath.Base(sshSig), file)
+	}
+	if config.IsDryRun() {
+		log.Notef("will verify signature of manifest %s", file)
+		return "", nil
+	}
+
+	if args == nil {
+		out, err := runVerify("", nil, "ssh-keygen", "-Y", "find-principals",
+			"-f", v.AllowedSigners, "-s", sshSig)
+		principal := strings.SplitN(strings.TrimSpace(out), "\n", 2)[0]
+		if err != nil || principal == "" {
+			return "", fmt.Errorf("signer of manifest %s is not allowed", file)
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		stdin = f
+		args = []string{"ssh-keygen", "-Y", "verify", "-f", v.AllowedSigners,
+			"-I", principal, "-n", manifestSigNamespace, "-s", sshSig}
+	}
+	out, err := runVerify("", stdin, args...)
+	if err != nil {
+		return "", fmt.Errorf("bad signature of manifest %s: %s", file, strings.TrimSpace(out))
+	}
+	fpr, err := v.trusted(out)
+	if err != nil {
+		return "", fmt.Errorf("manifest %s is %s", file, err)
+	}
+	return fpr, nil
+}
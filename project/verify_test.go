@@ -0,0 +1,157 @@
+package project
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestTrusted(t *testing.T) {
+	assert := assert.New(t)
+
+	gpgOutput := "[GNUPG:] NEWSIG\n" +
+		"[GNUPG:] GOODSIG 5A3D5F2E1B1C0D9E Example User <user@example.com>\n" +
+		"[GNUPG:] VALIDSIG 4D8A1F3C2B0E9D7A6F5E4D3C5A3D5F2E1B1C0D9E 2020-01-02 1577923200 0 4 0 1 8 00 4D8A1F3C2B0E9D7A6F5E4D3C5A3D5F2E1B1C0D9E\n"
+	sshOutput := `Good "git" signature for user@example.com with ED25519 key SHA256:q4nPi4ZKHbD2XyvYUBmE3YcxsEpp5BR5nrvxHh7w3Jc` + "\n"
+
+	trust := ManifestTrust{}
+	_, err := trust.trusted("gpg: Signature made Thu Jan  2 08:00:00 2020\n")
+	assert.Equal("no good signature found", err.Error())
+	fpr, err := trust.trusted(gpgOutput)
+	assert.Nil(err)
+	assert.Equal("4D8A1F3C2B0E9D7A6F5E4D3C5A3D5F2E1B1C0D9E", fpr)
+	fpr, err = trust.trusted(sshOutput)
+	assert.Nil(err)
+	assert.Equal("SHA256:q4nPi4ZKHbD2XyvYUBmE3YcxsEpp5BR5nrvxHh7w3Jc", fpr)
+
+	// Long key ID is suffix of fingerprint.
+	trust.Keys = []string{"5A3D5F2E1B1C0D9E"}
+	_, err = trust.trusted(gpgOutput)
+	assert.Nil(err)
+	_, err = trust.trusted(sshOutput)
+	assert.Equal("signed by untrusted key SHA256:q4nPi4ZKHbD2XyvYUBmE3YcxsEpp5BR5nrvxHh7w3Jc", err.Error())
+
+	trust.Keys = []string{"SHA256:q4nPi4ZKHbD2XyvYUBmE3YcxsEpp5BR5nrvxHh7w3Jc"}
+	_, err = trust.trusted(sshOutput)
+	assert.Nil(err)
+	_, err = trust.trusted(gpgOutput)
+	assert.NotNil(err)
+}
+
+func TestManifestVerifyCommit(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-verify-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	output := "[GNUPG:] VALIDSIG 4D8A1F3C2B0E9D7A6F5E4D3C5A3D5F2E1B1C0D9E\n"
+	failed := false
+	mock := helper.MockExecutor{
+		Handler: func(c *helper.Command) ([]byte, error) {
+			if failed {
+				io.WriteString(c.Stderr, "error: no signature found\n")
+				return nil, errors.New("exit status 1")
+			}
+			io.WriteString(c.Stderr, output)
+			return nil, nil
+		},
+	}
+	defer helper.SetExecutor(&mock)()
+
+	mp := ManifestProject{}
+	mp.Settings = &RepoSettings{TopDir: tmpdir}
+	mp.GitDir = filepath.Join(tmpdir, ".repo", "manifests.git")
+	assert.Nil(os.MkdirAll(mp.GitDir, 0755))
+
+	trust := mp.ManifestTrust()
+	assert.False(trust.Verify)
+
+	// Global git config is used before manifest project is configured.
+	config.GitDefaultConfig.Set(config.CfgRepoManifestVerify, "true")
+	mp.ReadSettings()
+	trust = mp.ManifestTrust()
+	config.GitDefaultConfig.Unset(config.CfgRepoManifestVerify)
+	assert.True(trust.Verify)
+
+	cfg := mp.Config()
+	cfg.Set(config.CfgRepoManifestVerify, "true")
+	cfg.Set(config.CfgRepoManifestKeys, "0x5a3d5f2e1b1c0d9e, SHA256:q4nPi4ZKHbD2XyvYUBmE3YcxsEpp5BR5nrvxHh7w3Jc")
+	cfg.Set(config.CfgRepoManifestSigners, "allowed_signers")
+	assert.Nil(mp.SaveConfig(cfg))
+	mp.ReadSettings()
+	trust = mp.ManifestTrust()
+	assert.True(trust.Verify)
+	assert.Equal([]string{
+		"5A3D5F2E1B1C0D9E",
+		"SHA256:q4nPi4ZKHbD2XyvYUBmE3YcxsEpp5BR5nrvxHh7w3Jc",
+	}, trust.Keys)
+	assert.Equal(filepath.Join(tmpdir, "allowed_signers"), trust.AllowedSigners)
+
+	fpr, err := mp.VerifyCommit("HEAD", trust)
+	assert.Nil(err)
+	assert.Equal("4D8A1F3C2B0E9D7A6F5E4D3C5A3D5F2E1B1C0D9E", fpr)
+	assert.Equal([]string{
+		"git -c gpg.ssh.allowedSignersFile=" + trust.AllowedSigners + " verify-commit --raw HEAD",
+	}, mock.CommandLines())
+
+	output = "[GNUPG:] VALIDSIG 0123456789ABCDEF0123456789ABCDEF01234567\n"
+	_, err = mp.VerifyCommit("HEAD", trust)
+	assert.Equal("manifest commit HEAD is signed by untrusted key 0123456789ABCDEF0123456789ABCDEF01234567", err.Error())
+
+	failed = true
+	_, err = mp.VerifyCommit("HEAD", trust)
+	assert.Equal("bad signature of manifest commit HEAD: error: no signature found", err.Error())
+}
+
+func TestManifestVerifyFile(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-verify-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	mock := helper.MockExecutor{
+		Handler: func(c *helper.Command) ([]byte, error) {
+			switch c.Args[2] {
+			case "find-principals":
+				io.WriteString(c.Stdout, "user@example.com\n")
+			case "verify":
+				io.WriteString(c.Stdout, `Good "git-repo" signature for user@example.com with ED25519 key SHA256:q4nPi4ZKHbD2XyvYUBmE3YcxsEpp5BR5nrvxHh7w3Jc`+"\n")
+			}
+			return nil, nil
+		},
+	}
+	defer helper.SetExecutor(&mock)()
+
+	file := filepath.Join(tmpdir, "snapshot.xml")
+	assert.Nil(ioutil.WriteFile(file, []byte("<manifest/>\n"), 0644))
+
+	trust := ManifestTrust{Verify: true}
+	_, err = trust.VerifyFile(file)
+	assert.Equal("no signature (snapshot.xml.asc or snapshot.xml.sig) of manifest "+file, err.Error())
+
+	assert.Nil(ioutil.WriteFile(file+".sig", []byte("signature"), 0644))
+	_, err = trust.VerifyFile(file)
+	assert.Equal("cannot verify SSH signature of "+file+": repo.manifestsigners is not set", err.Error())
+
+	trust.AllowedSigners = filepath.Join(tmpdir, "allowed_signers")
+	fpr, err := trust.VerifyFile(file)
+	assert.Nil(err)
+	assert.Equal("SHA256:q4nPi4ZKHbD2XyvYUBmE3YcxsEpp5BR5nrvxHh7w3Jc", fpr)
+	assert.Equal([]string{
+		"ssh-keygen -Y find-principals -f " + trust.AllowedSigners + " -s " + file + ".sig",
+		"ssh-keygen -Y verify -f " + trust.AllowedSigners + " -I user@example.com -n git-repo -s " + file + ".sig",
+	}, mock.CommandLines())
+}
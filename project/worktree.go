@@ -0,0 +1,393 @@
+package project
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/path"
+)
+
+// IsWorktree indicates project is checked out by git worktree, and the
+// shared object store is the main repository.
+func (v Project) IsWorktree() bool {
+	return v.Settings != nil &&
+		v.Settings.Worktree &&
+		!v.Settings.Mirror &&
+		!v.IsMetaProject()
+}
+
+// legacyGitDir returns gitdir of project in legacy layout.
+func (v Project) legacyGitDir() string {
+	return filepath.Join(v.TopDir(), config.DotRepo, config.Projects, v.Path+".git")
+}
+
+// WorktreeGitDir returns gitdir of a worktree added by git worktree, or
+// empty string if workDir is not a worktree added by git worktree.
+func WorktreeGitDir(workDir string) string {
+	dir := readGitDirFile(filepath.Join(workDir, ".git"))
+	if dir == "" || !path.IsFile(filepath.Join(dir, "commondir")) {
+		return ""
+	}
+	return dir
+}
+
+// prepareWorktree adds worktree of project by git worktree, or migrates
+// worktree of legacy layout, whose gitdir is in ".repo/projects/".
+func (v *Project) prepareWorktree() error {
+	var (
+		err     error
+		rev     string
+		dotGit  = filepath.Join(v.WorkDir, ".git")
+		legacy  = ""
+		renames map[string]string
+	)
+
+	if path.Exist(dotGit) {
+		dir := readGitDirFile(dotGit)
+		if dir == "" || !samePath(dir, v.legacyGitDir()) {
+			return nil
+		}
+		legacy = dir
+		out, err := helper.RunCommand(&helper.Command{
+			Args: []string{GIT, "rev-parse", "--verify", "HEAD"},
+			Dir:  legacy,
+		})
+		if err != nil {
+			return fmt.Errorf("fail to migrate '%s', invalid HEAD: %s", v.WorkDir, err)
+		}
+		rev = strings.TrimSpace(string(out))
+		log.Notef("%smigrate '%s' to git worktree", v.Prompt(), v.WorkDir)
+		renames, err = v.migrateRefs(legacy)
+		if err != nil {
+			return fmt.Errorf("fail to migrate refs of '%s': %s", legacy, err)
+		}
+	} else {
+		if v.Revision == "" {
+			return nil
+		}
+		rev, err = v.ResolveRemoteTracking(v.Revision)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Worktree of project may have files, such as nested projects, which
+	// git worktree refuses to add to. Add worktree in a temporary dir,
+	// and then move its ".git" file to worktree of project.
+	tmpDir, err := ioutil.TempDir(filepath.Join(v.TopDir(), config.DotRepo), "worktree-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	tmpWorkDir := filepath.Join(tmpDir, strings.Replace(v.Path, "/", "_", -1))
+	err = executeCommandIn(v.GitDir, []string{
+		GIT,
+		"worktree",
+		"add",
+		"--no-checkout",
+		"--detach",
+		"--lock",
+		tmpWorkDir,
+		rev,
+	})
+	if err != nil {
+		return fmt.Errorf("fail to add worktree for %s: %s", v.Name, err)
+	}
+	gitDir := readGitDirFile(filepath.Join(tmpWorkDir, ".git"))
+	if gitDir == "" {
+		return fmt.Errorf("fail to add worktree for %s: no gitdir found", v.Name)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(gitDir, "gitdir"), []byte(dotGit+"\n"), 0644)
+	if err != nil {
+		return err
+	}
+	relDir, err := filepath.Rel(v.WorkDir, gitDir)
+	if err != nil {
+		relDir = gitDir
+	}
+	err = ioutil.WriteFile(dotGit, []byte("gitdir: "+relDir+"\n"), 0644)
+	if err != nil {
+		return fmt.Errorf("fail to create gitdir for %s: %s", v.Name, err)
+	}
+
+	if legacy == "" {
+		// Checkout files of fresh worktree, and do not overwrite
+		// untracked files.
+		return executeCommandIn(v.WorkDir, []string{GIT, "read-tree", "-m", "-u", "HEAD"})
+	}
+
+	// Keep index and current branch of legacy worktree.
+	head, err := ioutil.ReadFile(filepath.Join(legacy, "HEAD"))
+	if err == nil && strings.HasPrefix(string(head), "ref: ") {
+		ref := strings.TrimSpace(strings.TrimPrefix(string(head), "ref: "))
+		if target, ok := renames[strings.TrimPrefix(ref, config.RefsHeads)]; ok {
+			head = []byte("ref: " + config.RefsHeads + target + "\n")
+		}
+		err = ioutil.WriteFile(filepath.Join(gitDir, "HEAD"), head, 0644)
+	}
+	if err != nil {
+		return err
+	}
+	if path.IsFile(filepath.Join(legacy, "index")) {
+		err = os.Rename(filepath.Join(legacy, "index"), filepath.Join(gitDir, "index"))
+		if err != nil {
+			return err
+		}
+	}
+	// Reflog of HEAD belongs to the worktree.
+	err = copyReflog(legacy, "HEAD", gitDir, "HEAD")
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(legacy)
+}
+
+// refStash is the ref of git stash, whose entries are kept in its reflog.
+const refStash = "refs/stash"
+
+// copyReflog copies reflog of ref in gitDir to reflog of target in
+// targetGitDir. Nothing is copied if ref has no reflog.
+func copyReflog(gitDir, ref, targetGitDir, target string) error {
+	data, err := ioutil.ReadFile(filepath.Join(gitDir, "logs", ref))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	file := filepath.Join(targetGitDir, "logs", target)
+	err = os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// migrateStash moves entries of stash in legacy gitdir onto the stash of
+// the main repository, which is shared by all worktrees.
+func (v *Project) migrateStash(legacy string) error {
+	data, err := ioutil.ReadFile(filepath.Join(legacy, "logs", refStash))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	type stashEntry struct {
+		commit  string
+		message string
+	}
+	entries := []stashEntry{}
+	for _, line := range strings.Split(string(data), "\n") {
+		// Line of reflog: "<old> <new> <ident> <time> <tz>\t<message>".
+		items := strings.SplitN(line, "\t", 2)
+		fields := strings.Fields(items[0])
+		if len(items) != 2 || len(fields) < 2 {
+			continue
+		}
+		entries = append(entries, stashEntry{fields[1], items[1]})
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	// Commits of stash may be only in the object store of legacy gitdir,
+	// push them to the main repository with temporary refs.
+	tmpRefs := []string{}
+	args := []string{GIT, "push", "-q", v.GitDir}
+	for i, entry := range entries {
+		ref := fmt.Sprintf("refs/migrate-stash/%d", i)
+		tmpRefs = append(tmpRefs, ref)
+		args = append(args, entry.commit+":"+ref)
+	}
+	err = executeCommandIn(legacy, args)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, ref := range tmpRefs {
+			executeCommandIn(v.GitDir, []string{GIT, "update-ref", "-d", ref})
+		}
+	}()
+
+	// Entries in reflog are from the oldest to the newest.
+	for _, entry := range entries {
+		err = executeCommandIn(v.GitDir, []string{
+			GIT,
+			"update-ref",
+			"--create-reflog",
+			"-m",
+			entry.message,
+			refStash,
+			entry.commit,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listRefs returns refs and their commits under prefix in gitDir, with
+// prefix stripped from names of refs.
+func listRefs(gitDir, prefix string) (map[string]string, error) {
+	out, err := helper.RunCommand(&helper.Command{
+		Args: []string{GIT, "for-each-ref", "--format=%(objectname) %(refname)", prefix},
+		Dir:  gitDir,
+	})
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		items := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(items) != 2 {
+			continue
+		}
+		refs[strings.TrimPrefix(items[1], prefix)] = items[0]
+	}
+	return refs, nil
+}
+
+// migrateRefs copies refs and tracking settings of branches in legacy
+// gitdir to the main repository of git worktree. Projects sharing the
+// main repository may have branches with the same name, and a branch
+// which already exists in the main repository is renamed with path of
+// project as suffix. Returns the renamed branches.
+func (v *Project) migrateRefs(legacy string) (map[string]string, error) {
+	// Projects sharing the main repository may be migrated at the same
+	// time, and they must not see branches of each other half-copied.
+	lock := helper.NewLockFile(filepath.Join(v.GitDir, "migrate.lock"))
+	if err := lock.Lock(true); err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	// Object store of legacy layout has no remote settings.
+	if v.ObjectsRepository().GitConfigRemoteURL(v.RemoteName) == "" {
+		err := v.GitInit()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	existing, err := listRefs(v.GitDir, config.RefsHeads)
+	if err != nil {
+		return nil, err
+	}
+	branches, err := listRefs(legacy, config.RefsHeads)
+	if err != nil {
+		return nil, err
+	}
+	published, err := listRefs(legacy, config.RefsPub)
+	if err != nil {
+		return nil, err
+	}
+
+	renames := make(map[string]string)
+	refspecs := []string{}
+	for branch := range branches {
+		target := branch
+		if _, ok := existing[branch]; ok {
+			target = branch + "-" + strings.Replace(v.Path, "/", "-", -1)
+			if _, ok := existing[target]; ok {
+				return nil, fmt.Errorf("branch '%s' exists in other worktree, and cannot rename to '%s'",
+					branch, target)
+			}
+			log.Warnf("%sbranch '%s' exists in other worktree, renamed to '%s'",
+				v.Prompt(), branch, target)
+			renames[branch] = target
+		}
+		refspecs = append(refspecs, config.RefsHeads+branch+":"+config.RefsHeads+target)
+		if _, ok := published[branch]; ok {
+			refspecs = append(refspecs, config.RefsPub+branch+":"+config.RefsPub+target)
+		}
+	}
+
+	// Other refs, such as notes, are copied as they are. Stash is
+	// migrated with its reflog by migrateStash.
+	others, err := listRefs(legacy, "refs/")
+	if err != nil {
+		return nil, err
+	}
+	for ref := range others {
+		ref = "refs/" + ref
+		if ref == refStash ||
+			strings.HasPrefix(ref, config.RefsHeads) ||
+			strings.HasPrefix(ref, config.RefsPub) ||
+			strings.HasPrefix(ref, config.RefsRemotes) ||
+			strings.HasPrefix(ref, config.RefsTags) {
+			continue
+		}
+		refspecs = append(refspecs, ref+":"+ref)
+	}
+
+	args := []string{GIT, "push", "-q", v.GitDir}
+	args = append(args, refspecs...)
+	args = append(args,
+		"refs/remotes/*:refs/remotes/*",
+		"refs/tags/*:refs/tags/*",
+	)
+	err = executeCommandIn(legacy, args)
+	if err != nil {
+		return nil, err
+	}
+
+	for branch := range branches {
+		target := branch
+		if name, ok := renames[branch]; ok {
+			target = name
+		}
+		err = copyReflog(legacy, config.RefsHeads+branch, v.GitDir, config.RefsHeads+target)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = v.migrateStash(legacy)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := helper.RunCommand(&helper.Command{
+		Args: []string{GIT, "config", "-f", filepath.Join(legacy, "config"), "--get-regexp", `^branch\.`},
+	})
+	if err != nil {
+		// No branch settings.
+		return renames, nil
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		kv := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := kv[0]
+		// Key is in the form of "branch.<name>.<var>", and name of
+		// branch may have dots.
+		if pos := strings.LastIndex(key, "."); pos > len("branch.") {
+			if target, ok := renames[key[len("branch."):pos]]; ok {
+				key = "branch." + target + key[pos:]
+			}
+		}
+		err = executeCommandIn("", []string{
+			GIT,
+			"config",
+			"-f",
+			filepath.Join(v.GitDir, "config"),
+			key,
+			kv[1],
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return renames, nil
+}
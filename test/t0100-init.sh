@@ -26,7 +26,19 @@ test_expect_success "check installed hooks" '
 	EOF
 	head -1 .git-repo/hooks/commit-msg >actual &&
 	test_cmp expect actual &&
-	test -x .git-repo/hooks/commit-msg
+	test -x .git-repo/hooks/commit-msg &&
+	head -1 .git-repo/hooks/pre-auto-gc >actual &&
+	test_cmp expect actual &&
+	test -x .git-repo/hooks/pre-auto-gc
+'
+
+test_expect_success "outdated hooks are updated" '
+	echo 1 >.git-repo/hooks/VERSION &&
+	echo "#!/bin/sh" >.git-repo/hooks/pre-auto-gc &&
+	git-repo version >/dev/null &&
+	echo 2 >expect &&
+	test_cmp expect .git-repo/hooks/VERSION &&
+	grep "Auto packing deferred" .git-repo/hooks/pre-auto-gc
 '
 
 test_expect_success "manifest points to default.xml" '
@@ -278,4 +290,70 @@ test_expect_success "check default branch and tracking branch" '
 	)
 '
 
+test_expect_success "switch manifest url" '
+	git clone --bare "${REPO_TEST_REPOSITORIES}/hello/manifests" manifests-mirror.git &&
+	(
+		cd work &&
+		git-repo init -u "file://${HOME}/manifests-mirror.git" &&
+		git -C .repo/manifests config remote.origin.url >actual &&
+		echo "file://${HOME}/manifests-mirror.git" >expect &&
+		test_cmp expect actual &&
+		git -C .repo/manifests config manifest.name >actual &&
+		echo default.xml >expect &&
+		test_cmp expect actual
+	)
+'
+
+test_expect_success "show version of manifest repository" '
+	(
+		cd work &&
+		git-repo version >out &&
+		grep "^manifest" out >actual &&
+		cat >expect <<-EOF &&
+		manifest url file://${HOME}/manifests-mirror.git
+		manifest revision master ($(git -C .repo/manifests rev-parse --short=7 HEAD))
+		EOF
+		test_cmp expect actual
+	)
+'
+
+test_expect_success "settings saved in .repo/config.json" '
+	(
+		cd work &&
+		git-repo init -u "file://${HOME}/manifests-mirror.git" \
+			-g all -p linux --depth 1 &&
+		cat .repo/config.json
+	) >actual &&
+	cat >expect <<-EOF &&
+	{
+	  "manifest_name": "default.xml",
+	  "groups": "all,platform-linux",
+	  "platform": "linux",
+	  "depth": 1
+	}
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "jobs of workspace in git config saved in settings" '
+	(
+		cd work &&
+		git -C .repo/manifests config repo.jobs 3 &&
+		git-repo init -u "file://${HOME}/manifests-mirror.git" \
+			--partial-clone-filter blob:none &&
+		cat .repo/config.json
+	) >actual &&
+	cat >expect <<-EOF &&
+	{
+	  "manifest_name": "default.xml",
+	  "groups": "all,platform-linux",
+	  "platform": "linux",
+	  "depth": 1,
+	  "partial_clone_filter": "blob:none",
+	  "jobs": 3
+	}
+	EOF
+	test_cmp expect actual
+'
+
 test_done
@@ -0,0 +1,97 @@
+#!/bin/sh
+
+test_description="switch manifest branch with safety check"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url -g all -b Maint &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	)
+'
+
+test_expect_success "switch without local work" '
+	(
+		cd work &&
+		git-repo switch-manifest master &&
+		git -C .repo/manifests log -1 --pretty="%s" &&
+		git-repo switch-manifest Maint &&
+		git -C .repo/manifests log -1 --pretty="%s"
+	) >out 2>&1 &&
+	grep -v -e "^NOTE" -e "^HEAD" out >actual &&
+	cat >expect<<-EOF &&
+	Version 2.0
+	Version 1.0
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "create local work" '
+	(
+		cd work &&
+		git-repo start --all my/topic &&
+		cd main &&
+		echo hack >topic.txt &&
+		git add topic.txt &&
+		test_tick &&
+		git commit -q -m "topic: new file"
+	)
+'
+
+test_expect_success "refuse to switch if local work would be stranded" '
+	(
+		cd work &&
+		test_must_fail git-repo init -b master
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	NOTE: switch manifest branch from Maint to master
+	NOTE:   main: revision Maint -> master
+	NOTE:   projects/app1: revision Maint -> master
+	NOTE:   projects/app1/module1: revision refs/tags/v0.2.0 -> refs/tags/v1.0.0
+	NOTE:   projects/app2: revision Maint -> master
+	WARNING: main: branch my/topic has 1 unpublished commit(s)
+	Error: local work would be stranded, use --force to switch manifest branch
+	EOF
+	test_cmp expect actual &&
+	(
+		cd work/.repo/manifests &&
+		git log -1 --pretty="%s"
+	) >actual &&
+	cat >expect<<-EOF &&
+	Version 1.0
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "switch with --force" '
+	(
+		cd work &&
+		git-repo switch-manifest --force master
+	) >out 2>&1 &&
+	grep -e "^WARNING" -e "^Error" out >actual &&
+	cat >expect<<-EOF &&
+	WARNING: main: branch my/topic has 1 unpublished commit(s)
+	EOF
+	test_cmp expect actual &&
+	(
+		cd work/.repo/manifests &&
+		git log -1 --pretty="%s"
+	) >actual &&
+	cat >expect<<-EOF &&
+	Version 2.0
+	EOF
+	test_cmp expect actual
+'
+
+test_done
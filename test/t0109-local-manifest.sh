@@ -0,0 +1,163 @@
+#!/bin/sh
+
+test_description="manage local manifests"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url -g all -b Maint
+	)
+'
+
+test_expect_success "no local manifests" '
+	(
+		cd work &&
+		git-repo local-manifest list
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	NOTE: no local manifests
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "pin revision of project" '
+	(
+		cd work &&
+		git-repo local-manifest add main -r refs/tags/v1.0 &&
+		git-repo local-manifest add drivers/driver-1 --drop &&
+		git-repo local-manifest list
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	NOTE: local manifest local.xml is updated, run '"'"'git repo sync'"'"' to apply
+	NOTE: local manifest local.xml is updated, run '"'"'git repo sync'"'"' to apply
+	local.xml:
+	  extend-project main path=main revision=refs/tags/v1.0
+	  remove-project drivers/driver1
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "add new project" '
+	(
+		cd work &&
+		git-repo local-manifest add -f extra tools/extra --path extra &&
+		git-repo local-manifest list
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	NOTE: local manifest extra.xml is updated, run '"'"'git repo sync'"'"' to apply
+	extra.xml:
+	  project tools/extra path=extra
+	local.xml:
+	  extend-project main path=main revision=refs/tags/v1.0
+	  remove-project drivers/driver1
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "invalid changes are rejected" '
+	(
+		cd work &&
+		test_must_fail git-repo local-manifest add unknown &&
+		test_must_fail git-repo local-manifest add main --path main &&
+		test_must_fail git-repo local-manifest add tools/another --path extra &&
+		test_must_fail git-repo local-manifest add tools/another --path another --remote bad
+	) >out 2>&1 &&
+	grep "^Error" out >actual &&
+	cat >expect<<-EOF &&
+	Error: cannot find project '"'"'unknown'"'"' in manifest, use --path to add a new project
+	Error: project '"'"'main'"'"' already exists in manifest
+	EOF
+	head -2 actual >actual2 &&
+	test_cmp expect actual2 &&
+	test $(grep -c "^Error" out) -eq 4
+'
+
+test_expect_success "remove entries and files" '
+	(
+		cd work &&
+		git-repo local-manifest remove main drivers/driver1 &&
+		git-repo local-manifest remove extra.xml &&
+		test_must_fail git-repo local-manifest remove main &&
+		git-repo local-manifest list
+	) >out 2>&1 &&
+	head -4 out >actual &&
+	cat >expect<<-EOF &&
+	NOTE: remove 1 entry(s) of '"'"'main'"'"' from local.xml
+	NOTE: remove 1 entry(s) of '"'"'drivers/driver1'"'"' from local.xml
+	NOTE: local manifest extra.xml is removed
+	Error: cannot find '"'"'main'"'"' in local manifests
+	EOF
+	test_cmp expect actual &&
+	test ! -e work/.repo/local_manifests/local.xml &&
+	tail -1 out >actual &&
+	cat >expect<<-EOF &&
+	NOTE: no local manifests
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "sync" '
+	(
+		cd work &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	) >out 2>&1
+'
+
+test_expect_success "freeze projects of group" '
+	(
+		cd work &&
+		git-repo local-manifest freeze -g drivers &&
+		git-repo local-manifest list
+	) >actual 2>&1 &&
+	driver1=$(git -C work/drivers/driver-1 rev-parse HEAD) &&
+	driver2=$(git -C work/drivers/driver-2 rev-parse HEAD) &&
+	cat >expect<<-EOF &&
+	NOTE: 2 project(s) are pinned in local manifest frozen.xml
+	frozen.xml:
+	  extend-project drivers/driver1 path=drivers/driver-1 revision=$driver1 upstream=Maint
+	  extend-project drivers/driver2 path=drivers/driver-2 revision=$driver2 upstream=Maint
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "pinned projects keep revisions, others float" '
+	(
+		cd work &&
+		git-repo manifest
+	) >out &&
+	driver1=$(git -C work/drivers/driver-1 rev-parse HEAD) &&
+	grep "<project name=\"\(drivers/driver1\|main\)\"" out >actual &&
+	cat >expect<<-EOF &&
+	  <project name="drivers/driver1" path="drivers/driver-1" remote="driver" revision="$driver1" upstream="Maint" groups="drivers"/>
+	  <project name="main" groups="app">
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "freeze without groups or projects" '
+	(
+		cd work &&
+		test_must_fail git-repo local-manifest freeze
+	)
+'
+
+test_expect_success "unfreeze" '
+	(
+		cd work &&
+		git-repo local-manifest remove frozen.xml
+	) &&
+	test ! -e work/.repo/local_manifests/frozen.xml
+'
+
+test_done
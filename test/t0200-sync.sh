@@ -145,6 +145,16 @@ test_expect_success "git-repo sync (-l), checkouted" '
 	)
 '
 
+test_expect_success "default hooks are installed for all projects" '
+	(
+		cd work &&
+		test -L .repo/project-objects/main.git/hooks/pre-auto-gc &&
+		test -L .repo/project-objects/project1.git/hooks/pre-auto-gc &&
+		test -L .repo/project-objects/project2.git/hooks/pre-auto-gc &&
+		test -x .repo/projects/main.git/hooks/pre-auto-gc
+	)
+'
+
 test_expect_success "check .repo/project.list" '
 	(
 		cd work &&
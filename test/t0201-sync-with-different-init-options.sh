@@ -240,4 +240,20 @@ test_expect_success "obsolete project driver-2 will be deleted" '
 	)
 '
 
+test_expect_success "git-repo init --partial-clone-filter and sync" '
+	mkdir work-partial &&
+	(
+		cd work-partial &&
+		git-repo init -u $manifest_url --partial-clone-filter blob:none &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" &&
+		grep "\"partial_clone_filter\": \"blob:none\"" .repo/config.json &&
+		git -C main config --get-regexp "^remote\..*\.partialclonefilter" >actual &&
+		grep "blob:none" actual &&
+		test_must_fail git -C .repo/manifests config --get-regexp "^remote\..*\.promisor"
+	)
+'
+
 test_done
@@ -73,7 +73,6 @@ test_expect_success "git-repo sync to master branch, do not overwrite edit files
 		cat >expect <<-EOF &&
 		 M VERSION
 		 M VERSION
-		?? module1/
 		 M VERSION
 		 M VERSION
 		EOF
@@ -190,4 +190,121 @@ test_expect_success 'git repo sync and update submodules' '
 	test_cmp expect actual
 '
 
+test_expect_success 'git repo sync with sync-s updates submodules' '
+	(
+		cd work1/manifests &&
+		sed -e "s#path=\"main\"#path=\"main\" sync-s=\"true\"#" default.xml >sync-s.xml &&
+		git add sync-s.xml &&
+		git commit -m "sync-s manifest" &&
+		git push -u origin master
+	) &&
+	url="file://$HOME/repo/manifests.git" &&
+	mkdir work3 &&
+	(
+		cd work3 &&
+		git repo init -u "$url" -m sync-s.xml &&
+		git repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	) &&
+	(
+		cd work3/main &&
+		git log -1 --pretty="%s" &&
+		( cd submodule-1 && git log -1 --pretty="%s" ) &&
+		( cd submodule-1/submodule-1-1 && git log -1 --pretty="%s" ) &&
+		( cd submodule-2 && git log -1 --pretty="%s" )
+	) >actual &&
+	cat >expect <<-EOF &&
+	update submodule-1
+	add submodule-1-1 in submodule-1
+	initial submodule-1-1
+	initial submodule-2
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success 'git repo status shows submodules not in sync' '
+	(
+		cd work3 &&
+		(
+			cd main &&
+			git submodule deinit -f submodule-2
+		) &&
+		git repo status >out &&
+		grep "submodule-2" out >actual &&
+		printf " S-\tsubmodule-2 (not initialized)\n" >expect &&
+		test_cmp expect actual
+	)
+'
+
+test_expect_success 'git repo sync initializes submodules again' '
+	(
+		cd work3 &&
+		git repo sync -l
+	) &&
+	(
+		cd work3/main/submodule-2 &&
+		git log -1 --pretty="%s"
+	) >actual &&
+	cat >expect <<-EOF &&
+	initial submodule-2
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success 'git repo init --submodules fetches and updates submodules' '
+	url="file://$HOME/repo/manifests.git" &&
+	mkdir work4 &&
+	(
+		cd work4 &&
+		git repo init -u "$url" --submodules &&
+		git repo sync -vv \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" \
+			>out 2>&1 &&
+		grep "fetching using command: .*--recurse-submodules=on-demand" out
+	) &&
+	(
+		cd work4/main &&
+		( cd submodule-1 && git log -1 --pretty="%s" ) &&
+		( cd submodule-1/submodule-1-1 && git log -1 --pretty="%s" ) &&
+		( cd submodule-2 && git log -1 --pretty="%s" )
+	) >actual &&
+	cat >expect <<-EOF &&
+	add submodule-1-1 in submodule-1
+	initial submodule-1-1
+	initial submodule-2
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success 'git repo sync --fetch-submodules fetches and updates submodules' '
+	url="file://$HOME/repo/manifests.git" &&
+	mkdir work5 &&
+	(
+		cd work5 &&
+		git repo init -u "$url" &&
+		git repo sync -vv --fetch-submodules \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" \
+			>out 2>&1 &&
+		grep "fetching using command: .*--recurse-submodules=on-demand" out
+	) &&
+	(
+		cd work5/main &&
+		( cd submodule-1 && git log -1 --pretty="%s" ) &&
+		( cd submodule-1/submodule-1-1 && git log -1 --pretty="%s" ) &&
+		( cd submodule-2 && git log -1 --pretty="%s" )
+	) >actual &&
+	cat >expect <<-EOF &&
+	add submodule-1-1 in submodule-1
+	initial submodule-1-1
+	initial submodule-2
+	EOF
+	test_cmp expect actual
+'
+
 test_done
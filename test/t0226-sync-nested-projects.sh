@@ -0,0 +1,98 @@
+#!/bin/sh
+
+test_description="sync nested projects defined in different manifest files"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	)
+'
+
+test_expect_success "nested project without force-path is rejected" '
+	mkdir -p work/.repo/local_manifests &&
+	cat >work/.repo/local_manifests/nested.xml <<-EOF &&
+	<manifest>
+	  <project name="drivers/driver2" path="main/driver" remote="driver" />
+	</manifest>
+	EOF
+	(
+		cd work &&
+		test_must_fail git-repo sync -l
+	) >out 2>&1 &&
+	grep "^FATAL" out >actual &&
+	cat >expect<<-EOF &&
+	FATAL: path '"'"'main/driver'"'"' of project '"'"'drivers/driver2'"'"' is nested in project '"'"'main'"'"', set force-path to allow it in '"'"'$(pwd)/work/.repo/local_manifests/nested.xml'"'"'
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "nested project is excluded from outer project" '
+	cat >work/.repo/local_manifests/nested.xml <<-EOF &&
+	<manifest>
+	  <project name="drivers/driver2" path="main/driver" remote="driver" force-path="true" />
+	</manifest>
+	EOF
+	(
+		cd work &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	) >out 2>&1 &&
+	test -f work/main/driver/.git/HEAD -o -f work/main/driver/.git &&
+	grep "^/driver/$" work/.repo/projects/main.git/info/exclude &&
+	(
+		cd work/main &&
+		git status --porcelain
+	) >actual &&
+	test_must_be_empty actual
+'
+
+test_expect_success "tracked files in nested path are left out by sparse checkout" '
+	cat >work/.repo/local_manifests/nested.xml <<-EOF &&
+	<manifest>
+	  <project name="drivers/driver2" path="main/README.md" remote="driver" force-path="true" />
+	</manifest>
+	EOF
+	(
+		cd work &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	) >out 2>&1 &&
+	grep "files in '"'"'README.md'"'"' are hidden by nested project" out &&
+	test -d work/main/README.md &&
+	test "$(git -C work/main config core.sparseCheckout)" = "true" &&
+	(
+		cd work/main &&
+		git status --porcelain
+	) >actual &&
+	test_must_be_empty actual
+'
+
+test_expect_success "sparse checkout is disabled after nested project is removed" '
+	rm work/.repo/local_manifests/nested.xml &&
+	(
+		cd work &&
+		git-repo sync -l
+	) >out 2>&1 &&
+	test -f work/main/README.md &&
+	test_must_fail git -C work/main config core.sparseCheckout &&
+	test_must_fail grep "^/driver/$" work/.repo/projects/main.git/info/exclude
+'
+
+test_done
@@ -0,0 +1,79 @@
+#!/bin/sh
+
+test_description="test 'git-repo sync --report-file' and '--detailed-exit-code'"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url
+	)
+'
+
+test_expect_success "sync with report file" '
+	(
+		cd work &&
+		git-repo sync --detailed-exit-code --report-file ../report.json \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	) &&
+	grep "^  \"status\": \"ok\",$" report.json &&
+	grep "\"name\": \"project1/module1\"," report.json &&
+	grep "\"new_head\": \"$(git -C work/main rev-parse HEAD)\"," report.json &&
+	! grep "\"old_head\"" report.json &&
+	! grep "\"error\"" report.json
+'
+
+test_expect_success "old head is reported in the next sync" '
+	(
+		cd work &&
+		git-repo sync -l --report-file ../report.json
+	) &&
+	grep "^  \"status\": \"ok\",$" report.json &&
+	grep "\"old_head\": \"$(git -C work/main rev-parse HEAD)\"," report.json
+'
+
+test_expect_success "partial failure with detailed exit code" '
+	mkdir -p work/.repo/local_manifests &&
+	cat >work/.repo/local_manifests/bad.xml <<-EOF &&
+	<manifest>
+	  <project name="not-exist" path="not-exist" />
+	</manifest>
+	EOF
+	(
+		cd work &&
+		test_expect_code 2 git-repo sync -n --detailed-exit-code --report-file ../report.json
+	) &&
+	grep "^  \"status\": \"partial\",$" report.json &&
+	grep "\"error\": \"fail to fetch project '"'"'not-exist'"'"'" report.json
+'
+
+test_expect_success "exit code without --detailed-exit-code" '
+	(
+		cd work &&
+		test_expect_code 255 git-repo sync -n
+	)
+'
+
+test_expect_success "--interval can only be used in a mirror" '
+	(
+		cd work &&
+		test_must_fail git-repo sync --interval 1m
+	) >out 2>&1 &&
+	grep "^Error: --interval can only be used in a mirror" out &&
+	(
+		cd work &&
+		test_must_fail git-repo sync --metrics-listen :9090
+	) >out 2>&1 &&
+	grep "^Error: --metrics-listen must be used with --interval" out
+'
+
+test_done
@@ -0,0 +1,81 @@
+#!/bin/sh
+
+test_description="test lock of workspace and projects"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url &&
+		git-repo sync
+	) &&
+	test ! -f work/.repo/repo.lock &&
+	test_must_be_empty "$(ls work/.repo/locks)"
+'
+
+test_expect_success "fail to sync if workspace is locked" '
+	cat >work/.repo/repo.lock <<-EOF &&
+	{"pid":$$,"host":"$(hostname)","command":"git-repo sync","time":"2020-01-01T00:00:00Z"}
+	EOF
+	(
+		cd work &&
+		test_must_fail git-repo sync
+	) >actual 2>&1 &&
+	grep "is held by another process (pid $$ on $(hostname) since 2020-01-01T00:00:00Z, command: git-repo sync)" actual &&
+	test -f work/.repo/repo.lock
+'
+
+test_expect_success "fail to upload if workspace is locked" '
+	(
+		cd work &&
+		test_must_fail git-repo upload
+	) >actual 2>&1 &&
+	grep "repo.lock'"'"' is held by another process" actual
+'
+
+test_expect_success "wait for lock" '
+	(
+		sleep 1 &&
+		rm work/.repo/repo.lock
+	) &
+	(
+		cd work &&
+		git-repo sync -l --wait
+	) >actual 2>&1 &&
+	grep "repo.lock'"'"' is held by another process (.*), waiting for it to be released" actual &&
+	test ! -f work/.repo/repo.lock
+'
+
+test_expect_success "take over stale lock" '
+	cat >work/.repo/repo.lock <<-EOF &&
+	{"pid":1073741824,"host":"$(hostname)","command":"git-repo sync","time":"2020-01-01T00:00:00Z"}
+	EOF
+	(
+		cd work &&
+		git-repo sync -l
+	) >actual 2>&1 &&
+	grep "take over stale lock" actual &&
+	test ! -f work/.repo/repo.lock
+'
+
+test_expect_success "fail to sync locked project" '
+	mkdir -p work/.repo/locks &&
+	cat >work/.repo/locks/main.lock <<-EOF &&
+	{"pid":$$,"host":"$(hostname)","command":"git-repo upload","time":"2020-01-01T00:00:00Z"}
+	EOF
+	(
+		cd work &&
+		test_must_fail git-repo sync -l
+	) >actual 2>&1 &&
+	grep "main.lock'"'"' is held by another process" actual &&
+	rm work/.repo/locks/main.lock
+'
+
+test_done
@@ -0,0 +1,143 @@
+#!/bin/sh
+
+test_description="test sync with --force-sync and --fail-fast"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url &&
+		git-repo sync
+	)
+'
+
+test_expect_success "worktree of another repository" '
+	(
+		cd work &&
+		rm -rf main projects/app2 &&
+		git init -q --separate-git-dir="$(pwd)/../other-main.git" main &&
+		git init -q --separate-git-dir="$(pwd)/../other-app2.git" projects/app2
+	)
+'
+
+test_expect_success "fail to sync if worktree belongs to another repository" '
+	(
+		cd work &&
+		test_must_fail git-repo sync -l -j 1
+	) >out 2>&1 &&
+	grep -c "use --force-sync to overwrite" out >actual &&
+	echo 2 >expect &&
+	test_cmp expect actual
+'
+
+test_expect_success "stop on the first error with --fail-fast" '
+	(
+		cd work &&
+		test_must_fail git-repo sync -l -j 1 --fail-fast
+	) >out 2>&1 &&
+	grep -c "use --force-sync to overwrite" out >actual &&
+	echo 1 >expect &&
+	test_cmp expect actual &&
+	grep "sync is aborted for --fail-fast" out
+'
+
+test_expect_success "cannot combine --fail-fast and --force-broken" '
+	(
+		cd work &&
+		test_must_fail git-repo sync --fail-fast --force-broken
+	)
+'
+
+test_expect_success "overwrite worktrees with --force-sync" '
+	(
+		cd work &&
+		git-repo sync -l --force-sync
+	) >out 2>&1 &&
+	grep -A2 "following paths are removed for --force-sync" out >actual &&
+	cat >expect<<-EOF &&
+	WARNING: following paths are removed for --force-sync:
+	WARNING:   main
+	WARNING:   projects/app2
+	EOF
+	test_cmp expect actual &&
+	echo "gitdir: ../.repo/projects/main.git" >expect &&
+	test_cmp expect work/main/.git &&
+	test -f work/main/VERSION
+'
+
+test_expect_success "gitdir links to another object directory" '
+	(
+		cd work/.repo/projects/main.git &&
+		rm objects &&
+		ln -s ../../../../other-main.git/objects objects
+	) &&
+	(
+		cd work &&
+		test_must_fail git-repo sync -n
+	) >out 2>&1 &&
+	grep "points to a different object directory" out
+'
+
+test_expect_success "overwrite gitdir with --force-sync" '
+	(
+		cd work &&
+		git-repo sync -n --force-sync
+	) >out 2>&1 &&
+	grep -A2 "following paths are removed for --force-sync" out >actual &&
+	cat >expect<<-EOF &&
+	WARNING: following paths are removed for --force-sync:
+	WARNING:   .repo/projects/main.git
+	WARNING:   main
+	EOF
+	test_cmp expect actual &&
+	echo "../../project-objects/main.git/objects" >expect &&
+	readlink work/.repo/projects/main.git/objects >actual &&
+	test_cmp expect actual &&
+	(
+		cd work &&
+		git-repo sync -l
+	) &&
+	test -f work/main/VERSION
+'
+
+test_expect_success "keep worktrees of nested projects with --force-sync" '
+	mkdir -p work/.repo/local_manifests &&
+	cat >work/.repo/local_manifests/nested.xml <<-EOF &&
+	<manifest>
+	  <project name="drivers/driver2" path="main/driver" remote="driver" force-path="true" />
+	</manifest>
+	EOF
+	(
+		cd work &&
+		git-repo sync &&
+		echo wip >main/driver/wip.txt &&
+		rm main/.git &&
+		git init -q --separate-git-dir="$(pwd)/../other-main-2.git" main &&
+		git-repo sync -l --force-sync
+	) >out 2>&1 &&
+	grep -A1 "following paths are removed for --force-sync" out >actual &&
+	cat >expect<<-EOF &&
+	WARNING: following paths are removed for --force-sync:
+	WARNING:   main
+	EOF
+	test_cmp expect actual &&
+	echo "gitdir: ../.repo/projects/main.git" >expect &&
+	test_cmp expect work/main/.git &&
+	test -f work/main/VERSION &&
+	test -f work/main/driver/wip.txt &&
+	(
+		cd work/main/driver &&
+		git status --porcelain
+	) >actual &&
+	echo "?? wip.txt" >expect &&
+	test_cmp expect actual
+'
+
+test_done
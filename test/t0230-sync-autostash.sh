@@ -0,0 +1,155 @@
+#!/bin/sh
+
+test_description="git-repo sync rebases topic branch, and --autostash"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${HOME}/repositories/manifests.git"
+
+test_expect_success "setup" '
+	mkdir repositories &&
+	git init --bare repositories/manifests.git &&
+	git init --bare repositories/app1.git &&
+	(
+		mkdir tmp &&
+		cd tmp &&
+		git clone --no-local ../repositories/manifests.git &&
+		git clone --no-local ../repositories/app1.git
+	)
+	touch .repo &&
+	mkdir work
+'
+
+test_expect_success "setup repositories: manifests" '
+	(
+		cd tmp/manifests &&
+		cat >default.xml <<-EOF &&
+		<?xml version="1.0" encoding="UTF-8"?>
+		<manifest>
+		  <remote  name="origin"
+			   fetch=".."
+			   revision="master"
+			   review="https://example.com" />
+		  <default remote="origin"
+			   revision="master"
+			   sync-j="4" />
+		  <project name="repositories/app1.git" path="app1" groups="app"/>
+		</manifest>
+		EOF
+		git add default.xml &&
+		test_tick &&
+		git commit -m "initial" &&
+		git push -u origin HEAD
+	)
+'
+
+test_expect_success "setup repositories: app1" '
+	(
+		cd tmp/app1 &&
+		echo "app1: 1.0.0" >VERSION &&
+		echo "readme" >README &&
+		echo "notes" >NOTES &&
+		git add VERSION README NOTES &&
+		test_tick &&
+		git commit -m "initial" &&
+		git push -u origin HEAD
+	)
+'
+
+test_expect_success "git-repo sync" '
+	(
+		cd work &&
+		git-repo init -u "$manifest_url" &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	)
+'
+
+test_expect_success "new local commit and local changes in topic branch" '
+	(
+		cd work &&
+		git repo start --all jx/topic &&
+		(
+			cd app1 &&
+			echo "readme: hack" >README &&
+			git add README &&
+			test_tick &&
+			git commit -m "app1: local hack" &&
+			echo "notes: hack" >NOTES
+		)
+	)
+'
+
+test_expect_success "new upstream commit" '
+	(
+		cd tmp/app1 &&
+		echo "app1: 2.0.0" >VERSION &&
+		git add VERSION &&
+		test_tick &&
+		git commit -m "app1: 2.0.0" &&
+		git push origin HEAD
+	)
+'
+
+test_expect_success "fail to sync, worktree is dirty" '
+	(
+		cd work &&
+		test_must_fail git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" \
+			>out 2>&1 &&
+		grep "^Error:" out >actual &&
+		cat >expect <<-EOF &&
+		Error: worktree of repositories/app1 is dirty, checkout failed
+		EOF
+		test_cmp expect actual
+	)
+'
+
+test_expect_success "cannot combine --detach and --autostash" '
+	(
+		cd work &&
+		test_must_fail git-repo sync --detach --autostash
+	)
+'
+
+test_expect_success "sync --autostash, rebase local commit onto upstream" '
+	(
+		cd work &&
+		git-repo sync --autostash \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" &&
+		cd app1 &&
+		git symbolic-ref HEAD >actual &&
+		git log --pretty="%s" >>actual &&
+		cat >expect <<-EOF &&
+		refs/heads/jx/topic
+		app1: local hack
+		app1: 2.0.0
+		initial
+		EOF
+		test_cmp expect actual
+	)
+'
+
+test_expect_success "local changes are restored from stash" '
+	(
+		cd work/app1 &&
+		git status -uno --porcelain >actual &&
+		git stash list >>actual &&
+		cat >expect <<-EOF &&
+		 M NOTES
+		EOF
+		test_cmp expect actual &&
+		cat NOTES >actual &&
+		echo "notes: hack" >expect &&
+		test_cmp expect actual
+	)
+'
+
+test_done
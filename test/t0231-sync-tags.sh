@@ -0,0 +1,131 @@
+#!/bin/sh
+
+test_description="git-repo sync with sync-tags, --tags and --no-tags"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${HOME}/repositories/manifests.git"
+
+test_expect_success "setup" '
+	mkdir repositories &&
+	git init --bare repositories/manifests.git &&
+	git init --bare repositories/app1.git &&
+	git init --bare repositories/app2.git &&
+	(
+		mkdir tmp &&
+		cd tmp &&
+		git clone --no-local ../repositories/manifests.git &&
+		git clone --no-local ../repositories/app1.git &&
+		git clone --no-local ../repositories/app2.git
+	)
+	touch .repo &&
+	mkdir work
+'
+
+test_expect_success "setup repositories: manifests" '
+	(
+		cd tmp/manifests &&
+		cat >default.xml <<-EOF &&
+		<?xml version="1.0" encoding="UTF-8"?>
+		<manifest>
+		  <remote  name="origin"
+			   fetch=".."
+			   revision="master"
+			   review="https://example.com" />
+		  <default remote="origin"
+			   revision="master"
+			   sync-j="4" />
+		  <project name="repositories/app1.git" path="app1" sync-tags="false" />
+		  <project name="repositories/app2.git" path="app2" />
+		</manifest>
+		EOF
+		git add default.xml &&
+		test_tick &&
+		git commit -m "initial" &&
+		git push -u origin HEAD
+	)
+'
+
+test_expect_success "setup repositories: app1 and app2 with tags" '
+	for app in app1 app2
+	do
+		(
+			cd tmp/$app &&
+			echo "$app: 1.0.0" >VERSION &&
+			git add VERSION &&
+			test_tick &&
+			git commit -m "$app: 1.0.0" &&
+			git tag -m v1.0.0 v1.0.0 &&
+			git push origin HEAD v1.0.0
+		) || return 1
+	done
+'
+
+test_expect_success "git-repo sync, no tags for project with sync-tags=false" '
+	(
+		cd work &&
+		git-repo init -u "$manifest_url" &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" &&
+		(
+			echo "app1:" $(git -C app1 tag) &&
+			echo "app2:" $(git -C app2 tag)
+		) >actual &&
+		cat >expect <<-EOF &&
+		app1:
+		app2: v1.0.0
+		EOF
+		test_cmp expect actual
+	)
+'
+
+test_expect_success "git-repo info shows tags policy" '
+	(
+		cd work &&
+		git-repo info >out &&
+		grep "^Fetch tags:" out >actual &&
+		cat >expect <<-EOF &&
+		Fetch tags: no (sync-tags)
+		Fetch tags: yes (sync-tags)
+		EOF
+		test_cmp expect actual &&
+		git-repo info --tags >out &&
+		grep "^Fetch tags:" out >actual &&
+		cat >expect <<-EOF &&
+		Fetch tags: yes (--tags)
+		Fetch tags: yes (--tags)
+		EOF
+		test_cmp expect actual
+	)
+'
+
+test_expect_success "cannot combine --tags and --no-tags" '
+	(
+		cd work &&
+		test_must_fail git-repo sync --tags --no-tags
+	)
+'
+
+test_expect_success "git-repo sync --tags fetches tags of all projects" '
+	(
+		cd work &&
+		git-repo sync --tags \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" &&
+		(
+			echo "app1:" $(git -C app1 tag) &&
+			echo "app2:" $(git -C app2 tag)
+		) >actual &&
+		cat >expect <<-EOF &&
+		app1: v1.0.0
+		app2: v1.0.0
+		EOF
+		test_cmp expect actual
+	)
+'
+
+test_done
@@ -0,0 +1,109 @@
+#!/bin/sh
+
+test_description="projects of the same name share one object store"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${HOME}/repositories/manifests.git"
+
+test_expect_success "setup" '
+	mkdir repositories &&
+	git init --bare repositories/manifests.git &&
+	git init --bare repositories/app1.git &&
+	(
+		mkdir tmp &&
+		cd tmp &&
+		git clone --no-local ../repositories/manifests.git &&
+		git clone --no-local ../repositories/app1.git
+	)
+	touch .repo &&
+	mkdir work
+'
+
+test_expect_success "setup repositories" '
+	(
+		cd tmp/manifests &&
+		cat >default.xml <<-EOF &&
+		<?xml version="1.0" encoding="UTF-8"?>
+		<manifest>
+		  <remote  name="origin"
+			   fetch=".."
+			   revision="master"
+			   review="https://example.com" />
+		  <default remote="origin"
+			   revision="master"
+			   sync-j="4" />
+		  <project name="repositories/app1.git" path="app1" />
+		  <project name="repositories/app1.git" path="app1-copy" />
+		</manifest>
+		EOF
+		git add default.xml &&
+		test_tick &&
+		git commit -m "initial" &&
+		git push -u origin HEAD
+	) &&
+	(
+		cd tmp/app1 &&
+		echo "app1: 1.0.0" >VERSION &&
+		git add VERSION &&
+		test_tick &&
+		git commit -m "app1: 1.0.0" &&
+		git push -u origin HEAD
+	)
+'
+
+test_expect_success "git-repo sync" '
+	(
+		cd work &&
+		git-repo init -u "$manifest_url" &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	)
+'
+
+test_expect_success "objects of app1 and app1-copy are shared" '
+	(
+		cd work/.repo &&
+		test -d project-objects/repositories/app1.git/objects &&
+		test -h projects/app1.git/objects &&
+		test -h projects/app1-copy.git/objects &&
+		(cd projects/app1.git/objects && pwd -P) >actual &&
+		(cd projects/app1-copy.git/objects && pwd -P) >expect &&
+		test_cmp expect actual
+	)
+'
+
+test_expect_success "private objects of legacy layout" '
+	(
+		cd work/.repo/projects/app1-copy.git &&
+		rm objects &&
+		cp -R ../../project-objects/repositories/app1.git/objects objects &&
+		test ! -h objects &&
+		echo unreachable | git --git-dir=. hash-object -w --stdin >../../../unreachable
+	)
+'
+
+test_expect_success "sync moves private objects to shared object store" '
+	(
+		cd work &&
+		git-repo sync -n \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" &&
+		cd .repo &&
+		test -h projects/app1-copy.git/objects &&
+		(cd projects/app1.git/objects && pwd -P) >actual &&
+		(cd projects/app1-copy.git/objects && pwd -P) >expect &&
+		test_cmp expect actual &&
+		cd ../app1-copy &&
+		git log --pretty="%s" >actual &&
+		echo "app1: 1.0.0" >expect &&
+		test_cmp expect actual &&
+		git cat-file -e $(cat ../unreachable)
+	)
+'
+
+test_done
@@ -0,0 +1,183 @@
+#!/bin/sh
+
+test_description="check out projects by git worktree"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${HOME}/repositories/manifests.git"
+
+test_expect_success "setup" '
+	mkdir repositories &&
+	git init --bare repositories/manifests.git &&
+	git init --bare repositories/app1.git &&
+	(
+		mkdir tmp &&
+		cd tmp &&
+		git clone --no-local ../repositories/manifests.git &&
+		git clone --no-local ../repositories/app1.git
+	)
+	touch .repo &&
+	mkdir work legacy
+'
+
+test_expect_success "setup repositories" '
+	(
+		cd tmp/manifests &&
+		cat >default.xml <<-EOF &&
+		<?xml version="1.0" encoding="UTF-8"?>
+		<manifest>
+		  <remote  name="origin"
+			   fetch=".."
+			   revision="master"
+			   review="https://example.com" />
+		  <default remote="origin"
+			   revision="master"
+			   sync-j="4" />
+		  <project name="repositories/app1.git" path="app1" />
+		  <project name="repositories/app1.git" path="app1-copy" />
+		</manifest>
+		EOF
+		git add default.xml &&
+		test_tick &&
+		git commit -m "initial" &&
+		git push -u origin HEAD
+	) &&
+	(
+		cd tmp/app1 &&
+		echo "app1: 1.0.0" >VERSION &&
+		git add VERSION &&
+		test_tick &&
+		git commit -m "app1: 1.0.0" &&
+		git push -u origin HEAD
+	)
+'
+
+test_expect_success "cannot init --worktree with --mirror" '
+	mkdir mirror &&
+	(
+		cd mirror &&
+		test_must_fail git-repo init --mirror --worktree -u "$manifest_url"
+	)
+'
+
+test_expect_success "git-repo init --worktree and sync" '
+	(
+		cd work &&
+		git-repo init --worktree -u "$manifest_url" &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	)
+'
+
+test_expect_success "projects are worktrees of shared object store" '
+	(
+		cd work &&
+		test -f app1/.git &&
+		test -f app1-copy/.git &&
+		test ! -d .repo/projects/app1.git &&
+		test ! -d .repo/projects/app1-copy.git &&
+		git -C .repo/project-objects/repositories/app1.git worktree list >out &&
+		grep "/app1 " out &&
+		grep "/app1-copy " out &&
+		echo "app1: 1.0.0" >expect &&
+		test_cmp expect app1/VERSION &&
+		test_cmp expect app1-copy/VERSION
+	)
+'
+
+test_expect_success "legacy workspace with topic branch" '
+	(
+		cd legacy &&
+		git-repo init -u "$manifest_url" &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" &&
+		test -d .repo/projects/app1.git &&
+		git-repo start --all topic &&
+		(
+			cd app1 &&
+			echo "hacked" >>VERSION &&
+			git add VERSION &&
+			test_tick &&
+			git commit -m "topic: hack" &&
+			echo "stashed" >>VERSION &&
+			git stash push -m "wip: stashed"
+		) &&
+		(
+			cd app1-copy &&
+			echo "copy" >>VERSION &&
+			git add VERSION &&
+			test_tick &&
+			git commit -m "topic: copy"
+		)
+	)
+'
+
+test_expect_success "sync migrates legacy layout to git worktree" '
+	(
+		cd legacy &&
+		git-repo init --worktree -u "$manifest_url" &&
+		git-repo sync -l &&
+		test ! -d .repo/projects/app1.git &&
+		test ! -d .repo/projects/app1-copy.git &&
+		git -C .repo/project-objects/repositories/app1.git worktree list >out &&
+		grep "/app1 " out &&
+		git -C app1 log -1 --pretty="%s" >actual &&
+		echo "topic: hack" >expect &&
+		test_cmp expect actual &&
+		git -C app1 rev-parse --abbrev-ref --symbolic-full-name "@{u}" >actual &&
+		echo "origin/master" >expect &&
+		test_cmp expect actual &&
+		git -C app1 status --porcelain >actual &&
+		test_must_be_empty actual &&
+		git -C app1-copy status --porcelain >actual &&
+		test_must_be_empty actual
+	)
+'
+
+test_expect_success "stash and reflog are kept after migration" '
+	(
+		cd legacy &&
+		git -C app1 stash list --pretty="%gs" >actual &&
+		echo "On topic: wip: stashed" >expect &&
+		test_cmp expect actual &&
+		git -C app1 stash show -p >out &&
+		grep "^+stashed" out &&
+		branch=$(git -C app1 symbolic-ref --short HEAD) &&
+		git -C app1 reflog show --pretty="%gs" "$branch" >actual &&
+		grep "topic: hack" actual &&
+		test_must_fail git -C .repo/project-objects/repositories/app1.git \
+			rev-parse --verify -q refs/migrate-stash/0
+	)
+'
+
+test_expect_success "colliding topic branches are renamed after migration" '
+	(
+		cd legacy &&
+		git -C app1 log -1 --pretty="%s" >actual &&
+		echo "topic: hack" >expect &&
+		test_cmp expect actual &&
+		git -C app1-copy log -1 --pretty="%s" >actual &&
+		echo "topic: copy" >expect &&
+		test_cmp expect actual &&
+		git -C app1 rev-parse --abbrev-ref HEAD >actual &&
+		git -C app1-copy rev-parse --abbrev-ref HEAD >>actual &&
+		sort actual >actual.sorted &&
+		if grep -q "^topic-app1-copy$" actual.sorted
+		then
+			printf "topic\ntopic-app1-copy\n" >expect
+		else
+			printf "topic\ntopic-app1\n" >expect
+		fi &&
+		test_cmp expect actual.sorted &&
+		git -C app1-copy rev-parse --abbrev-ref --symbolic-full-name "@{u}" >actual &&
+		echo "origin/master" >expect &&
+		test_cmp expect actual
+	)
+'
+
+test_done
@@ -0,0 +1,133 @@
+#!/bin/sh
+
+test_description="git-repo sync --run-hooks runs post-sync command of projects"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${HOME}/repositories/manifests.git"
+
+test_expect_success "setup" '
+	mkdir repositories &&
+	git init --bare repositories/manifests.git &&
+	git init --bare repositories/app1.git &&
+	git init --bare repositories/app2.git &&
+	(
+		mkdir tmp &&
+		cd tmp &&
+		git clone --no-local ../repositories/manifests.git &&
+		git clone --no-local ../repositories/app1.git &&
+		git clone --no-local ../repositories/app2.git
+	)
+	touch .repo &&
+	mkdir work
+'
+
+test_expect_success "setup repositories" '
+	(
+		cd tmp/manifests &&
+		cat >default.xml <<-EOF &&
+		<?xml version="1.0" encoding="UTF-8"?>
+		<manifest>
+		  <remote  name="origin"
+			   fetch=".."
+			   revision="master"
+			   review="https://example.com" />
+		  <default remote="origin"
+			   revision="master"
+			   sync-j="4" />
+		  <project name="repositories/app1.git" path="app1">
+		    <annotation name="post-sync" value="./generate.sh"/>
+		  </project>
+		  <project name="repositories/app2.git" path="app2">
+		    <annotation name="post-sync" value="../app1/generate.sh"/>
+		  </project>
+		</manifest>
+		EOF
+		git add default.xml &&
+		test_tick &&
+		git commit -m "initial" &&
+		git push -u origin HEAD
+	) &&
+	(
+		cd tmp/app1 &&
+		cat >generate.sh <<-\EOF &&
+		#!/bin/sh
+		echo "generated by $REPO_PROJECT" >generated.txt
+		echo "secret: $SECRET_TOKEN" >secret.txt
+		EOF
+		chmod a+x generate.sh &&
+		git add generate.sh &&
+		test_tick &&
+		git commit -m "app1: add generate.sh" &&
+		git push -u origin HEAD
+	) &&
+	(
+		cd tmp/app2 &&
+		echo "app2: 1.0.0" >VERSION &&
+		git add VERSION &&
+		test_tick &&
+		git commit -m "app2: 1.0.0" &&
+		git push -u origin HEAD
+	)
+'
+
+test_expect_success "git-repo sync without --run-hooks" '
+	(
+		cd work &&
+		git-repo init -u "$manifest_url" &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" &&
+		test ! -f app1/generated.txt
+	)
+'
+
+test_expect_success "post-sync command is not approved" '
+	(
+		cd work &&
+		test_must_fail git-repo sync -l --run-hooks --assume-no &&
+		test ! -f app1/generated.txt
+	)
+'
+
+test_expect_success "post-sync command is not run in dryrun mode" '
+	(
+		cd work &&
+		test_must_fail git-repo sync -l --run-hooks --assume-yes --dryrun >out 2>&1 &&
+		grep "app1> will run post-sync command: ./generate.sh" out &&
+		test ! -f app1/generated.txt
+	)
+'
+
+test_expect_success "post-sync command outside of project is refused" '
+	(
+		cd work &&
+		test_must_fail git-repo sync -l --run-hooks --assume-yes >out 2>&1 &&
+		grep "is outside of project" out &&
+		echo "generated by repositories/app1" >expect &&
+		test_cmp expect app1/generated.txt
+	)
+'
+
+test_expect_success "post-sync command does not see environments of git-repo" '
+	(
+		cd work &&
+		test_must_fail env SECRET_TOKEN=leaked \
+			git-repo sync -l --run-hooks --assume-yes &&
+		echo "secret: " >expect &&
+		test_cmp expect app1/secret.txt &&
+		echo "generated by repositories/app1" >expect &&
+		test_cmp expect app1/generated.txt
+	)
+'
+
+test_expect_success "cannot combine -n and --run-hooks" '
+	(
+		cd work &&
+		test_must_fail git-repo sync -n --run-hooks
+	)
+'
+
+test_done
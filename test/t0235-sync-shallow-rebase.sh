@@ -0,0 +1,127 @@
+#!/bin/sh
+
+test_description="git-repo sync deepens shallow clone to rebase topic branch"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${HOME}/repositories/manifests.git"
+
+test_expect_success "setup" '
+	mkdir repositories &&
+	git init --bare repositories/manifests.git &&
+	git init --bare repositories/app1.git &&
+	(
+		mkdir tmp &&
+		cd tmp &&
+		git clone --no-local ../repositories/manifests.git &&
+		git clone --no-local ../repositories/app1.git
+	)
+	touch .repo &&
+	mkdir work
+'
+
+test_expect_success "setup repositories: manifests" '
+	(
+		cd tmp/manifests &&
+		cat >default.xml <<-EOF &&
+		<?xml version="1.0" encoding="UTF-8"?>
+		<manifest>
+		  <remote  name="origin"
+			   fetch=".."
+			   revision="master"
+			   review="https://example.com" />
+		  <default remote="origin"
+			   revision="master"
+			   sync-j="4" />
+		  <project name="repositories/app1.git" path="app1" groups="app"/>
+		</manifest>
+		EOF
+		git add default.xml &&
+		test_tick &&
+		git commit -m "initial" &&
+		git push -u origin HEAD
+	)
+'
+
+test_expect_success "setup repositories: app1" '
+	(
+		cd tmp/app1 &&
+		echo "app1: 1.0.0" >VERSION &&
+		echo "readme" >README &&
+		git add VERSION README &&
+		test_tick &&
+		git commit -m "initial" &&
+		echo "app1: 1.0.1" >VERSION &&
+		git add VERSION &&
+		test_tick &&
+		git commit -m "app1: 1.0.1" &&
+		git push -u origin HEAD
+	)
+'
+
+test_expect_success "git-repo sync with depth 1" '
+	(
+		cd work &&
+		git-repo init -u "$manifest_url" --depth 1 &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" &&
+		test -f .repo/projects/app1.git/shallow
+	)
+'
+
+test_expect_success "new local commit in topic branch" '
+	(
+		cd work &&
+		git repo start --all jx/topic &&
+		(
+			cd app1 &&
+			echo "readme: hack" >README &&
+			git add README &&
+			test_tick &&
+			git commit -m "app1: local hack"
+		)
+	)
+'
+
+test_expect_success "new upstream commits" '
+	(
+		cd tmp/app1 &&
+		echo "app1: 2.0.0" >VERSION &&
+		git add VERSION &&
+		test_tick &&
+		git commit -m "app1: 2.0.0" &&
+		echo "app1: 3.0.0" >VERSION &&
+		git add VERSION &&
+		test_tick &&
+		git commit -m "app1: 3.0.0" &&
+		git push origin HEAD
+	)
+'
+
+test_expect_success "sync deepens shallow clone, and rebases local commit" '
+	(
+		cd work &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" \
+			>out 2>&1 &&
+		grep "deepen shallow clone" out &&
+		cd app1 &&
+		git symbolic-ref HEAD >actual &&
+		git log --pretty="%s" -4 >>actual &&
+		cat >expect <<-EOF &&
+		refs/heads/jx/topic
+		app1: local hack
+		app1: 3.0.0
+		app1: 2.0.0
+		app1: 1.0.1
+		EOF
+		test_cmp expect actual
+	)
+'
+
+test_done
@@ -128,4 +128,25 @@ test_expect_success "check current commit of app1" '
 	)
 '
 
+test_expect_success "start branch in project of current subdirectory" '
+	(
+		cd work &&
+		mkdir -p projects/app1/sub/dir &&
+		(
+			cd projects/app1/sub/dir &&
+			git-repo start my/subdir .
+		) &&
+		cat >expect<<-EOF &&
+		main: my/topic1
+		projects/app1: my/subdir
+		projects/app2: my/topic1
+		EOF
+		for p in main projects/app1 projects/app2
+		do
+			echo "$p: $(cd $p && git_current_branch)"
+		done >actual &&
+		test_cmp expect actual
+	)
+'
+
 test_done
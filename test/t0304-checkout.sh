@@ -0,0 +1,98 @@
+#!/bin/sh
+
+test_description="checkout branch test"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work
+'
+
+test_expect_success "git-repo init" '
+	(
+		cd work &&
+		git-repo init -u $manifest_url -g all -b Maint &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	)
+'
+
+test_expect_success "create branch my/topic1 in all projects" '
+	(
+		cd work &&
+		git-repo start --all my/topic1
+	)
+'
+
+test_expect_success "create branch my/topic2 in app1 and app2" '
+	(
+		cd work &&
+		git-repo start my/topic2 projects/app1 projects/app2
+	)
+'
+
+test_expect_success "checkout my/topic1" '
+	(
+		cd work &&
+		git-repo checkout my/topic1
+	)
+'
+
+test_expect_success "check current branch after checkout my/topic1" '
+	(
+		cd work &&
+		git-repo forall -c '"'"'echo "$REPO_PATH: $(git symbolic-ref --short HEAD)"'"'"'
+	) >actual &&
+	cat >expect<<-EOF &&
+	main: my/topic1
+	projects/app1: my/topic1
+	projects/app1/module1: my/topic1
+	projects/app2: my/topic1
+	drivers/driver-1: my/topic1
+	drivers/driver-2: my/topic1
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "checkout my/topic2, skip projects without the branch" '
+	(
+		cd work &&
+		git-repo checkout my/topic2
+	)
+'
+
+test_expect_success "check current branch after checkout my/topic2" '
+	(
+		cd work &&
+		git-repo forall -c '"'"'echo "$REPO_PATH: $(git symbolic-ref --short HEAD)"'"'"'
+	) >actual &&
+	cat >expect<<-EOF &&
+	main: my/topic1
+	projects/app1: my/topic2
+	projects/app1/module1: my/topic1
+	projects/app2: my/topic2
+	drivers/driver-1: my/topic1
+	drivers/driver-2: my/topic1
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "checkout a nonexistent branch" '
+	(
+		cd work &&
+		test_must_fail git-repo checkout my/topic3
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	Error: no project has branch '"'"'my/topic3'"'"'
+	EOF
+	test_cmp expect actual
+'
+
+test_done
@@ -0,0 +1,94 @@
+#!/bin/sh
+
+test_description="show branches of projects"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work
+'
+
+test_expect_success "git-repo init" '
+	(
+		cd work &&
+		git-repo init -u $manifest_url -g all -b Maint &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	)
+'
+
+test_expect_success "no branches" '
+	(
+		cd work &&
+		git-repo branches
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	NOTE: (no branches)
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "create branches" '
+	(
+		cd work &&
+		git-repo start --all my/topic1 &&
+		git-repo start my/topic2 projects/app1 projects/app2 &&
+		git-repo start my/topic3 main projects/app1 projects/app2 \
+			projects/app1/module1 drivers/driver-1
+	)
+'
+
+test_expect_success "show branches" '
+	(
+		cd work &&
+		git-repo branches
+	) >actual &&
+	cat >expect<<-EOF &&
+	*  my/topic1                 | in all projects
+	   my/topic2                 | in projects/app1, projects/app2
+	*  my/topic3                 | not in drivers/driver-2
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "show published branches" '
+	(
+		cd work &&
+		(
+			cd projects/app1 &&
+			git update-ref refs/published/my/topic1 refs/heads/my/topic1 &&
+			git update-ref refs/published/my/topic2 refs/heads/my/topic2
+		) &&
+		(
+			cd projects/app2 &&
+			git update-ref refs/published/my/topic2 refs/heads/my/topic2
+		) &&
+		git-repo branches
+	) >actual &&
+	cat >expect<<-EOF &&
+	*p my/topic1                 | in all projects
+	 P my/topic2                 | in projects/app1, projects/app2
+	*  my/topic3                 | not in drivers/driver-2
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "show branches of specific project" '
+	(
+		cd work &&
+		git-repo branches drivers/driver-2
+	) >actual &&
+	cat >expect<<-EOF &&
+	*  my/topic1                 | in all projects
+	EOF
+	test_cmp expect actual
+'
+
+test_done
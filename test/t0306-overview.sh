@@ -0,0 +1,124 @@
+#!/bin/sh
+
+test_description="show overview of unpublished commits"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work
+'
+
+test_expect_success "git-repo init" '
+	(
+		cd work &&
+		git-repo init -u $manifest_url -g all -b Maint &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	)
+'
+
+test_expect_success "no unpublished commits" '
+	(
+		cd work &&
+		git-repo start --all my/topic1
+	) &&
+	(
+		cd work &&
+		git-repo overview
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	NOTE: no unpublished commits
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "create commits" '
+	(
+		cd work &&
+		(
+			cd main &&
+			echo hack >topic1.txt &&
+			git add topic1.txt &&
+			test_tick &&
+			git commit -q -m "topic1: new file"
+		) &&
+		(
+			cd projects/app1 &&
+			echo hack >topic1.txt &&
+			git add topic1.txt &&
+			test_tick &&
+			git commit -q -m "topic1: new file"
+		) &&
+		git-repo start my/topic2 projects/app1 &&
+		(
+			cd projects/app1 &&
+			echo hack >topic2.txt &&
+			git add topic2.txt &&
+			test_tick &&
+			git commit -q -m "topic2: new file"
+		)
+	)
+'
+
+test_expect_success "show overview" '
+	(
+		cd work &&
+		git-repo overview
+	) >out &&
+	sed -e "s/[0-9a-f]\{7\} /<hash> /" -e "s/| .*/| <date>/" <out >actual &&
+	cat >expect<<-EOF &&
+	Projects Overview
+	project main/
+	  * my/topic1 | <date>
+	      - <hash> topic1: new file
+	project projects/app1/
+	    my/topic1 | <date>
+	      - <hash> topic1: new file
+	  * my/topic2 | <date>
+	      - <hash> topic2: new file
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "show overview of current branch" '
+	(
+		cd work &&
+		git-repo overview -c projects/app1
+	) >out &&
+	sed -e "s/[0-9a-f]\{7\} /<hash> /" -e "s/| .*/| <date>/" <out >actual &&
+	cat >expect<<-EOF &&
+	Projects Overview
+	project projects/app1/
+	  * my/topic2 | <date>
+	      - <hash> topic2: new file
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "published commits are not shown" '
+	(
+		cd work &&
+		(
+			cd projects/app1 &&
+			git update-ref refs/published/my/topic2 refs/heads/my/topic2
+		) &&
+		git-repo overview projects/app1
+	) >out &&
+	sed -e "s/[0-9a-f]\{7\} /<hash> /" -e "s/| .*/| <date>/" <out >actual &&
+	cat >expect<<-EOF &&
+	Projects Overview
+	project projects/app1/
+	    my/topic1 | <date>
+	      - <hash> topic1: new file
+	EOF
+	test_cmp expect actual
+'
+
+test_done
@@ -184,6 +184,37 @@ test_expect_success "agit-flow proto v2: no agit-receive-pack, and push with env
 	)
 '
 
+test_expect_success "agit-flow: upload with topic" '
+	(
+		cd work &&
+		cat >expect<<-EOF &&
+		Upload project main/ to remote branch Maint:
+		  branch my/topic1 ( 1 commit(s)):
+		         <hash>
+		to https://example.com (y/N)? Yes
+		NOTE: main> will execute command: git push -o topic=my-topic ssh://git@ssh.example.com/main.git refs/heads/my/topic1:refs/for/Maint/my/topic1
+		NOTE: main> with extra environment: AGIT_FLOW=git-repo/n.n.n.n
+		NOTE: main> with extra environment: GIT_SSH_COMMAND=ssh -o SendEnv=AGIT_FLOW
+		NOTE: main> will update-ref refs/published/my/topic1 on refs/heads/my/topic1, reason: review from my/topic1 to Maint on https://example.com
+		
+		----------------------------------------------------------------------
+		Topic my-topic: 1 branch(es) of 1 project(s) uploaded, 0 failed
+		EOF
+		git-repo upload \
+			--dryrun \
+			--no-cache \
+			--no-edit \
+			--assume-yes \
+			--topic my-topic \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\", \"version\":2}" \
+			>out 2>&1 &&
+		sed -e "s/[0-9a-f]\{40\}/<hash>/g" -e "s/git-repo\/[^ \"\\]*/git-repo\/n.n.n.n/g" <out >actual &&
+		test_cmp expect actual
+	)
+'
+
 test_expect_success "new branch, and do nothing for for upload --cbr" '
 	(
 		cd work &&
@@ -168,4 +168,153 @@ test_expect_success "upload --dryrun with reviewers" '
 	)
 '
 
+test_expect_success "upload --dryrun with topic and hashtags" '
+	(
+		cd work &&
+		cat >expect<<-EOF &&
+		Upload project main/ to remote branch Maint:
+		  branch my/topic1 ( 1 commit(s)):
+		         <hash>
+		to https://example.com (y/N)? Yes
+		NOTE: main> will execute command: git push --receive-pack=gerrit receive-pack ssh://committer@ssh.example.com:29418/main.git refs/heads/my/topic1:refs/for/Maint%topic=my-topic,t=tag1,t=tag2,t=tag3,r=user1
+		NOTE: main> will update-ref refs/published/my/topic1 on refs/heads/my/topic1, reason: review from my/topic1 to Maint on https://example.com
+		
+		----------------------------------------------------------------------
+		Topic my-topic: 1 branch(es) of 1 project(s) uploaded, 0 failed
+		EOF
+		git-repo upload \
+			--assume-yes \
+			--no-edit \
+			--dryrun \
+			--mock-git-push \
+			--reviewers user1 \
+			--topic my-topic \
+			--hashtag tag1,tag2 \
+			--ht tag3 \
+			>out 2>&1 &&
+		sed -e "s/[0-9a-f]\{40\}/<hash>/g" out >actual &&
+		test_cmp expect actual
+	)
+'
+
+test_expect_success "upload --dryrun with --ready and --remove-private" '
+	(
+		cd work &&
+		cat >expect<<-EOF &&
+		Upload project main/ to remote branch Maint:
+		  branch my/topic1 ( 1 commit(s)):
+		         <hash>
+		to https://example.com (y/N)? Yes
+		NOTE: main> will execute command: git push --receive-pack=gerrit receive-pack ssh://committer@ssh.example.com:29418/main.git refs/heads/my/topic1:refs/for/Maint%ready,remove-private
+		NOTE: main> will update-ref refs/published/my/topic1 on refs/heads/my/topic1, reason: review from my/topic1 to Maint on https://example.com
+		
+		----------------------------------------------------------------------
+		EOF
+		git-repo upload \
+			--assume-yes \
+			--no-edit \
+			--dryrun \
+			--mock-git-push \
+			--ready \
+			--remove-private \
+			>out 2>&1 &&
+		sed -e "s/[0-9a-f]\{40\}/<hash>/g" out >actual &&
+		test_cmp expect actual
+	)
+'
+
+test_expect_success "cannot combine --wip and --ready" '
+	(
+		cd work &&
+		cat >expect<<-EOF &&
+		Error: cannot combine --wip and --ready
+		EOF
+		test_must_fail git-repo upload \
+			--assume-yes \
+			--no-edit \
+			--dryrun \
+			--mock-git-push \
+			--wip \
+			--ready \
+			>out 2>&1 &&
+		head -1 out >actual &&
+		test_cmp expect actual
+	)
+'
+
+test_expect_success "upload: commits fail to pass validations" '
+	(
+		cd work &&
+		git -C main config upload.maxSubjectLength 10 &&
+		git -C main config upload.requireSignedOffBy true &&
+		git -C main config upload.forbiddenFiles "*.txt" &&
+		cat >expect<<-EOF &&
+		Upload project main/ to remote branch Maint:
+		  branch my/topic1 ( 1 commit(s)):
+		         <hash>
+		to https://example.com (y/N)? Yes
+		ERROR: main> commit <abbr>: subject is longer than 10 characters
+		ERROR: main> commit <abbr>: missing Signed-off-by
+		ERROR: main> commit <abbr>: forbidden file topic1.txt
+		
+		----------------------------------------------------------------------
+		[FAILED] main/           my/topic1       (3 problem(s) found in commits)
+		EOF
+		test_must_fail git-repo upload \
+			--assume-yes \
+			--no-edit \
+			--dryrun \
+			--mock-git-push \
+			>out 2>&1 &&
+		sed -e "s/[0-9a-f]\{40\}/<hash>/g" -e "s/commit [0-9a-f]\{7\}/commit <abbr>/g" out |
+			sed -n -e "/^Upload/,/^\[FAILED\]/p" >actual &&
+		test_cmp expect actual
+	)
+'
+
+test_expect_success "upload --no-verify: skip validations" '
+	(
+		cd work &&
+		git-repo upload \
+			--assume-yes \
+			--no-edit \
+			--dryrun \
+			--mock-git-push \
+			--no-verify \
+			>out 2>&1 &&
+		grep "will execute command: git push" out &&
+		git -C main config --unset upload.maxSubjectLength &&
+		git -C main config --unset upload.requireSignedOffBy &&
+		git -C main config --unset upload.forbiddenFiles
+	)
+'
+
+test_expect_success "upload: insert missing Change-Id" '
+	(
+		cd work/main &&
+		git checkout -q my/topic1 &&
+		git config upload.requireChangeId true &&
+		git config upload.insertChangeId true &&
+		echo hack >topic2.txt &&
+		git add topic2.txt &&
+		test_tick &&
+		git commit --no-verify -m "topic1: another file" &&
+		cd .. &&
+		git -C main show -s --format=%B HEAD >out &&
+		test_must_fail grep "^Change-Id:" out &&
+		git-repo upload \
+			--assume-yes \
+			--no-edit \
+			--mock-git-push \
+			>out 2>&1 &&
+		grep "NOTE: main> insert Change-Id into 1 commit(s) of branch my/topic1" out &&
+		git -C main show -s --format=%B HEAD >out &&
+		grep "^Change-Id: I[0-9a-f]\{40\}$" out &&
+		git -C main show -s --format=%B HEAD~ >out &&
+		grep "^Change-Id: I[0-9a-f]\{40\}$" out &&
+		git -C main status --porcelain >out &&
+		test_must_be_empty out
+	)
+'
+
 test_done
@@ -0,0 +1,128 @@
+#!/bin/sh
+
+test_description="upload with pre-upload hook defined in repo-hooks"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work
+'
+
+test_expect_success "git-repo init & sync" '
+	(
+		cd work &&
+		git-repo init -u $manifest_url -g all -b Maint &&
+		git-repo sync  \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"gerrit\"}" &&
+		git repo start --all my/topic1
+	)
+'
+
+test_expect_success "enable pre-upload hook in project2" '
+	(
+		cd work &&
+		mkdir -p .repo/local_manifests &&
+		cat >.repo/local_manifests/hooks.xml <<-EOF &&
+		<?xml version="1.0" encoding="UTF-8"?>
+		<manifest>
+		  <repo-hooks in-project="project2" enabled-list="pre-upload"/>
+		</manifest>
+		EOF
+		cat >projects/app2/pre-upload <<-\EOF &&
+		#!/bin/sh
+		echo "pre-upload hook: $REPO_PATH: $# commit(s)"
+		test -z "$REJECT_UPLOAD"
+		EOF
+		chmod a+x projects/app2/pre-upload &&
+		(
+			cd main &&
+			echo hack >topic1.txt &&
+			git add topic1.txt &&
+			test_tick &&
+			git commit -m "topic1: new file"
+		)
+	)
+'
+
+test_expect_success "upload --dryrun: show hook without running it" '
+	(
+		cd work &&
+		git-repo upload \
+			--assume-yes \
+			--no-edit \
+			--dryrun \
+			>out 2>&1 &&
+		grep "NOTE: main> will run pre-upload hook: .*/projects/app2/pre-upload" out &&
+		test_must_fail grep "^pre-upload hook:" out
+	)
+'
+
+test_expect_success "hook is not approved" '
+	(
+		cd work &&
+		test_must_fail git-repo upload \
+			--assume-no \
+			--no-edit \
+			--mock-git-push \
+			>out 2>&1 &&
+		grep "Repository hook script .*/projects/app2/pre-upload is new or changed since last run." out &&
+		grep "Error: pre-upload hook is not approved, use --no-verify to skip it" out
+	)
+'
+
+test_expect_success "run approved hook" '
+	(
+		cd work &&
+		cat >expect<<-EOF &&
+		pre-upload hook: main: 1 commit(s)
+		NOTE: main> will execute command: git push --receive-pack=gerrit receive-pack ssh://committer@ssh.example.com/main.git refs/heads/my/topic1:refs/for/Maint
+		EOF
+		git-repo upload \
+			--assume-yes \
+			--no-edit \
+			--mock-git-push \
+			>out 2>&1 &&
+		grep -e "^pre-upload hook:" -e "will execute command" out >actual &&
+		test_cmp expect actual &&
+		git -C main update-ref -d refs/published/my/topic1
+	)
+'
+
+test_expect_success "failed hook stops upload" '
+	(
+		cd work &&
+		test_must_fail env REJECT_UPLOAD=1 git-repo upload \
+			--assume-yes \
+			--verify \
+			--no-edit \
+			--mock-git-push \
+			>out 2>&1 &&
+		grep "^pre-upload hook: main: 1 commit(s)" out &&
+		grep "^\[FAILED\] main/ *my/topic1" out &&
+		grep "(pre-upload hook failed: exit status 1)" out &&
+		test_must_fail grep "will execute command" out
+	)
+'
+
+test_expect_success "upload --no-verify: skip hook" '
+	(
+		cd work &&
+		REJECT_UPLOAD=1 git-repo upload \
+			--assume-yes \
+			--no-verify \
+			--no-edit \
+			--mock-git-push \
+			>out 2>&1 &&
+		test_must_fail grep "^pre-upload hook:" out &&
+		grep "will execute command" out
+	)
+'
+
+test_done
@@ -95,4 +95,71 @@ test_expect_success "execute cmd (-i module1)" '
 	test_cmp expect actual
 '
 
+test_expect_success "export REPO_RREV and REPO_LREV" '
+	(
+		cd work &&
+		git-repo forall -g app -j 1 -c '"'"'test "$REPO_LREV" = "$(git rev-parse HEAD)" && echo $REPO_PATH $REPO_RREV'"'"'
+	) >actual &&
+	cat >expect<<-EOF &&
+	main master
+	projects/app1 master
+	projects/app1/module1 refs/tags/v1.0.0
+	projects/app2 master
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "continue on errors and report failed projects" '
+	(
+		cd work &&
+		test_must_fail git-repo forall -g app -j 1 -c '"'"'test $REPO_PATH != projects/app1 && echo $REPO_PATH'"'"'
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	main
+	projects/app1/module1
+	projects/app2
+	Error: command failed in 1 project(s): projects/app1
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "abort on errors (-e)" '
+	(
+		cd work &&
+		test_must_fail git-repo forall -g app -j 1 -e -c '"'"'test $REPO_PATH != projects/app1 && echo $REPO_PATH'"'"'
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	main
+	Error: aborted: command failed in projects/app1
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "export annotations of project" '
+	mkdir -p work/.repo/local_manifests &&
+	cat >work/.repo/local_manifests/annotation.xml <<-EOF &&
+	<manifest>
+	  <project name="main" path="main-annotated" groups="annotated">
+	    <annotation name="BUILD_TYPE" value="release" />
+	    <annotation name="SECRET" value="hidden" keep="false" />
+	  </project>
+	</manifest>
+	EOF
+	(
+		cd work &&
+		git-repo sync main-annotated \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	) >out 2>&1 &&
+	(
+		cd work &&
+		git-repo forall -g annotated -c '"'"'echo $REPO_PATH: $REPO__BUILD_TYPE, $REPO__SECRET'"'"'
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	main-annotated: release,
+	EOF
+	test_cmp expect actual
+'
+
 test_done
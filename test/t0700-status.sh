@@ -21,17 +21,12 @@ test_expect_success "setup" '
 	)
 '
 
-test_expect_success "project1 has untracked module1" '
+test_expect_success "nested module1 is excluded from project1" '
 	(
 		cd work &&
 		git-repo status
 	) >actual &&
-	cat >expect<<-EOF &&
-	project projects/app1/                          (*** NO BRANCH ***)
-	 --	module1/
-
-	EOF
-	test_cmp expect actual
+	test_must_be_empty actual
 '
 
 test_expect_success "start new branch" '
@@ -41,8 +36,7 @@ test_expect_success "start new branch" '
 		git-repo status
 	) >actual &&
 	cat >expect<<-EOF &&
-	project projects/app1/                          branch jx/topic
-	 --	module1/
+	project projects/app1/module1/                  branch jx/topic [behind 1]
 
 	EOF
 	test_cmp expect actual
@@ -59,6 +53,8 @@ test_expect_success "ignore module1" '
 	project projects/app1/                          branch jx/topic
 	 A-	.gitignore
 
+	project projects/app1/module1/                  branch jx/topic [behind 1]
+
 	EOF
 	test_cmp expect actual
 '
@@ -73,6 +69,8 @@ test_expect_success "update .gitignore" '
 	project projects/app1/                          branch jx/topic
 	 Am	.gitignore
 
+	project projects/app1/module1/                  branch jx/topic [behind 1]
+
 	EOF
 	test_cmp expect actual
 '
@@ -88,9 +86,49 @@ test_expect_success "working directory clean" '
 		git-repo status
 	) >actual 2>&1 &&
 	cat >expect<<-EOF &&
+	project projects/app1/                          branch jx/topic [ahead 1]
+
+	project projects/app1/module1/                  branch jx/topic [behind 1]
+
 	NOTE: nothing to commit (working directory clean)
 	EOF
 	test_cmp expect actual
 '
 
+test_expect_success "show commits ahead of upstream for dirty project" '
+	(
+		cd work/projects/app1 &&
+		echo hack >>VERSION &&
+		git-repo status
+	) >actual &&
+	cat >expect<<-EOF &&
+	project projects/app1/                          branch jx/topic [ahead 1]
+	 -m	VERSION
+
+	project projects/app1/module1/                  branch jx/topic [behind 1]
+
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "show orphan paths" '
+	(
+		cd work &&
+		mkdir -p drivers/driver-3 &&
+		touch orphan.txt drivers/driver-3/README &&
+		git-repo status -o
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	project projects/app1/                          branch jx/topic [ahead 1]
+	 -m	VERSION
+
+	project projects/app1/module1/                  branch jx/topic [behind 1]
+
+	Objects not within a project (orphans)
+	 --	drivers/driver-3/
+	 --	orphan.txt
+	EOF
+	test_cmp expect actual
+'
+
 test_done
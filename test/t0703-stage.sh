@@ -0,0 +1,93 @@
+#!/bin/sh
+
+test_description="test 'git-repo stage'"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -g all -u $manifest_url &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}" &&
+		git-repo start --all jx/topic
+	)
+'
+
+test_expect_success "stage without -i" '
+	(
+		cd work &&
+		test_must_fail git-repo stage
+	)
+'
+
+test_expect_success "nothing to stage" '
+	(
+		cd work &&
+		git-repo stage -i
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	NOTE: no projects have unstaged changes
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "stage selected projects" '
+	(
+		cd work &&
+		echo hack >>main/VERSION &&
+		echo hack >projects/app1/new-file &&
+		echo hack >>drivers/driver-1/VERSION &&
+		echo "3 projects/app1" | git-repo stage -i
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	Projects with unstaged changes
+	  1: main/
+	  2: projects/app1/
+	  3: drivers/driver-1/
+	Stage which projects ("*" for all, "q" to quit)? NOTE: staged changes in drivers/driver-1
+	NOTE: staged changes in projects/app1
+	EOF
+	test_cmp expect actual &&
+	(
+		cd work &&
+		git-repo status -j 1
+	) >actual &&
+	cat >expect<<-EOF &&
+	project main/                                   branch jx/topic
+	 -m	VERSION
+
+	project projects/app1/                          branch jx/topic
+	 A-	new-file
+
+	project projects/app1/module1/                  branch jx/topic [behind 1]
+
+	project drivers/driver-1/                       branch jx/topic
+	 M-	VERSION
+
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "quit without staging" '
+	(
+		cd work &&
+		echo q | git-repo stage -i
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	Projects with unstaged changes
+	  1: main/
+	Stage which projects ("*" for all, "q" to quit)? NOTE: nothing staged
+	EOF
+	test_cmp expect actual
+'
+
+test_done
@@ -139,4 +139,36 @@ test_expect_success "download failed using ff-only" '
 	test_cmp expect actual
 '
 
+test_expect_success "restore using sync and start again" '
+	(
+		cd work &&
+		git-repo sync --detach \
+			--no-cache \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response "ssh.example.com 29418" &&
+		git-repo start --all jx/topic
+	)
+'
+
+test_expect_success "download latest patch set if no patch set provided" '
+	(
+		cd work &&
+		git-repo download \
+			--no-cache \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response "ssh.example.com 29418" \
+			main 12345
+	) &&
+	(
+		cd work/main &&
+		git log --pretty="    %s" -1 &&
+		cat topic.txt
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	    New topic
+	main: patch-2
+	EOF
+	test_cmp expect actual
+'
+
 test_done
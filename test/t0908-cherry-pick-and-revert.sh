@@ -0,0 +1,120 @@
+#!/bin/sh
+
+test_description="test 'git-repo cherry-pick' and 'git-repo revert'"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url &&
+		git-repo sync \
+			--no-cache \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response "ssh.example.com 29418" &&
+		git-repo start --all jx/topic
+	)
+'
+
+test_expect_success "create commit in another branch" '
+	(
+		cd work/main &&
+		git checkout -q -b jx/other &&
+		echo hack >hack.txt &&
+		git add hack.txt &&
+		test_tick &&
+		git commit -q -F - <<-EOF &&
+		hack: new file
+
+		Change-Id: I1234567890123456789012345678901234567890
+		EOF
+		git checkout -q jx/topic
+	)
+'
+
+test_expect_success "cherry-pick commit in project of current dir" '
+	(
+		cd work/main &&
+		git-repo cherry-pick jx/other
+	) &&
+	(
+		cd work/main &&
+		echo "Branch: $(git branch -l | grep "^*")" &&
+		git log --pretty="    %s" -2 &&
+		test -f hack.txt &&
+		git log -1 --pretty=%B >msg &&
+		test $(grep -c "^Change-Id: I" msg) -eq 1 &&
+		! grep I1234567890123456789012345678901234567890 msg &&
+		grep "^(cherry picked from commit $(git rev-parse jx/other))" msg
+	) >out 2>&1 &&
+	head -3 out >actual &&
+	cat >expect<<-EOF &&
+	Branch: * jx/topic
+	    hack: new file
+	    Version 2.0.0-dev
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "revert commit found in all projects" '
+	(
+		cd work &&
+		commit=$(git -C main rev-parse --short HEAD) &&
+		git-repo revert $commit
+	) &&
+	(
+		cd work/main &&
+		git log --pretty="    %s" -3 &&
+		test ! -f hack.txt &&
+		git log -1 --pretty=%B | grep "^Change-Id: I"
+	) >out 2>&1 &&
+	head -3 out >actual &&
+	cat >expect<<-EOF &&
+	    Revert "hack: new file"
+	    hack: new file
+	    Version 2.0.0-dev
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "cannot find commit" '
+	(
+		cd work/main &&
+		test_must_fail git-repo cherry-pick no-such-commit
+	) >out 2>&1 &&
+	head -1 out >actual &&
+	cat >expect<<-EOF &&
+	Error: main> cannot find commit '"'"'no-such-commit'"'"'
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "cherry-pick change from review system" '
+	(
+		cd work &&
+		git-repo cherry-pick \
+			--no-cache \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response "ssh.example.com 29418" \
+			main 12345/2
+	) &&
+	(
+		cd work/main &&
+		echo "Branch: $(git branch -l | grep "^*")" &&
+		git log --pretty="    %s" -2
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	Branch: * jx/topic
+	    New topic
+	    Revert "hack: new file"
+	EOF
+	test_cmp expect actual
+'
+
+test_done
@@ -517,4 +517,63 @@ test_expect_success "git-repo list -n -p -f -g app -r ^pro.*" '
 	test_cmp expect actual
 '
 
+test_expect_success "git-repo list ." '
+	(
+		cd work/projects/app1 &&
+		git-repo list . &&
+		git-repo list module1 ../app2
+	) >actual &&
+	cat >expect<<-EOF &&
+	projects/app1 : project1
+	projects/app1/module1 : project1/module1
+	projects/app2 : project2
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git-repo list --relative-to" '
+	(
+		cd work/projects &&
+		git-repo list --relative-to . -r app &&
+		git-repo list --relative-to ../drivers -p -r driver1
+	) >actual &&
+	cat >expect<<-EOF &&
+	app1 : project1
+	app1/module1 : project1/module1
+	app2 : project2
+	driver-1
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git-repo list with wildcards" '
+	(
+		cd work &&
+		git-repo list "projects/app*" &&
+		git-repo list -n "drivers/*"
+	) >actual &&
+	cat >expect<<-EOF &&
+	projects/app1 : project1
+	projects/app2 : project2
+	drivers/driver1
+	drivers/driver2
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git-repo list with unknown projects" '
+	(
+		cd work &&
+		test_must_fail git-repo list nonexist >out 2>&1 &&
+		head -1 out &&
+		test_must_fail git-repo list "nonexist*" >out 2>&1 &&
+		head -1 out
+	) >actual &&
+	cat >expect<<-EOF &&
+	Error: cannot find project with name/path '"'"'nonexist'"'"'
+	Error: no project matches pattern '"'"'nonexist*'"'"'
+	EOF
+	test_cmp expect actual
+'
+
 test_done
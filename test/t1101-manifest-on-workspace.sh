@@ -27,19 +27,22 @@ test_expect_success "git repo manifest: show manifest of Maint branch" '
 		git-repo manifest
 	) >actual &&
 	cat >expect<<-EOF &&
+	<?xml version="1.0" encoding="UTF-8"?>
 	<manifest>
-	  <remote name="aone" alias="origin" fetch="." review="https://example.com"></remote>
-	  <remote name="driver" fetch=".." review="https://example.com"></remote>
-	  <default remote="aone" revision="Maint" sync-j="4"></default>
-	  <project name="main" path="main" groups="app">
-	    <copyfile src="VERSION" dest="VERSION"></copyfile>
-	    <linkfile src="Makefile" dest="Makefile"></linkfile>
+	  <remote name="aone" fetch="." alias="origin" review="https://example.com"/>
+	  <remote name="driver" fetch=".." review="https://example.com"/>
+	  
+	  <default remote="aone" revision="Maint" sync-j="4"/>
+	  
+	  <project name="drivers/driver1" path="drivers/driver-1" remote="driver" groups="drivers"/>
+	  <project name="drivers/driver2" path="drivers/driver-2" remote="driver" groups="notdefault,drivers"/>
+	  <project name="main" groups="app">
+	    <copyfile src="VERSION" dest="VERSION"/>
+	    <linkfile src="Makefile" dest="Makefile"/>
 	  </project>
-	  <project name="project1" path="projects/app1" groups="app"></project>
-	  <project name="project1/module1" path="projects/app1/module1" revision="refs/tags/v0.2.0" groups="app"></project>
-	  <project name="project2" path="projects/app2" groups="app"></project>
-	  <project name="drivers/driver1" path="drivers/driver-1" remote="driver" groups="drivers"></project>
-	  <project name="drivers/driver2" path="drivers/driver-2" remote="driver" groups="notdefault,drivers"></project>
+	  <project name="project1" path="projects/app1" groups="app"/>
+	  <project name="project1/module1" path="projects/app1/module1" revision="refs/tags/v0.2.0" groups="app"/>
+	  <project name="project2" path="projects/app2" groups="app"/>
 	</manifest>
 	EOF
 	test_cmp expect actual
@@ -52,19 +55,22 @@ test_expect_success "git repo manifest -o actual" '
 		git-repo manifest -o ../actual
 	) &&
 	cat >expect<<-EOF &&
+	<?xml version="1.0" encoding="UTF-8"?>
 	<manifest>
-	  <remote name="aone" alias="origin" fetch="." review="https://example.com"></remote>
-	  <remote name="driver" fetch=".." review="https://example.com"></remote>
-	  <default remote="aone" revision="Maint" sync-j="4"></default>
-	  <project name="main" path="main" groups="app">
-	    <copyfile src="VERSION" dest="VERSION"></copyfile>
-	    <linkfile src="Makefile" dest="Makefile"></linkfile>
+	  <remote name="aone" fetch="." alias="origin" review="https://example.com"/>
+	  <remote name="driver" fetch=".." review="https://example.com"/>
+	  
+	  <default remote="aone" revision="Maint" sync-j="4"/>
+	  
+	  <project name="drivers/driver1" path="drivers/driver-1" remote="driver" groups="drivers"/>
+	  <project name="drivers/driver2" path="drivers/driver-2" remote="driver" groups="notdefault,drivers"/>
+	  <project name="main" groups="app">
+	    <copyfile src="VERSION" dest="VERSION"/>
+	    <linkfile src="Makefile" dest="Makefile"/>
 	  </project>
-	  <project name="project1" path="projects/app1" groups="app"></project>
-	  <project name="project1/module1" path="projects/app1/module1" revision="refs/tags/v0.2.0" groups="app"></project>
-	  <project name="project2" path="projects/app2" groups="app"></project>
-	  <project name="drivers/driver1" path="drivers/driver-1" remote="driver" groups="drivers"></project>
-	  <project name="drivers/driver2" path="drivers/driver-2" remote="driver" groups="notdefault,drivers"></project>
+	  <project name="project1" path="projects/app1" groups="app"/>
+	  <project name="project1/module1" path="projects/app1/module1" revision="refs/tags/v0.2.0" groups="app"/>
+	  <project name="project2" path="projects/app2" groups="app"/>
 	</manifest>
 	EOF
 	test_cmp expect actual
@@ -84,19 +90,22 @@ test_expect_success "git repo manifest: show manifest of master branch" '
 		git-repo manifest
 	) >actual 2>&1 &&
 	cat >expect<<-EOF &&
+	<?xml version="1.0" encoding="UTF-8"?>
 	<manifest>
-	  <remote name="aone" alias="origin" fetch="." review="https://example.com"></remote>
-	  <remote name="driver" fetch=".." review="https://example.com" revision="Maint"></remote>
-	  <default remote="aone" revision="master" sync-j="4"></default>
-	  <project name="main" path="main" groups="app">
-	    <copyfile src="VERSION" dest="VERSION"></copyfile>
-	    <linkfile src="Makefile" dest="Makefile"></linkfile>
+	  <remote name="aone" fetch="." alias="origin" review="https://example.com"/>
+	  <remote name="driver" fetch=".." review="https://example.com" revision="Maint"/>
+	  
+	  <default remote="aone" revision="master" sync-j="4"/>
+	  
+	  <project name="drivers/driver1" path="drivers/driver-1" remote="driver" groups="drivers"/>
+	  <project name="drivers/driver2" path="drivers/driver-2" remote="driver" groups="notdefault,drivers"/>
+	  <project name="main" groups="app">
+	    <copyfile src="VERSION" dest="VERSION"/>
+	    <linkfile src="Makefile" dest="Makefile"/>
 	  </project>
-	  <project name="project1" path="projects/app1" groups="app"></project>
-	  <project name="project1/module1" path="projects/app1/module1" revision="refs/tags/v1.0.0" groups="app"></project>
-	  <project name="project2" path="projects/app2" groups="app"></project>
-	  <project name="drivers/driver1" path="drivers/driver-1" remote="driver" groups="drivers"></project>
-	  <project name="drivers/driver2" path="drivers/driver-2" remote="driver" groups="notdefault,drivers"></project>
+	  <project name="project1" path="projects/app1" groups="app"/>
+	  <project name="project1/module1" path="projects/app1/module1" revision="refs/tags/v1.0.0" groups="app"/>
+	  <project name="project2" path="projects/app2" groups="app"/>
 	</manifest>
 	EOF
 	test_cmp expect actual
@@ -108,19 +117,22 @@ test_expect_success "git repo manifest: freeze manifest" '
 		git-repo manifest -r
 	) >actual 2>&1 &&
 	cat >expect<<-EOF &&
+	<?xml version="1.0" encoding="UTF-8"?>
 	<manifest>
-	  <remote name="aone" alias="origin" fetch="." review="https://example.com"></remote>
-	  <remote name="driver" fetch=".." review="https://example.com" revision="Maint"></remote>
-	  <default remote="aone" revision="master" sync-j="4"></default>
-	  <project name="main" path="main" revision="4d13a6c1a2c17fcb3b109f2b1586d1485463e636" groups="app" upstream="master">
-	    <copyfile src="VERSION" dest="VERSION"></copyfile>
-	    <linkfile src="Makefile" dest="Makefile"></linkfile>
+	  <remote name="aone" fetch="." alias="origin" review="https://example.com"/>
+	  <remote name="driver" fetch=".." review="https://example.com" revision="Maint"/>
+	  
+	  <default remote="aone" revision="master" sync-j="4"/>
+	  
+	  <project name="drivers/driver1" path="drivers/driver-1" remote="driver" revision="faa6f5cedc80d51cb57505376ef99878b66cd020" upstream="Maint" dest-branch="Maint" groups="drivers"/>
+	  <project name="drivers/driver2" path="drivers/driver-2" remote="driver" revision="df3d4c64f8d3be5365e1c778ba77976bda701c32" upstream="Maint" dest-branch="Maint" groups="notdefault,drivers"/>
+	  <project name="main" revision="4d13a6c1a2c17fcb3b109f2b1586d1485463e636" upstream="master" dest-branch="master" groups="app">
+	    <copyfile src="VERSION" dest="VERSION"/>
+	    <linkfile src="Makefile" dest="Makefile"/>
 	  </project>
-	  <project name="project1" path="projects/app1" revision="2fdfd9b9ff3bb556a74363bd0dacec0d29a0cc2a" groups="app" upstream="master"></project>
-	  <project name="project1/module1" path="projects/app1/module1" revision="8fc882db0d6eaa24013f4ee3772e6765eb920d21" groups="app" upstream="refs/tags/v1.0.0"></project>
-	  <project name="project2" path="projects/app2" revision="98dc74a3fac99714338633327dbab62b5189375b" groups="app" upstream="master"></project>
-	  <project name="drivers/driver1" path="drivers/driver-1" remote="driver" revision="faa6f5cedc80d51cb57505376ef99878b66cd020" groups="drivers" upstream="Maint"></project>
-	  <project name="drivers/driver2" path="drivers/driver-2" remote="driver" revision="df3d4c64f8d3be5365e1c778ba77976bda701c32" groups="notdefault,drivers" upstream="Maint"></project>
+	  <project name="project1" path="projects/app1" revision="2fdfd9b9ff3bb556a74363bd0dacec0d29a0cc2a" upstream="master" dest-branch="master" groups="app"/>
+	  <project name="project1/module1" path="projects/app1/module1" revision="8fc882db0d6eaa24013f4ee3772e6765eb920d21" upstream="refs/tags/v1.0.0" groups="app"/>
+	  <project name="project2" path="projects/app2" revision="98dc74a3fac99714338633327dbab62b5189375b" upstream="master" dest-branch="master" groups="app"/>
 	</manifest>
 	EOF
 	test_cmp expect actual
@@ -132,22 +144,95 @@ test_expect_success "git repo manifest: freeze manifest --suppress-upstream-revi
 		git-repo manifest -r --suppress-upstream-revision
 	) >actual 2>&1 &&
 	cat >expect<<-EOF &&
+	<?xml version="1.0" encoding="UTF-8"?>
 	<manifest>
-	  <remote name="aone" alias="origin" fetch="." review="https://example.com"></remote>
-	  <remote name="driver" fetch=".." review="https://example.com" revision="Maint"></remote>
-	  <default remote="aone" revision="master" sync-j="4"></default>
-	  <project name="main" path="main" revision="4d13a6c1a2c17fcb3b109f2b1586d1485463e636" groups="app">
-	    <copyfile src="VERSION" dest="VERSION"></copyfile>
-	    <linkfile src="Makefile" dest="Makefile"></linkfile>
+	  <remote name="aone" fetch="." alias="origin" review="https://example.com"/>
+	  <remote name="driver" fetch=".." review="https://example.com" revision="Maint"/>
+	  
+	  <default remote="aone" revision="master" sync-j="4"/>
+	  
+	  <project name="drivers/driver1" path="drivers/driver-1" remote="driver" revision="faa6f5cedc80d51cb57505376ef99878b66cd020" dest-branch="Maint" groups="drivers"/>
+	  <project name="drivers/driver2" path="drivers/driver-2" remote="driver" revision="df3d4c64f8d3be5365e1c778ba77976bda701c32" dest-branch="Maint" groups="notdefault,drivers"/>
+	  <project name="main" revision="4d13a6c1a2c17fcb3b109f2b1586d1485463e636" dest-branch="master" groups="app">
+	    <copyfile src="VERSION" dest="VERSION"/>
+	    <linkfile src="Makefile" dest="Makefile"/>
 	  </project>
-	  <project name="project1" path="projects/app1" revision="2fdfd9b9ff3bb556a74363bd0dacec0d29a0cc2a" groups="app"></project>
-	  <project name="project1/module1" path="projects/app1/module1" revision="8fc882db0d6eaa24013f4ee3772e6765eb920d21" groups="app"></project>
-	  <project name="project2" path="projects/app2" revision="98dc74a3fac99714338633327dbab62b5189375b" groups="app"></project>
-	  <project name="drivers/driver1" path="drivers/driver-1" remote="driver" revision="faa6f5cedc80d51cb57505376ef99878b66cd020" groups="drivers"></project>
-	  <project name="drivers/driver2" path="drivers/driver-2" remote="driver" revision="df3d4c64f8d3be5365e1c778ba77976bda701c32" groups="notdefault,drivers"></project>
+	  <project name="project1" path="projects/app1" revision="2fdfd9b9ff3bb556a74363bd0dacec0d29a0cc2a" dest-branch="master" groups="app"/>
+	  <project name="project1/module1" path="projects/app1/module1" revision="8fc882db0d6eaa24013f4ee3772e6765eb920d21" groups="app"/>
+	  <project name="project2" path="projects/app2" revision="98dc74a3fac99714338633327dbab62b5189375b" dest-branch="master" groups="app"/>
 	</manifest>
 	EOF
 	test_cmp expect actual
 '
 
+test_expect_success "git repo manifest: freeze manifest --suppress-upstream-revision --suppress-dest-branch" '
+	(
+		cd work &&
+		git-repo manifest -r --suppress-upstream-revision --suppress-dest-branch
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	<?xml version="1.0" encoding="UTF-8"?>
+	<manifest>
+	  <remote name="aone" fetch="." alias="origin" review="https://example.com"/>
+	  <remote name="driver" fetch=".." review="https://example.com" revision="Maint"/>
+	  
+	  <default remote="aone" revision="master" sync-j="4"/>
+	  
+	  <project name="drivers/driver1" path="drivers/driver-1" remote="driver" revision="faa6f5cedc80d51cb57505376ef99878b66cd020" groups="drivers"/>
+	  <project name="drivers/driver2" path="drivers/driver-2" remote="driver" revision="df3d4c64f8d3be5365e1c778ba77976bda701c32" groups="notdefault,drivers"/>
+	  <project name="main" revision="4d13a6c1a2c17fcb3b109f2b1586d1485463e636" groups="app">
+	    <copyfile src="VERSION" dest="VERSION"/>
+	    <linkfile src="Makefile" dest="Makefile"/>
+	  </project>
+	  <project name="project1" path="projects/app1" revision="2fdfd9b9ff3bb556a74363bd0dacec0d29a0cc2a" groups="app"/>
+	  <project name="project1/module1" path="projects/app1/module1" revision="8fc882db0d6eaa24013f4ee3772e6765eb920d21" groups="app"/>
+	  <project name="project2" path="projects/app2" revision="98dc74a3fac99714338633327dbab62b5189375b" groups="app"/>
+	</manifest>
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git repo manifest --validate: no problems" '
+	(
+		cd work &&
+		git-repo manifest --validate
+	) >actual 2>&1 &&
+	cat >expect<<-EOF &&
+	NOTE: no problems found in manifest files
+	EOF
+	test_cmp expect actual
+'
+
+test_expect_success "git repo manifest --validate: unknown fields in local manifest" '
+	mkdir -p work/.repo/local_manifests &&
+	cat >work/.repo/local_manifests/local.xml <<-EOF &&
+	<manifest>
+	  <project name="project3" path="projects/app3" revison="master"/>
+	  <superproject name="super"/>
+	</manifest>
+	EOF
+	(
+		cd work &&
+		test_must_fail git-repo manifest --validate
+	) >actual 2>&1 &&
+	sed -e "s#$(pwd)/##" actual >actual.out &&
+	cat >expect<<-EOF &&
+	work/.repo/local_manifests/local.xml:2: unknown attribute '"'"'revison'"'"' in element <project>
+	work/.repo/local_manifests/local.xml:3: unknown element <superproject>
+	Error: 2 problem(s) found in manifest files
+	EOF
+	test_cmp expect actual.out
+'
+
+test_expect_success "git repo manifest: fail in strict mode" '
+	test_when_finished "git config --global --unset repo.manifestStrict" &&
+	git config --global repo.manifestStrict true &&
+	(
+		cd work &&
+		test_must_fail git-repo manifest
+	) >actual 2>&1 &&
+	grep "unknown fields in manifest file" actual &&
+	rm work/.repo/local_manifests/local.xml
+'
+
 test_done
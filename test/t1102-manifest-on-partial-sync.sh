@@ -27,19 +27,22 @@ test_expect_success "git repo manifest: show manifest of Maint branch" '
 		git-repo manifest
 	) >actual &&
 	cat >expect<<-EOF &&
+	<?xml version="1.0" encoding="UTF-8"?>
 	<manifest>
-	  <remote name="aone" alias="origin" fetch="." review="https://example.com"></remote>
-	  <remote name="driver" fetch=".." review="https://example.com"></remote>
-	  <default remote="aone" revision="Maint" sync-j="4"></default>
-	  <project name="main" path="main" groups="app">
-	    <copyfile src="VERSION" dest="VERSION"></copyfile>
-	    <linkfile src="Makefile" dest="Makefile"></linkfile>
+	  <remote name="aone" fetch="." alias="origin" review="https://example.com"/>
+	  <remote name="driver" fetch=".." review="https://example.com"/>
+	  
+	  <default remote="aone" revision="Maint" sync-j="4"/>
+	  
+	  <project name="drivers/driver1" path="drivers/driver-1" remote="driver" groups="drivers"/>
+	  <project name="drivers/driver2" path="drivers/driver-2" remote="driver" groups="notdefault,drivers"/>
+	  <project name="main" groups="app">
+	    <copyfile src="VERSION" dest="VERSION"/>
+	    <linkfile src="Makefile" dest="Makefile"/>
 	  </project>
-	  <project name="project1" path="projects/app1" groups="app"></project>
-	  <project name="project1/module1" path="projects/app1/module1" revision="refs/tags/v0.2.0" groups="app"></project>
-	  <project name="project2" path="projects/app2" groups="app"></project>
-	  <project name="drivers/driver1" path="drivers/driver-1" remote="driver" groups="drivers"></project>
-	  <project name="drivers/driver2" path="drivers/driver-2" remote="driver" groups="notdefault,drivers"></project>
+	  <project name="project1" path="projects/app1" groups="app"/>
+	  <project name="project1/module1" path="projects/app1/module1" revision="refs/tags/v0.2.0" groups="app"/>
+	  <project name="project2" path="projects/app2" groups="app"/>
 	</manifest>
 	EOF
 	test_cmp expect actual
@@ -55,19 +58,22 @@ test_expect_success "git repo manifest: freeze manifest with partial sync" '
 	WARNING: repository for drivers/driver1 is missing, fail to parse HEAD
 	ERROR: cannot open git repo '"'"'.../work/.repo/projects/drivers/driver-2.git'"'"': repository does not exist
 	WARNING: repository for drivers/driver2 is missing, fail to parse HEAD
+	<?xml version="1.0" encoding="UTF-8"?>
 	<manifest>
-	  <remote name="aone" alias="origin" fetch="." review="https://example.com"></remote>
-	  <remote name="driver" fetch=".." review="https://example.com"></remote>
-	  <default remote="aone" revision="Maint" sync-j="4"></default>
-	  <project name="main" path="main" revision="9bf4b931514c8eab528d41bc557949213a529846" groups="app" upstream="Maint">
-	    <copyfile src="VERSION" dest="VERSION"></copyfile>
-	    <linkfile src="Makefile" dest="Makefile"></linkfile>
+	  <remote name="aone" fetch="." alias="origin" review="https://example.com"/>
+	  <remote name="driver" fetch=".." review="https://example.com"/>
+	  
+	  <default remote="aone" revision="Maint" sync-j="4"/>
+	  
+	  <project name="drivers/driver1" path="drivers/driver-1" remote="driver" groups="drivers"/>
+	  <project name="drivers/driver2" path="drivers/driver-2" remote="driver" groups="notdefault,drivers"/>
+	  <project name="main" revision="9bf4b931514c8eab528d41bc557949213a529846" upstream="Maint" dest-branch="Maint" groups="app">
+	    <copyfile src="VERSION" dest="VERSION"/>
+	    <linkfile src="Makefile" dest="Makefile"/>
 	  </project>
-	  <project name="project1" path="projects/app1" revision="a3946522edb40ee1693e879944ff35c7f379c608" groups="app" upstream="Maint"></project>
-	  <project name="project1/module1" path="projects/app1/module1" revision="260da37cd2a35272375f0f3e64d917765b1d05e8" groups="app" upstream="refs/tags/v0.2.0"></project>
-	  <project name="project2" path="projects/app2" revision="a256c3712bbe2bef657e64b3e8ac244b9e709dc4" groups="app" upstream="Maint"></project>
-	  <project name="drivers/driver1" path="drivers/driver-1" remote="driver" groups="drivers"></project>
-	  <project name="drivers/driver2" path="drivers/driver-2" remote="driver" groups="notdefault,drivers"></project>
+	  <project name="project1" path="projects/app1" revision="a3946522edb40ee1693e879944ff35c7f379c608" upstream="Maint" dest-branch="Maint" groups="app"/>
+	  <project name="project1/module1" path="projects/app1/module1" revision="260da37cd2a35272375f0f3e64d917765b1d05e8" upstream="refs/tags/v0.2.0" groups="app"/>
+	  <project name="project2" path="projects/app2" revision="a256c3712bbe2bef657e64b3e8ac244b9e709dc4" upstream="Maint" dest-branch="Maint" groups="app"/>
 	</manifest>
 	EOF
 	test_cmp expect actual
@@ -83,19 +89,22 @@ test_expect_success "git repo manifest: freeze manifest with partial sync, --sup
 	WARNING: repository for drivers/driver1 is missing, fail to parse HEAD
 	ERROR: cannot open git repo '"'"'.../work/.repo/projects/drivers/driver-2.git'"'"': repository does not exist
 	WARNING: repository for drivers/driver2 is missing, fail to parse HEAD
+	<?xml version="1.0" encoding="UTF-8"?>
 	<manifest>
-	  <remote name="aone" alias="origin" fetch="." review="https://example.com"></remote>
-	  <remote name="driver" fetch=".." review="https://example.com"></remote>
-	  <default remote="aone" revision="Maint" sync-j="4"></default>
-	  <project name="main" path="main" revision="9bf4b931514c8eab528d41bc557949213a529846" groups="app">
-	    <copyfile src="VERSION" dest="VERSION"></copyfile>
-	    <linkfile src="Makefile" dest="Makefile"></linkfile>
+	  <remote name="aone" fetch="." alias="origin" review="https://example.com"/>
+	  <remote name="driver" fetch=".." review="https://example.com"/>
+	  
+	  <default remote="aone" revision="Maint" sync-j="4"/>
+	  
+	  <project name="drivers/driver1" path="drivers/driver-1" remote="driver" groups="drivers"/>
+	  <project name="drivers/driver2" path="drivers/driver-2" remote="driver" groups="notdefault,drivers"/>
+	  <project name="main" revision="9bf4b931514c8eab528d41bc557949213a529846" dest-branch="Maint" groups="app">
+	    <copyfile src="VERSION" dest="VERSION"/>
+	    <linkfile src="Makefile" dest="Makefile"/>
 	  </project>
-	  <project name="project1" path="projects/app1" revision="a3946522edb40ee1693e879944ff35c7f379c608" groups="app"></project>
-	  <project name="project1/module1" path="projects/app1/module1" revision="260da37cd2a35272375f0f3e64d917765b1d05e8" groups="app"></project>
-	  <project name="project2" path="projects/app2" revision="a256c3712bbe2bef657e64b3e8ac244b9e709dc4" groups="app"></project>
-	  <project name="drivers/driver1" path="drivers/driver-1" remote="driver" groups="drivers"></project>
-	  <project name="drivers/driver2" path="drivers/driver-2" remote="driver" groups="notdefault,drivers"></project>
+	  <project name="project1" path="projects/app1" revision="a3946522edb40ee1693e879944ff35c7f379c608" dest-branch="Maint" groups="app"/>
+	  <project name="project1/module1" path="projects/app1/module1" revision="260da37cd2a35272375f0f3e64d917765b1d05e8" groups="app"/>
+	  <project name="project2" path="projects/app2" revision="a256c3712bbe2bef657e64b3e8ac244b9e709dc4" dest-branch="Maint" groups="app"/>
 	</manifest>
 	EOF
 	test_cmp expect actual
@@ -91,6 +91,21 @@ test_expect_success "create branches and dirty worktree" '
 #  * drivers/driver-1: README.md (M)
 #  * drivers/driver-2: (Detached) new-commit, and README.md (M)
 #
+test_expect_success "git-repo prune --dryrun" '
+	(
+		cd work &&
+		git-repo branches >expect &&
+		git-repo prune --dryrun >out 2>&1 &&
+		git-repo branches >actual &&
+		test_cmp expect actual &&
+		head -1 out
+	) >actual &&
+	cat >expect<<-EOF &&
+	Branches to be pruned (dryrun)
+	EOF
+	test_cmp expect actual
+'
+
 test_expect_success "git-repo prune all" '
 	(
 		cd work &&
@@ -0,0 +1,58 @@
+#!/bin/sh
+
+test_description="test 'git-repo gc'"
+
+. ./lib/sharness.sh
+
+# Create manifest repositories
+manifest_url="file://${REPO_TEST_REPOSITORIES}/hello/manifests"
+
+test_expect_success "setup" '
+	# create .repo file as a barrier, not find .repo deeper
+	touch .repo &&
+	mkdir work &&
+	(
+		cd work &&
+		git-repo init -u $manifest_url &&
+		git config -f .repo/manifests.git/config repo.gc never &&
+		git-repo sync \
+			--mock-ssh-info-status 200 \
+			--mock-ssh-info-response \
+			"{\"host\":\"ssh.example.com\", \"port\":22, \"type\":\"agit\"}"
+	)
+'
+
+test_expect_success "create loose objects" '
+	(
+		cd work/projects/app1 &&
+		for i in 1 2 3
+		do
+			echo "loose object $i" >loose-$i.txt &&
+			git add loose-$i.txt &&
+			test_tick &&
+			git commit -q -m "loose object $i" || return 1
+		done
+	) >/dev/null
+'
+
+test_expect_success "git-repo gc --auto skips object stores" '
+	(
+		cd work &&
+		git-repo gc --auto -j 1 >out 2>&1 &&
+		grep "nothing to gc" out
+	)
+'
+
+test_expect_success "git-repo gc packs loose objects" '
+	(
+		cd work &&
+		git-repo gc -j 1 &&
+		cd projects/app1 &&
+		git count-objects -v >out &&
+		grep "^count:" out >actual &&
+		echo "count: 0" >expect &&
+		test_cmp expect actual
+	)
+'
+
+test_done
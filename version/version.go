@@ -23,7 +23,7 @@ import (
 	"strconv"
 	"strings"
 
-	log "github.com/jiangxin/multi-log"
+	"github.com/alibaba/git-repo-go/log"
 )
 
 var (
@@ -131,23 +131,12 @@ func CompareVersion(_left, _right string) int {
 	return 0
 }
 
-// ValidateGitVersion is used to check installed git version.
-func ValidateGitVersion() {
-	var (
-		// lower conflict verison
-		lcVersion string
-		// higher compatible version
-		hcVersion      string
-		messages       []string
-		suppressIssues bool
-	)
-
-	if _, ok := os.LookupEnv("GIT_REPO_SUPPRESS_COMPATIBLE_ISSUES"); ok {
-		suppressIssues = true
-	}
-
+// CheckGitVersion checks compatible issues of gitVersion. Returns the
+// lowest version required (lcVersion), the version suggested (hcVersion),
+// and messages of issues.
+func CheckGitVersion(gitVersion string) (lcVersion, hcVersion string, messages []string) {
 	for _, issue := range gitCompatibleIssues {
-		if CompareVersion(GitVersion, issue.Version) < 0 {
+		if CompareVersion(gitVersion, issue.Version) < 0 {
 			if issue.Fatal {
 				if CompareVersion(issue.Version, lcVersion) > 0 {
 					lcVersion = issue.Version
@@ -160,6 +149,22 @@ func ValidateGitVersion() {
 			messages = append(messages, issue.Message)
 		}
 	}
+	return
+}
+
+// ValidateGitVersion is used to check installed git version.
+func ValidateGitVersion() {
+	var (
+		suppressIssues bool
+	)
+
+	if _, ok := os.LookupEnv("GIT_REPO_SUPPRESS_COMPATIBLE_ISSUES"); ok {
+		suppressIssues = true
+	}
+
+	// lcVersion is lower conflict verison, and hcVersion is higher
+	// compatible version.
+	lcVersion, hcVersion, messages := CheckGitVersion(GitVersion)
 
 	if GitVersion == "" {
 		log.Errorf("Please install git to version %s or above", lcVersion)
@@ -19,3 +19,22 @@ func TestCompareVersion(t *testing.T) {
 	assert.Equal(1, CompareVersion("0.1.0", "undefined"))
 	assert.Equal(-1, CompareVersion("undefined", "0.1.0"))
 }
+
+func TestCheckGitVersion(t *testing.T) {
+	assert := assert.New(t)
+
+	lcVersion, hcVersion, messages := CheckGitVersion("1.7.1")
+	assert.Equal("1.7.10", lcVersion)
+	assert.Equal("2.10.0", hcVersion)
+	assert.Equal(4, len(messages))
+
+	lcVersion, hcVersion, messages = CheckGitVersion("2.9.1")
+	assert.Equal("", lcVersion)
+	assert.Equal("2.10.0", hcVersion)
+	assert.Equal(1, len(messages))
+
+	lcVersion, hcVersion, messages = CheckGitVersion("2.20.1")
+	assert.Equal("", lcVersion)
+	assert.Equal("", hcVersion)
+	assert.Nil(messages)
+}
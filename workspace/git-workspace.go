@@ -5,10 +5,10 @@ import (
 	"net/http"
 	"path/filepath"
 
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/manifest"
 	"github.com/alibaba/git-repo-go/path"
 	"github.com/alibaba/git-repo-go/project"
-	log "github.com/jiangxin/multi-log"
 )
 
 var (
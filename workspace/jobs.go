@@ -0,0 +1,21 @@
+package workspace
+
+import (
+	"github.com/alibaba/git-repo-go/config"
+)
+
+// Jobs returns number of jobs to run simultaneously. Sync-j of manifest
+// default is overridden by jobs in settings of workspace, which is
+// overridden by jobsOption (value of --jobs, or 0 if not given).
+// Returns fallback if none of them is set.
+func (v RepoWorkSpace) Jobs(jobsOption, fallback int) int {
+	var syncJ, wsJobs int
+
+	if v.Manifest != nil && v.Manifest.Default != nil {
+		syncJ = v.Manifest.Default.SyncJ
+	}
+	if v.ManifestProject != nil && v.ManifestProject.Settings != nil {
+		wsJobs = v.ManifestProject.Settings.Jobs
+	}
+	return config.EffectiveJobs(fallback, syncJ, wsJobs, jobsOption)
+}
@@ -0,0 +1,49 @@
+package workspace
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepoWorkSpaceJobs(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	workdir := filepath.Join(tmpdir, "workdir")
+	assert.Nil(os.MkdirAll(workdir, 0755))
+	assert.Nil(testCreateManifests(workdir, "https://example.com/manifest.git"))
+	assert.Nil(os.Symlink(filepath.Join(workdir, ".repo", "manifests", "m1.xml"),
+		filepath.Join(workdir, ".repo", "manifest.xml")))
+	ws, err := NewRepoWorkSpace(workdir)
+	assert.Nil(err)
+
+	// No sync-j in manifest.
+	assert.Equal(4, ws.Jobs(0, 4))
+	assert.Equal(2, ws.Jobs(2, 4))
+
+	ws.Manifest.Default.SyncJ = 8
+	assert.Equal(8, ws.Jobs(0, 4))
+	assert.Equal(2, ws.Jobs(2, 4))
+
+	ws.Settings().Jobs = 6
+	assert.Equal(6, ws.Jobs(0, 4))
+	assert.Equal(2, ws.Jobs(2, 4))
+
+	// Git config of workspace overrides the settings file.
+	cfg := ws.ManifestProject.Config()
+	cfg.Set(config.CfgRepoJobs, 3)
+	assert.Nil(ws.ManifestProject.SaveConfig(cfg))
+	ws.ManifestProject.ReadSettings()
+	assert.Equal(3, ws.Jobs(0, 4))
+	assert.Equal(2, ws.Jobs(2, 4))
+}
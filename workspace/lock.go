@@ -0,0 +1,40 @@
+package workspace
+
+import (
+	"path/filepath"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
+)
+
+// lockFile returns lock of workspace.
+func (v RepoWorkSpace) lockFile() *helper.LockFile {
+	return helper.NewLockFile(filepath.Join(v.AdminDir(), config.RepoLock))
+}
+
+// Lock acquires exclusive lock of workspace for commands like sync,
+// which change manifests and many projects. Returns a function to
+// release the lock.
+func (v RepoWorkSpace) Lock() (func(), error) {
+	lock := v.lockFile()
+	if err := lock.Lock(config.WaitLock()); err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := lock.Unlock(); err != nil {
+			log.Warnf("fail to unlock workspace: %s", err)
+		}
+	}, nil
+}
+
+// WaitUnlocked checks workspace is not locked, and waits for it if --wait
+// is given. Commands which only lock projects call it to not run
+// together with sync.
+func (v RepoWorkSpace) WaitUnlocked() error {
+	lock := v.lockFile()
+	if err := lock.Lock(config.WaitLock()); err != nil {
+		return err
+	}
+	return lock.Unlock()
+}
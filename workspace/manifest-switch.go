@@ -0,0 +1,123 @@
+package workspace
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/config"
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/alibaba/git-repo-go/project"
+)
+
+// RevisionChange is a project whose revision will be changed.
+type RevisionChange struct {
+	Path        string
+	OldRevision string
+	NewRevision string
+}
+
+// ManifestSwitch is the result of comparing projects of the workspace
+// with projects of a new manifest.
+type ManifestSwitch struct {
+	Changed  []RevisionChange
+	Added    []string
+	Removed  []string
+	Stranded []string
+}
+
+// HasChanges indicates whether projects will be changed.
+func (v ManifestSwitch) HasChanges() bool {
+	return len(v.Changed) > 0 || len(v.Added) > 0 || len(v.Removed) > 0
+}
+
+func shortRevision(rev string) string {
+	return strings.TrimPrefix(rev, config.RefsHeads)
+}
+
+// strandedWork returns local work of project which will be left behind.
+// Uncommitted changes are only checked if the project will be removed.
+func strandedWork(p *project.Project, removed bool) []string {
+	var result []string
+
+	if !p.Exists() {
+		return nil
+	}
+	if removed && !p.IsClean() {
+		result = append(result,
+			fmt.Sprintf("%s: has uncommitted changes", p.Path))
+	}
+	for _, b := range p.Heads() {
+		commits, err := p.UnpublishedCommits(b.Name)
+		if err != nil || len(commits) == 0 {
+			continue
+		}
+		result = append(result,
+			fmt.Sprintf("%s: branch %s has %d unpublished commit(s)",
+				p.Path,
+				b.ShortName(),
+				len(commits)))
+	}
+	return result
+}
+
+// CompareManifest finds out projects which will be changed, added or
+// removed after switching to manifest m, and local work which would be
+// stranded by the switch.
+func (v RepoWorkSpace) CompareManifest(m *manifest.Manifest) *ManifestSwitch {
+	result := ManifestSwitch{}
+	newProjects := make(map[string]*project.Project)
+	for _, mp := range m.AllProjects() {
+		mp := mp
+		p := project.NewProject(&mp, v.ManifestProject.Settings, m)
+		newProjects[p.Path] = p
+		if _, ok := v.projectByPath[p.Path]; !ok {
+			result.Added = append(result.Added, p.Path)
+		}
+	}
+
+	for _, p := range v.Projects {
+		newProject, ok := newProjects[p.Path]
+		if !ok {
+			result.Removed = append(result.Removed, p.Path)
+			result.Stranded = append(result.Stranded, strandedWork(p, true)...)
+			continue
+		}
+		if shortRevision(p.Revision) != shortRevision(newProject.Revision) {
+			result.Changed = append(result.Changed, RevisionChange{
+				Path:        p.Path,
+				OldRevision: shortRevision(p.Revision),
+				NewRevision: shortRevision(newProject.Revision),
+			})
+			result.Stranded = append(result.Stranded, strandedWork(p, false)...)
+		}
+	}
+	return &result
+}
+
+// CompareGroups finds out projects which will be added to or removed
+// from disk after groups of workspace are changed from oldGroups to
+// newGroups, and local work which would be stranded.
+func (v RepoWorkSpace) CompareGroups(oldGroups, newGroups string) (*ManifestSwitch, error) {
+	result := ManifestSwitch{}
+
+	oldGroups, err := v.effectiveGroups(oldGroups, "")
+	if err != nil {
+		return nil, err
+	}
+	newGroups, err = v.effectiveGroups(newGroups, "")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range v.Projects {
+		oldMatched := p.MatchGroups(oldGroups)
+		newMatched := p.MatchGroups(newGroups)
+		if newMatched && !oldMatched && !p.Exists() {
+			result.Added = append(result.Added, p.Path)
+		} else if oldMatched && !newMatched && p.Exists() {
+			result.Removed = append(result.Removed, p.Path)
+			result.Stranded = append(result.Stranded, strandedWork(p, true)...)
+		}
+	}
+	return &result, nil
+}
@@ -0,0 +1,95 @@
+package workspace
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/project"
+)
+
+const (
+	// ownersFile is a CODEOWNERS-style file in manifests project, which
+	// maps projects to owners.
+	ownersFile = "OWNERS"
+	// ownersAnnotation is annotation of project in manifest for owners,
+	// which overrides owners file.
+	ownersAnnotation = "owners"
+)
+
+// ownersRule is a line of owners file.
+type ownersRule struct {
+	Pattern string
+	Owners  []string
+}
+
+// splitOwners splits owners separated by commas or spaces.
+func splitOwners(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
+
+// parseOwners parses owners file. Each line has a pattern followed by
+// owners, and lines starting with "#" are comments.
+func parseOwners(data []byte) []ownersRule {
+	rules := []ownersRule{}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := splitOwners(line)
+		rules = append(rules, ownersRule{
+			Pattern: strings.TrimSuffix(fields[0], "/"),
+			Owners:  fields[1:],
+		})
+	}
+	return rules
+}
+
+// Match indicates whether pattern of rule matches path or name of
+// project. A pattern without wildcards also matches projects under it.
+func (v ownersRule) Match(p *project.Project) bool {
+	for _, s := range []string{p.Path, p.Name} {
+		if ok, _ := filepath.Match(v.Pattern, s); ok {
+			return true
+		}
+		if v.Pattern == "*" || strings.HasPrefix(s, v.Pattern+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOwners returns owners of the last matching rule, like CODEOWNERS.
+func matchOwners(rules []ownersRule, p *project.Project) []string {
+	owners := []string{}
+	for _, rule := range rules {
+		if rule.Match(p) {
+			owners = rule.Owners
+		}
+	}
+	return owners
+}
+
+// ProjectOwners returns owners of project, which are defined in annotation
+// "owners" of project, or in file "OWNERS" of manifests project.
+func (v RepoWorkSpace) ProjectOwners(p *project.Project) []string {
+	if value, ok := p.GetAnnotation(ownersAnnotation); ok {
+		return splitOwners(value)
+	}
+	if v.ManifestProject == nil {
+		return []string{}
+	}
+	data, err := ioutil.ReadFile(filepath.Join(v.ManifestProject.WorkDir, ownersFile))
+	if err != nil {
+		log.Debugf("fail to read owners file: %s", err)
+		return []string{}
+	}
+	return matchOwners(parseOwners(data), p)
+}
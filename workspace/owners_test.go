@@ -0,0 +1,50 @@
+package workspace
+
+import (
+	"testing"
+
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchOwners(t *testing.T) {
+	assert := assert.New(t)
+
+	rules := parseOwners([]byte(`# default owners
+*                 admin@example.com
+
+platform/         platform-team, alice@example.com
+platform/build    build-team
+*/drivers         driver-team
+`))
+	assert.Equal(4, len(rules))
+	assert.Equal("platform", rules[1].Pattern)
+	assert.Equal([]string{"platform-team", "alice@example.com"}, rules[1].Owners)
+
+	newProject := func(name, path string) *project.Project {
+		return &project.Project{
+			Repository: project.Repository{
+				Project: manifest.Project{Name: name, Path: path},
+			},
+		}
+	}
+	assert.Equal([]string{"admin@example.com"},
+		matchOwners(rules, newProject("docs", "docs")))
+	assert.Equal([]string{"platform-team", "alice@example.com"},
+		matchOwners(rules, newProject("platform/app", "app")))
+	assert.Equal([]string{"build-team"},
+		matchOwners(rules, newProject("platform/build", "build")))
+	assert.Equal([]string{"driver-team"},
+		matchOwners(rules, newProject("kernel/drivers", "kernel/drivers")))
+	assert.Equal([]string{}, matchOwners(rules[1:], newProject("docs", "docs")))
+}
+
+func TestProjectOwnersAnnotation(t *testing.T) {
+	assert := assert.New(t)
+
+	ws := RepoWorkSpace{}
+	p := &project.Project{}
+	p.Annotations = []manifest.Annotation{{Name: "owners", Value: "alice, bob"}}
+	assert.Equal([]string{"alice", "bob"}, ws.ProjectOwners(p))
+}
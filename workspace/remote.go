@@ -6,8 +6,8 @@ import (
 	"strings"
 
 	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/project"
-	log "github.com/jiangxin/multi-log"
 )
 
 const (
@@ -37,7 +37,19 @@ func (v *RepoWorkSpace) LoadRemotes(noCache bool) error {
 			err     error
 		)
 
-		if r.Review == "" {
+		protoType := strings.ToLower(r.Type)
+		if protoType == "" {
+			protoType = helper.ProtoTypeFromURL(r.Review)
+		}
+		if protoType == "" {
+			protoType = helper.ProtoTypeFromURL(r.Fetch)
+		}
+
+		if protoType != "" {
+			// No need to query ssh_info for type defined in manifest,
+			// or well-known hosts such as GitHub and GitLab.
+			sshInfo = &helper.SSHInfo{ProtoType: protoType}
+		} else if r.Review == "" {
 			log.Infof("attribute 'review' is not defined in remote '%s'", r.Name)
 			sshInfo = &helper.SSHInfo{}
 		} else {
@@ -0,0 +1,174 @@
+package workspace
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/git-repo-go/helper"
+	"github.com/alibaba/git-repo-go/log"
+	"github.com/alibaba/git-repo-go/project"
+)
+
+// pythonHookShim loads a repo-hooks python script, and calls its main()
+// function in the same way as Android repo.
+const pythonHookShim = `import runpy, sys
+hook = runpy.run_path(sys.argv[1])
+hook["main"](project_list=[sys.argv[2]], worktree_list=[sys.argv[3]], commit_list=sys.argv[4:])
+`
+
+// disabledHooksAnnotation is name of annotation of project, which lists
+// hooks not to run for the project, or "*" for all hooks.
+const disabledHooksAnnotation = "disabled-hooks"
+
+// cfgRepoHookEnabled returns git config of workspace to turn on or off a
+// hook in enabled-list of repo-hooks.
+func cfgRepoHookEnabled(name string) string {
+	return fmt.Sprintf("repo.hooks.%s.enabled", name)
+}
+
+// RepoHook is a hook defined in the repo-hooks element of manifest.
+type RepoHook struct {
+	Name    string
+	Project *project.Project
+	Script  string
+
+	topDir string
+}
+
+// GetRepoHook returns hook of name, which must be defined in the
+// enabled-list of repo-hooks. Returns nil if hook is not enabled.
+func (v RepoWorkSpace) GetRepoHook(name string) (*RepoHook, error) {
+	if v.Manifest == nil || v.Manifest.RepoHooks == nil {
+		return nil, nil
+	}
+
+	enabled := false
+	for _, hook := range v.Manifest.RepoHooks.EnabledHooks() {
+		if hook == name {
+			enabled = true
+			break
+		}
+	}
+	if !enabled {
+		return nil, nil
+	}
+	if v.ManifestProject != nil &&
+		!v.ManifestProject.Config().GetBool(cfgRepoHookEnabled(name), true) {
+		log.Debugf("hook '%s' is turned off by git config of workspace", name)
+		return nil, nil
+	}
+
+	projects := v.GetProjectsWithName(v.Manifest.RepoHooks.InProject)
+	if len(projects) == 0 {
+		return nil, fmt.Errorf("cannot find hooks project '%s'",
+			v.Manifest.RepoHooks.InProject)
+	}
+
+	hook := RepoHook{
+		Name:    name,
+		Project: projects[0],
+		topDir:  v.RootDir,
+	}
+	for _, script := range []string{name + ".py", name} {
+		script = filepath.Join(hook.Project.WorkDir, script)
+		if fi, err := os.Stat(script); err == nil && fi.Mode().IsRegular() {
+			hook.Script = script
+			break
+		}
+	}
+	if hook.Script == "" {
+		log.Warnf("hook '%s' is enabled, but not found in project '%s'",
+			name,
+			hook.Project.Name)
+		return nil, nil
+	}
+	return &hook, nil
+}
+
+// IsEnabledFor indicates whether hook runs for project, unless it is
+// listed in annotation "disabled-hooks" of the project.
+func (v RepoHook) IsEnabledFor(p *project.Project) bool {
+	value, ok := p.GetAnnotation(disabledHooksAnnotation)
+	if !ok {
+		return true
+	}
+	for _, name := range strings.FieldsFunc(value,
+		func(c rune) bool { return c == ',' || c == ' ' }) {
+		if name == "*" || name == v.Name {
+			return false
+		}
+	}
+	return true
+}
+
+func (v RepoHook) approvedHashKey() string {
+	return fmt.Sprintf("repo.hooks.%s.approvedhash", v.Name)
+}
+
+// Hash returns hash of the hook script.
+func (v RepoHook) Hash() (string, error) {
+	data, err := ioutil.ReadFile(v.Script)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha1.Sum(data)), nil
+}
+
+// IsApproved checks whether hook script is approved by user, and not
+// changed since then.
+func (v RepoHook) IsApproved() bool {
+	hash, err := v.Hash()
+	if err != nil {
+		return false
+	}
+	return v.Project.Config().Get(v.approvedHashKey()) == hash
+}
+
+// Approve saves hash of hook script, so user will not be asked again
+// until the script is changed.
+func (v RepoHook) Approve() error {
+	hash, err := v.Hash()
+	if err != nil {
+		return err
+	}
+	cfg := v.Project.Config()
+	cfg.Set(v.approvedHashKey(), hash)
+	return v.Project.SaveConfig(cfg)
+}
+
+// Run executes hook for project with commits. Python script is loaded
+// and its main() is called, while other script is executed directly in
+// worktree of the project with commits as arguments.
+func (v RepoHook) Run(p *project.Project, commits []string) error {
+	cmd := helper.Command{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Env: append([]string{
+			"REPO_PROJECT=" + p.Name,
+			"REPO_PATH=" + p.Path,
+		}, p.AnnotationEnv()...),
+	}
+	if strings.HasSuffix(v.Script, ".py") {
+		python, err := exec.LookPath("python3")
+		if err != nil {
+			python = "python"
+		}
+		cmd.Args = []string{python, "-c", pythonHookShim, v.Script, p.Name, p.WorkDir}
+		cmd.Args = append(cmd.Args, commits...)
+		cmd.Dir = v.topDir
+	} else {
+		cmd.Args = append([]string{v.Script}, commits...)
+		cmd.Dir = p.WorkDir
+	}
+	log.Debugf("%srun %s hook: %s", p.Prompt(), v.Name, v.Script)
+	if _, err := helper.RunCommand(&cmd); err != nil {
+		return fmt.Errorf("%s hook failed: %s", v.Name, err)
+	}
+	return nil
+}
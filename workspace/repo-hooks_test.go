@@ -0,0 +1,33 @@
+package workspace
+
+import (
+	"testing"
+
+	"github.com/alibaba/git-repo-go/manifest"
+	"github.com/alibaba/git-repo-go/project"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepoHookIsEnabledFor(t *testing.T) {
+	assert := assert.New(t)
+
+	hook := RepoHook{Name: "pre-upload"}
+	newProject := func(annotations ...manifest.Annotation) *project.Project {
+		return &project.Project{
+			Repository: project.Repository{
+				Project: manifest.Project{
+					Name:        "platform/app",
+					Annotations: annotations,
+				},
+			},
+		}
+	}
+
+	assert.True(hook.IsEnabledFor(newProject()))
+	assert.True(hook.IsEnabledFor(newProject(
+		manifest.Annotation{Name: "disabled-hooks", Value: "post-sync"})))
+	assert.False(hook.IsEnabledFor(newProject(
+		manifest.Annotation{Name: "disabled-hooks", Value: "post-sync, pre-upload"})))
+	assert.False(hook.IsEnabledFor(newProject(
+		manifest.Annotation{Name: "disabled-hooks", Value: "*"})))
+}
@@ -5,17 +5,18 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"runtime"
+	"regexp"
 	"strings"
 
 	"github.com/alibaba/git-repo-go/cap"
+	"github.com/alibaba/git-repo-go/common"
 	"github.com/alibaba/git-repo-go/config"
 	"github.com/alibaba/git-repo-go/errors"
+	"github.com/alibaba/git-repo-go/log"
 	"github.com/alibaba/git-repo-go/manifest"
 	"github.com/alibaba/git-repo-go/path"
 	"github.com/alibaba/git-repo-go/project"
 	"github.com/jiangxin/goconfig"
-	log "github.com/jiangxin/multi-log"
 )
 
 // RepoWorkSpace is the toplevel structure for manipulating git-repo worktree.
@@ -236,11 +237,144 @@ func (v RepoWorkSpace) GetProjectWithPath(p string) *project.Project {
 	return v.projectByPath[p]
 }
 
+// FindProject returns project which contains dir, and dir can be a
+// subdirectory of the project. Relative dir is based on current dir.
+// Returns nil if dir does not belong to any project.
+func (v RepoWorkSpace) FindProject(dir string) *project.Project {
+	dir, err := path.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	if p, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = p
+	}
+	rel, err := filepath.Rel(v.RootDir, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return nil
+	}
+
+	// Walk up to find the innermost project, for projects may be nested.
+	for rel != "." && rel != "/" {
+		if p := v.GetProjectWithPath(rel); p != nil {
+			return p
+		}
+		rel = filepath.Dir(rel)
+	}
+	return nil
+}
+
+// FilterProjectsByRegex returns projects whose name or path matches one
+// of the regex patterns, or returns projects which do not match any of
+// the patterns if inverse is true. Returns all projects if no pattern is
+// given.
+func FilterProjectsByRegex(projects []*project.Project, patterns []string, inverse bool) []*project.Project {
+	var (
+		result = []*project.Project{}
+		res    = []*regexp.Regexp{}
+	)
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Warnf("cannot compile regex pattern %s: %s", pattern, err)
+			continue
+		}
+		res = append(res, re)
+	}
+	if len(res) == 0 {
+		return projects
+	}
+
+	for _, p := range projects {
+		matched := false
+		for _, re := range res {
+			if re.MatchString(p.Name) || re.MatchString(p.Path) {
+				matched = true
+				break
+			}
+		}
+		if matched != inverse {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// isGlobPattern checks whether arg has wildcard characters.
+func isGlobPattern(arg string) bool {
+	return strings.ContainsAny(arg, "*?[")
+}
+
+// matchProjects returns projects whose name or path matches glob pattern.
+func (v RepoWorkSpace) matchProjects(pattern string) []*project.Project {
+	result := []*project.Project{}
+	for _, p := range v.Projects {
+		if ok, _ := filepath.Match(pattern, p.Name); ok {
+			result = append(result, p)
+		} else if ok, _ := filepath.Match(pattern, p.Path); ok {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// resolveProjectArg returns projects matching arg, which can be name, path
+// (relative to pDir), a subdirectory of a project, or a glob pattern.
+func (v RepoWorkSpace) resolveProjectArg(arg, pDir string) ([]*project.Project, error) {
+	if isGlobPattern(arg) {
+		ps := v.matchProjects(arg)
+		if len(ps) == 0 && pDir != "" {
+			ps = v.matchProjects(filepath.Join(pDir, arg))
+		}
+		if len(ps) == 0 {
+			return nil, errors.NoMatchingProjectError(arg)
+		}
+		return ps, nil
+	}
+
+	ps := v.GetProjectsWithName(arg)
+	if pDir != "" {
+		arg = filepath.Clean(filepath.Join(pDir, arg))
+	}
+	p := v.GetProjectWithPath(arg)
+	if len(ps) > 0 {
+		if p != nil && p.Name != ps[0].Name {
+			return nil, errors.AmbiguousProjectError(arg, ps[0].Name, p.Path)
+		}
+		return ps, nil
+	}
+	if p == nil && path.Exist(filepath.Join(v.RootDir, arg)) {
+		p = v.FindProject(filepath.Join(v.RootDir, arg))
+	}
+	if p == nil {
+		return nil, errors.NoSuchProjectError(arg)
+	}
+	return []*project.Project{p}, nil
+}
+
+// effectiveGroups returns groups to match projects, which has default
+// groups if groups is empty, and platform groups of platform (or platform
+// of workspace if platform is empty).
+func (v RepoWorkSpace) effectiveGroups(groups, platform string) (string, error) {
+	if groups == "" {
+		groups = manifest.DefaultGroups()
+	}
+	if platform == "" {
+		platform = v.Settings().Platform
+	}
+	if v.IsMirror() && (platform == "" || platform == manifest.PlatformAuto) {
+		platform = manifest.PlatformNone
+	}
+	return manifest.EffectiveGroups(groups, platform)
+}
+
 // GetProjectsOptions is options for GetProjects() function.
 type GetProjectsOptions struct {
 	Groups       string
+	Platform     string // Override platform of workspace, such as "linux"
 	MissingOK    bool
-	SubmodulesOK bool
+	Regex        []string
+	InverseRegex []string
 }
 
 // GetProjects returns all matching projects.
@@ -261,46 +395,29 @@ func (v RepoWorkSpace) GetProjects(o *GetProjectsOptions, args ...string) ([]*pr
 	}
 	groups = o.Groups
 	if groups == "" {
-		groups = v.ManifestProject.Config().Get(config.CfgManifestGroups)
-		if groups == "" {
-			groups = "default,platform-" + runtime.GOOS
-		}
+		groups = v.Settings().Groups
+	}
+	groups, err := v.effectiveGroups(groups, o.Platform)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(o.Regex) > 0 && len(o.InverseRegex) > 0 {
+		return nil, fmt.Errorf("--regex and --inverse-regex cannot be used together")
 	}
 
 	if len(args) == 0 {
 		allProjects = v.Projects
 	} else {
 		for _, arg := range args {
-			ps := v.GetProjectsWithName(arg)
-			if len(ps) == 0 {
-				if pDir != "" {
-					arg = filepath.Clean(filepath.Join(pDir, arg))
-				}
-				p := v.GetProjectWithPath(arg)
-				if p != nil {
-					ps = append(ps, p)
-				}
-			}
-			if len(ps) == 0 {
-				return nil, errors.NoSuchProjectError(arg)
+			ps, err := v.resolveProjectArg(arg, pDir)
+			if err != nil {
+				return nil, err
 			}
-			allProjects = append(allProjects, ps...)
+			allProjects = project.Join(allProjects, ps)
 		}
 	}
 
-	derivedProjects := []*project.Project{}
-	for _, p := range allProjects {
-		if o.SubmodulesOK || p.IsSyncS() {
-			for _, sp := range p.GetSubmoduleProjects() {
-				derivedProjects = append(derivedProjects, sp)
-			}
-		}
-	}
-
-	if len(derivedProjects) > 0 {
-		allProjects = project.Join(allProjects, derivedProjects)
-	}
-
 	for _, p := range allProjects {
 		if !o.MissingOK && !p.Exists() {
 			if len(args) > 0 {
@@ -315,12 +432,19 @@ func (v RepoWorkSpace) GetProjects(o *GetProjectsOptions, args ...string) ([]*pr
 		}
 	}
 
+	if len(o.InverseRegex) > 0 {
+		result = FilterProjectsByRegex(result, o.InverseRegex, true)
+	} else if len(o.Regex) > 0 {
+		result = FilterProjectsByRegex(result, o.Regex, false)
+	}
+
 	return result, nil
 }
 
 type freezeProject struct {
-	WorkSpace    *RepoWorkSpace
-	FillUpstream bool
+	WorkSpace      *RepoWorkSpace
+	FillUpstream   bool
+	FillDestBranch bool
 }
 
 func (v *freezeProject) Process(mp *manifest.Project, parentDir string) error {
@@ -341,39 +465,51 @@ func (v *freezeProject) Process(mp *manifest.Project, parentDir string) error {
 		return nil
 	}
 
-	if v.WorkSpace.Settings().Mirror {
-		rev, err = p.ResolveRevision(p.Revision)
-		if err != nil {
-			log.Warn(err)
-			return nil
-		}
-	} else {
-		rev, err = p.ResolveRevision("HEAD")
-		if err != nil {
-			log.Warn(err)
-			return nil
-		}
+	rev, err = v.WorkSpace.CurrentRevision(p)
+	if err != nil {
+		log.Warn(err)
+		return nil
 	}
 
+	// Keep the original branch, so that a pinned project can still be
+	// uploaded for review to the right branch.
 	if v.FillUpstream {
 		if p.Upstream != "" {
 			mp.Upstream = p.Upstream
-		} else {
+		} else if !common.IsSha(p.Revision) {
 			mp.Upstream = p.Revision
 		}
 	}
+	if v.FillDestBranch {
+		if p.DestBranch != "" {
+			mp.DestBranch = p.DestBranch
+		} else if !common.IsImmutable(p.Revision) {
+			mp.DestBranch = p.Revision
+		}
+	}
 
 	mp.Revision = rev
 	return nil
 }
 
+// CurrentRevision returns commit ID which project is checked out, or
+// revision of project in a mirror.
+func (v RepoWorkSpace) CurrentRevision(p *project.Project) (string, error) {
+	if v.Settings().Mirror {
+		return p.ResolveRevision(p.Revision)
+	}
+	return p.ResolveRevision("HEAD")
+}
+
 // FreezeManifest changes projects of manifest, and set revision of project to
-// fixed revision.
-func (v *RepoWorkSpace) FreezeManifest(fillUpstream bool) error {
+// fixed revision. The original branch is saved in upstream and dest-branch
+// if fillUpstream and fillDestBranch are set.
+func (v *RepoWorkSpace) FreezeManifest(fillUpstream, fillDestBranch bool) error {
 
 	handle := &freezeProject{
-		WorkSpace:    v,
-		FillUpstream: fillUpstream,
+		WorkSpace:      v,
+		FillUpstream:   fillUpstream,
+		FillDestBranch: fillDestBranch,
 	}
 	return v.Manifest.ProjectHandle(handle)
 }
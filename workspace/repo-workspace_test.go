@@ -226,3 +226,46 @@ func TestManifestsProjectName(t *testing.T) {
 	actual = manifestsProjectName(URL, "../../../../../../..")
 	assert.Equal(expect, actual)
 }
+
+func TestFindProject(t *testing.T) {
+	var (
+		tmpdir string
+		err    error
+		assert = assert.New(t)
+	)
+
+	tmpdir, err = ioutil.TempDir("", "git-repo-")
+	if err != nil {
+		panic(err)
+	}
+	defer func(dir string) {
+		os.RemoveAll(dir)
+	}(tmpdir)
+
+	workdir := filepath.Join(tmpdir, "workdir")
+	assert.Nil(os.MkdirAll(workdir, 0755))
+	workdir, err = filepath.EvalSymlinks(workdir)
+	assert.Nil(err)
+	mURL := "https://example.com/zhiyou.jx/manifest.git"
+	assert.Nil(testCreateManifests(workdir, mURL))
+	assert.Nil(os.Symlink(filepath.Join(workdir, ".repo", "manifests", "m1.xml"),
+		filepath.Join(workdir, ".repo", "manifest.xml")))
+	ws, err := NewRepoWorkSpace(workdir)
+	assert.Nil(err)
+
+	p := ws.FindProject(filepath.Join(workdir, "platform-drivers", "nic", "src"))
+	if assert.NotNil(p) {
+		assert.Equal("platform-drivers/nic", p.Path)
+	}
+	p = ws.FindProject(filepath.Join(workdir, "platform-drivers", "src"))
+	if assert.NotNil(p) {
+		assert.Equal("platform/drivers", p.Name)
+	}
+	p = ws.FindProject(filepath.Join(workdir, "platform-manifest"))
+	if assert.NotNil(p) {
+		assert.Equal("platform/manifest", p.Name)
+	}
+	assert.Nil(ws.FindProject(filepath.Join(workdir, "other")))
+	assert.Nil(ws.FindProject(workdir))
+	assert.Nil(ws.FindProject(tmpdir))
+}